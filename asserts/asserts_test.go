@@ -67,7 +67,9 @@ func (as *assertsSuite) TestTypeNames(c *C) {
 		"test-only-2",
 		"test-only-no-authority",
 		"test-only-no-authority-pk",
+		"test-only-seq",
 		"validation",
+		"validation-set",
 	})
 }
 
@@ -888,6 +890,7 @@ func (as *assertsSuite) TestWithAuthority(c *C) {
 		"serial",
 		"system-user",
 		"validation",
+		"validation-set",
 		"repair",
 	}
 	c.Check(withAuthority, HasLen, asserts.NumAssertionType-3) // excluding device-session-request, serial-request, account-key-request
@@ -52,11 +52,13 @@ func IsNotFound(err error) bool {
 // A Backstore stores assertions. It can store and retrieve assertions
 // by type under unique primary key headers (whose names are available
 // from assertType.PrimaryKey). Plus it supports searching by headers.
+// Superseded revisions are kept and can be retrieved with History.
 // Lookups can be limited to a maximum allowed format.
 type Backstore interface {
 	// Put stores an assertion.
 	// It is responsible for checking that assert is newer than a
 	// previously stored revision with the same primary key headers.
+	// The previously stored revisions are kept as history.
 	Put(assertType *AssertionType, assert Assertion) error
 	// Get returns the assertion with the given unique key for its
 	// primary key headers.  If none is present it returns a
@@ -65,6 +67,18 @@ type Backstore interface {
 	// Search returns assertions matching the given headers.
 	// It invokes foundCb for each found assertion.
 	Search(assertType *AssertionType, headers map[string]string, foundCb func(Assertion), maxFormat int) error
+	// History returns all the stored revisions, ordered by
+	// increasing revision, of the assertion with the given unique
+	// key for its primary key headers. If none is present it
+	// returns a NotFoundError, usually with omitted Headers.
+	History(assertType *AssertionType, key []string, maxFormat int) ([]Assertion, error)
+	// SequenceMemberAfter returns for a sequence-forming assertType
+	// the first assertion in the sequence under the given
+	// sequenceKey with sequence number larger than after. If
+	// after is -1 it returns the assertion with the largest
+	// sequence number. If none exists it returns a NotFoundError,
+	// usually with omitted Headers.
+	SequenceMemberAfter(assertType *AssertionType, sequenceKey []string, after, maxFormat int) (SequenceMember, error)
 }
 
 type nullBackstore struct{}
@@ -81,6 +95,14 @@ func (nbs nullBackstore) Search(t *AssertionType, h map[string]string, f func(As
 	return nil
 }
 
+func (nbs nullBackstore) History(t *AssertionType, k []string, maxFormat int) ([]Assertion, error) {
+	return nil, &NotFoundError{Type: t}
+}
+
+func (nbs nullBackstore) SequenceMemberAfter(t *AssertionType, kp []string, after, maxFormat int) (SequenceMember, error) {
+	return nil, &NotFoundError{Type: t}
+}
+
 // A KeypairManager is a manager and backstore for private/public key pairs.
 type KeypairManager interface {
 	// Put stores the given private/public key pair,
@@ -181,6 +203,15 @@ type RODatabase interface {
 	// (trusted or not) based on arbitrary headers.  It returns a
 	// NotFoundError if no assertion can be found.
 	FindManyPredefined(assertionType *AssertionType, headers map[string]string) ([]Assertion, error)
+	// FindHistory finds all the stored revisions of an assertion,
+	// ordered by increasing revision, based on arbitrary headers.
+	// Provided headers must contain the primary key for the
+	// assertion type. It returns a NotFoundError if the assertion
+	// cannot be found.
+	FindHistory(assertionType *AssertionType, headers map[string]string) ([]Assertion, error)
+	// FindSequence finds an assertion for the given headers and
+	// after for a sequence-forming type. See Database.FindSequence.
+	FindSequence(assertType *AssertionType, sequenceHeaders map[string]string, after, maxFormat int) (SequenceMember, error)
 	// Check tests whether the assertion is properly signed and consistent with all the stored knowledge.
 	Check(assert Assertion) error
 }
@@ -368,6 +399,76 @@ func (db *Database) Check(assert Assertion) error {
 	return nil
 }
 
+// RevokedKeyError is returned when an assertion is signed with an
+// account-key that is not valid anymore, because a revision of the
+// account-key with an earlier until revoked it, because it expired or
+// because it is not known anymore.
+type RevokedKeyError struct {
+	Ref   *Ref
+	KeyID string
+}
+
+func (e *RevokedKeyError) Error() string {
+	return fmt.Sprintf("%v is signed with revoked or expired key %q", e.Ref, e.KeyID)
+}
+
+// CheckValidSigningKey checks the assertion, usually one already in
+// the database, against the current revision of the account-key that
+// signed it. The key must be valid now, otherwise a RevokedKeyError
+// is returned. This is on purpose independent of when the assertion
+// was signed: a key is revoked by shortening its validity, which must
+// affect the assertions already signed with it as well, as they cannot
+// be trusted anymore.
+func (db *Database) CheckValidSigningKey(assert Assertion) error {
+	if assert.Type().flags&noAuthority != 0 {
+		return nil
+	}
+	accKey, err := db.findAccountKey(assert.AuthorityID(), assert.SignKeyID())
+	if IsNotFound(err) {
+		return &RevokedKeyError{Ref: assert.Ref(), KeyID: assert.SignKeyID()}
+	}
+	if err != nil {
+		return fmt.Errorf("error finding matching public key for signature: %v", err)
+	}
+	if !accKey.isKeyValidAt(time.Now()) {
+		return &RevokedKeyError{Ref: assert.Ref(), KeyID: assert.SignKeyID()}
+	}
+	return nil
+}
+
+// FindRevoked re-checks all the assertions added to the database with
+// CheckValidSigningKey and returns the ones signed with account-keys
+// that have been revoked or have expired for them.
+func (db *Database) FindRevoked() ([]Assertion, error) {
+	var revoked []Assertion
+	var cbErr error
+	for _, name := range TypeNames() {
+		assertType := Type(name)
+		if assertType.flags&noAuthority != 0 {
+			continue
+		}
+		foundCb := func(a Assertion) {
+			if cbErr != nil {
+				return
+			}
+			err := db.CheckValidSigningKey(a)
+			if _, ok := err.(*RevokedKeyError); ok {
+				revoked = append(revoked, a)
+				return
+			}
+			cbErr = err
+		}
+		err := db.bs.Search(assertType, nil, foundCb, assertType.MaxSupportedFormat())
+		if err != nil {
+			return nil, err
+		}
+		if cbErr != nil {
+			return nil, cbErr
+		}
+	}
+	return revoked, nil
+}
+
 // Add persists the assertion after ensuring it is properly signed and consistent with all the stored knowledge.
 // It will return an error when trying to add an older revision of the assertion than the one currently stored.
 func (db *Database) Add(assert Assertion) error {
@@ -527,6 +628,108 @@ func (db *Database) FindManyPredefined(assertionType *AssertionType, headers map
 	return db.findMany([]Backstore{db.trusted, db.predefined}, assertionType, headers)
 }
 
+// FindHistory finds all the stored revisions of an assertion, ordered
+// by increasing revision, based on arbitrary headers. Provided
+// headers must contain the primary key for the assertion type.
+// It returns a NotFoundError if the assertion cannot be found.
+func (db *Database) FindHistory(assertionType *AssertionType, headers map[string]string) ([]Assertion, error) {
+	err := checkAssertType(assertionType)
+	if err != nil {
+		return nil, err
+	}
+	keyValues, err := PrimaryKeyFromHeaders(assertionType, headers)
+	if err != nil {
+		return nil, err
+	}
+
+	maxFormat := assertionType.MaxSupportedFormat()
+	for _, bs := range db.backstores {
+		history, err := bs.History(assertionType, keyValues, maxFormat)
+		if IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		res := make([]Assertion, 0, len(history))
+		for _, a := range history {
+			if searchMatch(a, headers) {
+				res = append(res, a)
+			}
+		}
+		if len(res) != 0 {
+			return res, nil
+		}
+		break
+	}
+
+	return nil, &NotFoundError{Type: assertionType, Headers: headers}
+}
+
+// FindSequence finds an assertion for the given headers and after for
+// a sequence-forming type.
+// The provided headers must contain a sequence key, i.e. a prefix of
+// the primary key for the assertion type except for the sequence
+// number header.
+// The assertion is the first in the sequence under the sequence key
+// with sequence number > after.
+// If after is -1 it returns instead the assertion with the largest
+// sequence number.
+// It will constrain itself to assertions with format <= maxFormat
+// unless maxFormat is -1.
+// It returns a NotFoundError if the assertion cannot be found.
+func (db *Database) FindSequence(assertType *AssertionType, sequenceHeaders map[string]string, after, maxFormat int) (SequenceMember, error) {
+	err := checkAssertType(assertType)
+	if err != nil {
+		return nil, err
+	}
+	if !assertType.SequenceForming() {
+		return nil, fmt.Errorf("cannot use FindSequence with non sequence-forming assertion type %q", assertType.Name)
+	}
+	maxSupp := assertType.MaxSupportedFormat()
+	if maxFormat == -1 {
+		maxFormat = maxSupp
+	} else {
+		if maxFormat > maxSupp {
+			return nil, fmt.Errorf("cannot find %q assertions for format %d higher than supported format %d", assertType.Name, maxFormat, maxSupp)
+		}
+	}
+	if after < -1 {
+		return nil, fmt.Errorf("cannot find %q assertions after invalid sequence number %d", assertType.Name, after)
+	}
+
+	// form the sequence key using all keys but the last one which
+	// is the sequence number
+	seqKey, err := PrimaryKeyFromHeaders(&AssertionType{
+		Name:       assertType.Name,
+		PrimaryKey: assertType.PrimaryKey[:len(assertType.PrimaryKey)-1],
+	}, sequenceHeaders)
+	if err != nil {
+		return nil, err
+	}
+
+	var assert SequenceMember
+	for _, bs := range db.backstores {
+		a, err := bs.SequenceMemberAfter(assertType, seqKey, after, maxFormat)
+		if IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		// pick the best candidate across backstores
+		if assert == nil || (after == -1 && a.Sequence() > assert.Sequence()) || (after != -1 && a.Sequence() < assert.Sequence()) {
+			assert = a
+		}
+	}
+
+	if assert == nil {
+		return nil, &NotFoundError{Type: assertType, Headers: sequenceHeaders}
+	}
+
+	return assert, nil
+}
+
 // assertion checkers
 
 // CheckSigningKeyIsNotExpired checks that the signing key is not expired.
@@ -764,6 +764,147 @@ func (safs *signAddFindSuite) TestFindMany(c *C) {
 	})
 }
 
+func (safs *signAddFindSuite) TestFindHistory(c *C) {
+	for _, rev := range []string{"0", "1", "2"} {
+		headers := map[string]interface{}{
+			"authority-id": "canonical",
+			"primary-key":  "a",
+			"other":        "other-" + rev,
+			"revision":     rev,
+		}
+		a, err := safs.signingDB.Sign(asserts.TestOnlyType, headers, nil, safs.signingKeyID)
+		c.Assert(err, IsNil)
+		err = safs.db.Add(a)
+		c.Assert(err, IsNil)
+	}
+
+	history, err := safs.db.FindHistory(asserts.TestOnlyType, map[string]string{
+		"primary-key": "a",
+	})
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 3)
+	for i, a := range history {
+		c.Check(a.Revision(), Equals, i)
+	}
+
+	history, err = safs.db.FindHistory(asserts.TestOnlyType, map[string]string{
+		"primary-key": "a",
+		"other":       "other-1",
+	})
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 1)
+	c.Check(history[0].Revision(), Equals, 1)
+
+	_, err = safs.db.FindHistory(asserts.TestOnlyType, map[string]string{
+		"primary-key": "b",
+	})
+	c.Check(asserts.IsNotFound(err), Equals, true)
+
+	_, err = safs.db.FindHistory(asserts.TestOnlyType, nil)
+	c.Check(err, ErrorMatches, "must provide primary key: primary-key")
+}
+
+func (safs *signAddFindSuite) TestFindSequence(c *C) {
+	for _, seq := range []string{"1", "2", "4"} {
+		headers := map[string]interface{}{
+			"authority-id": "canonical",
+			"n":            "s1",
+			"sequence":     seq,
+		}
+		a, err := safs.signingDB.Sign(asserts.TestOnlySeqType, headers, nil, safs.signingKeyID)
+		c.Assert(err, IsNil)
+		err = safs.db.Add(a)
+		c.Assert(err, IsNil)
+	}
+
+	seqHeaders := map[string]string{
+		"n": "s1",
+	}
+	a, err := safs.db.FindSequence(asserts.TestOnlySeqType, seqHeaders, -1, -1)
+	c.Assert(err, IsNil)
+	c.Check(a.Sequence(), Equals, 4)
+
+	a, err = safs.db.FindSequence(asserts.TestOnlySeqType, seqHeaders, 2, -1)
+	c.Assert(err, IsNil)
+	c.Check(a.Sequence(), Equals, 4)
+
+	a, err = safs.db.FindSequence(asserts.TestOnlySeqType, seqHeaders, 0, -1)
+	c.Assert(err, IsNil)
+	c.Check(a.Sequence(), Equals, 1)
+
+	_, err = safs.db.FindSequence(asserts.TestOnlySeqType, seqHeaders, 4, -1)
+	c.Check(err, DeepEquals, &asserts.NotFoundError{
+		Type:    asserts.TestOnlySeqType,
+		Headers: seqHeaders,
+	})
+
+	_, err = safs.db.FindSequence(asserts.TestOnlySeqType, nil, -1, -1)
+	c.Check(err, ErrorMatches, "must provide primary key: n")
+
+	_, err = safs.db.FindSequence(asserts.TestOnlySeqType, seqHeaders, -2, -1)
+	c.Check(err, ErrorMatches, `cannot find "test-only-seq" assertions after invalid sequence number -2`)
+
+	_, err = safs.db.FindSequence(asserts.TestOnlyType, map[string]string{}, -1, -1)
+	c.Check(err, ErrorMatches, `cannot use FindSequence with non sequence-forming assertion type "test-only"`)
+}
+
+func (safs *signAddFindSuite) TestFindRevoked(c *C) {
+	pk1 := testPrivKey1
+	since := time.Now().Add(-time.Hour)
+
+	acct1 := assertstest.NewAccount(safs.signingDB, "acc-id1", map[string]interface{}{
+		"authority-id": "canonical",
+		"account-id":   "acc-id1",
+	}, safs.signingKeyID)
+	acct1Key := assertstest.NewAccountKey(safs.signingDB, acct1, map[string]interface{}{
+		"authority-id": "canonical",
+		"since":        since.Format(time.RFC3339),
+	}, pk1.PublicKey(), safs.signingKeyID)
+
+	err := safs.db.Add(acct1)
+	c.Assert(err, IsNil)
+	err = safs.db.Add(acct1Key)
+	c.Assert(err, IsNil)
+
+	acct1Signing := assertstest.NewSigningDB("acc-id1", pk1)
+	a, err := acct1Signing.Sign(asserts.TestOnlyType, map[string]interface{}{
+		"primary-key": "a",
+	}, nil, "")
+	c.Assert(err, IsNil)
+	err = safs.db.Add(a)
+	c.Assert(err, IsNil)
+
+	c.Check(safs.db.CheckValidSigningKey(a), IsNil)
+	revoked, err := safs.db.FindRevoked()
+	c.Assert(err, IsNil)
+	c.Check(revoked, HasLen, 0)
+
+	// revoke the key with a new revision having an until in the past
+	acct1KeyRevoked := assertstest.NewAccountKey(safs.signingDB, acct1, map[string]interface{}{
+		"authority-id": "canonical",
+		"since":        since.Format(time.RFC3339),
+		"until":        time.Now().Add(-time.Minute).Format(time.RFC3339),
+		"revision":     "1",
+	}, pk1.PublicKey(), safs.signingKeyID)
+	err = safs.db.Add(acct1KeyRevoked)
+	c.Assert(err, IsNil)
+
+	err = safs.db.CheckValidSigningKey(a)
+	c.Check(err, DeepEquals, &asserts.RevokedKeyError{
+		Ref:   a.Ref(),
+		KeyID: pk1.PublicKey().ID(),
+	})
+	c.Check(err, ErrorMatches, `test-only \(a\) is signed with revoked or expired key "[[:alnum:]_-]+"`)
+
+	revoked, err = safs.db.FindRevoked()
+	c.Assert(err, IsNil)
+	c.Assert(revoked, HasLen, 1)
+	c.Check(revoked[0].Ref(), DeepEquals, a.Ref())
+
+	// the account and the key itself are signed by a trusted key
+	c.Check(safs.db.CheckValidSigningKey(acct1KeyRevoked), IsNil)
+}
+
 func (safs *signAddFindSuite) TestFindFindsPredefined(c *C) {
 	pk1 := testPrivKey1
 
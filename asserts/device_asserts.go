@@ -31,6 +31,8 @@ import (
 type Model struct {
 	assertionBase
 	classic          bool
+	kernel           string
+	kernelTrack      string
 	requiredSnaps    []string
 	sysUserAuthority []string
 	timestamp        time.Time
@@ -78,7 +80,12 @@ func (mod *Model) Gadget() string {
 
 // Kernel returns the kernel snap the model uses.
 func (mod *Model) Kernel() string {
-	return mod.HeaderString("kernel")
+	return mod.kernel
+}
+
+// KernelTrack returns the track the kernel snap is pinned to, if any.
+func (mod *Model) KernelTrack() string {
+	return mod.kernelTrack
 }
 
 // Base returns the base snap the model uses.
@@ -165,6 +172,21 @@ var (
 	classicModelOptional = []string{"architecture", "gadget"}
 )
 
+func splitKernelTrack(value string) (name, track string, err error) {
+	l := strings.SplitN(value, "=", 2)
+	if len(l) == 1 {
+		return value, "", nil
+	}
+	name, track = l[0], l[1]
+	if name == "" {
+		return "", "", fmt.Errorf("\"kernel\" header cannot specify a track without a snap name")
+	}
+	if track == "" || strings.Contains(track, "/") {
+		return "", "", fmt.Errorf("\"kernel\" header has invalid track %q", track)
+	}
+	return name, track, nil
+}
+
 func assembleModel(assert assertionBase) (Assertion, error) {
 	err := checkAuthorityMatchesBrand(&assert)
 	if err != nil {
@@ -203,6 +225,12 @@ func assembleModel(assert assertionBase) (Assertion, error) {
 		}
 	}
 
+	// kernel can be pinned to a track with "kernel: <name>=<track>"
+	kernel, kernelTrack, err := splitKernelTrack(assert.HeaderString("kernel"))
+	if err != nil {
+		return nil, err
+	}
+
 	// store is optional but must be a string, defaults to the ubuntu store
 	_, err = checkOptionalString(assert.headers, "store")
 	if err != nil {
@@ -241,6 +269,8 @@ func assembleModel(assert assertionBase) (Assertion, error) {
 	return &Model{
 		assertionBase:    assert,
 		classic:          classic,
+		kernel:           kernel,
+		kernelTrack:      kernelTrack,
 		requiredSnaps:    reqSnaps,
 		sysUserAuthority: sysUserAuthority,
 		timestamp:        timestamp,
@@ -102,6 +102,7 @@ func (mods *modelSuite) TestDecodeOK(c *C) {
 	c.Check(model.Architecture(), Equals, "amd64")
 	c.Check(model.Gadget(), Equals, "brand-gadget")
 	c.Check(model.Kernel(), Equals, "baz-linux")
+	c.Check(model.KernelTrack(), Equals, "")
 	c.Check(model.Base(), Equals, "core18")
 	c.Check(model.Store(), Equals, "brand-store")
 	c.Check(model.RequiredSnaps(), DeepEquals, []string{"foo", "bar"})
@@ -205,6 +206,9 @@ func (mods *modelSuite) TestDecodeInvalid(c *C) {
 		{"gadget: brand-gadget\n", "gadget: \n", `"gadget" header should not be empty`},
 		{"kernel: baz-linux\n", "", `"kernel" header is mandatory`},
 		{"kernel: baz-linux\n", "kernel: \n", `"kernel" header should not be empty`},
+		{"kernel: baz-linux\n", "kernel: baz-linux=\n", `"kernel" header has invalid track ""`},
+		{"kernel: baz-linux\n", "kernel: baz-linux=18/stable\n", `"kernel" header has invalid track "18/stable"`},
+		{"kernel: baz-linux\n", "kernel: =18\n", `"kernel" header cannot specify a track without a snap name`},
 		{"store: brand-store\n", "store:\n  - xyz\n", `"store" header must be a string`},
 		{mods.tsLine, "", `"timestamp" header is mandatory`},
 		{mods.tsLine, "timestamp: \n", `"timestamp" header should not be empty`},
@@ -222,6 +226,16 @@ func (mods *modelSuite) TestDecodeInvalid(c *C) {
 	}
 }
 
+func (mods *modelSuite) TestDecodeKernelTrack(c *C) {
+	encoded := strings.Replace(modelExample, "TSLINE", mods.tsLine, 1)
+	encoded = strings.Replace(encoded, "kernel: baz-linux\n", "kernel: baz-linux=18\n", 1)
+	a, err := asserts.Decode([]byte(encoded))
+	c.Assert(err, IsNil)
+	model := a.(*asserts.Model)
+	c.Check(model.Kernel(), Equals, "baz-linux")
+	c.Check(model.KernelTrack(), Equals, "18")
+}
+
 func (mods *modelSuite) TestModelCheck(c *C) {
 	ex, err := asserts.Decode([]byte(strings.Replace(modelExample, "TSLINE", mods.tsLine, 1)))
 	c.Assert(err, IsNil)
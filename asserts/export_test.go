@@ -135,12 +135,35 @@ func assembleTestOnlyNoAuthorityPK(assert assertionBase) (Assertion, error) {
 
 var TestOnlyNoAuthorityPKType = &AssertionType{"test-only-no-authority-pk", []string{"pk"}, assembleTestOnlyNoAuthorityPK, noAuthority}
 
+type TestOnlySeq struct {
+	assertionBase
+	seq int
+}
+
+func (seq *TestOnlySeq) Sequence() int {
+	return seq.seq
+}
+
+func assembleTestOnlySeq(assert assertionBase) (Assertion, error) {
+	seq, err := checkInt(assert.headers, "sequence")
+	if err != nil {
+		return nil, err
+	}
+	return &TestOnlySeq{
+		assertionBase: assert,
+		seq:           seq,
+	}, nil
+}
+
+var TestOnlySeqType = &AssertionType{"test-only-seq", []string{"n", "sequence"}, assembleTestOnlySeq, sequenceForming}
+
 func init() {
 	typeRegistry[TestOnlyType.Name] = TestOnlyType
 	maxSupportedFormat[TestOnlyType.Name] = 1
 	typeRegistry[TestOnly2Type.Name] = TestOnly2Type
 	typeRegistry[TestOnlyNoAuthorityType.Name] = TestOnlyNoAuthorityType
 	typeRegistry[TestOnlyNoAuthorityPKType.Name] = TestOnlyNoAuthorityPKType
+	typeRegistry[TestOnlySeqType.Name] = TestOnlySeqType
 	formatAnalyzer[TestOnlyType] = func(headers map[string]interface{}, _ []byte) (int, error) {
 		if _, ok := headers["format-1-feature"]; ok {
 			return 1, nil
@@ -24,6 +24,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -158,6 +159,18 @@ func (fsbs *filesystemBackstore) Put(assertType *AssertionType, assert Assertion
 		activeFn = fmt.Sprintf("active.%d", formatnum)
 	}
 	diskPrimaryPath := filepath.Join(diskPrimaryPathComps(primaryPath, activeFn)...)
+	// keep the revision we are about to replace as history
+	prevAssert, err := fsbs.readAssertion(assertType, diskPrimaryPath)
+	if err == nil {
+		supersededFn := fmt.Sprintf("superseded.%d", prevAssert.Revision())
+		diskSupersededPath := filepath.Join(diskPrimaryPathComps(primaryPath, supersededFn)...)
+		err = atomicWriteEntry(Encode(prevAssert), false, fsbs.top, assertType.Name, diskSupersededPath)
+		if err != nil {
+			return fmt.Errorf("broken assertion storage, cannot write superseded assertion: %v", err)
+		}
+	} else if err != errNotFound {
+		return err
+	}
 	err = atomicWriteEntry(Encode(assert), false, fsbs.top, assertType.Name, diskPrimaryPath)
 	if err != nil {
 		return fmt.Errorf("broken assertion storage, cannot write assertion: %v", err)
@@ -176,6 +189,46 @@ func (fsbs *filesystemBackstore) Get(assertType *AssertionType, key []string, ma
 	return a, err
 }
 
+func (fsbs *filesystemBackstore) History(assertType *AssertionType, key []string, maxFormat int) ([]Assertion, error) {
+	fsbs.mu.RLock()
+	defer fsbs.mu.RUnlock()
+
+	var res []Assertion
+	namesCb := func(relpaths []string) error {
+		for _, relpath := range relpaths {
+			a, err := fsbs.readAssertion(assertType, relpath)
+			if err != nil {
+				return err
+			}
+			if a.Format() <= maxFormat {
+				res = append(res, a)
+			}
+		}
+		return nil
+	}
+
+	// both the active assertions and the superseded ones
+	comps := diskPrimaryPathComps(key, "*")
+	assertTypeTop := filepath.Join(fsbs.top, assertType.Name)
+	err := findWildcard(assertTypeTop, comps, namesCb)
+	if err != nil {
+		return nil, fmt.Errorf("broken assertion storage, looking for %s: %v", assertType.Name, err)
+	}
+
+	if len(res) == 0 {
+		return nil, &NotFoundError{Type: assertType}
+	}
+
+	sort.Sort(byRevision(res))
+	return res, nil
+}
+
+type byRevision []Assertion
+
+func (br byRevision) Len() int           { return len(br) }
+func (br byRevision) Swap(i, j int)      { br[i], br[j] = br[j], br[i] }
+func (br byRevision) Less(i, j int) bool { return br[i].Revision() < br[j].Revision() }
+
 func (fsbs *filesystemBackstore) search(assertType *AssertionType, diskPattern []string, foundCb func(Assertion), maxFormat int) error {
 	assertTypeTop := filepath.Join(fsbs.top, assertType.Name)
 	candCb := func(diskPrimaryPaths []string) error {
@@ -219,3 +272,64 @@ func (fsbs *filesystemBackstore) Search(assertType *AssertionType, headers map[s
 	}
 	return fsbs.search(assertType, diskPattern, candCb, maxFormat)
 }
+
+func (fsbs *filesystemBackstore) SequenceMemberAfter(assertType *AssertionType, sequenceKey []string, after, maxFormat int) (SequenceMember, error) {
+	if !assertType.SequenceForming() {
+		return nil, fmt.Errorf("internal error: SequenceMemberAfter on non sequence-forming assertion type %q", assertType.Name)
+	}
+	if len(sequenceKey) != len(assertType.PrimaryKey)-1 {
+		return nil, fmt.Errorf("internal error: SequenceMemberAfter's sequence key argument length must be exactly 1 less than the assertion type primary key")
+	}
+
+	fsbs.mu.RLock()
+	defer fsbs.mu.RUnlock()
+
+	// collect the candidate sequence numbers with their active files
+	candidates := make(map[int][]string)
+	var seqs []int
+	candCb := func(diskPrimaryPaths []string) error {
+		seqComp := filepath.Base(filepath.Dir(diskPrimaryPaths[0]))
+		seq, err := strconv.Atoi(seqComp)
+		if err != nil {
+			return fmt.Errorf("invalid sequence number directory: %q", seqComp)
+		}
+		if seq <= after {
+			return nil
+		}
+		candidates[seq] = diskPrimaryPaths
+		seqs = append(seqs, seq)
+		return nil
+	}
+
+	n := len(sequenceKey)
+	diskPattern := make([]string, n+2)
+	for i, comp := range sequenceKey {
+		diskPattern[i] = url.QueryEscape(comp)
+	}
+	diskPattern[n] = "*"
+	diskPattern[n+1] = "active*"
+
+	assertTypeTop := filepath.Join(fsbs.top, assertType.Name)
+	err := findWildcard(assertTypeTop, diskPattern, candCb)
+	if err != nil {
+		return nil, fmt.Errorf("broken assertion storage, searching for %s: %v", assertType.Name, err)
+	}
+
+	if after == -1 {
+		sort.Sort(sort.Reverse(sort.IntSlice(seqs)))
+	} else {
+		sort.Ints(seqs)
+	}
+	for _, seq := range seqs {
+		a, err := fsbs.pickLatestAssertion(assertType, candidates[seq], maxFormat)
+		if err == errNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return a.(SequenceMember), nil
+	}
+
+	return nil, &NotFoundError{Type: assertType}
+}
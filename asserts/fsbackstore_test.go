@@ -256,3 +256,99 @@ func (fsbss *fsBackstoreSuite) TestSearchFormat(c *C) {
 	c.Check(as[0].Revision(), Equals, 1)
 
 }
+
+func (fsbss *fsBackstoreSuite) TestHistory(c *C) {
+	topDir := filepath.Join(c.MkDir(), "asserts-db")
+	bs, err := asserts.OpenFSBackstore(topDir)
+	c.Assert(err, IsNil)
+
+	var revs []asserts.Assertion
+	for _, hdrs := range []string{"", "revision: 1\n", "format: 1\nrevision: 2\n", "revision: 3\n"} {
+		a, err := asserts.Decode([]byte("type: test-only\n" +
+			"authority-id: auth-id1\n" +
+			"primary-key: foo\n" +
+			hdrs +
+			"sign-key-sha3-384: Jv8_JiHiIzJVcO9M55pPdqSDWUvuhfDIBJUS-3VW7F_idjix7Ffn5qMxB21ZQuij" +
+			"\n\n" +
+			"AXNpZw=="))
+		c.Assert(err, IsNil)
+		err = bs.Put(asserts.TestOnlyType, a)
+		c.Assert(err, IsNil)
+		revs = append(revs, a)
+	}
+
+	a, err := bs.Get(asserts.TestOnlyType, []string{"foo"}, 1)
+	c.Assert(err, IsNil)
+	c.Check(a.Revision(), Equals, 3)
+
+	history, err := bs.History(asserts.TestOnlyType, []string{"foo"}, 1)
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 4)
+	for i, a := range history {
+		c.Check(a.Revision(), Equals, i)
+		c.Check(asserts.Encode(a), DeepEquals, asserts.Encode(revs[i]))
+	}
+
+	history, err = bs.History(asserts.TestOnlyType, []string{"foo"}, 0)
+	c.Assert(err, IsNil)
+	c.Assert(history, HasLen, 3)
+	c.Check(history[0].Revision(), Equals, 0)
+	c.Check(history[1].Revision(), Equals, 1)
+	c.Check(history[2].Revision(), Equals, 3)
+
+	_, err = bs.History(asserts.TestOnlyType, []string{"bar"}, 1)
+	c.Check(err, DeepEquals, &asserts.NotFoundError{
+		Type: asserts.TestOnlyType,
+	})
+}
+
+func (fsbss *fsBackstoreSuite) TestSequenceMemberAfter(c *C) {
+	topDir := filepath.Join(c.MkDir(), "asserts-db")
+	bs, err := asserts.OpenFSBackstore(topDir)
+	c.Assert(err, IsNil)
+
+	for _, seq := range []string{"1", "2", "5"} {
+		a, err := asserts.Decode([]byte("type: test-only-seq\n" +
+			"authority-id: auth-id1\n" +
+			"n: s1\n" +
+			"sequence: " + seq + "\n" +
+			"sign-key-sha3-384: Jv8_JiHiIzJVcO9M55pPdqSDWUvuhfDIBJUS-3VW7F_idjix7Ffn5qMxB21ZQuij" +
+			"\n\n" +
+			"AXNpZw=="))
+		c.Assert(err, IsNil)
+		err = bs.Put(asserts.TestOnlySeqType, a)
+		c.Assert(err, IsNil)
+	}
+
+	tests := []struct {
+		after, expected int
+	}{
+		{-1, 5},
+		{0, 1},
+		{1, 2},
+		{2, 5},
+		{3, 5},
+		{5, -1},
+		{6, -1},
+	}
+	for _, t := range tests {
+		a, err := bs.SequenceMemberAfter(asserts.TestOnlySeqType, []string{"s1"}, t.after, 0)
+		if t.expected == -1 {
+			c.Check(err, DeepEquals, &asserts.NotFoundError{
+				Type: asserts.TestOnlySeqType,
+			})
+			continue
+		}
+		c.Assert(err, IsNil, Commentf("after %d", t.after))
+		c.Check(a.Sequence(), Equals, t.expected)
+		c.Check(a.HeaderString("n"), Equals, "s1")
+	}
+
+	_, err = bs.SequenceMemberAfter(asserts.TestOnlySeqType, []string{"s2"}, -1, 0)
+	c.Check(err, DeepEquals, &asserts.NotFoundError{
+		Type: asserts.TestOnlySeqType,
+	})
+
+	_, err = bs.SequenceMemberAfter(asserts.TestOnlyType, nil, -1, 0)
+	c.Check(err, ErrorMatches, `internal error: SequenceMemberAfter on non sequence-forming assertion type "test-only"`)
+}
@@ -63,13 +63,17 @@ func checkNotEmptyStringWhat(m map[string]interface{}, name, what string) (strin
 }
 
 func checkOptionalString(headers map[string]interface{}, name string) (string, error) {
-	value, ok := headers[name]
+	return checkOptionalStringWhat(headers, name, "header")
+}
+
+func checkOptionalStringWhat(m map[string]interface{}, name, what string) (string, error) {
+	value, ok := m[name]
 	if !ok {
 		return "", nil
 	}
 	s, ok := value.(string)
 	if !ok {
-		return "", fmt.Errorf("%q header must be a string", name)
+		return "", fmt.Errorf("%q %s must be a string", name, what)
 	}
 	return s, nil
 }
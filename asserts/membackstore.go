@@ -21,6 +21,8 @@ package asserts
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
 	"sync"
 )
 
@@ -32,12 +34,16 @@ type memoryBackstore struct {
 type memBSNode interface {
 	put(assertType *AssertionType, key []string, assert Assertion) error
 	get(key []string, maxFormat int) (Assertion, error)
+	history(key []string, maxFormat int) ([]Assertion, error)
 	search(hint []string, found func(Assertion), maxFormat int)
+	sequenceMemberAfter(prefix []string, after, maxFormat int) (SequenceMember, error)
 }
 
 type memBSBranch map[string]memBSNode
 
-type memBSLeaf map[string]map[int]Assertion
+// memBSLeaf keeps all the stored revisions for each key, ordered by
+// increasing revision.
+type memBSLeaf map[string][]Assertion
 
 func (br memBSBranch) put(assertType *AssertionType, key []string, assert Assertion) error {
 	key0 := key[0]
@@ -54,14 +60,13 @@ func (br memBSBranch) put(assertType *AssertionType, key []string, assert Assert
 }
 
 func (leaf memBSLeaf) cur(key0 string, maxFormat int) (a Assertion) {
-	for formatnum, a1 := range leaf[key0] {
-		if formatnum <= maxFormat {
-			if a == nil || a1.Revision() > a.Revision() {
-				a = a1
-			}
+	revs := leaf[key0]
+	for i := len(revs) - 1; i >= 0; i-- {
+		if revs[i].Format() <= maxFormat {
+			return revs[i]
 		}
 	}
-	return a
+	return nil
 }
 
 func (leaf memBSLeaf) put(assertType *AssertionType, key []string, assert Assertion) error {
@@ -74,10 +79,7 @@ func (leaf memBSLeaf) put(assertType *AssertionType, key []string, assert Assert
 			return &RevisionError{Current: curRev, Used: rev}
 		}
 	}
-	if _, ok := leaf[key0]; !ok {
-		leaf[key0] = make(map[int]Assertion)
-	}
-	leaf[key0][assert.Format()] = assert
+	leaf[key0] = append(leaf[key0], assert)
 	return nil
 }
 
@@ -103,6 +105,28 @@ func (leaf memBSLeaf) get(key []string, maxFormat int) (Assertion, error) {
 	return cur, nil
 }
 
+func (br memBSBranch) history(key []string, maxFormat int) ([]Assertion, error) {
+	key0 := key[0]
+	down := br[key0]
+	if down == nil {
+		return nil, errNotFound
+	}
+	return down.history(key[1:], maxFormat)
+}
+
+func (leaf memBSLeaf) history(key []string, maxFormat int) ([]Assertion, error) {
+	var res []Assertion
+	for _, a := range leaf[key[0]] {
+		if a.Format() <= maxFormat {
+			res = append(res, a)
+		}
+	}
+	if len(res) == 0 {
+		return nil, errNotFound
+	}
+	return res, nil
+}
+
 func (br memBSBranch) search(hint []string, found func(Assertion), maxFormat int) {
 	hint0 := hint[0]
 	if hint0 == "" {
@@ -136,6 +160,46 @@ func (leaf memBSLeaf) search(hint []string, found func(Assertion), maxFormat int
 	}
 }
 
+func (br memBSBranch) sequenceMemberAfter(prefix []string, after, maxFormat int) (SequenceMember, error) {
+	if len(prefix) == 0 {
+		return nil, fmt.Errorf("internal error: sequence key is too short")
+	}
+	prefix0 := prefix[0]
+	down := br[prefix0]
+	if down == nil {
+		return nil, errNotFound
+	}
+	return down.sequenceMemberAfter(prefix[1:], after, maxFormat)
+}
+
+func (leaf memBSLeaf) sequenceMemberAfter(prefix []string, after, maxFormat int) (SequenceMember, error) {
+	if len(prefix) != 0 {
+		return nil, fmt.Errorf("internal error: sequence key is too long")
+	}
+	var res SequenceMember
+	for key := range leaf {
+		seq, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, fmt.Errorf("internal error: unexpected sequence number %q", key)
+		}
+		if seq <= after {
+			continue
+		}
+		if res != nil && ((after == -1 && seq < res.Sequence()) || (after != -1 && seq > res.Sequence())) {
+			continue
+		}
+		cur := leaf.cur(key, maxFormat)
+		if cur == nil {
+			continue
+		}
+		res = cur.(SequenceMember)
+	}
+	if res == nil {
+		return nil, errNotFound
+	}
+	return res, nil
+}
+
 // NewMemoryBackstore creates a memory backed assertions backstore.
 func NewMemoryBackstore() Backstore {
 	return &memoryBackstore{
@@ -170,6 +234,21 @@ func (mbs *memoryBackstore) Get(assertType *AssertionType, key []string, maxForm
 	return a, err
 }
 
+func (mbs *memoryBackstore) History(assertType *AssertionType, key []string, maxFormat int) ([]Assertion, error) {
+	mbs.mu.RLock()
+	defer mbs.mu.RUnlock()
+
+	internalKey := make([]string, 1+len(assertType.PrimaryKey))
+	internalKey[0] = assertType.Name
+	copy(internalKey[1:], key)
+
+	res, err := mbs.top.history(internalKey, maxFormat)
+	if err == errNotFound {
+		return nil, &NotFoundError{Type: assertType}
+	}
+	return res, err
+}
+
 func (mbs *memoryBackstore) Search(assertType *AssertionType, headers map[string]string, foundCb func(Assertion), maxFormat int) error {
 	mbs.mu.RLock()
 	defer mbs.mu.RUnlock()
@@ -189,3 +268,25 @@ func (mbs *memoryBackstore) Search(assertType *AssertionType, headers map[string
 	mbs.top.search(hint, candCb, maxFormat)
 	return nil
 }
+
+func (mbs *memoryBackstore) SequenceMemberAfter(assertType *AssertionType, sequenceKey []string, after, maxFormat int) (SequenceMember, error) {
+	if !assertType.SequenceForming() {
+		return nil, fmt.Errorf("internal error: SequenceMemberAfter on non sequence-forming assertion type %q", assertType.Name)
+	}
+	if len(sequenceKey) != len(assertType.PrimaryKey)-1 {
+		return nil, fmt.Errorf("internal error: SequenceMemberAfter's sequence key argument length must be exactly 1 less than the assertion type primary key")
+	}
+
+	mbs.mu.RLock()
+	defer mbs.mu.RUnlock()
+
+	internalPrefix := make([]string, len(assertType.PrimaryKey))
+	internalPrefix[0] = assertType.Name
+	copy(internalPrefix[1:], sequenceKey)
+
+	a, err := mbs.top.sequenceMemberAfter(internalPrefix, after, maxFormat)
+	if err == errNotFound {
+		return nil, &NotFoundError{Type: assertType}
+	}
+	return a, err
+}
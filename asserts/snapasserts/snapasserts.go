@@ -36,6 +36,28 @@ type Finder interface {
 	Find(assertionType *asserts.AssertionType, headers map[string]string) (asserts.Assertion, error)
 }
 
+// signingKeyChecker is optionally implemented by a Finder that can
+// check that an assertion is still signed with a valid key, see
+// asserts.Database.CheckValidSigningKey.
+type signingKeyChecker interface {
+	CheckValidSigningKey(assert asserts.Assertion) error
+}
+
+// checkNotRevoked checks that the given snap assertions are not
+// signed with revoked or expired keys, if db can tell.
+func checkNotRevoked(name string, db Finder, as ...asserts.Assertion) error {
+	checker, ok := db.(signingKeyChecker)
+	if !ok {
+		return nil
+	}
+	for _, a := range as {
+		if err := checker.CheckValidSigningKey(a); err != nil {
+			return fmt.Errorf("cannot install snap %q: %v", name, err)
+		}
+	}
+	return nil
+}
+
 func findSnapDeclaration(snapID, name string, db Finder) (*asserts.SnapDeclaration, error) {
 	a, err := db.Find(asserts.SnapDeclarationType, map[string]string{
 		"series":  release.Series,
@@ -83,7 +105,7 @@ func CrossCheck(name, snapSHA3_384 string, snapSize uint64, si *snap.SideInfo, d
 		return fmt.Errorf("cannot install snap %q that is undergoing a rename to %q", name, snapDecl.SnapName())
 	}
 
-	return nil
+	return checkNotRevoked(name, db, snapRev, snapDecl)
 }
 
 // DeriveSideInfo tries to construct a SideInfo for the given snap using its digest to find the relevant snap assertions with the information in the given database. It will fail with an asserts.NotFoundError if it cannot find them.
@@ -116,6 +138,10 @@ func DeriveSideInfo(snapPath string, db Finder) (*snap.SideInfo, error) {
 
 	name := snapDecl.SnapName()
 
+	if err := checkNotRevoked(name, db, snapRev, snapDecl); err != nil {
+		return nil, err
+	}
+
 	return &snap.SideInfo{
 		RealName: name,
 		SnapID:   snapID,
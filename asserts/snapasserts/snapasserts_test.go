@@ -208,6 +208,48 @@ func (s *snapassertsSuite) TestCrossCheckRevokedSnapDecl(c *C) {
 	c.Check(err, ErrorMatches, `cannot install snap "foo" with a revoked snap declaration`)
 }
 
+func (s *snapassertsSuite) revokeStoreKey(c *C) {
+	// revoke the store key altogether
+	storeKey := s.storeSigning.StoreAccountKey("")
+	storePubKey, err := s.storeSigning.PublicKey("")
+	c.Assert(err, IsNil)
+	revokedKey := assertstest.NewAccountKey(s.storeSigning.RootSigning, s.storeSigning.TrustedAccount, map[string]interface{}{
+		"name":     "store",
+		"since":    storeKey.Since().Format(time.RFC3339),
+		"until":    storeKey.Since().Format(time.RFC3339),
+		"revision": "1",
+	}, storePubKey, "")
+	err = s.localDB.Add(revokedKey)
+	c.Assert(err, IsNil)
+}
+
+func (s *snapassertsSuite) TestCrossCheckRevokedSigningKey(c *C) {
+	digest := makeDigest(12)
+	size := uint64(len(fakeSnap(12)))
+	headers := map[string]interface{}{
+		"snap-id":       "snap-id-1",
+		"snap-sha3-384": digest,
+		"snap-size":     fmt.Sprintf("%d", size),
+		"snap-revision": "12",
+		"developer-id":  s.dev1Acct.AccountID(),
+		"timestamp":     time.Now().Format(time.RFC3339),
+	}
+	snapRev, err := s.storeSigning.Sign(asserts.SnapRevisionType, headers, nil, "")
+	c.Assert(err, IsNil)
+	err = s.localDB.Add(snapRev)
+	c.Assert(err, IsNil)
+
+	si := &snap.SideInfo{
+		SnapID:   "snap-id-1",
+		Revision: snap.R(12),
+	}
+
+	s.revokeStoreKey(c)
+
+	err = snapasserts.CrossCheck("foo", digest, size, si, s.localDB)
+	c.Check(err, ErrorMatches, `cannot install snap "foo": snap-revision \(.*\) is signed with revoked or expired key ".*"`)
+}
+
 func (s *snapassertsSuite) TestDeriveSideInfoHappy(c *C) {
 	digest := makeDigest(42)
 	size := uint64(len(fakeSnap(42)))
@@ -239,6 +281,33 @@ func (s *snapassertsSuite) TestDeriveSideInfoHappy(c *C) {
 	})
 }
 
+func (s *snapassertsSuite) TestDeriveSideInfoRevokedSigningKey(c *C) {
+	digest := makeDigest(42)
+	size := uint64(len(fakeSnap(42)))
+	headers := map[string]interface{}{
+		"snap-id":       "snap-id-1",
+		"snap-sha3-384": digest,
+		"snap-size":     fmt.Sprintf("%d", size),
+		"snap-revision": "42",
+		"developer-id":  s.dev1Acct.AccountID(),
+		"timestamp":     time.Now().Format(time.RFC3339),
+	}
+	snapRev, err := s.storeSigning.Sign(asserts.SnapRevisionType, headers, nil, "")
+	c.Assert(err, IsNil)
+	err = s.localDB.Add(snapRev)
+	c.Assert(err, IsNil)
+
+	tempdir := c.MkDir()
+	snapPath := filepath.Join(tempdir, "anon.snap")
+	err = ioutil.WriteFile(snapPath, fakeSnap(42), 0644)
+	c.Assert(err, IsNil)
+
+	s.revokeStoreKey(c)
+
+	_, err = snapasserts.DeriveSideInfo(snapPath, s.localDB)
+	c.Check(err, ErrorMatches, `cannot install snap "foo": snap-revision \(.*\) is signed with revoked or expired key ".*"`)
+}
+
 func (s *snapassertsSuite) TestDeriveSideInfoNoSignatures(c *C) {
 	tempdir := c.MkDir()
 	snapPath := filepath.Join(tempdir, "anon.snap")
@@ -0,0 +1,241 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package asserts
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Presence represents a presence constraint on a snap listed in a
+// validation-set assertion.
+type Presence string
+
+const (
+	// PresenceRequired means the snap must be installed.
+	PresenceRequired Presence = "required"
+	// PresenceOptional means the snap may be installed or not.
+	PresenceOptional Presence = "optional"
+	// PresenceInvalid means the snap must not be installed.
+	PresenceInvalid Presence = "invalid"
+)
+
+func checkPresence(snap map[string]interface{}, what string) (Presence, error) {
+	presence, err := checkOptionalStringWhat(snap, "presence", what)
+	if err != nil {
+		return "", err
+	}
+	switch Presence(presence) {
+	case "":
+		return PresenceRequired, nil
+	case PresenceRequired, PresenceOptional, PresenceInvalid:
+		return Presence(presence), nil
+	default:
+		return "", fmt.Errorf(`"presence" %s must be one of required|optional|invalid: %q`, what, presence)
+	}
+}
+
+// ValidationSetSnap holds the details about a snap constrained by a
+// validation-set assertion.
+type ValidationSetSnap struct {
+	Name   string
+	SnapID string
+
+	Presence Presence
+
+	// Revision is the revision the snap must be at, or 0 if any
+	// revision is fine.
+	Revision int
+}
+
+// ValidationSet holds a validation-set assertion, which lists
+// specific snaps that must be present, can be present or must not be
+// present, optionally at specific revisions.
+type ValidationSet struct {
+	assertionBase
+
+	sequence  int
+	snaps     []*ValidationSetSnap
+	timestamp time.Time
+}
+
+// Series returns the series for which the snap in the set are declared.
+func (vs *ValidationSet) Series() string {
+	return vs.HeaderString("series")
+}
+
+// AccountID returns the identifier of the account that signed this assertion.
+func (vs *ValidationSet) AccountID() string {
+	return vs.HeaderString("account-id")
+}
+
+// Name returns the name under which the validation set is known.
+func (vs *ValidationSet) Name() string {
+	return vs.HeaderString("name")
+}
+
+// Sequence returns the sequential number of the validation set in its
+// named sequence.
+func (vs *ValidationSet) Sequence() int {
+	return vs.sequence
+}
+
+// Snaps returns the constrained snaps of the validation set.
+func (vs *ValidationSet) Snaps() []*ValidationSetSnap {
+	return vs.snaps
+}
+
+// Timestamp returns the time when the validation set was issued.
+func (vs *ValidationSet) Timestamp() time.Time {
+	return vs.timestamp
+}
+
+// Prerequisites returns references to this validation set's prerequisite assertions.
+func (vs *ValidationSet) Prerequisites() []*Ref {
+	return []*Ref{
+		{AccountType, []string{vs.AccountID()}},
+	}
+}
+
+var (
+	validValidationSetName = regexp.MustCompile("^[a-z0-9](?:-?[a-z0-9])*$")
+	validSnapName          = regexp.MustCompile("^(?:[a-z0-9]+-?)*[a-z](?:-?[a-z0-9])*$")
+)
+
+func checkValidationSetSnap(snap map[string]interface{}) (*ValidationSetSnap, error) {
+	name, err := checkStringMatchesWhat(snap, "name", "of snap", validSnapName)
+	if err != nil {
+		return nil, err
+	}
+
+	what := fmt.Sprintf("of snap %q", name)
+
+	snapID, err := checkStringMatchesWhat(snap, "id", what, validSnapID)
+	if err != nil {
+		return nil, err
+	}
+
+	presence, err := checkPresence(snap, what)
+	if err != nil {
+		return nil, err
+	}
+
+	var revision int
+	if revisionStr, ok := snap["revision"]; ok {
+		s, ok := revisionStr.(string)
+		if !ok {
+			return nil, fmt.Errorf(`"revision" %s must be a string`, what)
+		}
+		revision, err = strconv.Atoi(s)
+		if err != nil || revision < 1 {
+			return nil, fmt.Errorf(`"revision" %s must be >=1: %q`, what, s)
+		}
+		if presence == PresenceInvalid {
+			return nil, fmt.Errorf(`cannot specify revision %s at the same time as stating its presence is invalid`, what)
+		}
+	}
+
+	return &ValidationSetSnap{
+		Name:     name,
+		SnapID:   snapID,
+		Presence: presence,
+		Revision: revision,
+	}, nil
+}
+
+func checkValidationSetSnaps(snapList interface{}) ([]*ValidationSetSnap, error) {
+	const wrongHeaderType = `"snaps" header must be a list of maps`
+
+	entries, ok := snapList.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf(wrongHeaderType)
+	}
+
+	seen := make(map[string]bool, len(entries))
+	seenIDs := make(map[string]string, len(entries))
+	snaps := make([]*ValidationSetSnap, 0, len(entries))
+	for _, entry := range entries {
+		snap, ok := entry.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf(wrongHeaderType)
+		}
+		valSetSnap, err := checkValidationSetSnap(snap)
+		if err != nil {
+			return nil, err
+		}
+
+		if seen[valSetSnap.Name] {
+			return nil, fmt.Errorf("cannot list the same snap %q multiple times", valSetSnap.Name)
+		}
+		seen[valSetSnap.Name] = true
+		if other := seenIDs[valSetSnap.SnapID]; other != "" {
+			return nil, fmt.Errorf("cannot specify the same snap id %q multiple times, specified for snaps %q and %q", valSetSnap.SnapID, other, valSetSnap.Name)
+		}
+		seenIDs[valSetSnap.SnapID] = valSetSnap.Name
+
+		snaps = append(snaps, valSetSnap)
+	}
+
+	return snaps, nil
+}
+
+func assembleValidationSet(assert assertionBase) (Assertion, error) {
+	authorityID := assert.AuthorityID()
+	accountID := assert.HeaderString("account-id")
+	if accountID != authorityID {
+		return nil, fmt.Errorf("authority-id and account-id must match, validation-set assertions are expected to be signed by the issuer account: %q != %q", authorityID, accountID)
+	}
+
+	_, err := checkStringMatches(assert.headers, "name", validValidationSetName)
+	if err != nil {
+		return nil, err
+	}
+
+	sequence, err := checkInt(assert.headers, "sequence")
+	if err != nil {
+		return nil, err
+	}
+	if sequence < 1 {
+		return nil, fmt.Errorf(`"sequence" header must be >=1: %d`, sequence)
+	}
+
+	snapList, ok := assert.headers["snaps"]
+	if !ok {
+		return nil, fmt.Errorf(`"snaps" header is mandatory`)
+	}
+	snaps, err := checkValidationSetSnaps(snapList)
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp, err := checkRFC3339Date(assert.headers, "timestamp")
+	if err != nil {
+		return nil, err
+	}
+
+	return &ValidationSet{
+		assertionBase: assert,
+		sequence:      sequence,
+		snaps:         snaps,
+		timestamp:     timestamp,
+	}, nil
+}
@@ -0,0 +1,172 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package asserts_test
+
+import (
+	"strings"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+)
+
+type validationSetSuite struct {
+	ts     time.Time
+	tsLine string
+}
+
+var _ = Suite(&validationSetSuite{})
+
+func (vss *validationSetSuite) SetUpSuite(c *C) {
+	vss.ts = time.Now().Truncate(time.Second).UTC()
+	vss.tsLine = "timestamp: " + vss.ts.Format(time.RFC3339) + "\n"
+}
+
+const (
+	validationSetExample = `type: validation-set
+authority-id: brand-id1
+series: 16
+account-id: brand-id1
+name: baz-3000-good
+sequence: 2
+snaps:
+  -
+    name: baz-linux
+    id: bazlinuxidididididididididididid
+    presence: optional
+    revision: 99
+  -
+    name: baz-app
+    id: bazappididididididididididididid
+  -
+    name: baz-bad
+    id: bazbadididididididididididididid
+    presence: invalid
+TSLINE body-length: 0
+sign-key-sha3-384: Jv8_JiHiIzJVcO9M55pPdqSDWUvuhfDIBJUS-3VW7F_idjix7Ffn5qMxB21ZQuij
+
+AXNpZw==`
+)
+
+func (vss *validationSetSuite) encoded() string {
+	return strings.Replace(validationSetExample, "TSLINE ", vss.tsLine, 1)
+}
+
+func (vss *validationSetSuite) TestDecodeOK(c *C) {
+	a, err := asserts.Decode([]byte(vss.encoded()))
+	c.Assert(err, IsNil)
+	c.Check(a.Type(), Equals, asserts.ValidationSetType)
+	valset := a.(*asserts.ValidationSet)
+	c.Check(valset.AuthorityID(), Equals, "brand-id1")
+	c.Check(valset.Timestamp(), Equals, vss.ts)
+	c.Check(valset.Series(), Equals, "16")
+	c.Check(valset.AccountID(), Equals, "brand-id1")
+	c.Check(valset.Name(), Equals, "baz-3000-good")
+	c.Check(valset.Sequence(), Equals, 2)
+	c.Check(valset.Snaps(), DeepEquals, []*asserts.ValidationSetSnap{
+		{
+			Name:     "baz-linux",
+			SnapID:   "bazlinuxidididididididididididid",
+			Presence: asserts.PresenceOptional,
+			Revision: 99,
+		}, {
+			Name:     "baz-app",
+			SnapID:   "bazappididididididididididididid",
+			Presence: asserts.PresenceRequired,
+		}, {
+			Name:     "baz-bad",
+			SnapID:   "bazbadididididididididididididid",
+			Presence: asserts.PresenceInvalid,
+		},
+	})
+	c.Check(valset.Prerequisites(), DeepEquals, []*asserts.Ref{
+		{Type: asserts.AccountType, PrimaryKey: []string{"brand-id1"}},
+	})
+	c.Check(a.Ref().PrimaryKey, DeepEquals, []string{"16", "brand-id1", "baz-3000-good", "2"})
+}
+
+const (
+	validationSetErrPrefix = "assertion validation-set: "
+)
+
+func (vss *validationSetSuite) TestDecodeInvalid(c *C) {
+	encoded := vss.encoded()
+
+	snapsStanza := encoded[strings.Index(encoded, "snaps:"):strings.Index(encoded, "timestamp:")]
+
+	invalidTests := []struct{ original, invalid, expectedErr string }{
+		{"series: 16\n", "", `"series" header is mandatory`},
+		{"series: 16\n", "series: \n", `"series" header should not be empty`},
+		{"account-id: brand-id1\n", "", `"account-id" header is mandatory`},
+		{"account-id: brand-id1\n", "account-id: random\n", `authority-id and account-id must match, validation-set assertions are expected to be signed by the issuer account: "brand-id1" != "random"`},
+		{"name: baz-3000-good\n", "", `"name" header is mandatory`},
+		{"name: baz-3000-good\n", "name: \n", `"name" header should not be empty`},
+		{"name: baz-3000-good\n", "name: baz_3000\n", `"name" header contains invalid characters: "baz_3000"`},
+		{"sequence: 2\n", "", `"sequence" header is mandatory`},
+		{"sequence: 2\n", "sequence: x\n", `"sequence" header is not an integer: x`},
+		{"sequence: 2\n", "sequence: 0\n", `"sequence" header must be >=1: 0`},
+		{snapsStanza, "", `"snaps" header is mandatory`},
+		{snapsStanza, "snaps: snap\n", `"snaps" header must be a list of maps`},
+		{snapsStanza, "snaps:\n  - snap\n", `"snaps" header must be a list of maps`},
+		{"name: baz-linux\n", "other: 1\n", `"name" of snap is mandatory`},
+		{"name: baz-linux\n", "name: linux_2\n", `"name" of snap contains invalid characters: "linux_2"`},
+		{"name: baz-bad\n", "name: baz-app\n", `cannot list the same snap "baz-app" multiple times`},
+		{"id: bazlinuxidididididididididididid\n", "id: 2\n", `"id" of snap "baz-linux" contains invalid characters: "2"`},
+		{"id: bazlinuxidididididididididididid\n", "id: bazappididididididididididididid\n", `cannot specify the same snap id "bazappididididididididididididid" multiple times, specified for snaps "baz-linux" and "baz-app"`},
+		{"presence: optional\n", "presence:\n      - opt\n", `"presence" of snap "baz-linux" must be a string`},
+		{"presence: optional\n", "presence: no\n", `"presence" of snap "baz-linux" must be one of required|optional|invalid: "no"`},
+		{"revision: 99\n", "revision: 0\n", `"revision" of snap "baz-linux" must be >=1: "0"`},
+		{"revision: 99\n", "revision: x\n", `"revision" of snap "baz-linux" must be >=1: "x"`},
+		{"presence: invalid\n", "presence: invalid\n    revision: 1\n", `cannot specify revision of snap "baz-bad" at the same time as stating its presence is invalid`},
+		{vss.tsLine, "", `"timestamp" header is mandatory`},
+		{vss.tsLine, "timestamp: 12:30\n", `"timestamp" header is not a RFC3339 date: .*`},
+	}
+
+	for _, test := range invalidTests {
+		invalid := strings.Replace(encoded, test.original, test.invalid, 1)
+		_, err := asserts.Decode([]byte(invalid))
+		c.Check(err, ErrorMatches, validationSetErrPrefix+test.expectedErr, Commentf("%s", test.invalid))
+	}
+}
+
+func (vss *validationSetSuite) TestValidationSetCheck(c *C) {
+	storeDB, db := makeStoreAndCheckDB(c)
+	brandDB := setup3rdPartySigning(c, "brand-id1", storeDB, db)
+
+	headers := map[string]interface{}{
+		"series":     "16",
+		"account-id": "brand-id1",
+		"name":       "baz-3000-good",
+		"sequence":   "1",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name": "baz-app",
+				"id":   "bazappididididididididididididid",
+			},
+		},
+		"timestamp": time.Now().Format(time.RFC3339),
+	}
+	valset, err := brandDB.Sign(asserts.ValidationSetType, headers, nil, "")
+	c.Assert(err, IsNil)
+
+	err = db.Check(valset)
+	c.Assert(err, IsNil)
+}
@@ -37,6 +37,7 @@ type AppInfo struct {
 	Name        string `json:"name"`
 	DesktopFile string `json:"desktop-file,omitempty"`
 	Daemon      string `json:"daemon,omitempty"`
+	DaemonScope string `json:"daemon-scope,omitempty"`
 	Enabled     bool   `json:"enabled,omitempty"`
 	Active      bool   `json:"active,omitempty"`
 	CommonID    string `json:"common-id,omitempty"`
@@ -81,8 +82,16 @@ func (client *Client) Apps(names []string, opts AppOptions) ([]*AppInfo, error)
 
 // LogOptions represent the options of the Logs call.
 type LogOptions struct {
-	N      int  // The maximum number of log lines to retrieve initially. If <0, no limit.
-	Follow bool // Whether to continue returning new lines as they appear
+	N        int       // The maximum number of log lines to retrieve initially. If <0, no limit.
+	Follow   bool      // Whether to continue returning new lines as they appear
+	Priority string    // Only retrieve lines of this syslog priority or more important (by name or 0-7)
+	Since    time.Time // If not zero, only retrieve lines logged at or after this time
+	Until    time.Time // If not zero, only retrieve lines logged at or before this time
+	Grep     string    // Only retrieve lines whose message matches this regular expression
+	Cursor   string    // Only retrieve lines after the one with this journal cursor
+	// AllFields asks for all the journal fields of each line to be
+	// returned, in Log.Fields.
+	AllFields bool
 }
 
 // A Log holds the information of a single syslog entry
@@ -91,6 +100,14 @@ type Log struct {
 	Message   string    `json:"message"`   // The log message itself
 	SID       string    `json:"sid"`       // The syslog identifier
 	PID       string    `json:"pid"`       // The process identifier
+
+	Cursor   string `json:"cursor,omitempty"`   // The journal cursor, to resume reading after this entry
+	BootID   string `json:"boot-id,omitempty"`  // The id of the boot the entry was logged in
+	Unit     string `json:"unit,omitempty"`     // The systemd unit that logged the entry
+	Priority string `json:"priority,omitempty"` // The syslog priority, 0 (emerg) to 7 (debug)
+
+	// Fields holds all the journal fields of the entry, if asked for.
+	Fields map[string]string `json:"fields,omitempty"`
 }
 
 func (l Log) String() string {
@@ -107,6 +124,24 @@ func (client *Client) Logs(names []string, opts LogOptions) (<-chan Log, error)
 	if opts.Follow {
 		query.Set("follow", strconv.FormatBool(opts.Follow))
 	}
+	if opts.Priority != "" {
+		query.Set("priority", opts.Priority)
+	}
+	if !opts.Since.IsZero() {
+		query.Set("since", opts.Since.Format(time.RFC3339))
+	}
+	if !opts.Until.IsZero() {
+		query.Set("until", opts.Until.Format(time.RFC3339))
+	}
+	if opts.Grep != "" {
+		query.Set("grep", opts.Grep)
+	}
+	if opts.Cursor != "" {
+		query.Set("cursor", opts.Cursor)
+	}
+	if opts.AllFields {
+		query.Set("fields", "all")
+	}
 
 	rsp, err := client.raw("GET", "/v2/logs", query, nil, nil)
 	if err != nil {
@@ -22,8 +22,10 @@ package client_test
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/check.v1"
 
@@ -204,6 +206,53 @@ func (cs *clientSuite) TestClientLogsOpts(c *check.C) {
 	}
 }
 
+func (cs *clientSuite) TestClientLogsFilters(c *check.C) {
+	since := time.Date(2018, 3, 1, 10, 0, 0, 0, time.UTC)
+	until := since.Add(time.Hour)
+	ch, err := cs.cli.Logs([]string{"foo"}, client.LogOptions{
+		N:         10,
+		Priority:  "err",
+		Since:     since,
+		Until:     until,
+		Grep:      "fail.*",
+		Cursor:    "s=abc;i=42",
+		AllFields: true,
+	})
+	c.Assert(err, check.IsNil)
+	for range ch {
+	}
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/logs")
+	c.Check(cs.req.URL.Query(), check.DeepEquals, url.Values{
+		"names":    {"foo"},
+		"n":        {"10"},
+		"priority": {"err"},
+		"since":    {"2018-03-01T10:00:00Z"},
+		"until":    {"2018-03-01T11:00:00Z"},
+		"grep":     {"fail.*"},
+		"cursor":   {"s=abc;i=42"},
+		"fields":   {"all"},
+	})
+}
+
+func (cs *clientSuite) TestClientLogsFullEntries(c *check.C) {
+	cs.rsp = "\x1e" + `{"message":"hello","cursor":"s=abc;i=42","boot-id":"0ab1c2","unit":"snap.foo.bar.service","priority":"6","fields":{"MESSAGE":"hello","_PID":"42"}}` + "\n"
+
+	ch, err := cs.cli.Logs(nil, client.LogOptions{AllFields: true})
+	c.Assert(err, check.IsNil)
+	var logs []client.Log
+	for log := range ch {
+		logs = append(logs, log)
+	}
+	c.Check(logs, check.DeepEquals, []client.Log{{
+		Message:  "hello",
+		Cursor:   "s=abc;i=42",
+		BootID:   "0ab1c2",
+		Unit:     "snap.foo.bar.service",
+		Priority: "6",
+		Fields:   map[string]string{"MESSAGE": "hello", "_PID": "42"},
+	}})
+}
+
 func (cs *clientSuite) TestClientServiceStart(c *check.C) {
 	cs.rsp = `{"type": "async", "status-code": 202, "change": "24"}`
 
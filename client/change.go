@@ -20,9 +20,11 @@
 package client
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/url"
 	"time"
 )
@@ -60,6 +62,7 @@ type Task struct {
 	Kind     string       `json:"kind"`
 	Summary  string       `json:"summary"`
 	Status   string       `json:"status"`
+	Queued   bool         `json:"queued,omitempty"`
 	Log      []string     `json:"log,omitempty"`
 	Progress TaskProgress `json:"progress"`
 
@@ -90,6 +93,112 @@ func (client *Client) Change(id string) (*Change, error) {
 	return &chgd.Change, nil
 }
 
+// A ChangeEvent is a modification of a change or of one of its tasks,
+// as streamed by WatchChange.
+type ChangeEvent struct {
+	// Kind is one of "change", "task", "task-status",
+	// "task-progress" and "task-log".
+	Kind string `json:"kind"`
+
+	// Change is the whole change, for "change" events.
+	Change *Change `json:"change,omitempty"`
+	// Task is a task that was added to the change, for "task" events.
+	Task *Task `json:"task,omitempty"`
+
+	// TaskID is the id of the modified task for the other events,
+	// which carry its new status, progress, or log entry.
+	TaskID   string        `json:"task-id,omitempty"`
+	Status   string        `json:"status,omitempty"`
+	Queued   bool          `json:"queued,omitempty"`
+	Progress *TaskProgress `json:"progress,omitempty"`
+	Log      string        `json:"log,omitempty"`
+
+	// Err is set for the last event if the stream was interrupted.
+	Err error `json:"-"`
+}
+
+// WatchChange streams the events of the change with the given id as
+// they happen. The first event is a "change" one with the change as it
+// was when the watching started, and so is the last one, sent once the
+// change is ready, after which the channel is closed. If the stream is
+// interrupted before that, e.g. by snapd restarting, a last event with
+// Err set is sent before closing the channel. Closing done stops the
+// watching, and the channel is closed without further events.
+func (client *Client) WatchChange(id string, done <-chan struct{}) (<-chan ChangeEvent, error) {
+	rsp, err := client.raw("GET", "/v2/changes/"+id+"/events", nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if rsp.StatusCode != 200 {
+		defer rsp.Body.Close()
+		return nil, parseError(rsp)
+	}
+
+	ch := make(chan ChangeEvent, 20)
+	finished := make(chan struct{})
+	go func() {
+		// closing the body unblocks a pending read when done
+		select {
+		case <-done:
+		case <-finished:
+		}
+		rsp.Body.Close()
+	}()
+	go func() {
+		defer close(ch)
+		defer close(finished)
+
+		send := func(ev ChangeEvent) bool {
+			select {
+			case ch <- ev:
+				return true
+			case <-done:
+				return false
+			}
+		}
+
+		// events come in application/json-seq, described in RFC7464,
+		// see Logs
+		scanner := bufio.NewScanner(rsp.Body)
+		// task logs can make for long records
+		scanner.Buffer(nil, 1024*1024)
+		for scanner.Scan() {
+			buf := scanner.Bytes()
+			idx := bytes.IndexByte(buf, 0x1E)
+			if idx < 0 {
+				// no RS? skip
+				continue
+			}
+			var ev struct {
+				ChangeEvent
+				Change *changeAndData `json:"change,omitempty"`
+			}
+			if err := json.Unmarshal(buf[idx+1:], &ev); err != nil {
+				// truncated/corrupted/binary record? skip
+				continue
+			}
+			if ev.Change != nil {
+				ev.Change.Change.data = ev.Change.Data
+				ev.ChangeEvent.Change = &ev.Change.Change
+			}
+			if !send(ev.ChangeEvent) {
+				return
+			}
+			if ev.ChangeEvent.Change != nil && ev.ChangeEvent.Change.Ready {
+				// the stream ends with the ready change
+				return
+			}
+		}
+		err := scanner.Err()
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		send(ChangeEvent{Err: fmt.Errorf("cannot watch change %s: %v", id, err)})
+	}()
+
+	return ch, nil
+}
+
 // Abort attempts to abort a change that is in not yet ready.
 func (client *Client) Abort(id string) (*Change, error) {
 	var postData struct {
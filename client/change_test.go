@@ -24,6 +24,7 @@ import (
 
 	"github.com/snapcore/snapd/client"
 	"io/ioutil"
+	"net/http"
 	"time"
 )
 
@@ -231,3 +232,107 @@ func (cs *clientSuite) TestClientAbort(c *check.C) {
 
 	c.Assert(string(body), check.Equals, "{\"action\":\"abort\"}\n")
 }
+
+func (cs *clientSuite) TestClientWatchChange(c *check.C) {
+	cs.rsp = "\x1e" + `{"kind": "change", "change": {"id": "uno", "kind": "foo", "summary": "...", "status": "Doing", "ready": false, "data": {"n": 42}}}
+` + "\x1e" + `{"kind": "task", "task": {"id": "1", "kind": "bar", "summary": "...", "status": "Do", "progress": {"done": 0, "total": 1}}}
+` + "\x1e" + `{"kind": "task-status", "task-id": "1", "status": "Doing"}
+` + "\x1e" + `{"kind": "task-progress", "task-id": "1", "progress": {"label": "bar", "done": 5, "total": 10}}
+` + "\x1e" + `{"kind": "task-log", "task-id": "1", "log": "some log"}
+` + "\x1e" + `{"kind": "change", "change": {"id": "uno", "kind": "foo", "summary": "...", "status": "Done", "ready": true}}
+`
+
+	events, err := cs.cli.WatchChange("uno", nil)
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/changes/uno/events")
+
+	var got []client.ChangeEvent
+	for ev := range events {
+		got = append(got, ev)
+	}
+	c.Assert(got, check.HasLen, 6)
+
+	c.Check(got[0].Kind, check.Equals, "change")
+	c.Check(got[0].Change.ID, check.Equals, "uno")
+	var n int
+	c.Assert(got[0].Change.Get("n", &n), check.IsNil)
+	c.Check(n, check.Equals, 42)
+
+	c.Check(got[1], check.DeepEquals, client.ChangeEvent{
+		Kind: "task",
+		Task: &client.Task{
+			ID:       "1",
+			Kind:     "bar",
+			Summary:  "...",
+			Status:   "Do",
+			Progress: client.TaskProgress{Done: 0, Total: 1},
+		},
+	})
+	c.Check(got[2], check.DeepEquals, client.ChangeEvent{Kind: "task-status", TaskID: "1", Status: "Doing"})
+	c.Check(got[3], check.DeepEquals, client.ChangeEvent{Kind: "task-progress", TaskID: "1", Progress: &client.TaskProgress{Label: "bar", Done: 5, Total: 10}})
+	c.Check(got[4], check.DeepEquals, client.ChangeEvent{Kind: "task-log", TaskID: "1", Log: "some log"})
+	c.Check(got[5].Kind, check.Equals, "change")
+	c.Check(got[5].Change.Ready, check.Equals, true)
+	c.Check(got[5].Change.Status, check.Equals, "Done")
+}
+
+func (cs *clientSuite) TestClientWatchChangeInterrupted(c *check.C) {
+	cs.rsp = "\x1e" + `{"kind": "change", "change": {"id": "uno", "kind": "foo", "summary": "...", "status": "Doing", "ready": false}}
+` + "\x1e" + `{"kind": "task-status", "task-id": "1", "status": "Doing"}
+`
+
+	events, err := cs.cli.WatchChange("uno", nil)
+	c.Assert(err, check.IsNil)
+
+	var got []client.ChangeEvent
+	for ev := range events {
+		got = append(got, ev)
+	}
+	c.Assert(got, check.HasLen, 3)
+	c.Check(got[0].Err, check.IsNil)
+	c.Check(got[1].Err, check.IsNil)
+	c.Check(got[2].Err, check.ErrorMatches, `cannot watch change uno: unexpected EOF`)
+}
+
+func (cs *clientSuite) TestClientWatchChangeDone(c *check.C) {
+	rsp := "\x1e" + `{"kind": "change", "change": {"id": "uno", "kind": "foo", "summary": "...", "status": "Doing", "ready": false}}
+`
+	for i := 0; i < 50; i++ {
+		rsp += "\x1e" + `{"kind": "task-log", "task-id": "1", "log": "some log"}
+`
+	}
+	cs.rsp = rsp
+
+	done := make(chan struct{})
+	events, err := cs.cli.WatchChange("uno", done)
+	c.Assert(err, check.IsNil)
+	close(done)
+
+	// the events already sent are there but the channel gets closed
+	// instead of blocking on the rest of the stream
+	n := 0
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				c.Check(n < 51, check.Equals, true)
+				return
+			}
+			c.Check(ev.Err, check.IsNil)
+			n++
+		case <-time.After(5 * time.Second):
+			c.Fatal("events channel not closed")
+		}
+	}
+}
+
+func (cs *clientSuite) TestClientWatchChangeError(c *check.C) {
+	cs.status = 404
+	cs.header = http.Header{}
+	cs.header.Add("Content-Type", "application/json")
+	cs.rsp = `{"type": "error", "status-code": 404, "result": {"message": "cannot find change with id \"uno\""}}`
+
+	_, err := cs.cli.WatchChange("uno", nil)
+	c.Check(err, check.ErrorMatches, `cannot find change with id "uno"`)
+}
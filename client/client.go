@@ -77,6 +77,9 @@ type Client struct {
 	interactive bool
 
 	maintenance error
+
+	warningCount     int
+	warningTimestamp time.Time
 }
 
 // New returns a new instance of Client
@@ -117,6 +120,13 @@ func (client *Client) Maintenance() error {
 	return client.maintenance
 }
 
+// WarningsSummary returns the number of warnings that are ready to be shown to
+// the user, and the timestamp of the most recently added warning (useful for
+// silencing the warning alerts, and OKing the returned warnings).
+func (client *Client) WarningsSummary() (count int, timestamp time.Time) {
+	return client.warningCount, client.warningTimestamp
+}
+
 func (client *Client) WhoAmI() (string, error) {
 	user, err := readAuthData()
 	if os.IsNotExist(err) {
@@ -355,6 +365,9 @@ type response struct {
 	ResultInfo
 
 	Maintenance *Error `json:"maintenance"`
+
+	WarningCount     int       `json:"warning-count"`
+	WarningTimestamp time.Time `json:"warning-timestamp"`
 }
 
 // Error is the real value of response.Result when an error occurs.
@@ -398,6 +411,8 @@ const (
 
 	ErrorKindSystemRestart = "system-restart"
 	ErrorKindDaemonRestart = "daemon-restart"
+
+	ErrorKindUnsuccessful = "unsuccessful"
 )
 
 // IsTwoFactorError returns whether the given error is due to problems
@@ -463,6 +478,8 @@ func (rsp *response) err(cli *Client) error {
 		} else {
 			cli.maintenance = nil
 		}
+		cli.warningCount = rsp.WarningCount
+		cli.warningTimestamp = rsp.WarningTimestamp
 	}
 	if rsp.Type != "error" {
 		return nil
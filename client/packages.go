@@ -62,6 +62,13 @@ type Snap struct {
 	CommonIDs        []string      `json:"common-ids,omitempty"`
 	MountedFrom      string        `json:"mounted-from,omitempty"`
 
+	// RefreshHold is set when automatic refreshes of the snap are
+	// held, until the given time or indefinitely if it is zero
+	RefreshHold *time.Time `json:"refresh-hold,omitempty"`
+	// RefreshInhibitedSince is set when an automatic refresh of the
+	// snap is being postponed because its apps are running
+	RefreshInhibitedSince *time.Time `json:"refresh-inhibited-since,omitempty"`
+
 	Prices      map[string]float64 `json:"prices,omitempty"`
 	Screenshots []Screenshot       `json:"screenshots,omitempty"`
 
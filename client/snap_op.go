@@ -27,6 +27,7 @@ import (
 	"mime/multipart"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 type SnapOptions struct {
@@ -39,6 +40,7 @@ type SnapOptions struct {
 	Dangerous        bool   `json:"dangerous,omitempty"`
 	IgnoreValidation bool   `json:"ignore-validation,omitempty"`
 	Unaliased        bool   `json:"unaliased,omitempty"`
+	Purge            bool   `json:"purge,omitempty"`
 
 	Users []string `json:"users,omitempty"`
 }
@@ -126,6 +128,41 @@ func (client *Client) Switch(name string, options *SnapOptions) (changeID string
 	return client.doSnapAction("switch", name, options)
 }
 
+type holdData struct {
+	Action    string     `json:"action"`
+	HoldUntil *time.Time `json:"hold-until,omitempty"`
+}
+
+// HoldRefresh holds automatic refreshes of the snap until the given
+// time, or indefinitely if until is zero.
+func (client *Client) HoldRefresh(name string, until time.Time) error {
+	action := holdData{Action: "hold"}
+	if !until.IsZero() {
+		action.HoldUntil = &until
+	}
+	return client.doHoldAction(name, &action)
+}
+
+// UnholdRefresh lets the snap be automatically refreshed again.
+func (client *Client) UnholdRefresh(name string) error {
+	return client.doHoldAction(name, &holdData{Action: "unhold"})
+}
+
+func (client *Client) doHoldAction(name string, action *holdData) error {
+	data, err := json.Marshal(action)
+	if err != nil {
+		return fmt.Errorf("cannot marshal snap action: %s", err)
+	}
+	path := fmt.Sprintf("/v2/snaps/%s", name)
+
+	headers := map[string]string{
+		"Content-Type": "application/json",
+	}
+
+	_, err = client.doSync("POST", path, nil, headers, bytes.NewBuffer(data), nil)
+	return err
+}
+
 // SnapshotMany snapshots many snaps (all, if names empty) for many users (all, if users is empty).
 func (client *Client) SnapshotMany(names []string, users []string) (setID uint64, changeID string, err error) {
 	result, changeID, err := client.doMultiSnapActionFull("snapshot", names, &SnapOptions{Users: users})
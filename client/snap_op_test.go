@@ -28,6 +28,7 @@ import (
 	"mime"
 	"mime/multipart"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/check.v1"
 
@@ -337,3 +338,38 @@ func (cs *clientSuite) TestClientOpTryModeDangerous(c *check.C) {
 	_, err := cs.cli.Try(snapdir, &client.SnapOptions{Dangerous: true})
 	c.Assert(err, check.Equals, client.ErrDangerousNotApplicable)
 }
+
+func (cs *clientSuite) TestClientHoldRefresh(c *check.C) {
+	until := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, t := range []struct {
+		until    time.Time
+		expected map[string]interface{}
+	}{
+		{until, map[string]interface{}{"action": "hold", "hold-until": "2030-01-02T03:04:05Z"}},
+		{time.Time{}, map[string]interface{}{"action": "hold"}},
+	} {
+		cs.rsp = `{"type": "sync", "result": null}`
+		err := cs.cli.HoldRefresh("foo", t.until)
+		c.Assert(err, check.IsNil)
+		c.Check(cs.req.Method, check.Equals, "POST")
+		c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps/foo")
+
+		var body map[string]interface{}
+		err = json.NewDecoder(cs.req.Body).Decode(&body)
+		c.Assert(err, check.IsNil)
+		c.Check(body, check.DeepEquals, t.expected)
+	}
+}
+
+func (cs *clientSuite) TestClientUnholdRefresh(c *check.C) {
+	cs.rsp = `{"type": "sync", "result": null}`
+	err := cs.cli.UnholdRefresh("foo")
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snaps/foo")
+
+	var body map[string]interface{}
+	err = json.NewDecoder(cs.req.Body).Decode(&body)
+	c.Assert(err, check.IsNil)
+	c.Check(body, check.DeepEquals, map[string]interface{}{"action": "unhold"})
+}
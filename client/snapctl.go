@@ -35,11 +35,29 @@ type SnapCtlOptions struct {
 	Args []string `json:"args"`
 }
 
+// UnsuccessfulError is returned by RunSnapctl when the snapctl command
+// ran but wants its caller to exit with the given non-zero exit code.
+type UnsuccessfulError struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+}
+
+func (e *UnsuccessfulError) Error() string {
+	return fmt.Sprintf("snapctl returned exit code %d", e.ExitCode)
+}
+
 type snapctlOutput struct {
 	Stdout string `json:"stdout"`
 	Stderr string `json:"stderr"`
 }
 
+type snapctlUnsuccessful struct {
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	ExitCode int    `json:"exit-code"`
+}
+
 // RunSnapctl requests a snapctl run for the given options.
 func (client *Client) RunSnapctl(options *SnapCtlOptions) (stdout, stderr []byte, err error) {
 	b, err := json.Marshal(options)
@@ -49,6 +67,23 @@ func (client *Client) RunSnapctl(options *SnapCtlOptions) (stdout, stderr []byte
 
 	var output snapctlOutput
 	_, err = client.doSync("POST", "/v2/snapctl", nil, nil, bytes.NewReader(b), &output)
+	if e, ok := err.(*Error); ok && e.Kind == ErrorKindUnsuccessful {
+		// the value was already decoded generically, go through json
+		// again to get at the typed fields
+		var unsuccessful snapctlUnsuccessful
+		v, err := json.Marshal(e.Value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot marshal unsuccessful result: %v", err)
+		}
+		if err := json.Unmarshal(v, &unsuccessful); err != nil {
+			return nil, nil, fmt.Errorf("cannot unmarshal unsuccessful result: %v", err)
+		}
+		return nil, nil, &UnsuccessfulError{
+			Stdout:   []byte(unsuccessful.Stdout),
+			Stderr:   []byte(unsuccessful.Stderr),
+			ExitCode: unsuccessful.ExitCode,
+		}
+	}
 	if err != nil {
 		return nil, nil, err
 	}
@@ -66,3 +66,31 @@ func (cs *clientSuite) TestClientRunSnapctl(c *check.C) {
 		"args":       []interface{}{"foo", "bar"},
 	})
 }
+
+func (cs *clientSuite) TestClientRunSnapctlUnsuccessful(c *check.C) {
+	cs.rsp = `{
+		"type": "error",
+		"status-code": 200,
+		"result": {
+			"message": "unsuccessful with exit code: 1",
+			"kind": "unsuccessful",
+			"value": {
+				"stdout": "test stdout",
+				"stderr": "test stderr",
+				"exit-code": 1
+			}
+		}
+	}`
+
+	options := &client.SnapCtlOptions{
+		ContextID: "1234ABCD",
+		Args:      []string{"is-connected", "plug"},
+	}
+
+	_, _, err := cs.cli.RunSnapctl(options)
+	c.Assert(err, check.DeepEquals, &client.UnsuccessfulError{
+		Stdout:   []byte("test stdout"),
+		Stderr:   []byte("test stderr"),
+		ExitCode: 1,
+	})
+}
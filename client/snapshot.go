@@ -24,6 +24,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"strconv"
 	"strings"
@@ -71,6 +72,10 @@ type Snapshot struct {
 	Size int64 `json:"size,omitempty"`
 	// if the snapshot failed to open this will be the reason why
 	Broken string `json:"broken,omitempty"`
+
+	// set if the snapshot was created automatically on snap removal;
+	// such snapshots are expired after the system-configured retention
+	Auto bool `json:"auto,omitempty"`
 }
 
 // IsValid checks whether the snapshot is missing information that
@@ -172,3 +177,43 @@ func (client *Client) snapshotAction(action *snapshotAction) (changeID string, e
 
 	return client.doAsync("POST", "/v2/snapshots", nil, headers, bytes.NewBuffer(data))
 }
+
+// SnapshotExportContentType is the content type of exported snapshot sets.
+const SnapshotExportContentType = "application/x.snapd.snapshot"
+
+// SnapshotExport streams the given snapshot set, in a format suitable for
+// SnapshotImport. The caller must close the returned reader.
+func (client *Client) SnapshotExport(setID uint64) (io.ReadCloser, error) {
+	rsp, err := client.raw("GET", fmt.Sprintf("/v2/snapshots/%d/export", setID), nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if rsp.StatusCode != 200 {
+		defer rsp.Body.Close()
+		return nil, parseError(rsp)
+	}
+	if contentType := rsp.Header.Get("Content-Type"); contentType != SnapshotExportContentType {
+		rsp.Body.Close()
+		return nil, fmt.Errorf("unexpected snapshot export content type %q", contentType)
+	}
+
+	return rsp.Body, nil
+}
+
+// SnapshotImportSet is the result of importing a snapshot set.
+type SnapshotImportSet struct {
+	ID    uint64   `json:"set-id"`
+	Snaps []string `json:"snaps"`
+}
+
+// SnapshotImport adds the snapshot set read from r, as written by
+// SnapshotExport, under a new set ID.
+func (client *Client) SnapshotImport(r io.Reader) (SnapshotImportSet, error) {
+	headers := map[string]string{
+		"Content-Type": SnapshotExportContentType,
+	}
+
+	var importSet SnapshotImportSet
+	_, err := client.doSync("POST", "/v2/snapshots", nil, headers, r, &importSet)
+	return importSet, err
+}
@@ -20,6 +20,9 @@
 package client_test
 
 import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
 	"net/url"
 	"time"
 
@@ -136,3 +139,47 @@ func (cs *clientSuite) TestClientCheckSnapshots(c *check.C) {
 func (cs *clientSuite) TestClientRestoreSnapshots(c *check.C) {
 	cs.testClientSnapshotAction(c, "restore", cs.cli.RestoreSnapshots)
 }
+
+func (cs *clientSuite) TestClientSnapshotExport(c *check.C) {
+	cs.header = http.Header{"Content-Type": []string{client.SnapshotExportContentType}}
+	cs.rsp = "exported"
+	r, err := cs.cli.SnapshotExport(42)
+	c.Assert(err, check.IsNil)
+	defer r.Close()
+	buf, err := ioutil.ReadAll(r)
+	c.Assert(err, check.IsNil)
+	c.Check(string(buf), check.Equals, "exported")
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snapshots/42/export")
+}
+
+func (cs *clientSuite) TestClientSnapshotExportError(c *check.C) {
+	cs.header = http.Header{"Content-Type": []string{"application/json"}}
+	cs.status = 404
+	cs.rsp = `{"type": "error", "status-code": 404, "result": {"message": "no such set"}}`
+	_, err := cs.cli.SnapshotExport(42)
+	c.Check(err, check.ErrorMatches, "no such set")
+}
+
+func (cs *clientSuite) TestClientSnapshotExportBadContentType(c *check.C) {
+	cs.header = http.Header{"Content-Type": []string{"text/plain"}}
+	cs.rsp = "exported"
+	_, err := cs.cli.SnapshotExport(42)
+	c.Check(err, check.ErrorMatches, `unexpected snapshot export content type "text/plain"`)
+}
+
+func (cs *clientSuite) TestClientSnapshotImport(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"result": {"set-id": 7, "snaps": ["foo", "bar"]}
+	}`
+	importSet, err := cs.cli.SnapshotImport(bytes.NewBufferString("exported"))
+	c.Assert(err, check.IsNil)
+	c.Check(importSet, check.DeepEquals, client.SnapshotImportSet{ID: 7, Snaps: []string{"foo", "bar"}})
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/snapshots")
+	c.Check(cs.req.Header.Get("Content-Type"), check.Equals, client.SnapshotExportContentType)
+	buf, err := ioutil.ReadAll(cs.req.Body)
+	c.Assert(err, check.IsNil)
+	c.Check(string(buf), check.Equals, "exported")
+}
@@ -0,0 +1,106 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// ValidationSet describes a validation set tracked by the system.
+type ValidationSet struct {
+	AccountID string `json:"account-id"`
+	Name      string `json:"name"`
+	PinnedAt  int    `json:"pinned-at,omitempty"`
+	Mode      string `json:"mode"`
+	Sequence  int    `json:"sequence"`
+	Valid     bool   `json:"valid"`
+	// Notes describes why the validation set is not satisfied.
+	Notes string `json:"notes,omitempty"`
+}
+
+// ValidationSetOptions holds the options for applying a validation set.
+type ValidationSetOptions struct {
+	// Mode is either "monitor" or "enforce".
+	Mode string
+	// Sequence pins the validation set to the given sequence;
+	// 0 tracks the latest one.
+	Sequence int
+}
+
+type validationSetAction struct {
+	Action   string `json:"action"`
+	Mode     string `json:"mode,omitempty"`
+	Sequence int    `json:"sequence,omitempty"`
+}
+
+func validationSetPath(accountID, name string) string {
+	return fmt.Sprintf("/v2/validation-sets/%s/%s", accountID, name)
+}
+
+// ListValidationSets returns the validation sets tracked by the system.
+func (client *Client) ListValidationSets() ([]*ValidationSet, error) {
+	var sets []*ValidationSet
+	_, err := client.doSync("GET", "/v2/validation-sets", nil, nil, nil, &sets)
+	if err != nil {
+		return nil, fmt.Errorf("cannot list validation sets: %v", err)
+	}
+	return sets, nil
+}
+
+// ValidationSet returns the given validation set tracked by the system.
+func (client *Client) ValidationSet(accountID, name string) (*ValidationSet, error) {
+	var vs ValidationSet
+	_, err := client.doSync("GET", validationSetPath(accountID, name), nil, nil, nil, &vs)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get validation set: %v", err)
+	}
+	return &vs, nil
+}
+
+func (client *Client) validationSetAction(accountID, name string, action *validationSetAction, result interface{}) error {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(action); err != nil {
+		return err
+	}
+	_, err := client.doSync("POST", validationSetPath(accountID, name), nil, nil, &body, result)
+	return err
+}
+
+// ApplyValidationSet starts tracking the given validation set, or
+// changes how it is tracked, according to opts.
+func (client *Client) ApplyValidationSet(accountID, name string, opts *ValidationSetOptions) (*ValidationSet, error) {
+	action := &validationSetAction{
+		Action:   "apply",
+		Mode:     opts.Mode,
+		Sequence: opts.Sequence,
+	}
+	var vs ValidationSet
+	if err := client.validationSetAction(accountID, name, action, &vs); err != nil {
+		return nil, err
+	}
+	return &vs, nil
+}
+
+// ForgetValidationSet stops tracking the given validation set.
+func (client *Client) ForgetValidationSet(accountID, name string) error {
+	return client.validationSetAction(accountID, name, &validationSetAction{Action: "forget"}, nil)
+}
@@ -0,0 +1,103 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package client_test
+
+import (
+	"encoding/json"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+)
+
+func (cs *clientSuite) TestListValidationSets(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": [
+			{"account-id": "foo", "name": "bar", "mode": "monitor", "sequence": 3, "valid": true},
+			{"account-id": "foo", "name": "baz", "mode": "enforce", "pinned-at": 1, "sequence": 1, "valid": false, "notes": "nope"}
+		]
+	}`
+	sets, err := cs.cli.ListValidationSets()
+	c.Assert(err, check.IsNil)
+	c.Check(sets, check.DeepEquals, []*client.ValidationSet{
+		{AccountID: "foo", Name: "bar", Mode: "monitor", Sequence: 3, Valid: true},
+		{AccountID: "foo", Name: "baz", Mode: "enforce", PinnedAt: 1, Sequence: 1, Notes: "nope"},
+	})
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/validation-sets")
+}
+
+func (cs *clientSuite) TestValidationSet(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": {"account-id": "foo", "name": "bar", "mode": "monitor", "sequence": 3, "valid": true}
+	}`
+	vs, err := cs.cli.ValidationSet("foo", "bar")
+	c.Assert(err, check.IsNil)
+	c.Check(vs, check.DeepEquals, &client.ValidationSet{AccountID: "foo", Name: "bar", Mode: "monitor", Sequence: 3, Valid: true})
+	c.Check(cs.req.Method, check.Equals, "GET")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/validation-sets/foo/bar")
+}
+
+func (cs *clientSuite) TestValidationSetError(c *check.C) {
+	cs.rsp = `{
+		"type": "error",
+		"status-code": 404,
+		"result": {"message": "validation set foo/bar is not tracked"}
+	}`
+	_, err := cs.cli.ValidationSet("foo", "bar")
+	c.Assert(err, check.ErrorMatches, "cannot get validation set: validation set foo/bar is not tracked")
+}
+
+func (cs *clientSuite) TestApplyValidationSet(c *check.C) {
+	cs.rsp = `{
+		"type": "sync",
+		"status-code": 200,
+		"result": {"account-id": "foo", "name": "bar", "mode": "enforce", "pinned-at": 2, "sequence": 2, "valid": true}
+	}`
+	vs, err := cs.cli.ApplyValidationSet("foo", "bar", &client.ValidationSetOptions{Mode: "enforce", Sequence: 2})
+	c.Assert(err, check.IsNil)
+	c.Check(vs, check.DeepEquals, &client.ValidationSet{AccountID: "foo", Name: "bar", Mode: "enforce", PinnedAt: 2, Sequence: 2, Valid: true})
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/validation-sets/foo/bar")
+	var body map[string]interface{}
+	c.Assert(json.NewDecoder(cs.req.Body).Decode(&body), check.IsNil)
+	c.Check(body, check.DeepEquals, map[string]interface{}{
+		"action":   "apply",
+		"mode":     "enforce",
+		"sequence": 2.0,
+	})
+}
+
+func (cs *clientSuite) TestForgetValidationSet(c *check.C) {
+	cs.rsp = `{"type": "sync", "status-code": 200, "result": null}`
+	err := cs.cli.ForgetValidationSet("foo", "bar")
+	c.Assert(err, check.IsNil)
+	c.Check(cs.req.Method, check.Equals, "POST")
+	c.Check(cs.req.URL.Path, check.Equals, "/v2/validation-sets/foo/bar")
+	var body map[string]interface{}
+	c.Assert(json.NewDecoder(cs.req.Body).Decode(&body), check.IsNil)
+	c.Check(body, check.DeepEquals, map[string]interface{}{
+		"action": "forget",
+	})
+}
@@ -75,6 +75,20 @@ static void test_verify_security_tag(void)
 	g_assert_true(verify_security_tag
 		      ("snap.123test.hook.configure", "123test"));
 
+	// Instances of snaps
+	g_assert_true(verify_security_tag("snap.foo_bar.app", "foo_bar"));
+	g_assert_true(verify_security_tag
+		      ("snap.foo_bar.hook.configure", "foo_bar"));
+	g_assert_true(verify_security_tag("snap.foo_1234567890.app",
+					  "foo_1234567890"));
+	g_assert_false(verify_security_tag("snap.foo_bar.app", "foo"));
+	g_assert_false(verify_security_tag("snap.foo.app", "foo_bar"));
+	g_assert_false(verify_security_tag("snap.foo_.app", "foo_"));
+	g_assert_false(verify_security_tag("snap.foo_Bar.app", "foo_Bar"));
+	g_assert_false(verify_security_tag("snap.foo_bar-baz.app",
+					   "foo_bar-baz"));
+	g_assert_false(verify_security_tag("snap.foo_12345678901.app",
+					   "foo_12345678901"));
 }
 
 static void test_sc_snap_name_validate(void)
@@ -214,6 +228,78 @@ static void test_sc_snap_name_validate__respects_error_protocol(void)
 	    ("snap name must use lower case letters, digits or dashes\n");
 }
 
+static void test_sc_instance_key_validate(void)
+{
+	struct sc_error *err = NULL;
+
+	sc_instance_key_validate("abc123", &err);
+	g_assert_null(err);
+
+	sc_instance_key_validate("1234567890", &err);
+	g_assert_null(err);
+
+	const char *bad_keys[] = { "Abc", "a-b", "a_b", "a b" };
+	for (size_t i = 0; i < sizeof bad_keys / sizeof *bad_keys; i++) {
+		sc_instance_key_validate(bad_keys[i], &err);
+		g_assert_nonnull(err);
+		g_assert_true(sc_error_match
+			      (err, SC_SNAP_DOMAIN,
+			       SC_SNAP_INVALID_INSTANCE_KEY));
+		g_assert_cmpstr(sc_error_msg(err), ==,
+				"instance key must use lower case letters or digits");
+		sc_error_free(err);
+	}
+
+	sc_instance_key_validate("", &err);
+	g_assert_nonnull(err);
+	g_assert_cmpstr(sc_error_msg(err), ==,
+			"instance key must contain at least one letter or digit");
+	sc_error_free(err);
+
+	sc_instance_key_validate("12345678901", &err);
+	g_assert_nonnull(err);
+	g_assert_cmpstr(sc_error_msg(err), ==,
+			"instance key must be at most 10 characters");
+	sc_error_free(err);
+}
+
+static void test_sc_instance_name_validate(void)
+{
+	struct sc_error *err = NULL;
+
+	sc_instance_name_validate("hello-world", &err);
+	g_assert_null(err);
+
+	sc_instance_name_validate("hello-world_foo", &err);
+	g_assert_null(err);
+
+	sc_instance_name_validate("hello-world_foo_bar", &err);
+	g_assert_nonnull(err);
+	g_assert_true(sc_error_match
+		      (err, SC_SNAP_DOMAIN, SC_SNAP_INVALID_INSTANCE_NAME));
+	g_assert_cmpstr(sc_error_msg(err), ==,
+			"snap instance name can contain only one underscore");
+	sc_error_free(err);
+
+	sc_instance_name_validate("hello-world_", &err);
+	g_assert_nonnull(err);
+	g_assert_true(sc_error_match
+		      (err, SC_SNAP_DOMAIN, SC_SNAP_INVALID_INSTANCE_KEY));
+	sc_error_free(err);
+
+	sc_instance_name_validate("hello world_foo", &err);
+	g_assert_nonnull(err);
+	g_assert_true(sc_error_match
+		      (err, SC_SNAP_DOMAIN, SC_SNAP_INVALID_NAME));
+	sc_error_free(err);
+
+	sc_instance_name_validate("_foo", &err);
+	g_assert_nonnull(err);
+	g_assert_true(sc_error_match
+		      (err, SC_SNAP_DOMAIN, SC_SNAP_INVALID_NAME));
+	sc_error_free(err);
+}
+
 static void test_sc_snap_drop_instance_name_no_dest(void)
 {
 	if (g_test_subprocess()) {
@@ -294,6 +380,10 @@ static void __attribute__ ((constructor)) init(void)
 			test_sc_snap_name_validate);
 	g_test_add_func("/snap/sc_snap_name_validate/respects_error_protocol",
 			test_sc_snap_name_validate__respects_error_protocol);
+	g_test_add_func("/snap/sc_instance_key_validate",
+			test_sc_instance_key_validate);
+	g_test_add_func("/snap/sc_instance_name_validate",
+			test_sc_instance_name_validate);
 	g_test_add_func("/snap/sc_snap_drop_instance_name/basic",
 			test_sc_snap_drop_instance_name_basic);
 	g_test_add_func("/snap/sc_snap_drop_instance_name/no_dest",
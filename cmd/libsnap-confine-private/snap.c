@@ -30,7 +30,7 @@
 bool verify_security_tag(const char *security_tag, const char *snap_name)
 {
 	const char *whitelist_re =
-	    "^snap\\.([a-z0-9](-?[a-z0-9])*)\\.([a-zA-Z0-9](-?[a-zA-Z0-9])*|hook\\.[a-z](-?[a-z])*)$";
+	    "^snap\\.([a-z0-9](-?[a-z0-9])*(_[a-z0-9]{1,10})?)\\.([a-zA-Z0-9](-?[a-zA-Z0-9])*|hook\\.[a-z](-?[a-z])*)$";
 	regex_t re;
 	if (regcomp(&re, whitelist_re, REG_EXTENDED) != 0)
 		die("can not compile regex %s", whitelist_re);
@@ -56,7 +56,7 @@ bool verify_security_tag(const char *security_tag, const char *snap_name)
 bool sc_is_hook_security_tag(const char *security_tag)
 {
 	const char *whitelist_re =
-	    "^snap\\.[a-z](-?[a-z0-9])*\\.(hook\\.[a-z](-?[a-z])*)$";
+	    "^snap\\.[a-z](-?[a-z0-9])*(_[a-z0-9]{1,10})?\\.(hook\\.[a-z](-?[a-z])*)$";
 
 	regex_t re;
 	if (regcomp(&re, whitelist_re, REG_EXTENDED | REG_NOSUB) != 0)
@@ -169,6 +169,93 @@ void sc_snap_name_validate(const char *snap_name, struct sc_error **errorp)
 	sc_error_forward(errorp, err);
 }
 
+void sc_instance_key_validate(const char *instance_key,
+			      struct sc_error **errorp)
+{
+	// NOTE: This function should be synchronized with the Go
+	// implementation in snap.ValidateInstanceName.
+	struct sc_error *err = NULL;
+
+	if (instance_key == NULL) {
+		err = sc_error_init(SC_SNAP_DOMAIN, SC_SNAP_INVALID_INSTANCE_KEY,
+				    "instance key cannot be NULL");
+		goto out;
+	}
+	// This is a regexp-free routine hand-coding the following pattern:
+	//
+	// "^[a-z0-9]{1,10}$"
+	//
+	// See sc_snap_name_validate for the motivation.
+	const char *p = instance_key;
+	int n = 0, m;
+	for (; *p != '\0';) {
+		if ((m = skip_lowercase_letters(&p)) > 0) {
+			n += m;
+			continue;
+		}
+		if ((m = skip_digits(&p)) > 0) {
+			n += m;
+			continue;
+		}
+		err = sc_error_init(SC_SNAP_DOMAIN, SC_SNAP_INVALID_INSTANCE_KEY,
+				    "instance key must use lower case letters or digits");
+		goto out;
+	}
+	if (n == 0) {
+		err = sc_error_init(SC_SNAP_DOMAIN, SC_SNAP_INVALID_INSTANCE_KEY,
+				    "instance key must contain at least one letter or digit");
+	} else if (n > 10) {
+		err = sc_error_init(SC_SNAP_DOMAIN, SC_SNAP_INVALID_INSTANCE_KEY,
+				    "instance key must be at most 10 characters");
+	}
+ out:
+	sc_error_forward(errorp, err);
+}
+
+void sc_instance_name_validate(const char *instance_name,
+			       struct sc_error **errorp)
+{
+	// NOTE: This function should be synchronized with the two other
+	// implementations: validate_instance_name and
+	// snap.ValidateInstanceName.
+	struct sc_error *err = NULL;
+
+	if (instance_name == NULL) {
+		err =
+		    sc_error_init(SC_SNAP_DOMAIN, SC_SNAP_INVALID_INSTANCE_NAME,
+				  "snap instance name cannot be NULL");
+		goto out;
+	}
+	// 40 characters of snap name, an underscore, 10 characters of instance
+	// key, one extra character to catch anything longer and the terminator.
+	char s[53] = { 0 };
+	strncpy(s, instance_name, sizeof s - 1);
+
+	char *t = s;
+	const char *snap_name = strsep(&t, "_");
+	const char *instance_key = strsep(&t, "_");
+	const char *third_separator = strsep(&t, "_");
+	if (third_separator != NULL) {
+		err =
+		    sc_error_init(SC_SNAP_DOMAIN, SC_SNAP_INVALID_INSTANCE_NAME,
+				  "snap instance name can contain only one underscore");
+		goto out;
+	}
+
+	sc_snap_name_validate(snap_name, &err);
+	if (err != NULL) {
+		goto out;
+	}
+	// The instance key is optional, but if there was an underscore it
+	// must not be empty.
+	if (instance_key != NULL) {
+		sc_instance_key_validate(instance_key, &err);
+	}
+
+ out:
+	sc_error_forward(errorp, err);
+}
+
 void sc_snap_drop_instance_name(const char *snap_name, char *base,
 				size_t base_size)
 {
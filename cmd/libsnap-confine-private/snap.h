@@ -31,6 +31,10 @@
 enum {
 	/** The name of the snap is not valid. */
 	SC_SNAP_INVALID_NAME = 1,
+	/** The instance key of the snap is not valid. */
+	SC_SNAP_INVALID_INSTANCE_KEY = 2,
+	/** The instance name of the snap is not valid. */
+	SC_SNAP_INVALID_INSTANCE_NAME = 3,
 };
 
 /**
@@ -44,13 +48,40 @@ enum {
  **/
 void sc_snap_name_validate(const char *snap_name, struct sc_error **errorp);
 
+/**
+ * Validate the given instance key.
+ *
+ * Valid instance key cannot be NULL and must match a regular expression
+ * describing the strict naming requirements. Please refer to snapd source code
+ * for details.
+ *
+ * The error protocol is observed so if the caller doesn't provide an outgoing
+ * error pointer the function will die on any error.
+ **/
+void sc_instance_key_validate(const char *instance_key,
+			      struct sc_error **errorp);
+
+/**
+ * Validate the given snap instance name.
+ *
+ * Valid instance name must be composed of a valid snap name and, optionally,
+ * an underscore followed by a valid instance key.
+ *
+ * The error protocol is observed so if the caller doesn't provide an outgoing
+ * error pointer the function will die on any error.
+ **/
+void sc_instance_name_validate(const char *instance_name,
+			       struct sc_error **errorp);
+
 /**
  * Validate security tag against strict naming requirements and snap name.
  *
  *  The executable name is of form:
- *   snap.<name>.(<appname>|hook.<hookname>)
+ *   snap.<name>(_<instance key>).(<appname>|hook.<hookname>)
  *  - <name> must start with lowercase letter, then may contain
- *   lowercase alphanumerics and '-'; it must match snap_name
+ *   lowercase alphanumerics and '-'; together with the optional
+ *   instance key it must match snap_name
+ *  - <instance key> may contain only lowercase alphanumerics
  *  - <appname> may contain alphanumerics and '-'
  *  - <hookname must start with a lowercase letter, then may
  *   contain lowercase letters and '-'
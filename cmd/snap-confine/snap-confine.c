@@ -108,11 +108,17 @@ int main(int argc, char **argv)
 		return 0;
 	}
 
-	const char *snap_name = getenv("SNAP_NAME");
+	// The snap name used from here on is the name of the snap instance,
+	// which differs from SNAP_NAME for snaps installed in parallel with
+	// other instances of themselves.
+	const char *snap_name = getenv("SNAP_INSTANCE_NAME");
+	if (snap_name == NULL) {
+		snap_name = getenv("SNAP_NAME");
+	}
 	if (snap_name == NULL) {
 		die("SNAP_NAME is not set");
 	}
-	sc_snap_name_validate(snap_name, NULL);
+	sc_instance_name_validate(snap_name, NULL);
 
 	// Collect and validate the security tag and a few other things passed on
 	// command line.
@@ -26,6 +26,8 @@ var (
 	Run              = run
 	ExecApp          = execApp
 	ExecHook         = execHook
+
+	AbsoluteCommandChain = absoluteCommandChain
 )
 
 func MockSyscallExec(f func(argv0 string, argv []string, envv []string) (err error)) func() {
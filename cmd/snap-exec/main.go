@@ -200,6 +200,14 @@ func execApp(snapApp, revision, command string, args []string) error {
 	}
 	fullCmd = append(fullCmd, cmdArgs...)
 	fullCmd = append(fullCmd, args...)
+
+	// the command chain, if any, wraps the command (or the shell)
+	chain, err := absoluteCommandChain(app.Snap, app.CommandChain)
+	if err != nil {
+		return err
+	}
+	fullCmd = append(chain, fullCmd...)
+
 	if err := syscallExec(fullCmd[0], fullCmd, env); err != nil {
 		return fmt.Errorf("cannot exec %q: %s", fullCmd[0], err)
 	}
@@ -230,5 +238,26 @@ func execHook(snapName, revision, hookName string) error {
 
 	// run the hook
 	hookPath := filepath.Join(hook.Snap.HooksDir(), hook.Name)
-	return syscallExec(hookPath, []string{hookPath}, env)
+	chain, err := absoluteCommandChain(hook.Snap, hook.CommandChain)
+	if err != nil {
+		return err
+	}
+	fullCmd := append(chain, hookPath)
+	return syscallExec(fullCmd[0], fullCmd, env)
+}
+
+// absoluteCommandChain returns the command chain with each element
+// made absolute by prefixing it with the mount directory of the snap.
+// Elements that would end up outside of the snap are an error.
+func absoluteCommandChain(snapInfo *snap.Info, commandChain []string) ([]string, error) {
+	mountDir := snapInfo.MountDir()
+	chain := make([]string, 0, len(commandChain))
+	for _, element := range commandChain {
+		path := filepath.Join(mountDir, element)
+		if !strings.HasPrefix(path, mountDir+"/") {
+			return nil, fmt.Errorf("cannot use command-chain element %q: not inside the snap", element)
+		}
+		chain = append(chain, path)
+	}
+	return chain, nil
 }
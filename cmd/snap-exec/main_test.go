@@ -313,6 +313,87 @@ func (s *snapExecSuite) TestSnapExecShellIntegration(c *C) {
 	c.Check(execEnv, testutil.Contains, "LD_LIBRARY_PATH=/some/path/lib")
 }
 
+var mockChainYaml = []byte(`name: snapname
+version: 1.0
+apps:
+ app:
+  command: run-app cmd-arg1
+  command-chain: [chain1, chain2]
+hooks:
+ configure:
+  command-chain: [chain1, chain2]
+`)
+
+func (s *snapExecSuite) TestSnapExecAppCommandChainIntegration(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	snaptest.MockSnap(c, string(mockChainYaml), &snap.SideInfo{
+		Revision: snap.R("42"),
+	})
+
+	execArgv0 := ""
+	execArgs := []string{}
+	restore := snapExec.MockSyscallExec(func(argv0 string, argv []string, env []string) error {
+		execArgv0 = argv0
+		execArgs = argv
+		return nil
+	})
+	defer restore()
+
+	chain1 := fmt.Sprintf("%s/snapname/42/chain1", dirs.SnapMountDir)
+	chain2 := fmt.Sprintf("%s/snapname/42/chain2", dirs.SnapMountDir)
+	app := fmt.Sprintf("%s/snapname/42/run-app", dirs.SnapMountDir)
+
+	err := snapExec.ExecApp("snapname.app", "42", "", []string{"arg1"})
+	c.Assert(err, IsNil)
+	c.Check(execArgv0, Equals, chain1)
+	c.Check(execArgs, DeepEquals, []string{chain1, chain2, app, "cmd-arg1", "arg1"})
+
+	// the shell goes through the command chain too
+	err = snapExec.ExecApp("snapname.app", "42", "shell", []string{"-c", "echo foo"})
+	c.Assert(err, IsNil)
+	c.Check(execArgv0, Equals, chain1)
+	c.Check(execArgs, DeepEquals, []string{chain1, chain2, "/bin/bash", "-c", "echo foo"})
+}
+
+func (s *snapExecSuite) TestSnapExecHookCommandChainIntegration(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	snaptest.MockSnap(c, string(mockChainYaml), &snap.SideInfo{
+		Revision: snap.R("42"),
+	})
+
+	execArgv0 := ""
+	execArgs := []string{}
+	restore := snapExec.MockSyscallExec(func(argv0 string, argv []string, env []string) error {
+		execArgv0 = argv0
+		execArgs = argv
+		return nil
+	})
+	defer restore()
+
+	chain1 := fmt.Sprintf("%s/snapname/42/chain1", dirs.SnapMountDir)
+	chain2 := fmt.Sprintf("%s/snapname/42/chain2", dirs.SnapMountDir)
+	hook := fmt.Sprintf("%s/snapname/42/meta/hooks/configure", dirs.SnapMountDir)
+
+	err := snapExec.ExecHook("snapname", "42", "configure")
+	c.Assert(err, IsNil)
+	c.Check(execArgv0, Equals, chain1)
+	c.Check(execArgs, DeepEquals, []string{chain1, chain2, hook})
+}
+
+func (s *snapExecSuite) TestAbsoluteCommandChain(c *C) {
+	info := &snap.Info{SuggestedName: "snapname", SideInfo: snap.SideInfo{Revision: snap.R("42")}}
+	mountDir := info.MountDir()
+
+	chain, err := snapExec.AbsoluteCommandChain(info, []string{"chain1", "bin/../chain2", "/chain3"})
+	c.Assert(err, IsNil)
+	c.Check(chain, DeepEquals, []string{mountDir + "/chain1", mountDir + "/chain2", mountDir + "/chain3"})
+
+	for _, element := range []string{"", ".", "..", "../chain", "bin/../../chain"} {
+		_, err := snapExec.AbsoluteCommandChain(info, []string{"chain1", element})
+		c.Check(err, ErrorMatches, `cannot use command-chain element ".*": not inside the snap`, Commentf("%q", element))
+	}
+}
+
 func (s *snapExecSuite) TestSnapExecAppIntegrationWithVars(c *C) {
 	dirs.SetRootDir(c.MkDir())
 	snaptest.MockSnap(c, string(mockYaml), &snap.SideInfo{
@@ -247,6 +247,84 @@ int validate_snap_name(const char* snap_name)
     return 0;
 }
 
+// validate_instance_key performs full validation of the given instance key.
+int validate_instance_key(const char* instance_key)
+{
+    // NOTE: This function should be synchronized with the two other
+    // implementations: sc_instance_key_validate and snap.ValidateInstanceName.
+
+    if (instance_key == NULL) {
+        bootstrap_msg = "instance key cannot be NULL";
+        return -1;
+    }
+    // This is a regexp-free routine hand-coding the following pattern:
+    //
+    // "^[a-z0-9]{1,10}$"
+    const char* p = instance_key;
+    int n=0, m;
+    for (; *p != '\0';) {
+        if ((m = skip_lowercase_letters(&p)) > 0) {
+            n += m;
+            continue;
+        }
+        if ((m = skip_digits(&p)) > 0) {
+            n += m;
+            continue;
+        }
+        bootstrap_msg = "instance key must use lower case letters or digits";
+        return -1;
+    }
+    if (n == 0) {
+        bootstrap_msg = "instance key must contain at least one letter or digit";
+        return -1;
+    }
+    if (n > 10) {
+        bootstrap_msg = "instance key must be at most 10 characters";
+        return -1;
+    }
+
+    bootstrap_msg = NULL;
+    return 0;
+}
+
+// validate_instance_name performs full validation of the given snap instance
+// name, that is a snap name optionally followed by an underscore and an
+// instance key.
+int validate_instance_name(const char* instance_name)
+{
+    // NOTE: This function should be synchronized with the two other
+    // implementations: sc_instance_name_validate and snap.ValidateInstanceName.
+
+    if (instance_name == NULL) {
+        bootstrap_msg = "snap instance name cannot be NULL";
+        return -1;
+    }
+    // 40 characters of snap name, an underscore, 10 characters of instance
+    // key, one extra character to catch anything longer and the terminator.
+    char s[53] = {0};
+    strncpy(s, instance_name, sizeof s - 1);
+
+    char* instance_key = strchr(s, '_');
+    if (instance_key != NULL) {
+        *instance_key = '\0';
+        instance_key++;
+        if (strchr(instance_key, '_') != NULL) {
+            bootstrap_msg = "snap instance name can contain only one underscore";
+            return -1;
+        }
+    }
+
+    if (validate_snap_name(s) < 0) {
+        return -1;
+    }
+    if (instance_key != NULL && validate_instance_key(instance_key) < 0) {
+        return -1;
+    }
+
+    bootstrap_msg = NULL;
+    return 0;
+}
+
 // process_arguments parses given a command line
 // argc and argv are defined as for the main() function
 void process_arguments(int argc, char *const *argv, const char** snap_name_out, bool* should_setns_out, bool* process_user_fstab)
@@ -314,9 +392,9 @@ void process_arguments(int argc, char *const *argv, const char** snap_name_out,
 
     // Ensure that the snap name is valid so that we don't blindly setns into
     // something that is controlled by a potential attacker.
-    if (validate_snap_name(snap_name) < 0) {
+    if (validate_instance_name(snap_name) < 0) {
         bootstrap_errno = 0;
-        // bootstap_msg is set by validate_snap_name;
+        // bootstap_msg is set by validate_instance_name;
         return;
     }
     // We have a valid snap name now so let's store it.
@@ -98,6 +98,14 @@ func validateSnapName(snapName string) int {
 	return int(C.validate_snap_name(cStr))
 }
 
+// validateInstanceName checks if the snap instance name is valid.
+// This also sets bootstrap_msg on failure.
+func validateInstanceName(instanceName string) int {
+	cStr := C.CString(instanceName)
+	defer C.free(unsafe.Pointer(cStr))
+	return int(C.validate_instance_name(cStr))
+}
+
 // processArguments parses commnad line arguments.
 // The argument cmdline is a string with embedded
 // NUL bytes, separating particular arguments.
@@ -39,6 +39,19 @@ func (s *bootstrapSuite) TestValidateSnapName(c *C) {
 	c.Assert(update.ValidateSnapName(""), Equals, -1)
 }
 
+// Check that ValidateInstanceName accepts snap names with optional instance keys.
+func (s *bootstrapSuite) TestValidateInstanceName(c *C) {
+	c.Assert(update.ValidateInstanceName("hello-world"), Equals, 0)
+	c.Assert(update.ValidateInstanceName("hello-world_foo"), Equals, 0)
+	c.Assert(update.ValidateInstanceName("hello-world_1234567890"), Equals, 0)
+	c.Assert(update.ValidateInstanceName("hello-world_"), Equals, -1)
+	c.Assert(update.ValidateInstanceName("hello-world_foo_bar"), Equals, -1)
+	c.Assert(update.ValidateInstanceName("hello-world_Foo"), Equals, -1)
+	c.Assert(update.ValidateInstanceName("hello-world_12345678901"), Equals, -1)
+	c.Assert(update.ValidateInstanceName("hello/world_foo"), Equals, -1)
+	c.Assert(update.ValidateInstanceName("_foo"), Equals, -1)
+}
+
 // Test various cases of command line handling.
 func (s *bootstrapSuite) TestProcessArguments(c *C) {
 	cases := []struct {
@@ -64,6 +77,10 @@ func (s *bootstrapSuite) TestProcessArguments(c *C) {
 		{[]string{"argv0", "invalid-"}, "", false, false, "snap name cannot end with a dash"},
 		{[]string{"argv0", "@invalid"}, "", false, false, "snap name must use lower case letters, digits or dashes"},
 		{[]string{"argv0", "INVALID"}, "", false, false, "snap name must use lower case letters, digits or dashes"},
+		// Snap instance names are parsed and validated correctly.
+		{[]string{"argv0", "snapname_foo"}, "snapname_foo", true, false, ""},
+		{[]string{"argv0", "snapname_foo_bar"}, "", false, false, "snap instance name can contain only one underscore"},
+		{[]string{"argv0", "snapname_"}, "", false, false, "instance key must contain at least one letter or digit"},
 		// The option --from-snap-confine disables setns.
 		{[]string{"argv0", "--from-snap-confine", "snapname"}, "snapname", false, false, ""},
 		{[]string{"argv0", "snapname", "--from-snap-confine"}, "snapname", false, false, ""},
@@ -30,8 +30,9 @@ import (
 
 var (
 	// change
-	ValidateSnapName = validateSnapName
-	ProcessArguments = processArguments
+	ValidateSnapName     = validateSnapName
+	ValidateInstanceName = validateInstanceName
+	ProcessArguments     = processArguments
 	// freezer
 	FreezeSnapProcesses = freezeSnapProcesses
 	ThawSnapProcesses   = thawSnapProcesses
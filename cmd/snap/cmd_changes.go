@@ -170,7 +170,12 @@ func (c *cmdTasks) showChange(cli *client.Client, chid string) error {
 		if t.Status == "Doing" && t.Progress.Total > 1 {
 			summary = fmt.Sprintf("%s (%.2f%%)", summary, float64(t.Progress.Done)/float64(t.Progress.Total)*100.0)
 		}
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", t.Status, spawnTime, readyTime, summary)
+		status := t.Status
+		if t.Queued {
+			// ready to run, waiting for other tasks to finish
+			status = "Queued"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", status, spawnTime, readyTime, summary)
 	}
 
 	w.Flush()
@@ -69,6 +69,22 @@ Do +2016-04-21T01:02:03Z +2016-04-21T01:02:04Z +some summary
 	c.Check(s.Stderr(), check.Equals, "")
 }
 
+func (s *SnapSuite) TestChangeQueued(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v2/changes/42")
+		fmt.Fprintln(w, strings.Replace(mockChangeJSON, `"status": "Do", "progress"`, `"status": "Do", "queued": true, "progress"`, 1))
+	})
+	expectedChange := `(?ms)Status +Spawn +Ready +Summary
+Queued +2016-04-21T01:02:03Z +2016-04-21T01:02:04Z +some summary
+`
+	rest, err := snap.Parser().ParseArgs([]string{"tasks", "--abs-time", "42"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Matches, expectedChange)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
 func (s *SnapSuite) TestChangeSimpleRebooting(c *check.C) {
 	n := 0
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
@@ -30,6 +30,7 @@ import (
 
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/strutil"
 )
 
@@ -53,9 +54,17 @@ func init() {
 
 type snapsByName []*client.Snap
 
-func (s snapsByName) Len() int           { return len(s) }
-func (s snapsByName) Less(i, j int) bool { return s[i].Name < s[j].Name }
-func (s snapsByName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s snapsByName) Len() int      { return len(s) }
+func (s snapsByName) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s snapsByName) Less(i, j int) bool {
+	// list the instances of a snap right after it
+	iName, iKey := snap.SplitInstanceName(s[i].Name)
+	jName, jKey := snap.SplitInstanceName(s[j].Name)
+	if iName == jName {
+		return iKey < jKey
+	}
+	return iName < jName
+}
 
 func (x *cmdList) Execute(args []string) error {
 	if len(args) > 0 {
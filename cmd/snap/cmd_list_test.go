@@ -66,6 +66,38 @@ foo +4.2 +17 +potatoes +bar +-
 	c.Check(s.Stderr(), check.Equals, "")
 }
 
+func (s *SnapSuite) TestListWithInstances(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/snaps")
+			c.Check(r.URL.RawQuery, check.Equals, "")
+			fmt.Fprintln(w, `{"type": "sync", "result": [
+{"name": "foo-bar", "status": "active", "version": "1.0", "developer": "bar", "revision":3, "tracking-channel": "stable"},
+{"name": "foo_staging", "status": "active", "version": "4.3", "developer": "bar", "revision":18, "tracking-channel": "beta"},
+{"name": "foo", "status": "active", "version": "4.2", "developer": "bar", "revision":17, "tracking-channel": "stable"}
+]}`)
+		default:
+			c.Fatalf("expected to get 1 requests, now on %d", n+1)
+		}
+
+		n++
+	})
+	rest, err := snap.Parser().ParseArgs([]string{"list"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	// instances are listed under their instance name, right after
+	// the snap they are an instance of
+	c.Check(s.Stdout(), check.Matches, `Name +Version +Rev +Tracking +Developer +Notes
+foo +4.2 +17 +stable +bar +-
+foo_staging +4.3 +18 +beta +bar +-
+foo-bar +1.0 +3 +stable +bar +-
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
 func (s *SnapSuite) TestListAll(c *check.C) {
 	n := 0
 	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
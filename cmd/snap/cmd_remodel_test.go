@@ -0,0 +1,81 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+const remodelModel = "type: model\n..."
+
+func (s *SnapSuite) TestRemodel(c *C) {
+	newModelFile := filepath.Join(c.MkDir(), "new-model")
+	err := ioutil.WriteFile(newModelFile, []byte(remodelModel), 0644)
+	c.Assert(err, IsNil)
+
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, Equals, "POST")
+			c.Check(r.URL.Path, Equals, "/v2/model")
+			c.Check(DecodedRequestBody(c, r), DeepEquals, map[string]interface{}{
+				"new-model": remodelModel,
+			})
+			fmt.Fprintln(w, `{"type":"async", "status-code": 202, "change": "101"}`)
+		case 1:
+			c.Check(r.Method, Equals, "GET")
+			c.Check(r.URL.Path, Equals, "/v2/changes/101")
+			fmt.Fprintln(w, `{"type":"sync", "result":{"ready": true, "status": "Done"}}`)
+		default:
+			c.Fatalf("expected to get 2 requests, now on %d", n+1)
+		}
+
+		n++
+	})
+
+	rest, err := snap.Parser().ParseArgs([]string{"remodel", newModelFile})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(s.Stdout(), Equals, fmt.Sprintf("New model %s set\n", newModelFile))
+	c.Check(s.Stderr(), Equals, "")
+	c.Check(n, Equals, 2)
+}
+
+func (s *SnapSuite) TestRemodelError(c *C) {
+	newModelFile := filepath.Join(c.MkDir(), "new-model")
+	err := ioutil.WriteFile(newModelFile, []byte(remodelModel), 0644)
+	c.Assert(err, IsNil)
+
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(400)
+		fmt.Fprintln(w, `{"type": "error", "result": {"message": "cannot remodel device: boom"}, "status-code": 400}`)
+	})
+
+	_, err = snap.Parser().ParseArgs([]string{"remodel", newModelFile})
+	c.Assert(err, ErrorMatches, "cannot remodel: cannot remodel device: boom")
+}
@@ -41,6 +41,7 @@ import (
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/runinhibit"
 	"github.com/snapcore/snapd/snap/snapenv"
 	"github.com/snapcore/snapd/strutil/shlex"
 	"github.com/snapcore/snapd/timeutil"
@@ -337,8 +338,36 @@ func (x *cmdRun) straceOpts() (opts []string, raw bool, err error) {
 	return opts, raw, nil
 }
 
+// runInhibitPollTime is how often snap run checks whether apps of a
+// snap that is being changed by snapd can be started again.
+var runInhibitPollTime = 500 * time.Millisecond
+
+// waitWhileInhibited blocks while snapd inhibits running apps of the
+// given snap, e.g. in the middle of a refresh.
+func waitWhileInhibited(snapName string) error {
+	notified := false
+	for {
+		hint, err := runinhibit.IsLocked(snapName)
+		if err != nil {
+			return err
+		}
+		if hint == runinhibit.HintNotInhibited {
+			return nil
+		}
+		if !notified {
+			// TRANSLATORS: %q is a snap name
+			fmt.Fprintf(Stderr, i18n.G("snap package %q is being refreshed, please wait\n"), snapName)
+			notified = true
+		}
+		time.Sleep(runInhibitPollTime)
+	}
+}
+
 func (x *cmdRun) snapRunApp(snapApp string, args []string) error {
 	snapName, appName := snap.SplitSnapApp(snapApp)
+	if err := waitWhileInhibited(snapName); err != nil {
+		return err
+	}
 	info, err := getSnapInfo(snapName, snap.R(0))
 	if err != nil {
 		return err
@@ -34,6 +34,7 @@ import (
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/runinhibit"
 	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/testutil"
 	"github.com/snapcore/snapd/x11"
@@ -136,6 +137,40 @@ func (s *SnapSuite) TestSnapRunAppIntegration(c *check.C) {
 	c.Check(execEnv, testutil.Contains, "SNAP_REVISION=x2")
 }
 
+func (s *SnapSuite) TestSnapRunAppWaitsWhileInhibited(c *check.C) {
+	defer mockSnapConfine(dirs.DistroLibExecDir)()
+	defer snaprun.MockRunInhibitPollTime(time.Millisecond)()
+
+	// mock installed snap
+	snaptest.MockSnapCurrent(c, string(mockYaml), &snap.SideInfo{
+		Revision: snap.R("x2"),
+	})
+
+	c.Assert(runinhibit.LockWithHint("snapname", runinhibit.HintInhibitedForRefresh), check.IsNil)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		time.Sleep(50 * time.Millisecond)
+		c.Check(runinhibit.Unlock("snapname"), check.IsNil)
+	}()
+
+	execCalled := false
+	restorer := snaprun.MockSyscallExec(func(arg0 string, args []string, envv []string) error {
+		hint, err := runinhibit.IsLocked("snapname")
+		c.Check(err, check.IsNil)
+		c.Check(hint, check.Equals, runinhibit.HintNotInhibited)
+		execCalled = true
+		return nil
+	})
+	defer restorer()
+
+	_, err := snaprun.Parser().ParseArgs([]string{"run", "snapname.app"})
+	c.Assert(err, check.IsNil)
+	<-done
+	c.Check(execCalled, check.Equals, true)
+	c.Check(s.Stderr(), check.Equals, "snap package \"snapname\" is being refreshed, please wait\n")
+}
+
 func (s *SnapSuite) TestSnapRunClassicAppIntegration(c *check.C) {
 	defer mockSnapConfine(dirs.DistroLibExecDir)()
 
@@ -20,8 +20,10 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/jessevdk/go-flags"
 
@@ -38,6 +40,9 @@ type svcStatus struct {
 type svcLogs struct {
 	N          string `short:"n" default:"10"`
 	Follow     bool   `short:"f"`
+	Since      string `long:"since"`
+	Priority   string `long:"priority"`
+	JSON       bool   `long:"json"`
 	Positional struct {
 		ServiceNames []serviceName `required:"1"`
 	} `positional-args:"yes" required:"yes"`
@@ -80,8 +85,11 @@ func init() {
 	addCommand("services", shortServicesHelp, longServicesHelp, func() flags.Commander { return &svcStatus{} }, nil, argdescs)
 	addCommand("logs", shortLogsHelp, longLogsHelp, func() flags.Commander { return &svcLogs{} },
 		map[string]string{
-			"n": i18n.G("Show only the given number of lines, or 'all'."),
-			"f": i18n.G("Wait for new lines and print them as they come in."),
+			"n":        i18n.G("Show only the given number of lines, or 'all'."),
+			"f":        i18n.G("Wait for new lines and print them as they come in."),
+			"since":    i18n.G("Show only lines logged since the given time (like 2018-03-01T10:00:00Z) or duration ago (like 2h)."),
+			"priority": i18n.G("Show only lines of the given priority or more important (emerg, alert, crit, err, warning, notice, info, debug, or 0 to 7)."),
+			"json":     i18n.G("Print each line as JSON, including all the journal fields."),
 		}, argdescs)
 
 	addCommand("start", shortStartHelp, longStartHelp, func() flags.Commander { return &svcStart{} },
@@ -127,7 +135,10 @@ func (s *svcStatus) Execute(args []string) error {
 			startup = i18n.G("enabled")
 		}
 		current := i18n.G("inactive")
-		if svc.Active {
+		if svc.DaemonScope == "user" {
+			// user services run in each user session
+			current = "-"
+		} else if svc.Active {
 			current = i18n.G("active")
 		}
 		fmt.Fprintf(w, "%s.%s\t%s\t%s\n", svc.Snap, svc.Name, startup, current)
@@ -150,18 +161,52 @@ func (s *svcLogs) Execute(args []string) error {
 		sN = int(n)
 	}
 
-	logs, err := Client().Logs(svcNames(s.Positional.ServiceNames), client.LogOptions{N: sN, Follow: s.Follow})
+	opts := client.LogOptions{
+		N:         sN,
+		Follow:    s.Follow,
+		Priority:  s.Priority,
+		AllFields: s.JSON,
+	}
+	if s.Since != "" {
+		since, err := parseLogsSince(s.Since)
+		if err != nil {
+			return err
+		}
+		opts.Since = since
+	}
+
+	logs, err := Client().Logs(svcNames(s.Positional.ServiceNames), opts)
 	if err != nil {
 		return err
 	}
 
+	enc := json.NewEncoder(Stdout)
 	for log := range logs {
+		if s.JSON {
+			if err := enc.Encode(log); err != nil {
+				return err
+			}
+			continue
+		}
 		fmt.Fprintln(Stdout, log)
 	}
 
 	return nil
 }
 
+// parseLogsSince parses the argument to --since, which is either a
+// timestamp or a duration to go back from now.
+func parseLogsSince(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf(i18n.G("cannot parse --since %q: expected a time like 2018-03-01T10:00:00Z or a duration like 2h"), s)
+	}
+	return timeNow().Add(-d), nil
+}
+
 type svcStart struct {
 	waitMixin
 	Positional struct {
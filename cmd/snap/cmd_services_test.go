@@ -22,6 +22,7 @@ package main_test
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"time"
 
 	"gopkg.in/check.v1"
@@ -169,3 +170,58 @@ func (s *appOpSuite) TestAppOps(c *check.C) {
 		}
 	}
 }
+
+func (s *appOpSuite) TestLogsFilters(c *check.C) {
+	restore := snap.MockTimeNow(func() time.Time {
+		return time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC)
+	})
+	defer restore()
+
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v2/logs")
+		c.Check(r.URL.Query(), check.DeepEquals, url.Values{
+			"names":    {"foo.bar"},
+			"n":        {"10"},
+			"since":    {"2018-03-01T10:00:00Z"},
+			"priority": {"err"},
+		})
+		w.Header().Set("Content-Type", "application/json-seq")
+		fmt.Fprint(w, "\x1e"+`{"timestamp":"2018-03-01T10:30:00Z","message":"oops","sid":"foo.bar","pid":"42"}`+"\n")
+		n++
+	})
+
+	rest, err := snap.Parser().ParseArgs([]string{"logs", "--since", "2h", "--priority", "err", "foo.bar"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(n, check.Equals, 1)
+	c.Check(s.Stdout(), check.Equals, "2018-03-01T10:30:00Z foo.bar[42]: oops\n")
+	c.Check(s.Stderr(), check.Equals, "")
+}
+
+func (s *appOpSuite) TestLogsJSON(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Query(), check.DeepEquals, url.Values{
+			"names":  {"foo"},
+			"n":      {"10"},
+			"since":  {"2018-03-01T10:00:00Z"},
+			"fields": {"all"},
+		})
+		w.Header().Set("Content-Type", "application/json-seq")
+		fmt.Fprint(w, "\x1e"+`{"timestamp":"2018-03-01T10:30:00Z","message":"oops","sid":"foo.bar","pid":"42","cursor":"c1","fields":{"MESSAGE":"oops"}}`+"\n")
+	})
+
+	_, err := snap.Parser().ParseArgs([]string{"logs", "--json", "--since", "2018-03-01T10:00:00Z", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `{"timestamp":"2018-03-01T10:30:00Z","message":"oops","sid":"foo.bar","pid":"42","cursor":"c1","fields":{"MESSAGE":"oops"}}`+"\n")
+}
+
+func (s *appOpSuite) TestLogsBadSince(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request")
+	})
+
+	_, err := snap.Parser().ParseArgs([]string{"logs", "--since", "yesterday", "foo"})
+	c.Assert(err, check.ErrorMatches, `cannot parse --since "yesterday": .*`)
+}
@@ -41,6 +41,11 @@ snap's configuration hook returns successfully.
 Nested values may be modified via a dotted path:
 
     $ snap set author.name=frank
+
+Configuration options may be removed by appending an exclamation mark to
+their name:
+
+    $ snap set snap-name author!
 `)
 
 type cmdSet struct {
@@ -70,6 +75,10 @@ func (x *cmdSet) Execute(args []string) error {
 	patchValues := make(map[string]interface{})
 	for _, patchValue := range x.Positional.ConfValues {
 		parts := strings.SplitN(patchValue, "=", 2)
+		if len(parts) == 1 && strings.HasSuffix(patchValue, "!") {
+			patchValues[strings.TrimSuffix(patchValue, "!")] = nil
+			continue
+		}
 		if len(parts) != 2 {
 			return fmt.Errorf(i18n.G("invalid configuration: %q (want key=value)"), patchValue)
 		}
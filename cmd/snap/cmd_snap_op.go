@@ -64,6 +64,11 @@ The remove command removes the named snap from the system.
 By default all the snap revisions are removed, including their data and the
 common data directory. When a --revision option is passed only the specified
 revision is removed.
+
+Unless the --purge option is passed, a snapshot of the data of the snap is
+saved before it is removed (see 'snap help saved'). How long these automatic
+snapshots are kept is controlled by the snapshots.automatic.retention system
+option.
 `)
 
 var longRefreshHelp = i18n.G(`
@@ -78,6 +83,11 @@ have developer access to the snap, either directly or through the
 store's collaboration feature, and to be logged in (see 'snap help login').
 
 Note a later refresh will typically undo a revision override.
+
+The --hold option holds automatic refreshes of the given snap, either
+indefinitely or for the given duration (for example --hold=72h). Held snaps
+can still be refreshed explicitly by name. The --unhold option lifts a
+previous hold.
 `)
 
 var longTryHelp = i18n.G(`
@@ -105,6 +115,7 @@ type cmdRemove struct {
 	waitMixin
 
 	Revision   string `long:"revision"`
+	Purge      bool   `long:"purge"`
 	Positional struct {
 		Snaps []installedSnapName `positional-arg-name:"<snap>" required:"1"`
 	} `positional-args:"yes" required:"yes"`
@@ -174,7 +185,7 @@ func (x *cmdRemove) removeMany(opts *client.SnapOptions) error {
 }
 
 func (x *cmdRemove) Execute([]string) error {
-	opts := &client.SnapOptions{Revision: x.Revision}
+	opts := &client.SnapOptions{Revision: x.Revision, Purge: x.Purge}
 	if len(x.Positional.Snaps) == 1 {
 		return x.removeOne(opts)
 	}
@@ -182,6 +193,9 @@ func (x *cmdRemove) Execute([]string) error {
 	if x.Revision != "" {
 		return errors.New(i18n.G("a single snap name is needed to specify the revision"))
 	}
+	if x.Purge {
+		return errors.New(i18n.G("a single snap name is needed to specify --purge"))
+	}
 	return x.removeMany(nil)
 }
 
@@ -483,6 +497,8 @@ type cmdRefresh struct {
 	List             bool   `long:"list"`
 	Time             bool   `long:"time"`
 	IgnoreValidation bool   `long:"ignore-validation"`
+	Hold             string `long:"hold" optional:"yes" optional-value:"forever"`
+	Unhold           bool   `long:"unhold"`
 	Positional       struct {
 		Snaps []installedSnapName `positional-arg-name:"<snap>"`
 	} `positional-args:"yes"`
@@ -596,6 +612,39 @@ func (x *cmdRefresh) listRefresh() error {
 	return nil
 }
 
+func (x *cmdRefresh) holdRefresh(name string) error {
+	cli := Client()
+	if x.Unhold {
+		if err := cli.UnholdRefresh(name); err != nil {
+			return err
+		}
+		// TRANSLATORS: %q is a snap name
+		fmt.Fprintf(Stdout, i18n.G("Automatic refreshes of %q are no longer held\n"), name)
+		return nil
+	}
+
+	if x.Hold == "forever" {
+		if err := cli.HoldRefresh(name, time.Time{}); err != nil {
+			return err
+		}
+		// TRANSLATORS: %q is a snap name
+		fmt.Fprintf(Stdout, i18n.G("Automatic refreshes of %q held indefinitely\n"), name)
+		return nil
+	}
+
+	d, err := time.ParseDuration(x.Hold)
+	if err != nil || d <= 0 {
+		return fmt.Errorf(i18n.G("cannot parse hold duration %q: expected a positive duration like 72h"), x.Hold)
+	}
+	until := time.Now().Add(d)
+	if err := cli.HoldRefresh(name, until); err != nil {
+		return err
+	}
+	// TRANSLATORS: %q is a snap name, %s is a time
+	fmt.Fprintf(Stdout, i18n.G("Automatic refreshes of %q held until %s\n"), name, x.fmtTime(until))
+	return nil
+}
+
 func (x *cmdRefresh) Execute([]string) error {
 	if err := x.setChannelFromCommandline(); err != nil {
 		return err
@@ -604,6 +653,19 @@ func (x *cmdRefresh) Execute([]string) error {
 		return err
 	}
 
+	if x.Hold != "" || x.Unhold {
+		if x.Hold != "" && x.Unhold {
+			return errors.New(i18n.G("cannot use --hold and --unhold together"))
+		}
+		if x.asksForMode() || x.asksForChannel() || x.Time || x.List || x.Amend || x.Revision != "" || x.IgnoreValidation {
+			return errors.New(i18n.G("--hold and --unhold do not take other flags"))
+		}
+		if len(x.Positional.Snaps) != 1 {
+			return errors.New(i18n.G("a single snap name is needed to hold or unhold refreshes"))
+		}
+		return x.holdRefresh(string(x.Positional.Snaps[0]))
+	}
+
 	if x.Time {
 		if x.asksForMode() || x.asksForChannel() {
 			return errors.New(i18n.G("--time does not take mode nor channel flags"))
@@ -890,7 +952,10 @@ func (x cmdSwitch) Execute(args []string) error {
 
 func init() {
 	addCommand("remove", shortRemoveHelp, longRemoveHelp, func() flags.Commander { return &cmdRemove{} },
-		waitDescs.also(map[string]string{"revision": i18n.G("Remove only the given revision")}), nil)
+		waitDescs.also(map[string]string{
+			"revision": i18n.G("Remove only the given revision"),
+			"purge":    i18n.G("Remove the snap without saving a snapshot of its data"),
+		}), nil)
 	addCommand("install", shortInstallHelp, longInstallHelp, func() flags.Commander { return &cmdInstall{} },
 		waitDescs.also(channelDescs).also(modeDescs).also(map[string]string{
 			"revision":        i18n.G("Install the given revision of a snap, to which you must have developer access"),
@@ -905,6 +970,10 @@ func init() {
 			"list":              i18n.G("Show available snaps for refresh but do not perform a refresh"),
 			"time":              i18n.G("Show auto refresh information but do not perform a refresh"),
 			"ignore-validation": i18n.G("Ignore validation by other snaps blocking the refresh"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"hold": i18n.G("Hold automatic refreshes of the snap, indefinitely or for the given duration"),
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"unhold": i18n.G("Lift a hold on automatic refreshes of the snap"),
 		}), nil)
 	addCommand("try", shortTryHelp, longTryHelp, func() flags.Commander { return &cmdTry{} }, waitDescs.also(modeDescs), nil)
 	addCommand("enable", shortEnableHelp, longEnableHelp, func() flags.Commander { return &cmdEnable{} }, waitDescs, nil)
@@ -686,6 +686,82 @@ func (s *SnapOpSuite) TestRefreshOne(c *check.C) {
 
 }
 
+func (s *SnapOpSuite) TestRefreshHoldForever(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps/foo")
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action": "hold",
+		})
+		fmt.Fprintln(w, `{"type": "sync", "result": null}`)
+	})
+	rest, err := snap.Parser().ParseArgs([]string{"refresh", "--hold", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, "Automatic refreshes of \"foo\" held indefinitely\n")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapOpSuite) TestRefreshHoldDuration(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps/foo")
+		body := DecodedRequestBody(c, r)
+		c.Check(body["action"], check.Equals, "hold")
+		until, err := time.Parse(time.RFC3339, body["hold-until"].(string))
+		c.Assert(err, check.IsNil)
+		c.Check(until.After(time.Now().Add(71*time.Hour)), check.Equals, true)
+		c.Check(until.Before(time.Now().Add(73*time.Hour)), check.Equals, true)
+		fmt.Fprintln(w, `{"type": "sync", "result": null}`)
+	})
+	_, err := snap.Parser().ParseArgs([]string{"refresh", "--hold=72h", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Matches, `Automatic refreshes of "foo" held until .*\n`)
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapOpSuite) TestRefreshUnhold(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Method, check.Equals, "POST")
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps/foo")
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action": "unhold",
+		})
+		fmt.Fprintln(w, `{"type": "sync", "result": null}`)
+	})
+	_, err := snap.Parser().ParseArgs([]string{"refresh", "--unhold", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "Automatic refreshes of \"foo\" are no longer held\n")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *SnapOpSuite) TestRefreshHoldUnhappy(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request to %s", r.URL.Path)
+	})
+	for _, t := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"--hold", "--unhold", "foo"}, `cannot use --hold and --unhold together`},
+		{[]string{"--hold", "--beta", "foo"}, `--hold and --unhold do not take other flags`},
+		{[]string{"--unhold", "--list"}, `--hold and --unhold do not take other flags`},
+		{[]string{"--hold"}, `a single snap name is needed to hold or unhold refreshes`},
+		{[]string{"--unhold", "foo", "bar"}, `a single snap name is needed to hold or unhold refreshes`},
+		{[]string{"--hold=soon", "foo"}, `cannot parse hold duration "soon": expected a positive duration like 72h`},
+		{[]string{"--hold=-1h", "foo"}, `cannot parse hold duration "-1h": expected a positive duration like 72h`},
+	} {
+		_, err := snap.Parser().ParseArgs(append([]string{"refresh"}, t.args...))
+		c.Check(err, check.ErrorMatches, t.err, check.Commentf("%v", t.args))
+	}
+}
+
 func (s *SnapOpSuite) TestRefreshOneSwitchChannel(c *check.C) {
 	s.RedirectClientToTestServer(s.srv.handle)
 	s.srv.checker = func(r *http.Request) {
@@ -1009,6 +1085,32 @@ func (s *SnapOpSuite) TestRemove(c *check.C) {
 	c.Check(s.srv.n, check.Equals, s.srv.total)
 }
 
+func (s *SnapOpSuite) TestRemovePurge(c *check.C) {
+	s.srv.total = 3
+	s.srv.checker = func(r *http.Request) {
+		c.Check(r.URL.Path, check.Equals, "/v2/snaps/foo")
+		c.Check(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action": "remove",
+			"purge":  true,
+		})
+	}
+
+	s.RedirectClientToTestServer(s.srv.handle)
+	rest, err := snap.Parser().ParseArgs([]string{"remove", "--purge", "foo"})
+	c.Assert(err, check.IsNil)
+	c.Assert(rest, check.DeepEquals, []string{})
+	c.Check(s.Stdout(), check.Matches, `(?sm).*foo removed`)
+	c.Check(s.Stderr(), check.Equals, "")
+	// ensure that the fake server api was actually hit
+	c.Check(s.srv.n, check.Equals, s.srv.total)
+}
+
+func (s *SnapOpSuite) TestRemoveManyPurge(c *check.C) {
+	s.RedirectClientToTestServer(nil)
+	_, err := snap.Parser().ParseArgs([]string{"remove", "--purge", "one", "two"})
+	c.Assert(err, check.ErrorMatches, `a single snap name is needed to specify --purge`)
+}
+
 func (s *SnapOpSuite) TestRemoveManyRevision(c *check.C) {
 	s.RedirectClientToTestServer(nil)
 	_, err := snap.Parser().ParseArgs([]string{"remove", "--revision=17", "one", "two"})
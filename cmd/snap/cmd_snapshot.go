@@ -0,0 +1,519 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/strutil"
+)
+
+var (
+	shortSavedHelp   = i18n.G("List currently stored snapshots")
+	shortSaveHelp    = i18n.G("Save a snapshot of the current data")
+	shortForgetHelp  = i18n.G("Delete a snapshot")
+	shortCheckHelp   = i18n.G("Check a snapshot")
+	shortRestoreHelp = i18n.G("Restore a snapshot")
+	shortExportHelp  = i18n.G("Export a snapshot to a file")
+	shortImportHelp  = i18n.G("Import a snapshot from a file")
+)
+
+var longSavedHelp = i18n.G(`
+The saved command displays a list of snapshots that have been created
+previously with the 'save' command.
+`)
+var longSaveHelp = i18n.G(`
+The save command creates a snapshot of the current user, system and
+configuration data for the given snaps.
+
+By default, this command saves the data of all snaps for all users.
+Alternatively, you can specify the data of which snaps to save, or
+for which users, or a combination of these.
+
+If a snap is included in a save operation, excluding its system and
+configuration data from the snapshot is not currently possible. This
+restriction may be lifted in the future.
+`)
+var longForgetHelp = i18n.G(`
+The forget command deletes a snapshot. This operation can not be
+undone.
+
+A snapshot contains archives for the user, system and configuration
+data of each snap included in the snapshot.
+
+By default, this command forgets all the data in a snapshot.
+Alternatively, you can specify the data of which snaps to forget.
+`)
+var longCheckHelp = i18n.G(`
+The check-snapshot command verifies the user, system and configuration
+data of the snaps included in the specified snapshot.
+
+The check operation runs the same data integrity verification that is
+performed when a snapshot is restored.
+
+By default, this command checks all the data in a snapshot.
+Alternatively, you can specify the data of which snaps to check, or
+for which users, or a combination of these.
+
+If a snap is included in a check-snapshot operation, excluding its
+system and configuration data from verification is not currently
+possible. This restriction may be lifted in the future.
+`)
+var longRestoreHelp = i18n.G(`
+The restore command replaces the current user, system and
+configuration data of included snaps, with the corresponding data from
+the specified snapshot.
+
+By default, this command restores all the data in a snapshot.
+Alternatively, you can specify the data of which snaps to restore, or
+for which users, or a combination of these.
+
+If a snap is included in a restore operation, excluding its system and
+configuration data from the restore is not currently possible. This
+restriction may be lifted in the future.
+`)
+var longExportHelp = i18n.G(`
+The export-snapshot command writes the specified snapshot, including
+the data of all the snaps in it, to a single file.
+
+The file can be moved to a different system, and added to its
+snapshots with the 'import-snapshot' command.
+`)
+var longImportHelp = i18n.G(`
+The import-snapshot command adds the snapshot in the given file, as
+created by the 'export-snapshot' command, to the snapshots of this
+system.
+
+The imported snapshot is given a new set id. Its data is verified
+before it is accepted.
+`)
+
+type snapshotID uint64
+
+func (snapshotID) Complete(match string) []flags.Completion {
+	shots, err := Client().SnapshotSets(0, nil)
+	if err != nil {
+		return nil
+	}
+	var ret []flags.Completion
+	for _, sg := range shots {
+		sid := strconv.FormatUint(sg.ID, 10)
+		if strings.HasPrefix(sid, match) {
+			ret = append(ret, flags.Completion{Item: sid})
+		}
+	}
+
+	return ret
+}
+
+// usersMixin is for commands that can act on the data of only some users.
+type usersMixin struct {
+	Users string `long:"users"`
+}
+
+var usersDescs = mixinDescs{
+	// TRANSLATORS: This should not start with a lowercase letter.
+	"users": i18n.G("Snapshot data of only specific users (comma-separated) (default: all users)"),
+}
+
+func (mx usersMixin) users() []string {
+	if mx.Users == "" {
+		return nil
+	}
+	users := strings.Split(mx.Users, ",")
+	for i := range users {
+		users[i] = strings.TrimSpace(users[i])
+	}
+	return users
+}
+
+type savedCmd struct {
+	timeMixin
+	ID         snapshotID `long:"id"`
+	Positional struct {
+		Snaps []installedSnapName `positional-arg-name:"<snap>"`
+	} `positional-args:"yes"`
+}
+
+func (x *savedCmd) Execute([]string) error {
+	setID := uint64(x.ID)
+	snaps := installedSnapNames(x.Positional.Snaps)
+	list, err := Client().SnapshotSets(setID, snaps)
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		fmt.Fprintln(Stdout, i18n.G("No snapshots found."))
+		return nil
+	}
+	w := tabWriter()
+	defer w.Flush()
+
+	fmt.Fprintf(w,
+		"%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+		// TRANSLATORS: 'Set' as in group or bag of things
+		i18n.G("Set"),
+		"Snap",
+		// TRANSLATORS: 'Time' as in the time the snapshot was taken
+		i18n.G("Time"),
+		i18n.G("Version"),
+		// TRANSLATORS: 'Rev' is an abbreviation of 'Revision'
+		i18n.G("Rev"),
+		i18n.G("Size"),
+		i18n.G("Notes"))
+	for _, sg := range list {
+		for _, sh := range sg.Snapshots {
+			notes := []string{}
+			if sh.Auto {
+				notes = append(notes, "auto")
+			}
+			if sh.Broken != "" {
+				notes = append(notes, "broken: "+sh.Broken)
+			}
+			note := "-"
+			if len(notes) > 0 {
+				note = strings.Join(notes, ", ")
+			}
+			size := strutil.SizeToStr(sh.Size)
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", sg.ID, sh.Snap, x.fmtTime(sh.Time), sh.Version, sh.Revision, size, note)
+		}
+	}
+	return nil
+}
+
+type saveCmd struct {
+	waitMixin
+	usersMixin
+	Positional struct {
+		Snaps []installedSnapName `positional-arg-name:"<snap>"`
+	} `positional-args:"yes"`
+}
+
+func (x *saveCmd) Execute([]string) error {
+	snaps := installedSnapNames(x.Positional.Snaps)
+	cli := Client()
+	setID, changeID, err := cli.SnapshotMany(snaps, x.users())
+	if err != nil {
+		return err
+	}
+	if _, err := x.wait(cli, changeID); err != nil {
+		if err == noWait {
+			return nil
+		}
+		return err
+	}
+
+	y := &savedCmd{
+		ID: snapshotID(setID),
+	}
+	y.Positional.Snaps = x.Positional.Snaps
+	return y.Execute(nil)
+}
+
+type forgetCmd struct {
+	waitMixin
+	Positional struct {
+		ID    snapshotID          `positional-arg-name:"<id>"`
+		Snaps []installedSnapName `positional-arg-name:"<snap>"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func (x *forgetCmd) Execute([]string) error {
+	setID := uint64(x.Positional.ID)
+	snaps := installedSnapNames(x.Positional.Snaps)
+	cli := Client()
+	changeID, err := cli.ForgetSnapshots(setID, snaps)
+	if err != nil {
+		return err
+	}
+	_, err = x.wait(cli, changeID)
+	if err == noWait {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(snaps) > 0 {
+		// TRANSLATORS: the %s is a comma-separated list of quoted snap names
+		fmt.Fprintf(Stdout, i18n.NG("Snapshot #%d of snap %s forgotten.\n", "Snapshot #%d of snaps %s forgotten.\n", len(snaps)), setID, strutil.Quoted(snaps))
+	} else {
+		fmt.Fprintf(Stdout, i18n.G("Snapshot #%d forgotten.\n"), setID)
+	}
+	return nil
+}
+
+type checkSnapshotCmd struct {
+	waitMixin
+	usersMixin
+	Positional struct {
+		ID    snapshotID          `positional-arg-name:"<id>"`
+		Snaps []installedSnapName `positional-arg-name:"<snap>"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func (x *checkSnapshotCmd) Execute([]string) error {
+	setID := uint64(x.Positional.ID)
+	snaps := installedSnapNames(x.Positional.Snaps)
+	users := x.users()
+	cli := Client()
+	changeID, err := cli.CheckSnapshots(setID, snaps, users)
+	if err != nil {
+		return err
+	}
+	_, err = x.wait(cli, changeID)
+	if err == noWait {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// TODO: also mention the home archives that were actually checked
+	if len(snaps) > 0 {
+		// TRANSLATORS: the %s is a comma-separated list of quoted snap names
+		fmt.Fprintf(Stdout, i18n.G("Snapshot #%d of snaps %s verified successfully.\n"),
+			setID, strutil.Quoted(snaps))
+	} else {
+		fmt.Fprintf(Stdout, i18n.G("Snapshot #%d verified successfully.\n"), setID)
+	}
+	return nil
+}
+
+type restoreCmd struct {
+	waitMixin
+	usersMixin
+	Positional struct {
+		ID    snapshotID          `positional-arg-name:"<id>"`
+		Snaps []installedSnapName `positional-arg-name:"<snap>"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func (x *restoreCmd) Execute([]string) error {
+	setID := uint64(x.Positional.ID)
+	snaps := installedSnapNames(x.Positional.Snaps)
+	users := x.users()
+	cli := Client()
+	changeID, err := cli.RestoreSnapshots(setID, snaps, users)
+	if err != nil {
+		return err
+	}
+	_, err = x.wait(cli, changeID)
+	if err == noWait {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// TODO: also mention the home archives that were actually restored
+	if len(snaps) > 0 {
+		// TRANSLATORS: the %s is a comma-separated list of quoted snap names
+		fmt.Fprintf(Stdout, i18n.G("Restored snapshot #%d of snaps %s.\n"),
+			setID, strutil.Quoted(snaps))
+	} else {
+		fmt.Fprintf(Stdout, i18n.G("Restored snapshot #%d.\n"), setID)
+	}
+	return nil
+}
+
+type exportSnapshotCmd struct {
+	Positional struct {
+		ID       snapshotID     `positional-arg-name:"<id>"`
+		Filename flags.Filename `positional-arg-name:"<filename>"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func (x *exportSnapshotCmd) Execute([]string) error {
+	setID := uint64(x.Positional.ID)
+	filename := string(x.Positional.Filename)
+
+	r, err := Client().SnapshotExport(setID)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	aw, err := osutil.NewAtomicFile(filename, 0600, 0, osutil.NoChown, osutil.NoChown)
+	if err != nil {
+		return err
+	}
+	// Cancel is a NOP once committed
+	defer aw.Cancel()
+
+	if _, err := io.Copy(aw, r); err != nil {
+		return fmt.Errorf(i18n.G("cannot export snapshot #%d: %v"), setID, err)
+	}
+	if err := aw.Commit(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(Stdout, i18n.G("Exported snapshot #%d into %q\n"), setID, filename)
+	return nil
+}
+
+type importSnapshotCmd struct {
+	Positional struct {
+		Filename flags.Filename `positional-arg-name:"<filename>"`
+	} `positional-args:"yes" required:"yes"`
+}
+
+func (x *importSnapshotCmd) Execute([]string) error {
+	filename := string(x.Positional.Filename)
+	f, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf(i18n.G("cannot open snapshot file: %v"), err)
+	}
+	defer f.Close()
+
+	importSet, err := Client().SnapshotImport(f)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(Stdout, i18n.G("Imported snapshot as #%d\n"), importSet.ID)
+	y := &savedCmd{
+		ID: snapshotID(importSet.ID),
+	}
+	return y.Execute(nil)
+}
+
+func init() {
+	addCommand("saved",
+		shortSavedHelp,
+		longSavedHelp,
+		func() flags.Commander {
+			return &savedCmd{}
+		},
+		timeDescs.also(map[string]string{
+			"id": i18n.G("Show only a specific snapshot."),
+		}),
+		nil)
+
+	addCommand("save",
+		shortSaveHelp,
+		longSaveHelp,
+		func() flags.Commander {
+			return &saveCmd{}
+		}, waitDescs.also(usersDescs), nil)
+
+	addCommand("restore",
+		shortRestoreHelp,
+		longRestoreHelp,
+		func() flags.Commander {
+			return &restoreCmd{}
+		}, waitDescs.also(map[string]string{
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"users": i18n.G("Restore data of only specific users (comma-separated) (default: all users)"),
+		}), []argDesc{
+			{
+				// TRANSLATORS: This needs to be wrapped in <>s.
+				name: i18n.G("<id>"),
+				// TRANSLATORS: This should not start with a lowercase letter.
+				desc: i18n.G("Set id of snapshot to restore (see 'snap help saved')"),
+			}, {
+				// TRANSLATORS: This needs to be wrapped in <>s.
+				name: i18n.G("<snap>"),
+				// TRANSLATORS: This should not start with a lowercase letter.
+				desc: i18n.G("The snap for which data will be restored"),
+			},
+		})
+
+	addCommand("forget",
+		shortForgetHelp,
+		longForgetHelp,
+		func() flags.Commander {
+			return &forgetCmd{}
+		}, waitDescs, []argDesc{
+			{
+				// TRANSLATORS: This needs to be wrapped in <>s.
+				name: i18n.G("<id>"),
+				// TRANSLATORS: This should not start with a lowercase letter.
+				desc: i18n.G("Set id of snapshot to delete (see 'snap help saved')"),
+			}, {
+				// TRANSLATORS: This needs to be wrapped in <>s.
+				name: i18n.G("<snap>"),
+				// TRANSLATORS: This should not start with a lowercase letter.
+				desc: i18n.G("The snap for which data will be deleted"),
+			},
+		})
+
+	addCommand("check-snapshot",
+		shortCheckHelp,
+		longCheckHelp,
+		func() flags.Commander {
+			return &checkSnapshotCmd{}
+		}, waitDescs.also(map[string]string{
+			// TRANSLATORS: This should not start with a lowercase letter.
+			"users": i18n.G("Check data of only specific users (comma-separated) (default: all users)"),
+		}), []argDesc{
+			{
+				// TRANSLATORS: This needs to be wrapped in <>s.
+				name: i18n.G("<id>"),
+				// TRANSLATORS: This should not start with a lowercase letter.
+				desc: i18n.G("Set id of snapshot to verify (see 'snap help saved')"),
+			}, {
+				// TRANSLATORS: This needs to be wrapped in <>s.
+				name: i18n.G("<snap>"),
+				// TRANSLATORS: This should not start with a lowercase letter.
+				desc: i18n.G("The snap for which data will be verified"),
+			},
+		})
+	addCommand("export-snapshot",
+		shortExportHelp,
+		longExportHelp,
+		func() flags.Commander {
+			return &exportSnapshotCmd{}
+		}, nil, []argDesc{
+			{
+				// TRANSLATORS: This needs to be wrapped in <>s.
+				name: i18n.G("<id>"),
+				// TRANSLATORS: This should not start with a lowercase letter.
+				desc: i18n.G("Set id of snapshot to export (see 'snap help saved')"),
+			}, {
+				// TRANSLATORS: This needs to be wrapped in <>s.
+				name: i18n.G("<filename>"),
+				// TRANSLATORS: This should not start with a lowercase letter.
+				desc: i18n.G("The file to export the snapshot to"),
+			},
+		})
+
+	addCommand("import-snapshot",
+		shortImportHelp,
+		longImportHelp,
+		func() flags.Commander {
+			return &importSnapshotCmd{}
+		}, nil, []argDesc{
+			{
+				// TRANSLATORS: This needs to be wrapped in <>s.
+				name: i18n.G("<filename>"),
+				// TRANSLATORS: This should not start with a lowercase letter.
+				desc: i18n.G("The exported snapshot file to import"),
+			},
+		})
+}
@@ -0,0 +1,299 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	snap "github.com/snapcore/snapd/cmd/snap"
+	"github.com/snapcore/snapd/osutil"
+)
+
+type snapshotSuite struct {
+	BaseSnapSuite
+
+	restoreAll func()
+}
+
+var _ = check.Suite(&snapshotSuite{})
+
+func (s *snapshotSuite) SetUpTest(c *check.C) {
+	s.BaseSnapSuite.SetUpTest(c)
+
+	restoreClientRetry := client.MockDoRetry(time.Millisecond, 10*time.Millisecond)
+	restorePollTime := snap.MockPollTime(time.Millisecond)
+	s.restoreAll = func() {
+		restoreClientRetry()
+		restorePollTime()
+	}
+}
+
+func (s *snapshotSuite) TearDownTest(c *check.C) {
+	s.restoreAll()
+	s.BaseSnapSuite.TearDownTest(c)
+}
+
+const snapshotSetsJSON = `{"type": "sync", "status-code": 200, "result": [{"id": 1, "snapshots": [{"set": 1, "time": "2018-07-21T09:00:00Z", "snap": "htop", "revision": "1168", "version": "2.0.2", "size": 1024, "sha3-384": {"archive.tgz": "..."}}]}]}`
+
+// mockSnapshotChange serves the given POST, then a change that is
+// done on its second poll, and then, if given, the snapshot list.
+func (s *snapshotSuite) mockSnapshotChange(c *check.C, path string, body map[string]interface{}, async string, listAfter bool) *int {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "POST")
+			c.Check(r.URL.Path, check.Equals, path)
+			c.Check(DecodedRequestBody(c, r), check.DeepEquals, body)
+			w.WriteHeader(202)
+			fmt.Fprintln(w, async)
+		case 1:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/changes/42")
+			fmt.Fprintln(w, `{"type": "sync", "result": {"status": "Doing"}}`)
+		case 2:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/changes/42")
+			fmt.Fprintln(w, `{"type": "sync", "result": {"ready": true, "status": "Done"}}`)
+		case 3:
+			if !listAfter {
+				c.Fatalf("expected to get 3 requests, now on %d", n+1)
+			}
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/snapshots")
+			c.Check(r.URL.Query().Get("set"), check.Equals, "1")
+			fmt.Fprintln(w, snapshotSetsJSON)
+		default:
+			c.Fatalf("unexpected request %d", n+1)
+		}
+
+		n++
+	})
+	return &n
+}
+
+func (s *snapshotSuite) TestSaved(c *check.C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v2/snapshots")
+		c.Check(r.URL.Query().Get("snaps"), check.Equals, "htop")
+		fmt.Fprintln(w, snapshotSetsJSON)
+		n++
+	})
+
+	rest, err := snap.Parser().ParseArgs([]string{"saved", "--abs-time", "htop"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, `Set  Snap  Time                  Version  Rev   Size  Notes
+1    htop  2018-07-21T09:00:00Z  2.0.2    1168  1kB   -
+`)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 1)
+}
+
+func (s *snapshotSuite) TestSavedAuto(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, strings.Replace(snapshotSetsJSON, `"size": 1024,`, `"size": 1024, "auto": true,`, 1))
+	})
+
+	_, err := snap.Parser().ParseArgs([]string{"saved", "--abs-time"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, `Set  Snap  Time                  Version  Rev   Size  Notes
+1    htop  2018-07-21T09:00:00Z  2.0.2    1168  1kB   auto
+`)
+}
+
+func (s *snapshotSuite) TestSavedNone(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": []}`)
+	})
+
+	_, err := snap.Parser().ParseArgs([]string{"saved"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "No snapshots found.\n")
+}
+
+func (s *snapshotSuite) TestSave(c *check.C) {
+	n := s.mockSnapshotChange(c, "/v2/snaps", map[string]interface{}{
+		"action": "snapshot",
+		"snaps":  []interface{}{"htop"},
+		"users":  []interface{}{"foo", "bar"},
+	}, `{"type": "async", "status-code": 202, "change": "42", "result": {"set-id": 1}}`, true)
+
+	rest, err := snap.Parser().ParseArgs([]string{"save", "--users=foo, bar", "htop"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(strings.HasPrefix(s.Stdout(), "Set  Snap  Time"), check.Equals, true)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(*n, check.Equals, 4)
+}
+
+func (s *snapshotSuite) TestSaveNoWait(c *check.C) {
+	n := s.mockSnapshotChange(c, "/v2/snaps", map[string]interface{}{
+		"action": "snapshot",
+	}, `{"type": "async", "status-code": 202, "change": "42", "result": {"set-id": 1}}`, false)
+
+	_, err := snap.Parser().ParseArgs([]string{"save", "--no-wait"})
+	c.Assert(err, check.IsNil)
+	c.Check(s.Stdout(), check.Equals, "42\n")
+	c.Check(*n, check.Equals, 1)
+}
+
+func (s *snapshotSuite) TestSnapshotOps(c *check.C) {
+	type table struct {
+		args     []string
+		body     map[string]interface{}
+		expected string
+	}
+	tests := []table{
+		{
+			args:     []string{"forget", "1"},
+			body:     map[string]interface{}{"set": json.Number("1"), "action": "forget"},
+			expected: "Snapshot #1 forgotten.\n",
+		}, {
+			args:     []string{"forget", "1", "htop"},
+			body:     map[string]interface{}{"set": json.Number("1"), "action": "forget", "snaps": []interface{}{"htop"}},
+			expected: "Snapshot #1 of snap \"htop\" forgotten.\n",
+		}, {
+			args:     []string{"check-snapshot", "1"},
+			body:     map[string]interface{}{"set": json.Number("1"), "action": "check"},
+			expected: "Snapshot #1 verified successfully.\n",
+		}, {
+			args:     []string{"check-snapshot", "--users=foo", "1", "htop", "top"},
+			body:     map[string]interface{}{"set": json.Number("1"), "action": "check", "snaps": []interface{}{"htop", "top"}, "users": []interface{}{"foo"}},
+			expected: "Snapshot #1 of snaps \"htop\", \"top\" verified successfully.\n",
+		}, {
+			args:     []string{"restore", "1"},
+			body:     map[string]interface{}{"set": json.Number("1"), "action": "restore"},
+			expected: "Restored snapshot #1.\n",
+		}, {
+			args:     []string{"restore", "1", "htop"},
+			body:     map[string]interface{}{"set": json.Number("1"), "action": "restore", "snaps": []interface{}{"htop"}},
+			expected: "Restored snapshot #1 of snaps \"htop\".\n",
+		},
+	}
+
+	for _, test := range tests {
+		comm := check.Commentf("%q", test.args)
+		s.ResetStdStreams()
+		n := s.mockSnapshotChange(c, "/v2/snapshots", test.body, `{"type": "async", "status-code": 202, "change": "42"}`, false)
+
+		rest, err := snap.Parser().ParseArgs(test.args)
+		c.Assert(err, check.IsNil, comm)
+		c.Check(rest, check.HasLen, 0, comm)
+		c.Check(s.Stdout(), check.Equals, test.expected, comm)
+		c.Check(s.Stderr(), check.Equals, "", comm)
+		c.Check(*n, check.Equals, 3, comm)
+	}
+}
+
+func (s *snapshotSuite) TestSnapshotOpsNeedID(c *check.C) {
+	s.RedirectClientToTestServer(nil)
+	for _, op := range []string{"forget", "check-snapshot", "restore"} {
+		_, err := snap.Parser().ParseArgs([]string{op})
+		c.Check(err, check.ErrorMatches, `.* required argument .* not provided`, check.Commentf(op))
+	}
+}
+
+func (s *snapshotSuite) TestExportSnapshot(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, check.Equals, "GET")
+		c.Check(r.URL.Path, check.Equals, "/v2/snapshots/1/export")
+		w.Header().Set("Content-Type", client.SnapshotExportContentType)
+		fmt.Fprint(w, "exported")
+	})
+
+	fn := filepath.Join(c.MkDir(), "out.snapshot")
+	rest, err := snap.Parser().ParseArgs([]string{"export-snapshot", "1", fn})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stdout(), check.Equals, fmt.Sprintf("Exported snapshot #1 into %q\n", fn))
+	c.Check(s.Stderr(), check.Equals, "")
+
+	buf, err := ioutil.ReadFile(fn)
+	c.Assert(err, check.IsNil)
+	c.Check(string(buf), check.Equals, "exported")
+}
+
+func (s *snapshotSuite) TestExportSnapshotNotFound(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(404)
+		fmt.Fprintln(w, `{"type": "error", "status-code": 404, "result": {"message": "no such set"}}`)
+	})
+
+	fn := filepath.Join(c.MkDir(), "out.snapshot")
+	_, err := snap.Parser().ParseArgs([]string{"export-snapshot", "1", fn})
+	c.Check(err, check.ErrorMatches, "no such set")
+	c.Check(osutil.FileExists(fn), check.Equals, false)
+}
+
+func (s *snapshotSuite) TestImportSnapshot(c *check.C) {
+	fn := filepath.Join(c.MkDir(), "in.snapshot")
+	c.Assert(ioutil.WriteFile(fn, []byte("exported"), 0600), check.IsNil)
+
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		switch n {
+		case 0:
+			c.Check(r.Method, check.Equals, "POST")
+			c.Check(r.URL.Path, check.Equals, "/v2/snapshots")
+			c.Check(r.Header.Get("Content-Type"), check.Equals, client.SnapshotExportContentType)
+			buf, err := ioutil.ReadAll(r.Body)
+			c.Assert(err, check.IsNil)
+			c.Check(string(buf), check.Equals, "exported")
+			fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": {"set-id": 1, "snaps": ["htop"]}}`)
+		case 1:
+			c.Check(r.Method, check.Equals, "GET")
+			c.Check(r.URL.Path, check.Equals, "/v2/snapshots")
+			c.Check(r.URL.Query().Get("set"), check.Equals, "1")
+			fmt.Fprintln(w, snapshotSetsJSON)
+		default:
+			c.Fatalf("unexpected request %d", n+1)
+		}
+		n++
+	})
+
+	rest, err := snap.Parser().ParseArgs([]string{"import-snapshot", fn})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(strings.HasPrefix(s.Stdout(), "Imported snapshot as #1\nSet  Snap  Time"), check.Equals, true)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(n, check.Equals, 2)
+}
+
+func (s *snapshotSuite) TestImportSnapshotNoFile(c *check.C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request")
+	})
+
+	_, err := snap.Parser().ParseArgs([]string{"import-snapshot", filepath.Join(c.MkDir(), "nope")})
+	c.Check(err, check.ErrorMatches, "cannot open snapshot file: .* no such file or directory")
+}
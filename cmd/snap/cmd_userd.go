@@ -33,8 +33,10 @@ import (
 
 type cmdUserd struct {
 	userd userd.Userd
+	agent userd.SessionAgent
 
 	Autostart bool `long:"autostart"`
+	Agent     bool `long:"agent"`
 }
 
 var shortUserdHelp = i18n.G("Start the userd service")
@@ -50,6 +52,7 @@ func init() {
 			return &cmdUserd{}
 		}, map[string]string{
 			"autostart": i18n.G("Autostart user applications"),
+			"agent":     i18n.G("Run the session agent that manages user services for snapd"),
 		}, nil)
 	cmd.hidden = true
 }
@@ -63,6 +66,10 @@ func (x *cmdUserd) Execute(args []string) error {
 		return x.runAutostart()
 	}
 
+	if x.Agent {
+		return x.runAgent()
+	}
+
 	if err := x.userd.Init(); err != nil {
 		return err
 	}
@@ -80,6 +87,24 @@ func (x *cmdUserd) Execute(args []string) error {
 	return x.userd.Stop()
 }
 
+func (x *cmdUserd) runAgent() error {
+	if err := x.agent.Init(); err != nil {
+		return err
+	}
+	x.agent.Start()
+
+	ch := make(chan os.Signal)
+	signal.Notify(ch, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case sig := <-ch:
+		fmt.Fprintf(Stdout, "Exiting on %s.\n", sig)
+	case <-x.agent.Dying():
+		// something called Stop(), or serving failed
+	}
+
+	return x.agent.Stop()
+}
+
 func (x *cmdUserd) runAutostart() error {
 	if err := userd.AutostartSessionApps(); err != nil {
 		return fmt.Errorf("autostart failed for the following apps:\n%v", err)
@@ -0,0 +1,199 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jessevdk/go-flags"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+)
+
+type cmdValidate struct {
+	Monitor bool `long:"monitor"`
+	Enforce bool `long:"enforce"`
+	Forget  bool `long:"forget"`
+
+	Positional struct {
+		ValidationSet string `positional-arg-name:"<validation set>"`
+	} `positional-args:"yes"`
+}
+
+var shortValidateHelp = i18n.G("List or apply validation sets")
+var longValidateHelp = i18n.G(`
+The validate command lists or applies validation sets. A validation set
+constrains which snaps, and at which revisions, should be present on the
+system.
+
+Without arguments it lists the validation sets tracked by the system.
+Given a validation set as account-id/name, it shows whether the system
+satisfies it.
+
+With --monitor the system reports whether it satisfies the validation set;
+with --enforce it also refuses to install, refresh or remove snaps in a way
+that would break it. Both track the latest sequence of the validation set
+unless one is given as account-id/name=sequence. --forget stops tracking
+the validation set.
+`)
+
+func init() {
+	addCommand("validate", shortValidateHelp, longValidateHelp, func() flags.Commander { return &cmdValidate{} }, map[string]string{
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"monitor": i18n.G("Monitor the given validation set"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"enforce": i18n.G("Enforce the given validation set"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		"forget": i18n.G("Forget the given validation set"),
+	}, []argDesc{{
+		// TRANSLATORS: This needs to be wrapped in <>s.
+		name: i18n.G("<validation set>"),
+		// TRANSLATORS: This should not start with a lowercase letter.
+		desc: i18n.G("Validation set as account-id/name[=sequence]"),
+	}})
+}
+
+// parseValidationSet parses a validation set given as
+// account-id/name[=sequence].
+func parseValidationSet(arg string) (accountID, name string, sequence int, err error) {
+	errPrefix := func() string {
+		return fmt.Sprintf(i18n.G("cannot parse validation set %q"), arg)
+	}
+	ref := arg
+	if i := strings.IndexRune(arg, '='); i >= 0 {
+		ref = arg[:i]
+		sequence, err = strconv.Atoi(arg[i+1:])
+		if err != nil || sequence < 1 {
+			return "", "", 0, fmt.Errorf(i18n.G("%s: invalid sequence %q"), errPrefix(), arg[i+1:])
+		}
+	}
+	parts := strings.Split(ref, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", 0, fmt.Errorf(i18n.G("%s: expected account-id/name[=sequence]"), errPrefix())
+	}
+	return parts[0], parts[1], sequence, nil
+}
+
+func fmtValidationSet(vs *client.ValidationSet) string {
+	if vs.PinnedAt == 0 {
+		return fmt.Sprintf("%s/%s", vs.AccountID, vs.Name)
+	}
+	return fmt.Sprintf("%s/%s=%d", vs.AccountID, vs.Name, vs.PinnedAt)
+}
+
+func fmtValid(vs *client.ValidationSet) string {
+	if vs.Valid {
+		return i18n.G("valid")
+	}
+	return i18n.G("invalid")
+}
+
+func (cmd *cmdValidate) Execute(args []string) error {
+	if len(args) > 0 {
+		return ErrExtraArgs
+	}
+
+	n := 0
+	for _, b := range []bool{cmd.Monitor, cmd.Enforce, cmd.Forget} {
+		if b {
+			n++
+		}
+	}
+	if n > 1 {
+		return errors.New(i18n.G("cannot use --monitor, --enforce and --forget together"))
+	}
+
+	if cmd.Positional.ValidationSet == "" {
+		if n > 0 {
+			return errors.New(i18n.G("missing validation set argument"))
+		}
+		return cmd.list()
+	}
+
+	accountID, name, sequence, err := parseValidationSet(cmd.Positional.ValidationSet)
+	if err != nil {
+		return err
+	}
+
+	cli := Client()
+	switch {
+	case cmd.Forget:
+		if sequence != 0 {
+			return errors.New(i18n.G("cannot use a sequence with --forget"))
+		}
+		return cli.ForgetValidationSet(accountID, name)
+	case cmd.Monitor, cmd.Enforce:
+		mode := "monitor"
+		if cmd.Enforce {
+			mode = "enforce"
+		}
+		vs, err := cli.ApplyValidationSet(accountID, name, &client.ValidationSetOptions{
+			Mode:     mode,
+			Sequence: sequence,
+		})
+		if err != nil {
+			return err
+		}
+		if vs.Mode == "enforce" {
+			// TRANSLATORS: the first %s is the validation set, the second is whether the system satisfies it
+			fmt.Fprintf(Stdout, i18n.G("Enforcing %s at sequence %d (%s)\n"), fmtValidationSet(vs), vs.Sequence, fmtValid(vs))
+		} else {
+			// TRANSLATORS: the first %s is the validation set, the second is whether the system satisfies it
+			fmt.Fprintf(Stdout, i18n.G("Monitoring %s at sequence %d (%s)\n"), fmtValidationSet(vs), vs.Sequence, fmtValid(vs))
+		}
+		return nil
+	}
+
+	if sequence != 0 {
+		return errors.New(i18n.G("cannot use a sequence without --monitor or --enforce"))
+	}
+	vs, err := cli.ValidationSet(accountID, name)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(Stdout, fmtValid(vs))
+	if vs.Notes != "" {
+		fmt.Fprintln(Stdout, vs.Notes)
+	}
+	return nil
+}
+
+func (cmd *cmdValidate) list() error {
+	sets, err := Client().ListValidationSets()
+	if err != nil {
+		return err
+	}
+	if len(sets) == 0 {
+		fmt.Fprintln(Stderr, i18n.G("No validation sets are being tracked."))
+		return nil
+	}
+
+	w := tabWriter()
+	fmt.Fprintln(w, i18n.G("Validation\tMode\tSeq\tCurrent"))
+	for _, vs := range sets {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", fmtValidationSet(vs), vs.Mode, vs.Sequence, fmtValid(vs))
+	}
+	w.Flush()
+	return nil
+}
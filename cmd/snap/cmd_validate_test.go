@@ -0,0 +1,154 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	. "gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+)
+
+func (s *SnapSuite) TestValidateList(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v2/validation-sets")
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": [
+			{"account-id": "foo", "name": "bar", "mode": "monitor", "sequence": 3, "valid": true},
+			{"account-id": "foo", "name": "baz", "mode": "enforce", "pinned-at": 1, "sequence": 1, "valid": false}
+		]}`)
+	})
+
+	rest, err := snap.Parser().ParseArgs([]string{"validate"})
+	c.Assert(err, IsNil)
+	c.Assert(rest, DeepEquals, []string{})
+	c.Check(s.Stdout(), Equals, `Validation  Mode     Seq  Current
+foo/bar     monitor  3    valid
+foo/baz=1   enforce  1    invalid
+`)
+	c.Check(s.Stderr(), Equals, "")
+}
+
+func (s *SnapSuite) TestValidateListEmpty(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": []}`)
+	})
+
+	_, err := snap.Parser().ParseArgs([]string{"validate"})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Equals, "")
+	c.Check(s.Stderr(), Equals, "No validation sets are being tracked.\n")
+}
+
+func (s *SnapSuite) TestValidateShow(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v2/validation-sets/foo/bar")
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result":
+			{"account-id": "foo", "name": "bar", "mode": "monitor", "sequence": 3, "valid": false, "notes": "snap \"x\" is missing"}
+		}`)
+	})
+
+	_, err := snap.Parser().ParseArgs([]string{"validate", "foo/bar"})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Equals, "invalid\nsnap \"x\" is missing\n")
+	c.Check(s.Stderr(), Equals, "")
+}
+
+func (s *SnapSuite) TestValidateEnforce(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "POST")
+		c.Check(r.URL.Path, Equals, "/v2/validation-sets/foo/bar")
+		c.Check(DecodedRequestBody(c, r), DeepEquals, map[string]interface{}{
+			"action":   "apply",
+			"mode":     "enforce",
+			"sequence": json.Number("2"),
+		})
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result":
+			{"account-id": "foo", "name": "bar", "mode": "enforce", "pinned-at": 2, "sequence": 2, "valid": true}
+		}`)
+	})
+
+	_, err := snap.Parser().ParseArgs([]string{"validate", "--enforce", "foo/bar=2"})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Equals, "Enforcing foo/bar=2 at sequence 2 (valid)\n")
+	c.Check(s.Stderr(), Equals, "")
+}
+
+func (s *SnapSuite) TestValidateMonitor(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "POST")
+		c.Check(DecodedRequestBody(c, r), DeepEquals, map[string]interface{}{
+			"action": "apply",
+			"mode":   "monitor",
+		})
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result":
+			{"account-id": "foo", "name": "bar", "mode": "monitor", "sequence": 5, "valid": false}
+		}`)
+	})
+
+	_, err := snap.Parser().ParseArgs([]string{"validate", "--monitor", "foo/bar"})
+	c.Assert(err, IsNil)
+	c.Check(s.Stdout(), Equals, "Monitoring foo/bar at sequence 5 (invalid)\n")
+}
+
+func (s *SnapSuite) TestValidateForget(c *C) {
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		c.Check(r.Method, Equals, "POST")
+		c.Check(r.URL.Path, Equals, "/v2/validation-sets/foo/bar")
+		c.Check(DecodedRequestBody(c, r), DeepEquals, map[string]interface{}{
+			"action": "forget",
+		})
+		fmt.Fprintln(w, `{"type": "sync", "status-code": 200, "result": null}`)
+	})
+
+	_, err := snap.Parser().ParseArgs([]string{"validate", "--forget", "foo/bar"})
+	c.Assert(err, IsNil)
+	c.Check(n, Equals, 1)
+	c.Check(s.Stdout(), Equals, "")
+}
+
+func (s *SnapSuite) TestValidateUnhappy(c *C) {
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Fatalf("unexpected request to %s", r.URL.Path)
+	})
+
+	for _, t := range []struct {
+		args []string
+		err  string
+	}{
+		{[]string{"--monitor", "--enforce", "foo/bar"}, "cannot use --monitor, --enforce and --forget together"},
+		{[]string{"--enforce"}, "missing validation set argument"},
+		{[]string{"foo"}, `cannot parse validation set "foo": expected account-id/name\[=sequence\]`},
+		{[]string{"foo/bar/baz"}, `cannot parse validation set "foo/bar/baz": expected account-id/name\[=sequence\]`},
+		{[]string{"--monitor", "foo/bar=x"}, `cannot parse validation set "foo/bar=x": invalid sequence "x"`},
+		{[]string{"--monitor", "foo/bar=0"}, `cannot parse validation set "foo/bar=0": invalid sequence "0"`},
+		{[]string{"--forget", "foo/bar=1"}, "cannot use a sequence with --forget"},
+		{[]string{"foo/bar=1"}, "cannot use a sequence without --monitor or --enforce"},
+	} {
+		_, err := snap.Parser().ParseArgs(append([]string{"validate"}, t.args...))
+		c.Check(err, ErrorMatches, t.err, Commentf("%v", t.args))
+	}
+}
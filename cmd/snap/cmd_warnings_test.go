@@ -0,0 +1,211 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package main_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	snap "github.com/snapcore/snapd/cmd/snap"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+)
+
+type warningSuite struct {
+	BaseSnapSuite
+}
+
+var _ = check.Suite(&warningSuite{})
+
+const twoWarnings = `{
+			"result": [
+			    {
+				"expire-after": 2419200000000000,
+				"first-added": "2018-09-19T12:41:18.505007495Z",
+				"last-added": "2018-09-19T12:41:18.505007495Z",
+				"message": "hello world number one",
+				"repeat-after": 86400000000000
+			    },
+			    {
+				"expire-after": 2419200000000000,
+				"first-added": "2018-09-19T12:44:19.680362867Z",
+				"last-added": "2018-09-19T12:44:19.680362867Z",
+				"message": "hello world number two",
+				"repeat-after": 86400000000000
+			    }
+			],
+			"status": "OK",
+			"status-code": 200,
+			"type": "sync"
+		}`
+
+func mkWarningsFakeHandler(c *check.C, body string) func(w http.ResponseWriter, r *http.Request) {
+	var called bool
+	return func(w http.ResponseWriter, r *http.Request) {
+		if called {
+			c.Fatalf("expected a single request")
+		}
+		called = true
+		c.Check(r.URL.Path, check.Equals, "/v2/warnings")
+		c.Check(r.URL.Query(), check.HasLen, 0)
+
+		buf, err := ioutil.ReadAll(r.Body)
+		c.Assert(err, check.IsNil)
+		c.Check(string(buf), check.Equals, "")
+		c.Check(r.Method, check.Equals, "GET")
+		w.WriteHeader(200)
+		fmt.Fprintln(w, body)
+	}
+}
+
+func (s *warningSuite) warnFilename(c *check.C) string {
+	user, err := osutil.RealUser()
+	c.Assert(err, check.IsNil)
+	return filepath.Join(dirs.GlobalRootDir, user.HomeDir, ".snap", "warnings.json")
+}
+
+func (s *warningSuite) TestNoWarningsEver(c *check.C) {
+	s.RedirectClientToTestServer(mkWarningsFakeHandler(c, `{"type": "sync", "status-code": 200, "result": []}`))
+
+	rest, err := snap.Parser().ParseArgs([]string{"warnings", "--abs-time"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Equals, "No further warnings.\n")
+	c.Check(osutil.FileExists(s.warnFilename(c)), check.Equals, false)
+}
+
+func (s *warningSuite) TestWarnings(c *check.C) {
+	s.RedirectClientToTestServer(mkWarningsFakeHandler(c, twoWarnings))
+
+	rest, err := snap.Parser().ParseArgs([]string{"warnings", "--abs-time"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Equals, `
+last-occurrence:   2018-09-19T12:41:18Z
+warning: |
+  hello world number one
+---
+last-occurrence:   2018-09-19T12:44:19Z
+warning: |
+  hello world number two
+`[1:])
+	c.Check(osutil.FileExists(s.warnFilename(c)), check.Equals, true)
+}
+
+func (s *warningSuite) TestVerboseWarnings(c *check.C) {
+	s.RedirectClientToTestServer(mkWarningsFakeHandler(c, twoWarnings))
+
+	rest, err := snap.Parser().ParseArgs([]string{"warnings", "--abs-time", "--verbose"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Equals, `
+first-occurrence:  2018-09-19T12:41:18Z
+last-occurrence:   2018-09-19T12:41:18Z
+acknowledged:      -
+repeats-after:     1d00h
+expires-after:     28d0h
+warning: |
+  hello world number one
+---
+first-occurrence:  2018-09-19T12:44:19Z
+last-occurrence:   2018-09-19T12:44:19Z
+acknowledged:      -
+repeats-after:     1d00h
+expires-after:     28d0h
+warning: |
+  hello world number two
+`[1:])
+}
+
+func (s *warningSuite) TestOkay(c *check.C) {
+	t0 := time.Now()
+	c.Assert(os.MkdirAll(filepath.Dir(s.warnFilename(c)), 0700), check.IsNil)
+	data, err := json.Marshal(map[string]time.Time{"timestamp": t0})
+	c.Assert(err, check.IsNil)
+	c.Assert(ioutil.WriteFile(s.warnFilename(c), data, 0600), check.IsNil)
+
+	var n int
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		if n != 1 {
+			c.Fatalf("expected 1 request, now on %d", n)
+		}
+		c.Check(r.URL.Path, check.Equals, "/v2/warnings")
+		c.Check(r.URL.Query(), check.HasLen, 0)
+		c.Assert(DecodedRequestBody(c, r), check.DeepEquals, map[string]interface{}{
+			"action":    "okay",
+			"timestamp": t0.Format(time.RFC3339Nano),
+		})
+		c.Check(r.Method, check.Equals, "POST")
+		w.WriteHeader(200)
+		fmt.Fprintln(w, `{
+			"status": "OK",
+			"status-code": 200,
+			"type": "sync"
+		}`)
+	})
+
+	rest, err := snap.Parser().ParseArgs([]string{"okay"})
+	c.Assert(err, check.IsNil)
+	c.Check(rest, check.HasLen, 0)
+	c.Check(s.Stderr(), check.Equals, "")
+	c.Check(s.Stdout(), check.Equals, "")
+}
+
+func (s *warningSuite) TestOkayBeforeWarnings(c *check.C) {
+	_, err := snap.Parser().ParseArgs([]string{"okay"})
+	c.Assert(err, check.ErrorMatches, "you must have looked at the warnings before acknowledging them. Try 'snap warnings'.")
+}
+
+func (s *warningSuite) TestPresentWarnings(c *check.C) {
+	now := time.Now()
+
+	// no warnings, nothing to say
+	snap.MaybePresentWarnings(0, time.Time{})
+	c.Check(s.Stderr(), check.Equals, "")
+
+	// never looked at the warnings
+	snap.MaybePresentWarnings(1, now)
+	c.Check(s.Stderr(), check.Equals, "WARNING: There is 1 new warning. See 'snap warnings'.\n")
+	s.stderr.Reset()
+
+	c.Assert(os.MkdirAll(filepath.Dir(s.warnFilename(c)), 0700), check.IsNil)
+	data, err := json.Marshal(map[string]time.Time{"timestamp": now})
+	c.Assert(err, check.IsNil)
+	c.Assert(ioutil.WriteFile(s.warnFilename(c), data, 0600), check.IsNil)
+
+	// no warnings since the user last looked
+	snap.MaybePresentWarnings(2, now)
+	c.Check(s.Stderr(), check.Equals, "")
+
+	// new warnings since the user last looked
+	snap.MaybePresentWarnings(2, now.Add(time.Second))
+	c.Check(s.Stderr(), check.Equals, "WARNING: There are 2 new warnings. See 'snap warnings'.\n")
+}
@@ -71,3 +71,54 @@ func (s *SnapSuite) TestCmdWatch(c *C) {
 
 	c.Check(meter.Values, DeepEquals, []float64{51200})
 }
+
+func (s *SnapSuite) TestCmdWatchFollowsEvents(c *C) {
+	meter := &progresstest.Meter{}
+	defer progress.MockMeter(meter)()
+	defer snap.MockFollowChangeEvents(true)()
+
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v2/changes/42/events")
+		w.Header().Set("Content-Type", "application/json-seq")
+		w.WriteHeader(200)
+		fmt.Fprintln(w, "\x1e"+`{"kind": "change", "change": {"id": "42", "kind": "some-kind", "summary": "some summary...", "status": "Doing", "ready": false, "tasks": [{"id": "84", "kind": "bar", "summary": "some summary", "status": "Doing", "progress": {"label": "my-snap", "done": 0, "total": 102400}}]}}`)
+		fmt.Fprintln(w, "\x1e"+`{"kind": "task-progress", "task-id": "84", "progress": {"label": "my-snap", "done": 51200, "total": 102400}}`)
+		fmt.Fprintln(w, "\x1e"+`{"kind": "task-status", "task-id": "84", "status": "Done"}`)
+		fmt.Fprintln(w, "\x1e"+`{"kind": "change", "change": {"id": "42", "status": "Done", "ready": true}}`)
+		n++
+	})
+
+	_, err := snap.Parser().ParseArgs([]string{"watch", "42"})
+	c.Assert(err, IsNil)
+	c.Check(n, Equals, 1)
+
+	c.Check(meter.Values, DeepEquals, []float64{51200})
+}
+
+func (s *SnapSuite) TestCmdWatchFallsBackToPolling(c *C) {
+	meter := &progresstest.Meter{}
+	defer progress.MockMeter(meter)()
+	defer snap.MockFollowChangeEvents(true)()
+
+	n := 0
+	s.RedirectClientToTestServer(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		switch n {
+		case 0:
+			// an older snapd
+			c.Check(r.URL.Path, Equals, "/v2/changes/42/events")
+			w.WriteHeader(404)
+			fmt.Fprintln(w, `{"type": "error", "status-code": 404, "result": {"message": "not found"}}`)
+		case 1:
+			c.Check(r.URL.Path, Equals, "/v2/changes/42")
+			fmt.Fprintln(w, `{"type": "sync", "result": {"id": "42", "ready": true, "status": "Done"}}`)
+		}
+		n++
+	})
+
+	_, err := snap.Parser().ParseArgs([]string{"watch", "42"})
+	c.Assert(err, IsNil)
+	c.Check(n, Equals, 2)
+}
@@ -62,6 +62,14 @@ func MockMaxGoneTime(d time.Duration) (restore func()) {
 	}
 }
 
+func MockRunInhibitPollTime(d time.Duration) (restore func()) {
+	old := runInhibitPollTime
+	runInhibitPollTime = d
+	return func() {
+		runInhibitPollTime = old
+	}
+}
+
 func MockSyscallExec(f func(string, []string, []string) error) (restore func()) {
 	syscallExecOrig := syscallExec
 	syscallExec = f
@@ -167,3 +175,13 @@ func MockWaitConfTimeout(d time.Duration) (restore func()) {
 func Wait(cli *client.Client, id string) (*client.Change, error) {
 	return waitMixin{}.wait(cli, id)
 }
+
+var MaybePresentWarnings = maybePresentWarnings
+
+func MockFollowChangeEvents(follow bool) (restore func()) {
+	old := followChangeEvents
+	followChangeEvents = follow
+	return func() {
+		followChangeEvents = old
+	}
+}
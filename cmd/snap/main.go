@@ -294,9 +294,14 @@ var ClientConfig = client.Config{
 	Interactive: terminal.IsTerminal(0),
 }
 
+// lastClient is the latest client handed out by Client; it is used to
+// present the warnings summary the daemon sent along with its responses.
+var lastClient *client.Client
+
 // Client returns a new client using ClientConfig as configuration.
 func Client() *client.Client {
-	return client.New(&ClientConfig)
+	lastClient = client.New(&ClientConfig)
+	return lastClient
 }
 
 func init() {
@@ -387,8 +392,12 @@ func (e *exitStatus) Error() string {
 }
 
 func run() error {
+	lastClient = nil
 	parser := Parser()
 	_, err := parser.Parse()
+	if err == nil && lastClient != nil {
+		maybePresentWarnings(lastClient.WarningsSummary())
+	}
 	if err != nil {
 		if e, ok := err.(*flags.Error); ok {
 			if e.Type == flags.ErrHelp || e.Type == flags.ErrCommandRequired {
@@ -78,6 +78,8 @@ func (s *BaseSnapSuite) SetUpTest(c *C) {
 	os.Setenv(TestAuthFileEnvKey, s.AuthFile)
 
 	snapdsnap.MockSanitizePlugsSlots(func(snapInfo *snapdsnap.Info) {})
+	// most tests only mock /v2/changes/{id}, so poll it
+	s.AddCleanup(snap.MockFollowChangeEvents(false))
 
 	err := os.MkdirAll(filepath.Dir(dirs.SnapSystemKeyFile), 0755)
 	c.Assert(err, IsNil)
@@ -61,6 +61,7 @@ type Notes struct {
 	Disabled         bool
 	Broken           bool
 	IgnoreValidation bool
+	RefreshHeld      bool
 }
 
 func NotesFromChannelSnapInfo(ref *snap.ChannelSnapInfo) *Notes {
@@ -95,6 +96,7 @@ func NotesFromLocal(snp *client.Snap) *Notes {
 		Disabled:         snp.Status != client.StatusActive,
 		Broken:           snp.Broken != "",
 		IgnoreValidation: snp.IgnoreValidation,
+		RefreshHeld:      snp.RefreshHold != nil,
 	}
 }
 
@@ -161,6 +163,11 @@ func (n *Notes) String() string {
 		ns = append(ns, i18n.G("ignore-validation"))
 	}
 
+	if n.RefreshHeld {
+		// TRANSLATORS: if possible, a single short word
+		ns = append(ns, i18n.G("held"))
+	}
+
 	if len(ns) == 0 {
 		return "-"
 	}
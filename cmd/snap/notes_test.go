@@ -20,6 +20,8 @@
 package main_test
 
 import (
+	"time"
+
 	"gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/client"
@@ -88,6 +90,12 @@ func (notesSuite) TestNotesIgnoreValidation(c *check.C) {
 	}).String(), check.Equals, "ignore-validation")
 }
 
+func (notesSuite) TestNotesRefreshHeld(c *check.C) {
+	c.Check((&snap.Notes{
+		RefreshHeld: true,
+	}).String(), check.Equals, "held")
+}
+
 func (notesSuite) TestNotesNothing(c *check.C) {
 	c.Check((&snap.Notes{}).String(), check.Equals, "-")
 }
@@ -104,4 +112,5 @@ func (notesSuite) TestNotesFromLocal(c *check.C) {
 	c.Check(snap.NotesFromLocal(&client.Snap{DevMode: true}).DevMode, check.Equals, true)
 	c.Check(snap.NotesFromLocal(&client.Snap{Confinement: client.DevModeConfinement}).DevMode, check.Equals, false)
 	c.Check(snap.NotesFromLocal(&client.Snap{IgnoreValidation: true}).IgnoreValidation, check.Equals, true)
+	c.Check(snap.NotesFromLocal(&client.Snap{RefreshHold: &time.Time{}}).RefreshHeld, check.Equals, true)
 }
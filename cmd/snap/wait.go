@@ -34,6 +34,10 @@ import (
 var (
 	maxGoneTime = 5 * time.Second
 	pollTime    = 100 * time.Millisecond
+
+	// whether to follow changes through the events streamed by snapd,
+	// instead of just polling them
+	followChangeEvents = true
 )
 
 type waitMixin struct {
@@ -77,10 +81,21 @@ func (wmx waitMixin) wait(cli *client.Client, id string) (*client.Change, error)
 		close(c)
 	}()
 
-	tMax := time.Time{}
-
 	var lastID string
 	lastLog := map[string]string{}
+	show := func(chg *client.Change) {
+		showProgress(pb, chg, &lastID, lastLog)
+	}
+
+	// follow the change as it happens if snapd can stream its events,
+	// falling back to polling if not or if the stream is interrupted
+	if followChangeEvents {
+		if chg := followChange(cli, id, show); chg != nil {
+			return changeResult(chg)
+		}
+	}
+
+	tMax := time.Time{}
 	for {
 		var rebootingErr error
 		chg, err := cli.Change(id)
@@ -113,36 +128,10 @@ func (wmx waitMixin) wait(cli *client.Client, id string) (*client.Change, error)
 			tMax = time.Time{}
 		}
 
-		for _, t := range chg.Tasks {
-			switch {
-			case t.Status != "Doing":
-				continue
-			case t.Progress.Total == 1:
-				pb.Spin(t.Summary)
-				nowLog := lastLogStr(t.Log)
-				if lastLog[t.ID] != nowLog {
-					pb.Notify(nowLog)
-					lastLog[t.ID] = nowLog
-				}
-			case t.ID == lastID:
-				pb.Set(float64(t.Progress.Done))
-			default:
-				pb.Start(t.Summary, float64(t.Progress.Total))
-				lastID = t.ID
-			}
-			break
-		}
+		show(chg)
 
 		if chg.Ready {
-			if chg.Status == "Done" {
-				return chg, nil
-			}
-
-			if chg.Err != "" {
-				return chg, errors.New(chg.Err)
-			}
-
-			return nil, fmt.Errorf(i18n.G("change finished in status %q with no error message"), chg.Status)
+			return changeResult(chg)
 		}
 
 		if rebootingErr != nil {
@@ -156,6 +145,107 @@ func (wmx waitMixin) wait(cli *client.Client, id string) (*client.Change, error)
 	}
 }
 
+// showProgress shows the progress of the first task of chg that is in
+// progress.
+func showProgress(pb progress.Meter, chg *client.Change, lastID *string, lastLog map[string]string) {
+	for _, t := range chg.Tasks {
+		switch {
+		case t.Status != "Doing":
+			continue
+		case t.Progress.Total == 1:
+			pb.Spin(t.Summary)
+			nowLog := lastLogStr(t.Log)
+			if lastLog[t.ID] != nowLog {
+				pb.Notify(nowLog)
+				lastLog[t.ID] = nowLog
+			}
+		case t.ID == *lastID:
+			pb.Set(float64(t.Progress.Done))
+		default:
+			pb.Start(t.Summary, float64(t.Progress.Total))
+			*lastID = t.ID
+		}
+		break
+	}
+}
+
+// changeResult returns what wait returns for the ready chg.
+func changeResult(chg *client.Change) (*client.Change, error) {
+	if chg.Status == "Done" {
+		return chg, nil
+	}
+
+	if chg.Err != "" {
+		return chg, errors.New(chg.Err)
+	}
+
+	return nil, fmt.Errorf(i18n.G("change finished in status %q with no error message"), chg.Status)
+}
+
+// followChange follows the change with the given id through the events
+// streamed by snapd, calling show whenever it's modified. It returns the
+// change once it's ready, or nil if it couldn't be followed until then.
+func followChange(cli *client.Client, id string, show func(*client.Change)) *client.Change {
+	done := make(chan struct{})
+	defer close(done)
+	events, err := cli.WatchChange(id, done)
+	if err != nil {
+		return nil
+	}
+
+	var chg *client.Change
+	for ev := range events {
+		if ev.Err != nil {
+			// the stream was interrupted, let the caller poll
+			return nil
+		}
+		switch ev.Kind {
+		case "change":
+			chg = ev.Change
+		case "task":
+			if chg != nil && ev.Task != nil {
+				chg.Tasks = append(chg.Tasks, ev.Task)
+			}
+		default:
+			t := findTask(chg, ev.TaskID)
+			if t == nil {
+				continue
+			}
+			switch ev.Kind {
+			case "task-status":
+				t.Status = ev.Status
+				t.Queued = ev.Queued
+			case "task-progress":
+				if ev.Progress != nil {
+					t.Progress = *ev.Progress
+				}
+			case "task-log":
+				t.Log = append(t.Log, ev.Log)
+			}
+		}
+		if chg == nil {
+			continue
+		}
+		if chg.Ready {
+			return chg
+		}
+		show(chg)
+	}
+	return nil
+}
+
+func findTask(chg *client.Change, id string) *client.Task {
+	if chg == nil {
+		return nil
+	}
+	for _, t := range chg.Tasks {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
 func lastLogStr(logs []string) string {
 	if len(logs) == 0 {
 		return ""
@@ -84,6 +84,7 @@ var api = []*Command{
 	assertsCmd,
 	assertsFindManyCmd,
 	stateChangeCmd,
+	stateChangeEventsCmd,
 	stateChangesCmd,
 	createUserCmd,
 	buyCmd,
@@ -95,6 +96,12 @@ var api = []*Command{
 	appsCmd,
 	logsCmd,
 	debugCmd,
+	snapshotCmd,
+	snapshotExportCmd,
+	warningsCmd,
+	modelCmd,
+	validationSetsListCmd,
+	validationSetsCmd,
 }
 
 var (
@@ -199,6 +206,12 @@ var (
 		POST:     abortChange,
 	}
 
+	stateChangeEventsCmd = &Command{
+		Path:   "/v2/changes/{id}/events",
+		UserOK: true,
+		GET:    getChangeEvents,
+	}
+
 	stateChangesCmd = &Command{
 		Path:   "/v2/changes",
 		UserOK: true,
@@ -886,6 +899,12 @@ type snapInstruction struct {
 	LeaveOld bool         `json:"temp-dropped-leave-old"`
 	License  *licenseData `json:"license"`
 	Snaps    []string     `json:"snaps"`
+	Users    []string     `json:"users"`
+	Purge    bool         `json:"purge,omitempty"`
+
+	// HoldUntil is used with the "hold" action, holding automatic
+	// refreshes indefinitely if unset
+	HoldUntil *time.Time `json:"hold-until,omitempty"`
 
 	// The fields below should not be unmarshalled into. Do not export them.
 	userID int
@@ -910,6 +929,7 @@ type snapInstructionResult struct {
 	summary  string
 	affected []string
 	tasksets []*state.TaskSet
+	result   map[string]interface{}
 }
 
 var (
@@ -924,6 +944,8 @@ var (
 	snapstateRevert            = snapstate.Revert
 	snapstateRevertToRevision  = snapstate.RevertToRevision
 	snapstateSwitch            = snapstate.Switch
+	snapstateHoldRefresh       = snapstate.HoldRefresh
+	snapstateUnholdRefresh     = snapstate.UnholdRefresh
 
 	assertstateRefreshSnapDeclarations = assertstate.RefreshSnapDeclarations
 )
@@ -1113,7 +1135,8 @@ func snapRemoveMany(inst *snapInstruction, st *state.State) (*snapInstructionRes
 }
 
 func snapRemove(inst *snapInstruction, st *state.State) (string, []*state.TaskSet, error) {
-	ts, err := snapstate.Remove(st, inst.Snaps[0], inst.Revision)
+	flags := &snapstate.RemoveFlags{Purge: inst.Purge}
+	ts, err := snapstate.Remove(st, inst.Snaps[0], inst.Revision, flags)
 	if err != nil {
 		return "", nil, err
 	}
@@ -1302,6 +1325,11 @@ func postSnap(c *Command, r *http.Request, user *auth.UserState) Response {
 		return BadRequest("%s", err)
 	}
 
+	switch inst.Action {
+	case "hold", "unhold":
+		return snapHold(&inst, state)
+	}
+
 	impl := inst.dispatch()
 	if impl == nil {
 		return BadRequest("unknown action %s", inst.Action)
@@ -1319,6 +1347,26 @@ func postSnap(c *Command, r *http.Request, user *auth.UserState) Response {
 	return AsyncResponse(nil, &Meta{Change: chg.ID()})
 }
 
+// snapHold holds or unholds automatic refreshes of a snap, which
+// happens right away without a change.
+func snapHold(inst *snapInstruction, st *state.State) Response {
+	var err error
+	if inst.Action == "hold" {
+		var until time.Time
+		if inst.HoldUntil != nil {
+			until = *inst.HoldUntil
+		}
+		err = snapstateHoldRefresh(st, inst.Snaps[0], until)
+	} else {
+		err = snapstateUnholdRefresh(st, inst.Snaps[0])
+	}
+	if err != nil {
+		return inst.errToResponse(err)
+	}
+
+	return SyncResponse(nil, nil)
+}
+
 func newChange(st *state.State, kind, summary string, tsets []*state.TaskSet, snapNames []string) *state.Change {
 	chg := st.NewChange(kind, summary)
 	for _, ts := range tsets {
@@ -1399,7 +1447,7 @@ func snapsOp(c *Command, r *http.Request, user *auth.UserState) Response {
 		return BadRequest("cannot decode request body into snap instruction: %v", err)
 	}
 
-	if inst.Channel != "" || !inst.Revision.Unset() || inst.DevMode || inst.JailMode {
+	if inst.Channel != "" || !inst.Revision.Unset() || inst.DevMode || inst.JailMode || inst.Purge {
 		return BadRequest("unsupported option provided for multi-snap operation")
 	}
 
@@ -1420,6 +1468,9 @@ func snapsOp(c *Command, r *http.Request, user *auth.UserState) Response {
 		op = snapInstallMany
 	case "remove":
 		op = snapRemoveMany
+	case "snapshot":
+		// see api_snapshots.go
+		op = snapshotMany
 	default:
 		return BadRequest("unsupported multi-snap operation %q", inst.Action)
 	}
@@ -1439,7 +1490,7 @@ func snapsOp(c *Command, r *http.Request, user *auth.UserState) Response {
 
 	chg.Set("api-data", map[string]interface{}{"snap-names": res.affected})
 
-	return AsyncResponse(nil, &Meta{Change: chg.ID()})
+	return AsyncResponse(res.result, &Meta{Change: chg.ID()})
 }
 
 func postSnaps(c *Command, r *http.Request, user *auth.UserState) Response {
@@ -2018,6 +2069,7 @@ type taskInfo struct {
 	Kind     string           `json:"kind"`
 	Summary  string           `json:"summary"`
 	Status   string           `json:"status"`
+	Queued   bool             `json:"queued,omitempty"`
 	Log      []string         `json:"log,omitempty"`
 	Progress taskInfoProgress `json:"progress"`
 
@@ -2060,6 +2112,7 @@ func change2changeInfo(chg *state.Change) *changeInfo {
 			Kind:    t.Kind(),
 			Summary: t.Summary(),
 			Status:  t.Status().String(),
+			Queued:  t.IsQueued(),
 			Log:     t.Log(),
 			Progress: taskInfoProgress{
 				Label: label,
@@ -2097,6 +2150,84 @@ func getChange(c *Command, r *http.Request, user *auth.UserState) Response {
 	return SyncResponse(change2changeInfo(chg), nil)
 }
 
+func getChangeEvents(c *Command, r *http.Request, user *auth.UserState) Response {
+	chID := muxVars(r)["id"]
+	state := c.d.overlord.State()
+	state.Lock()
+	defer state.Unlock()
+	chg := state.Change(chID)
+	if chg == nil {
+		return NotFound("cannot find change with id %q", chID)
+	}
+
+	modified, stop := chg.Watch()
+	return &changeEventsSeqResponse{
+		state:    state,
+		id:       chID,
+		initial:  change2changeInfo(chg),
+		modified: modified,
+		stop:     stop,
+	}
+}
+
+// changeEvent is a modification of a change or of one of its tasks, see
+// client.ChangeEvent.
+type changeEvent struct {
+	Kind string `json:"kind"`
+
+	Change *changeInfo `json:"change,omitempty"`
+	Task   *taskInfo   `json:"task,omitempty"`
+
+	TaskID   string            `json:"task-id,omitempty"`
+	Status   string            `json:"status,omitempty"`
+	Queued   bool              `json:"queued,omitempty"`
+	Progress *taskInfoProgress `json:"progress,omitempty"`
+	Log      string            `json:"log,omitempty"`
+}
+
+// changeTracker keeps the last seen state of the tasks of a change, to
+// compute the events of its modifications.
+type changeTracker map[string]*taskInfo
+
+func (ct changeTracker) events(chgInfo *changeInfo) []*changeEvent {
+	var events []*changeEvent
+	for _, t := range chgInfo.Tasks {
+		old := ct[t.ID]
+		ct[t.ID] = t
+		if old == nil {
+			events = append(events, &changeEvent{Kind: "task", Task: t})
+			continue
+		}
+		if t.Status != old.Status || t.Queued != old.Queued {
+			events = append(events, &changeEvent{Kind: "task-status", TaskID: t.ID, Status: t.Status, Queued: t.Queued})
+		}
+		if t.Progress != old.Progress {
+			progress := t.Progress
+			events = append(events, &changeEvent{Kind: "task-progress", TaskID: t.ID, Progress: &progress})
+		}
+		for _, entry := range newLogEntries(old.Log, t.Log) {
+			events = append(events, &changeEvent{Kind: "task-log", TaskID: t.ID, Log: entry})
+		}
+	}
+	return events
+}
+
+// newLogEntries returns the entries of log that are not in old, which
+// was an earlier version of it. The oldest entries of task logs are
+// dropped as new ones are added, so they might be gone from log.
+func newLogEntries(old, log []string) []string {
+	if len(old) == 0 {
+		return log
+	}
+	last := old[len(old)-1]
+	for i := len(log) - 1; i >= 0; i-- {
+		if log[i] == last {
+			return log[i+1:]
+		}
+	}
+	return log
+}
+
 func getChanges(c *Command, r *http.Request, user *auth.UserState) Response {
 	query := r.URL.Query()
 	qselect := query.Get("select")
@@ -2591,8 +2722,8 @@ func runSnapctl(c *Command, r *http.Request, user *auth.UserState) Response {
 	if err != nil {
 		return Forbidden("cannot get remote user: %s", err)
 	}
-	// we only allow "get" from regular users in snapctl
-	if uid != 0 && snapctlOptions.Args[0] != "get" {
+	// we only allow "get", "is-connected" and "services" from regular users in snapctl
+	if uid != 0 && !strutil.ListContains([]string{"get", "is-connected", "services"}, snapctlOptions.Args[0]) {
 		return Forbidden("cannot use %q with uid %d, try with sudo", snapctlOptions.Args[0], uid)
 	}
 
@@ -2603,6 +2734,21 @@ func runSnapctl(c *Command, r *http.Request, user *auth.UserState) Response {
 	if err != nil {
 		if e, ok := err.(*flags.Error); ok && e.Type == flags.ErrHelp {
 			stdout = []byte(e.Error())
+		} else if e, ok := err.(*ctlcmd.UnsuccessfulError); ok {
+			result := map[string]interface{}{
+				"stdout":    string(stdout),
+				"stderr":    string(stderr),
+				"exit-code": e.ExitCode,
+			}
+			return &resp{
+				Type: ResponseTypeError,
+				Result: &errorResult{
+					Message: e.Error(),
+					Kind:    errorKindUnsuccessful,
+					Value:   result,
+				},
+				Status: 200,
+			}
 		} else {
 			return BadRequest("error running snapctl: %s", err)
 		}
@@ -2800,12 +2946,13 @@ func getAppsInfo(c *Command, r *http.Request, user *auth.UserState) Response {
 		return rsp
 	}
 
-	return SyncResponse(clientAppInfosFromSnapAppInfos(appInfos), nil)
+	return SyncResponse(servicestate.ClientAppInfosFromSnapAppInfos(appInfos), nil)
 }
 
 func getLogs(c *Command, r *http.Request, user *auth.UserState) Response {
 	query := r.URL.Query()
 	n := "10"
+	limit := 10
 	if s := query.Get("n"); s != "" {
 		m, err := strconv.ParseInt(s, 0, 32)
 		if err != nil {
@@ -2813,8 +2960,10 @@ func getLogs(c *Command, r *http.Request, user *auth.UserState) Response {
 		}
 		if m < 0 {
 			n = "all"
+			limit = -1
 		} else {
 			n = s
+			limit = int(m)
 		}
 	}
 	follow := false
@@ -2825,6 +2974,50 @@ func getLogs(c *Command, r *http.Request, user *auth.UserState) Response {
 		}
 		follow = f
 	}
+	logOpts := systemd.LogOptions{
+		N:      n,
+		Follow: follow,
+		Cursor: query.Get("cursor"),
+	}
+	if s := query.Get("priority"); s != "" {
+		p, err := systemd.ParseLogPriority(s)
+		if err != nil {
+			return BadRequest(`invalid value for priority: %v`, err)
+		}
+		logOpts.Priority = strconv.Itoa(p)
+	}
+	if s := query.Get("since"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return BadRequest(`invalid value for since: %q: %v`, s, err)
+		}
+		logOpts.Since = t
+	}
+	if s := query.Get("until"); s != "" {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return BadRequest(`invalid value for until: %q: %v`, s, err)
+		}
+		logOpts.Until = t
+	}
+	// journalctl only learnt --grep in systemd 237, so do it ourselves
+	var grep *regexp.Regexp
+	if s := query.Get("grep"); s != "" {
+		re, err := regexp.Compile(s)
+		if err != nil {
+			return BadRequest(`invalid value for grep: %q: %v`, s, err)
+		}
+		grep = re
+	}
+	allFields := false
+	switch s := query.Get("fields"); s {
+	case "":
+		// nothing to do
+	case "all":
+		allFields = true
+	default:
+		return BadRequest(`invalid value for fields: %q`, s)
+	}
 
 	// only services have logs for now
 	opts := appInfoOptions{service: true}
@@ -2836,21 +3029,45 @@ func getLogs(c *Command, r *http.Request, user *auth.UserState) Response {
 		return AppNotFound("no matching services")
 	}
 
-	serviceNames := make([]string, len(appInfos))
-	for i, appInfo := range appInfos {
-		serviceNames[i] = appInfo.ServiceName()
+	serviceNames := make([]string, 0, len(appInfos))
+	for _, appInfo := range appInfos {
+		if appInfo.IsUserService() {
+			logOpts.UserServices = append(logOpts.UserServices, appInfo.ServiceName())
+			continue
+		}
+		serviceNames = append(serviceNames, appInfo.ServiceName())
 	}
 
 	sysd := systemd.New(dirs.GlobalRootDir, progress.Null)
-	reader, err := sysd.LogReader(serviceNames, n, follow)
+	logsRsp := &journalLineReaderSeqResponse{
+		follow:    follow,
+		grep:      grep,
+		limit:     limit,
+		allFields: allFields,
+	}
+	if grep != nil {
+		// the last n matching lines can be anywhere in the journal,
+		// so read it all and only then follow it if asked to
+		followOpts := logOpts
+		followOpts.N = "all"
+		logOpts.N = "all"
+		logOpts.Follow = false
+		if follow {
+			logsRsp.followAfter = func(cursor string) (io.ReadCloser, error) {
+				if cursor != "" {
+					followOpts.Cursor = cursor
+				}
+				return sysd.LogReader(serviceNames, followOpts)
+			}
+		}
+	}
+	reader, err := sysd.LogReader(serviceNames, logOpts)
 	if err != nil {
 		return InternalError("cannot get logs: %v", err)
 	}
+	logsRsp.ReadCloser = reader
 
-	return &journalLineReaderSeqResponse{
-		ReadCloser: reader,
-		follow:     follow,
-	}
+	return logsRsp
 }
 
 func postApps(c *Command, r *http.Request, user *auth.UserState) Response {
@@ -0,0 +1,115 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/overlord/devicestate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var _ = check.Suite(&modelSuite{})
+
+type modelSuite struct {
+	apiBaseSuite
+}
+
+func (s *modelSuite) TearDownTest(c *check.C) {
+	devicestateRemodel = devicestate.Remodel
+	s.apiBaseSuite.TearDownTest(c)
+}
+
+func (s *modelSuite) newModel(c *check.C) *asserts.Model {
+	a, err := s.storeSigning.RootSigning.Sign(asserts.ModelType, map[string]interface{}{
+		"series":       "16",
+		"brand-id":     "can0nical",
+		"model":        "pc",
+		"architecture": "amd64",
+		"gadget":       "pc",
+		"kernel":       "pc-kernel",
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, check.IsNil)
+	return a.(*asserts.Model)
+}
+
+func (s *modelSuite) postModel(c *check.C, body interface{}) *resp {
+	buf, err := json.Marshal(body)
+	c.Assert(err, check.IsNil)
+	req, err := http.NewRequest("POST", "/v2/model", bytes.NewBuffer(buf))
+	c.Assert(err, check.IsNil)
+	return postModel(modelCmd, req, nil).(*resp)
+}
+
+func (s *modelSuite) TestPostRemodel(c *check.C) {
+	d := s.daemonWithOverlordMock(c)
+	newModel := s.newModel(c)
+
+	var remodeled *asserts.Model
+	devicestateRemodel = func(st *state.State, nm *asserts.Model) (*state.Change, error) {
+		remodeled = nm
+		chg := st.NewChange("remodel", "...")
+		return chg, nil
+	}
+
+	rsp := s.postModel(c, map[string]string{"new-model": string(asserts.Encode(newModel))})
+	c.Assert(rsp.Type, check.Equals, ResponseTypeAsync)
+	c.Check(rsp.Status, check.Equals, 202)
+	c.Check(remodeled, check.DeepEquals, newModel)
+
+	st := d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Kind(), check.Equals, "remodel")
+}
+
+func (s *modelSuite) TestPostRemodelErrors(c *check.C) {
+	s.daemonWithOverlordMock(c)
+
+	devicestateRemodel = func(st *state.State, nm *asserts.Model) (*state.Change, error) {
+		return nil, errors.New("boom")
+	}
+
+	acct := s.storeSigning.StoreAccountKey("")
+	for _, t := range []struct {
+		body   interface{}
+		errStr string
+	}{
+		{"not-an-object", `cannot decode request body into remodel operation: .*`},
+		{map[string]string{"new-model": "garbage"}, `cannot decode new model assertion: .*`},
+		{map[string]string{"new-model": string(asserts.Encode(acct))}, `new model is not a model assertion: account-key`},
+		{map[string]string{"new-model": string(asserts.Encode(s.newModel(c)))}, `cannot remodel device: boom`},
+	} {
+		rsp := s.postModel(c, t.body)
+		c.Check(rsp.Type, check.Equals, ResponseTypeError)
+		c.Check(rsp.Status, check.Equals, 400)
+		c.Check(rsp.Result.(*errorResult).Message, check.Matches, t.errStr)
+	}
+}
@@ -0,0 +1,398 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"golang.org/x/net/context"
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/overlord/snapshotstate"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var _ = check.Suite(&snapshotSuite{})
+
+type snapshotSuite struct {
+	apiBaseSuite
+}
+
+func (s *snapshotSuite) TearDownTest(c *check.C) {
+	snapshotList = snapshotstate.List
+	snapshotCheck = snapshotstate.Check
+	snapshotForget = snapshotstate.Forget
+	snapshotRestore = snapshotstate.Restore
+	snapshotSave = snapshotstate.Save
+	snapshotExport = snapshotstate.Export
+	snapshotImport = snapshotstate.Import
+	s.apiBaseSuite.TearDownTest(c)
+}
+
+func (s *snapshotSuite) TestSnapshotMany(c *check.C) {
+	snapshotSave = func(st *state.State, snaps []string, users []string) (uint64, []string, *state.TaskSet, error) {
+		c.Check(snaps, check.HasLen, 2)
+		t := st.NewTask("fake-snapshot-2", "Snapshot two")
+		return 1, snaps, state.NewTaskSet(t), nil
+	}
+
+	inst := snapInstruction{Action: "snapshot", Snaps: []string{"foo", "bar"}}
+	st := s.daemonWithOverlordMock(c).overlord.State()
+	st.Lock()
+	res, err := snapshotMany(&inst, st)
+	st.Unlock()
+	c.Assert(err, check.IsNil)
+	c.Check(res.summary, check.Equals, `Snapshot snaps "foo", "bar"`)
+	c.Check(res.affected, check.DeepEquals, inst.Snaps)
+	c.Check(res.result, check.DeepEquals, map[string]interface{}{"set-id": uint64(1)})
+}
+
+func (s *snapshotSuite) TestListSnapshots(c *check.C) {
+	snapshots := []client.SnapshotSet{{ID: 1}, {ID: 42}}
+
+	snapshotList = func(context.Context, uint64, []string) ([]client.SnapshotSet, error) {
+		return snapshots, nil
+	}
+
+	req, err := http.NewRequest("GET", "/v2/snapshots", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := listSnapshots(snapshotCmd, req, nil).(*resp)
+	c.Check(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result, check.DeepEquals, snapshots)
+}
+
+func (s *snapshotSuite) TestListSnapshotsFiltering(c *check.C) {
+	snapshots := []client.SnapshotSet{{ID: 1}, {ID: 42}}
+
+	snapshotList = func(_ context.Context, setID uint64, _ []string) ([]client.SnapshotSet, error) {
+		c.Assert(setID, check.Equals, uint64(42))
+		return snapshots[1:], nil
+	}
+
+	req, err := http.NewRequest("GET", "/v2/snapshots?set=42", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := listSnapshots(snapshotCmd, req, nil).(*resp)
+	c.Check(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result, check.DeepEquals, []client.SnapshotSet{{ID: 42}})
+}
+
+func (s *snapshotSuite) TestListSnapshotsBadFiltering(c *check.C) {
+	snapshotList = func(_ context.Context, setID uint64, _ []string) ([]client.SnapshotSet, error) {
+		c.Fatal("snapshotList should not be reached (should have been blocked by validation!)")
+		return nil, nil
+	}
+
+	req, err := http.NewRequest("GET", "/v2/snapshots?set=no", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := listSnapshots(snapshotCmd, req, nil).(*resp)
+	c.Check(rsp.Type, check.Equals, ResponseTypeError)
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, `'set', if given, must be a positive base 10 number; got "no"`)
+}
+
+func (s *snapshotSuite) TestListSnapshotsListError(c *check.C) {
+	snapshotList = func(_ context.Context, setID uint64, _ []string) ([]client.SnapshotSet, error) {
+		return nil, errors.New("no")
+	}
+
+	req, err := http.NewRequest("GET", "/v2/snapshots", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := listSnapshots(snapshotCmd, req, nil).(*resp)
+	c.Check(rsp.Type, check.Equals, ResponseTypeError)
+	c.Check(rsp.Status, check.Equals, 500)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, "no")
+}
+
+func (s *snapshotSuite) TestFormatSnapshotAction(c *check.C) {
+	type table struct {
+		action   string
+		expected string
+	}
+	tests := []table{
+		{
+			`{"set": 2, "action": "verb"}`,
+			`Verb of snapshot set #2`,
+		}, {
+			`{"set": 2, "action": "verb", "snaps": ["foo"]}`,
+			`Verb of snapshot set #2 for snaps "foo"`,
+		}, {
+			`{"set": 2, "action": "verb", "snaps": ["foo", "bar"]}`,
+			`Verb of snapshot set #2 for snaps "foo", "bar"`,
+		}, {
+			`{"set": 2, "action": "verb", "users": ["meep"]}`,
+			`Verb of snapshot set #2 for users "meep"`,
+		}, {
+			`{"set": 2, "action": "verb", "users": ["meep", "quux"]}`,
+			`Verb of snapshot set #2 for users "meep", "quux"`,
+		}, {
+			`{"set": 2, "action": "verb", "users": ["meep", "quux"], "snaps": ["foo", "bar"]}`,
+			`Verb of snapshot set #2 for snaps "foo", "bar" for users "meep", "quux"`,
+		},
+	}
+
+	for _, test := range tests {
+		comm := check.Commentf(test.action)
+		var action snapshotAction
+		c.Assert(json.Unmarshal([]byte(test.action), &action), check.IsNil, comm)
+		c.Check(action.String(), check.Equals, test.expected, comm)
+	}
+}
+
+func (s *snapshotSuite) TestChangeSnapshots400(c *check.C) {
+	type table struct{ body, error string }
+	tests := []table{
+		{
+			body:  `"woodchucks`,
+			error: "cannot decode request body into snapshot operation:.*",
+		}, {
+			body:  `{}"woodchucks`,
+			error: "extra content found after snapshot operation",
+		}, {
+			body:  `{}`,
+			error: "snapshot operation requires snapshot set ID",
+		}, {
+			body:  `{"set": 42}`,
+			error: "snapshot operation requires action",
+		}, {
+			body:  `{"set": 42, "action": "bork"}`,
+			error: `unknown snapshot operation "bork"`,
+		}, {
+			body:  `{"set": 42, "action": "forget", "users": ["foo"]}`,
+			error: `snapshot "forget" operation cannot specify users`,
+		},
+	}
+
+	s.daemonWithOverlordMock(c)
+
+	for i, test := range tests {
+		comm := check.Commentf("%d:%q", i, test.body)
+		req, err := http.NewRequest("POST", "/v2/snapshots", strings.NewReader(test.body))
+		c.Assert(err, check.IsNil, comm)
+
+		rsp := changeSnapshots(snapshotCmd, req, nil).(*resp)
+		c.Check(rsp.Type, check.Equals, ResponseTypeError, comm)
+		c.Check(rsp.Status, check.Equals, 400, comm)
+		c.Check(rsp.Result.(*errorResult).Message, check.Matches, test.error, comm)
+	}
+}
+
+func (s *snapshotSuite) TestChangeSnapshots404(c *check.C) {
+	var done string
+	expectedError := errors.New("bzzt")
+	snapshotCheck = func(*state.State, uint64, []string, []string) ([]string, *state.TaskSet, error) {
+		done = "check"
+		return nil, nil, expectedError
+	}
+	snapshotRestore = func(*state.State, uint64, []string, []string) ([]string, *state.TaskSet, error) {
+		done = "restore"
+		return nil, nil, expectedError
+	}
+	snapshotForget = func(*state.State, uint64, []string) ([]string, *state.TaskSet, error) {
+		done = "forget"
+		return nil, nil, expectedError
+	}
+	s.daemonWithOverlordMock(c)
+
+	for _, expectedError = range []error{client.ErrSnapshotSetNotFound, client.ErrSnapshotSnapsNotFound} {
+		for _, action := range []string{"check", "restore", "forget"} {
+			done = ""
+			comm := check.Commentf("%s/%s", action, expectedError)
+			body := `{"set": 42, "action": "` + action + `"}`
+			req, err := http.NewRequest("POST", "/v2/snapshots", strings.NewReader(body))
+			c.Assert(err, check.IsNil, comm)
+
+			rsp := changeSnapshots(snapshotCmd, req, nil).(*resp)
+			c.Check(rsp.Type, check.Equals, ResponseTypeError, comm)
+			c.Check(rsp.Status, check.Equals, 404, comm)
+			c.Check(rsp.Result.(*errorResult).Message, check.Matches, expectedError.Error(), comm)
+			c.Check(done, check.Equals, action, comm)
+		}
+	}
+}
+
+func (s *snapshotSuite) TestChangeSnapshots500(c *check.C) {
+	var done string
+	expectedError := errors.New("bzzt")
+	snapshotCheck = func(*state.State, uint64, []string, []string) ([]string, *state.TaskSet, error) {
+		done = "check"
+		return nil, nil, expectedError
+	}
+	snapshotRestore = func(*state.State, uint64, []string, []string) ([]string, *state.TaskSet, error) {
+		done = "restore"
+		return nil, nil, expectedError
+	}
+	snapshotForget = func(*state.State, uint64, []string) ([]string, *state.TaskSet, error) {
+		done = "forget"
+		return nil, nil, expectedError
+	}
+	s.daemonWithOverlordMock(c)
+
+	for _, action := range []string{"check", "restore", "forget"} {
+		comm := check.Commentf("%s", action)
+		body := `{"set": 42, "action": "` + action + `"}`
+		req, err := http.NewRequest("POST", "/v2/snapshots", strings.NewReader(body))
+		c.Assert(err, check.IsNil, comm)
+
+		rsp := changeSnapshots(snapshotCmd, req, nil).(*resp)
+		c.Check(rsp.Type, check.Equals, ResponseTypeError, comm)
+		c.Check(rsp.Status, check.Equals, 500, comm)
+		c.Check(rsp.Result.(*errorResult).Message, check.Matches, expectedError.Error(), comm)
+		c.Check(done, check.Equals, action, comm)
+	}
+}
+
+func (s *snapshotSuite) TestChangeSnapshot(c *check.C) {
+	var shotID uint64
+	var snapshotSnaps, snapshotUsers []string
+	snapshotRestore = func(st *state.State, setID uint64, snaps, users []string) ([]string, *state.TaskSet, error) {
+		shotID = setID
+		snapshotSnaps = snaps
+		snapshotUsers = users
+		t := st.NewTask("fake-restore-snapshot", "Restore a snapshot")
+		return []string{"foo"}, state.NewTaskSet(t), nil
+	}
+
+	st := s.daemonWithOverlordMock(c).overlord.State()
+
+	body := `{"set": 42, "action": "restore", "snaps": ["foo"], "users": ["bar"]}`
+	req, err := http.NewRequest("POST", "/v2/snapshots", strings.NewReader(body))
+	c.Assert(err, check.IsNil)
+
+	rsp := changeSnapshots(snapshotCmd, req, nil).(*resp)
+	c.Check(rsp.Type, check.Equals, ResponseTypeAsync)
+	c.Check(rsp.Status, check.Equals, 202)
+
+	st.Lock()
+	defer st.Unlock()
+	chg := st.Change(rsp.Change)
+	c.Assert(chg, check.NotNil)
+	c.Check(chg.Kind(), check.Equals, "restore-snapshot")
+	c.Check(chg.Summary(), check.Equals, `Restore of snapshot set #42 for snaps "foo" for users "bar"`)
+	c.Check(shotID, check.Equals, uint64(42))
+	c.Check(snapshotSnaps, check.DeepEquals, []string{"foo"})
+	c.Check(snapshotUsers, check.DeepEquals, []string{"bar"})
+	var apiData map[string]interface{}
+	c.Check(chg.Get("api-data", &apiData), check.IsNil)
+	c.Check(apiData["snap-names"], check.DeepEquals, []interface{}{"foo"})
+}
+
+func (s *snapshotSuite) TestExportSnapshot(c *check.C) {
+	s.vars = map[string]string{"id": "42"}
+	snapshotList = func(_ context.Context, setID uint64, _ []string) ([]client.SnapshotSet, error) {
+		c.Check(setID, check.Equals, uint64(42))
+		return []client.SnapshotSet{{ID: 42}}, nil
+	}
+	snapshotExport = func(_ context.Context, setID uint64, w io.Writer) error {
+		c.Check(setID, check.Equals, uint64(42))
+		_, err := io.WriteString(w, "exported")
+		return err
+	}
+
+	req, err := http.NewRequest("GET", "/v2/snapshots/42/export", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := getSnapshotExport(snapshotExportCmd, req, nil)
+	rec := httptest.NewRecorder()
+	rsp.ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 200)
+	c.Check(rec.HeaderMap.Get("Content-Type"), check.Equals, "application/x.snapd.snapshot")
+	c.Check(rec.Body.String(), check.Equals, "exported")
+}
+
+func (s *snapshotSuite) TestExportSnapshotNotFound(c *check.C) {
+	s.vars = map[string]string{"id": "42"}
+	snapshotList = func(context.Context, uint64, []string) ([]client.SnapshotSet, error) {
+		return nil, nil
+	}
+	snapshotExport = func(context.Context, uint64, io.Writer) error {
+		c.Fatal("snapshotExport should not be reached")
+		return nil
+	}
+
+	req, err := http.NewRequest("GET", "/v2/snapshots/42/export", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := getSnapshotExport(snapshotExportCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, 404)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, client.ErrSnapshotSetNotFound.Error())
+}
+
+func (s *snapshotSuite) TestExportSnapshotBadID(c *check.C) {
+	s.vars = map[string]string{"id": "no"}
+
+	req, err := http.NewRequest("GET", "/v2/snapshots/no/export", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := getSnapshotExport(snapshotExportCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, `snapshot set ID must be a positive base 10 number; got "no"`)
+}
+
+func (s *snapshotSuite) TestImportSnapshot(c *check.C) {
+	d := s.daemonWithOverlordMock(c)
+	snapshotImport = func(_ context.Context, st *state.State, r io.Reader) (uint64, []string, error) {
+		c.Check(st, check.Equals, d.overlord.State())
+		buf, err := ioutil.ReadAll(r)
+		c.Check(err, check.IsNil)
+		c.Check(string(buf), check.Equals, "exported")
+		return 7, []string{"foo", "bar"}, nil
+	}
+
+	req, err := http.NewRequest("POST", "/v2/snapshots", bytes.NewBufferString("exported"))
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", "application/x.snapd.snapshot")
+
+	rsp := changeSnapshots(snapshotCmd, req, nil).(*resp)
+	c.Check(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result, check.DeepEquals, map[string]interface{}{
+		"set-id": uint64(7),
+		"snaps":  []string{"foo", "bar"},
+	})
+}
+
+func (s *snapshotSuite) TestImportSnapshotError(c *check.C) {
+	s.daemonWithOverlordMock(c)
+	snapshotImport = func(context.Context, *state.State, io.Reader) (uint64, []string, error) {
+		return 0, nil, errors.New("bzzt")
+	}
+
+	req, err := http.NewRequest("POST", "/v2/snapshots", bytes.NewBufferString("exported"))
+	c.Assert(err, check.IsNil)
+	req.Header.Set("Content-Type", "application/x.snapd.snapshot")
+
+	rsp := changeSnapshots(snapshotCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, "bzzt")
+}
@@ -103,6 +103,7 @@ type apiBaseSuite struct {
 	jctlSvcses         [][]string
 	jctlNs             []string
 	jctlFollows        []bool
+	jctlOpts           []systemd.LogOptions
 	jctlRCs            []io.ReadCloser
 	jctlErrs           []error
 
@@ -182,7 +183,9 @@ func (s *apiBaseSuite) TearDownSuite(c *check.C) {
 func (s *apiBaseSuite) systemctl(args ...string) (buf []byte, err error) {
 	s.sysctlArgses = append(s.sysctlArgses, args)
 
-	if args[0] != "show" && args[0] != "start" && args[0] != "stop" && args[0] != "restart" {
+	if len(args) == 6 && args[0] == "--user" && args[1] == "--global" && args[2] == "--root" && args[4] == "is-enabled" {
+		// user services are only ever queried for being enabled
+	} else if args[0] != "show" && args[0] != "start" && args[0] != "stop" && args[0] != "restart" {
 		panic(fmt.Sprintf("unexpected systemctl call: %v", args))
 	}
 
@@ -196,10 +199,11 @@ func (s *apiBaseSuite) systemctl(args ...string) (buf []byte, err error) {
 	return buf, err
 }
 
-func (s *apiBaseSuite) journalctl(svcs []string, n string, follow bool) (rc io.ReadCloser, err error) {
+func (s *apiBaseSuite) journalctl(svcs []string, opts systemd.LogOptions) (rc io.ReadCloser, err error) {
 	s.jctlSvcses = append(s.jctlSvcses, svcs)
-	s.jctlNs = append(s.jctlNs, n)
-	s.jctlFollows = append(s.jctlFollows, follow)
+	s.jctlNs = append(s.jctlNs, opts.N)
+	s.jctlFollows = append(s.jctlFollows, opts.Follow)
+	s.jctlOpts = append(s.jctlOpts, opts)
 
 	if len(s.jctlErrs) > 0 {
 		err, s.jctlErrs = s.jctlErrs[0], s.jctlErrs[1:]
@@ -218,6 +222,7 @@ func (s *apiBaseSuite) SetUpTest(c *check.C) {
 	s.jctlSvcses = nil
 	s.jctlNs = nil
 	s.jctlFollows = nil
+	s.jctlOpts = nil
 	s.jctlRCs = nil
 	s.jctlErrs = nil
 
@@ -255,6 +260,8 @@ func (s *apiBaseSuite) SetUpTest(c *check.C) {
 	snapstateTryPath = nil
 	snapstateUpdate = nil
 	snapstateUpdateMany = nil
+	snapstateHoldRefresh = nil
+	snapstateUnholdRefresh = nil
 }
 
 func (s *apiBaseSuite) TearDownTest(c *check.C) {
@@ -276,6 +283,8 @@ func (s *apiBaseSuite) TearDownTest(c *check.C) {
 	snapstateTryPath = snapstate.TryPath
 	snapstateUpdate = snapstate.Update
 	snapstateUpdateMany = snapstate.UpdateMany
+	snapstateHoldRefresh = snapstate.HoldRefresh
+	snapstateUnholdRefresh = snapstate.UnholdRefresh
 }
 
 func (s *apiBaseSuite) daemon(c *check.C) *Daemon {
@@ -723,7 +732,10 @@ func (s *apiSuite) TestMapLocalOfTryResolvesSymlink(c *check.C) {
 func (s *apiSuite) TestListIncludesAll(c *check.C) {
 	// Very basic check to help stop us from not adding all the
 	// commands to the command list.
-	found := countCommandDeclsIn(c, "api.go", check.Commentf("TestListIncludesAll"))
+	found := 0
+	for _, filename := range []string{"api.go", "api_snapshots.go", "api_warnings.go", "api_model.go", "api_validation_sets.go"} {
+		found += countCommandDeclsIn(c, filename, check.Commentf(filename))
+	}
 
 	c.Check(found, check.Equals, len(api),
 		check.Commentf(`At a glance it looks like you've not added all the Commands defined in api to the api list.`))
@@ -1535,6 +1547,41 @@ func (s *apiSuite) TestSnapsInfoAll(c *check.C) {
 	}
 }
 
+func (s *apiSuite) TestSnapsInfoAllParallelInstance(c *check.C) {
+	d := s.daemon(c)
+
+	s.mkInstalledInState(c, d, "foo", "bar", "v1", snap.R(1), true, "")
+	sideInfo := &snap.SideInfo{SnapID: "foo-id", RealName: "foo", Revision: snap.R(2)}
+	snaptest.MockSnapInstance(c, "foo_instance", "name: foo\nversion: v2\n", sideInfo)
+
+	st := d.overlord.State()
+	st.Lock()
+	snapstate.Set(st, "foo_instance", &snapstate.SnapState{
+		Active:      true,
+		Sequence:    []*snap.SideInfo{sideInfo},
+		Current:     snap.R(2),
+		Channel:     "stable",
+		InstanceKey: "instance",
+	})
+	st.Unlock()
+
+	for _, q := range []string{"", "?select=all"} {
+		req, err := http.NewRequest("GET", "/v2/snaps"+q, nil)
+		c.Assert(err, check.IsNil)
+		rsp := getSnapsInfo(snapsCmd, req, nil).(*resp)
+		c.Assert(rsp.Type, check.Equals, ResponseTypeSync)
+
+		got := make(map[string]interface{})
+		for _, snap := range snapList(rsp.Result) {
+			got[snap["name"].(string)] = snap["version"]
+		}
+		c.Check(got, check.DeepEquals, map[string]interface{}{
+			"foo":          "v1",
+			"foo_instance": "v2",
+		}, check.Commentf(q))
+	}
+}
+
 func (s *apiSuite) TestFind(c *check.C) {
 	s.suggestedCurrency = "EUR"
 
@@ -2087,6 +2134,106 @@ func (s *apiSuite) TestPostSnap(c *check.C) {
 	c.Check(soon, check.Equals, 1)
 }
 
+func (s *apiSuite) TestPostSnapHold(c *check.C) {
+	s.daemonWithOverlordMock(c)
+	s.vars = map[string]string{"name": "foo"}
+
+	until := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	var held []string
+	snapstateHoldRefresh = func(st *state.State, name string, t time.Time) error {
+		held = append(held, fmt.Sprintf("%s %s", name, t.Format(time.RFC3339)))
+		return nil
+	}
+
+	for _, body := range []string{
+		`{"action": "hold", "hold-until": "2030-01-02T03:04:05Z"}`,
+		`{"action": "hold"}`,
+	} {
+		req, err := http.NewRequest("POST", "/v2/snaps/foo", bytes.NewBufferString(body))
+		c.Assert(err, check.IsNil)
+
+		rsp := postSnap(snapCmd, req, nil).(*resp)
+		c.Check(rsp.Type, check.Equals, ResponseTypeSync)
+		c.Check(rsp.Status, check.Equals, 200)
+	}
+	c.Check(held, check.DeepEquals, []string{
+		"foo " + until.Format(time.RFC3339),
+		"foo " + time.Time{}.Format(time.RFC3339),
+	})
+}
+
+func (s *apiSuite) TestPostSnapUnhold(c *check.C) {
+	s.daemonWithOverlordMock(c)
+	s.vars = map[string]string{"name": "foo"}
+
+	var unheld []string
+	snapstateUnholdRefresh = func(st *state.State, name string) error {
+		unheld = append(unheld, name)
+		return nil
+	}
+
+	req, err := http.NewRequest("POST", "/v2/snaps/foo", bytes.NewBufferString(`{"action": "unhold"}`))
+	c.Assert(err, check.IsNil)
+
+	rsp := postSnap(snapCmd, req, nil).(*resp)
+	c.Check(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(unheld, check.DeepEquals, []string{"foo"})
+}
+
+func (s *apiSuite) TestPostSnapHoldNotInstalled(c *check.C) {
+	s.daemonWithOverlordMock(c)
+	s.vars = map[string]string{"name": "foo"}
+
+	snapstateHoldRefresh = func(st *state.State, name string, t time.Time) error {
+		return &snap.NotInstalledError{Snap: name}
+	}
+
+	req, err := http.NewRequest("POST", "/v2/snaps/foo", bytes.NewBufferString(`{"action": "hold"}`))
+	c.Assert(err, check.IsNil)
+
+	rsp := postSnap(snapCmd, req, nil).(*resp)
+	c.Check(rsp.Type, check.Equals, ResponseTypeError)
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Kind, check.Equals, errorKindSnapNotInstalled)
+}
+
+func (s *apiSuite) TestMapLocalRefreshHold(c *check.C) {
+	info := snap.Info{SideInfo: snap.SideInfo{RealName: "hello", Revision: snap.R(1)}}
+	snapst := snapstate.SnapState{}
+	about := aboutSnap{info: &info, snapst: &snapst}
+
+	c.Check(mapLocal(about).RefreshHold, check.IsNil)
+
+	// held indefinitely
+	snapst.RefreshHold = &time.Time{}
+	c.Assert(mapLocal(about).RefreshHold, check.NotNil)
+	c.Check(mapLocal(about).RefreshHold.IsZero(), check.Equals, true)
+
+	// held until a given time
+	until := time.Now().Add(time.Hour)
+	snapst.RefreshHold = &until
+	c.Assert(mapLocal(about).RefreshHold, check.NotNil)
+	c.Check(mapLocal(about).RefreshHold.Equal(until), check.Equals, true)
+
+	// expired holds are not shown
+	expired := time.Now().Add(-time.Hour)
+	snapst.RefreshHold = &expired
+	c.Check(mapLocal(about).RefreshHold, check.IsNil)
+}
+
+func (s *apiSuite) TestMapLocalRefreshInhibited(c *check.C) {
+	info := snap.Info{SideInfo: snap.SideInfo{RealName: "hello", Revision: snap.R(1)}}
+	snapst := snapstate.SnapState{}
+	about := aboutSnap{info: &info, snapst: &snapst}
+
+	c.Check(mapLocal(about).RefreshInhibitedSince, check.IsNil)
+
+	since := time.Now().Add(-time.Hour)
+	snapst.RefreshInhibitedTime = &since
+	c.Assert(mapLocal(about).RefreshInhibitedSince, check.NotNil)
+	c.Check(mapLocal(about).RefreshInhibitedSince.Equal(since), check.Equals, true)
+}
+
 func (s *apiSuite) TestPostSnapVerfySnapInstruction(c *check.C) {
 	s.daemonWithOverlordMock(c)
 
@@ -4719,6 +4866,121 @@ func (s *apiSuite) TestStateChange(c *check.C) {
 	})
 }
 
+func decodeChangeEvents(c *check.C, body []byte) []map[string]interface{} {
+	var events []map[string]interface{}
+	for _, rec := range bytes.Split(body, []byte{0x1E}) {
+		if len(rec) == 0 {
+			continue
+		}
+		var ev map[string]interface{}
+		c.Assert(json.Unmarshal(rec, &ev), check.IsNil)
+		events = append(events, ev)
+	}
+	return events
+}
+
+func (s *apiSuite) TestStateChangeEventsNotFound(c *check.C) {
+	newTestDaemon(c)
+	s.vars = map[string]string{"id": "42"}
+
+	req, err := http.NewRequest("GET", "/v2/changes/42/events", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getChangeEvents(stateChangeEventsCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, 404)
+}
+
+func (s *apiSuite) TestStateChangeEventsReady(c *check.C) {
+	d := newTestDaemon(c)
+	st := d.overlord.State()
+	st.Lock()
+	ids := setupChanges(st)
+	st.Unlock()
+	// the second change is in error, so it's ready
+	s.vars = map[string]string{"id": ids[1]}
+
+	req, err := http.NewRequest("GET", "/v2/changes/"+ids[1]+"/events", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getChangeEvents(stateChangeEventsCmd, req, nil)
+	rec := httptest.NewRecorder()
+	rsp.ServeHTTP(rec, req)
+
+	c.Check(rec.Code, check.Equals, 200)
+	c.Check(rec.HeaderMap.Get("Content-Type"), check.Equals, "application/json-seq")
+	events := decodeChangeEvents(c, rec.Body.Bytes())
+	c.Assert(events, check.HasLen, 1)
+	c.Check(events[0]["kind"], check.Equals, "change")
+	chg := events[0]["change"].(map[string]interface{})
+	c.Check(chg["id"], check.Equals, ids[1])
+	c.Check(chg["ready"], check.Equals, true)
+}
+
+func (s *apiSuite) TestStateChangeEventsFollow(c *check.C) {
+	d := newTestDaemon(c)
+	st := d.overlord.State()
+	st.Lock()
+	ids := setupChanges(st)
+	st.Unlock()
+	s.vars = map[string]string{"id": ids[0]}
+
+	req, err := http.NewRequest("GET", "/v2/changes/"+ids[0]+"/events", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getChangeEvents(stateChangeEventsCmd, req, nil)
+
+	st.Lock()
+	t1 := st.Task(ids[2])
+	t2 := st.Task(ids[3])
+	t1.SetStatus(state.DoingStatus)
+	t1.SetProgress("foo", 5, 10)
+	t1.Logf("l13")
+	t1.SetStatus(state.DoneStatus)
+	t2.SetStatus(state.DoneStatus)
+	st.Unlock()
+
+	rec := httptest.NewRecorder()
+	rsp.ServeHTTP(rec, req)
+
+	c.Check(rec.Code, check.Equals, 200)
+	events := decodeChangeEvents(c, rec.Body.Bytes())
+	c.Assert(events, check.HasLen, 7)
+	c.Check(events[0]["kind"], check.Equals, "change")
+	c.Check(events[0]["change"].(map[string]interface{})["ready"], check.Equals, false)
+	c.Check(events[1], check.DeepEquals, map[string]interface{}{
+		"kind":    "task-status",
+		"task-id": ids[2],
+		"status":  "Done",
+	})
+	c.Check(events[2], check.DeepEquals, map[string]interface{}{
+		"kind":     "task-progress",
+		"task-id":  ids[2],
+		"progress": map[string]interface{}{"label": "foo", "done": 5., "total": 10.},
+	})
+	c.Check(events[3]["kind"], check.Equals, "task-log")
+	c.Check(events[3]["task-id"], check.Equals, ids[2])
+	c.Check(events[3]["log"], check.Matches, ".* INFO l13")
+	c.Check(events[4], check.DeepEquals, map[string]interface{}{
+		"kind":    "task-status",
+		"task-id": ids[3],
+		"status":  "Done",
+	})
+	// tasks without explicit progress are done once done
+	c.Check(events[5], check.DeepEquals, map[string]interface{}{
+		"kind":     "task-progress",
+		"task-id":  ids[3],
+		"progress": map[string]interface{}{"label": "", "done": 1., "total": 1.},
+	})
+	c.Check(events[6]["kind"], check.Equals, "change")
+	c.Check(events[6]["change"].(map[string]interface{})["ready"], check.Equals, true)
+}
+
+func (s *apiSuite) TestNewLogEntries(c *check.C) {
+	c.Check(newLogEntries(nil, []string{"a", "b"}), check.DeepEquals, []string{"a", "b"})
+	c.Check(newLogEntries([]string{"a", "b"}, []string{"a", "b"}), check.HasLen, 0)
+	c.Check(newLogEntries([]string{"a", "b"}, []string{"a", "b", "c"}), check.DeepEquals, []string{"c"})
+	// the oldest entries were dropped
+	c.Check(newLogEntries([]string{"a", "b"}, []string{"b", "c", "d"}), check.DeepEquals, []string{"c", "d"})
+	c.Check(newLogEntries([]string{"a", "b"}, []string{"c", "d"}), check.DeepEquals, []string{"c", "d"})
+}
+
 func (s *apiSuite) TestStateChangeAbort(c *check.C) {
 	restore := state.MockTime(time.Date(2016, 04, 21, 1, 2, 3, 0, time.UTC))
 	defer restore()
@@ -6089,6 +6351,12 @@ func (s *apiSuite) TestSnapctlGetUID(c *check.C) {
 		{0, "get", "something", 200},
 		{1000, "set", "some=thing", 403},
 		{0, "set", "some=thing", 200},
+		{1000, "is-connected", "plug", 200},
+		{0, "is-connected", "plug", 200},
+		{1000, "services", "foo.svc", 200},
+		{0, "services", "foo.svc", 200},
+		{1000, "stop", "foo.svc", 403},
+		{0, "stop", "foo.svc", 200},
 	} {
 		uid = t.uid
 		buf := bytes.NewBufferString(fmt.Sprintf(`{"context-id": "some-context", "args": [%q, %q]}`, t.cmd, t.arg))
@@ -6099,6 +6367,35 @@ func (s *apiSuite) TestSnapctlGetUID(c *check.C) {
 	}
 }
 
+func (s *apiSuite) TestSnapctlUnsuccessfulError(c *check.C) {
+	_ = s.daemon(c)
+
+	runSnapctlUcrednetGet = func(string) (uint32, uint32, string, error) {
+		return 100, 1000, dirs.SnapSocket, nil
+	}
+	defer func() { runSnapctlUcrednetGet = ucrednetGet }()
+	ctlcmdRun = func(*hookstate.Context, []string) ([]byte, []byte, error) {
+		return []byte("out"), []byte("err"), &ctlcmd.UnsuccessfulError{ExitCode: 1}
+	}
+	defer func() { ctlcmdRun = ctlcmd.Run }()
+
+	buf := bytes.NewBufferString(`{"context-id": "some-context", "args": ["is-connected", "plug"]}`)
+	req, err := http.NewRequest("POST", "/v2/snapctl", buf)
+	c.Assert(err, check.IsNil)
+	rsp := runSnapctl(snapctlCmd, req, nil).(*resp)
+	c.Check(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Type, check.Equals, ResponseTypeError)
+	c.Check(rsp.Result, check.DeepEquals, &errorResult{
+		Message: "unsuccessful with exit code: 1",
+		Kind:    errorKindUnsuccessful,
+		Value: map[string]interface{}{
+			"stdout":    "out",
+			"stderr":    "err",
+			"exit-code": 1,
+		},
+	})
+}
+
 var _ = check.Suite(&postDebugSuite{})
 
 type postDebugSuite struct {
@@ -6273,6 +6570,27 @@ UnitFileState=enabled
 	c.Check(sort.StringsAreSorted(appNames), check.Equals, true)
 }
 
+func (s *appSuite) TestGetAppsInfoUserService(c *check.C) {
+	s.mkInstalledInState(c, s.d, "snap-e", "dev", "v1", snap.R(1), true, "apps: {svc4: {daemon: simple, daemon-scope: user}}")
+
+	req, err := http.NewRequest("GET", "/v2/apps?names=snap-e", nil)
+	c.Assert(err, check.IsNil)
+
+	rsp := getAppsInfo(appsCmd, req, nil).(*resp)
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Assert(rsp.Result, check.FitsTypeOf, []client.AppInfo{})
+	c.Check(rsp.Result.([]client.AppInfo), check.DeepEquals, []client.AppInfo{{
+		Snap:        "snap-e",
+		Name:        "svc4",
+		Daemon:      "simple",
+		DaemonScope: "user",
+		Enabled:     true,
+	}})
+	c.Check(s.sysctlArgses, check.DeepEquals, [][]string{
+		{"--user", "--global", "--root", dirs.GlobalRootDir, "is-enabled", "snap.snap-e.svc4.service"},
+	})
+}
+
 func (s *appSuite) TestGetAppsInfoNames(c *check.C) {
 
 	req, err := http.NewRequest("GET", "/v2/apps?names=snap-d", nil)
@@ -6527,6 +6845,22 @@ func (s *appSuite) TestLogs(c *check.C) {
 `[1:])
 }
 
+func (s *appSuite) TestLogsUserServices(c *check.C) {
+	s.mkInstalledInState(c, s.d, "snap-e", "dev", "v1", snap.R(1), true, "apps: {svc4: {daemon: simple, daemon-scope: user}, svc5: {daemon: simple}}")
+	s.jctlRCs = []io.ReadCloser{ioutil.NopCloser(strings.NewReader(""))}
+
+	req, err := http.NewRequest("GET", "/v2/logs?names=snap-e", nil)
+	c.Assert(err, check.IsNil)
+
+	rec := httptest.NewRecorder()
+	getLogs(logsCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 200)
+
+	c.Check(s.jctlSvcses, check.DeepEquals, [][]string{{"snap.snap-e.svc5.service"}})
+	c.Assert(s.jctlOpts, check.HasLen, 1)
+	c.Check(s.jctlOpts[0].UserServices, check.DeepEquals, []string{"snap.snap-e.svc4.service"})
+}
+
 func (s *appSuite) TestLogsN(c *check.C) {
 	type T struct {
 		in  string
@@ -6603,6 +6937,118 @@ func (s *appSuite) TestLogsBadName(c *check.C) {
 	c.Assert(rsp.Type, check.Equals, ResponseTypeError)
 }
 
+func (s *appSuite) TestLogsFilters(c *check.C) {
+	s.jctlRCs = []io.ReadCloser{ioutil.NopCloser(strings.NewReader(""))}
+
+	req, err := http.NewRequest("GET", "/v2/logs?names=snap-a.svc2&priority=warning&since=2018-03-01T10:00:00Z&until=2018-03-01T11:00:00%2B01:00&cursor=s%3Dabc%3Bi%3D42", nil)
+	c.Assert(err, check.IsNil)
+
+	rec := httptest.NewRecorder()
+	getLogs(logsCmd, req, nil).ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 200)
+
+	c.Assert(s.jctlOpts, check.HasLen, 1)
+	opts := s.jctlOpts[0]
+	c.Check(opts.N, check.Equals, "10")
+	c.Check(opts.Priority, check.Equals, "4")
+	c.Check(opts.Cursor, check.Equals, "s=abc;i=42")
+	c.Check(opts.Since.Equal(time.Date(2018, 3, 1, 10, 0, 0, 0, time.UTC)), check.Equals, true)
+	c.Check(opts.Until.Equal(time.Date(2018, 3, 1, 10, 0, 0, 0, time.UTC)), check.Equals, true)
+}
+
+func (s *appSuite) TestLogsBadFilters(c *check.C) {
+	for _, q := range []string{
+		"priority=loud",
+		"since=yesterday",
+		"until=2018-03-01",
+		"grep=(",
+		"fields=some",
+	} {
+		req, err := http.NewRequest("GET", "/v2/logs?"+q, nil)
+		c.Assert(err, check.IsNil)
+
+		rsp := getLogs(logsCmd, req, nil).(*resp)
+		c.Check(rsp.Status, check.Equals, 400, check.Commentf(q))
+		c.Check(rsp.Type, check.Equals, ResponseTypeError, check.Commentf(q))
+	}
+	c.Check(s.jctlOpts, check.HasLen, 0)
+}
+
+func (s *appSuite) TestLogsGrepAndAllFields(c *check.C) {
+	s.jctlRCs = []io.ReadCloser{ioutil.NopCloser(strings.NewReader(`
+{"MESSAGE": "hello1", "SYSLOG_IDENTIFIER": "xyzzy", "_PID": "42", "__REALTIME_TIMESTAMP": "42", "__CURSOR": "c1", "_BOOT_ID": "b1", "_SYSTEMD_UNIT": "snap.snap-a.svc2.service", "PRIORITY": "6"}
+{"MESSAGE": "failed2", "SYSLOG_IDENTIFIER": "xyzzy", "_PID": "42", "__REALTIME_TIMESTAMP": "44", "__CURSOR": "c2", "_BOOT_ID": "b1", "_SYSTEMD_UNIT": "snap.snap-a.svc2.service", "PRIORITY": "3"}
+{"MESSAGE": "hello3", "SYSLOG_IDENTIFIER": "xyzzy", "_PID": "42", "__REALTIME_TIMESTAMP": "46", "__CURSOR": "c3", "_BOOT_ID": "b1", "_SYSTEMD_UNIT": "snap.snap-a.svc2.service", "PRIORITY": "6"}
+	`))}
+
+	req, err := http.NewRequest("GET", "/v2/logs?names=snap-a.svc2&grep=^fail&fields=all", nil)
+	c.Assert(err, check.IsNil)
+
+	rec := httptest.NewRecorder()
+	getLogs(logsCmd, req, nil).ServeHTTP(rec, req)
+
+	c.Check(rec.Code, check.Equals, 200)
+	c.Check(rec.Body.String(), check.Equals, `
+{"timestamp":"1970-01-01T00:00:00.000044Z","message":"failed2","sid":"xyzzy","pid":"42","cursor":"c2","boot-id":"b1","unit":"snap.snap-a.svc2.service","priority":"3","fields":{"MESSAGE":"failed2","PRIORITY":"3","SYSLOG_IDENTIFIER":"xyzzy","_BOOT_ID":"b1","_PID":"42","_SYSTEMD_UNIT":"snap.snap-a.svc2.service","__CURSOR":"c2","__REALTIME_TIMESTAMP":"44"}}
+`[1:])
+}
+
+func (s *appSuite) TestLogsGrepAndN(c *check.C) {
+	s.jctlRCs = []io.ReadCloser{ioutil.NopCloser(strings.NewReader(`
+{"MESSAGE": "failed1", "__CURSOR": "c1"}
+{"MESSAGE": "hello2", "__CURSOR": "c2"}
+{"MESSAGE": "failed3", "__CURSOR": "c3"}
+{"MESSAGE": "failed4", "__CURSOR": "c4"}
+{"MESSAGE": "hello5", "__CURSOR": "c5"}
+	`))}
+
+	req, err := http.NewRequest("GET", "/v2/logs?names=snap-a.svc2&grep=^fail&n=2", nil)
+	c.Assert(err, check.IsNil)
+
+	rec := httptest.NewRecorder()
+	getLogs(logsCmd, req, nil).ServeHTTP(rec, req)
+
+	// the last 2 matching lines, not the matching ones among the
+	// last 2 lines
+	c.Check(rec.Code, check.Equals, 200)
+	c.Check(rec.Body.String(), check.Matches, "\x1e.*\"message\":\"failed3\".*\n\x1e.*\"message\":\"failed4\".*\n")
+	c.Assert(s.jctlOpts, check.HasLen, 1)
+	c.Check(s.jctlOpts[0].N, check.Equals, "all")
+	c.Check(s.jctlOpts[0].Follow, check.Equals, false)
+}
+
+func (s *appSuite) TestLogsGrepAndNFollow(c *check.C) {
+	s.jctlRCs = []io.ReadCloser{
+		ioutil.NopCloser(strings.NewReader(`
+{"MESSAGE": "failed1", "__CURSOR": "c1"}
+{"MESSAGE": "failed2", "__CURSOR": "c2"}
+{"MESSAGE": "hello3", "__CURSOR": "c3"}
+	`)),
+		ioutil.NopCloser(strings.NewReader(`
+{"MESSAGE": "hello4", "__CURSOR": "c4"}
+{"MESSAGE": "failed5", "__CURSOR": "c5"}
+	`)),
+	}
+
+	req, err := http.NewRequest("GET", "/v2/logs?names=snap-a.svc2&grep=^fail&n=1&follow=true&cursor=c0", nil)
+	c.Assert(err, check.IsNil)
+
+	rec := httptest.NewRecorder()
+	getLogs(logsCmd, req, nil).ServeHTTP(rec, req)
+
+	// the last matching line so far, then the new matching ones
+	c.Check(rec.Code, check.Equals, 200)
+	c.Check(rec.Body.String(), check.Matches, "\x1e.*\"message\":\"failed2\".*\n\x1e.*\"message\":\"failed5\".*\n")
+	c.Assert(s.jctlOpts, check.HasLen, 2)
+	c.Check(s.jctlOpts[0].N, check.Equals, "all")
+	c.Check(s.jctlOpts[0].Follow, check.Equals, false)
+	c.Check(s.jctlOpts[0].Cursor, check.Equals, "c0")
+	// following starts after the last line read
+	c.Check(s.jctlOpts[1].N, check.Equals, "all")
+	c.Check(s.jctlOpts[1].Follow, check.Equals, true)
+	c.Check(s.jctlOpts[1].Cursor, check.Equals, "c3")
+}
+
 func (s *appSuite) TestLogsSad(c *check.C) {
 	s.jctlErrs = []error{errors.New("potato")}
 	req, err := http.NewRequest("GET", "/v2/logs", nil)
@@ -6756,7 +7202,7 @@ func (s *appSuite) TestPostAppsConflict(c *check.C) {
 		}
 	}()
 
-	ts, err := snapstate.Remove(st, "snap-a", snap.R(0))
+	ts, err := snapstate.Remove(st, "snap-a", snap.R(0), nil)
 	c.Assert(err, check.IsNil)
 	// need a change to make the tasks visible
 	st.NewChange("enable", "...").AddAll(ts)
@@ -0,0 +1,190 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var (
+	validationSetsListCmd = &Command{
+		Path:   "/v2/validation-sets",
+		UserOK: true,
+		GET:    listValidationSets,
+	}
+
+	validationSetsCmd = &Command{
+		Path:   "/v2/validation-sets/{account}/{name}",
+		UserOK: true,
+		GET:    getValidationSet,
+		POST:   applyValidationSet,
+	}
+)
+
+var (
+	assertstateApplyValidationSet  = assertstate.ApplyValidationSet
+	assertstateForgetValidationSet = assertstate.ForgetValidationSet
+)
+
+type validationSetResult struct {
+	AccountID string `json:"account-id"`
+	Name      string `json:"name"`
+	PinnedAt  int    `json:"pinned-at,omitempty"`
+	Mode      string `json:"mode"`
+	Sequence  int    `json:"sequence"`
+	Valid     bool   `json:"valid"`
+	// Notes describes why the validation set is not satisfied
+	Notes string `json:"notes,omitempty"`
+}
+
+func validationSetResultFor(st *state.State, tr *assertstate.ValidationSetTracking) (*validationSetResult, error) {
+	vs, err := assertstate.ValidationSet(st, tr.AccountID, tr.Name, tr.Current)
+	if err != nil {
+		return nil, err
+	}
+	res := &validationSetResult{
+		AccountID: tr.AccountID,
+		Name:      tr.Name,
+		PinnedAt:  tr.PinnedAt,
+		Mode:      string(tr.Mode),
+		Sequence:  tr.Current,
+		Valid:     true,
+	}
+	if err := assertstate.CheckInstalledSnaps(st, vs); err != nil {
+		res.Valid = false
+		res.Notes = err.Error()
+	}
+	return res, nil
+}
+
+func listValidationSets(c *Command, r *http.Request, _ *auth.UserState) Response {
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	vsmap, err := assertstate.ValidationSets(st)
+	if err != nil {
+		return InternalError("cannot list validation sets: %v", err)
+	}
+	keys := make([]string, 0, len(vsmap))
+	for key := range vsmap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	results := make([]*validationSetResult, 0, len(keys))
+	for _, key := range keys {
+		res, err := validationSetResultFor(st, vsmap[key])
+		if err != nil {
+			return InternalError("cannot get validation set %s: %v", key, err)
+		}
+		results = append(results, res)
+	}
+
+	return SyncResponse(results, nil)
+}
+
+func getValidationSet(c *Command, r *http.Request, _ *auth.UserState) Response {
+	vars := muxVars(r)
+	accountID := vars["account"]
+	name := vars["name"]
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var tr assertstate.ValidationSetTracking
+	err := assertstate.GetValidationSet(st, accountID, name, &tr)
+	if err == state.ErrNoState {
+		return NotFound("validation set %s is not tracked", assertstate.ValidationSetKey(accountID, name))
+	}
+	if err != nil {
+		return InternalError("cannot get validation set: %v", err)
+	}
+	res, err := validationSetResultFor(st, &tr)
+	if err != nil {
+		return InternalError("cannot get validation set: %v", err)
+	}
+
+	return SyncResponse(res, nil)
+}
+
+type validationSetAction struct {
+	Action   string `json:"action"`
+	Mode     string `json:"mode"`
+	Sequence int    `json:"sequence,omitempty"`
+}
+
+func applyValidationSet(c *Command, r *http.Request, user *auth.UserState) Response {
+	vars := muxVars(r)
+	accountID := vars["account"]
+	name := vars["name"]
+
+	var action validationSetAction
+	decoder := json.NewDecoder(r.Body)
+	if err := decoder.Decode(&action); err != nil {
+		return BadRequest("cannot decode request body into validation set action: %v", err)
+	}
+
+	st := c.d.overlord.State()
+	st.Lock()
+	defer st.Unlock()
+
+	switch action.Action {
+	case "apply":
+		if action.Sequence < 0 {
+			return BadRequest("invalid sequence %d", action.Sequence)
+		}
+		mode := assertstate.ValidationSetMode(action.Mode)
+		if mode != assertstate.MonitorMode && mode != assertstate.EnforceMode {
+			return BadRequest("invalid mode %q", action.Mode)
+		}
+		userID := 0
+		if user != nil {
+			userID = user.ID
+		}
+		tr, err := assertstateApplyValidationSet(st, accountID, name, action.Sequence, mode, userID)
+		if err != nil {
+			if _, ok := err.(*asserts.NotFoundError); ok {
+				return NotFound("cannot apply validation set: %v", err)
+			}
+			return BadRequest("cannot apply validation set: %v", err)
+		}
+		res, err := validationSetResultFor(st, tr)
+		if err != nil {
+			return InternalError("cannot get validation set: %v", err)
+		}
+		return SyncResponse(res, nil)
+	case "forget":
+		if err := assertstateForgetValidationSet(st, accountID, name); err != nil {
+			return BadRequest("cannot forget validation set: %v", err)
+		}
+		return SyncResponse(nil, nil)
+	default:
+		return BadRequest("unknown validation set action %q", action.Action)
+	}
+}
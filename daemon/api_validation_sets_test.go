@@ -0,0 +1,229 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+var _ = check.Suite(&validationSetsSuite{})
+
+type validationSetsSuite struct {
+	apiBaseSuite
+}
+
+func (s *validationSetsSuite) TearDownTest(c *check.C) {
+	assertstateApplyValidationSet = assertstate.ApplyValidationSet
+	assertstateForgetValidationSet = assertstate.ForgetValidationSet
+	s.apiBaseSuite.TearDownTest(c)
+}
+
+func (s *validationSetsSuite) mockValidationSet(c *check.C, st *state.State, mode assertstate.ValidationSetMode) {
+	a, err := s.storeSigning.RootSigning.Sign(asserts.ValidationSetType, map[string]interface{}{
+		"series":     "16",
+		"account-id": "can0nical",
+		"name":       "my-set",
+		"sequence":   "3",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name": "foo",
+				"id":   "fooididididididididididididididi",
+			},
+		},
+		"timestamp": time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, check.IsNil)
+	assertAdd(st, a)
+
+	st.Lock()
+	defer st.Unlock()
+	st.Set("validation-sets", map[string]*assertstate.ValidationSetTracking{
+		"can0nical/my-set": {
+			AccountID: "can0nical",
+			Name:      "my-set",
+			Mode:      mode,
+			Current:   3,
+		},
+	})
+}
+
+func (s *validationSetsSuite) req(c *check.C, method, path string, body interface{}) *resp {
+	var buf bytes.Buffer
+	if body != nil {
+		err := json.NewEncoder(&buf).Encode(body)
+		c.Assert(err, check.IsNil)
+	}
+	req, err := http.NewRequest(method, path, &buf)
+	c.Assert(err, check.IsNil)
+
+	cmd := validationSetsListCmd
+	if parts := strings.Split(strings.TrimPrefix(path, "/v2/validation-sets/"), "/"); len(parts) == 2 {
+		cmd = validationSetsCmd
+		s.vars = map[string]string{"account": parts[0], "name": parts[1]}
+	}
+	var rsp Response
+	switch method {
+	case "GET":
+		rsp = cmd.GET(cmd, req, nil)
+	case "POST":
+		rsp = cmd.POST(cmd, req, nil)
+	}
+	return rsp.(*resp)
+}
+
+func (s *validationSetsSuite) TestListValidationSets(c *check.C) {
+	d := s.daemon(c)
+	st := d.overlord.State()
+
+	rsp := s.req(c, "GET", "/v2/validation-sets", nil)
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result, check.DeepEquals, []*validationSetResult{})
+
+	s.mockValidationSet(c, st, assertstate.MonitorMode)
+
+	rsp = s.req(c, "GET", "/v2/validation-sets", nil)
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result, check.DeepEquals, []*validationSetResult{{
+		AccountID: "can0nical",
+		Name:      "my-set",
+		Mode:      "monitor",
+		Sequence:  3,
+		Valid:     false,
+		Notes:     "validation set can0nical/my-set is not satisfied:\n - snap \"foo\" is required but not installed",
+	}})
+}
+
+func (s *validationSetsSuite) TestGetValidationSet(c *check.C) {
+	d := s.daemon(c)
+	st := d.overlord.State()
+
+	rsp := s.req(c, "GET", "/v2/validation-sets/can0nical/my-set", nil)
+	c.Check(rsp.Status, check.Equals, 404)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, "validation set can0nical/my-set is not tracked")
+
+	s.mockValidationSet(c, st, assertstate.EnforceMode)
+	st.Lock()
+	snapstate.Set(st, "foo", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "foo", SnapID: "fooididididididididididididididi", Revision: snap.R(1)},
+		},
+		Current: snap.R(1),
+	})
+	st.Unlock()
+
+	rsp = s.req(c, "GET", "/v2/validation-sets/can0nical/my-set", nil)
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result, check.DeepEquals, &validationSetResult{
+		AccountID: "can0nical",
+		Name:      "my-set",
+		Mode:      "enforce",
+		Sequence:  3,
+		Valid:     true,
+	})
+}
+
+func (s *validationSetsSuite) TestApplyValidationSet(c *check.C) {
+	d := s.daemon(c)
+	st := d.overlord.State()
+	s.mockValidationSet(c, st, assertstate.MonitorMode)
+
+	var called int
+	assertstateApplyValidationSet = func(st *state.State, accountID, name string, sequence int, mode assertstate.ValidationSetMode, userID int) (*assertstate.ValidationSetTracking, error) {
+		called++
+		c.Check(accountID, check.Equals, "can0nical")
+		c.Check(name, check.Equals, "my-set")
+		c.Check(sequence, check.Equals, 3)
+		c.Check(mode, check.Equals, assertstate.EnforceMode)
+		return &assertstate.ValidationSetTracking{
+			AccountID: accountID,
+			Name:      name,
+			Mode:      mode,
+			PinnedAt:  sequence,
+			Current:   sequence,
+		}, nil
+	}
+
+	rsp := s.req(c, "POST", "/v2/validation-sets/can0nical/my-set", map[string]interface{}{
+		"action":   "apply",
+		"mode":     "enforce",
+		"sequence": 3,
+	})
+	c.Assert(rsp.Status, check.Equals, 200)
+	c.Check(called, check.Equals, 1)
+	res := rsp.Result.(*validationSetResult)
+	c.Check(res.Mode, check.Equals, "enforce")
+	c.Check(res.PinnedAt, check.Equals, 3)
+}
+
+func (s *validationSetsSuite) TestApplyValidationSetErrors(c *check.C) {
+	s.daemon(c)
+
+	assertstateApplyValidationSet = func(st *state.State, accountID, name string, sequence int, mode assertstate.ValidationSetMode, userID int) (*assertstate.ValidationSetTracking, error) {
+		return nil, errors.New("boom")
+	}
+
+	for _, t := range []struct {
+		body   interface{}
+		status int
+		errStr string
+	}{
+		{"not-an-object", 400, `cannot decode request body into validation set action: .*`},
+		{map[string]interface{}{"action": "frobnicate"}, 400, `unknown validation set action "frobnicate"`},
+		{map[string]interface{}{"action": "apply", "mode": "bogus"}, 400, `invalid mode "bogus"`},
+		{map[string]interface{}{"action": "apply", "mode": "monitor", "sequence": -1}, 400, `invalid sequence -1`},
+		{map[string]interface{}{"action": "apply", "mode": "monitor"}, 400, `cannot apply validation set: boom`},
+		{map[string]interface{}{"action": "forget"}, 400, `cannot forget validation set: validation set can0nical/my-set is not tracked`},
+	} {
+		rsp := s.req(c, "POST", "/v2/validation-sets/can0nical/my-set", t.body)
+		c.Check(rsp.Status, check.Equals, t.status, check.Commentf("%v", t.body))
+		c.Check(rsp.Result.(*errorResult).Message, check.Matches, t.errStr)
+	}
+}
+
+func (s *validationSetsSuite) TestForgetValidationSet(c *check.C) {
+	d := s.daemon(c)
+	st := d.overlord.State()
+	s.mockValidationSet(c, st, assertstate.MonitorMode)
+
+	rsp := s.req(c, "POST", "/v2/validation-sets/can0nical/my-set", map[string]interface{}{
+		"action": "forget",
+	})
+	c.Assert(rsp.Status, check.Equals, 200)
+
+	st.Lock()
+	defer st.Unlock()
+	vsmap, err := assertstate.ValidationSets(st)
+	c.Assert(err, check.IsNil)
+	c.Check(vsmap, check.HasLen, 0)
+}
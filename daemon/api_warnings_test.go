@@ -0,0 +1,122 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+var _ = check.Suite(&warningSuite{})
+
+type warningSuite struct {
+	apiBaseSuite
+}
+
+func (s *warningSuite) addWarnings(c *check.C) (st *state.State, last time.Time) {
+	st = s.daemonWithOverlordMock(c).overlord.State()
+	st.Lock()
+	defer st.Unlock()
+	st.Warnf("hello world")
+	st.Warnf("something happened")
+	_, last = st.PendingWarnings()
+	return st, last
+}
+
+func (s *warningSuite) TestGetWarnings(c *check.C) {
+	st, _ := s.addWarnings(c)
+
+	for _, q := range []string{"", "?select=pending", "?select=all"} {
+		req, err := http.NewRequest("GET", "/v2/warnings"+q, nil)
+		c.Assert(err, check.IsNil)
+		rsp := getWarnings(warningsCmd, req, nil).(*resp)
+		c.Check(rsp.Type, check.Equals, ResponseTypeSync, check.Commentf("%q", q))
+		c.Check(rsp.Status, check.Equals, 200)
+		c.Assert(rsp.Result, check.HasLen, 2)
+		ws := rsp.Result.([]*state.Warning)
+		c.Check(ws[0].String(), check.Equals, "hello world")
+		c.Check(ws[1].String(), check.Equals, "something happened")
+	}
+
+	st.Lock()
+	st.OkayWarnings(time.Now())
+	st.Unlock()
+
+	req, err := http.NewRequest("GET", "/v2/warnings", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getWarnings(warningsCmd, req, nil).(*resp)
+	c.Check(rsp.Result, check.DeepEquals, []state.Warning{})
+
+	req, err = http.NewRequest("GET", "/v2/warnings?select=all", nil)
+	c.Assert(err, check.IsNil)
+	rsp = getWarnings(warningsCmd, req, nil).(*resp)
+	c.Check(rsp.Result, check.HasLen, 2)
+}
+
+func (s *warningSuite) TestGetWarningsBadSelect(c *check.C) {
+	s.daemonWithOverlordMock(c)
+
+	req, err := http.NewRequest("GET", "/v2/warnings?select=foo", nil)
+	c.Assert(err, check.IsNil)
+	rsp := getWarnings(warningsCmd, req, nil).(*resp)
+	c.Check(rsp.Type, check.Equals, ResponseTypeError)
+	c.Check(rsp.Status, check.Equals, 400)
+	c.Check(rsp.Result.(*errorResult).Message, check.Equals, `invalid select parameter: "foo"`)
+}
+
+func (s *warningSuite) TestAckWarnings(c *check.C) {
+	st, last := s.addWarnings(c)
+
+	buf, err := json.Marshal(map[string]interface{}{"action": "okay", "timestamp": last})
+	c.Assert(err, check.IsNil)
+	req, err := http.NewRequest("POST", "/v2/warnings", bytes.NewReader(buf))
+	c.Assert(err, check.IsNil)
+	rsp := ackWarnings(warningsCmd, req, nil).(*resp)
+	c.Check(rsp.Type, check.Equals, ResponseTypeSync)
+	c.Check(rsp.Status, check.Equals, 200)
+	c.Check(rsp.Result, check.DeepEquals, 2)
+
+	st.Lock()
+	defer st.Unlock()
+	n, _ := st.WarningsSummary()
+	c.Check(n, check.Equals, 0)
+}
+
+func (s *warningSuite) TestAckWarningsErrors(c *check.C) {
+	s.daemonWithOverlordMock(c)
+
+	for body, msg := range map[string]string{
+		`{"action": "forget"}`: `unknown warning action "forget"`,
+		`{"action": `:          `cannot decode request body into warnings operation: unexpected EOF`,
+	} {
+		req, err := http.NewRequest("POST", "/v2/warnings", bytes.NewBufferString(body))
+		c.Assert(err, check.IsNil)
+		rsp := ackWarnings(warningsCmd, req, nil).(*resp)
+		c.Check(rsp.Type, check.Equals, ResponseTypeError)
+		c.Check(rsp.Status, check.Equals, 400)
+		c.Check(rsp.Result.(*errorResult).Message, check.Equals, msg)
+	}
+}
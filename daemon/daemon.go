@@ -188,6 +188,10 @@ type maintenanceTransmitter interface {
 	transmitMaintenance(kind errorKind, message string)
 }
 
+type warningsTransmitter interface {
+	transmitWarnings(count int, stamp time.Time)
+}
+
 func (c *Command) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	st := c.d.overlord.State()
 	st.Lock()
@@ -234,6 +238,13 @@ func (c *Command) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if warnTransmitter, ok := rsp.(warningsTransmitter); ok {
+		st.Lock()
+		count, stamp := st.WarningsSummary()
+		st.Unlock()
+		warnTransmitter.transmitWarnings(count, stamp)
+	}
+
 	rsp.ServeHTTP(w, r)
 }
 
@@ -193,6 +193,43 @@ func (s *daemonSuite) TestCommandRestartingState(c *check.C) {
 	})
 }
 
+func (s *daemonSuite) TestCommandWarnings(c *check.C) {
+	d := newTestDaemon(c)
+
+	cmd := &Command{d: d}
+	cmd.GET = func(*Command, *http.Request, *auth.UserState) Response {
+		return SyncResponse(nil, nil)
+	}
+	req, err := http.NewRequest("GET", "", nil)
+	c.Assert(err, check.IsNil)
+	req.RemoteAddr = "pid=100;uid=0;" + req.RemoteAddr
+
+	rec := httptest.NewRecorder()
+	cmd.ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 200)
+	var rst map[string]interface{}
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rst), check.IsNil)
+	c.Check(rst["warning-count"], check.IsNil)
+	c.Check(rst["warning-timestamp"], check.IsNil)
+
+	st := d.overlord.State()
+	st.Lock()
+	st.Warnf("hello world")
+	_, stamp := st.PendingWarnings()
+	st.Unlock()
+
+	rec = httptest.NewRecorder()
+	cmd.ServeHTTP(rec, req)
+	c.Check(rec.Code, check.Equals, 200)
+	var rst2 struct {
+		WarningCount     int       `json:"warning-count"`
+		WarningTimestamp time.Time `json:"warning-timestamp"`
+	}
+	c.Assert(json.Unmarshal(rec.Body.Bytes(), &rst2), check.IsNil)
+	c.Check(rst2.WarningCount, check.Equals, 1)
+	c.Check(rst2.WarningTimestamp.Equal(stamp), check.Equals, true)
+}
+
 func (s *daemonSuite) TestGuestAccess(c *check.C) {
 	get := &http.Request{Method: "GET"}
 	put := &http.Request{Method: "PUT"}
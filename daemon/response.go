@@ -27,11 +27,14 @@ import (
 	"mime"
 	"net/http"
 	"path/filepath"
+	"regexp"
 	"strconv"
+	"time"
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/systemd"
 )
 
@@ -58,6 +61,9 @@ type resp struct {
 	Result interface{}  `json:"result,omitempty"`
 	*Meta
 	Maintenance *errorResult `json:"maintenance,omitempty"`
+
+	WarningCount     int       `json:"warning-count,omitempty"`
+	WarningTimestamp time.Time `json:"warning-timestamp,omitempty"`
 }
 
 func (r *resp) transmitMaintenance(kind errorKind, message string) {
@@ -67,6 +73,11 @@ func (r *resp) transmitMaintenance(kind errorKind, message string) {
 	}
 }
 
+func (r *resp) transmitWarnings(count int, stamp time.Time) {
+	r.WarningCount = count
+	r.WarningTimestamp = stamp
+}
+
 // TODO This is being done in a rush to get the proper external
 //      JSON representation in the API in time for the release.
 //      The right code style takes a bit more work and unifies
@@ -84,16 +95,25 @@ type respJSON struct {
 	Result     interface{}  `json:"result"`
 	*Meta
 	Maintenance *errorResult `json:"maintenance,omitempty"`
+
+	WarningCount     int        `json:"warning-count,omitempty"`
+	WarningTimestamp *time.Time `json:"warning-timestamp,omitempty"`
 }
 
 func (r *resp) MarshalJSON() ([]byte, error) {
+	var stamp *time.Time
+	if r.WarningCount > 0 {
+		stamp = &r.WarningTimestamp
+	}
 	return json.Marshal(respJSON{
-		Type:        r.Type,
-		Status:      r.Status,
-		StatusText:  http.StatusText(r.Status),
-		Result:      r.Result,
-		Meta:        r.Meta,
-		Maintenance: r.Maintenance,
+		Type:             r.Type,
+		Status:           r.Status,
+		StatusText:       http.StatusText(r.Status),
+		Result:           r.Result,
+		Meta:             r.Meta,
+		Maintenance:      r.Maintenance,
+		WarningCount:     r.WarningCount,
+		WarningTimestamp: stamp,
 	})
 }
 
@@ -158,6 +178,8 @@ const (
 
 	errorKindDaemonRestart = errorKind("daemon-restart")
 	errorKindSystemRestart = errorKind("system-restart")
+
+	errorKindUnsuccessful = errorKind("unsuccessful")
 )
 
 type errorValue interface{}
@@ -232,10 +254,20 @@ func (f FileResponse) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 // The reader is always closed when done (this is important for
 // osutil.WatingStdoutPipe).
 //
+// If grep is set, only the lines whose message matches it are output, and
+// only the last limit of those unless limit is negative; the reader then
+// needs to return all the lines, and followAfter, if set, is used to follow
+// the journal after the last one of them. If allFields is set, all the
+// journal fields of each line are included too.
+//
 // Tip: “jq” knows how to read this; “jq --seq” both reads and writes this.
 type journalLineReaderSeqResponse struct {
 	io.ReadCloser
-	follow bool
+	follow      bool
+	grep        *regexp.Regexp
+	limit       int
+	followAfter func(cursor string) (io.ReadCloser, error)
+	allFields   bool
 }
 
 func (rr *journalLineReaderSeqResponse) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -243,37 +275,71 @@ func (rr *journalLineReaderSeqResponse) ServeHTTP(w http.ResponseWriter, r *http
 
 	flusher, hasFlusher := w.(http.Flusher)
 
-	var err error
-	dec := json.NewDecoder(rr)
 	writer := bufio.NewWriter(w)
 	enc := json.NewEncoder(writer)
-	for {
-		var log systemd.Log
-		if err = dec.Decode(&log); err != nil {
-			break
-		}
-
+	write := func(log systemd.Log) error {
 		writer.WriteByte(0x1E) // RS -- see ascii(7), and RFC7464
 
 		// ignore the error...
 		t, _ := log.Time()
-		if err = enc.Encode(client.Log{
+		clog := client.Log{
 			Timestamp: t,
 			Message:   log.Message(),
 			SID:       log.SID(),
 			PID:       log.PID(),
-		}); err != nil {
-			break
+			Cursor:    log.Cursor(),
+			BootID:    log.BootID(),
+			Unit:      log.Unit(),
+			Priority:  log.Priority(),
+		}
+		if rr.allFields {
+			clog.Fields = log
+		}
+		if err := enc.Encode(clog); err != nil {
+			return err
 		}
 
 		if rr.follow {
-			if e := writer.Flush(); e != nil {
-				break
+			if err := writer.Flush(); err != nil {
+				return err
 			}
 			if hasFlusher {
 				flusher.Flush()
 			}
 		}
+		return nil
+	}
+
+	var err error
+	if rr.grep == nil {
+		_, err = rr.read(rr.ReadCloser, write)
+	} else {
+		// keep the last matches until all the lines are read
+		var matches []systemd.Log
+		var cursor string
+		cursor, err = rr.read(rr.ReadCloser, func(log systemd.Log) error {
+			matches = append(matches, log)
+			if rr.limit >= 0 && len(matches) > rr.limit {
+				matches = matches[1:]
+			}
+			return nil
+		})
+		if err == io.EOF {
+			err = nil
+			for _, log := range matches {
+				if err = write(log); err != nil {
+					break
+				}
+			}
+		}
+		if err == nil && rr.followAfter != nil {
+			var reader io.ReadCloser
+			reader, err = rr.followAfter(cursor)
+			if err == nil {
+				_, err = rr.read(reader, write)
+				reader.Close()
+			}
+		}
 	}
 	if err != nil && err != io.EOF {
 		fmt.Fprintf(writer, `\x1E{"error": %q}\n`, err)
@@ -285,6 +351,103 @@ func (rr *journalLineReaderSeqResponse) ServeHTTP(w http.ResponseWriter, r *http
 	rr.Close()
 }
 
+// read decodes the lines from the reader, passing the ones that match grep,
+// if set, to emit, until either fails. It returns the cursor of the last
+// line read, and io.EOF once all the lines are read.
+func (rr *journalLineReaderSeqResponse) read(reader io.Reader, emit func(systemd.Log) error) (cursor string, err error) {
+	dec := json.NewDecoder(reader)
+	for {
+		var log systemd.Log
+		if err := dec.Decode(&log); err != nil {
+			return cursor, err
+		}
+		cursor = log.Cursor()
+		if rr.grep != nil && !rr.grep.MatchString(log.Message()) {
+			continue
+		}
+		if err := emit(log); err != nil {
+			return cursor, err
+		}
+	}
+}
+
+// A changeEventsSeqResponse's ServeHTTP method streams the events of a
+// change, see changeEvent, as a json-seq response. It starts with the
+// change as it was initially, and ends with it once it's ready, unless
+// the client goes away first.
+type changeEventsSeqResponse struct {
+	state    *state.State
+	id       string
+	initial  *changeInfo
+	modified <-chan struct{}
+	stop     func()
+}
+
+func (cr *changeEventsSeqResponse) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer cr.stop()
+
+	w.Header().Set("Content-Type", "application/json-seq")
+
+	flusher, hasFlusher := w.(http.Flusher)
+	var closed <-chan bool
+	if notifier, ok := w.(http.CloseNotifier); ok {
+		closed = notifier.CloseNotify()
+	}
+
+	writer := bufio.NewWriter(w)
+	enc := json.NewEncoder(writer)
+	send := func(events []*changeEvent) error {
+		for _, ev := range events {
+			writer.WriteByte(0x1E) // RS -- see ascii(7), and RFC7464
+			if err := enc.Encode(ev); err != nil {
+				return err
+			}
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		if hasFlusher {
+			flusher.Flush()
+		}
+		return nil
+	}
+
+	tracker := make(changeTracker)
+	tracker.events(cr.initial)
+	ready := cr.initial.Ready
+	if err := send([]*changeEvent{{Kind: "change", Change: cr.initial}}); err != nil {
+		logger.Noticef("cannot stream response; problem writing: %v", err)
+		return
+	}
+	for !ready {
+		select {
+		case <-cr.modified:
+		case <-closed:
+			return
+		}
+
+		cr.state.Lock()
+		chg := cr.state.Change(cr.id)
+		if chg == nil {
+			// pruned away
+			cr.state.Unlock()
+			return
+		}
+		chgInfo := change2changeInfo(chg)
+		cr.state.Unlock()
+
+		events := tracker.events(chgInfo)
+		ready = chgInfo.Ready
+		if ready {
+			events = append(events, &changeEvent{Kind: "change", Change: chgInfo})
+		}
+		if err := send(events); err != nil {
+			logger.Noticef("cannot stream response; problem writing: %v", err)
+			return
+		}
+	}
+}
+
 type assertResponse struct {
 	assertions []asserts.Assertion
 	bundle     bool
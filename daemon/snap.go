@@ -26,17 +26,14 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/snapcore/snapd/client"
-	"github.com/snapcore/snapd/dirs"
-	"github.com/snapcore/snapd/logger"
-	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/servicestate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
-	"github.com/snapcore/snapd/progress"
 	"github.com/snapcore/snapd/snap"
-	"github.com/snapcore/snapd/systemd"
 )
 
 var errNoSnap = errors.New("snap not installed")
@@ -123,7 +120,7 @@ func allLocalSnapInfos(st *state.State, all bool, wanted map[string]bool) ([]abo
 		var err error
 		if all {
 			for _, seq := range snapst.Sequence {
-				info, err = snap.ReadInfo(seq.RealName, seq)
+				info, err = snap.ReadInfo(snap.InstanceName(seq.RealName, snapst.InstanceKey), seq)
 				if err != nil {
 					break
 				}
@@ -264,39 +261,6 @@ func appInfosFor(st *state.State, names []string, opts appInfoOptions) ([]*snap.
 	return appInfos, nil
 }
 
-func clientAppInfosFromSnapAppInfos(apps []*snap.AppInfo) []client.AppInfo {
-	// TODO: pass in an actual notifier here instead of null
-	//       (Status doesn't _need_ it, but benefits from it)
-	sysd := systemd.New(dirs.GlobalRootDir, progress.Null)
-
-	out := make([]client.AppInfo, len(apps))
-	for i, app := range apps {
-		out[i] = client.AppInfo{
-			Snap:     app.Snap.Name(),
-			Name:     app.Name,
-			CommonID: app.CommonID,
-		}
-		if fn := app.DesktopFile(); osutil.FileExists(fn) {
-			out[i].DesktopFile = fn
-		}
-
-		if app.IsService() {
-			// TODO: look into making a single call to Status for all services
-			if sts, err := sysd.Status(app.ServiceName()); err != nil {
-				logger.Noticef("cannot get status of service %q: %v", app.Name, err)
-			} else if len(sts) != 1 {
-				logger.Noticef("cannot get status of service %q: expected 1 result, got %d", app.Name, len(sts))
-			} else {
-				out[i].Daemon = sts[0].Daemon
-				out[i].Enabled = sts[0].Enabled
-				out[i].Active = sts[0].Active
-			}
-		}
-	}
-
-	return out
-}
-
 func mapLocal(about aboutSnap) *client.Snap {
 	localSnap, snapst := about.info, about.snapst
 	status := "installed"
@@ -310,7 +274,7 @@ func mapLocal(about aboutSnap) *client.Snap {
 	}
 	sort.Sort(bySnapApp(snapapps))
 
-	apps := clientAppInfosFromSnapAppInfos(snapapps)
+	apps := servicestate.ClientAppInfosFromSnapAppInfos(snapapps)
 
 	// TODO: expose aliases information and state?
 
@@ -345,6 +309,15 @@ func mapLocal(about aboutSnap) *client.Snap {
 		MountedFrom:      localSnap.MountFile(),
 	}
 
+	if snapst.RefreshHeld(time.Now()) {
+		hold := *snapst.RefreshHold
+		result.RefreshHold = &hold
+	}
+	if snapst.RefreshInhibitedTime != nil {
+		inhibited := *snapst.RefreshInhibitedTime
+		result.RefreshInhibitedSince = &inhibited
+	}
+
 	if result.TryMode {
 		// Readlink instead of EvalSymlinks because it's only expected
 		// to be one level, and should still resolve if the target does
@@ -33,6 +33,7 @@ var (
 	DownloadUnpackGadget = downloadUnpackGadget
 	BootstrapToRootDir   = bootstrapToRootDir
 	InstallCloudConfig   = installCloudConfig
+	KernelChannel        = kernelChannel
 )
 
 func (tsto *ToolingStore) User() *auth.UserState {
@@ -261,6 +261,21 @@ func MockTrusted(mockTrusted []asserts.Assertion) (restore func()) {
 	}
 }
 
+var risks = []string{"stable", "candidate", "beta", "edge"}
+
+// kernelChannel returns the channel to fetch a kernel pinned by the
+// model to the given track from, keeping the risk (and branch) of the
+// requested channel.
+func kernelChannel(track, channel string) string {
+	l := strings.Split(channel, "/")
+	for i, c := range l {
+		if strutil.ListContains(risks, c) {
+			return track + "/" + strings.Join(l[i:], "/")
+		}
+	}
+	return track + "/stable"
+}
+
 func bootstrapToRootDir(tsto *ToolingStore, model *asserts.Model, opts *Options, local *localInfos) error {
 	// FIXME: try to avoid doing this
 	if opts.RootDir != "" {
@@ -348,7 +363,14 @@ func bootstrapToRootDir(tsto *ToolingStore, model *asserts.Model, opts *Options,
 			fmt.Fprintf(Stdout, "Fetching %s\n", snapName)
 		}
 
-		fn, info, err := acquireSnap(tsto, name, dlOpts, local)
+		snapOpts := dlOpts
+		if name == model.Kernel() && model.KernelTrack() != "" {
+			kernelOpts := *dlOpts
+			kernelOpts.Channel = kernelChannel(model.KernelTrack(), opts.Channel)
+			snapOpts = &kernelOpts
+		}
+
+		fn, info, err := acquireSnap(tsto, name, snapOpts, local)
 		if err != nil {
 			return err
 		}
@@ -74,6 +74,7 @@ type imageSuite struct {
 
 	downloadedSnaps map[string]string
 	storeSnapInfo   map[string]*snap.Info
+	storeChannels   map[string]string
 	tsto            *image.ToolingStore
 
 	storeSigning *assertstest.StoreStack
@@ -98,6 +99,7 @@ func (s *imageSuite) SetUpTest(c *C) {
 	image.Stderr = s.stderr
 	s.downloadedSnaps = make(map[string]string)
 	s.storeSnapInfo = make(map[string]*snap.Info)
+	s.storeChannels = make(map[string]string)
 	s.tsto = image.MockToolingStore(s)
 
 	s.storeSigning = assertstest.NewStoreStack("canonical", nil)
@@ -174,6 +176,7 @@ func (s *imageSuite) TearDownTest(c *C) {
 // interface for the store
 func (s *imageSuite) SnapInfo(spec store.SnapSpec, user *auth.UserState) (*snap.Info, error) {
 	if info, ok := s.storeSnapInfo[spec.Name]; ok {
+		s.storeChannels[spec.Name] = spec.Channel
 		return info, nil
 	}
 	return nil, fmt.Errorf("no %q in the fake store", spec.Name)
@@ -806,6 +809,63 @@ func (s *imageSuite) TestBootstrapToRootDirKernelPublisherMismatch(c *C) {
 	c.Assert(err, ErrorMatches, `cannot use kernel "pc-kernel" published by "other" for model by "my-brand"`)
 }
 
+func (s *imageSuite) TestBootstrapToRootDirKernelTrack(c *C) {
+	restore := image.MockTrusted(s.storeSigning.Trusted)
+	defer restore()
+
+	headers := s.model.Headers()
+	headers["kernel"] = "pc-kernel=18"
+	headers["timestamp"] = time.Now().Format(time.RFC3339)
+	model, err := s.brandSigning.Sign(asserts.ModelType, headers, nil, "")
+	c.Assert(err, IsNil)
+
+	rootdir := filepath.Join(c.MkDir(), "imageroot")
+
+	// FIXME: bootstrapToRootDir needs an unpacked gadget yaml
+	gadgetUnpackDir := filepath.Join(c.MkDir(), "gadget")
+
+	s.setupSnaps(c, gadgetUnpackDir, map[string]string{
+		"pc":        "canonical",
+		"pc-kernel": "canonical",
+	})
+
+	// mock the mount cmds (for the extract kernel assets stuff)
+	c1 := testutil.MockCommand(c, "mount", "")
+	defer c1.Restore()
+	c2 := testutil.MockCommand(c, "umount", "")
+	defer c2.Restore()
+
+	opts := &image.Options{
+		RootDir:         rootdir,
+		GadgetUnpackDir: gadgetUnpackDir,
+		Channel:         "beta",
+	}
+	local, err := image.LocalSnaps(s.tsto, opts)
+	c.Assert(err, IsNil)
+
+	err = image.BootstrapToRootDir(s.tsto, model.(*asserts.Model), opts, local)
+	c.Assert(err, IsNil)
+
+	c.Check(s.storeChannels, DeepEquals, map[string]string{
+		"core":           "beta",
+		"pc-kernel":      "18/beta",
+		"pc":             "beta",
+		"required-snap1": "beta",
+	})
+}
+
+func (s *imageSuite) TestKernelChannel(c *C) {
+	for _, t := range []struct{ channel, expected string }{
+		{"", "18/stable"},
+		{"edge", "18/edge"},
+		{"beta/hotfix", "18/beta/hotfix"},
+		{"latest/candidate", "18/candidate"},
+		{"other/edge/branch", "18/edge/branch"},
+	} {
+		c.Check(image.KernelChannel("18", t.channel), Equals, t.expected, Commentf("%q", t.channel))
+	}
+}
+
 func (s *imageSuite) TestInstallCloudConfigNoConfig(c *C) {
 	targetDir := c.MkDir()
 	emptyGadgetDir := c.MkDir()
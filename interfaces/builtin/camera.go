@@ -19,6 +19,12 @@
 
 package builtin
 
+import (
+	"regexp"
+
+	"github.com/snapcore/snapd/interfaces/hotplug"
+)
+
 const cameraSummary = `allows access to all cameras`
 
 const cameraBaseDeclarationSlots = `
@@ -44,8 +50,30 @@ const cameraConnectedPlugAppArmor = `
 
 var cameraConnectedPlugUDev = []string{`KERNEL=="video[0-9]*"`}
 
+// Pattern to match the device nodes of cameras reported by hotplug events
+var cameraDeviceNodePattern = regexp.MustCompile("^/dev/video[0-9]+$")
+
+// cameraInterface is the type for the camera interface, it creates a slot
+// for every video4linux device plugged in.
+type cameraInterface struct {
+	commonInterface
+}
+
+func (iface *cameraInterface) HotplugDeviceDetected(di *hotplug.HotplugDeviceInfo, spec *hotplug.Specification) error {
+	if di.Subsystem() != "video4linux" || !cameraDeviceNodePattern.MatchString(di.DeviceName()) {
+		return nil
+	}
+	label, _ := di.Attribute("ID_V4L_PRODUCT")
+	return spec.SetSlot(&hotplug.RequestedSlotSpec{
+		Label: label,
+		Attrs: map[string]interface{}{
+			"path": di.DeviceName(),
+		},
+	})
+}
+
 func init() {
-	registerIface(&commonInterface{
+	registerIface(&cameraInterface{commonInterface{
 		name:                  "camera",
 		summary:               cameraSummary,
 		implicitOnCore:        true,
@@ -54,5 +82,5 @@ func init() {
 		connectedPlugAppArmor: cameraConnectedPlugAppArmor,
 		connectedPlugUDev:     cameraConnectedPlugUDev,
 		reservedForOS:         true,
-	})
+	}})
 }
@@ -25,6 +25,7 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/hotplug"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/testutil"
@@ -108,6 +109,35 @@ func (s *CameraInterfaceSuite) TestAutoConnect(c *C) {
 	c.Assert(s.iface.AutoConnect(s.plugInfo, s.slotInfo), Equals, true)
 }
 
+func (s *CameraInterfaceSuite) TestHotplugDeviceDetected(c *C) {
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{
+		"DEVPATH":        "/devices/pci0000:00/0000:00:14.0/usb1/1-8/1-8:1.0/video4linux/video0",
+		"DEVNAME":        "/dev/video0",
+		"SUBSYSTEM":      "video4linux",
+		"ID_V4L_PRODUCT": "Integrated Camera",
+	})
+	c.Assert(err, IsNil)
+	spec := hotplug.NewSpecification()
+	c.Assert(s.iface.(hotplug.Definer).HotplugDeviceDetected(di, spec), IsNil)
+	c.Check(spec.Slot(), DeepEquals, &hotplug.RequestedSlotSpec{
+		Label: "Integrated Camera",
+		Attrs: map[string]interface{}{"path": "/dev/video0"},
+	})
+}
+
+func (s *CameraInterfaceSuite) TestHotplugDeviceDetectedIgnoresOtherDevices(c *C) {
+	for _, env := range []map[string]string{
+		{"DEVPATH": "/devices/a", "DEVNAME": "/dev/ttyUSB0", "SUBSYSTEM": "tty"},
+		{"DEVPATH": "/devices/b", "DEVNAME": "/dev/v4l-subdev0", "SUBSYSTEM": "video4linux"},
+	} {
+		di, err := hotplug.NewHotplugDeviceInfo(env)
+		c.Assert(err, IsNil)
+		spec := hotplug.NewSpecification()
+		c.Assert(s.iface.(hotplug.Definer).HotplugDeviceDetected(di, spec), IsNil)
+		c.Check(spec.Slot(), IsNil)
+	}
+}
+
 func (s *CameraInterfaceSuite) TestInterfaces(c *C) {
 	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
 }
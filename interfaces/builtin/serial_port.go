@@ -27,6 +27,7 @@ import (
 
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
+	"github.com/snapcore/snapd/interfaces/hotplug"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/snap"
 )
@@ -208,6 +209,26 @@ func (iface *serialPortInterface) AutoConnect(*snap.PlugInfo, *snap.SlotInfo) bo
 	return true
 }
 
+// HotplugDeviceDetected creates a slot for USB serial adapters. The slot
+// refers to the device node, that is updated if the adapter comes back
+// under a different name.
+func (iface *serialPortInterface) HotplugDeviceDetected(di *hotplug.HotplugDeviceInfo, spec *hotplug.Specification) error {
+	bus, _ := di.Attribute("ID_BUS")
+	if di.Subsystem() != "tty" || bus != "usb" || !serialDeviceNodePattern.MatchString(di.DeviceName()) {
+		return nil
+	}
+	label, _ := di.Attribute("ID_MODEL_FROM_DATABASE")
+	if label == "" {
+		label, _ = di.Attribute("ID_MODEL")
+	}
+	return spec.SetSlot(&hotplug.RequestedSlotSpec{
+		Label: label,
+		Attrs: map[string]interface{}{
+			"path": di.DeviceName(),
+		},
+	})
+}
+
 func (iface *serialPortInterface) hasUsbAttrs(attrs interfaces.Attrer) bool {
 	var v int64
 	if err := attrs.Attr("usb-vendor", &v); err == nil {
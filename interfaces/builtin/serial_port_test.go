@@ -25,6 +25,7 @@ import (
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/apparmor"
 	"github.com/snapcore/snapd/interfaces/builtin"
+	"github.com/snapcore/snapd/interfaces/hotplug"
 	"github.com/snapcore/snapd/interfaces/udev"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
@@ -551,6 +552,50 @@ SUBSYSTEM=="tty", SUBSYSTEMS=="usb", ATTRS{idVendor}=="ffff", ATTRS{idProduct}==
 	checkConnectedPlugSnippet(s.testPlugPort3, s.testUDev2, expectedSnippet10, expectedExtraSnippet10)
 }
 
+func (s *SerialPortInterfaceSuite) TestHotplugDeviceDetected(c *C) {
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{
+		"DEVPATH":                "/devices/pci0000:00/0000:00:14.0/usb2/2-1/2-1:1.0/ttyUSB0/tty/ttyUSB0",
+		"DEVNAME":                "/dev/ttyUSB0",
+		"SUBSYSTEM":              "tty",
+		"ID_BUS":                 "usb",
+		"ID_MODEL":               "FT232R_USB_UART",
+		"ID_MODEL_FROM_DATABASE": "FT232 Serial (UART) IC",
+	})
+	c.Assert(err, IsNil)
+	spec := hotplug.NewSpecification()
+	c.Assert(s.iface.(hotplug.Definer).HotplugDeviceDetected(di, spec), IsNil)
+	c.Assert(spec.Slot(), DeepEquals, &hotplug.RequestedSlotSpec{
+		Label: "FT232 Serial (UART) IC",
+		Attrs: map[string]interface{}{"path": "/dev/ttyUSB0"},
+	})
+
+	// the slot created for the device is valid
+	slot := &snap.SlotInfo{
+		Snap:      &snap.Info{SuggestedName: "core", Type: snap.TypeOS},
+		Name:      "serial-port",
+		Interface: "serial-port",
+		Attrs:     spec.Slot().Attrs,
+	}
+	c.Check(interfaces.BeforePrepareSlot(s.iface, slot), IsNil)
+}
+
+func (s *SerialPortInterfaceSuite) TestHotplugDeviceDetectedIgnoresOtherDevices(c *C) {
+	for _, env := range []map[string]string{
+		// built-in serial port
+		{"DEVPATH": "/devices/pnp0/00:05/tty/ttyS0", "DEVNAME": "/dev/ttyS0", "SUBSYSTEM": "tty"},
+		// not a serial port
+		{"DEVPATH": "/devices/a", "DEVNAME": "/dev/video0", "SUBSYSTEM": "video4linux", "ID_BUS": "usb"},
+		// unexpected device node
+		{"DEVPATH": "/devices/b", "DEVNAME": "/dev/ttyFOO0", "SUBSYSTEM": "tty", "ID_BUS": "usb"},
+	} {
+		di, err := hotplug.NewHotplugDeviceInfo(env)
+		c.Assert(err, IsNil)
+		spec := hotplug.NewSpecification()
+		c.Assert(s.iface.(hotplug.Definer).HotplugDeviceDetected(di, spec), IsNil)
+		c.Check(spec.Slot(), IsNil)
+	}
+}
+
 func (s *SerialPortInterfaceSuite) TestInterfaces(c *C) {
 	c.Check(builtin.Interfaces(), testutil.DeepContains, s.iface)
 }
@@ -0,0 +1,133 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package hotplug
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"path/filepath"
+
+	"github.com/snapcore/snapd/dirs"
+)
+
+// HotplugDeviceInfo carries information about added/removed device detected at runtime.
+type HotplugDeviceInfo struct {
+	// map of all attributes returned for given uevent.
+	data map[string]string
+}
+
+// NewHotplugDeviceInfo creates HotplugDeviceInfo structure related to udev add or remove event.
+func NewHotplugDeviceInfo(env map[string]string) (*HotplugDeviceInfo, error) {
+	if _, ok := env["DEVPATH"]; !ok {
+		return nil, fmt.Errorf("missing device path attribute")
+	}
+	return &HotplugDeviceInfo{
+		data: env,
+	}, nil
+}
+
+// Returns the value of "SUBSYSTEM" attribute of the udev event associated with the device, e.g. "usb".
+// Subsystem value is always present.
+func (h *HotplugDeviceInfo) Subsystem() string {
+	return h.data["SUBSYSTEM"]
+}
+
+// Returns full device path under /sysfs, e.g /sys/devices/pci0000:00/0000:00:14.0/usb1/1-2.
+// The path is derived from DEVPATH attribute of the udev event.
+func (h *HotplugDeviceInfo) DevicePath() string {
+	// DEVPATH is guaranteed to exist (checked in the ctor).
+	path, _ := h.Attribute("DEVPATH")
+	return filepath.Join(dirs.SysfsDir, path)
+}
+
+// Returns the value of "MINOR" attribute of the udev event associated with the device.
+// The Minor value may be empty.
+func (h *HotplugDeviceInfo) Minor() string {
+	return h.data["MINOR"]
+}
+
+// Returns the value of "MAJOR" attribute of the udev event associated with the device.
+// The Major value may be empty.
+func (h *HotplugDeviceInfo) Major() string {
+	return h.data["MAJOR"]
+}
+
+// Returns the value of "DEVNAME" attribute of the udev event associated with the device, e.g. "/dev/ttyUSB1".
+// The DeviceName value may be empty.
+func (h *HotplugDeviceInfo) DeviceName() string {
+	return h.data["DEVNAME"]
+}
+
+// Returns the value of "DEVTYPE" attribute of the udev event associated with the device, e.g. "usb_device".
+// The DeviceType value may be empty.
+func (h *HotplugDeviceInfo) DeviceType() string {
+	return h.data["DEVTYPE"]
+}
+
+// Generic method for getting arbitrary attribute from the uevent data.
+func (h *HotplugDeviceInfo) Attribute(name string) (string, bool) {
+	val, ok := h.data[name]
+	return val, ok
+}
+
+// keyAttributes are the attributes that identify a physical device
+// across reconnects, in the order they are considered.
+var keyAttributes = [][]string{
+	{"ID_VENDOR_ID", "ID_MODEL_ID", "ID_SERIAL"},
+	{"ID_VENDOR_ID", "ID_MODEL_ID", "ID_SERIAL_SHORT"},
+	{"ID_V4L_PRODUCT", "ID_SERIAL"},
+}
+
+// Key returns a key that identifies the device in a stable way, so that
+// the same physical device gets the same key when it is plugged again.
+// The key is derived from the vendor, model and serial attributes when
+// present, and from the device path otherwise.
+func (h *HotplugDeviceInfo) Key() string {
+	for _, attrs := range keyAttributes {
+		values := make([]string, 0, len(attrs))
+		for _, attr := range attrs {
+			if val, ok := h.data[attr]; ok && val != "" {
+				values = append(values, attr+"="+val)
+			}
+		}
+		if len(values) == len(attrs) {
+			return hashKey(values...)
+		}
+	}
+	// DEVPATH is guaranteed to exist (checked in the ctor).
+	return hashKey("DEVPATH=" + h.data["DEVPATH"])
+}
+
+func hashKey(values ...string) string {
+	h := sha256.New()
+	for _, val := range values {
+		h.Write([]byte(val))
+		h.Write([]byte{0})
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+func (h *HotplugDeviceInfo) String() string {
+	s := h.DevicePath()
+	if name := h.DeviceName(); name != "" {
+		s += fmt.Sprintf(" (%s)", name)
+	}
+	return s
+}
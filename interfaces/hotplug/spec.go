@@ -0,0 +1,67 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package hotplug
+
+import (
+	"fmt"
+)
+
+// RequestedSlotSpec is a definition of the slot to create in response to hotplug event.
+type RequestedSlotSpec struct {
+	// Name is how the interface wants to name the slot. When left empty,
+	// one will be generated on demand. The hotplug machinery appends a
+	// suffix to ensure uniqueness of the name.
+	Name  string
+	Label string
+	Attrs map[string]interface{}
+}
+
+// Specification contains data about all slots that a particular interface wants to create in response to hotplug events.
+type Specification struct {
+	slot *RequestedSlotSpec
+}
+
+// NewSpecification creates an empty hotplug Specification.
+func NewSpecification() *Specification {
+	return &Specification{}
+}
+
+// SetSlot adds a specification of a slot.
+func (h *Specification) SetSlot(slotSpec *RequestedSlotSpec) error {
+	if h.slot != nil {
+		return fmt.Errorf("slot specification already created")
+	}
+	// only a copy is kept, the caller may reuse the spec
+	attrs := make(map[string]interface{}, len(slotSpec.Attrs))
+	for k, v := range slotSpec.Attrs {
+		attrs[k] = v
+	}
+	h.slot = &RequestedSlotSpec{
+		Name:  slotSpec.Name,
+		Label: slotSpec.Label,
+		Attrs: attrs,
+	}
+	return nil
+}
+
+// Slot returns the slot created by the interface, or nil if none was.
+func (h *Specification) Slot() *RequestedSlotSpec {
+	return h.slot
+}
@@ -0,0 +1,46 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package hotplug
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+type specSuite struct{}
+
+var _ = Suite(&specSuite{})
+
+func (s *specSuite) TestSetSlot(c *C) {
+	spec := NewSpecification()
+	c.Assert(spec.Slot(), IsNil)
+
+	attrs := map[string]interface{}{"foo": "bar"}
+	c.Assert(spec.SetSlot(&RequestedSlotSpec{Name: "slot", Label: "A slot", Attrs: attrs}), IsNil)
+	attrs["foo"] = "changed"
+
+	c.Assert(spec.Slot(), DeepEquals, &RequestedSlotSpec{
+		Name:  "slot",
+		Label: "A slot",
+		Attrs: map[string]interface{}{"foo": "bar"},
+	})
+
+	err := spec.SetSlot(&RequestedSlotSpec{Name: "other"})
+	c.Assert(err, ErrorMatches, "slot specification already created")
+}
@@ -65,6 +65,19 @@ func (r *Repository) Interface(interfaceName string) Interface {
 	return r.ifaces[interfaceName]
 }
 
+// AllInterfaces returns all the interfaces added to the repository, ordered by name.
+func (r *Repository) AllInterfaces() []Interface {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	ifaces := make([]Interface, 0, len(r.ifaces))
+	for _, iface := range r.ifaces {
+		ifaces = append(ifaces, iface)
+	}
+	sort.Sort(byInterfaceName(ifaces))
+	return ifaces
+}
+
 // AddInterface adds the provided interface to the repository.
 func (r *Repository) AddInterface(i Interface) error {
 	r.m.Lock()
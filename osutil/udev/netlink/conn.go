@@ -0,0 +1,142 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netlink
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Mode selects the multicast group of the uevent socket.
+type Mode int
+
+const (
+	// KernelEvent are the raw events sent by the kernel.
+	KernelEvent Mode = 1
+	// UdevEvent are the events sent by udev once it processed them,
+	// these carry the properties udev attached to the device.
+	UdevEvent Mode = 2
+)
+
+// readTimeout is how long a single read on the socket blocks, so that
+// a monitor notices that it was asked to stop.
+var readTimeout = 100 * time.Millisecond
+
+// UEventConn is a connection to the uevent netlink socket.
+type UEventConn struct {
+	fd int
+}
+
+// Connect opens the netlink socket and subscribes to the given mode.
+func (c *UEventConn) Connect(mode Mode) error {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return os.NewSyscallError("socket", err)
+	}
+	addr := &syscall.SockaddrNetlink{
+		Family: syscall.AF_NETLINK,
+		Groups: uint32(mode),
+	}
+	if err := syscall.Bind(fd, addr); err != nil {
+		syscall.Close(fd)
+		return os.NewSyscallError("bind", err)
+	}
+	tv := syscall.NsecToTimeval(readTimeout.Nanoseconds())
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		syscall.Close(fd)
+		return os.NewSyscallError("setsockopt", err)
+	}
+	c.fd = fd
+	return nil
+}
+
+// Close closes the netlink socket.
+func (c *UEventConn) Close() error {
+	return syscall.Close(c.fd)
+}
+
+// ReadMsg reads a single raw message from the socket. It returns
+// syscall.EAGAIN if no message arrived in time.
+func (c *UEventConn) ReadMsg() ([]byte, error) {
+	buf := make([]byte, os.Getpagesize()*4)
+	n, _, err := syscall.Recvfrom(c.fd, buf, 0)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// ReadUEvent reads and parses a single uevent from the socket.
+func (c *UEventConn) ReadUEvent() (*UEvent, error) {
+	msg, err := c.ReadMsg()
+	if err != nil {
+		return nil, err
+	}
+	return ParseUEvent(msg)
+}
+
+// Monitor sends the events read from the socket to queue and the
+// errors to errors, until the returned stop function is called. The
+// stop function waits at most stopTimeout for the monitor to finish and
+// reports whether it did.
+func (c *UEventConn) Monitor(queue chan<- UEvent, errors chan<- error) (stop func(stopTimeout time.Duration) bool) {
+	quit := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-quit:
+				return
+			default:
+			}
+			uevent, err := c.ReadUEvent()
+			if err == syscall.EAGAIN || err == syscall.EINTR {
+				continue
+			}
+			if err != nil {
+				select {
+				case errors <- fmt.Errorf("cannot read uevent: %v", err):
+				case <-quit:
+					return
+				}
+				continue
+			}
+			select {
+			case queue <- *uevent:
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	return func(stopTimeout time.Duration) bool {
+		close(quit)
+		select {
+		case <-done:
+			return true
+		case <-time.After(stopTimeout):
+			return false
+		}
+	}
+}
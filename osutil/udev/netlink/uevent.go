@@ -0,0 +1,145 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package netlink implements a minimal reader of the uevents that the
+// kernel and udev broadcast over the NETLINK_KOBJECT_UEVENT socket.
+package netlink
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// KObjAction is the action carried by a uevent.
+type KObjAction string
+
+const (
+	ADD     KObjAction = "add"
+	REMOVE  KObjAction = "remove"
+	UPDATE  KObjAction = "update"
+	CHANGE  KObjAction = "change"
+	MOVE    KObjAction = "move"
+	ONLINE  KObjAction = "online"
+	OFFLINE KObjAction = "offline"
+	BIND    KObjAction = "bind"
+	UNBIND  KObjAction = "unbind"
+)
+
+// UEvent describes a single event about a kernel object.
+type UEvent struct {
+	Action KObjAction
+	KObj   string
+	Env    map[string]string
+}
+
+func (e UEvent) String() string {
+	return fmt.Sprintf("%s@%s", e.Action, e.KObj)
+}
+
+// libudevMagic is the magic number found in the header of the messages
+// broadcast by udev, in network byte order.
+const libudevMagic = 0xfeedcafe
+
+// libudevPrefix is the prefix of the messages broadcast by udev.
+var libudevPrefix = []byte("libudev\x00")
+
+// ParseUEvent parses a raw message read from the uevent netlink socket.
+// Both the kernel format ("action@devpath" followed by the properties)
+// and the udev format (a binary header followed by the properties) are
+// understood.
+func ParseUEvent(raw []byte) (*UEvent, error) {
+	if bytes.HasPrefix(raw, libudevPrefix) {
+		return parseUdevEvent(raw)
+	}
+	return parseKernelEvent(raw)
+}
+
+func parseKernelEvent(raw []byte) (*UEvent, error) {
+	fields := bytes.Split(raw, []byte{0})
+	if len(fields) == 0 || len(fields[0]) == 0 {
+		return nil, fmt.Errorf("cannot parse uevent: empty message")
+	}
+	header := strings.SplitN(string(fields[0]), "@", 2)
+	if len(header) != 2 || header[0] == "" || header[1] == "" {
+		return nil, fmt.Errorf("cannot parse uevent: invalid header %q", fields[0])
+	}
+	env, err := parseEnv(fields[1:])
+	if err != nil {
+		return nil, err
+	}
+	return &UEvent{
+		Action: KObjAction(header[0]),
+		KObj:   header[1],
+		Env:    env,
+	}, nil
+}
+
+func parseUdevEvent(raw []byte) (*UEvent, error) {
+	// struct udev_monitor_netlink_header {
+	//	char prefix[8];
+	//	unsigned int magic;
+	//	unsigned int header_size;
+	//	unsigned int properties_off;
+	//	unsigned int properties_len;
+	//	...
+	// }
+	const minHeaderSize = 8 + 4*4
+	if len(raw) < minHeaderSize {
+		return nil, fmt.Errorf("cannot parse udev event: message too short")
+	}
+	if magic := binary.BigEndian.Uint32(raw[8:12]); magic != libudevMagic {
+		return nil, fmt.Errorf("cannot parse udev event: invalid magic %#x", magic)
+	}
+	propOff := binary.LittleEndian.Uint32(raw[16:20])
+	propLen := binary.LittleEndian.Uint32(raw[20:24])
+	if uint64(propOff)+uint64(propLen) > uint64(len(raw)) {
+		return nil, fmt.Errorf("cannot parse udev event: properties out of bounds")
+	}
+	env, err := parseEnv(bytes.Split(raw[propOff:propOff+propLen], []byte{0}))
+	if err != nil {
+		return nil, err
+	}
+	action := env["ACTION"]
+	devpath := env["DEVPATH"]
+	if action == "" || devpath == "" {
+		return nil, fmt.Errorf("cannot parse udev event: missing ACTION or DEVPATH")
+	}
+	return &UEvent{
+		Action: KObjAction(action),
+		KObj:   devpath,
+		Env:    env,
+	}, nil
+}
+
+func parseEnv(fields [][]byte) (map[string]string, error) {
+	env := make(map[string]string, len(fields))
+	for _, field := range fields {
+		if len(field) == 0 {
+			continue
+		}
+		kv := strings.SplitN(string(field), "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("cannot parse uevent: invalid property %q", field)
+		}
+		env[kv[0]] = kv[1]
+	}
+	return env, nil
+}
@@ -0,0 +1,115 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package netlink_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/osutil/udev/netlink"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type ueventSuite struct{}
+
+var _ = Suite(&ueventSuite{})
+
+func (s *ueventSuite) TestParseKernelEvent(c *C) {
+	raw := []byte("add@/devices/pci0000:00/0000:00:14.0/usb2/2-1/2-1:1.0/ttyUSB0/tty/ttyUSB0\x00" +
+		"ACTION=add\x00" +
+		"DEVPATH=/devices/pci0000:00/0000:00:14.0/usb2/2-1/2-1:1.0/ttyUSB0/tty/ttyUSB0\x00" +
+		"SUBSYSTEM=tty\x00" +
+		"MAJOR=188\x00" +
+		"MINOR=0\x00" +
+		"DEVNAME=ttyUSB0\x00" +
+		"SEQNUM=4117\x00")
+
+	uevent, err := netlink.ParseUEvent(raw)
+	c.Assert(err, IsNil)
+	c.Check(uevent.Action, Equals, netlink.ADD)
+	c.Check(uevent.KObj, Equals, "/devices/pci0000:00/0000:00:14.0/usb2/2-1/2-1:1.0/ttyUSB0/tty/ttyUSB0")
+	c.Check(uevent.Env, DeepEquals, map[string]string{
+		"ACTION":    "add",
+		"DEVPATH":   "/devices/pci0000:00/0000:00:14.0/usb2/2-1/2-1:1.0/ttyUSB0/tty/ttyUSB0",
+		"SUBSYSTEM": "tty",
+		"MAJOR":     "188",
+		"MINOR":     "0",
+		"DEVNAME":   "ttyUSB0",
+		"SEQNUM":    "4117",
+	})
+	c.Check(uevent.String(), Equals, "add@/devices/pci0000:00/0000:00:14.0/usb2/2-1/2-1:1.0/ttyUSB0/tty/ttyUSB0")
+}
+
+func mockUdevMessage(magic uint32, props string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("libudev\x00")
+	binary.Write(&buf, binary.BigEndian, magic)
+	// header size, properties offset and length
+	const headerSize = 40
+	binary.Write(&buf, binary.LittleEndian, uint32(headerSize))
+	binary.Write(&buf, binary.LittleEndian, uint32(headerSize))
+	binary.Write(&buf, binary.LittleEndian, uint32(len(props)))
+	// filter fields
+	buf.Write(make([]byte, headerSize-buf.Len()))
+	buf.WriteString(props)
+	return buf.Bytes()
+}
+
+func (s *ueventSuite) TestParseUdevEvent(c *C) {
+	raw := mockUdevMessage(0xfeedcafe, "ACTION=remove\x00"+
+		"DEVPATH=/devices/virtual/video4linux/video0\x00"+
+		"SUBSYSTEM=video4linux\x00"+
+		"DEVNAME=/dev/video0\x00"+
+		"ID_V4L_PRODUCT=Integrated Camera\x00")
+
+	uevent, err := netlink.ParseUEvent(raw)
+	c.Assert(err, IsNil)
+	c.Check(uevent.Action, Equals, netlink.REMOVE)
+	c.Check(uevent.KObj, Equals, "/devices/virtual/video4linux/video0")
+	c.Check(uevent.Env, DeepEquals, map[string]string{
+		"ACTION":         "remove",
+		"DEVPATH":        "/devices/virtual/video4linux/video0",
+		"SUBSYSTEM":      "video4linux",
+		"DEVNAME":        "/dev/video0",
+		"ID_V4L_PRODUCT": "Integrated Camera",
+	})
+}
+
+func (s *ueventSuite) TestParseErrors(c *C) {
+	for _, t := range []struct {
+		raw []byte
+		err string
+	}{
+		{[]byte(""), "cannot parse uevent: empty message"},
+		{[]byte("add\x00FOO=bar\x00"), `cannot parse uevent: invalid header "add"`},
+		{[]byte("add@/devices/foo\x00FOO\x00"), `cannot parse uevent: invalid property "FOO"`},
+		{[]byte("libudev\x00\x01"), "cannot parse udev event: message too short"},
+		{mockUdevMessage(0xcafe, "ACTION=add\x00"), "cannot parse udev event: invalid magic 0xcafe"},
+		{mockUdevMessage(0xfeedcafe, "ACTION=add\x00")[:45], "cannot parse udev event: properties out of bounds"},
+		{mockUdevMessage(0xfeedcafe, "ACTION=add\x00"), "cannot parse udev event: missing ACTION or DEVPATH"},
+	} {
+		_, err := netlink.ParseUEvent(t.raw)
+		c.Check(err, ErrorMatches, t.err, Commentf("%q", t.raw))
+	}
+}
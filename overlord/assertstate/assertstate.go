@@ -29,6 +29,7 @@ import (
 
 	"github.com/snapcore/snapd/asserts"
 	"github.com/snapcore/snapd/asserts/snapasserts"
+	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/release"
@@ -378,9 +379,54 @@ func delayedCrossMgrInit() {
 	snapstate.AutoRefreshAssertions = AutoRefreshAssertions
 	// hook retrieving auto-aliases into snapstate logic
 	snapstate.AutoAliases = AutoAliases
+	// hook enforcement of validation sets into snapstate logic
+	snapstate.EnforcedValidationSets = EnforcedValidationSets
 }
 
 // AutoRefreshAssertions tries to refresh all assertions
 func AutoRefreshAssertions(s *state.State, userID int) error {
-	return RefreshSnapDeclarations(s, userID)
+	if err := RefreshSnapDeclarations(s, userID); err != nil {
+		return err
+	}
+	if err := RefreshAccountKeys(s, userID); err != nil {
+		// not fatal, the keys will be refreshed next time
+		logger.Noticef("Cannot refresh account-keys: %v", err)
+	}
+	return warnRevoked(s)
+}
+
+// RefreshAccountKeys tries to refresh the account-keys in the system
+// assertion database, learning about keys that got revoked through
+// a revision with an earlier until.
+func RefreshAccountKeys(s *state.State, userID int) error {
+	keys, err := cachedDB(s).FindMany(asserts.AccountKeyType, nil)
+	if asserts.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fetching := func(f asserts.Fetcher) error {
+		for _, a := range keys {
+			// predefined keys are skipped by the fetcher
+			if err := f.Fetch(a.Ref()); err != nil {
+				return fmt.Errorf("cannot refresh account-key %q: %v", a.(*asserts.AccountKey).PublicKeyID(), err)
+			}
+		}
+		return nil
+	}
+	return doFetch(s, userID, fetching)
+}
+
+// warnRevoked re-checks the system assertion database and adds a
+// warning for each assertion signed with a revoked or expired key.
+func warnRevoked(s *state.State) error {
+	revoked, err := cachedDB(s).FindRevoked()
+	if err != nil {
+		return err
+	}
+	for _, a := range revoked {
+		s.Warnf("assertion %v is signed with revoked or expired key %q, snaps relying on it cannot be installed", a.Ref(), a.SignKeyID())
+	}
+	return nil
 }
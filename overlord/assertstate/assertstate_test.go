@@ -711,6 +711,77 @@ func (s *assertMgrSuite) TestRefreshSnapDeclarations(c *C) {
 	c.Check(a.(*asserts.SnapDeclaration).Revision(), Equals, 1)
 }
 
+func (s *assertMgrSuite) TestAutoRefreshAssertionsRevokedKey(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// a developer key in use for a while
+	now := time.Now()
+	since := now.Add(-2 * time.Hour)
+	devPrivKey, _ := assertstest.GenerateKey(752)
+	devAcctKey := assertstest.NewAccountKey(s.storeSigning, s.dev1Acct, map[string]interface{}{
+		"name":  "other",
+		"since": since.Format(time.RFC3339),
+	}, devPrivKey.PublicKey(), "")
+	err := s.storeSigning.Add(devAcctKey)
+	c.Assert(err, IsNil)
+	devSigning := assertstest.NewSigningDB(s.dev1Acct.AccountID(), devPrivKey)
+
+	// previous state
+	err = assertstate.Add(s.state, s.storeSigning.StoreAccountKey(""))
+	c.Assert(err, IsNil)
+	err = assertstate.Add(s.state, s.dev1Acct)
+	c.Assert(err, IsNil)
+	err = assertstate.Add(s.state, devAcctKey)
+	c.Assert(err, IsNil)
+
+	// something signed by the developer an hour ago
+	vs, err := devSigning.Sign(asserts.ValidationSetType, map[string]interface{}{
+		"series":     "16",
+		"account-id": s.dev1Acct.AccountID(),
+		"name":       "my-set",
+		"sequence":   "1",
+		"snaps": []interface{}{
+			map[string]interface{}{
+				"name": "foo",
+				"id":   "fooididididididididididididididi",
+			},
+		},
+		"timestamp": now.Add(-time.Hour).Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	err = assertstate.Add(s.state, vs)
+	c.Assert(err, IsNil)
+
+	err = assertstate.AutoRefreshAssertions(s.state, 0)
+	c.Assert(err, IsNil)
+	c.Check(s.state.AllWarnings(), HasLen, 0)
+
+	// the store revokes the developer key as of half an hour ago, that
+	// is after the validation-set was signed, it is still affected
+	revokedKey := assertstest.NewAccountKey(s.storeSigning, s.dev1Acct, map[string]interface{}{
+		"name":     "other",
+		"since":    since.Format(time.RFC3339),
+		"until":    now.Add(-30 * time.Minute).Format(time.RFC3339),
+		"revision": "1",
+	}, devPrivKey.PublicKey(), "")
+	err = s.storeSigning.Add(revokedKey)
+	c.Assert(err, IsNil)
+
+	err = assertstate.AutoRefreshAssertions(s.state, 0)
+	c.Assert(err, IsNil)
+
+	a, err := assertstate.DB(s.state).Find(asserts.AccountKeyType, map[string]string{
+		"public-key-sha3-384": devPrivKey.PublicKey().ID(),
+	})
+	c.Assert(err, IsNil)
+	c.Check(a.Revision(), Equals, 1)
+
+	warns := s.state.AllWarnings()
+	c.Assert(warns, HasLen, 1)
+	c.Check(warns[0].String(), Matches, `assertion validation-set \(1; .*name:my-set\) is signed with revoked or expired key ".*", snaps relying on it cannot be installed`)
+}
+
 func (s *assertMgrSuite) TestValidateRefreshesNothing(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
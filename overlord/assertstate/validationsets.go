@@ -0,0 +1,306 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package assertstate
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/snap"
+)
+
+// ValidationSetMode describes how the constraints of a tracked
+// validation set are applied.
+type ValidationSetMode string
+
+const (
+	// MonitorMode only reports whether the system satisfies the
+	// validation set.
+	MonitorMode ValidationSetMode = "monitor"
+	// EnforceMode refuses operations that would break the
+	// validation set.
+	EnforceMode ValidationSetMode = "enforce"
+)
+
+// ValidationSetTracking holds the tracking parameters of a validation
+// set the system follows.
+type ValidationSetTracking struct {
+	AccountID string            `json:"account-id"`
+	Name      string            `json:"name"`
+	Mode      ValidationSetMode `json:"mode"`
+
+	// PinnedAt is the sequence the validation set was pinned to
+	// explicitly, or 0 if the latest known sequence is tracked.
+	PinnedAt int `json:"pinned-at,omitempty"`
+
+	// Current is the sequence of the validation set in use.
+	Current int `json:"current"`
+}
+
+// ValidationSetKey returns the key under which a validation set with
+// the given account and name is tracked.
+func ValidationSetKey(accountID, name string) string {
+	return accountID + "/" + name
+}
+
+// ValidationSets returns all the validation sets tracked by the
+// system, keyed by ValidationSetKey.
+func ValidationSets(st *state.State) (map[string]*ValidationSetTracking, error) {
+	var vsmap map[string]*ValidationSetTracking
+	err := st.Get("validation-sets", &vsmap)
+	if err != nil && err != state.ErrNoState {
+		return nil, err
+	}
+	if vsmap == nil {
+		vsmap = make(map[string]*ValidationSetTracking)
+	}
+	return vsmap, nil
+}
+
+// GetValidationSet retrieves the tracking parameters of the given
+// validation set. It returns state.ErrNoState if it is not tracked.
+func GetValidationSet(st *state.State, accountID, name string, tr *ValidationSetTracking) error {
+	vsmap, err := ValidationSets(st)
+	if err != nil {
+		return err
+	}
+	vs := vsmap[ValidationSetKey(accountID, name)]
+	if vs == nil {
+		return state.ErrNoState
+	}
+	*tr = *vs
+	return nil
+}
+
+func updateValidationSet(st *state.State, tr *ValidationSetTracking) error {
+	vsmap, err := ValidationSets(st)
+	if err != nil {
+		return err
+	}
+	vsmap[ValidationSetKey(tr.AccountID, tr.Name)] = tr
+	st.Set("validation-sets", vsmap)
+	return nil
+}
+
+// ForgetValidationSet stops tracking the given validation set.
+func ForgetValidationSet(st *state.State, accountID, name string) error {
+	vsmap, err := ValidationSets(st)
+	if err != nil {
+		return err
+	}
+	key := ValidationSetKey(accountID, name)
+	if vsmap[key] == nil {
+		return fmt.Errorf("validation set %s is not tracked", key)
+	}
+	delete(vsmap, key)
+	st.Set("validation-sets", vsmap)
+	return nil
+}
+
+// ValidationSet returns the validation-set assertion with the given
+// sequence from the system assertion database.
+func ValidationSet(st *state.State, accountID, name string, sequence int) (*asserts.ValidationSet, error) {
+	a, err := DB(st).Find(asserts.ValidationSetType, map[string]string{
+		"series":     release.Series,
+		"account-id": accountID,
+		"name":       name,
+		"sequence":   strconv.Itoa(sequence),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return a.(*asserts.ValidationSet), nil
+}
+
+// latestKnownSequence returns the highest sequence of the given
+// validation set in the system assertion database, or 0.
+func latestKnownSequence(st *state.State, accountID, name string) (int, error) {
+	a, err := DB(st).FindSequence(asserts.ValidationSetType, map[string]string{
+		"series":     release.Series,
+		"account-id": accountID,
+		"name":       name,
+	}, -1, -1)
+	if asserts.IsNotFound(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return a.Sequence(), nil
+}
+
+// fetchValidationSet fetches the given sequence of the validation set,
+// or the latest one if sequence is 0, returning the sequence fetched.
+func fetchValidationSet(st *state.State, accountID, name string, sequence int, userID int) (int, error) {
+	ref := func(seq int) *asserts.Ref {
+		return &asserts.Ref{
+			Type:       asserts.ValidationSetType,
+			PrimaryKey: []string{release.Series, accountID, name, strconv.Itoa(seq)},
+		}
+	}
+	isNotFound := func(err error) bool {
+		notFound, ok := err.(*asserts.NotFoundError)
+		return ok && notFound.Type == asserts.ValidationSetType
+	}
+
+	if sequence > 0 {
+		err := doFetch(st, userID, func(f asserts.Fetcher) error {
+			return f.Fetch(ref(sequence))
+		})
+		if isNotFound(err) {
+			return 0, fmt.Errorf("cannot find validation set %s at sequence %d", ValidationSetKey(accountID, name), sequence)
+		}
+		return sequence, err
+	}
+
+	latest, err := latestKnownSequence(st, accountID, name)
+	if err != nil {
+		return 0, err
+	}
+	// TODO: ask the store directly for the latest sequence once it
+	// can tell us, for now look for newer ones than known one by one
+	err = doFetch(st, userID, func(f asserts.Fetcher) error {
+		for {
+			err := f.Fetch(ref(latest + 1))
+			if isNotFound(err) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			latest++
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	if latest == 0 {
+		return 0, fmt.Errorf("cannot find validation set %s", ValidationSetKey(accountID, name))
+	}
+	return latest, nil
+}
+
+// CheckInstalledSnaps checks whether the installed snaps satisfy the
+// constraints of the validation set, returning an error describing
+// all the problems if they don't.
+func CheckInstalledSnaps(st *state.State, vs *asserts.ValidationSet) error {
+	snapStates, err := snapstate.All(st)
+	if err != nil {
+		return err
+	}
+	byID := make(map[string]*snapstate.SnapState, len(snapStates))
+	byName := make(map[string]*snapstate.SnapState, len(snapStates))
+	for name, snapst := range snapStates {
+		si := snapst.CurrentSideInfo()
+		if si.SnapID != "" {
+			byID[si.SnapID] = snapst
+		}
+		byName[name] = snapst
+	}
+
+	var problems []string
+	for _, sn := range vs.Snaps() {
+		snapst := byID[sn.SnapID]
+		if snapst == nil {
+			snapst = byName[sn.Name]
+		}
+		switch {
+		case sn.Presence == asserts.PresenceInvalid && snapst != nil:
+			problems = append(problems, fmt.Sprintf("snap %q is installed but must not be", sn.Name))
+		case sn.Presence == asserts.PresenceRequired && snapst == nil:
+			problems = append(problems, fmt.Sprintf("snap %q is required but not installed", sn.Name))
+		case snapst != nil && sn.Revision != 0 && snapst.Current != snap.R(sn.Revision):
+			problems = append(problems, fmt.Sprintf("snap %q is at revision %s instead of revision %d", sn.Name, snapst.Current, sn.Revision))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.Strings(problems)
+	return fmt.Errorf("validation set %s is not satisfied:\n - %s", ValidationSetKey(vs.AccountID(), vs.Name()), strings.Join(problems, "\n - "))
+}
+
+// ApplyValidationSet fetches the given validation set, at the given
+// sequence or the latest one if sequence is 0, and starts tracking it
+// in the given mode. Enforcing a validation set fails if the
+// installed snaps do not satisfy it.
+func ApplyValidationSet(st *state.State, accountID, name string, sequence int, mode ValidationSetMode, userID int) (*ValidationSetTracking, error) {
+	if mode != MonitorMode && mode != EnforceMode {
+		return nil, fmt.Errorf("invalid validation set mode %q", mode)
+	}
+	current, err := fetchValidationSet(st, accountID, name, sequence, userID)
+	if err != nil {
+		return nil, err
+	}
+	vs, err := ValidationSet(st, accountID, name, current)
+	if err != nil {
+		return nil, err
+	}
+	if mode == EnforceMode {
+		if err := CheckInstalledSnaps(st, vs); err != nil {
+			return nil, fmt.Errorf("cannot enforce %v", err)
+		}
+	}
+
+	tr := &ValidationSetTracking{
+		AccountID: accountID,
+		Name:      name,
+		Mode:      mode,
+		PinnedAt:  sequence,
+		Current:   current,
+	}
+	if err := updateValidationSet(st, tr); err != nil {
+		return nil, err
+	}
+	return tr, nil
+}
+
+// EnforcedValidationSets returns the validation sets the system
+// currently enforces.
+func EnforcedValidationSets(st *state.State) ([]*asserts.ValidationSet, error) {
+	vsmap, err := ValidationSets(st)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(vsmap))
+	for key, tr := range vsmap {
+		if tr.Mode == EnforceMode {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	sets := make([]*asserts.ValidationSet, 0, len(keys))
+	for _, key := range keys {
+		tr := vsmap[key]
+		vs, err := ValidationSet(st, tr.AccountID, tr.Name, tr.Current)
+		if err != nil {
+			return nil, fmt.Errorf("internal error: cannot find enforced validation set %s: %v", key, err)
+		}
+		sets = append(sets, vs)
+	}
+	return sets, nil
+}
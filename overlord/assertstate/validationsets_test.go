@@ -0,0 +1,168 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package assertstate_test
+
+import (
+	"fmt"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/overlord/assertstate"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (s *assertMgrSuite) validationSet(c *C, sequence int, snaps ...interface{}) *asserts.ValidationSet {
+	vs, err := s.dev1Signing.Sign(asserts.ValidationSetType, map[string]interface{}{
+		"series":     "16",
+		"account-id": s.dev1Acct.AccountID(),
+		"name":       "my-set",
+		"sequence":   fmt.Sprintf("%d", sequence),
+		"snaps":      snaps,
+		"timestamp":  time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	err = s.storeSigning.Add(vs)
+	c.Assert(err, IsNil)
+	return vs.(*asserts.ValidationSet)
+}
+
+func (s *assertMgrSuite) setupValidationSets(c *C) {
+	s.validationSet(c, 1, map[string]interface{}{
+		"name": "foo",
+		"id":   "fooididididididididididididididi",
+	})
+	s.validationSet(c, 2, map[string]interface{}{
+		"name":     "foo",
+		"id":       "fooididididididididididididididi",
+		"revision": "5",
+	})
+}
+
+func (s *assertMgrSuite) TestApplyValidationSetLatest(c *C) {
+	s.setupValidationSets(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	tr, err := assertstate.ApplyValidationSet(s.state, s.dev1Acct.AccountID(), "my-set", 0, assertstate.MonitorMode, 0)
+	c.Assert(err, IsNil)
+	c.Check(tr, DeepEquals, &assertstate.ValidationSetTracking{
+		AccountID: s.dev1Acct.AccountID(),
+		Name:      "my-set",
+		Mode:      assertstate.MonitorMode,
+		Current:   2,
+	})
+
+	var stored assertstate.ValidationSetTracking
+	err = assertstate.GetValidationSet(s.state, s.dev1Acct.AccountID(), "my-set", &stored)
+	c.Assert(err, IsNil)
+	c.Check(&stored, DeepEquals, tr)
+
+	vs, err := assertstate.ValidationSet(s.state, s.dev1Acct.AccountID(), "my-set", 2)
+	c.Assert(err, IsNil)
+	c.Check(vs.Snaps()[0].Revision, Equals, 5)
+
+	// monitored sets are not enforced
+	sets, err := assertstate.EnforcedValidationSets(s.state)
+	c.Assert(err, IsNil)
+	c.Check(sets, HasLen, 0)
+}
+
+func (s *assertMgrSuite) TestApplyValidationSetPinned(c *C) {
+	s.setupValidationSets(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	tr, err := assertstate.ApplyValidationSet(s.state, s.dev1Acct.AccountID(), "my-set", 1, assertstate.MonitorMode, 0)
+	c.Assert(err, IsNil)
+	c.Check(tr.PinnedAt, Equals, 1)
+	c.Check(tr.Current, Equals, 1)
+
+	_, err = assertstate.ApplyValidationSet(s.state, s.dev1Acct.AccountID(), "my-set", 3, assertstate.MonitorMode, 0)
+	c.Check(err, ErrorMatches, fmt.Sprintf("cannot find validation set %s/my-set at sequence 3", s.dev1Acct.AccountID()))
+}
+
+func (s *assertMgrSuite) TestApplyValidationSetNotFound(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, err := assertstate.ApplyValidationSet(s.state, s.dev1Acct.AccountID(), "my-set", 0, assertstate.MonitorMode, 0)
+	c.Check(err, ErrorMatches, fmt.Sprintf("cannot find validation set %s/my-set", s.dev1Acct.AccountID()))
+
+	_, err = assertstate.ApplyValidationSet(s.state, s.dev1Acct.AccountID(), "my-set", 0, "bogus", 0)
+	c.Check(err, ErrorMatches, `invalid validation set mode "bogus"`)
+}
+
+func (s *assertMgrSuite) TestApplyValidationSetEnforce(c *C) {
+	s.setupValidationSets(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, err := assertstate.ApplyValidationSet(s.state, s.dev1Acct.AccountID(), "my-set", 0, assertstate.EnforceMode, 0)
+	c.Check(err, ErrorMatches, `cannot enforce validation set .*/my-set is not satisfied:
+ - snap "foo" is required but not installed`)
+
+	snapstate.Set(s.state, "foo", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "foo", SnapID: "fooididididididididididididididi", Revision: snap.R(4)},
+		},
+		Current: snap.R(4),
+	})
+
+	_, err = assertstate.ApplyValidationSet(s.state, s.dev1Acct.AccountID(), "my-set", 0, assertstate.EnforceMode, 0)
+	c.Check(err, ErrorMatches, `cannot enforce validation set .*/my-set is not satisfied:
+ - snap "foo" is at revision 4 instead of revision 5`)
+
+	tr, err := assertstate.ApplyValidationSet(s.state, s.dev1Acct.AccountID(), "my-set", 1, assertstate.EnforceMode, 0)
+	c.Assert(err, IsNil)
+	c.Check(tr.Mode, Equals, assertstate.EnforceMode)
+
+	sets, err := assertstate.EnforcedValidationSets(s.state)
+	c.Assert(err, IsNil)
+	c.Assert(sets, HasLen, 1)
+	c.Check(sets[0].Sequence(), Equals, 1)
+}
+
+func (s *assertMgrSuite) TestForgetValidationSet(c *C) {
+	s.setupValidationSets(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	err := assertstate.ForgetValidationSet(s.state, s.dev1Acct.AccountID(), "my-set")
+	c.Check(err, ErrorMatches, `validation set .*/my-set is not tracked`)
+
+	_, err = assertstate.ApplyValidationSet(s.state, s.dev1Acct.AccountID(), "my-set", 0, assertstate.MonitorMode, 0)
+	c.Assert(err, IsNil)
+
+	err = assertstate.ForgetValidationSet(s.state, s.dev1Acct.AccountID(), "my-set")
+	c.Assert(err, IsNil)
+
+	var tr assertstate.ValidationSetTracking
+	err = assertstate.GetValidationSet(s.state, s.dev1Acct.AccountID(), "my-set", &tr)
+	c.Check(err, Equals, state.ErrNoState)
+}
@@ -20,20 +20,56 @@
 package overlord
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/state"
 )
 
+// JournalPath returns the path of the journal of the state file at
+// statePath, e.g. state.journal next to state.json.
+func JournalPath(statePath string) string {
+	return strings.TrimSuffix(statePath, filepath.Ext(statePath)) + ".journal"
+}
+
 type overlordStateBackend struct {
 	path           string
 	ensureBefore   func(d time.Duration)
 	requestRestart func(t state.RestartType)
+
+	journal *os.File
 }
 
 func (osb *overlordStateBackend) Checkpoint(data []byte) error {
-	return osutil.AtomicWriteFile(osb.path, data, 0600, 0)
+	if err := osutil.AtomicWriteFile(osb.path, data, 0600, 0); err != nil {
+		return err
+	}
+	// the journal is now fully contained in the checkpoint
+	if osb.journal != nil {
+		osb.journal.Close()
+		osb.journal = nil
+	}
+	if err := os.Remove(JournalPath(osb.path)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (osb *overlordStateBackend) Journal(entry []byte) error {
+	if osb.journal == nil {
+		f, err := os.OpenFile(JournalPath(osb.path), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+		if err != nil {
+			return err
+		}
+		osb.journal = f
+	}
+	if _, err := osb.journal.Write(entry); err != nil {
+		return err
+	}
+	return osb.journal.Sync()
 }
 
 func (osb *overlordStateBackend) EnsureBefore(d time.Duration) {
@@ -46,6 +46,8 @@ func ParseKey(key string) (subkeys []string, err error) {
 	return subkeys, nil
 }
 
+// PatchConfig sets the value of the given key path in the config, creating
+// the intermediate maps as needed. A nil value marks the key for removal.
 func PatchConfig(snapName string, subkeys []string, pos int, config interface{}, value *json.RawMessage) (interface{}, error) {
 
 	switch config := config.(type) {
@@ -62,13 +64,24 @@ func PatchConfig(snapName string, subkeys []string, pos int, config interface{},
 		// Raw replaces pristine on commit. Unpack, update, and repack.
 		var configm map[string]interface{}
 
-		if err := jsonutil.DecodeWithNumber(bytes.NewReader(*config), &configm); err != nil {
+		if config == nil {
+			// The value is being removed. Start afresh.
+			if value == nil {
+				return config, nil
+			}
+			configm = make(map[string]interface{})
+		} else if err := jsonutil.DecodeWithNumber(bytes.NewReader(*config), &configm); err != nil {
 			return nil, fmt.Errorf("snap %q option %q is not a map", snapName, strings.Join(subkeys[:pos], "."))
 		}
 		_, err := PatchConfig(snapName, subkeys, pos, configm, value)
 		if err != nil {
 			return nil, err
 		}
+		// The raw value is committed as is, so drop what is being
+		// removed from it now.
+		if purgeRemoved(configm); len(configm) == 0 {
+			return (*json.RawMessage)(nil), nil
+		}
 		return jsonRaw(configm), nil
 
 	case map[string]interface{}:
@@ -88,6 +101,26 @@ func PatchConfig(snapName string, subkeys []string, pos int, config interface{},
 	panic(fmt.Errorf("internal error: unexpected configuration type %T", config))
 }
 
+// purgeRemoved deletes the values marked for removal from the given map,
+// along with the maps left empty by that.
+func purgeRemoved(config map[string]interface{}) {
+	for k, v := range config {
+		switch v := v.(type) {
+		case *json.RawMessage:
+			if v == nil {
+				delete(config, k)
+			}
+		case map[string]interface{}:
+			if len(v) == 0 {
+				continue
+			}
+			if purgeRemoved(v); len(v) == 0 {
+				delete(config, k)
+			}
+		}
+	}
+}
+
 // Get unmarshals into result the value of the provided snap's configuration key.
 // If the key does not exist, an error of type *NoOptionError is returned.
 // The provided key may be formed as a dotted key path through nested maps.
@@ -74,6 +74,11 @@ func changes(cfgStr string, cfg map[string]interface{}) []string {
 		case map[string]interface{}:
 			out = append(out, changes(cfgStr+"."+k, subCfg)...)
 		case *json.RawMessage:
+			if subCfg == nil {
+				// the option is being removed
+				out = append(out, cfgStr+"."+k)
+				continue
+			}
 			// check if we need to dive into a sub-config
 			var configm map[string]interface{}
 			if err := jsonutil.DecodeWithNumber(bytes.NewReader(*subCfg), &configm); err == nil {
@@ -130,7 +135,7 @@ func (t *Transaction) Set(snapName, key string, value interface{}) error {
 	// would go unperceived by the configuration patching below.
 	if len(subkeys) > 1 {
 		var result interface{}
-		err = getFromPristine(snapName, subkeys, 0, t.pristine[snapName], &result)
+		err = getFromPristine(snapName, subkeys, 0, mergedConfig(t.pristine[snapName], config), &result)
 		if err != nil && !IsNoOption(err) {
 			return err
 		}
@@ -144,6 +149,51 @@ func (t *Transaction) Set(snapName, key string, value interface{}) error {
 	return nil
 }
 
+// Unset removes the provided snap's configuration key. The key may be
+// formed as a dotted key path through nested maps, in which case only the
+// innermost key is removed. Maps left empty by the removal are removed as
+// well when the transaction is committed.
+//
+// Changes are not persisted until Commit is called.
+func (t *Transaction) Unset(snapName, key string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	subkeys, err := ParseKey(key)
+	if err != nil {
+		return err
+	}
+	if len(subkeys) == 0 {
+		return fmt.Errorf("cannot unset the whole configuration of snap %q", snapName)
+	}
+
+	config, ok := t.changes[snapName]
+	if !ok {
+		config = make(map[string]interface{})
+	}
+
+	// Unsetting a key under a value that is not a map is an error, as
+	// much as setting it is.
+	var result interface{}
+	err = getFromPristine(snapName, subkeys, 0, mergedConfig(t.pristine[snapName], config), &result)
+	if IsNoOption(err) {
+		// nothing to remove
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// a nil value marks the key for removal
+	_, err = PatchConfig(snapName, subkeys, 0, config, nil)
+	if err != nil {
+		return err
+	}
+
+	t.changes[snapName] = config
+	return nil
+}
+
 // Get unmarshals into result the cached value of the provided snap's configuration key.
 // If the key does not exist, an error of type *NoOptionError is returned.
 // The provided key may be formed as a dotted key path through nested maps.
@@ -159,12 +209,13 @@ func (t *Transaction) Get(snapName, key string, result interface{}) error {
 		return err
 	}
 
-	err = GetFromChange(snapName, subkeys, 0, t.changes[snapName], result)
-	if IsNoOption(err) {
-		err = getFromPristine(snapName, subkeys, 0, t.pristine[snapName], result)
+	changes, ok := t.changes[snapName]
+	if !ok {
+		return getFromPristine(snapName, subkeys, 0, t.pristine[snapName], result)
 	}
-
-	return err
+	// look at the configuration as it will be once committed, so that
+	// removed keys are not seen and changed maps are seen in full
+	return getFromPristine(snapName, subkeys, 0, mergedConfig(t.pristine[snapName], changes), result)
 }
 
 // GetMaybe unmarshals into result the cached value of the provided snap's configuration key.
@@ -241,12 +292,10 @@ func (t *Transaction) Commit() {
 
 	// Iterate through the write cache and save each item.
 	for snapName, snapChanges := range t.changes {
-		config, ok := t.pristine[snapName]
-		if !ok {
-			config = make(map[string]*json.RawMessage)
-		}
-		for k, v := range snapChanges {
-			config[k] = commitChange(config[k], v)
+		config := mergedConfig(t.pristine[snapName], snapChanges)
+		if len(config) == 0 {
+			delete(t.pristine, snapName)
+			continue
 		}
 		t.pristine[snapName] = config
 	}
@@ -257,6 +306,23 @@ func (t *Transaction) Commit() {
 	t.changes = make(map[string]map[string]interface{})
 }
 
+// mergedConfig returns a copy of the given pristine configuration of a
+// snap with the changes applied to it, and the removed keys gone.
+func mergedConfig(pristine map[string]*json.RawMessage, changes map[string]interface{}) map[string]*json.RawMessage {
+	config := make(map[string]*json.RawMessage, len(pristine)+len(changes))
+	for k, v := range pristine {
+		config[k] = v
+	}
+	for k, v := range changes {
+		if raw := commitChange(config[k], v); raw != nil {
+			config[k] = raw
+		} else {
+			delete(config, k)
+		}
+	}
+	return config
+}
+
 func jsonRaw(v interface{}) *json.RawMessage {
 	data, err := json.Marshal(v)
 	if err != nil {
@@ -266,21 +332,34 @@ func jsonRaw(v interface{}) *json.RawMessage {
 	return &raw
 }
 
+// commitChange applies the change to the pristine value and returns the
+// result, or nil if the value was removed.
 func commitChange(pristine *json.RawMessage, change interface{}) *json.RawMessage {
 	switch change := change.(type) {
 	case *json.RawMessage:
+		// a nil change removes the value
 		return change
 	case map[string]interface{}:
-		if pristine == nil {
-			return jsonRaw(change)
-		}
 		var pristinem map[string]*json.RawMessage
-		if err := jsonutil.DecodeWithNumber(bytes.NewReader(*pristine), &pristinem); err != nil {
-			// Not a map. Overwrite with the change.
-			return jsonRaw(change)
+		if pristine != nil {
+			if err := jsonutil.DecodeWithNumber(bytes.NewReader(*pristine), &pristinem); err != nil {
+				// Not a map. Overwrite with the change.
+				pristinem = nil
+			}
+		}
+		if pristinem == nil {
+			pristinem = make(map[string]*json.RawMessage, len(change))
 		}
 		for k, v := range change {
-			pristinem[k] = commitChange(pristinem[k], v)
+			if raw := commitChange(pristinem[k], v); raw != nil {
+				pristinem[k] = raw
+			} else {
+				delete(pristinem, k)
+			}
+		}
+		if len(pristinem) == 0 {
+			// all the values were removed
+			return nil
 		}
 		return jsonRaw(pristinem)
 	}
@@ -178,6 +178,67 @@ var setGetTests = [][]setGetOp{{
 	`set one.bad--bad.two=1 => invalid option name: "bad--bad"`,
 	`set one.-bad.two=1 => invalid option name: "-bad"`,
 	`set one.bad-.two=1 => invalid option name: "bad-"`,
+}, {
+	// Unset.
+	`set one=1 two.three=3 two.four=4`,
+	`commit`,
+	`unset one two.three`,
+	`changes core.one core.two.three`,
+	`get one=- two={"four":4}`,
+	`getunder one=1 two={"three":3,"four":4}`,
+	`commit`,
+	`get one=- two={"four":4}`,
+	`getunder one=- two={"four":4}`,
+}, {
+	// Maps left empty by unset are removed.
+	`set one.two.three=3`,
+	`commit`,
+	`unset one.two.three`,
+	`get one=-`,
+	`commit`,
+	`getunder one=-`,
+	`set one.two.three=3`,
+	`get one={"two":{"three":3}}`,
+}, {
+	// Unset and set again in the same transaction.
+	`set one={"two":2,"three":3}`,
+	`commit`,
+	`unset one`,
+	`set one.four=4`,
+	`get one={"four":4}`,
+	`commit`,
+	`getunder one={"four":4}`,
+}, {
+	// Unset of unknown options is fine.
+	`unset one.two`,
+	`get one=-`,
+	`commit`,
+	`getunder one=-`,
+}, {
+	// Unset within a value set in the same transaction.
+	`set one={"two":2,"three":{"four":4},"five":5}`,
+	`unset one.two one.three.four`,
+	`get one={"five":5}`,
+	`changes core.one.five`,
+	`unset one.five`,
+	`get one=-`,
+	`commit`,
+	`getunder one=-`,
+}, {
+	// Cannot unset through known scalars.
+	`set one.two=2`,
+	`unset one.two.three => snap "core" option "one\.two" is not a map`,
+}, {
+	// Null values are kept, unlike unset ones.
+	`set one=null two=2`,
+	`commit`,
+	`unset two`,
+	`get one=null two=-`,
+	`commit`,
+	`get one=null two=-`,
+}, {
+	// Unset with invalid option names.
+	`unset one.-bad => invalid option name: "-bad"`,
 }}
 
 func (s *transactionSuite) TestSetGet(c *C) {
@@ -231,6 +292,19 @@ func (s *transactionSuite) TestSetGet(c *C) {
 					c.Assert(obtained, DeepEquals, expected)
 				}
 
+			case "unset":
+				for _, k := range op.list() {
+					if k == "=>" {
+						break
+					}
+					err := t.Unset(snap, k)
+					if op.fails() {
+						c.Assert(err, ErrorMatches, op.error())
+					} else {
+						c.Assert(err, IsNil)
+					}
+				}
+
 			case "commit":
 				t.Commit()
 
@@ -54,20 +54,26 @@ func coreCfg(tr Conf, key string) (result string, err error) {
 // supportedConfigurations will be filled in by the files (like proxy.go)
 // that handle this configuration.
 var supportedConfigurations = map[string]bool{
-	"core.experimental.layouts": true,
+	"core.experimental.layouts":               true,
+	"core.experimental.parallel-instances":    true,
+	"core.experimental.hotplug":               true,
+	"core.experimental.refresh-app-awareness": true,
 }
 
 func validateExperimentalSettings(tr Conf) error {
-	layoutsEnabled, err := coreCfg(tr, "experimental.layouts")
-	if err != nil {
-		return err
-	}
-	switch layoutsEnabled {
-	case "", "true", "false":
-		return nil
-	default:
-		return fmt.Errorf("experimental.layouts can only be set to 'true' or 'false'")
+	for _, k := range []string{"experimental.layouts", "experimental.parallel-instances", "experimental.hotplug", "experimental.refresh-app-awareness"} {
+		enabled, err := coreCfg(tr, k)
+		if err != nil {
+			return err
+		}
+		switch enabled {
+		case "", "true", "false":
+			// valid
+		default:
+			return fmt.Errorf("%s can only be set to 'true' or 'false'", k)
+		}
 	}
+	return nil
 }
 
 func Run(tr Conf) error {
@@ -81,12 +87,18 @@ func Run(tr Conf) error {
 	if err := validateProxyStore(tr); err != nil {
 		return err
 	}
+	if err := validateOfflineStore(tr); err != nil {
+		return err
+	}
 	if err := validateRefreshSchedule(tr); err != nil {
 		return err
 	}
 	if err := validateExperimentalSettings(tr); err != nil {
 		return err
 	}
+	if err := validateAutomaticSnapshotsRetention(tr); err != nil {
+		return err
+	}
 	// FIXME: ensure the user cannot set "core seed.loaded"
 
 	// capture cloud information
@@ -26,8 +26,10 @@ import (
 
 	. "gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/configstate/configcore"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/systemd"
 )
 
@@ -109,28 +111,32 @@ type runCfgSuite struct {
 var _ = Suite(&runCfgSuite{})
 
 func (r *runCfgSuite) TestConfigureExperimentalSettingsInvalid(c *C) {
-	conf := &mockConf{
-		state: r.state,
-		conf: map[string]interface{}{
-			"experimental.layouts": "foo",
-		},
-	}
-
-	err := configcore.Run(conf)
-	c.Check(err, ErrorMatches, `experimental.layouts can only be set to 'true' or 'false'`)
-}
-
-func (r *runCfgSuite) TestConfigureExperimentalSettingsHappy(c *C) {
-	for _, t := range []string{"true", "false"} {
+	for _, k := range []string{"experimental.layouts", "experimental.parallel-instances", "experimental.hotplug", "experimental.refresh-app-awareness"} {
 		conf := &mockConf{
 			state: r.state,
 			conf: map[string]interface{}{
-				"experimental.layouts": t,
+				k: "foo",
 			},
 		}
 
 		err := configcore.Run(conf)
-		c.Check(err, IsNil)
+		c.Check(err, ErrorMatches, fmt.Sprintf(`%s can only be set to 'true' or 'false'`, k))
+	}
+}
+
+func (r *runCfgSuite) TestConfigureExperimentalSettingsHappy(c *C) {
+	for _, k := range []string{"experimental.layouts", "experimental.parallel-instances", "experimental.hotplug", "experimental.refresh-app-awareness"} {
+		for _, t := range []string{"true", "false"} {
+			conf := &mockConf{
+				state: r.state,
+				conf: map[string]interface{}{
+					k: t,
+				},
+			}
+
+			err := configcore.Run(conf)
+			c.Check(err, IsNil)
+		}
 	}
 }
 
@@ -145,3 +151,27 @@ func (r *runCfgSuite) TestConfigureUnknownOption(c *C) {
 	err := configcore.Run(conf)
 	c.Check(err, ErrorMatches, `cannot set "unknown.option": unsupported system option`)
 }
+
+func (r *runCfgSuite) TestConfigureUnsetOption(c *C) {
+	restore := release.MockOnClassic(true)
+	defer restore()
+
+	r.state.Lock()
+	tr := config.NewTransaction(r.state)
+	c.Assert(tr.Set("core", "experimental.hotplug", true), IsNil)
+	tr.Commit()
+	tr = config.NewTransaction(r.state)
+	r.state.Unlock()
+
+	c.Assert(tr.Unset("core", "experimental.hotplug"), IsNil)
+	c.Check(tr.Changes(), DeepEquals, []string{"core.experimental.hotplug"})
+	c.Assert(configcore.Run(tr), IsNil)
+
+	r.state.Lock()
+	defer r.state.Unlock()
+	tr.Commit()
+
+	var value interface{}
+	err := config.NewTransaction(r.state).Get("core", "experimental", &value)
+	c.Check(err, ErrorMatches, `snap "core" has no "experimental" configuration option`)
+}
@@ -0,0 +1,67 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	"path/filepath"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+)
+
+type offlineStoreSuite struct {
+	configcoreSuite
+}
+
+var _ = Suite(&offlineStoreSuite{})
+
+func (s *offlineStoreSuite) TestConfigureOfflineStoreHappy(c *C) {
+	for _, dir := range []string{"", c.MkDir()} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"offline-store": dir,
+			},
+		})
+		c.Check(err, IsNil, Commentf(dir))
+	}
+}
+
+func (s *offlineStoreSuite) TestConfigureOfflineStoreRelative(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"offline-store": "some/dir",
+		},
+	})
+	c.Assert(err, ErrorMatches, `offline-store must be an absolute path, not "some/dir"`)
+}
+
+func (s *offlineStoreSuite) TestConfigureOfflineStoreNotADirectory(c *C) {
+	dir := filepath.Join(c.MkDir(), "missing")
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"offline-store": dir,
+		},
+	})
+	c.Assert(err, ErrorMatches, `cannot set offline-store to ".*/missing": not a directory`)
+}
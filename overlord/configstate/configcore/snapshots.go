@@ -0,0 +1,51 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore
+
+import (
+	"fmt"
+	"time"
+)
+
+func init() {
+	supportedConfigurations["core.snapshots.automatic.retention"] = true
+}
+
+// minimum retention of automatic snapshots, so they can't be set to
+// expire before anyone had a chance to notice they are needed
+const minAutomaticSnapshotRetention = 24 * time.Hour
+
+func validateAutomaticSnapshotsRetention(tr Conf) error {
+	retentionStr, err := coreCfg(tr, "snapshots.automatic.retention")
+	if err != nil {
+		return err
+	}
+	if retentionStr == "" || retentionStr == "no" {
+		return nil
+	}
+	retention, err := time.ParseDuration(retentionStr)
+	if err != nil {
+		return fmt.Errorf("snapshots.automatic.retention must be a duration or \"no\", not %q", retentionStr)
+	}
+	if retention < minAutomaticSnapshotRetention {
+		return fmt.Errorf("snapshots.automatic.retention must be at least %s", minAutomaticSnapshotRetention)
+	}
+	return nil
+}
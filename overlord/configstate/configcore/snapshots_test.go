@@ -0,0 +1,64 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package configcore_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/configstate/configcore"
+)
+
+type snapshotsSuite struct {
+	configcoreSuite
+}
+
+var _ = Suite(&snapshotsSuite{})
+
+func (s *snapshotsSuite) TestConfigureAutomaticSnapshotsRetentionHappy(c *C) {
+	for _, retention := range []string{"", "no", "24h", "72h", "8760h"} {
+		err := configcore.Run(&mockConf{
+			state: s.state,
+			conf: map[string]interface{}{
+				"snapshots.automatic.retention": retention,
+			},
+		})
+		c.Check(err, IsNil, Commentf(retention))
+	}
+}
+
+func (s *snapshotsSuite) TestConfigureAutomaticSnapshotsRetentionInvalid(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"snapshots.automatic.retention": "invalid",
+		},
+	})
+	c.Assert(err, ErrorMatches, `snapshots.automatic.retention must be a duration or "no", not "invalid"`)
+}
+
+func (s *snapshotsSuite) TestConfigureAutomaticSnapshotsRetentionTooShort(c *C) {
+	err := configcore.Run(&mockConf{
+		state: s.state,
+		conf: map[string]interface{}{
+			"snapshots.automatic.retention": "23h59m",
+		},
+	})
+	c.Assert(err, ErrorMatches, `snapshots.automatic.retention must be at least 24h0m0s`)
+}
@@ -94,6 +94,7 @@ func ConfigureInstalled(st *state.State, snapName string, patch map[string]inter
 }
 
 // Configure returns a taskset to apply the given configuration patch.
+// Keys with a nil value in the patch are unset.
 func Configure(st *state.State, snapName string, patch map[string]interface{}, flags int) *state.TaskSet {
 	summary := fmt.Sprintf(i18n.G("Run configure hook of %q snap"), snapName)
 	// regular configuration hook
@@ -28,6 +28,7 @@ import (
 
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/overlord/configstate"
+	"github.com/snapcore/snapd/overlord/configstate/config"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/hookstate/hooktest"
 	"github.com/snapcore/snapd/overlord/snapstate"
@@ -108,6 +109,30 @@ func (s *configureHandlerSuite) TestBeforeInitializesTransaction(c *C) {
 	c.Check(value, Equals, "bar")
 }
 
+func (s *configureHandlerSuite) TestBeforeUnsetsNilValues(c *C) {
+	s.context.Lock()
+	tr := config.NewTransaction(s.context.State())
+	c.Assert(tr.Set("test-snap", "foo", "bar"), IsNil)
+	c.Assert(tr.Set("test-snap", "baz", "qux"), IsNil)
+	tr.Commit()
+	s.context.Set("patch", map[string]interface{}{
+		"foo": nil,
+	})
+	s.context.Unlock()
+
+	c.Check(s.handler.Before(), IsNil)
+
+	s.context.Lock()
+	tr = configstate.ContextTransaction(s.context)
+	s.context.Unlock()
+
+	var value string
+	c.Check(tr.Get("test-snap", "foo", &value), ErrorMatches, `snap "test-snap" has no "foo" configuration option`)
+	c.Check(tr.Get("test-snap", "baz", &value), IsNil)
+	c.Check(value, Equals, "qux")
+	c.Check(tr.Changes(), DeepEquals, []string{"test-snap.foo"})
+}
+
 func (s *configureHandlerSuite) TestBeforeInitializesTransactionUseDefaults(c *C) {
 	r := release.MockOnClassic(false)
 	defer r()
@@ -108,6 +108,12 @@ func (h *configureHandler) Before() error {
 	}
 
 	for key, value := range patch {
+		if value == nil {
+			if err := tr.Unset(snapName, key); err != nil {
+				return err
+			}
+			continue
+		}
 		if err := tr.Set(snapName, key, value); err != nil {
 			return err
 		}
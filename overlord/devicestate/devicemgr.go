@@ -81,6 +81,7 @@ func Manager(s *state.State, hookManager *hookstate.HookManager) (*DeviceManager
 	runner.AddHandler("generate-device-key", m.doGenerateDeviceKey, nil)
 	runner.AddHandler("request-serial", m.doRequestSerial, nil)
 	runner.AddHandler("mark-seeded", m.doMarkSeeded, nil)
+	runner.AddHandler("set-model", m.doSetModel, m.undoSetModel)
 
 	return m, nil
 }
@@ -225,6 +226,11 @@ func (m *DeviceManager) ensureOperational() error {
 		return nil
 	}
 
+	if m.changeInFlight("remodel") {
+		// the remodel requests the serial for the new model
+		return nil
+	}
+
 	var storeID, gadget string
 	model, err := Model(m.state)
 	if err != nil && err != state.ErrNoState {
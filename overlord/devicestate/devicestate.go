@@ -26,6 +26,7 @@ import (
 	"sync"
 
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/i18n"
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/netutil"
 	"github.com/snapcore/snapd/overlord/assertstate"
@@ -36,6 +37,7 @@ import (
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/store/offline"
 )
 
 // Model returns the device model assertion.
@@ -174,11 +176,16 @@ func checkGadgetOrKernel(st *state.State, snapInfo, curInfo *snap.Info, flags sn
 	if err != nil && err != state.ErrNoState {
 		return fmt.Errorf("cannot find original %s snap: %v", kind, err)
 	}
-	if currentSnap != nil {
+	if currentSnap != nil && currentSnap.Name() == snapInfo.Name() {
 		// already installed, snapstate takes care
 		return nil
 	}
-	// first installation of a gadget/kernel
+	if currentSnap != nil && !flags.Remodel {
+		// snapstate refuses the switch
+		return nil
+	}
+	// first installation of a gadget/kernel, or switch to the
+	// one of a new model
 
 	expectedName := getName(model)
 	if expectedName == "" { // can happen only on classic
@@ -202,6 +209,32 @@ func delayedCrossMgrInit() {
 	snapstate.CanManageRefreshes = CanManageRefreshes
 	snapstate.IsOnMeteredConnection = netutil.IsOnMeteredConnection
 	snapstate.Model = Model
+	snapstate.OfflineStore = offlineStore
+}
+
+type cachedOfflineStoreKey struct{}
+
+// offlineStore returns the offline store for the directory set with the
+// offline-store core option, if any.
+func offlineStore(st *state.State) snapstate.StoreService {
+	tr := config.NewTransaction(st)
+	var dir string
+	err := tr.GetMaybe("core", "offline-store", &dir)
+	if err != nil {
+		logger.Noticef("Cannot get offline-store setting: %v", err)
+		return nil
+	}
+	if dir == "" {
+		return nil
+	}
+
+	// keep the store, and its index, as long as the setting is unchanged
+	sto, _ := st.Cached(cachedOfflineStoreKey{}).(*offline.Store)
+	if sto == nil || sto.Dir() != dir {
+		sto = offline.New(dir)
+		st.Cache(cachedOfflineStoreKey{}, sto)
+	}
+	return sto
 }
 
 // ProxyStore returns the store assertion for the proxy store if one is set.
@@ -270,3 +303,149 @@ func CanManageRefreshes(st *state.State) bool {
 
 	return false
 }
+
+var (
+	snapstateInstall = snapstate.Install
+	snapstateUpdate  = snapstate.Update
+)
+
+// Remodel takes a new model assertion and generates a change that
+// takes the device from the current model to the new one, or returns
+// an error if the transition is not possible.
+//
+// TODO: support remodeling to different brands, gadgets, bases and stores.
+func Remodel(st *state.State, new *asserts.Model) (*state.Change, error) {
+	var seeded bool
+	err := st.Get("seeded", &seeded)
+	if err != nil && err != state.ErrNoState {
+		return nil, err
+	}
+	if !seeded {
+		return nil, fmt.Errorf("cannot remodel until fully seeded")
+	}
+
+	current, err := Model(st)
+	if err == state.ErrNoState {
+		return nil, fmt.Errorf("cannot remodel without a current model assertion")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if current.Series() != new.Series() {
+		return nil, fmt.Errorf("cannot remodel to different series yet")
+	}
+	if current.BrandID() != new.BrandID() {
+		return nil, fmt.Errorf("cannot remodel to different brands yet")
+	}
+	if current.Architecture() != new.Architecture() {
+		return nil, fmt.Errorf("cannot remodel to different architectures yet")
+	}
+	if current.Classic() != new.Classic() {
+		return nil, fmt.Errorf("cannot remodel from classic to core or vice versa")
+	}
+	if current.Gadget() != new.Gadget() {
+		return nil, fmt.Errorf("cannot remodel to different gadgets yet")
+	}
+	if current.Base() != new.Base() {
+		return nil, fmt.Errorf("cannot remodel to different bases yet")
+	}
+	// the snaps of the new model would be looked up in the store of
+	// the current one
+	if current.Store() != new.Store() {
+		return nil, fmt.Errorf("cannot remodel to different stores yet")
+	}
+	if current.Model() == new.Model() && current.Revision() >= new.Revision() {
+		return nil, fmt.Errorf("cannot remodel to the same or an older revision of the current model")
+	}
+
+	if err := assertstate.DB(st).Check(new); err != nil {
+		return nil, fmt.Errorf("cannot use new model assertion: %v", err)
+	}
+
+	for _, chg := range st.Changes() {
+		if chg.Kind() == "remodel" && !chg.Status().Ready() {
+			return nil, fmt.Errorf("cannot remodel while another remodel is in progress")
+		}
+	}
+
+	tss, err := remodelTasks(st, current, new)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := fmt.Sprintf(i18n.G("Remodel device to %v/%v (%v)"), new.BrandID(), new.Model(), new.Revision())
+	chg := st.NewChange("remodel", msg)
+	for _, ts := range tss {
+		chg.AddAll(ts)
+	}
+
+	return chg, nil
+}
+
+// remodelTasks computes the task sets to switch from the current to
+// the new model. The new model is set first, followed by a request for
+// a new serial if the model name changes, followed by the kernel switch
+// or track change and the installation of newly required snaps. Snaps
+// that are no longer required by the new model are kept.
+func remodelTasks(st *state.State, current, new *asserts.Model) ([]*state.TaskSet, error) {
+	var tss []*state.TaskSet
+
+	setModel := st.NewTask("set-model", i18n.G("Set new model assertion"))
+	setModel.Set("new-model", string(asserts.Encode(new)))
+	last := state.NewTaskSet(setModel)
+	tss = append(tss, last)
+
+	if current.Model() != new.Model() {
+		genKey := st.NewTask("generate-device-key", i18n.G("Generate device key"))
+		genKey.WaitFor(setModel)
+		requestSerial := st.NewTask("request-serial", i18n.G("Request device serial"))
+		requestSerial.WaitFor(genKey)
+		last = state.NewTaskSet(genKey, requestSerial)
+		tss = append(tss, last)
+	}
+
+	var snapTss []*state.TaskSet
+
+	kernelChannel := ""
+	if new.KernelTrack() != "" {
+		kernelChannel = new.KernelTrack() + "/stable"
+	}
+	if current.Kernel() != new.Kernel() {
+		ts, err := snapstateInstall(st, new.Kernel(), kernelChannel, snap.R(0), 0, snapstate.Flags{Remodel: true})
+		if err != nil {
+			return nil, err
+		}
+		snapTss = append(snapTss, ts)
+	} else if current.KernelTrack() != new.KernelTrack() && kernelChannel != "" {
+		ts, err := snapstateUpdate(st, new.Kernel(), kernelChannel, snap.R(0), 0, snapstate.Flags{})
+		if err != nil {
+			return nil, err
+		}
+		snapTss = append(snapTss, ts)
+	}
+
+	for _, snapName := range new.RequiredSnaps() {
+		_, err := snapstate.CurrentInfo(st, snapName)
+		if err == nil {
+			// already installed, keep it
+			continue
+		}
+		if _, ok := err.(*snap.NotInstalledError); !ok {
+			return nil, err
+		}
+		ts, err := snapstateInstall(st, snapName, "", snap.R(0), 0, snapstate.Flags{Required: true})
+		if err != nil {
+			return nil, err
+		}
+		snapTss = append(snapTss, ts)
+	}
+
+	for _, ts := range snapTss {
+		ts.WaitAll(last)
+		last = ts
+		tss = append(tss, ts)
+	}
+
+	return tss, nil
+}
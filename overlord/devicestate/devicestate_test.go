@@ -60,6 +60,7 @@ import (
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/store/offline"
 	"github.com/snapcore/snapd/store/storetest"
 	"github.com/snapcore/snapd/strutil"
 )
@@ -325,7 +326,7 @@ func (s *deviceMgrSuite) findBecomeOperationalChange(skipIDs ...string) *state.C
 func (s *deviceMgrSuite) TestKnownTaskKinds(c *C) {
 	kinds := s.mgr.KnownTaskKinds()
 	sort.Strings(kinds)
-	c.Assert(kinds, DeepEquals, []string{"generate-device-key", "mark-seeded", "request-serial"})
+	c.Assert(kinds, DeepEquals, []string{"generate-device-key", "mark-seeded", "request-serial", "set-model"})
 }
 
 func (s *deviceMgrSuite) TestFullDeviceRegistrationHappy(c *C) {
@@ -1468,6 +1469,42 @@ func (s *deviceMgrSuite) TestDeviceAssertionsProxyStore(c *C) {
 	c.Assert(sto.Store(), Equals, "foo")
 }
 
+func (s *deviceMgrSuite) TestOfflineStore(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// not configured, the usual store is used
+	_, ok := snapstate.Store(s.state).(*fakeStore)
+	c.Check(ok, Equals, true)
+
+	dir := c.MkDir()
+	tr := config.NewTransaction(s.state)
+	c.Assert(tr.Set("core", "offline-store", dir), IsNil)
+	tr.Commit()
+
+	sto, ok := snapstate.Store(s.state).(*offline.Store)
+	c.Assert(ok, Equals, true)
+	c.Check(sto.Dir(), Equals, dir)
+	// the same store is kept while the setting is unchanged
+	c.Check(snapstate.Store(s.state), Equals, sto)
+
+	otherDir := c.MkDir()
+	tr = config.NewTransaction(s.state)
+	c.Assert(tr.Set("core", "offline-store", otherDir), IsNil)
+	tr.Commit()
+
+	sto, ok = snapstate.Store(s.state).(*offline.Store)
+	c.Assert(ok, Equals, true)
+	c.Check(sto.Dir(), Equals, otherDir)
+
+	tr = config.NewTransaction(s.state)
+	c.Assert(tr.Set("core", "offline-store", ""), IsNil)
+	tr.Commit()
+
+	_, ok = snapstate.Store(s.state).(*fakeStore)
+	c.Check(ok, Equals, true)
+}
+
 func (s *deviceMgrSuite) TestDeviceManagerEnsureSeedYamlAlreadySeeded(c *C) {
 	s.state.Lock()
 	s.state.Set("seeded", true)
@@ -2264,3 +2301,336 @@ func (s *deviceMgrSuite) TestMarkSeededInConfig(c *C) {
 	// changes
 	c.Check(s.state.Changes(), HasLen, 1)
 }
+
+func (s *deviceMgrSuite) makeModel(c *C, model string, extras map[string]interface{}) *asserts.Model {
+	headers := map[string]interface{}{
+		"series":       "16",
+		"brand-id":     "my-brand",
+		"model":        model,
+		"architecture": "amd64",
+		"gadget":       "pc",
+		"kernel":       "pc-kernel",
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}
+	for k, v := range extras {
+		headers[k] = v
+	}
+	a, err := s.brandSigning.Sign(asserts.ModelType, headers, nil, "")
+	c.Assert(err, IsNil)
+	return a.(*asserts.Model)
+}
+
+func (s *deviceMgrSuite) setupRemodel(c *C, current *asserts.Model) {
+	s.setupBrands(c)
+	err := assertstate.Add(s.state, current)
+	c.Assert(err, IsNil)
+	err = auth.SetDevice(s.state, &auth.DeviceState{
+		Brand:           "my-brand",
+		Model:           current.Model(),
+		Serial:          "serial-1",
+		KeyID:           "key-id",
+		SessionMacaroon: "session",
+	})
+	c.Assert(err, IsNil)
+	s.state.Set("seeded", true)
+}
+
+func (s *deviceMgrSuite) TestRemodelUnhappyNotSeeded(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setupBrands(c)
+	_, err := devicestate.Remodel(s.state, s.makeModel(c, "my-model", nil))
+	c.Check(err, ErrorMatches, "cannot remodel until fully seeded")
+
+	s.state.Set("seeded", true)
+	_, err = devicestate.Remodel(s.state, s.makeModel(c, "my-model", nil))
+	c.Check(err, ErrorMatches, "cannot remodel without a current model assertion")
+}
+
+func (s *deviceMgrSuite) TestRemodelUnhappy(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setupRemodel(c, s.makeModel(c, "my-model", map[string]interface{}{
+		"revision": "1",
+	}))
+
+	for _, t := range []struct {
+		model  string
+		extras map[string]interface{}
+		errStr string
+	}{
+		{"my-model", map[string]interface{}{"architecture": "pdp-7"}, "cannot remodel to different architectures yet"},
+		{"my-model", map[string]interface{}{"gadget": "other-gadget"}, "cannot remodel to different gadgets yet"},
+		{"my-model", map[string]interface{}{"base": "core18"}, "cannot remodel to different bases yet"},
+		{"my-model", map[string]interface{}{"store": "other-store"}, "cannot remodel to different stores yet"},
+		{"my-model", map[string]interface{}{"revision": "1"}, "cannot remodel to the same or an older revision of the current model"},
+		{"my-model", nil, "cannot remodel to the same or an older revision of the current model"},
+	} {
+		_, err := devicestate.Remodel(s.state, s.makeModel(c, t.model, t.extras))
+		c.Check(err, ErrorMatches, t.errStr)
+	}
+
+	// a model from another brand
+	other, err := s.storeSigning.RootSigning.Sign(asserts.ModelType, map[string]interface{}{
+		"series":       "16",
+		"brand-id":     "canonical",
+		"model":        "pc",
+		"architecture": "amd64",
+		"gadget":       "pc",
+		"kernel":       "pc-kernel",
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	_, err = devicestate.Remodel(s.state, other.(*asserts.Model))
+	c.Check(err, ErrorMatches, "cannot remodel to different brands yet")
+
+	// another remodel is in progress
+	chg := s.state.NewChange("remodel", "...")
+	chg.AddTask(s.state.NewTask("nop", "..."))
+	_, err = devicestate.Remodel(s.state, s.makeModel(c, "other-model", nil))
+	c.Check(err, ErrorMatches, "cannot remodel while another remodel is in progress")
+}
+
+func (s *deviceMgrSuite) TestRemodelRequiredSnapsAndKernelTrack(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setupRemodel(c, s.makeModel(c, "my-model", map[string]interface{}{
+		"required-snaps": []interface{}{"foo"},
+	}))
+	for _, name := range []string{"foo", "pc-kernel"} {
+		si := &snap.SideInfo{RealName: name, Revision: snap.R(1)}
+		snaptest.MockSnap(c, fmt.Sprintf("name: %s\nversion: 1\n", name), si)
+		snapstate.Set(s.state, name, &snapstate.SnapState{
+			Active:   true,
+			Sequence: []*snap.SideInfo{si},
+			Current:  si.Revision,
+		})
+	}
+
+	restore := devicestate.MockSnapstateInstall(func(st *state.State, name, channel string, revision snap.Revision, userID int, flags snapstate.Flags) (*state.TaskSet, error) {
+		c.Check(flags, Equals, snapstate.Flags{Required: true})
+		c.Check(channel, Equals, "")
+		tInstall := st.NewTask("fake-install", fmt.Sprintf("Install %s", name))
+		return state.NewTaskSet(tInstall), nil
+	})
+	defer restore()
+	restore = devicestate.MockSnapstateUpdate(func(st *state.State, name, channel string, revision snap.Revision, userID int, flags snapstate.Flags) (*state.TaskSet, error) {
+		c.Check(name, Equals, "pc-kernel")
+		c.Check(channel, Equals, "18/stable")
+		tUpdate := st.NewTask("fake-update", fmt.Sprintf("Update %s to track %s", name, channel))
+		return state.NewTaskSet(tUpdate), nil
+	})
+	defer restore()
+
+	new := s.makeModel(c, "my-model", map[string]interface{}{
+		"revision":       "1",
+		"kernel":         "pc-kernel=18",
+		"required-snaps": []interface{}{"foo", "bar"},
+	})
+	chg, err := devicestate.Remodel(s.state, new)
+	c.Assert(err, IsNil)
+	c.Check(chg.Kind(), Equals, "remodel")
+	c.Check(chg.Summary(), Equals, "Remodel device to my-brand/my-model (1)")
+
+	tl := chg.Tasks()
+	c.Assert(tl, HasLen, 3)
+	setModel, tUpdate, tInstall := tl[0], tl[1], tl[2]
+	c.Check(setModel.Kind(), Equals, "set-model")
+	c.Check(tUpdate.Summary(), Equals, "Update pc-kernel to track 18/stable")
+	c.Check(tInstall.Summary(), Equals, "Install bar")
+	c.Check(tUpdate.WaitTasks(), DeepEquals, []*state.Task{setModel})
+	c.Check(tInstall.WaitTasks(), DeepEquals, []*state.Task{tUpdate})
+
+	var encoded string
+	c.Assert(setModel.Get("new-model", &encoded), IsNil)
+	c.Check(encoded, Equals, string(asserts.Encode(new)))
+}
+
+func (s *deviceMgrSuite) TestRemodelNewModelSwitchesKernelAndRequestsSerial(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setupRemodel(c, s.makeModel(c, "my-model", nil))
+
+	restore := devicestate.MockSnapstateInstall(func(st *state.State, name, channel string, revision snap.Revision, userID int, flags snapstate.Flags) (*state.TaskSet, error) {
+		c.Check(name, Equals, "other-kernel")
+		c.Check(flags, Equals, snapstate.Flags{Remodel: true})
+		tInstall := st.NewTask("fake-install", fmt.Sprintf("Install %s", name))
+		return state.NewTaskSet(tInstall), nil
+	})
+	defer restore()
+
+	chg, err := devicestate.Remodel(s.state, s.makeModel(c, "other-model", map[string]interface{}{
+		"kernel": "other-kernel",
+	}))
+	c.Assert(err, IsNil)
+
+	tl := chg.Tasks()
+	c.Assert(tl, HasLen, 4)
+	setModel, genKey, requestSerial, tInstall := tl[0], tl[1], tl[2], tl[3]
+	c.Check(setModel.Kind(), Equals, "set-model")
+	c.Check(genKey.Kind(), Equals, "generate-device-key")
+	c.Check(genKey.WaitTasks(), DeepEquals, []*state.Task{setModel})
+	c.Check(requestSerial.Kind(), Equals, "request-serial")
+	c.Check(requestSerial.WaitTasks(), DeepEquals, []*state.Task{genKey})
+	c.Check(tInstall.Summary(), Equals, "Install other-kernel")
+	c.Check(tInstall.WaitTasks(), DeepEquals, []*state.Task{genKey, requestSerial})
+}
+
+func (s *deviceMgrSuite) TestDoSetModel(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setupRemodel(c, s.makeModel(c, "my-model", nil))
+	new := s.makeModel(c, "other-model", map[string]interface{}{
+		"store": "other-store",
+	})
+
+	chg := s.state.NewChange("remodel", "...")
+	t := s.state.NewTask("set-model", "...")
+	t.Set("new-model", string(asserts.Encode(new)))
+	chg.AddTask(t)
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	device, err := auth.Device(s.state)
+	c.Assert(err, IsNil)
+	c.Check(device, DeepEquals, &auth.DeviceState{
+		Brand: "my-brand",
+		Model: "other-model",
+		KeyID: "key-id",
+	})
+
+	model, err := devicestate.Model(s.state)
+	c.Assert(err, IsNil)
+	c.Check(model.Model(), Equals, "other-model")
+	c.Check(model.Store(), Equals, "other-store")
+}
+
+func (s *deviceMgrSuite) TestDoSetModelSameModelKeepsSerial(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setupRemodel(c, s.makeModel(c, "my-model", nil))
+	new := s.makeModel(c, "my-model", map[string]interface{}{
+		"revision":       "1",
+		"required-snaps": []interface{}{"foo"},
+	})
+
+	chg := s.state.NewChange("remodel", "...")
+	t := s.state.NewTask("set-model", "...")
+	t.Set("new-model", string(asserts.Encode(new)))
+	chg.AddTask(t)
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	device, err := auth.Device(s.state)
+	c.Assert(err, IsNil)
+	c.Check(device.Serial, Equals, "serial-1")
+	c.Check(device.SessionMacaroon, Equals, "session")
+
+	model, err := devicestate.Model(s.state)
+	c.Assert(err, IsNil)
+	c.Check(model.Revision(), Equals, 1)
+	c.Check(model.RequiredSnaps(), DeepEquals, []string{"foo"})
+}
+
+func (s *deviceMgrSuite) TestDoSetModelUndo(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	devicestate.AddErrorTriggerHandler(s.mgr)
+
+	s.setupRemodel(c, s.makeModel(c, "my-model", nil))
+	new := s.makeModel(c, "other-model", nil)
+
+	chg := s.state.NewChange("remodel", "...")
+	t := s.state.NewTask("set-model", "...")
+	t.Set("new-model", string(asserts.Encode(new)))
+	chg.AddTask(t)
+	terr := s.state.NewTask("error-trigger", "provoking total undo")
+	terr.WaitFor(t)
+	chg.AddTask(terr)
+
+	s.state.Unlock()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), NotNil)
+	c.Check(t.Status(), Equals, state.UndoneStatus)
+	device, err := auth.Device(s.state)
+	c.Assert(err, IsNil)
+	c.Check(device, DeepEquals, &auth.DeviceState{
+		Brand:           "my-brand",
+		Model:           "my-model",
+		Serial:          "serial-1",
+		KeyID:           "key-id",
+		SessionMacaroon: "session",
+	})
+}
+
+func (s *deviceMgrSuite) TestEnsureOperationalSkippedDuringRemodel(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setupRemodel(c, s.makeModel(c, "my-model", nil))
+	// the remodel to a new model cleared the serial
+	err := auth.SetDevice(s.state, &auth.DeviceState{
+		Brand: "my-brand",
+		Model: "my-model",
+		KeyID: "key-id",
+	})
+	c.Assert(err, IsNil)
+	s.setupGadget(c, "name: gadget\ntype: gadget\nversion: 1\n", "")
+	chg := s.state.NewChange("remodel", "...")
+	chg.AddTask(s.state.NewTask("nop", "..."))
+
+	s.state.Unlock()
+	s.mgr.Ensure()
+	s.state.Lock()
+
+	c.Check(s.findBecomeOperationalChange(), IsNil)
+
+	// once the remodel is over the device gets registered as usual
+	chg.SetStatus(state.DoneStatus)
+
+	s.state.Unlock()
+	s.mgr.Ensure()
+	s.state.Lock()
+
+	c.Check(s.findBecomeOperationalChange(), NotNil)
+}
+
+func (s *deviceMgrSuite) TestCheckKernelRemodel(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.setupRemodel(c, s.makeModel(c, "my-model", map[string]interface{}{
+		"kernel": "new-kernel",
+	}))
+	si := &snap.SideInfo{RealName: "pc-kernel", Revision: snap.R(1)}
+	snaptest.MockSnap(c, "name: pc-kernel\ntype: kernel\nversion: 1\n", si)
+	snapstate.Set(s.state, "pc-kernel", &snapstate.SnapState{
+		SnapType: "kernel",
+		Active:   true,
+		Sequence: []*snap.SideInfo{si},
+		Current:  si.Revision,
+	})
+
+	otherKrnlInfo := snaptest.MockInfo(c, "{type: kernel, name: other-kernel, version: 0}", nil)
+	err := devicestate.CheckGadgetOrKernel(s.state, otherKrnlInfo, nil, snapstate.Flags{Remodel: true})
+	c.Check(err, ErrorMatches, `cannot install kernel "other-kernel", model assertion requests "new-kernel"`)
+
+	newKrnlInfo := snaptest.MockInfo(c, "{type: kernel, name: new-kernel, version: 0}", nil)
+	err = devicestate.CheckGadgetOrKernel(s.state, newKrnlInfo, nil, snapstate.Flags{Remodel: true})
+	c.Check(err, IsNil)
+}
@@ -20,10 +20,15 @@
 package devicestate
 
 import (
+	"errors"
 	"time"
 
+	"gopkg.in/tomb.v2"
+
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
 )
 
 func MockKeyLength(n int) (restore func()) {
@@ -124,3 +129,30 @@ var (
 	IncEnsureOperationalAttempts = incEnsureOperationalAttempts
 	EnsureOperationalAttempts    = ensureOperationalAttempts
 )
+
+type snapstateFunc func(st *state.State, name, channel string, revision snap.Revision, userID int, flags snapstate.Flags) (*state.TaskSet, error)
+
+func MockSnapstateInstall(f snapstateFunc) (restore func()) {
+	old := snapstateInstall
+	snapstateInstall = f
+	return func() {
+		snapstateInstall = old
+	}
+}
+
+func MockSnapstateUpdate(f snapstateFunc) (restore func()) {
+	old := snapstateUpdate
+	snapstateUpdate = f
+	return func() {
+		snapstateUpdate = old
+	}
+}
+
+// AddErrorTriggerHandler registers a handler to test full aborting of
+// changes.
+func AddErrorTriggerHandler(m *DeviceManager) {
+	erroringHandler := func(task *state.Task, _ *tomb.Tomb) error {
+		return errors.New("error out")
+	}
+	m.runner.AddHandler("error-trigger", erroringHandler, nil)
+}
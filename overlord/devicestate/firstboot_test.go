@@ -127,6 +127,25 @@ func (s *FirstBootTestSuite) TearDownTest(c *C) {
 	dirs.SetRootDir("/")
 }
 
+// readPersistedState returns the state as persisted on disk, including
+// the entries in the state journal.
+func readPersistedState(c *C) *state.State {
+	r, err := os.Open(dirs.SnapStateFile)
+	c.Assert(err, IsNil)
+	defer r.Close()
+	j, err := os.Open(overlord.JournalPath(dirs.SnapStateFile))
+	if os.IsNotExist(err) {
+		st, err := state.ReadState(nil, r)
+		c.Assert(err, IsNil)
+		return st
+	}
+	c.Assert(err, IsNil)
+	defer j.Close()
+	st, err := state.ReadStateWithJournal(nil, r, j)
+	c.Assert(err, IsNil)
+	return st
+}
+
 func checkTrivialSeeding(c *C, tsAll []*state.TaskSet) {
 	// run internal core config and  mark seeded
 	c.Check(tsAll, HasLen, 2)
@@ -480,10 +499,7 @@ func (s *FirstBootTestSuite) TestPopulateFromSeedHappy(c *C) {
 	c.Check(osutil.FileExists(filepath.Join(dirs.SnapMountDir, "local", "x1", "meta", "snap.yaml")), Equals, true)
 
 	// verify
-	r, err := os.Open(dirs.SnapStateFile)
-	c.Assert(err, IsNil)
-	state, err := state.ReadState(nil, r)
-	c.Assert(err, IsNil)
+	state := readPersistedState(c)
 
 	state.Lock()
 	defer state.Unlock()
@@ -669,10 +685,7 @@ snaps:
 	c.Check(osutil.FileExists(filepath.Join(dirs.SnapMountDir, "bar", "65", "meta", "snap.yaml")), Equals, true)
 
 	// verify
-	r, err := os.Open(dirs.SnapStateFile)
-	c.Assert(err, IsNil)
-	state, err := state.ReadState(nil, r)
-	c.Assert(err, IsNil)
+	state := readPersistedState(c)
 
 	state.Lock()
 	defer state.Unlock()
@@ -857,10 +870,7 @@ snaps:
 	c.Check(osutil.FileExists(filepath.Join(dirs.SnapMountDir, "foo", "128", "meta", "snap.yaml")), Equals, true)
 
 	// verify
-	r, err := os.Open(dirs.SnapStateFile)
-	c.Assert(err, IsNil)
-	state, err := state.ReadState(nil, r)
-	c.Assert(err, IsNil)
+	state := readPersistedState(c)
 
 	state.Lock()
 	defer state.Unlock()
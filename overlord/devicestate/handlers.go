@@ -53,6 +53,73 @@ func (m *DeviceManager) doMarkSeeded(t *state.Task, _ *tomb.Tomb) error {
 	return nil
 }
 
+func (m *DeviceManager) doSetModel(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var encodedModel string
+	if err := t.Get("new-model", &encodedModel); err != nil {
+		return err
+	}
+	a, err := asserts.Decode([]byte(encodedModel))
+	if err != nil {
+		return err
+	}
+	new, ok := a.(*asserts.Model)
+	if !ok {
+		return fmt.Errorf("internal error: new-model is not a model assertion but %q", a.Type().Name)
+	}
+
+	current, err := Model(st)
+	if err != nil {
+		return err
+	}
+	device, err := auth.Device(st)
+	if err != nil {
+		return err
+	}
+
+	err = assertstate.Add(st, new)
+	if err != nil && !asserts.IsUnaccceptedUpdate(err) {
+		return err
+	}
+
+	// remember the old device state to undo, only the first time
+	// around in case the task is rerun
+	var oldDevice auth.DeviceState
+	err = t.Get("old-device", &oldDevice)
+	if err == state.ErrNoState {
+		t.Set("old-device", device)
+	} else if err != nil {
+		return err
+	}
+
+	newDevice := *device
+	newDevice.Model = new.Model()
+	if current.Model() != new.Model() {
+		// a new serial is requested for the new model
+		newDevice.Serial = ""
+		newDevice.SessionMacaroon = ""
+	}
+	if current.Store() != new.Store() {
+		newDevice.SessionMacaroon = ""
+	}
+	return auth.SetDevice(st, &newDevice)
+}
+
+func (m *DeviceManager) undoSetModel(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var oldDevice auth.DeviceState
+	if err := t.Get("old-device", &oldDevice); err != nil {
+		return err
+	}
+	return auth.SetDevice(st, &oldDevice)
+}
+
 func useStaging() bool {
 	return osutil.GetenvBool("SNAPPY_USE_STAGING_STORE")
 }
@@ -31,6 +31,16 @@ import (
 	"github.com/jessevdk/go-flags"
 )
 
+// UnsuccessfulError is returned by commands that completed without an
+// error but want snapctl to exit with a non-zero exit code.
+type UnsuccessfulError struct {
+	ExitCode int
+}
+
+func (e *UnsuccessfulError) Error() string {
+	return fmt.Sprintf("unsuccessful with exit code: %d", e.ExitCode)
+}
+
 type baseCommand struct {
 	stdout io.Writer
 	stderr io.Writer
@@ -21,6 +21,8 @@ package ctlcmd
 
 import (
 	"fmt"
+
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/servicestate"
 	"github.com/snapcore/snapd/overlord/state"
@@ -35,6 +37,12 @@ func MockServicestateControlFunc(f func(*state.State, []*snap.AppInfo, *services
 	return func() { servicestateControl = old }
 }
 
+func MockServicestateClientAppInfos(f func([]*snap.AppInfo) []client.AppInfo) (restore func()) {
+	old := servicestateClientAppInfos
+	servicestateClientAppInfos = f
+	return func() { servicestateClientAppInfos = old }
+}
+
 func AddMockCommand(name string) *MockCommand {
 	mockCommand := NewMockCommand()
 	addCommand(name, "", "", func() command { return mockCommand })
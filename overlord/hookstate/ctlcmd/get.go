@@ -46,6 +46,8 @@ type getCommand struct {
 
 	Document bool `short:"d" description:"always return document, even with single key"`
 	Typed    bool `short:"t" description:"strict typing with nulls and quoted strings"`
+
+	ListConnections bool `long:"list-connections" description:"list the snaps connected to the plug or slot, along with their attributes"`
 }
 
 var shortGetHelp = i18n.G("The get command prints configuration and interface connection settings.")
@@ -80,6 +82,24 @@ by explicitly requesting that via the --plug and --slot command line options:
     $ snapctl get :myplug --slot usb-vendor
 
 This requests the "usb-vendor" setting from the slot that is connected to "myplug".
+
+The plugs or slots connected to a local interface endpoint, along with their
+static and dynamic attributes, may be listed with:
+
+    $ snapctl get --list-connections :myplug
+    [
+        {
+            "snap": "other-snap",
+            "slot": "otherslot",
+            "interface": "content",
+            "static-attrs": {
+                "read": ["/"]
+            }
+        }
+    ]
+
+Unlike reading the attributes of a connection, listing the connections is also
+possible outside of interface hooks.
 `)
 
 func init() {
@@ -144,6 +164,10 @@ func (c *getCommand) Execute(args []string) error {
 		return fmt.Errorf("cannot use -d and -t together")
 	}
 
+	if c.ListConnections {
+		return c.listConnections(context)
+	}
+
 	if strings.Contains(c.Positional.PlugOrSlotSpec, ":") {
 		parts := strings.SplitN(c.Positional.PlugOrSlotSpec, ":", 2)
 		snap, name := parts[0], parts[1]
@@ -164,6 +188,34 @@ func (c *getCommand) Execute(args []string) error {
 	return c.getConfigSetting(context)
 }
 
+func (c *getCommand) listConnections(context *hookstate.Context) error {
+	if len(c.Positional.Keys) > 0 || c.ForcePlugSide || c.ForceSlotSide || c.Typed || c.Document {
+		return fmt.Errorf(i18n.G("cannot use --list-connections with other options or keys"))
+	}
+	plugOrSlot := strings.TrimPrefix(c.Positional.PlugOrSlotSpec, ":")
+	if !strings.HasPrefix(c.Positional.PlugOrSlotSpec, ":") || plugOrSlot == "" || strings.Contains(plugOrSlot, ":") {
+		return fmt.Errorf(i18n.G("--list-connections requires a :<plug|slot> argument"))
+	}
+
+	st := context.State()
+	st.Lock()
+	peers, err := connectedPeers(st, context.SnapName(), plugOrSlot)
+	st.Unlock()
+	if err != nil {
+		return err
+	}
+	if peers == nil {
+		peers = []connectedPeer{}
+	}
+
+	bytes, err := json.MarshalIndent(peers, "", "\t")
+	if err != nil {
+		return err
+	}
+	c.printf("%s\n", string(bytes))
+	return nil
+}
+
 func (c *getCommand) getConfigSetting(context *hookstate.Context) error {
 	if c.ForcePlugSide || c.ForceSlotSide {
 		return fmt.Errorf("cannot use --plug or --slot without <snap>:<plug|slot> argument")
@@ -196,6 +248,10 @@ const (
 	prepareSlotHook
 	connectPlugHook
 	connectSlotHook
+	disconnectPlugHook
+	disconnectSlotHook
+	unpreparePlugHook
+	unprepareSlotHook
 	unknownHook
 )
 
@@ -208,6 +264,14 @@ func interfaceHookType(hookName string) (ifaceHookType, error) {
 		return prepareSlotHook, nil
 	} else if strings.HasPrefix(hookName, "connect-slot-") {
 		return connectSlotHook, nil
+	} else if strings.HasPrefix(hookName, "disconnect-plug-") {
+		return disconnectPlugHook, nil
+	} else if strings.HasPrefix(hookName, "disconnect-slot-") {
+		return disconnectSlotHook, nil
+	} else if strings.HasPrefix(hookName, "unprepare-plug-") {
+		return unpreparePlugHook, nil
+	} else if strings.HasPrefix(hookName, "unprepare-slot-") {
+		return unprepareSlotHook, nil
 	}
 	return unknownHook, fmt.Errorf("unknown hook type")
 }
@@ -275,7 +339,7 @@ func (c *getCommand) getInterfaceSetting(context *hookstate.Context, plugOrSlot
 		return fmt.Errorf("cannot use --plug and --slot together")
 	}
 
-	isPlugSide := (hookType == preparePlugHook || hookType == connectPlugHook)
+	isPlugSide := (hookType == preparePlugHook || hookType == connectPlugHook || hookType == disconnectPlugHook || hookType == unpreparePlugHook)
 	if err = validatePlugOrSlot(attrsTask, isPlugSide, plugOrSlot); err != nil {
 		return err
 	}
@@ -328,3 +328,44 @@ func (s *getAttrSuite) TestSlotHookTests(c *C) {
 		}
 	}
 }
+
+func (s *getAttrSuite) TestDisconnectAndUnprepareHooks(c *C) {
+	var attrsTaskID string
+	s.mockPlugHookContext.Lock()
+	c.Assert(s.mockPlugHookContext.Get("attrs-task", &attrsTaskID), IsNil)
+	s.mockPlugHookContext.Unlock()
+
+	st := s.mockPlugHookContext.State()
+	for _, test := range []struct {
+		hook, args, stdout, error string
+	}{
+		{hook: "disconnect-plug-aplug", args: "get :aplug aattr", stdout: "foo\n"},
+		{hook: "unprepare-plug-aplug", args: "get --slot :aplug battr", stdout: "bar\n"},
+		{hook: "disconnect-slot-bslot", args: "get :bslot battr", stdout: "bar\n"},
+		{hook: "unprepare-slot-bslot", args: "get :bslot dyn-slot-attr", stdout: "d\n"},
+		{hook: "disconnect-plug-aplug", args: "get :bslot battr", error: `unknown plug or slot "bslot"`},
+		{hook: "disconnect-plug-aplug", args: "set :aplug aattr=bar", error: "interface attributes can only be set during the execution of prepare hooks"},
+		{hook: "unprepare-slot-bslot", args: "set :bslot battr=foo", error: "interface attributes can only be set during the execution of prepare hooks"},
+	} {
+		c.Logf("Test: %s in %s", test.args, test.hook)
+
+		st.Lock()
+		task := st.NewTask("run-hook", "my test task")
+		st.Unlock()
+		setup := &hookstate.HookSetup{Snap: "test-snap", Revision: snap.R(1), Hook: test.hook}
+		context, err := hookstate.NewContext(task, st, setup, s.mockHandler, "")
+		c.Assert(err, IsNil)
+		context.Lock()
+		context.Set("attrs-task", attrsTaskID)
+		context.Unlock()
+
+		stdout, stderr, err := ctlcmd.Run(context, strings.Fields(test.args))
+		if test.error != "" {
+			c.Check(err, ErrorMatches, test.error)
+		} else {
+			c.Check(err, IsNil)
+			c.Check(string(stderr), Equals, "")
+			c.Check(string(stdout), Equals, test.stdout)
+		}
+	}
+}
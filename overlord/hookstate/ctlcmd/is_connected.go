@@ -0,0 +1,152 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/ifacestate/ifacerepo"
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+type isConnectedCommand struct {
+	baseCommand
+
+	Positional struct {
+		PlugOrSlotSpec string `positional-arg-name:"<plug|slot>"`
+	} `positional-args:"true"`
+}
+
+var shortIsConnectedHelp = i18n.G("Return success if the given plug or slot is connected")
+var longIsConnectedHelp = i18n.G(`
+The is-connected command returns success if the given plug or slot of the
+calling snap is connected, and failure otherwise.
+
+$ if snapctl is-connected plug; then
+    echo "plug is connected"
+  fi
+`)
+
+func init() {
+	addCommand("is-connected", shortIsConnectedHelp, longIsConnectedHelp, func() command { return &isConnectedCommand{} })
+}
+
+func (c *isConnectedCommand) Execute(args []string) error {
+	plugOrSlot := strings.TrimPrefix(c.Positional.PlugOrSlotSpec, ":")
+	if plugOrSlot == "" {
+		return fmt.Errorf(i18n.G("must specify a plug or slot name"))
+	}
+	if strings.Contains(plugOrSlot, ":") {
+		return fmt.Errorf(i18n.G("cannot check the connection of %q: only plugs and slots of the calling snap can be checked"), c.Positional.PlugOrSlotSpec)
+	}
+
+	context := c.context()
+	if context == nil {
+		return fmt.Errorf(i18n.G("cannot check connection status without a context"))
+	}
+
+	st := context.State()
+	st.Lock()
+	peers, err := connectedPeers(st, context.SnapName(), plugOrSlot)
+	st.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if len(peers) == 0 {
+		return &UnsuccessfulError{ExitCode: 1}
+	}
+	return nil
+}
+
+// connectedPeer describes the other end of an active connection of a
+// plug or slot.
+type connectedPeer struct {
+	Snap         string                 `json:"snap"`
+	Plug         string                 `json:"plug,omitempty"`
+	Slot         string                 `json:"slot,omitempty"`
+	Interface    string                 `json:"interface"`
+	StaticAttrs  map[string]interface{} `json:"static-attrs,omitempty"`
+	DynamicAttrs map[string]interface{} `json:"dynamic-attrs,omitempty"`
+}
+
+type byPeerName []connectedPeer
+
+func (p byPeerName) Len() int      { return len(p) }
+func (p byPeerName) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p byPeerName) Less(i, j int) bool {
+	if p[i].Snap != p[j].Snap {
+		return p[i].Snap < p[j].Snap
+	}
+	return p[i].Plug+p[i].Slot < p[j].Plug+p[j].Slot
+}
+
+// connectedPeers returns the peers of the active connections of the given
+// plug or slot of the snap, sorted by snap and plug or slot name.
+// The state must be locked by the caller.
+func connectedPeers(st *state.State, snapName, plugOrSlot string) ([]connectedPeer, error) {
+	repo := ifacerepo.Get(st)
+	if repo.Plug(snapName, plugOrSlot) == nil && repo.Slot(snapName, plugOrSlot) == nil {
+		return nil, fmt.Errorf(i18n.G("snap %q has no plug or slot named %q"), snapName, plugOrSlot)
+	}
+
+	conns, err := ifacestate.ConnectionStates(st)
+	if err != nil {
+		return nil, err
+	}
+
+	var peers []connectedPeer
+	for id, conn := range conns {
+		if !conn.Active() {
+			continue
+		}
+		connRef, err := interfaces.ParseConnRef(id)
+		if err != nil {
+			return nil, err
+		}
+		// a snap may connect its own plug to its own slot, so both
+		// sides are checked independently
+		if connRef.PlugRef.Snap == snapName && connRef.PlugRef.Name == plugOrSlot {
+			peers = append(peers, connectedPeer{
+				Snap:         connRef.SlotRef.Snap,
+				Slot:         connRef.SlotRef.Name,
+				Interface:    conn.Interface,
+				StaticAttrs:  conn.StaticSlotAttrs,
+				DynamicAttrs: conn.DynamicSlotAttrs,
+			})
+		}
+		if connRef.SlotRef.Snap == snapName && connRef.SlotRef.Name == plugOrSlot {
+			peers = append(peers, connectedPeer{
+				Snap:         connRef.PlugRef.Snap,
+				Plug:         connRef.PlugRef.Name,
+				Interface:    conn.Interface,
+				StaticAttrs:  conn.StaticPlugAttrs,
+				DynamicAttrs: conn.DynamicPlugAttrs,
+			})
+		}
+	}
+	sort.Sort(byPeerName(peers))
+	return peers, nil
+}
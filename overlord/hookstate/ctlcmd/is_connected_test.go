@@ -0,0 +1,215 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ctlcmd_test
+
+import (
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/hookstate/ctlcmd"
+	"github.com/snapcore/snapd/overlord/ifacestate/ifacerepo"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+)
+
+type isConnectedSuite struct {
+	st          *state.State
+	hookContext *hookstate.Context
+	appContext  *hookstate.Context
+}
+
+var _ = Suite(&isConnectedSuite{})
+
+const isConnectedConsumerYaml = `name: consumer
+version: 1
+plugs:
+  plug1:
+    interface: test
+  plug2:
+    interface: test
+  plug3:
+    interface: test
+  plug4:
+    interface: test
+`
+
+const isConnectedProducerYaml = `name: producer
+version: 1
+slots:
+  slot1:
+    interface: test
+    path: /foo
+  slot2:
+    interface: test
+`
+
+const isConnectedOtherConsumerYaml = `name: other-consumer
+version: 1
+plugs:
+  plug:
+    interface: test
+`
+
+func (s *isConnectedSuite) SetUpTest(c *C) {
+	s.st = state.New(nil)
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	repo := interfaces.NewRepository()
+	c.Assert(repo.AddInterface(&ifacetest.TestInterface{InterfaceName: "test"}), IsNil)
+	for _, yaml := range []string{isConnectedConsumerYaml, isConnectedProducerYaml, isConnectedOtherConsumerYaml} {
+		c.Assert(repo.AddSnap(snaptest.MockInfo(c, yaml, nil)), IsNil)
+	}
+	ifacerepo.Replace(s.st, repo)
+
+	s.st.Set("conns", map[string]interface{}{
+		"consumer:plug1 producer:slot1": map[string]interface{}{
+			"interface":    "test",
+			"slot-static":  map[string]interface{}{"path": "/foo"},
+			"slot-dynamic": map[string]interface{}{"bar": "baz"},
+		},
+		"other-consumer:plug producer:slot1": map[string]interface{}{
+			"interface":   "test",
+			"plug-static": map[string]interface{}{"attr": "value"},
+		},
+		"consumer:plug2 producer:slot2": map[string]interface{}{
+			"interface": "test",
+			"auto":      true,
+			"undesired": true,
+		},
+		"consumer:plug3 producer:slot2": map[string]interface{}{
+			"interface":    "test",
+			"hotplug-gone": true,
+		},
+	})
+
+	task := s.st.NewTask("test-task", "my test task")
+	var err error
+	s.hookContext, err = hookstate.NewContext(task, s.st, &hookstate.HookSetup{Snap: "consumer", Revision: snap.R(1), Hook: "configure"}, nil, "")
+	c.Assert(err, IsNil)
+	s.appContext, err = hookstate.NewContext(nil, s.st, &hookstate.HookSetup{Snap: "producer", Revision: snap.R(1)}, nil, "")
+	c.Assert(err, IsNil)
+}
+
+func (s *isConnectedSuite) TestIsConnected(c *C) {
+	for _, t := range []struct {
+		context *hookstate.Context
+		arg     string
+		exit    int
+	}{
+		{s.hookContext, "plug1", 0},
+		{s.hookContext, ":plug1", 0},
+		// disconnected by the user
+		{s.hookContext, "plug2", 1},
+		// hotplug device is gone
+		{s.hookContext, "plug3", 1},
+		// never connected
+		{s.hookContext, "plug4", 1},
+		{s.appContext, "slot1", 0},
+		{s.appContext, "slot2", 1},
+	} {
+		stdout, stderr, err := ctlcmd.Run(t.context, []string{"is-connected", t.arg})
+		comment := Commentf("%s", t.arg)
+		if t.exit == 0 {
+			c.Check(err, IsNil, comment)
+		} else {
+			c.Check(err, DeepEquals, &ctlcmd.UnsuccessfulError{ExitCode: t.exit}, comment)
+		}
+		c.Check(string(stdout), Equals, "", comment)
+		c.Check(string(stderr), Equals, "", comment)
+	}
+}
+
+func (s *isConnectedSuite) TestIsConnectedErrors(c *C) {
+	_, _, err := ctlcmd.Run(s.hookContext, []string{"is-connected"})
+	c.Check(err, ErrorMatches, "must specify a plug or slot name")
+	_, _, err = ctlcmd.Run(s.hookContext, []string{"is-connected", "producer:slot1"})
+	c.Check(err, ErrorMatches, `cannot check the connection of "producer:slot1": only plugs and slots of the calling snap can be checked`)
+	_, _, err = ctlcmd.Run(s.hookContext, []string{"is-connected", "slot1"})
+	c.Check(err, ErrorMatches, `snap "consumer" has no plug or slot named "slot1"`)
+	_, _, err = ctlcmd.Run(nil, []string{"is-connected", "plug1"})
+	c.Check(err, ErrorMatches, "cannot check connection status without a context")
+}
+
+func (s *isConnectedSuite) TestListConnectionsPlug(c *C) {
+	stdout, stderr, err := ctlcmd.Run(s.hookContext, []string{"get", "--list-connections", ":plug1"})
+	c.Assert(err, IsNil)
+	c.Check(string(stdout), Equals, `[
+	{
+		"snap": "producer",
+		"slot": "slot1",
+		"interface": "test",
+		"static-attrs": {
+			"path": "/foo"
+		},
+		"dynamic-attrs": {
+			"bar": "baz"
+		}
+	}
+]
+`)
+	c.Check(string(stderr), Equals, "")
+}
+
+func (s *isConnectedSuite) TestListConnectionsSlot(c *C) {
+	stdout, _, err := ctlcmd.Run(s.appContext, []string{"get", "--list-connections", ":slot1"})
+	c.Assert(err, IsNil)
+	c.Check(string(stdout), Equals, `[
+	{
+		"snap": "consumer",
+		"plug": "plug1",
+		"interface": "test"
+	},
+	{
+		"snap": "other-consumer",
+		"plug": "plug",
+		"interface": "test",
+		"static-attrs": {
+			"attr": "value"
+		}
+	}
+]
+`)
+
+	// inactive connections are not listed
+	stdout, _, err = ctlcmd.Run(s.appContext, []string{"get", "--list-connections", ":slot2"})
+	c.Assert(err, IsNil)
+	c.Check(string(stdout), Equals, "[]\n")
+}
+
+func (s *isConnectedSuite) TestListConnectionsErrors(c *C) {
+	for _, args := range [][]string{
+		{"get", "--list-connections", ":plug1", "key"},
+		{"get", "--list-connections", "--slot", ":plug1"},
+		{"get", "--list-connections", "-t", ":plug1"},
+	} {
+		_, _, err := ctlcmd.Run(s.hookContext, args)
+		c.Check(err, ErrorMatches, "cannot use --list-connections with other options or keys", Commentf("%v", args))
+	}
+	for _, arg := range []string{"plug1", "consumer:plug1", ":"} {
+		_, _, err := ctlcmd.Run(s.hookContext, []string{"get", "--list-connections", arg})
+		c.Check(err, ErrorMatches, "--list-connections requires a :<plug|slot> argument", Commentf("%v", arg))
+	}
+	_, _, err := ctlcmd.Run(s.hookContext, []string{"get", "--list-connections", ":slot1"})
+	c.Check(err, ErrorMatches, `snap "consumer" has no plug or slot named "slot1"`)
+}
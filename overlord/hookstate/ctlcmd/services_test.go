@@ -97,6 +97,9 @@ apps:
   command: bin/service
   daemon: simple
   reload-command: bin/reload
+ other-service:
+  command: bin/service
+  daemon: simple
 `
 
 const otherSnapYaml = `name: other-snap
@@ -269,6 +272,91 @@ func (s *servicectlSuite) TestRestartCommand(c *C) {
 	c.Assert(serviceChangeFuncCalled, Equals, true)
 }
 
+func (s *servicectlSuite) TestStartStopEnableDisableFromApp(c *C) {
+	var insts []*servicestate.Instruction
+	restore := mockServiceChangeFunc(func(appInfos []*snap.AppInfo, inst *servicestate.Instruction) {
+		c.Assert(appInfos, HasLen, 1)
+		c.Assert(appInfos[0].Name, Equals, "test-service")
+		insts = append(insts, inst)
+	})
+	defer restore()
+
+	// an app of the snap, reaching snapd through its cookie
+	appContext, err := hookstate.NewContext(nil, s.st, &hookstate.HookSetup{Snap: "test-snap", Revision: snap.R(1)}, nil, "")
+	c.Assert(err, IsNil)
+
+	_, _, err = ctlcmd.Run(appContext, []string{"stop", "--disable", "test-snap.test-service"})
+	c.Check(err, ErrorMatches, "forced error")
+	_, _, err = ctlcmd.Run(appContext, []string{"start", "--enable", "test-snap.test-service"})
+	c.Check(err, ErrorMatches, "forced error")
+	c.Check(insts, DeepEquals, []*servicestate.Instruction{{
+		Action:      "stop",
+		Names:       []string{"test-snap.test-service"},
+		StopOptions: client.StopOptions{Disable: true},
+	}, {
+		Action:       "start",
+		Names:        []string{"test-snap.test-service"},
+		StartOptions: client.StartOptions{Enable: true},
+	}})
+
+	// but not the services of other snaps
+	_, _, err = ctlcmd.Run(appContext, []string{"start", "other-snap.test-service"})
+	c.Check(err, ErrorMatches, `unknown service: "other-snap.test-service"`)
+}
+
+func (s *servicectlSuite) mockClientAppInfos(c *C) func() {
+	return ctlcmd.MockServicestateClientAppInfos(func(apps []*snap.AppInfo) []client.AppInfo {
+		out := make([]client.AppInfo, len(apps))
+		for i, app := range apps {
+			out[i] = client.AppInfo{
+				Snap:    app.Snap.Name(),
+				Name:    app.Name,
+				Daemon:  app.Daemon,
+				Enabled: app.Name == "test-service",
+				Active:  app.Name == "test-service",
+			}
+		}
+		return out
+	})
+}
+
+func (s *servicectlSuite) TestServicesCommand(c *C) {
+	defer s.mockClientAppInfos(c)()
+
+	stdout, stderr, err := ctlcmd.Run(s.mockContext, []string{"services"})
+	c.Assert(err, IsNil)
+	c.Check(string(stdout), Equals, `
+Service                  Startup   Current
+test-snap.other-service  disabled  inactive
+test-snap.test-service   enabled   active
+`[1:])
+	c.Check(string(stderr), Equals, "")
+
+	stdout, _, err = ctlcmd.Run(s.mockContext, []string{"services", "test-snap.other-service"})
+	c.Assert(err, IsNil)
+	c.Check(string(stdout), Equals, `
+Service                  Startup   Current
+test-snap.other-service  disabled  inactive
+`[1:])
+}
+
+func (s *servicectlSuite) TestServicesCommandFromApp(c *C) {
+	defer s.mockClientAppInfos(c)()
+
+	appContext, err := hookstate.NewContext(nil, s.st, &hookstate.HookSetup{Snap: "test-snap", Revision: snap.R(1)}, nil, "")
+	c.Assert(err, IsNil)
+
+	stdout, _, err := ctlcmd.Run(appContext, []string{"services", "test-snap.test-service"})
+	c.Assert(err, IsNil)
+	c.Check(string(stdout), Equals, `
+Service                 Startup  Current
+test-snap.test-service  enabled  active
+`[1:])
+
+	_, _, err = ctlcmd.Run(appContext, []string{"services", "other-snap"})
+	c.Check(err, ErrorMatches, `unknown service: "other-snap"`)
+}
+
 func (s *servicectlSuite) TestConflictingChange(c *C) {
 	s.st.Lock()
 	task := s.st.NewTask("link-snap", "conflicting task")
@@ -23,6 +23,7 @@ import (
 
 	"gopkg.in/tomb.v2"
 
+	"github.com/snapcore/snapd/overlord/ifacestate/udevmonitor"
 	"github.com/snapcore/snapd/overlord/state"
 )
 
@@ -55,3 +56,15 @@ func MockContentLinkRetryTimeout(d time.Duration) (restore func()) {
 	contentLinkRetryTimeout = d
 	return func() { contentLinkRetryTimeout = old }
 }
+
+func MockCreateUDevMonitor(new func(udevmonitor.DeviceAddedFunc, udevmonitor.DeviceRemovedFunc, udevmonitor.EnumerationDoneFunc) udevmonitor.Interface) (restore func()) {
+	old := createUDevMonitor
+	createUDevMonitor = new
+	return func() { createUDevMonitor = old }
+}
+
+func MockUDevMonitorRetryTimeout(d time.Duration) (restore func()) {
+	old := udevRetryTimeout
+	udevRetryTimeout = d
+	return func() { udevRetryTimeout = old }
+}
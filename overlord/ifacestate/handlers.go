@@ -151,6 +151,11 @@ func (m *InterfaceManager) setupProfilesForSnap(task *state.Task, _ *tomb.Tomb,
 	if err := m.repo.AddSnap(snapInfo); err != nil {
 		return err
 	}
+	if snapInfo.Type == snap.TypeOS {
+		if err := m.addHotplugSlots(snapInfo); err != nil {
+			return err
+		}
+	}
 	if len(snapInfo.BadInterfaces) > 0 {
 		task.Logf("%s", snap.BadInterfacesSummary(snapInfo))
 	}
@@ -503,8 +508,18 @@ func (m *InterfaceManager) doDisconnect(task *state.Task, _ *tomb.Tomb) error {
 		}
 	}
 
+	var autoDisconnect bool
+	if err := task.Get("auto-disconnect", &autoDisconnect); err != nil && err != state.ErrNoState {
+		return err
+	}
+
 	cref := interfaces.ConnRef{PlugRef: plugRef, SlotRef: slotRef}
-	if conn, ok := conns[cref.ID()]; ok && conn.Auto {
+	conn, ok := conns[cref.ID()]
+	if ok {
+		// store old connection info for undo
+		task.Set("old-conn", conn)
+	}
+	if ok && conn.Auto && !autoDisconnect {
 		conn.Undesired = true
 		conn.DynamicPlugAttrs = nil
 		conn.DynamicSlotAttrs = nil
@@ -523,6 +538,81 @@ func (m *InterfaceManager) undoConnect(task *state.Task, _ *tomb.Tomb) error {
 	st.Lock()
 	defer st.Unlock()
 
+	plugRef, slotRef, err := getPlugAndSlotRefs(task)
+	if err != nil {
+		return err
+	}
+	connRef := interfaces.ConnRef{PlugRef: plugRef, SlotRef: slotRef}
+
+	var oldconn connState
+	err = task.Get("old-conn", &oldconn)
+	if err == state.ErrNoState {
+		return m.undoConnectNew(task, &connRef)
+	}
+	if err != nil {
+		return err
+	}
+
+	conns, err := getConns(st)
+	if err != nil {
+		return err
+	}
+	conns[connRef.ID()] = oldconn
+	setConns(st, conns)
+	return nil
+}
+
+// undoConnectNew removes a connection that did not exist before the
+// connect task ran.
+func (m *InterfaceManager) undoConnectNew(task *state.Task, connRef *interfaces.ConnRef) error {
+	st := task.State()
+	conns, err := getConns(st)
+	if err != nil {
+		return err
+	}
+	if _, ok := conns[connRef.ID()]; !ok {
+		// doConnect did not get as far as recording the connection
+		return nil
+	}
+
+	if err := m.repo.Disconnect(connRef.PlugRef.Snap, connRef.PlugRef.Name, connRef.SlotRef.Snap, connRef.SlotRef.Name); err != nil {
+		return err
+	}
+	delete(conns, connRef.ID())
+	setConns(st, conns)
+
+	return m.setupConnectedSnapsSecurity(task, connRef)
+}
+
+// setupConnectedSnapsSecurity sets up the security profiles of both snaps
+// of the given connection, skipping the snaps that are gone.
+func (m *InterfaceManager) setupConnectedSnapsSecurity(task *state.Task, connRef *interfaces.ConnRef) error {
+	st := task.State()
+	for _, snapName := range []string{connRef.PlugRef.Snap, connRef.SlotRef.Snap} {
+		var snapst snapstate.SnapState
+		if err := snapstate.Get(st, snapName, &snapst); err != nil {
+			if err == state.ErrNoState {
+				continue
+			}
+			return err
+		}
+		snapInfo, err := snapst.CurrentInfo()
+		if err != nil {
+			return err
+		}
+		opts := confinementOptions(snapst.Flags)
+		if err := m.setupSnapSecurity(task, snapInfo, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *InterfaceManager) undoDisconnect(task *state.Task, _ *tomb.Tomb) error {
+	st := task.State()
+	st.Lock()
+	defer st.Unlock()
+
 	var oldconn connState
 	err := task.Get("old-conn", &oldconn)
 	if err == state.ErrNoState {
@@ -536,7 +626,23 @@ func (m *InterfaceManager) undoConnect(task *state.Task, _ *tomb.Tomb) error {
 	if err != nil {
 		return err
 	}
-	connRef := interfaces.ConnRef{PlugRef: plugRef, SlotRef: slotRef}
+	connRef := &interfaces.ConnRef{PlugRef: plugRef, SlotRef: slotRef}
+
+	plug := m.repo.Plug(plugRef.Snap, plugRef.Name)
+	slot := m.repo.Slot(slotRef.Snap, slotRef.Name)
+	if plug == nil || slot == nil {
+		// the snaps are gone, only restore the connection state
+		task.Logf("cannot restore connection %s: plug or slot no longer available", connRef.ID())
+	} else {
+		// the connection was allowed when it was made, do not check the policy again
+		if _, err := m.repo.Connect(connRef, oldconn.DynamicPlugAttrs, oldconn.DynamicSlotAttrs, nil); err != nil {
+			return err
+		}
+		if err := m.setupConnectedSnapsSecurity(task, connRef); err != nil {
+			return err
+		}
+	}
+
 	conns, err := getConns(st)
 	if err != nil {
 		return err
@@ -744,6 +850,51 @@ func (m *InterfaceManager) doAutoConnect(task *state.Task, _ *tomb.Tomb) error {
 	return nil
 }
 
+// doAutoDisconnect creates tasks for disconnecting all the interfaces of
+// a snap that is being removed, running the disconnect and unprepare hooks
+// of both sides of each connection.
+func (m *InterfaceManager) doAutoDisconnect(task *state.Task, _ *tomb.Tomb) error {
+	st := task.State()
+	st.Lock()
+	defer st.Unlock()
+
+	snapsup, err := snapstate.TaskSnapSetup(task)
+	if err != nil {
+		return err
+	}
+
+	snapName := snapsup.Name()
+	connRefs, err := m.repo.Connections(snapName)
+	if err != nil {
+		return err
+	}
+
+	// The disconnect tasks of the connections are serialized so that the
+	// hooks of a snap never run concurrently.
+	autots := state.NewTaskSet()
+	var prev *state.TaskSet
+	for _, connRef := range connRefs {
+		ts, err := disconnectTasks(st, connRef, disconnectOpts{AutoDisconnect: true})
+		if err != nil {
+			return err
+		}
+		if prev != nil {
+			ts.WaitAll(prev)
+		}
+		autots.AddAll(ts)
+		prev = ts
+	}
+
+	if len(autots.Tasks()) > 0 {
+		snapstate.InjectTasks(task, autots)
+
+		st.EnsureBefore(0)
+	}
+
+	task.SetStatus(state.DoneStatus)
+	return nil
+}
+
 func (m *InterfaceManager) undoAutoConnect(task *state.Task, _ *tomb.Tomb) error {
 	// TODO Introduce disconnection hooks, and run them here as well to give a chance
 	// for the snap to undo whatever it did when the connection was established.
@@ -821,3 +972,276 @@ func (m *InterfaceManager) undoTransitionUbuntuCore(t *state.Task, _ *tomb.Tomb)
 
 	return m.transitionConnectionsCoreMigration(st, newName, oldName)
 }
+
+func getHotplugAttrs(task *state.Task) (ifaceName, hotplugKey string, err error) {
+	if err = task.Get("interface", &ifaceName); err != nil {
+		return "", "", fmt.Errorf("internal error: cannot get interface name from hotplug task: %s", err)
+	}
+	if err = task.Get("hotplug-key", &hotplugKey); err != nil {
+		return "", "", fmt.Errorf("internal error: cannot get hotplug key from hotplug task: %s", err)
+	}
+	return ifaceName, hotplugKey, err
+}
+
+// doHotplugAddSlot adds the slot of a hotplugged device to the repository
+// and records it in the state. A device that was seen before gets its
+// old slot name back.
+func (m *InterfaceManager) doHotplugAddSlot(task *state.Task, _ *tomb.Tomb) error {
+	st := task.State()
+	st.Lock()
+	defer st.Unlock()
+
+	ifaceName, hotplugKey, err := getHotplugAttrs(task)
+	if err != nil {
+		return err
+	}
+	var requested hotplugSlotDef
+	if err := task.Get("slot", &requested); err != nil {
+		return fmt.Errorf("internal error: cannot get slot definition from hotplug task: %s", err)
+	}
+	iface := m.repo.Interface(ifaceName)
+	if iface == nil {
+		return fmt.Errorf("internal error: cannot find interface %q", ifaceName)
+	}
+
+	systemSnap, err := snapstate.CoreInfo(st)
+	if err != nil {
+		return fmt.Errorf("cannot find the system snap: %s", err)
+	}
+	slots, err := getHotplugSlots(st)
+	if err != nil {
+		return err
+	}
+
+	def := findHotplugSlot(slots, ifaceName, hotplugKey)
+	if def != nil {
+		if m.repo.Slot(systemSnap.Name(), def.Name) != nil {
+			// the device was reported more than once, nothing to do
+			return nil
+		}
+		def.Label = requested.Label
+		def.StaticAttrs = requested.StaticAttrs
+		def.HotplugGone = false
+	} else {
+		def = &requested
+		def.Name = m.hotplugSlotName(slots, systemSnap.Name(), requested.Name, ifaceName)
+	}
+
+	slot := def.slotInfo(systemSnap)
+	if err := interfaces.BeforePrepareSlot(iface, slot); err != nil {
+		return fmt.Errorf("cannot create slot for hotplug device: %s", err)
+	}
+	if err := m.repo.AddSlot(slot); err != nil {
+		return fmt.Errorf("cannot create slot for hotplug device: %s", err)
+	}
+	def.StaticAttrs = slot.Attrs
+	slots[def.Name] = def
+	setHotplugSlots(st, slots)
+	task.Logf("Created slot %q for interface %q", def.Name, ifaceName)
+	return nil
+}
+
+// doHotplugConnect restores the connections of a hotplug slot that were
+// active when its device went away and auto-connects the slot according
+// to the policy.
+func (m *InterfaceManager) doHotplugConnect(task *state.Task, _ *tomb.Tomb) error {
+	st := task.State()
+	st.Lock()
+	defer st.Unlock()
+
+	ifaceName, hotplugKey, err := getHotplugAttrs(task)
+	if err != nil {
+		return err
+	}
+	systemSnap, err := snapstate.CoreInfo(st)
+	if err != nil {
+		return fmt.Errorf("cannot find the system snap: %s", err)
+	}
+	systemSnapName := systemSnap.Name()
+	slots, err := getHotplugSlots(st)
+	if err != nil {
+		return err
+	}
+	def := findHotplugSlot(slots, ifaceName, hotplugKey)
+	if def == nil || def.HotplugGone {
+		return fmt.Errorf("internal error: cannot find %s slot of hotplug device with key %q", ifaceName, hotplugKey)
+	}
+	slot := m.repo.Slot(systemSnapName, def.Name)
+	if slot == nil {
+		return fmt.Errorf("internal error: cannot find slot %q of snap %q", def.Name, systemSnapName)
+	}
+
+	conns, err := getConns(st)
+	if err != nil {
+		return err
+	}
+
+	// restore the connections the slot had when the device was removed
+	affected := make(map[string]bool)
+	for id, conn := range conns {
+		if !conn.HotplugGone || conn.Interface != ifaceName {
+			continue
+		}
+		connRef, err := interfaces.ParseConnRef(id)
+		if err != nil {
+			return err
+		}
+		if connRef.SlotRef.Snap != systemSnapName || connRef.SlotRef.Name != def.Name {
+			continue
+		}
+		if _, err := m.repo.Connect(connRef, conn.DynamicPlugAttrs, conn.DynamicSlotAttrs, nil); err != nil {
+			task.Logf("cannot restore connection %s: %s", id, err)
+			continue
+		}
+		conn.HotplugGone = false
+		conns[id] = conn
+		affected[connRef.PlugRef.Snap] = true
+	}
+	if len(affected) > 0 {
+		setConns(st, conns)
+		affectedSnaps := make([]string, 0, len(affected)+1)
+		affectedSnaps = append(affectedSnaps, systemSnapName)
+		for name := range affected {
+			affectedSnaps = append(affectedSnaps, name)
+		}
+		sort.Strings(affectedSnaps[1:])
+		if err := m.setupAffectedSnaps(task, "", affectedSnaps); err != nil {
+			return err
+		}
+	}
+
+	// auto-connect the slot
+	autochecker, err := newAutoConnectChecker(st)
+	if err != nil {
+		return err
+	}
+	autots := state.NewTaskSet()
+	for _, plug := range m.repo.AutoConnectCandidatePlugs(systemSnapName, def.Name, autochecker.check) {
+		// make sure slot is the only viable connection for plug,
+		// same check as in doAutoConnect
+		candSlots := m.repo.AutoConnectCandidateSlots(plug.Snap.Name(), plug.Name, autochecker.check)
+		if len(candSlots) != 1 || candSlots[0].String() != slot.String() {
+			crefs := make([]string, len(candSlots))
+			for i, candidate := range candSlots {
+				crefs[i] = candidate.String()
+			}
+			task.Logf("cannot auto-connect slot %s to %s, candidates found: %s", slot, plug, strings.Join(crefs, ", "))
+			continue
+		}
+		connRef := interfaces.NewConnRef(plug, slot)
+		if _, ok := conns[connRef.ID()]; ok {
+			// the connection exists already or is undesired
+			continue
+		}
+		if err := checkConnectConflicts(st, task.Change(), plug.Snap.Name(), systemSnapName, nil); err != nil {
+			task.Logf("auto-connect of hotplug slot %q will be retried because of %q - %q conflict", def.Name, plug.Snap.Name(), systemSnapName)
+			return &state.Retry{After: connectRetryTimeout}
+		}
+		ts, err := connect(st, task, plug.Snap.Name(), plug.Name, systemSnapName, def.Name)
+		if err != nil {
+			return fmt.Errorf("internal error: auto-connect of %q failed: %s", connRef, err)
+		}
+		autots.AddAll(ts)
+	}
+	if len(autots.Tasks()) > 0 {
+		snapstate.InjectTasks(task, autots)
+		st.EnsureBefore(0)
+	}
+
+	task.SetStatus(state.DoneStatus)
+	return nil
+}
+
+// doHotplugDisconnect disconnects the slot of a hotplug device that went
+// away. The connections are kept in the state, marked as gone, so that
+// they can be restored if the device is plugged again.
+func (m *InterfaceManager) doHotplugDisconnect(task *state.Task, _ *tomb.Tomb) error {
+	st := task.State()
+	st.Lock()
+	defer st.Unlock()
+
+	ifaceName, hotplugKey, err := getHotplugAttrs(task)
+	if err != nil {
+		return err
+	}
+	systemSnap, err := snapstate.CoreInfo(st)
+	if err != nil {
+		return fmt.Errorf("cannot find the system snap: %s", err)
+	}
+	systemSnapName := systemSnap.Name()
+	slots, err := getHotplugSlots(st)
+	if err != nil {
+		return err
+	}
+	def := findHotplugSlot(slots, ifaceName, hotplugKey)
+	if def == nil || m.repo.Slot(systemSnapName, def.Name) == nil {
+		// nothing to disconnect
+		return nil
+	}
+
+	connRefs, err := m.repo.Connected(systemSnapName, def.Name)
+	if err != nil {
+		return err
+	}
+	if len(connRefs) == 0 {
+		return nil
+	}
+	conns, err := getConns(st)
+	if err != nil {
+		return err
+	}
+
+	affected := make(map[string]bool)
+	for _, connRef := range connRefs {
+		if err := m.repo.Disconnect(connRef.PlugRef.Snap, connRef.PlugRef.Name, connRef.SlotRef.Snap, connRef.SlotRef.Name); err != nil {
+			return err
+		}
+		if conn, ok := conns[connRef.ID()]; ok {
+			conn.HotplugGone = true
+			conns[connRef.ID()] = conn
+		}
+		affected[connRef.PlugRef.Snap] = true
+	}
+	setConns(st, conns)
+
+	affectedSnaps := make([]string, 0, len(affected)+1)
+	affectedSnaps = append(affectedSnaps, systemSnapName)
+	for name := range affected {
+		affectedSnaps = append(affectedSnaps, name)
+	}
+	sort.Strings(affectedSnaps[1:])
+	return m.setupAffectedSnaps(task, "", affectedSnaps)
+}
+
+// doHotplugRemoveSlot removes the (already disconnected) slot of a hotplug
+// device that went away from the repository.
+func (m *InterfaceManager) doHotplugRemoveSlot(task *state.Task, _ *tomb.Tomb) error {
+	st := task.State()
+	st.Lock()
+	defer st.Unlock()
+
+	ifaceName, hotplugKey, err := getHotplugAttrs(task)
+	if err != nil {
+		return err
+	}
+	systemSnap, err := snapstate.CoreInfo(st)
+	if err != nil {
+		return fmt.Errorf("cannot find the system snap: %s", err)
+	}
+	slots, err := getHotplugSlots(st)
+	if err != nil {
+		return err
+	}
+	def := findHotplugSlot(slots, ifaceName, hotplugKey)
+	if def == nil {
+		return nil
+	}
+	if m.repo.Slot(systemSnap.Name(), def.Name) != nil {
+		if err := m.repo.RemoveSlot(systemSnap.Name(), def.Name); err != nil {
+			return fmt.Errorf("cannot remove hotplug slot: %s", err)
+		}
+	}
+	def.HotplugGone = true
+	setHotplugSlots(st, slots)
+	return nil
+}
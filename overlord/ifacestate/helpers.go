@@ -48,6 +48,11 @@ func (m *InterfaceManager) initialize(extraInterfaces []interfaces.Interface, ex
 	if err := m.addSnaps(); err != nil {
 		return err
 	}
+	if systemSnap, err := snapstate.CoreInfo(m.state); err == nil {
+		if err := m.addHotplugSlots(systemSnap); err != nil {
+			return err
+		}
+	}
 	if err := m.renameCorePlugConnection(); err != nil {
 		return err
 	}
@@ -250,7 +255,9 @@ func (m *InterfaceManager) reloadConnections(snapName string) ([]string, error)
 	}
 	affected := make(map[string]bool)
 	for id, conn := range conns {
-		if conn.Undesired {
+		// connections of hotplug devices that went away are restored
+		// when the device comes back
+		if conn.Undesired || conn.HotplugGone {
 			continue
 		}
 		connRef, err := interfaces.ParseConnRef(id)
@@ -318,7 +325,10 @@ type connState struct {
 	Interface string `json:"interface,omitempty"`
 	// Undesired tracks connections that were manually disconnected after being auto-connected,
 	// so that they are not automatically reconnected again in the future.
-	Undesired        bool                   `json:"undesired,omitempty"`
+	Undesired bool `json:"undesired,omitempty"`
+	// HotplugGone tracks connections of hotplug slots whose device was
+	// removed, so that they are restored when the device comes back.
+	HotplugGone      bool                   `json:"hotplug-gone,omitempty"`
 	StaticPlugAttrs  map[string]interface{} `json:"plug-static,omitempty"`
 	DynamicPlugAttrs map[string]interface{} `json:"plug-dynamic,omitempty"`
 	StaticSlotAttrs  map[string]interface{} `json:"slot-static,omitempty"`
@@ -33,6 +33,14 @@ type connectHandler struct {
 	context *hookstate.Context
 }
 
+type disconnectHandler struct {
+	context *hookstate.Context
+}
+
+type unprepareHandler struct {
+	context *hookstate.Context
+}
+
 func (h *prepareHandler) Before() error {
 	return nil
 }
@@ -57,6 +65,30 @@ func (h *connectHandler) Error(err error) error {
 	return nil
 }
 
+func (h *disconnectHandler) Before() error {
+	return nil
+}
+
+func (h *disconnectHandler) Done() error {
+	return nil
+}
+
+func (h *disconnectHandler) Error(err error) error {
+	return nil
+}
+
+func (h *unprepareHandler) Before() error {
+	return nil
+}
+
+func (h *unprepareHandler) Done() error {
+	return nil
+}
+
+func (h *unprepareHandler) Error(err error) error {
+	return nil
+}
+
 // setupHooks sets hooks of InterfaceManager up
 func setupHooks(hookMgr *hookstate.HookManager) {
 	prepareGenerator := func(context *hookstate.Context) hookstate.Handler {
@@ -67,8 +99,20 @@ func setupHooks(hookMgr *hookstate.HookManager) {
 		return &connectHandler{context: context}
 	}
 
+	disconnectGenerator := func(context *hookstate.Context) hookstate.Handler {
+		return &disconnectHandler{context: context}
+	}
+
+	unprepareGenerator := func(context *hookstate.Context) hookstate.Handler {
+		return &unprepareHandler{context: context}
+	}
+
 	hookMgr.Register(regexp.MustCompile("^prepare-plug-[-a-z0-9]+$"), prepareGenerator)
 	hookMgr.Register(regexp.MustCompile("^prepare-slot-[-a-z0-9]+$"), prepareGenerator)
 	hookMgr.Register(regexp.MustCompile("^connect-plug-[-a-z0-9]+$"), connectGenerator)
 	hookMgr.Register(regexp.MustCompile("^connect-slot-[-a-z0-9]+$"), connectGenerator)
+	hookMgr.Register(regexp.MustCompile("^disconnect-plug-[-a-z0-9]+$"), disconnectGenerator)
+	hookMgr.Register(regexp.MustCompile("^disconnect-slot-[-a-z0-9]+$"), disconnectGenerator)
+	hookMgr.Register(regexp.MustCompile("^unprepare-plug-[-a-z0-9]+$"), unprepareGenerator)
+	hookMgr.Register(regexp.MustCompile("^unprepare-slot-[-a-z0-9]+$"), unprepareGenerator)
 }
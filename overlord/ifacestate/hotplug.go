@@ -0,0 +1,345 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/hotplug"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/ifacestate/udevmonitor"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+// hotplugSlotDef is the definition of a slot created in response to a
+// hotplug event, as stored in the state under "hotplug-slots".
+type hotplugSlotDef struct {
+	Name        string                 `json:"name"`
+	Interface   string                 `json:"interface"`
+	Label       string                 `json:"label,omitempty"`
+	StaticAttrs map[string]interface{} `json:"static-attrs,omitempty"`
+	HotplugKey  string                 `json:"hotplug-key"`
+	// HotplugGone is set when the device of the slot was removed, the
+	// definition is kept so that the slot gets the same name if the
+	// device is plugged again.
+	HotplugGone bool `json:"hotplug-gone,omitempty"`
+}
+
+func getHotplugSlots(st *state.State) (map[string]*hotplugSlotDef, error) {
+	var slots map[string]*hotplugSlotDef
+	err := st.Get("hotplug-slots", &slots)
+	if err != nil && err != state.ErrNoState {
+		return nil, err
+	}
+	if slots == nil {
+		slots = make(map[string]*hotplugSlotDef)
+	}
+	return slots, nil
+}
+
+func setHotplugSlots(st *state.State, slots map[string]*hotplugSlotDef) {
+	st.Set("hotplug-slots", slots)
+}
+
+// findHotplugSlot returns the definition of the slot created by the given
+// interface for the device with the given hotplug key, or nil.
+func findHotplugSlot(slots map[string]*hotplugSlotDef, ifaceName, hotplugKey string) *hotplugSlotDef {
+	for _, def := range slots {
+		if def.Interface == ifaceName && def.HotplugKey == hotplugKey {
+			return def
+		}
+	}
+	return nil
+}
+
+func (def *hotplugSlotDef) slotInfo(systemSnap *snap.Info) *snap.SlotInfo {
+	return &snap.SlotInfo{
+		Snap:       systemSnap,
+		Name:       def.Name,
+		Interface:  def.Interface,
+		Label:      def.Label,
+		Attrs:      def.StaticAttrs,
+		HotplugKey: def.HotplugKey,
+	}
+}
+
+// addHotplugSlots adds the slots of the hotplugged devices that are known
+// to be present to the repository. It is used whenever the system snap is
+// (re)added to the repository.
+func (m *InterfaceManager) addHotplugSlots(systemSnap *snap.Info) error {
+	slots, err := getHotplugSlots(m.state)
+	if err != nil {
+		return err
+	}
+	for _, def := range slots {
+		if def.HotplugGone {
+			continue
+		}
+		if err := m.repo.AddSlot(def.slotInfo(systemSnap)); err != nil {
+			logger.Noticef("cannot add hotplug slot %q: %s", def.Name, err)
+		}
+	}
+	return nil
+}
+
+// hotplugSlotName returns a name for a new hotplug slot that is not used by
+// any slot or plug of the system snap nor by any other hotplug slot.
+func (m *InterfaceManager) hotplugSlotName(slots map[string]*hotplugSlotDef, systemSnap string, requested, ifaceName string) string {
+	name := requested
+	if name == "" || interfaces.ValidateName(name) != nil {
+		name = ifaceName
+	}
+	taken := func(candidate string) bool {
+		if _, ok := slots[candidate]; ok {
+			return true
+		}
+		return m.repo.Slot(systemSnap, candidate) != nil || m.repo.Plug(systemSnap, candidate) != nil
+	}
+	candidate := name
+	for i := 1; taken(candidate); i++ {
+		candidate = fmt.Sprintf("%s-%d", name, i)
+	}
+	return candidate
+}
+
+func hotplugEnabled(st *state.State) (bool, error) {
+	tr := config.NewTransaction(st)
+	var enabled bool
+	if err := tr.GetMaybe("core", "experimental.hotplug", &enabled); err != nil {
+		return false, err
+	}
+	return enabled, nil
+}
+
+// udevRetryTimeout is how long to wait before trying to start the udev
+// monitor again after it failed.
+var udevRetryTimeout = time.Minute
+
+var createUDevMonitor = udevmonitor.New
+
+// ensureUDevMonitor starts the udev monitor if hotplug support is enabled
+// and the monitor is not running yet.
+func (m *InterfaceManager) ensureUDevMonitor() {
+	if m.udevMon != nil || time.Now().Before(m.udevRetry) {
+		return
+	}
+
+	m.state.Lock()
+	enabled, err := hotplugEnabled(m.state)
+	if err == nil && enabled {
+		m.enumeratedDeviceKeys = make(map[string]bool)
+	}
+	m.state.Unlock()
+	if err != nil {
+		logger.Noticef("cannot check if hotplug is enabled: %s", err)
+		return
+	}
+	if !enabled {
+		return
+	}
+
+	mon := createUDevMonitor(m.hotplugDeviceAdded, m.hotplugDeviceRemoved, m.hotplugEnumerationDone)
+	if err := mon.Connect(); err != nil {
+		m.udevRetry = time.Now().Add(udevRetryTimeout)
+		logger.Noticef("cannot start udev monitor: %s", err)
+		return
+	}
+	if err := mon.Run(); err != nil {
+		m.udevRetry = time.Now().Add(udevRetryTimeout)
+		logger.Noticef("cannot run udev monitor: %s", err)
+		return
+	}
+	m.udevMon = mon
+}
+
+func (m *InterfaceManager) stopUDevMonitor() {
+	if m.udevMon == nil {
+		return
+	}
+	if err := m.udevMon.Stop(); err != nil {
+		logger.Noticef("cannot stop udev monitor: %s", err)
+	}
+	m.udevMon = nil
+}
+
+// hotplugDeviceAdded is called by the udev monitor for every device that
+// is present when the monitor starts and for every device added later.
+// It asks the interfaces implementing hotplug.Definer whether they want a
+// slot for the device and creates the changes adding those slots.
+func (m *InterfaceManager) hotplugDeviceAdded(devinfo *hotplug.HotplugDeviceInfo) {
+	st := m.state
+	st.Lock()
+	defer st.Unlock()
+
+	key := devinfo.Key()
+	if m.enumeratedDeviceKeys != nil {
+		m.enumeratedDeviceKeys[key] = true
+	}
+
+	systemSnap, err := snapstate.CoreInfo(st)
+	if err != nil {
+		logger.Noticef("cannot handle hotplug device %s: %s", devinfo, err)
+		return
+	}
+	slots, err := getHotplugSlots(st)
+	if err != nil {
+		logger.Noticef("cannot handle hotplug device %s: %s", devinfo, err)
+		return
+	}
+
+	var created bool
+	for _, iface := range m.repo.AllInterfaces() {
+		definer, ok := iface.(hotplug.Definer)
+		if !ok {
+			continue
+		}
+		spec := hotplug.NewSpecification()
+		if err := definer.HotplugDeviceDetected(devinfo, spec); err != nil {
+			logger.Noticef("interface %q cannot handle hotplug device %s: %s", iface.Name(), devinfo, err)
+			continue
+		}
+		slotSpec := spec.Slot()
+		if slotSpec == nil {
+			continue
+		}
+		if def := findHotplugSlot(slots, iface.Name(), key); def != nil && !def.HotplugGone && m.repo.Slot(systemSnap.Name(), def.Name) != nil {
+			// the slot is there already
+			continue
+		}
+
+		def := &hotplugSlotDef{
+			Name:        slotSpec.Name,
+			Interface:   iface.Name(),
+			Label:       slotSpec.Label,
+			StaticAttrs: slotSpec.Attrs,
+			HotplugKey:  key,
+		}
+		chg := st.NewChange("hotplug-add", fmt.Sprintf(i18n.G("Add %s slot for device %s"), iface.Name(), devinfo))
+		addSlot := st.NewTask("hotplug-add-slot", fmt.Sprintf(i18n.G("Create %s slot for device %s"), iface.Name(), devinfo))
+		addSlot.Set("interface", iface.Name())
+		addSlot.Set("hotplug-key", key)
+		addSlot.Set("slot", def)
+		chg.AddTask(addSlot)
+		connect := st.NewTask("hotplug-connect", fmt.Sprintf(i18n.G("Connect %s slot for device %s"), iface.Name(), devinfo))
+		connect.Set("interface", iface.Name())
+		connect.Set("hotplug-key", key)
+		connect.WaitFor(addSlot)
+		chg.AddTask(connect)
+		created = true
+	}
+	if created {
+		st.EnsureBefore(0)
+	}
+}
+
+// hotplugDeviceRemoved is called by the udev monitor for every device
+// removed from the system; it creates the change disconnecting and
+// removing the slots of the device.
+func (m *InterfaceManager) hotplugDeviceRemoved(devinfo *hotplug.HotplugDeviceInfo) {
+	st := m.state
+	st.Lock()
+	defer st.Unlock()
+
+	if err := m.removeHotplugDevice(devinfo.Key(), devinfo.String()); err != nil {
+		logger.Noticef("cannot handle removal of hotplug device %s: %s", devinfo, err)
+	}
+}
+
+// hotplugEnumerationDone is called by the udev monitor once all the devices
+// present at startup were reported. The slots of the devices that went
+// away while snapd was not running are removed.
+func (m *InterfaceManager) hotplugEnumerationDone() {
+	st := m.state
+	st.Lock()
+	defer st.Unlock()
+
+	seen := m.enumeratedDeviceKeys
+	m.enumeratedDeviceKeys = nil
+
+	slots, err := getHotplugSlots(st)
+	if err != nil {
+		logger.Noticef("cannot remove slots of hotplug devices gone: %s", err)
+		return
+	}
+	gone := make(map[string]bool)
+	for _, def := range slots {
+		if !def.HotplugGone && !seen[def.HotplugKey] {
+			gone[def.HotplugKey] = true
+		}
+	}
+	keys := make([]string, 0, len(gone))
+	for key := range gone {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := m.removeHotplugDevice(key, key); err != nil {
+			logger.Noticef("cannot remove slots of hotplug device %s: %s", key, err)
+		}
+	}
+}
+
+// removeHotplugDevice creates a change disconnecting and removing all the
+// slots created for the device with the given hotplug key.
+func (m *InterfaceManager) removeHotplugDevice(key, what string) error {
+	st := m.state
+	slots, err := getHotplugSlots(st)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for name, def := range slots {
+		if def.HotplugKey == key && !def.HotplugGone {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+
+	chg := st.NewChange("hotplug-remove", fmt.Sprintf(i18n.G("Remove slots of device %s"), what))
+	var prev *state.Task
+	for _, name := range names {
+		def := slots[name]
+		disconnect := st.NewTask("hotplug-disconnect", fmt.Sprintf(i18n.G("Disconnect slot %s of device %s"), def.Name, what))
+		disconnect.Set("interface", def.Interface)
+		disconnect.Set("hotplug-key", key)
+		if prev != nil {
+			disconnect.WaitFor(prev)
+		}
+		chg.AddTask(disconnect)
+		removeSlot := st.NewTask("hotplug-remove-slot", fmt.Sprintf(i18n.G("Remove slot %s of device %s"), def.Name, what))
+		removeSlot.Set("interface", def.Interface)
+		removeSlot.Set("hotplug-key", key)
+		removeSlot.WaitFor(disconnect)
+		chg.AddTask(removeSlot)
+		prev = removeSlot
+	}
+	st.EnsureBefore(0)
+	return nil
+}
@@ -0,0 +1,349 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package ifacestate_test
+
+import (
+	"fmt"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/interfaces"
+	"github.com/snapcore/snapd/interfaces/hotplug"
+	"github.com/snapcore/snapd/interfaces/ifacetest"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/ifacestate"
+	"github.com/snapcore/snapd/overlord/ifacestate/udevmonitor"
+	"github.com/snapcore/snapd/overlord/snapstate"
+)
+
+type hotplugTestInterface struct {
+	ifacetest.TestInterface
+}
+
+func (iface *hotplugTestInterface) HotplugDeviceDetected(di *hotplug.HotplugDeviceInfo, spec *hotplug.Specification) error {
+	if di.Subsystem() != "test" {
+		return nil
+	}
+	return spec.SetSlot(&hotplug.RequestedSlotSpec{
+		Name:  "hotplugslot",
+		Attrs: map[string]interface{}{"path": di.DeviceName()},
+	})
+}
+
+type udevMonitorMock struct {
+	ConnectError, RunError            error
+	ConnectCalls, RunCalls, StopCalls int
+	AddDevice                         udevmonitor.DeviceAddedFunc
+	RemoveDevice                      udevmonitor.DeviceRemovedFunc
+	EnumerationDone                   udevmonitor.EnumerationDoneFunc
+}
+
+func (u *udevMonitorMock) Connect() error {
+	u.ConnectCalls++
+	return u.ConnectError
+}
+
+func (u *udevMonitorMock) Run() error {
+	u.RunCalls++
+	return u.RunError
+}
+
+func (u *udevMonitorMock) Stop() error {
+	u.StopCalls++
+	return nil
+}
+
+var hotplugConsumerYaml = `
+name: consumer
+version: 1
+plugs:
+ plug:
+  interface: test
+`
+
+func (s *interfaceManagerSuite) mockHotplug(c *C, enabled bool) *udevMonitorMock {
+	restore := assertstest.MockBuiltinBaseDeclaration([]byte(`
+type: base-declaration
+authority-id: canonical
+series: 16
+slots:
+  test:
+    allow-auto-connection: true
+`))
+	s.AddCleanup(restore)
+
+	s.mockIfaces(c, &hotplugTestInterface{TestInterface: ifacetest.TestInterface{InterfaceName: "test"}})
+	s.mockSnap(c, coreSnapYaml)
+	s.mockSnap(c, hotplugConsumerYaml)
+
+	s.state.Lock()
+	// the system snap is found by its type
+	for name, typ := range map[string]string{"core": "os", "consumer": "app"} {
+		var snapst snapstate.SnapState
+		c.Assert(snapstate.Get(s.state, name, &snapst), IsNil)
+		snapst.SnapType = typ
+		snapstate.Set(s.state, name, &snapst)
+	}
+
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "experimental.hotplug", enabled)
+	tr.Commit()
+	s.state.Unlock()
+
+	mon := &udevMonitorMock{}
+	s.AddCleanup(ifacestate.MockCreateUDevMonitor(func(added udevmonitor.DeviceAddedFunc, removed udevmonitor.DeviceRemovedFunc, done udevmonitor.EnumerationDoneFunc) udevmonitor.Interface {
+		mon.AddDevice = added
+		mon.RemoveDevice = removed
+		mon.EnumerationDone = done
+		return mon
+	}))
+	return mon
+}
+
+func mockDevice(c *C, devpath, devname, serial string) *hotplug.HotplugDeviceInfo {
+	di, err := hotplug.NewHotplugDeviceInfo(map[string]string{
+		"DEVPATH":      devpath,
+		"DEVNAME":      devname,
+		"SUBSYSTEM":    "test",
+		"ID_VENDOR_ID": "0001",
+		"ID_MODEL_ID":  "0002",
+		"ID_SERIAL":    serial,
+	})
+	c.Assert(err, IsNil)
+	return di
+}
+
+func (s *interfaceManagerSuite) TestHotplugDisabled(c *C) {
+	mon := s.mockHotplug(c, false)
+	mgr := s.manager(c)
+
+	c.Assert(mgr.Ensure(), IsNil)
+	c.Check(mon.ConnectCalls, Equals, 0)
+	c.Check(mon.AddDevice, IsNil)
+}
+
+func (s *interfaceManagerSuite) TestHotplugMonitorStartAndStop(c *C) {
+	mon := s.mockHotplug(c, true)
+	mgr := s.manager(c)
+
+	c.Assert(mgr.Ensure(), IsNil)
+	c.Check(mon.ConnectCalls, Equals, 1)
+	c.Check(mon.RunCalls, Equals, 1)
+	c.Check(mon.AddDevice, NotNil)
+
+	// the monitor is started once
+	c.Assert(mgr.Ensure(), IsNil)
+	c.Check(mon.ConnectCalls, Equals, 1)
+
+	mgr.Stop()
+	s.privateMgr = nil
+	c.Check(mon.StopCalls, Equals, 1)
+}
+
+func (s *interfaceManagerSuite) TestHotplugMonitorRetry(c *C) {
+	mon := s.mockHotplug(c, true)
+	mon.ConnectError = fmt.Errorf("boom")
+	mgr := s.manager(c)
+
+	c.Assert(mgr.Ensure(), IsNil)
+	c.Check(mon.ConnectCalls, Equals, 1)
+	c.Check(s.log.String(), Matches, "(?s).*cannot start udev monitor: boom.*")
+
+	// not retried before the timeout
+	c.Assert(mgr.Ensure(), IsNil)
+	c.Check(mon.ConnectCalls, Equals, 1)
+
+}
+
+func (s *interfaceManagerSuite) TestHotplugMonitorRetryAfterTimeout(c *C) {
+	restore := ifacestate.MockUDevMonitorRetryTimeout(0)
+	defer restore()
+	mon := s.mockHotplug(c, true)
+	mon.RunError = fmt.Errorf("bam")
+	mgr := s.manager(c)
+
+	c.Assert(mgr.Ensure(), IsNil)
+	c.Check(mon.RunCalls, Equals, 1)
+	c.Check(s.log.String(), Matches, "(?s).*cannot run udev monitor: bam.*")
+
+	mon.RunError = nil
+	c.Assert(mgr.Ensure(), IsNil)
+	c.Check(mon.ConnectCalls, Equals, 2)
+	c.Check(mon.RunCalls, Equals, 2)
+
+	// running now
+	c.Assert(mgr.Ensure(), IsNil)
+	c.Check(mon.RunCalls, Equals, 2)
+}
+
+func (s *interfaceManagerSuite) TestHotplugAddRemoveDevice(c *C) {
+	mon := s.mockHotplug(c, true)
+	mgr := s.manager(c)
+	c.Assert(mgr.Ensure(), IsNil)
+
+	dev := mockDevice(c, "/devices/a", "/dev/a", "1234")
+	mon.AddDevice(dev)
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	// the slot was created and auto-connected
+	repo := mgr.Repository()
+	slot := repo.Slot("core", "hotplugslot")
+	c.Assert(slot, NotNil)
+	c.Check(slot.Interface, Equals, "test")
+	c.Check(slot.HotplugKey, Equals, dev.Key())
+	c.Check(slot.Attrs, DeepEquals, map[string]interface{}{"path": "/dev/a"})
+
+	var hotplugSlots map[string]interface{}
+	c.Assert(s.state.Get("hotplug-slots", &hotplugSlots), IsNil)
+	c.Check(hotplugSlots, DeepEquals, map[string]interface{}{
+		"hotplugslot": map[string]interface{}{
+			"name":         "hotplugslot",
+			"interface":    "test",
+			"static-attrs": map[string]interface{}{"path": "/dev/a"},
+			"hotplug-key":  dev.Key(),
+		},
+	})
+
+	var conns map[string]interface{}
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns, DeepEquals, map[string]interface{}{
+		"consumer:plug core:hotplugslot": map[string]interface{}{
+			"interface":   "test",
+			"auto":        true,
+			"slot-static": map[string]interface{}{"path": "/dev/a"},
+		},
+	})
+	connected, err := repo.Connected("core", "hotplugslot")
+	c.Assert(err, IsNil)
+	c.Check(connected, HasLen, 1)
+
+	// the device goes away
+	s.state.Unlock()
+	mon.RemoveDevice(dev)
+	s.settle(c)
+	s.state.Lock()
+
+	c.Check(repo.Slot("core", "hotplugslot"), IsNil)
+	c.Assert(s.state.Get("hotplug-slots", &hotplugSlots), IsNil)
+	c.Check(hotplugSlots["hotplugslot"].(map[string]interface{})["hotplug-gone"], Equals, true)
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns, DeepEquals, map[string]interface{}{
+		"consumer:plug core:hotplugslot": map[string]interface{}{
+			"interface":    "test",
+			"auto":         true,
+			"hotplug-gone": true,
+			"slot-static":  map[string]interface{}{"path": "/dev/a"},
+		},
+	})
+
+	// the device comes back on a different port, the slot and the
+	// connection are restored
+	s.state.Unlock()
+	mon.AddDevice(mockDevice(c, "/devices/b", "/dev/b", "1234"))
+	s.settle(c)
+	s.state.Lock()
+
+	slot = repo.Slot("core", "hotplugslot")
+	c.Assert(slot, NotNil)
+	c.Check(slot.Attrs, DeepEquals, map[string]interface{}{"path": "/dev/b"})
+	connected, err = repo.Connected("core", "hotplugslot")
+	c.Assert(err, IsNil)
+	c.Check(connected, DeepEquals, []*interfaces.ConnRef{{
+		PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "plug"},
+		SlotRef: interfaces.SlotRef{Snap: "core", Name: "hotplugslot"},
+	}})
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns["consumer:plug core:hotplugslot"].(map[string]interface{})["hotplug-gone"], IsNil)
+	c.Assert(s.state.Get("hotplug-slots", &hotplugSlots), IsNil)
+	c.Check(hotplugSlots["hotplugslot"].(map[string]interface{})["hotplug-gone"], IsNil)
+}
+
+func (s *interfaceManagerSuite) TestHotplugSlotNamesAreUnique(c *C) {
+	mon := s.mockHotplug(c, true)
+	mgr := s.manager(c)
+	c.Assert(mgr.Ensure(), IsNil)
+
+	dev1 := mockDevice(c, "/devices/a", "/dev/a", "1")
+	dev2 := mockDevice(c, "/devices/b", "/dev/b", "2")
+	mon.AddDevice(dev1)
+	mon.AddDevice(dev2)
+	// reported twice, ignored
+	mon.AddDevice(dev2)
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	repo := mgr.Repository()
+	c.Assert(repo.Slot("core", "hotplugslot"), NotNil)
+	c.Assert(repo.Slot("core", "hotplugslot-1"), NotNil)
+	c.Check(repo.Slot("core", "hotplugslot-2"), IsNil)
+	// the changes may run in any order
+	keys := map[string]bool{
+		repo.Slot("core", "hotplugslot").HotplugKey:   true,
+		repo.Slot("core", "hotplugslot-1").HotplugKey: true,
+	}
+	c.Check(keys, DeepEquals, map[string]bool{dev1.Key(): true, dev2.Key(): true})
+}
+
+func (s *interfaceManagerSuite) TestHotplugEnumerationDoneRemovesGoneDevices(c *C) {
+	mon := s.mockHotplug(c, true)
+
+	present := mockDevice(c, "/devices/a", "/dev/a", "1")
+	s.state.Lock()
+	s.state.Set("hotplug-slots", map[string]interface{}{
+		"hotplugslot": map[string]interface{}{
+			"name":        "hotplugslot",
+			"interface":   "test",
+			"hotplug-key": present.Key(),
+		},
+		"hotplugslot-1": map[string]interface{}{
+			"name":        "hotplugslot-1",
+			"interface":   "test",
+			"hotplug-key": "gone-key",
+		},
+	})
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+	repo := mgr.Repository()
+	// the slots known from the state are added on startup
+	c.Assert(repo.Slot("core", "hotplugslot"), NotNil)
+	c.Assert(repo.Slot("core", "hotplugslot-1"), NotNil)
+
+	c.Assert(mgr.Ensure(), IsNil)
+	mon.AddDevice(present)
+	mon.EnumerationDone()
+	s.settle(c)
+
+	c.Check(repo.Slot("core", "hotplugslot"), NotNil)
+	c.Check(repo.Slot("core", "hotplugslot-1"), IsNil)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	var hotplugSlots map[string]map[string]interface{}
+	c.Assert(s.state.Get("hotplug-slots", &hotplugSlots), IsNil)
+	c.Check(hotplugSlots["hotplugslot"]["hotplug-gone"], IsNil)
+	c.Check(hotplugSlots["hotplugslot-1"]["hotplug-gone"], Equals, true)
+}
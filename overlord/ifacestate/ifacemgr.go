@@ -20,10 +20,13 @@
 package ifacestate
 
 import (
+	"time"
+
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/interfaces/backends"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/ifacestate/ifacerepo"
+	"github.com/snapcore/snapd/overlord/ifacestate/udevmonitor"
 	"github.com/snapcore/snapd/overlord/state"
 )
 
@@ -34,6 +37,13 @@ type InterfaceManager struct {
 	state  *state.State
 	runner *state.TaskRunner
 	repo   *interfaces.Repository
+
+	// udevMon is the monitor of hotplug devices, started on demand
+	udevMon   udevmonitor.Interface
+	udevRetry time.Time
+	// enumeratedDeviceKeys tracks the hotplug keys of the devices
+	// reported while the udev monitor enumerates existing devices
+	enumeratedDeviceKeys map[string]bool
 }
 
 // Manager returns a new InterfaceManager.
@@ -67,11 +77,18 @@ func Manager(s *state.State, hookManager *hookstate.HookManager, extraInterfaces
 	})
 
 	runner.AddHandler("connect", m.doConnect, m.undoConnect)
-	runner.AddHandler("disconnect", m.doDisconnect, nil)
+	runner.AddHandler("disconnect", m.doDisconnect, m.undoDisconnect)
 	runner.AddHandler("setup-profiles", m.doSetupProfiles, m.undoSetupProfiles)
 	runner.AddHandler("remove-profiles", m.doRemoveProfiles, m.doSetupProfiles)
 	runner.AddHandler("discard-conns", m.doDiscardConns, m.undoDiscardConns)
 	runner.AddHandler("auto-connect", m.doAutoConnect, m.undoAutoConnect)
+	runner.AddHandler("auto-disconnect", m.doAutoDisconnect, nil)
+
+	// hotplug
+	runner.AddHandler("hotplug-add-slot", m.doHotplugAddSlot, nil)
+	runner.AddHandler("hotplug-connect", m.doHotplugConnect, nil)
+	runner.AddHandler("hotplug-disconnect", m.doHotplugDisconnect, nil)
+	runner.AddHandler("hotplug-remove-slot", m.doHotplugRemoveSlot, nil)
 
 	// helper for ubuntu-core -> core
 	runner.AddHandler("transition-ubuntu-core", m.doTransitionUbuntuCore, m.undoTransitionUbuntuCore)
@@ -85,6 +102,7 @@ func (m *InterfaceManager) KnownTaskKinds() []string {
 
 // Ensure implements StateManager.Ensure.
 func (m *InterfaceManager) Ensure() error {
+	m.ensureUDevMonitor()
 	m.runner.Ensure()
 	return nil
 }
@@ -96,6 +114,7 @@ func (m *InterfaceManager) Wait() {
 
 // Stop implements StateManager.Stop.
 func (m *InterfaceManager) Stop() {
+	m.stopUDevMonitor()
 	m.runner.Stop()
 }
 
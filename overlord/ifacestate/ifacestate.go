@@ -31,6 +31,7 @@ import (
 	"github.com/snapcore/snapd/interfaces/policy"
 	"github.com/snapcore/snapd/overlord/assertstate"
 	"github.com/snapcore/snapd/overlord/hookstate"
+	"github.com/snapcore/snapd/overlord/ifacestate/ifacerepo"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
@@ -207,7 +208,7 @@ func connect(st *state.State, mainTask *state.Task, plugSnap, plugName, slotSnap
 
 	connectInterface.Set("slot", interfaces.SlotRef{Snap: slotSnap, Name: slotName})
 	connectInterface.Set("plug", interfaces.PlugRef{Snap: plugSnap, Name: plugName})
-	connectInterface.Set("auto", mainTask != nil && mainTask.Kind() == "auto-connect")
+	connectInterface.Set("auto", mainTask != nil && (mainTask.Kind() == "auto-connect" || mainTask.Kind() == "hotplug-connect"))
 
 	// Expose a copy of all plug and slot attributes coming from yaml to interface hooks. The hooks will be able
 	// to modify them but all attributes will be checked against assertions after the hooks are run.
@@ -269,7 +270,11 @@ func initialConnectAttributes(st *state.State, plugSnap string, plugName string,
 	addImplicitSlots(snapInfo)
 	slot, ok := snapInfo.Slots[slotName]
 	if !ok {
-		return nil, nil, fmt.Errorf("snap %q has no slot named %q", slotSnap, slotName)
+		// slots of hotplugged devices are only known to the repository
+		slot = ifacerepo.Get(st).Slot(slotSnap, slotName)
+		if slot == nil || slot.HotplugKey == "" {
+			return nil, nil, fmt.Errorf("snap %q has no slot named %q", slotSnap, slotName)
+		}
 	}
 
 	return plug.Attrs, slot.Attrs, nil
@@ -284,12 +289,129 @@ func Disconnect(st *state.State, plugSnap, plugName, slotSnap, slotName string)
 		return nil, err
 	}
 
+	connRef := &interfaces.ConnRef{
+		PlugRef: interfaces.PlugRef{Snap: plugSnap, Name: plugName},
+		SlotRef: interfaces.SlotRef{Snap: slotSnap, Name: slotName},
+	}
+	return disconnectTasks(st, connRef, disconnectOpts{})
+}
+
+type disconnectOpts struct {
+	// AutoDisconnect is set when the connection is broken because one
+	// of its snaps is being removed.
+	AutoDisconnect bool
+}
+
+func disconnectTasks(st *state.State, connRef *interfaces.ConnRef, flags disconnectOpts) (*state.TaskSet, error) {
+	// Create a series of tasks:
+	//  - disconnect-plug-<plug> hook
+	//  - disconnect-slot-<slot> hook
+	//  - disconnect task
+	//  - unprepare-slot-<slot> hook
+	//  - unprepare-plug-<plug> hook
+	// The tasks run in sequence (are serialized by WaitFor), mirroring
+	// the tasks created by connect. The hooks can read the attributes
+	// of the connection with 'snapctl get'.
+	plugSnap, plugName := connRef.PlugRef.Snap, connRef.PlugRef.Name
+	slotSnap, slotName := connRef.SlotRef.Snap, connRef.SlotRef.Name
+
+	conns, err := getConns(st)
+	if err != nil {
+		return nil, err
+	}
+	conn := conns[connRef.ID()]
+
 	summary := fmt.Sprintf(i18n.G("Disconnect %s:%s from %s:%s"),
 		plugSnap, plugName, slotSnap, slotName)
-	task := st.NewTask("disconnect", summary)
-	task.Set("slot", interfaces.SlotRef{Snap: slotSnap, Name: slotName})
-	task.Set("plug", interfaces.PlugRef{Snap: plugSnap, Name: plugName})
-	return state.NewTaskSet(task), nil
+	disconnectTask := st.NewTask("disconnect", summary)
+	disconnectTask.Set("slot", connRef.SlotRef)
+	disconnectTask.Set("plug", connRef.PlugRef)
+	if flags.AutoDisconnect {
+		disconnectTask.Set("auto-disconnect", true)
+	}
+
+	// Expose the attributes of the connection to the interface hooks.
+	for attr, value := range map[string]map[string]interface{}{
+		"plug-static":  conn.StaticPlugAttrs,
+		"plug-dynamic": conn.DynamicPlugAttrs,
+		"slot-static":  conn.StaticSlotAttrs,
+		"slot-dynamic": conn.DynamicSlotAttrs,
+	} {
+		if value == nil {
+			value = map[string]interface{}{}
+		}
+		disconnectTask.Set(attr, value)
+	}
+
+	initialContext := make(map[string]interface{})
+	initialContext["attrs-task"] = disconnectTask.ID()
+
+	hookTask := func(snapName, hookName string) *state.Task {
+		hookSetup := &hookstate.HookSetup{
+			Snap:     snapName,
+			Hook:     hookName,
+			Optional: true,
+			// a broken hook must not prevent the removal of a snap
+			IgnoreError: flags.AutoDisconnect,
+		}
+		summary := fmt.Sprintf(i18n.G("Run hook %s of snap %q"), hookSetup.Hook, hookSetup.Snap)
+		return hookstate.HookTask(st, summary, hookSetup, initialContext)
+	}
+
+	disconnectPlug := hookTask(plugSnap, "disconnect-plug-"+plugName)
+	disconnectSlot := hookTask(slotSnap, "disconnect-slot-"+slotName)
+	disconnectSlot.WaitFor(disconnectPlug)
+	disconnectTask.WaitFor(disconnectSlot)
+	unprepareSlot := hookTask(slotSnap, "unprepare-slot-"+slotName)
+	unprepareSlot.WaitFor(disconnectTask)
+	unpreparePlug := hookTask(plugSnap, "unprepare-plug-"+plugName)
+	unpreparePlug.WaitFor(unprepareSlot)
+
+	return state.NewTaskSet(disconnectPlug, disconnectSlot, disconnectTask, unprepareSlot, unpreparePlug), nil
+}
+
+// ConnectionState is the state of a connection as recorded by the
+// interface manager.
+type ConnectionState struct {
+	Interface        string
+	Auto             bool
+	Undesired        bool
+	HotplugGone      bool
+	StaticPlugAttrs  map[string]interface{}
+	DynamicPlugAttrs map[string]interface{}
+	StaticSlotAttrs  map[string]interface{}
+	DynamicSlotAttrs map[string]interface{}
+}
+
+// Active returns true if the connection is in effect, that is it was
+// neither disconnected by the user nor is its hotplug device gone.
+func (c ConnectionState) Active() bool {
+	return !(c.Undesired || c.HotplugGone)
+}
+
+// ConnectionStates returns the states of all the connections known to
+// the system, keyed by connection ID. Connections that are not active
+// are included as well.
+func ConnectionStates(st *state.State) (map[string]ConnectionState, error) {
+	conns, err := getConns(st)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[string]ConnectionState, len(conns))
+	for id, cstate := range conns {
+		states[id] = ConnectionState{
+			Interface:        cstate.Interface,
+			Auto:             cstate.Auto,
+			Undesired:        cstate.Undesired,
+			HotplugGone:      cstate.HotplugGone,
+			StaticPlugAttrs:  cstate.StaticPlugAttrs,
+			DynamicPlugAttrs: cstate.DynamicPlugAttrs,
+			StaticSlotAttrs:  cstate.StaticSlotAttrs,
+			DynamicSlotAttrs: cstate.DynamicSlotAttrs,
+		}
+	}
+	return states, nil
 }
 
 // CheckInterfaces checks whether plugs and slots of snap are allowed for installation.
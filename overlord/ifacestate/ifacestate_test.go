@@ -163,9 +163,14 @@ func (s *interfaceManagerSuite) TestKnownTaskKinds(c *C) {
 	sort.Strings(kinds)
 	c.Assert(kinds, DeepEquals, []string{
 		"auto-connect",
+		"auto-disconnect",
 		"connect",
 		"discard-conns",
 		"disconnect",
+		"hotplug-add-slot",
+		"hotplug-connect",
+		"hotplug-disconnect",
+		"hotplug-remove-slot",
 		"remove-profiles",
 		"setup-profiles",
 		"transition-ubuntu-core"})
@@ -668,10 +673,31 @@ func (s *interfaceManagerSuite) TestDisconnectTask(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
 
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface":    "test",
+			"plug-static":  map[string]interface{}{"attr1": "value1"},
+			"plug-dynamic": map[string]interface{}{"attr3": "value3"},
+			"slot-static":  map[string]interface{}{"attr2": "value2"},
+		},
+	})
+
 	ts, err := ifacestate.Disconnect(s.state, "consumer", "plug", "producer", "slot")
 	c.Assert(err, IsNil)
+	c.Assert(ts.Tasks(), HasLen, 5)
+
+	var hs hookstate.HookSetup
+	checkHook := func(task *state.Task, snapName, hookName string) {
+		c.Check(task.Kind(), Equals, "run-hook")
+		c.Assert(task.Get("hook-setup", &hs), IsNil)
+		c.Check(hs, Equals, hookstate.HookSetup{Snap: snapName, Hook: hookName, Optional: true})
+	}
+	checkHook(ts.Tasks()[0], "consumer", "disconnect-plug-plug")
+	checkHook(ts.Tasks()[1], "producer", "disconnect-slot-slot")
+	checkHook(ts.Tasks()[3], "producer", "unprepare-slot-slot")
+	checkHook(ts.Tasks()[4], "consumer", "unprepare-plug-plug")
 
-	task := ts.Tasks()[0]
+	task := ts.Tasks()[2]
 	c.Assert(task.Kind(), Equals, "disconnect")
 	var plug interfaces.PlugRef
 	err = task.Get("plug", &plug)
@@ -683,6 +709,29 @@ func (s *interfaceManagerSuite) TestDisconnectTask(c *C) {
 	c.Assert(err, IsNil)
 	c.Assert(slot.Snap, Equals, "producer")
 	c.Assert(slot.Name, Equals, "slot")
+
+	// the attributes of the connection are available to the hooks
+	for attr, expected := range map[string]map[string]interface{}{
+		"plug-static":  {"attr1": "value1"},
+		"plug-dynamic": {"attr3": "value3"},
+		"slot-static":  {"attr2": "value2"},
+		"slot-dynamic": {},
+	} {
+		var attrs map[string]interface{}
+		c.Assert(task.Get(attr, &attrs), IsNil)
+		c.Check(attrs, DeepEquals, expected, Commentf(attr))
+	}
+
+	for _, i := range []int{0, 1, 3, 4} {
+		var context map[string]interface{}
+		c.Assert(ts.Tasks()[i].Get("hook-context", &context), IsNil)
+		c.Check(context["attrs-task"], Equals, task.ID())
+	}
+
+	// the tasks run in sequence
+	for i := 1; i < len(ts.Tasks()); i++ {
+		c.Check(ts.Tasks()[i].WaitTasks(), DeepEquals, []*state.Task{ts.Tasks()[i-1]})
+	}
 }
 
 // Disconnect works when both plug and slot are specified
@@ -721,17 +770,16 @@ func (s *interfaceManagerSuite) testDisconnect(c *C, plugSnap, plugName, slotSna
 	c.Assert(err, IsNil)
 	change.AddAll(ts)
 	s.state.Unlock()
-	mgr.Ensure()
-	mgr.Wait()
+	s.settle(c)
 
 	s.state.Lock()
 	defer s.state.Unlock()
 
 	// Ensure that the task succeeded.
 	c.Assert(change.Err(), IsNil)
-	task := change.Tasks()[0]
-	c.Check(task.Kind(), Equals, "disconnect")
-	c.Check(task.Status(), Equals, state.DoneStatus)
+	for _, task := range change.Tasks() {
+		c.Check(task.Status(), Equals, state.DoneStatus)
+	}
 
 	c.Check(change.Status(), Equals, state.DoneStatus)
 
@@ -1913,7 +1961,7 @@ func (s *interfaceManagerSuite) TestDisconnectSetsUpSecurity(c *C) {
 	})
 	s.state.Unlock()
 
-	mgr := s.manager(c)
+	_ = s.manager(c)
 
 	s.state.Lock()
 	ts, err := ifacestate.Disconnect(s.state, "consumer", "plug", "producer", "slot")
@@ -1928,9 +1976,7 @@ func (s *interfaceManagerSuite) TestDisconnectSetsUpSecurity(c *C) {
 	change.AddAll(ts)
 	s.state.Unlock()
 
-	mgr.Ensure()
-	mgr.Wait()
-	mgr.Stop()
+	s.settle(c)
 
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -1957,7 +2003,7 @@ func (s *interfaceManagerSuite) TestDisconnectTracksConnectionsInState(c *C) {
 	})
 	s.state.Unlock()
 
-	mgr := s.manager(c)
+	_ = s.manager(c)
 
 	s.state.Lock()
 	ts, err := ifacestate.Disconnect(s.state, "consumer", "plug", "producer", "slot")
@@ -1972,9 +2018,7 @@ func (s *interfaceManagerSuite) TestDisconnectTracksConnectionsInState(c *C) {
 	change.AddAll(ts)
 	s.state.Unlock()
 
-	mgr.Ensure()
-	mgr.Wait()
-	mgr.Stop()
+	s.settle(c)
 
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -1997,7 +2041,7 @@ func (s *interfaceManagerSuite) TestDisconnectDisablesAutoConnect(c *C) {
 	})
 	s.state.Unlock()
 
-	mgr := s.manager(c)
+	_ = s.manager(c)
 
 	s.state.Lock()
 	ts, err := ifacestate.Disconnect(s.state, "consumer", "plug", "producer", "slot")
@@ -2012,9 +2056,7 @@ func (s *interfaceManagerSuite) TestDisconnectDisablesAutoConnect(c *C) {
 	change.AddAll(ts)
 	s.state.Unlock()
 
-	mgr.Ensure()
-	mgr.Wait()
-	mgr.Stop()
+	s.settle(c)
 
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -2580,6 +2622,204 @@ func (s *interfaceManagerSuite) TestUndoConnectRestoresOldConnState(c *C) {
 		}})
 }
 
+func (s *interfaceManagerSuite) TestUndoConnectRemovesNewConnection(c *C) {
+	s.mockIfaces(c, &ifacetest.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+	mgr := s.manager(c)
+
+	s.state.Lock()
+	chg := s.state.NewChange("connect", "")
+	ts, err := ifacestate.Connect(s.state, "consumer", "plug", "producer", "slot")
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+	terr := s.state.NewTask("error-trigger", "provoking total undo")
+	terr.WaitAll(ts)
+	chg.AddTask(terr)
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(chg.Status(), Equals, state.ErrorStatus)
+
+	// the connection is gone from both the state and the repository
+	var conns map[string]interface{}
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns, HasLen, 0)
+	c.Check(mgr.Repository().Interfaces().Connections, HasLen, 0)
+}
+
+func (s *interfaceManagerSuite) TestUndoDisconnectRestoresConnection(c *C) {
+	s.mockIfaces(c, &ifacetest.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	connState := map[string]interface{}{
+		"interface":    "test",
+		"auto":         true,
+		"plug-static":  map[string]interface{}{"attr1": "value1"},
+		"slot-static":  map[string]interface{}{"attr2": "value2"},
+		"plug-dynamic": map[string]interface{}{"attr3": "value3"},
+	}
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": connState,
+	})
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+
+	s.state.Lock()
+	chg := s.state.NewChange("disconnect", "")
+	ts, err := ifacestate.Disconnect(s.state, "consumer", "plug", "producer", "slot")
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+	terr := s.state.NewTask("error-trigger", "provoking total undo")
+	terr.WaitAll(ts)
+	chg.AddTask(terr)
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(chg.Status(), Equals, state.ErrorStatus)
+
+	// the connection is restored in both the state and the repository
+	var conns map[string]interface{}
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns, DeepEquals, map[string]interface{}{
+		"consumer:plug producer:slot": connState,
+	})
+	repo := mgr.Repository()
+	c.Check(repo.Interfaces().Connections, DeepEquals, []*interfaces.ConnRef{{PlugRef: interfaces.PlugRef{Snap: "consumer", Name: "plug"}, SlotRef: interfaces.SlotRef{Snap: "producer", Name: "slot"}}})
+
+	// security was set up again for both snaps
+	c.Assert(s.secBackend.SetupCalls, HasLen, 4)
+	c.Check(s.secBackend.SetupCalls[2].SnapInfo.Name(), Equals, "consumer")
+	c.Check(s.secBackend.SetupCalls[3].SnapInfo.Name(), Equals, "producer")
+}
+
+func (s *interfaceManagerSuite) TestAutoDisconnect(c *C) {
+	s.mockIfaces(c, &ifacetest.TestInterface{InterfaceName: "test"})
+	s.mockSnap(c, consumerYaml)
+	s.mockSnap(c, producerYaml)
+
+	s.state.Lock()
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{"interface": "test", "auto": true},
+	})
+	s.state.Unlock()
+
+	mgr := s.manager(c)
+
+	s.state.Lock()
+	chg := s.state.NewChange("remove", "")
+	t := s.state.NewTask("auto-disconnect", "")
+	t.Set("snap-setup", &snapstate.SnapSetup{
+		SideInfo: &snap.SideInfo{
+			RealName: "producer",
+		},
+	})
+	chg.AddTask(t)
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Assert(chg.Err(), IsNil)
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+
+	var hooks []string
+	for _, t := range chg.Tasks() {
+		switch t.Kind() {
+		case "run-hook":
+			var hs hookstate.HookSetup
+			c.Assert(t.Get("hook-setup", &hs), IsNil)
+			// hooks cannot prevent the removal of the snap
+			c.Check(hs.IgnoreError, Equals, true)
+			hooks = append(hooks, hs.Snap+":"+hs.Hook)
+		case "disconnect":
+			var autoDisconnect bool
+			c.Assert(t.Get("auto-disconnect", &autoDisconnect), IsNil)
+			c.Check(autoDisconnect, Equals, true)
+		}
+	}
+	c.Check(hooks, DeepEquals, []string{
+		"consumer:disconnect-plug-plug",
+		"producer:disconnect-slot-slot",
+		"producer:unprepare-slot-slot",
+		"consumer:unprepare-plug-plug",
+	})
+
+	// the connection is forgotten rather than marked as undesired
+	var conns map[string]interface{}
+	c.Assert(s.state.Get("conns", &conns), IsNil)
+	c.Check(conns, HasLen, 0)
+	c.Check(mgr.Repository().Interfaces().Connections, HasLen, 0)
+}
+
+func (s *interfaceManagerSuite) TestConnectionStates(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.state.Set("conns", map[string]interface{}{
+		"consumer:plug producer:slot": map[string]interface{}{
+			"interface":    "test",
+			"auto":         true,
+			"plug-static":  map[string]interface{}{"attr1": "value1"},
+			"plug-dynamic": map[string]interface{}{"attr2": "value2"},
+			"slot-static":  map[string]interface{}{"attr3": "value3"},
+		},
+		"consumer:otherplug producer:otherslot": map[string]interface{}{
+			"interface": "test2",
+			"undesired": true,
+		},
+		"consumer:plug hotplug:slot": map[string]interface{}{
+			"interface":    "test",
+			"hotplug-gone": true,
+		},
+	})
+
+	states, err := ifacestate.ConnectionStates(s.state)
+	c.Assert(err, IsNil)
+	c.Check(states, DeepEquals, map[string]ifacestate.ConnectionState{
+		"consumer:plug producer:slot": {
+			Interface:        "test",
+			Auto:             true,
+			StaticPlugAttrs:  map[string]interface{}{"attr1": "value1"},
+			DynamicPlugAttrs: map[string]interface{}{"attr2": "value2"},
+			StaticSlotAttrs:  map[string]interface{}{"attr3": "value3"},
+		},
+		"consumer:otherplug producer:otherslot": {
+			Interface: "test2",
+			Undesired: true,
+		},
+		"consumer:plug hotplug:slot": {
+			Interface:   "test",
+			HotplugGone: true,
+		},
+	})
+	c.Check(states["consumer:plug producer:slot"].Active(), Equals, true)
+	c.Check(states["consumer:otherplug producer:otherslot"].Active(), Equals, false)
+	c.Check(states["consumer:plug hotplug:slot"].Active(), Equals, false)
+}
+
+func (s *interfaceManagerSuite) TestConnectionStatesNoConns(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	states, err := ifacestate.ConnectionStates(s.state)
+	c.Assert(err, IsNil)
+	c.Check(states, HasLen, 0)
+}
+
 func (s *interfaceManagerSuite) TestConnectErrorMissingSlotSnapOnAutoConnect(c *C) {
 	_ = s.manager(c)
 	s.mockSnap(c, producerYaml)
@@ -0,0 +1,245 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package udevmonitor watches udev for devices being added to and removed
+// from the system.
+package udevmonitor
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/snapcore/snapd/interfaces/hotplug"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil/udev/netlink"
+)
+
+// Interface is the interface of the udev monitor used by the interface
+// manager.
+type Interface interface {
+	Connect() error
+	Run() error
+	Stop() error
+}
+
+// DeviceAddedFunc is called when a device is added to the system.
+type DeviceAddedFunc func(device *hotplug.HotplugDeviceInfo)
+
+// DeviceRemovedFunc is called when a device is removed from the system.
+type DeviceRemovedFunc func(device *hotplug.HotplugDeviceInfo)
+
+// EnumerationDoneFunc is called once all the devices present when the
+// monitor started have been reported through DeviceAddedFunc.
+type EnumerationDoneFunc func()
+
+// stopTimeout is how long Stop waits for the netlink reader to finish.
+const stopTimeout = 5 * time.Second
+
+// Monitor monitors kernel uevents making it possible to find hotpluggable
+// devices.
+type Monitor struct {
+	deviceAdded     DeviceAddedFunc
+	deviceRemoved   DeviceRemovedFunc
+	enumerationDone EnumerationDoneFunc
+
+	netlinkConn   *netlink.UEventConn
+	netlinkEvents chan netlink.UEvent
+	netlinkErrors chan error
+	stopMonitor   func(time.Duration) bool
+
+	// seen tracks the devices (by their DEVPATH) reported during the
+	// initial enumeration, so that the events racing with it are not
+	// reported twice.
+	seen map[string]bool
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a udev monitor calling the given functions on device
+// events.
+func New(added DeviceAddedFunc, removed DeviceRemovedFunc, enumerationDone EnumerationDoneFunc) Interface {
+	return &Monitor{
+		deviceAdded:     added,
+		deviceRemoved:   removed,
+		enumerationDone: enumerationDone,
+		netlinkConn:     &netlink.UEventConn{},
+		netlinkEvents:   make(chan netlink.UEvent),
+		netlinkErrors:   make(chan error),
+		seen:            make(map[string]bool),
+		quit:            make(chan struct{}),
+	}
+}
+
+// Connect opens the netlink socket used to receive the udev events.
+func (m *Monitor) Connect() error {
+	if m.netlinkConn == nil {
+		return fmt.Errorf("udev monitor is not initialized")
+	}
+	if err := m.netlinkConn.Connect(netlink.UdevEvent); err != nil {
+		return fmt.Errorf("cannot start udev monitor: %s", err)
+	}
+	return nil
+}
+
+// Run enumerates the devices present in the system and then reports the
+// devices being added and removed, until Stop is called.
+func (m *Monitor) Run() error {
+	// start listening before enumerating so that no event is lost
+	m.stopMonitor = m.netlinkConn.Monitor(m.netlinkEvents, m.netlinkErrors)
+
+	devices, err := enumerateExistingDevices()
+	if err != nil {
+		m.stopMonitor(stopTimeout)
+		m.netlinkConn.Close()
+		return fmt.Errorf("cannot enumerate existing devices: %s", err)
+	}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		for _, dev := range devices {
+			devPath, _ := dev.Attribute("DEVPATH")
+			m.seen[devPath] = true
+			m.deviceAdded(dev)
+		}
+		if m.enumerationDone != nil {
+			m.enumerationDone()
+		}
+		// the events that arrived during the enumeration concern
+		// devices that may have been reported already
+		for {
+			select {
+			case err := <-m.netlinkErrors:
+				logger.Noticef("udev event error: %s", err)
+			case ev := <-m.netlinkEvents:
+				m.handleEvent(ev)
+			case <-m.quit:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Stop stops reporting events and closes the netlink socket.
+func (m *Monitor) Stop() error {
+	if m.stopMonitor == nil {
+		return nil
+	}
+	close(m.quit)
+	m.wg.Wait()
+	stopped := m.stopMonitor(stopTimeout)
+	m.stopMonitor = nil
+	if err := m.netlinkConn.Close(); err != nil {
+		return fmt.Errorf("cannot stop udev monitor: %s", err)
+	}
+	if !stopped {
+		return fmt.Errorf("cannot stop udev monitor: timeout")
+	}
+	return nil
+}
+
+func (m *Monitor) handleEvent(ev netlink.UEvent) {
+	dev, err := hotplug.NewHotplugDeviceInfo(ev.Env)
+	if err != nil {
+		logger.Noticef("cannot handle udev event %s: %s", ev, err)
+		return
+	}
+	devPath, _ := dev.Attribute("DEVPATH")
+
+	switch ev.Action {
+	case netlink.ADD:
+		if m.seen[devPath] {
+			// already reported by the enumeration
+			return
+		}
+		m.seen[devPath] = true
+		m.deviceAdded(dev)
+	case netlink.REMOVE:
+		delete(m.seen, devPath)
+		m.deviceRemoved(dev)
+	}
+}
+
+var udevadmCommand = func() ([]byte, error) {
+	return exec.Command("udevadm", "info", "-e").Output()
+}
+
+func enumerateExistingDevices() ([]*hotplug.HotplugDeviceInfo, error) {
+	out, err := udevadmCommand()
+	if err != nil {
+		return nil, err
+	}
+	return parseUdevadmOutput(bytes.NewReader(out))
+}
+
+// parseUdevadmOutput parses the output of "udevadm info -e", that is a
+// list of blocks separated by empty lines, one per device, where the
+// properties of the device are on the lines prefixed with "E: ".
+func parseUdevadmOutput(r io.Reader) ([]*hotplug.HotplugDeviceInfo, error) {
+	var devices []*hotplug.HotplugDeviceInfo
+	env := make(map[string]string)
+
+	flush := func() error {
+		if len(env) == 0 {
+			return nil
+		}
+		dev, err := hotplug.NewHotplugDeviceInfo(env)
+		if err != nil {
+			return err
+		}
+		devices = append(devices, dev)
+		env = make(map[string]string)
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !strings.HasPrefix(line, "E: ") {
+			continue
+		}
+		kv := strings.SplitN(line[3:], "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid udevadm output line %q", line)
+		}
+		env[kv[0]] = kv[1]
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
@@ -0,0 +1,101 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package udevmonitor_test
+
+import (
+	"bytes"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/interfaces/hotplug"
+	"github.com/snapcore/snapd/osutil/udev/netlink"
+	"github.com/snapcore/snapd/overlord/ifacestate/udevmonitor"
+)
+
+func TestHotplug(t *testing.T) { TestingT(t) }
+
+type udevMonitorSuite struct{}
+
+var _ = Suite(&udevMonitorSuite{})
+
+func (s *udevMonitorSuite) TestParseUdevadmOutput(c *C) {
+	out := `P: /devices/pci0000:00/0000:00:14.0/usb2/2-1/2-1:1.0/ttyUSB0/tty/ttyUSB0
+N: ttyUSB0
+S: serial/by-id/usb-FTDI_FT232R_USB_UART_A1-if00-port0
+E: DEVPATH=/devices/pci0000:00/0000:00:14.0/usb2/2-1/2-1:1.0/ttyUSB0/tty/ttyUSB0
+E: DEVNAME=/dev/ttyUSB0
+E: SUBSYSTEM=tty
+E: ID_BUS=usb
+
+P: /devices/virtual/video4linux/video0
+E: DEVPATH=/devices/virtual/video4linux/video0
+E: SUBSYSTEM=video4linux
+`
+	devices, err := udevmonitor.ParseUdevadmOutput(bytes.NewBufferString(out))
+	c.Assert(err, IsNil)
+	c.Assert(devices, HasLen, 2)
+
+	c.Check(devices[0].DeviceName(), Equals, "/dev/ttyUSB0")
+	c.Check(devices[0].Subsystem(), Equals, "tty")
+	v, ok := devices[0].Attribute("ID_BUS")
+	c.Check(ok, Equals, true)
+	c.Check(v, Equals, "usb")
+
+	c.Check(devices[1].Subsystem(), Equals, "video4linux")
+	c.Check(devices[1].DeviceName(), Equals, "")
+}
+
+func (s *udevMonitorSuite) TestParseUdevadmOutputErrors(c *C) {
+	_, err := udevmonitor.ParseUdevadmOutput(bytes.NewBufferString("P: /devices/foo\nE: FOO\n"))
+	c.Assert(err, ErrorMatches, `invalid udevadm output line "E: FOO"`)
+
+	_, err = udevmonitor.ParseUdevadmOutput(bytes.NewBufferString("P: /devices/foo\nE: FOO=bar\n"))
+	c.Assert(err, ErrorMatches, "missing device path attribute")
+}
+
+func (s *udevMonitorSuite) TestHandleEvent(c *C) {
+	var added, removed []string
+	mon := udevmonitor.New(func(di *hotplug.HotplugDeviceInfo) {
+		added = append(added, di.DeviceName())
+	}, func(di *hotplug.HotplugDeviceInfo) {
+		removed = append(removed, di.DeviceName())
+	}, nil).(*udevmonitor.Monitor)
+
+	enumerated, err := hotplug.NewHotplugDeviceInfo(map[string]string{"DEVPATH": "/devices/a", "DEVNAME": "/dev/a"})
+	c.Assert(err, IsNil)
+	mon.MarkSeen(enumerated)
+
+	for _, ev := range []netlink.UEvent{
+		// already reported by the enumeration
+		{Action: netlink.ADD, KObj: "/devices/a", Env: map[string]string{"DEVPATH": "/devices/a", "DEVNAME": "/dev/a"}},
+		{Action: netlink.ADD, KObj: "/devices/b", Env: map[string]string{"DEVPATH": "/devices/b", "DEVNAME": "/dev/b"}},
+		{Action: netlink.CHANGE, KObj: "/devices/b", Env: map[string]string{"DEVPATH": "/devices/b", "DEVNAME": "/dev/b"}},
+		{Action: netlink.REMOVE, KObj: "/devices/a", Env: map[string]string{"DEVPATH": "/devices/a", "DEVNAME": "/dev/a"}},
+		{Action: netlink.ADD, KObj: "/devices/a", Env: map[string]string{"DEVPATH": "/devices/a", "DEVNAME": "/dev/a"}},
+		// no DEVPATH, ignored
+		{Action: netlink.ADD, KObj: "/devices/c", Env: map[string]string{"DEVNAME": "/dev/c"}},
+	} {
+		mon.HandleEvent(ev)
+	}
+
+	c.Check(added, DeepEquals, []string{"/dev/b", "/dev/a"})
+	c.Check(removed, DeepEquals, []string{"/dev/a"})
+}
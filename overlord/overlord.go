@@ -22,6 +22,7 @@ package overlord
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sync"
@@ -41,6 +42,8 @@ import (
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/ifacestate"
 	"github.com/snapcore/snapd/overlord/patch"
+	"github.com/snapcore/snapd/overlord/servicestate"
+	"github.com/snapcore/snapd/overlord/snapshotstate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/store"
@@ -54,7 +57,7 @@ var (
 
 	pruneMaxChanges = 500
 
-	defaultCachedDownloads = 5
+	defaultCachedDownloadsSize int64 = 512 * 1024 * 1024
 
 	configstateInit = configstate.Init
 )
@@ -72,14 +75,15 @@ type Overlord struct {
 	// restarts
 	restartHandler func(t state.RestartType)
 	// managers
-	inited     bool
-	snapMgr    *snapstate.SnapManager
-	assertMgr  *assertstate.AssertManager
-	ifaceMgr   *ifacestate.InterfaceManager
-	hookMgr    *hookstate.HookManager
-	deviceMgr  *devicestate.DeviceManager
-	cmdMgr     *cmdstate.CommandManager
-	unknownMgr *UnknownTaskManager
+	inited      bool
+	snapMgr     *snapstate.SnapManager
+	assertMgr   *assertstate.AssertManager
+	ifaceMgr    *ifacestate.InterfaceManager
+	hookMgr     *hookstate.HookManager
+	deviceMgr   *devicestate.DeviceManager
+	cmdMgr      *cmdstate.CommandManager
+	snapshotMgr *snapshotstate.SnapshotManager
+	unknownMgr  *UnknownTaskManager
 }
 
 var storeNew = store.New
@@ -136,6 +140,8 @@ func New() (*Overlord, error) {
 	o.addManager(deviceMgr)
 
 	o.addManager(cmdstate.Manager(s))
+	o.addManager(snapshotstate.Manager(s))
+	o.addManager(servicestate.Manager(s))
 
 	configstateInit(hookMgr)
 
@@ -144,7 +150,7 @@ func New() (*Overlord, error) {
 	// setting up the store
 	authContext := auth.NewAuthContext(s, o.deviceMgr)
 	sto := storeNew(nil, authContext)
-	sto.SetCacheDownloads(defaultCachedDownloads)
+	sto.SetCacheDownloadsSize(defaultCachedDownloadsSize)
 
 	snapstate.ReplaceStore(s, sto)
 
@@ -169,16 +175,18 @@ func (o *Overlord) addManager(mgr StateManager) {
 		o.deviceMgr = x
 	case *cmdstate.CommandManager:
 		o.cmdMgr = x
+	case *snapshotstate.SnapshotManager:
+		o.snapshotMgr = x
 	}
 	o.stateEng.AddManager(mgr)
 	o.unknownMgr.Ignore(mgr.KnownTaskKinds())
 }
 
-func loadState(backend state.Backend) (*state.State, error) {
-	if !osutil.FileExists(dirs.SnapStateFile) {
+func loadState(backend *overlordStateBackend) (*state.State, error) {
+	if !osutil.FileExists(backend.path) {
 		// fail fast, mostly interesting for tests, this dir is setup
 		// by the snapd package
-		stateDir := filepath.Dir(dirs.SnapStateFile)
+		stateDir := filepath.Dir(backend.path)
 		if !osutil.IsDirectory(stateDir) {
 			return nil, fmt.Errorf("fatal: directory %q must be present", stateDir)
 		}
@@ -187,17 +195,38 @@ func loadState(backend state.Backend) (*state.State, error) {
 		return s, nil
 	}
 
-	r, err := os.Open(dirs.SnapStateFile)
+	r, err := os.Open(backend.path)
 	if err != nil {
 		return nil, fmt.Errorf("cannot read the state file: %s", err)
 	}
 	defer r.Close()
 
-	s, err := state.ReadState(backend, r)
+	var journal io.Reader
+	j, err := os.Open(JournalPath(backend.path))
+	switch {
+	case err == nil:
+		defer j.Close()
+		journal = j
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("cannot read the state journal: %s", err)
+	}
+
+	s, err := state.ReadStateWithJournal(backend, r, journal)
 	if err != nil {
 		return nil, err
 	}
 
+	if journal != nil {
+		// a journal left behind by an unclean shutdown, compact it
+		// right away into the full state so that it is not lost on
+		// a downgrade to a snapd not knowing about the journal; only
+		// a downgrade before snapd gets to run again can still miss
+		// the entries in it
+		s.Lock()
+		s.Compact()
+		s.Unlock()
+	}
+
 	// one-shot migrations
 	err = patch.Apply(s)
 	if err != nil {
@@ -279,6 +308,12 @@ func (o *Overlord) Stop() error {
 	o.loopTomb.Kill(nil)
 	err1 := o.loopTomb.Wait()
 	o.stateEng.Stop()
+	// leave behind just the full state, for anything not knowing
+	// about the journal
+	st := o.State()
+	st.Lock()
+	st.Compact()
+	st.Unlock()
 	return err1
 }
 
@@ -410,6 +445,12 @@ func (o *Overlord) CommandManager() *cmdstate.CommandManager {
 	return o.cmdMgr
 }
 
+// SnapshotManager returns the manager responsible for snapshots of
+// snap data.
+func (o *Overlord) SnapshotManager() *snapshotstate.SnapshotManager {
+	return o.snapshotMgr
+}
+
 // UnknownTaskManager returns the manager responsible for handling of
 // unknown tasks.
 func (o *Overlord) UnknownTaskManager() *UnknownTaskManager {
@@ -33,6 +33,7 @@ import (
 	"gopkg.in/tomb.v2"
 
 	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord"
 	"github.com/snapcore/snapd/overlord/auth"
 	"github.com/snapcore/snapd/overlord/hookstate"
@@ -94,7 +95,7 @@ func (ovs *overlordSuite) TestNew(c *C) {
 	// store is setup
 	sto := snapstate.Store(s)
 	c.Check(sto, FitsTypeOf, &store.Store{})
-	c.Check(sto.(*store.Store).CacheDownloads(), Equals, 5)
+	c.Check(sto.(*store.Store).CacheDownloadsSize(), Equals, int64(512*1024*1024))
 }
 
 func (ovs *overlordSuite) TestNewWithGoodState(c *C) {
@@ -475,6 +476,7 @@ func (ovs *overlordSuite) TestCheckpoint(c *C) {
 
 	s := o.State()
 	s.Lock()
+	s.Compact()
 	s.Set("mark", 1)
 	s.Unlock()
 
@@ -485,6 +487,78 @@ func (ovs *overlordSuite) TestCheckpoint(c *C) {
 	c.Check(dirs.SnapStateFile, testutil.FileContains, `"mark":1`)
 }
 
+func (ovs *overlordSuite) TestCheckpointJournal(c *C) {
+	o, err := overlord.New()
+	c.Assert(err, IsNil)
+
+	c.Check(dirs.SnapStateFile, testutil.FileContains, `"patch-level"`)
+
+	// only the modified entries are written, to the journal
+	s := o.State()
+	s.Lock()
+	s.Set("mark", 2)
+	s.Unlock()
+	c.Check(dirs.SnapStateFile, Not(testutil.FileContains), `"mark"`)
+	c.Check(overlord.JournalPath(dirs.SnapStateFile), testutil.FileContains, `"data":{"mark":2}`)
+
+	st, err := os.Stat(overlord.JournalPath(dirs.SnapStateFile))
+	c.Assert(err, IsNil)
+	c.Check(st.Mode().Perm(), Equals, os.FileMode(0600))
+
+	markSeeded(o)
+	// make sure we don't try to talk to the store
+	snapstate.CanAutoRefresh = nil
+
+	// stopping leaves behind just the full state
+	o.Loop()
+	err = o.Stop()
+	c.Assert(err, IsNil)
+	c.Check(dirs.SnapStateFile, testutil.FileContains, `"mark":2`)
+	c.Check(osutil.FileExists(overlord.JournalPath(dirs.SnapStateFile)), Equals, false)
+}
+
+func (ovs *overlordSuite) TestNewReplaysAndCompactsJournal(c *C) {
+	o, err := overlord.New()
+	c.Assert(err, IsNil)
+
+	s := o.State()
+	s.Lock()
+	s.Set("mark", 2)
+	s.Unlock()
+	// as if snapd was not stopped cleanly
+	c.Check(dirs.SnapStateFile, Not(testutil.FileContains), `"mark"`)
+	c.Check(overlord.JournalPath(dirs.SnapStateFile), testutil.FileContains, `"data":{"mark":2}`)
+
+	// the journal is replayed on load, and compacted right away so
+	// that nothing not knowing about it misses its entries
+	o, err = overlord.New()
+	c.Assert(err, IsNil)
+	s = o.State()
+	s.Lock()
+	var mark int
+	c.Check(s.Get("mark", &mark), IsNil)
+	s.Unlock()
+	c.Check(mark, Equals, 2)
+	c.Check(dirs.SnapStateFile, testutil.FileContains, `"mark":2`)
+	c.Check(osutil.FileExists(overlord.JournalPath(dirs.SnapStateFile)), Equals, false)
+}
+
+func (ovs *overlordSuite) TestJournalPath(c *C) {
+	c.Check(overlord.JournalPath("/var/lib/snapd/state.json"), Equals, "/var/lib/snapd/state.journal")
+	c.Check(overlord.JournalPath("/tmp/test.json"), Equals, "/tmp/test.journal")
+}
+
+func (ovs *overlordSuite) TestNewWithBadJournal(c *C) {
+	fakeState := []byte(fmt.Sprintf(`{"data":{"patch-level":%d},"changes":null,"tasks":null,"last-change-id":0,"last-task-id":0,"last-lane-id":0}`, patch.Level))
+	err := ioutil.WriteFile(dirs.SnapStateFile, fakeState, 0600)
+	c.Assert(err, IsNil)
+	err = ioutil.WriteFile(overlord.JournalPath(dirs.SnapStateFile), []byte("garbage\n"), 0600)
+	c.Assert(err, IsNil)
+
+	_, err = overlord.New()
+	c.Assert(err, ErrorMatches, "cannot read state journal entry: .*")
+}
+
 type runnerManager struct {
 	runner         *state.TaskRunner
 	ensureCallback func()
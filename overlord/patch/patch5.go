@@ -68,12 +68,12 @@ func patch5(st *state.State) error {
 			return err
 		}
 
-		err = wrappers.AddSnapServices(info, log)
+		err = wrappers.AddSnapServices(info, nil, log)
 		if err != nil {
 			return err
 		}
 
-		err = wrappers.StartServices(svcs, log)
+		err = wrappers.StartServices(svcs, nil, log)
 		if err != nil {
 			return err
 		}
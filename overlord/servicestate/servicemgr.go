@@ -0,0 +1,103 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package servicestate
+
+import (
+	"fmt"
+
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/userd/sessionagent"
+)
+
+// userServiceClient is the part of the session agent client used to
+// control the user session services.
+type userServiceClient interface {
+	ServicesStart(services []string) error
+	ServicesStop(services []string) error
+	ServicesRestart(services []string) error
+}
+
+var newUserServiceClient = func() userServiceClient {
+	return sessionagent.New()
+}
+
+// ServiceManager controls the services of the user sessions, through
+// the session agents.
+type ServiceManager struct {
+	runner *state.TaskRunner
+}
+
+// Manager returns a new ServiceManager.
+func Manager(st *state.State) *ServiceManager {
+	runner := state.NewTaskRunner(st)
+	runner.AddHandler("user-service-control", doUserServiceControl, nil)
+	return &ServiceManager{runner: runner}
+}
+
+// KnownTaskKinds is part of the overlord.StateManager interface.
+func (m *ServiceManager) KnownTaskKinds() []string {
+	return m.runner.KnownTaskKinds()
+}
+
+// Ensure is part of the overlord.StateManager interface.
+func (m *ServiceManager) Ensure() error {
+	m.runner.Ensure()
+	return nil
+}
+
+// Wait is part of the overlord.StateManager interface.
+func (m *ServiceManager) Wait() {
+	m.runner.Wait()
+}
+
+// Stop is part of the overlord.StateManager interface.
+func (m *ServiceManager) Stop() {
+	m.runner.Stop()
+}
+
+func doUserServiceControl(t *state.Task, tomb *tomb.Tomb) error {
+	var action string
+	var services []string
+
+	st := t.State()
+	st.Lock()
+	err1 := t.Get("action", &action)
+	err2 := t.Get("services", &services)
+	st.Unlock()
+	if err1 != nil {
+		return err1
+	}
+	if err2 != nil {
+		return err2
+	}
+
+	cli := newUserServiceClient()
+	switch action {
+	case "start":
+		return cli.ServicesStart(services)
+	case "stop":
+		return cli.ServicesStop(services)
+	case "restart":
+		return cli.ServicesRestart(services)
+	}
+	return fmt.Errorf("internal error: unknown user service action %q", action)
+}
@@ -1,7 +1,7 @@
 // -*- Mode: Go; indent-tabs-mode: t -*-
 
 /*
- * Copyright (C) 2015-2016 Canonical Ltd
+ * Copyright (C) 2015-2018 Canonical Ltd
  *
  * This program is free software: you can redistribute it and/or modify
  * it under the terms of the GNU General Public License version 3 as
@@ -21,14 +21,20 @@ package servicestate
 
 import (
 	"fmt"
+	"path/filepath"
 	"time"
 
 	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/cmdstate"
 	"github.com/snapcore/snapd/overlord/hookstate"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/progress"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/systemd"
 )
 
 type Instruction struct {
@@ -49,23 +55,31 @@ func Control(st *state.State, appInfos []*snap.AppInfo, inst *Instruction, conte
 	var tts []*state.TaskSet
 
 	var ctlcmds []string
+	// user services are enabled and disabled for all users, and
+	// started, stopped or restarted by the session agents
+	var userEnableCmd, userAction string
 	switch {
 	case inst.Action == "start":
 		if inst.Enable {
 			ctlcmds = []string{"enable"}
+			userEnableCmd = "enable"
 		}
 		ctlcmds = append(ctlcmds, "start")
+		userAction = "start"
 	case inst.Action == "stop":
 		if inst.Disable {
 			ctlcmds = []string{"disable"}
+			userEnableCmd = "disable"
 		}
 		ctlcmds = append(ctlcmds, "stop")
+		userAction = "stop"
 	case inst.Action == "restart":
 		if inst.Reload {
 			ctlcmds = []string{"reload-or-restart"}
 		} else {
 			ctlcmds = []string{"restart"}
 		}
+		userAction = "restart"
 	default:
 		return nil, fmt.Errorf("unknown action %q", inst.Action)
 	}
@@ -74,13 +88,19 @@ func Control(st *state.State, appInfos []*snap.AppInfo, inst *Instruction, conte
 	defer st.Unlock()
 
 	svcs := make([]string, 0, len(appInfos))
+	var userSvcs []string
 	snapNames := make([]string, 0, len(appInfos))
 	lastName := ""
-	names := make([]string, len(appInfos))
-	for i, svc := range appInfos {
-		svcs = append(svcs, svc.ServiceName())
+	var names, userNames []string
+	for _, svc := range appInfos {
 		snapName := svc.Snap.Name()
-		names[i] = snapName + "." + svc.Name
+		if svc.IsUserService() {
+			userSvcs = append(userSvcs, svc.ServiceName())
+			userNames = append(userNames, snapName+"."+svc.Name)
+		} else {
+			svcs = append(svcs, svc.ServiceName())
+			names = append(names, snapName+"."+svc.Name)
+		}
 		if snapName != lastName {
 			snapNames = append(snapNames, snapName)
 			lastName = snapName
@@ -105,16 +125,31 @@ func Control(st *state.State, appInfos []*snap.AppInfo, inst *Instruction, conte
 		return nil, &ServiceActionConflictError{err}
 	}
 
-	for _, cmd := range ctlcmds {
-		argv := append([]string{"systemctl", cmd}, svcs...)
-		desc := fmt.Sprintf("%s of %v", cmd, names)
-		// Give the systemctl a maximum time of 61 for now.
-		//
-		// Longer term we need to refactor this code and
-		// reuse the snapd/systemd and snapd/wrapper packages
-		// to control the timeout in a single place.
-		ts := cmdstate.ExecWithTimeout(st, desc, argv, 61*time.Second)
-		tts = append(tts, ts)
+	if len(svcs) > 0 {
+		for _, cmd := range ctlcmds {
+			argv := append([]string{"systemctl", cmd}, svcs...)
+			desc := fmt.Sprintf("%s of %v", cmd, names)
+			// Give the systemctl a maximum time of 61 for now.
+			//
+			// Longer term we need to refactor this code and
+			// reuse the snapd/systemd and snapd/wrapper packages
+			// to control the timeout in a single place.
+			ts := cmdstate.ExecWithTimeout(st, desc, argv, 61*time.Second)
+			tts = append(tts, ts)
+		}
+	}
+
+	if len(userSvcs) > 0 {
+		if userEnableCmd != "" {
+			argv := append([]string{"systemctl", "--user", "--global", userEnableCmd}, userSvcs...)
+			desc := fmt.Sprintf("%s of %v", userEnableCmd, userNames)
+			ts := cmdstate.ExecWithTimeout(st, desc, argv, 61*time.Second)
+			tts = append(tts, ts)
+		}
+		t := st.NewTask("user-service-control", fmt.Sprintf("%s of %v in user sessions", userAction, userNames))
+		t.Set("action", userAction)
+		t.Set("services", userSvcs)
+		tts = append(tts, state.NewTaskSet(t))
 	}
 
 	// make a taskset wait for its predecessor
@@ -124,3 +159,53 @@ func Control(st *state.State, appInfos []*snap.AppInfo, inst *Instruction, conte
 
 	return tts, nil
 }
+
+// ClientAppInfosFromSnapAppInfos returns client.AppInfos for the given
+// apps, with the current state of the services among them.
+func ClientAppInfosFromSnapAppInfos(apps []*snap.AppInfo) []client.AppInfo {
+	// TODO: pass in an actual notifier here instead of null
+	//       (Status doesn't _need_ it, but benefits from it)
+	sysd := systemd.New(dirs.GlobalRootDir, progress.Null)
+	userSysd := systemd.NewWithMode(dirs.GlobalRootDir, systemd.GlobalUserMode, progress.Null)
+
+	out := make([]client.AppInfo, len(apps))
+	for i, app := range apps {
+		out[i] = client.AppInfo{
+			Snap:     app.Snap.Name(),
+			Name:     app.Name,
+			CommonID: app.CommonID,
+		}
+		if fn := app.DesktopFile(); osutil.FileExists(fn) {
+			out[i].DesktopFile = fn
+		}
+
+		if app.IsUserService() {
+			out[i].Daemon = app.Daemon
+			out[i].DaemonScope = string(app.DaemonScope)
+			// user services run in each of the user sessions, so
+			// only whether they are enabled is known here
+			unit := app.ServiceName()
+			if app.Timer != nil {
+				unit = filepath.Base(app.Timer.File())
+			}
+			if enabled, err := userSysd.IsEnabled(unit); err != nil {
+				logger.Noticef("cannot get status of user service %q: %v", app.Name, err)
+			} else {
+				out[i].Enabled = enabled
+			}
+		} else if app.IsService() {
+			// TODO: look into making a single call to Status for all services
+			if sts, err := sysd.Status(app.ServiceName()); err != nil {
+				logger.Noticef("cannot get status of service %q: %v", app.Name, err)
+			} else if len(sts) != 1 {
+				logger.Noticef("cannot get status of service %q: expected 1 result, got %d", app.Name, len(sts))
+			} else {
+				out[i].Daemon = sts[0].Daemon
+				out[i].Enabled = sts[0].Enabled
+				out[i].Active = sts[0].Active
+			}
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,169 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package servicestate_test
+
+import (
+	"errors"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/overlord/servicestate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type serviceControlSuite struct {
+	state *state.State
+	mgr   *servicestate.ServiceManager
+
+	calls [][]string
+	err   error
+
+	restore func()
+}
+
+var _ = Suite(&serviceControlSuite{})
+
+func (s *serviceControlSuite) SetUpTest(c *C) {
+	s.state = state.New(nil)
+	s.mgr = servicestate.Manager(s.state)
+	s.calls = nil
+	s.err = nil
+	s.restore = servicestate.MockUserServiceClient(s)
+}
+
+func (s *serviceControlSuite) TearDownTest(c *C) {
+	s.restore()
+}
+
+func (s *serviceControlSuite) ServicesStart(services []string) error {
+	s.calls = append(s.calls, append([]string{"start"}, services...))
+	return s.err
+}
+
+func (s *serviceControlSuite) ServicesStop(services []string) error {
+	s.calls = append(s.calls, append([]string{"stop"}, services...))
+	return s.err
+}
+
+func (s *serviceControlSuite) ServicesRestart(services []string) error {
+	s.calls = append(s.calls, append([]string{"restart"}, services...))
+	return s.err
+}
+
+const snapYaml = `name: foo
+version: 1
+apps:
+  sys:
+    command: bin/sys
+    daemon: simple
+  usr:
+    command: bin/usr
+    daemon: simple
+    daemon-scope: user
+`
+
+func (s *serviceControlSuite) settle(c *C) {
+	for i := 0; i < 5; i++ {
+		c.Assert(s.mgr.Ensure(), IsNil)
+		s.mgr.Wait()
+	}
+}
+
+func (s *serviceControlSuite) TestControlUserServices(c *C) {
+	info := snaptest.MockInfo(c, snapYaml, &snap.SideInfo{Revision: snap.R(1)})
+	apps := []*snap.AppInfo{info.Apps["sys"], info.Apps["usr"]}
+
+	inst := &servicestate.Instruction{Action: "start", StartOptions: client.StartOptions{Enable: true}}
+	tts, err := servicestate.Control(s.state, apps, inst, nil)
+	c.Assert(err, IsNil)
+	c.Assert(tts, HasLen, 4)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	var argvs [][]string
+	for _, ts := range tts[:3] {
+		tasks := ts.Tasks()
+		c.Assert(tasks, HasLen, 1)
+		c.Check(tasks[0].Kind(), Equals, "exec-command")
+		var argv []string
+		c.Assert(tasks[0].Get("argv", &argv), IsNil)
+		argvs = append(argvs, argv)
+	}
+	c.Check(argvs, DeepEquals, [][]string{
+		{"systemctl", "enable", "snap.foo.sys.service"},
+		{"systemctl", "start", "snap.foo.sys.service"},
+		{"systemctl", "--user", "--global", "enable", "snap.foo.usr.service"},
+	})
+
+	tasks := tts[3].Tasks()
+	c.Assert(tasks, HasLen, 1)
+	c.Check(tasks[0].Kind(), Equals, "user-service-control")
+	c.Check(tasks[0].Summary(), Equals, "start of [foo.usr] in user sessions")
+	c.Check(tasks[0].WaitTasks(), DeepEquals, tts[2].Tasks())
+}
+
+func (s *serviceControlSuite) TestDoUserServiceControl(c *C) {
+	info := snaptest.MockInfo(c, snapYaml, &snap.SideInfo{Revision: snap.R(1)})
+
+	inst := &servicestate.Instruction{Action: "restart"}
+	tts, err := servicestate.Control(s.state, []*snap.AppInfo{info.Apps["usr"]}, inst, nil)
+	c.Assert(err, IsNil)
+	c.Assert(tts, HasLen, 1)
+
+	s.state.Lock()
+	chg := s.state.NewChange("restart", "...")
+	chg.AddAll(tts[0])
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(chg.Status(), Equals, state.DoneStatus)
+	c.Check(s.calls, DeepEquals, [][]string{{"restart", "snap.foo.usr.service"}})
+}
+
+func (s *serviceControlSuite) TestDoUserServiceControlError(c *C) {
+	s.err = errors.New("boom")
+	info := snaptest.MockInfo(c, snapYaml, &snap.SideInfo{Revision: snap.R(1)})
+
+	inst := &servicestate.Instruction{Action: "stop"}
+	tts, err := servicestate.Control(s.state, []*snap.AppInfo{info.Apps["usr"]}, inst, nil)
+	c.Assert(err, IsNil)
+
+	s.state.Lock()
+	chg := s.state.NewChange("stop", "...")
+	chg.AddAll(tts[0])
+	s.state.Unlock()
+
+	s.settle(c)
+
+	s.state.Lock()
+	defer s.state.Unlock()
+	c.Check(chg.Status(), Equals, state.ErrorStatus)
+	c.Check(chg.Err(), ErrorMatches, `(?s).*boom.*`)
+}
@@ -153,8 +153,14 @@ func Filename(snapshot *client.Snapshot) string {
 	return filepath.Join(dirs.SnapshotsDir, fmt.Sprintf("%d_%s_%s_%s.zip", snapshot.SetID, snapshot.Snap, snapshot.Version, snapshot.Revision))
 }
 
+// Flags encompasses extra flags for snapshots backend Save.
+type Flags struct {
+	// Auto marks the snapshot as taken automatically, e.g. on removal
+	Auto bool
+}
+
 // Save a snapshot
-func Save(ctx context.Context, id uint64, si *snap.Info, cfg map[string]interface{}, usernames []string) (*client.Snapshot, error) {
+func Save(ctx context.Context, id uint64, si *snap.Info, cfg map[string]interface{}, usernames []string, flags *Flags) (*client.Snapshot, error) {
 	if err := os.MkdirAll(dirs.SnapshotsDir, 0700); err != nil {
 		return nil, err
 	}
@@ -168,6 +174,7 @@ func Save(ctx context.Context, id uint64, si *snap.Info, cfg map[string]interfac
 		SHA3_384: make(map[string]string),
 		Size:     0,
 		Conf:     cfg,
+		Auto:     flags != nil && flags.Auto,
 	}
 
 	aw, err := osutil.NewAtomicFile(Filename(snapshot), 0600, 0, osutil.NoChown, osutil.NoChown)
@@ -194,22 +201,9 @@ func Save(ctx context.Context, id uint64, si *snap.Info, cfg map[string]interfac
 		}
 	}
 
-	metaWriter, err := w.Create(metadataName)
-	if err != nil {
+	if err := addMetaToZip(snapshot, w); err != nil {
 		return nil, err
 	}
-
-	hasher := crypto.SHA3_384.New()
-	enc := json.NewEncoder(io.MultiWriter(metaWriter, hasher))
-	if err := enc.Encode(snapshot); err != nil {
-		return nil, err
-	}
-
-	hashWriter, err := w.Create(metaHashName)
-	if err != nil {
-		return nil, err
-	}
-	fmt.Fprintf(hashWriter, "%x\n", hasher.Sum(nil))
 	if err := w.Close(); err != nil {
 		return nil, err
 	}
@@ -225,6 +219,27 @@ func Save(ctx context.Context, id uint64, si *snap.Info, cfg map[string]interfac
 	return snapshot, nil
 }
 
+// addMetaToZip adds the snapshot's metadata, and its hash, to the zip.
+func addMetaToZip(snapshot *client.Snapshot, w *zip.Writer) error {
+	metaWriter, err := w.Create(metadataName)
+	if err != nil {
+		return err
+	}
+
+	hasher := crypto.SHA3_384.New()
+	enc := json.NewEncoder(io.MultiWriter(metaWriter, hasher))
+	if err := enc.Encode(snapshot); err != nil {
+		return err
+	}
+
+	hashWriter, err := w.Create(metaHashName)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(hashWriter, "%x\n", hasher.Sum(nil))
+	return err
+}
+
 func addDirToZip(ctx context.Context, snapshot *client.Snapshot, w *zip.Writer, username string, entry, dir string) error {
 	hasher := crypto.SHA3_384.New()
 	if exists, isDir, err := osutil.DirExists(dir); !exists || !isDir || err != nil {
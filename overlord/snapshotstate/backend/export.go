@@ -0,0 +1,294 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package backend
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+)
+
+const (
+	exportManifestName = "export.json"
+	exportFormat       = 1
+)
+
+// exportManifest is the first entry of an exported snapshot set, and
+// describes the snapshot files that follow it.
+type exportManifest struct {
+	Format int      `json:"format"`
+	SetID  uint64   `json:"set-id"`
+	Files  []string `json:"files"`
+}
+
+// Export writes the snapshots of the given set to w, as a tar stream
+// suitable for Import.
+func Export(ctx context.Context, setID uint64, w io.Writer) error {
+	var filenames []string
+	err := Iter(ctx, func(r *Reader) error {
+		if r.SetID == setID {
+			filenames = append(filenames, r.Name())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(filenames) == 0 {
+		return client.ErrSnapshotSetNotFound
+	}
+
+	manifest := exportManifest{
+		Format: exportFormat,
+		SetID:  setID,
+		Files:  make([]string, len(filenames)),
+	}
+	for i, fn := range filenames {
+		manifest.Files[i] = filepath.Base(fn)
+	}
+	buf, err := json.Marshal(&manifest)
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(w)
+	now := time.Now()
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    exportManifestName,
+		Mode:    0600,
+		Size:    int64(len(buf)),
+		ModTime: now,
+	}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(buf); err != nil {
+		return err
+	}
+
+	for i, fn := range filenames {
+		if err := exportOne(ctx, tw, manifest.Files[i], fn); err != nil {
+			return err
+		}
+	}
+
+	return tw.Close()
+}
+
+func exportOne(ctx context.Context, tw *tar.Writer, name, fn string) error {
+	f, err := os.Open(fn)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    fi.Size(),
+		ModTime: fi.ModTime(),
+	}); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(io.MultiWriter(osutil.ContextWriter(ctx), tw), f)
+	return err
+}
+
+// Import reads a snapshot set, as written by Export, from r, and adds
+// it to the snapshots directory under the given set ID. Every snapshot
+// in the set is checked before any of them is added.
+func Import(ctx context.Context, id uint64, r io.Reader) (snapNames []string, err error) {
+	if err := os.MkdirAll(dirs.SnapshotsDir, 0700); err != nil {
+		return nil, err
+	}
+
+	tr := tar.NewReader(r)
+	hdr, err := tr.Next()
+	if err != nil {
+		return nil, fmt.Errorf("cannot read snapshot export: %v", err)
+	}
+	if hdr.Name != exportManifestName {
+		return nil, fmt.Errorf("cannot import snapshot: expected %q, got %q", exportManifestName, hdr.Name)
+	}
+	var manifest exportManifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("cannot decode snapshot export manifest: %v", err)
+	}
+	if manifest.Format != exportFormat {
+		return nil, fmt.Errorf("cannot import snapshot: unsupported export format %d", manifest.Format)
+	}
+	if len(manifest.Files) == 0 {
+		return nil, fmt.Errorf("cannot import snapshot: export has no snapshots")
+	}
+
+	pending := make(map[string]bool, len(manifest.Files))
+	for _, name := range manifest.Files {
+		if name != filepath.Base(name) || !strings.HasSuffix(name, ".zip") || pending[name] {
+			return nil, fmt.Errorf("cannot import snapshot: invalid file %q in export manifest", name)
+		}
+		pending[name] = true
+	}
+
+	var pendingFiles []*osutil.AtomicFile
+	var targets, committed []string
+	defer func() {
+		// Cancel is a NOP for the ones that got committed
+		for _, aw := range pendingFiles {
+			aw.Cancel()
+		}
+		if err != nil {
+			for _, fn := range committed {
+				os.Remove(fn)
+			}
+		}
+	}()
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot read snapshot export: %v", err)
+		}
+		if !pending[hdr.Name] {
+			return nil, fmt.Errorf("cannot import snapshot: unexpected file %q in export", hdr.Name)
+		}
+		delete(pending, hdr.Name)
+
+		aw, snapshot, err := importOne(ctx, id, manifest.SetID, tr)
+		if aw != nil {
+			pendingFiles = append(pendingFiles, aw)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot import snapshot %q: %v", hdr.Name, err)
+		}
+		targets = append(targets, Filename(snapshot))
+		snapNames = append(snapNames, snapshot.Snap)
+	}
+
+	for name := range pending {
+		return nil, fmt.Errorf("cannot import snapshot: export is missing %q", name)
+	}
+
+	for i, aw := range pendingFiles {
+		if err := aw.Commit(); err != nil {
+			return nil, err
+		}
+		committed = append(committed, targets[i])
+	}
+
+	return snapNames, nil
+}
+
+// importOne checks the snapshot read from r, and writes it out with the
+// new set ID into a pending atomic file for the caller to commit.
+func importOne(ctx context.Context, id, exportedID uint64, r io.Reader) (*osutil.AtomicFile, *client.Snapshot, error) {
+	tmp, err := ioutil.TempFile(dirs.SnapshotsDir, ".import-")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tmp.Close()
+	// only the open file is needed from here on
+	if err := os.Remove(tmp.Name()); err != nil {
+		return nil, nil, err
+	}
+
+	size, err := io.Copy(io.MultiWriter(osutil.ContextWriter(ctx), tmp), r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader, err := newReader(tmp)
+	if err != nil {
+		return nil, nil, err
+	}
+	if reader.SetID != exportedID {
+		return nil, nil, fmt.Errorf("snapshot is from set #%d, not #%d", reader.SetID, exportedID)
+	}
+	if err := reader.Check(ctx, nil); err != nil {
+		return nil, nil, err
+	}
+
+	snapshot := reader.Snapshot
+	snapshot.SetID = id
+
+	zr, err := zip.NewReader(tmp, size)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	aw, err := osutil.NewAtomicFile(Filename(&snapshot), 0600, 0, osutil.NoChown, osutil.NoChown)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	w := zip.NewWriter(aw)
+	for _, f := range zr.File {
+		if f.Name == metadataName || f.Name == metaHashName {
+			continue
+		}
+		if err := copyZipMember(w, f); err != nil {
+			return aw, nil, err
+		}
+	}
+	if err := addMetaToZip(&snapshot, w); err != nil {
+		return aw, nil, err
+	}
+	if err := w.Close(); err != nil {
+		return aw, nil, err
+	}
+
+	return aw, &snapshot, nil
+}
+
+func copyZipMember(w *zip.Writer, f *zip.File) error {
+	body, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	hdr := f.FileHeader
+	fw, err := w.CreateHeader(&hdr)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fw, body)
+	return err
+}
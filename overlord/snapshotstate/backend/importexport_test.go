@@ -0,0 +1,158 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package backend_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/net/context"
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (s *snapshotSuite) saveForExport(c *check.C, setID uint64) *client.Snapshot {
+	info := &snap.Info{SideInfo: snap.SideInfo{RealName: "hello-snap", Revision: snap.R(42)}, Version: "v1.33"}
+	cfg := map[string]interface{}{"some-setting": false}
+
+	shw, err := backend.Save(context.TODO(), setID, info, cfg, nil, nil)
+	c.Assert(err, check.IsNil)
+	return shw
+}
+
+func (s *snapshotSuite) TestExportImportRoundtrip(c *check.C) {
+	shw := s.saveForExport(c, 12)
+
+	var buf bytes.Buffer
+	c.Assert(backend.Export(context.TODO(), 12, &buf), check.IsNil)
+
+	snapNames, err := backend.Import(context.TODO(), 13, &buf)
+	c.Assert(err, check.IsNil)
+	c.Check(snapNames, check.DeepEquals, []string{"hello-snap"})
+
+	sets, err := backend.List(context.TODO(), 0, nil)
+	c.Assert(err, check.IsNil)
+	c.Assert(sets, check.HasLen, 2)
+	c.Check(sets[0].ID, check.Equals, uint64(12))
+	c.Check(sets[1].ID, check.Equals, uint64(13))
+
+	shr, err := backend.Open(filepath.Join(dirs.SnapshotsDir, "13_hello-snap_v1.33_42.zip"))
+	c.Assert(err, check.IsNil)
+	defer shr.Close()
+
+	c.Check(shr.SetID, check.Equals, uint64(13))
+	c.Check(shr.Snap, check.Equals, shw.Snap)
+	c.Check(shr.Revision, check.Equals, shw.Revision)
+	c.Check(shr.Conf, check.DeepEquals, shw.Conf)
+	c.Check(shr.SHA3_384, check.DeepEquals, shw.SHA3_384)
+	c.Check(shr.Check(context.TODO(), nil), check.IsNil)
+}
+
+func (s *snapshotSuite) TestExportNotFound(c *check.C) {
+	var buf bytes.Buffer
+	c.Check(backend.Export(context.TODO(), 42, &buf), check.Equals, client.ErrSnapshotSetNotFound)
+	c.Check(buf.Len(), check.Equals, 0)
+}
+
+// tamper rewrites the given export, replacing the content of the first
+// snapshot with the output of the given function
+func tamper(c *check.C, export []byte, f func([]byte) []byte) []byte {
+	tr := tar.NewReader(bytes.NewReader(export))
+	var out bytes.Buffer
+	tw := tar.NewWriter(&out)
+	n := 0
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		data, err := ioutil.ReadAll(tr)
+		c.Assert(err, check.IsNil)
+		if n == 1 {
+			data = f(data)
+			hdr.Size = int64(len(data))
+		}
+		n++
+		c.Assert(tw.WriteHeader(hdr), check.IsNil)
+		_, err = tw.Write(data)
+		c.Assert(err, check.IsNil)
+	}
+	c.Assert(tw.Close(), check.IsNil)
+	return out.Bytes()
+}
+
+func (s *snapshotSuite) TestImportBadSnapshot(c *check.C) {
+	s.saveForExport(c, 12)
+
+	var buf bytes.Buffer
+	c.Assert(backend.Export(context.TODO(), 12, &buf), check.IsNil)
+
+	bad := tamper(c, buf.Bytes(), func([]byte) []byte { return []byte("not a zip") })
+	_, err := backend.Import(context.TODO(), 13, bytes.NewReader(bad))
+	c.Check(err, check.ErrorMatches, `cannot import snapshot "12_hello-snap_v1.33_42.zip": .*`)
+
+	// nothing was left behind
+	fns, err := filepath.Glob(filepath.Join(dirs.SnapshotsDir, "*"))
+	c.Assert(err, check.IsNil)
+	c.Check(fns, check.DeepEquals, []string{filepath.Join(dirs.SnapshotsDir, "12_hello-snap_v1.33_42.zip")})
+}
+
+func (s *snapshotSuite) TestImportMissingSnapshot(c *check.C) {
+	s.saveForExport(c, 12)
+
+	var buf bytes.Buffer
+	c.Assert(backend.Export(context.TODO(), 12, &buf), check.IsNil)
+
+	// drop everything but the manifest
+	tr := tar.NewReader(&buf)
+	hdr, err := tr.Next()
+	c.Assert(err, check.IsNil)
+	manifest, err := ioutil.ReadAll(tr)
+	c.Assert(err, check.IsNil)
+	var out bytes.Buffer
+	tw := tar.NewWriter(&out)
+	c.Assert(tw.WriteHeader(hdr), check.IsNil)
+	_, err = tw.Write(manifest)
+	c.Assert(err, check.IsNil)
+	c.Assert(tw.Close(), check.IsNil)
+
+	_, err = backend.Import(context.TODO(), 13, &out)
+	c.Check(err, check.ErrorMatches, `cannot import snapshot: export is missing "12_hello-snap_v1.33_42.zip"`)
+}
+
+func (s *snapshotSuite) TestImportNotAnExport(c *check.C) {
+	_, err := backend.Import(context.TODO(), 13, bytes.NewBufferString("hello"))
+	c.Check(err, check.ErrorMatches, `cannot read snapshot export: .*`)
+
+	c.Assert(os.MkdirAll(dirs.SnapshotsDir, 0700), check.IsNil)
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	c.Assert(tw.WriteHeader(&tar.Header{Name: "foo.zip", Mode: 0600}), check.IsNil)
+	c.Assert(tw.Close(), check.IsNil)
+	_, err = backend.Import(context.TODO(), 13, &buf)
+	c.Check(err, check.ErrorMatches, `cannot import snapshot: expected "export.json", got "foo.zip"`)
+}
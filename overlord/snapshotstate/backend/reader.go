@@ -68,7 +68,15 @@ func Open(fn string) (reader *Reader, e error) {
 		}
 	}()
 
-	reader = &Reader{
+	return newReader(f)
+}
+
+// newReader loads and verifies the metadata of the snapshot in the given
+// file. As with Open, the returned Reader can be non-nil even if there is an
+// error, in which case it will have a non-empty Broken; unlike Open, the file
+// is left for the caller to close.
+func newReader(f *os.File) (*Reader, error) {
+	reader := &Reader{
 		File: f,
 	}
 
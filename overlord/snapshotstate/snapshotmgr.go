@@ -0,0 +1,399 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapshotstate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/net/context"
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+var (
+	osRemove             = os.Remove
+	snapstateCurrentInfo = snapstate.CurrentInfo
+	configGetSnapConfig  = config.GetSnapConfig
+	configSetSnapConfig  = config.SetSnapConfig
+	backendOpen          = backend.Open
+	backendSave          = backend.Save
+	backendRestore       = (*backend.Reader).Restore
+	backendCheck         = (*backend.Reader).Check
+	backendRevert        = (*backend.RestoreState).Revert
+	backendCleanup       = (*backend.RestoreState).Cleanup
+
+	timeNow = time.Now
+
+	// how often to look for automatic snapshots that have expired
+	forgetExpiredSnapshotsDelay = 24 * time.Hour
+)
+
+// SnapshotManager takes snapshots of active snaps, and checks,
+// restores and forgets them on request.
+type SnapshotManager struct {
+	state  *state.State
+	runner *state.TaskRunner
+
+	nextForgetExpired time.Time
+}
+
+// Manager returns a new SnapshotManager.
+func Manager(st *state.State) *SnapshotManager {
+	runner := state.NewTaskRunner(st)
+
+	runner.AddHandler("save-snapshot", doSave, doForget)
+	runner.AddHandler("forget-snapshot", doForget, nil)
+	runner.AddHandler("check-snapshot", doCheck, nil)
+	runner.AddHandler("restore-snapshot", doRestore, undoRestore)
+	runner.AddCleanup("restore-snapshot", cleanupRestore)
+
+	// check and forget don't affect the snaps themselves, so only
+	// save and restore need to conflict with snapstate operations
+	snapstate.AddAffectedSnapsByKind("save-snapshot", affectedSnaps)
+	snapstate.AddAffectedSnapsByKind("restore-snapshot", affectedSnaps)
+
+	snapstate.AutomaticSnapshot = AutomaticSnapshot
+
+	return &SnapshotManager{
+		state:  st,
+		runner: runner,
+	}
+}
+
+// KnownTaskKinds is part of the overlord.StateManager interface.
+func (m *SnapshotManager) KnownTaskKinds() []string {
+	return m.runner.KnownTaskKinds()
+}
+
+// Ensure is part of the overlord.StateManager interface.
+func (m *SnapshotManager) Ensure() error {
+	err := m.forgetExpiredSnapshots()
+	m.runner.Ensure()
+	return err
+}
+
+// Wait is part of the overlord.StateManager interface.
+func (m *SnapshotManager) Wait() {
+	m.runner.Wait()
+}
+
+// Stop is part of the overlord.StateManager interface.
+func (m *SnapshotManager) Stop() {
+	m.runner.Stop()
+}
+
+// forgetExpiredSnapshots removes the automatic snapshots that are
+// older than the configured retention; it only looks for them once
+// every forgetExpiredSnapshotsDelay.
+func (m *SnapshotManager) forgetExpiredSnapshots() error {
+	m.state.Lock()
+	defer m.state.Unlock()
+
+	now := timeNow()
+	if now.Before(m.nextForgetExpired) {
+		return nil
+	}
+	m.nextForgetExpired = now.Add(forgetExpiredSnapshotsDelay)
+
+	expiration, _, err := automaticSnapshotExpiration(m.state)
+	if err != nil {
+		return err
+	}
+	cutoff := now.Add(-expiration)
+
+	// as in newSnapshotSetID, iterating only reads the metadata, so
+	// keeping the state locked is ok
+	return backendIter(context.TODO(), func(r *backend.Reader) error {
+		if !r.Auto || !r.Time.Before(cutoff) {
+			return nil
+		}
+		if err := checkSnapshotTaskConflict(m.state, r.SetID, "check-snapshot", "restore-snapshot"); err != nil {
+			// in use; try again next time
+			return nil
+		}
+		if err := osRemove(r.Name()); err != nil && !os.IsNotExist(err) {
+			logger.Noticef("Cannot remove expired automatic snapshot %q: %v", r.Name(), err)
+		}
+		return nil
+	})
+}
+
+func affectedSnaps(t *state.Task) ([]string, error) {
+	var snapshot snapshotSetup
+	if err := t.Get("snapshot-setup", &snapshot); err != nil {
+		return nil, taskGetErrMsg(t, err, "snapshot")
+	}
+
+	return []string{snapshot.Snap}, nil
+}
+
+type snapshotSetup struct {
+	SetID    uint64   `json:"set-id"`
+	Snap     string   `json:"snap"`
+	Users    []string `json:"users,omitempty"`
+	Filename string   `json:"filename,omitempty"`
+	Auto     bool     `json:"auto,omitempty"`
+}
+
+func filename(setID uint64, si *snap.Info) string {
+	skel := &client.Snapshot{
+		SetID:    setID,
+		Snap:     si.Name(),
+		Revision: si.Revision,
+		Version:  si.Version,
+	}
+	return backend.Filename(skel)
+}
+
+// prepareSave does all the steps of doSave that require the state lock;
+// it has no real significance beyond making the lock handling simpler
+func prepareSave(task *state.Task) (snapshot *snapshotSetup, cur *snap.Info, cfg map[string]interface{}, err error) {
+	st := task.State()
+	st.Lock()
+	defer st.Unlock()
+
+	if err := task.Get("snapshot-setup", &snapshot); err != nil {
+		return nil, nil, nil, taskGetErrMsg(task, err, "snapshot")
+	}
+	cur, err = snapstateCurrentInfo(st, snapshot.Snap)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	// updating snapshot-setup with the filename, for use in undo
+	snapshot.Filename = filename(snapshot.SetID, cur)
+	task.Set("snapshot-setup", &snapshot)
+
+	rawCfg, err := configGetSnapConfig(st, snapshot.Snap)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if rawCfg != nil {
+		if err := json.Unmarshal(*rawCfg, &cfg); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	return snapshot, cur, cfg, nil
+}
+
+func doSave(task *state.Task, tomb *tomb.Tomb) error {
+	snapshot, cur, cfg, err := prepareSave(task)
+	if err != nil {
+		return err
+	}
+	_, err = backendSave(tomb.Context(nil), snapshot.SetID, cur, cfg, snapshot.Users, &backend.Flags{Auto: snapshot.Auto})
+	return err
+}
+
+// prepareRestore does the steps of doRestore that require the state lock
+// before the backend Restore call.
+func prepareRestore(task *state.Task) (snapshot *snapshotSetup, oldCfg map[string]interface{}, reader *backend.Reader, err error) {
+	st := task.State()
+
+	st.Lock()
+	defer st.Unlock()
+
+	if err := task.Get("snapshot-setup", &snapshot); err != nil {
+		return nil, nil, nil, taskGetErrMsg(task, err, "snapshot")
+	}
+
+	rawCfg, err := configGetSnapConfig(st, snapshot.Snap)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("cannot obtain current snap config for snapshot restore: %v", err)
+	}
+
+	if rawCfg != nil {
+		if err := json.Unmarshal(*rawCfg, &oldCfg); err != nil {
+			return nil, nil, nil, fmt.Errorf("cannot decode current snap config: %v", err)
+		}
+	}
+
+	reader, err = backendOpen(snapshot.Filename)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("cannot open snapshot: %v", err)
+	}
+	// note given the Open succeeded, caller needs to close it when done
+
+	return snapshot, oldCfg, reader, nil
+}
+
+// marshalSnapConfig encodes cfg to JSON and returns the raw JSON
+// message, unless cfg is nil, in which case nil is returned.
+func marshalSnapConfig(cfg map[string]interface{}) (*json.RawMessage, error) {
+	if cfg == nil {
+		// do not marshal nil, as that would result in a "null" raw
+		// message, which we want to avoid.
+		return nil, nil
+	}
+	buf, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	raw := json.RawMessage(buf)
+	return &raw, nil
+}
+
+func doRestore(task *state.Task, tomb *tomb.Tomb) error {
+	snapshot, oldCfg, reader, err := prepareRestore(task)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	st := task.State()
+	logf := func(format string, args ...interface{}) {
+		st.Lock()
+		defer st.Unlock()
+		task.Logf(format, args...)
+	}
+
+	restoreState, err := backendRestore(reader, tomb.Context(nil), snapshot.Users, logf)
+	if err != nil {
+		return err
+	}
+
+	raw, err := marshalSnapConfig(reader.Conf)
+	if err != nil {
+		backendRevert(restoreState)
+		return fmt.Errorf("cannot marshal saved config: %v", err)
+	}
+
+	st.Lock()
+	defer st.Unlock()
+
+	if err := configSetSnapConfig(st, snapshot.Snap, raw); err != nil {
+		backendRevert(restoreState)
+		return fmt.Errorf("cannot set snap config: %v", err)
+	}
+
+	restoreState.Config = oldCfg
+	task.Set("restore-state", restoreState)
+
+	return nil
+}
+
+func undoRestore(task *state.Task, _ *tomb.Tomb) error {
+	var restoreState backend.RestoreState
+	var snapshot snapshotSetup
+
+	st := task.State()
+	st.Lock()
+	defer st.Unlock()
+
+	if err := task.Get("restore-state", &restoreState); err != nil {
+		return taskGetErrMsg(task, err, "snapshot restore")
+	}
+	if err := task.Get("snapshot-setup", &snapshot); err != nil {
+		return taskGetErrMsg(task, err, "snapshot")
+	}
+
+	raw, err := marshalSnapConfig(restoreState.Config)
+	if err != nil {
+		return fmt.Errorf("cannot marshal saved config: %v", err)
+	}
+
+	if err := configSetSnapConfig(st, snapshot.Snap, raw); err != nil {
+		return fmt.Errorf("cannot restore saved config: %v", err)
+	}
+
+	backendRevert(&restoreState)
+
+	return nil
+}
+
+func cleanupRestore(task *state.Task, _ *tomb.Tomb) error {
+	var restoreState backend.RestoreState
+
+	st := task.State()
+	st.Lock()
+	status := task.Status()
+	err := task.Get("restore-state", &restoreState)
+	st.Unlock()
+
+	if status != state.DoneStatus {
+		// only need to clean up restores that worked
+		return nil
+	}
+
+	if err != nil {
+		// this is bad: we somehow lost the information to restore things
+		// but if we return the error we'll just get called again :-(
+		logger.Noticef("%v", taskGetErrMsg(task, err, "snapshot restore"))
+		return nil
+	}
+
+	backendCleanup(&restoreState)
+
+	return nil
+}
+
+func doCheck(task *state.Task, tomb *tomb.Tomb) error {
+	var snapshot snapshotSetup
+
+	st := task.State()
+	st.Lock()
+	err := task.Get("snapshot-setup", &snapshot)
+	st.Unlock()
+	if err != nil {
+		return taskGetErrMsg(task, err, "snapshot")
+	}
+
+	reader, err := backendOpen(snapshot.Filename)
+	if err != nil {
+		return fmt.Errorf("cannot open snapshot: %v", err)
+	}
+	defer reader.Close()
+
+	return backendCheck(reader, tomb.Context(nil), snapshot.Users)
+}
+
+func doForget(task *state.Task, _ *tomb.Tomb) error {
+	// note this is also undoSave
+	st := task.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var snapshot snapshotSetup
+	err := task.Get("snapshot-setup", &snapshot)
+
+	if err != nil {
+		return taskGetErrMsg(task, err, "snapshot")
+	}
+
+	if snapshot.Filename == "" {
+		return fmt.Errorf("internal error: task %s (%s) snapshot info is missing the filename", task.ID(), task.Kind())
+	}
+
+	if err := osRemove(snapshot.Filename); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,465 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapshotstate_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"golang.org/x/net/context"
+	"gopkg.in/check.v1"
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/snapshotstate"
+	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+type snapshotMgrSuite struct {
+	st *state.State
+}
+
+var _ = check.Suite(&snapshotMgrSuite{})
+
+func (s *snapshotMgrSuite) SetUpTest(c *check.C) {
+	dirs.SetRootDir(c.MkDir())
+	s.st = state.New(nil)
+}
+
+func (s *snapshotMgrSuite) TearDownTest(c *check.C) {
+	dirs.SetRootDir("")
+}
+
+func (s *snapshotMgrSuite) TestManager(c *check.C) {
+	mgr := snapshotstate.Manager(s.st)
+	kinds := mgr.KnownTaskKinds()
+	sort.Strings(kinds)
+	c.Check(kinds, check.DeepEquals, []string{
+		"check-snapshot",
+		"forget-snapshot",
+		"restore-snapshot",
+		"save-snapshot",
+	})
+}
+
+func (s *snapshotMgrSuite) newTask(kind string, snapshot map[string]interface{}) *state.Task {
+	s.st.Lock()
+	defer s.st.Unlock()
+
+	task := s.st.NewTask(kind, "...")
+	task.Set("snapshot-setup", snapshot)
+	return task
+}
+
+func (s *snapshotMgrSuite) TestDoSave(c *check.C) {
+	defer snapshotstate.MockSnapstateCurrentInfo(func(_ *state.State, snapname string) (*snap.Info, error) {
+		c.Check(snapname, check.Equals, "a-snap")
+		return &snap.Info{
+			SideInfo: snap.SideInfo{
+				RealName: "a-snap",
+				Revision: snap.R(-1),
+			},
+			Version: "1.33",
+		}, nil
+	})()
+	defer snapshotstate.MockConfigGetSnapConfig(func(_ *state.State, snapname string) (*json.RawMessage, error) {
+		c.Check(snapname, check.Equals, "a-snap")
+		buf := json.RawMessage(`{"hello": "there"}`)
+		return &buf, nil
+	})()
+	defer snapshotstate.MockBackendSave(func(_ context.Context, id uint64, si *snap.Info, cfg map[string]interface{}, usernames []string, flags *backend.Flags) (*client.Snapshot, error) {
+		c.Check(id, check.Equals, uint64(42))
+		c.Check(flags, check.DeepEquals, &backend.Flags{Auto: false})
+		c.Check(si.Name(), check.Equals, "a-snap")
+		c.Check(cfg, check.DeepEquals, map[string]interface{}{"hello": "there"})
+		c.Check(usernames, check.DeepEquals, []string{"a-user", "b-user"})
+		return nil, nil
+	})()
+
+	task := s.newTask("save-snapshot", map[string]interface{}{
+		"set-id": 42,
+		"snap":   "a-snap",
+		"users":  []string{"a-user", "b-user"},
+	})
+	err := snapshotstate.DoSave(task, &tomb.Tomb{})
+	c.Assert(err, check.IsNil)
+
+	// the filename is recorded for undo
+	s.st.Lock()
+	defer s.st.Unlock()
+	var snapshot map[string]interface{}
+	c.Assert(task.Get("snapshot-setup", &snapshot), check.IsNil)
+	c.Check(snapshot["filename"], check.Equals, filepath.Join(dirs.SnapshotsDir, "42_a-snap_1.33_x1.zip"))
+}
+
+func (s *snapshotMgrSuite) TestDoSaveAuto(c *check.C) {
+	defer snapshotstate.MockSnapstateCurrentInfo(func(*state.State, string) (*snap.Info, error) {
+		return &snap.Info{SideInfo: snap.SideInfo{RealName: "a-snap", Revision: snap.R(1)}}, nil
+	})()
+	defer snapshotstate.MockConfigGetSnapConfig(func(*state.State, string) (*json.RawMessage, error) {
+		return nil, nil
+	})()
+	saved := false
+	defer snapshotstate.MockBackendSave(func(_ context.Context, _ uint64, _ *snap.Info, _ map[string]interface{}, _ []string, flags *backend.Flags) (*client.Snapshot, error) {
+		saved = true
+		c.Check(flags, check.DeepEquals, &backend.Flags{Auto: true})
+		return nil, nil
+	})()
+
+	task := s.newTask("save-snapshot", map[string]interface{}{
+		"set-id": 42,
+		"snap":   "a-snap",
+		"auto":   true,
+	})
+	c.Assert(snapshotstate.DoSave(task, &tomb.Tomb{}), check.IsNil)
+	c.Check(saved, check.Equals, true)
+}
+
+func (s *snapshotMgrSuite) TestDoSaveFailsWithNoSnap(c *check.C) {
+	defer snapshotstate.MockSnapstateCurrentInfo(func(*state.State, string) (*snap.Info, error) {
+		return nil, errors.New("bzzt")
+	})()
+	defer snapshotstate.MockBackendSave(func(context.Context, uint64, *snap.Info, map[string]interface{}, []string, *backend.Flags) (*client.Snapshot, error) {
+		c.Fatal("backend.Save called")
+		return nil, nil
+	})()
+
+	task := s.newTask("save-snapshot", map[string]interface{}{"set-id": 42, "snap": "a-snap"})
+	err := snapshotstate.DoSave(task, &tomb.Tomb{})
+	c.Assert(err, check.ErrorMatches, "bzzt")
+}
+
+func (s *snapshotMgrSuite) TestDoSaveFailsBadConfig(c *check.C) {
+	defer snapshotstate.MockSnapstateCurrentInfo(func(*state.State, string) (*snap.Info, error) {
+		return &snap.Info{SideInfo: snap.SideInfo{RealName: "a-snap", Revision: snap.R(1)}}, nil
+	})()
+	defer snapshotstate.MockConfigGetSnapConfig(func(*state.State, string) (*json.RawMessage, error) {
+		buf := json.RawMessage(`"hello-there"`)
+		return &buf, nil
+	})()
+
+	task := s.newTask("save-snapshot", map[string]interface{}{"set-id": 42, "snap": "a-snap"})
+	err := snapshotstate.DoSave(task, &tomb.Tomb{})
+	c.Assert(err, check.ErrorMatches, ".* cannot unmarshal .*")
+}
+
+func (s *snapshotMgrSuite) TestDoSaveFailsNoTaskInfo(c *check.C) {
+	s.st.Lock()
+	task := s.st.NewTask("save-snapshot", "...")
+	s.st.Unlock()
+
+	err := snapshotstate.DoSave(task, &tomb.Tomb{})
+	c.Assert(err, check.ErrorMatches, `internal error: task 1 \(save-snapshot\) is missing snapshot information`)
+}
+
+func (s *snapshotMgrSuite) TestDoForget(c *check.C) {
+	var removed string
+	defer snapshotstate.MockOsRemove(func(fn string) error {
+		removed = fn
+		return nil
+	})()
+
+	task := s.newTask("forget-snapshot", map[string]interface{}{
+		"set-id":   42,
+		"snap":     "a-snap",
+		"filename": "/some/file.zip",
+	})
+	c.Assert(snapshotstate.DoForget(task, &tomb.Tomb{}), check.IsNil)
+	c.Check(removed, check.Equals, "/some/file.zip")
+}
+
+func (s *snapshotMgrSuite) TestDoForgetIgnoresMissing(c *check.C) {
+	task := s.newTask("forget-snapshot", map[string]interface{}{
+		"set-id":   42,
+		"snap":     "a-snap",
+		"filename": filepath.Join(c.MkDir(), "nonexistent.zip"),
+	})
+	c.Check(snapshotstate.DoForget(task, &tomb.Tomb{}), check.IsNil)
+}
+
+func (s *snapshotMgrSuite) TestDoForgetFails(c *check.C) {
+	defer snapshotstate.MockOsRemove(func(string) error {
+		return errors.New("bzzt")
+	})()
+
+	task := s.newTask("forget-snapshot", map[string]interface{}{
+		"set-id":   42,
+		"snap":     "a-snap",
+		"filename": "/some/file.zip",
+	})
+	c.Check(snapshotstate.DoForget(task, &tomb.Tomb{}), check.ErrorMatches, "bzzt")
+
+	task = s.newTask("forget-snapshot", map[string]interface{}{
+		"set-id": 42,
+		"snap":   "a-snap",
+	})
+	c.Check(snapshotstate.DoForget(task, &tomb.Tomb{}), check.ErrorMatches,
+		`internal error: task \d+ \(forget-snapshot\) snapshot info is missing the filename`)
+}
+
+func (s *snapshotMgrSuite) mockOpen(c *check.C, conf map[string]interface{}) (restore func()) {
+	fn := filepath.Join(c.MkDir(), "a-snap.zip")
+	return snapshotstate.MockBackendOpen(func(filename string) (*backend.Reader, error) {
+		fh, err := os.Create(fn)
+		c.Assert(err, check.IsNil)
+		return &backend.Reader{
+			File: fh,
+			Snapshot: client.Snapshot{
+				Snap: "a-snap",
+				Conf: conf,
+			},
+		}, nil
+	})
+}
+
+func (s *snapshotMgrSuite) TestDoRestore(c *check.C) {
+	defer snapshotstate.MockConfigGetSnapConfig(func(*state.State, string) (*json.RawMessage, error) {
+		buf := json.RawMessage(`{"old": "conf"}`)
+		return &buf, nil
+	})()
+	var newConf string
+	defer snapshotstate.MockConfigSetSnapConfig(func(_ *state.State, snapname string, raw *json.RawMessage) error {
+		c.Check(snapname, check.Equals, "a-snap")
+		newConf = string(*raw)
+		return nil
+	})()
+	defer s.mockOpen(c, map[string]interface{}{"new": "conf"})()
+	defer snapshotstate.MockBackendRestore(func(_ *backend.Reader, _ context.Context, users []string, _ backend.Logf) (*backend.RestoreState, error) {
+		c.Check(users, check.DeepEquals, []string{"a-user"})
+		return &backend.RestoreState{Created: []string{"/some/dir"}}, nil
+	})()
+	defer snapshotstate.MockBackendRevert(func(*backend.RestoreState) {
+		c.Fatal("backend.Revert called")
+	})()
+
+	task := s.newTask("restore-snapshot", map[string]interface{}{
+		"set-id":   42,
+		"snap":     "a-snap",
+		"users":    []string{"a-user"},
+		"filename": "/some/file.zip",
+	})
+	c.Assert(snapshotstate.DoRestore(task, &tomb.Tomb{}), check.IsNil)
+	c.Check(newConf, check.Equals, `{"new":"conf"}`)
+
+	s.st.Lock()
+	defer s.st.Unlock()
+	var restoreState backend.RestoreState
+	c.Assert(task.Get("restore-state", &restoreState), check.IsNil)
+	c.Check(restoreState, check.DeepEquals, backend.RestoreState{
+		Created: []string{"/some/dir"},
+		Config:  map[string]interface{}{"old": "conf"},
+	})
+}
+
+func (s *snapshotMgrSuite) TestDoRestoreFailsOpen(c *check.C) {
+	defer snapshotstate.MockConfigGetSnapConfig(func(*state.State, string) (*json.RawMessage, error) {
+		return nil, nil
+	})()
+	defer snapshotstate.MockBackendOpen(func(string) (*backend.Reader, error) {
+		return nil, errors.New("bzzt")
+	})()
+
+	task := s.newTask("restore-snapshot", map[string]interface{}{
+		"set-id":   42,
+		"snap":     "a-snap",
+		"filename": "/some/file.zip",
+	})
+	c.Check(snapshotstate.DoRestore(task, &tomb.Tomb{}), check.ErrorMatches, "cannot open snapshot: bzzt")
+}
+
+func (s *snapshotMgrSuite) TestDoRestoreFailsSetConfigReverts(c *check.C) {
+	defer snapshotstate.MockConfigGetSnapConfig(func(*state.State, string) (*json.RawMessage, error) {
+		return nil, nil
+	})()
+	defer snapshotstate.MockConfigSetSnapConfig(func(*state.State, string, *json.RawMessage) error {
+		return errors.New("bzzt")
+	})()
+	defer s.mockOpen(c, nil)()
+	defer snapshotstate.MockBackendRestore(func(*backend.Reader, context.Context, []string, backend.Logf) (*backend.RestoreState, error) {
+		return &backend.RestoreState{}, nil
+	})()
+	reverted := false
+	defer snapshotstate.MockBackendRevert(func(*backend.RestoreState) {
+		reverted = true
+	})()
+
+	task := s.newTask("restore-snapshot", map[string]interface{}{
+		"set-id":   42,
+		"snap":     "a-snap",
+		"filename": "/some/file.zip",
+	})
+	c.Check(snapshotstate.DoRestore(task, &tomb.Tomb{}), check.ErrorMatches, "cannot set snap config: bzzt")
+	c.Check(reverted, check.Equals, true)
+}
+
+func (s *snapshotMgrSuite) TestUndoRestore(c *check.C) {
+	var setConf string
+	defer snapshotstate.MockConfigSetSnapConfig(func(_ *state.State, _ string, raw *json.RawMessage) error {
+		setConf = string(*raw)
+		return nil
+	})()
+	var reverted *backend.RestoreState
+	defer snapshotstate.MockBackendRevert(func(rs *backend.RestoreState) {
+		reverted = rs
+	})()
+
+	task := s.newTask("restore-snapshot", map[string]interface{}{
+		"set-id":   42,
+		"snap":     "a-snap",
+		"filename": "/some/file.zip",
+	})
+	s.st.Lock()
+	task.Set("restore-state", &backend.RestoreState{
+		Created: []string{"/some/dir"},
+		Config:  map[string]interface{}{"old": "conf"},
+	})
+	s.st.Unlock()
+
+	c.Assert(snapshotstate.UndoRestore(task, &tomb.Tomb{}), check.IsNil)
+	c.Check(setConf, check.Equals, `{"old":"conf"}`)
+	c.Assert(reverted, check.NotNil)
+	c.Check(reverted.Created, check.DeepEquals, []string{"/some/dir"})
+}
+
+func (s *snapshotMgrSuite) TestCleanupRestore(c *check.C) {
+	cleanedUp := 0
+	defer snapshotstate.MockBackendCleanup(func(*backend.RestoreState) {
+		cleanedUp++
+	})()
+
+	task := s.newTask("restore-snapshot", map[string]interface{}{"set-id": 42, "snap": "a-snap"})
+	s.st.Lock()
+	task.Set("restore-state", &backend.RestoreState{})
+	s.st.Unlock()
+
+	// only done restores are cleaned up
+	c.Assert(snapshotstate.CleanupRestore(task, &tomb.Tomb{}), check.IsNil)
+	c.Check(cleanedUp, check.Equals, 0)
+
+	s.st.Lock()
+	task.SetStatus(state.DoneStatus)
+	s.st.Unlock()
+	c.Assert(snapshotstate.CleanupRestore(task, &tomb.Tomb{}), check.IsNil)
+	c.Check(cleanedUp, check.Equals, 1)
+}
+
+func (s *snapshotMgrSuite) TestDoCheck(c *check.C) {
+	defer s.mockOpen(c, nil)()
+	var checkedUsers []string
+	defer snapshotstate.MockBackendCheck(func(_ *backend.Reader, _ context.Context, users []string) error {
+		checkedUsers = users
+		return nil
+	})()
+
+	task := s.newTask("check-snapshot", map[string]interface{}{
+		"set-id":   42,
+		"snap":     "a-snap",
+		"users":    []string{"a-user"},
+		"filename": "/some/file.zip",
+	})
+	c.Assert(snapshotstate.DoCheck(task, &tomb.Tomb{}), check.IsNil)
+	c.Check(checkedUsers, check.DeepEquals, []string{"a-user"})
+}
+
+func (s *snapshotMgrSuite) TestDoCheckFails(c *check.C) {
+	defer s.mockOpen(c, nil)()
+	defer snapshotstate.MockBackendCheck(func(*backend.Reader, context.Context, []string) error {
+		return errors.New("bzzt")
+	})()
+
+	task := s.newTask("check-snapshot", map[string]interface{}{
+		"set-id":   42,
+		"snap":     "a-snap",
+		"filename": "/some/file.zip",
+	})
+	c.Check(snapshotstate.DoCheck(task, &tomb.Tomb{}), check.ErrorMatches, "bzzt")
+}
+
+func (s *snapshotMgrSuite) TestEnsureForgetsExpiredAutomaticSnapshots(c *check.C) {
+	now := time.Date(2018, 9, 1, 12, 0, 0, 0, time.UTC)
+	defer snapshotstate.MockTimeNow(func() time.Time { return now })()
+
+	dir := c.MkDir()
+	var snapshots []client.Snapshot
+	defer snapshotstate.MockBackendIter(func(_ context.Context, f func(*backend.Reader) error) error {
+		for _, shot := range snapshots {
+			fh, err := os.Create(filepath.Join(dir, fmt.Sprintf("%d_%s.zip", shot.SetID, shot.Snap)))
+			c.Assert(err, check.IsNil)
+			r := &backend.Reader{File: fh, Snapshot: shot}
+			err = f(r)
+			r.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})()
+	var removed []string
+	defer snapshotstate.MockOsRemove(func(fn string) error {
+		removed = append(removed, filepath.Base(fn))
+		return nil
+	})()
+
+	old := now.Add(-32 * 24 * time.Hour)
+	recent := now.Add(-time.Hour)
+	snapshots = []client.Snapshot{
+		{SetID: 1, Snap: "old-auto", Time: old, Auto: true},
+		{SetID: 2, Snap: "old-manual", Time: old},
+		{SetID: 3, Snap: "recent-auto", Time: recent, Auto: true},
+		{SetID: 4, Snap: "old-auto-in-use", Time: old, Auto: true},
+	}
+
+	s.st.Lock()
+	chg := s.st.NewChange("restore-snapshot", "...")
+	tsk := s.st.NewTask("restore-snapshot", "...")
+	tsk.Set("snapshot-setup", map[string]interface{}{"set-id": 4})
+	chg.AddTask(tsk)
+	s.st.Unlock()
+
+	mgr := snapshotstate.Manager(s.st)
+	c.Assert(mgr.Ensure(), check.IsNil)
+	c.Check(removed, check.DeepEquals, []string{"1_old-auto.zip"})
+
+	// it doesn't look again until a day has passed
+	removed = nil
+	c.Assert(mgr.Ensure(), check.IsNil)
+	c.Check(removed, check.HasLen, 0)
+
+	// with a shorter retention, more snapshots expire
+	s.st.Lock()
+	tr := config.NewTransaction(s.st)
+	tr.Set("core", "snapshots.automatic.retention", "30m")
+	tr.Commit()
+	chg.SetStatus(state.DoneStatus)
+	s.st.Unlock()
+
+	now = now.Add(25 * time.Hour)
+	c.Assert(mgr.Ensure(), check.IsNil)
+	c.Check(removed, check.DeepEquals, []string{"1_old-auto.zip", "3_recent-auto.zip", "4_old-auto-in-use.zip"})
+}
@@ -0,0 +1,410 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package snapshotstate implements the manager and state aspects
+// responsible for saving, checking, restoring and forgetting
+// snapshots of snap data.
+package snapshotstate
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
+)
+
+// how long automatic snapshots are kept unless configured otherwise
+const defaultAutomaticSnapshotExpiration = 31 * 24 * time.Hour
+
+var (
+	snapstateAll                     = snapstate.All
+	snapstateCheckChangeConflictMany = snapstate.CheckChangeConflictMany
+	backendIter                      = backend.Iter
+	backendList                      = backend.List
+	backendExport                    = backend.Export
+	backendImport                    = backend.Import
+)
+
+// newSnapshotSetID returns a snapshot set ID that is not in use either
+// on disk or in the state.
+func newSnapshotSetID(st *state.State) (uint64, error) {
+	var lastDiskSetID, lastStateSetID uint64
+
+	// note this keeps the state locked, which is ok as iterating
+	// over the snapshots only opens and reads the metadata
+	err := backendIter(context.TODO(), func(r *backend.Reader) error {
+		if r.SetID > lastDiskSetID {
+			lastDiskSetID = r.SetID
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("cannot list snapshots: %v", err)
+	}
+
+	err = st.Get("last-snapshot-set-id", &lastStateSetID)
+	if err != nil && err != state.ErrNoState {
+		return 0, err
+	}
+
+	setID := lastDiskSetID
+	if lastStateSetID > setID {
+		setID = lastStateSetID
+	}
+	setID++
+	st.Set("last-snapshot-set-id", setID)
+
+	return setID, nil
+}
+
+func allActiveSnapNames(st *state.State) ([]string, error) {
+	all, err := snapstateAll(st)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(all))
+	for name, snapst := range all {
+		if snapst.Active {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+type snapshotSnapSummary struct {
+	snap     string
+	filename string
+}
+
+type snapshotSnapSummaries []*snapshotSnapSummary
+
+func (summaries snapshotSnapSummaries) snapNames() []string {
+	names := make([]string, len(summaries))
+	for i, summary := range summaries {
+		names[i] = summary.snap
+	}
+	return names
+}
+
+// snapSummariesInSnapshotSet returns the summaries of the snapshots in
+// the given set, limited to the requested snaps if non-empty.
+func snapSummariesInSnapshotSet(setID uint64, requested []string) (summaries snapshotSnapSummaries, err error) {
+	sort.Strings(requested)
+	found := false
+	err = backendIter(context.TODO(), func(r *backend.Reader) error {
+		if r.SetID == setID {
+			found = true
+			if len(requested) == 0 || strutil.SortedListContains(requested, r.Snap) {
+				summaries = append(summaries, &snapshotSnapSummary{
+					snap:     r.Snap,
+					filename: r.Name(),
+				})
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, client.ErrSnapshotSetNotFound
+	}
+	if len(summaries) == 0 {
+		return nil, client.ErrSnapshotSnapsNotFound
+	}
+
+	return summaries, nil
+}
+
+func taskGetErrMsg(task *state.Task, err error, what string) error {
+	if err == state.ErrNoState {
+		return fmt.Errorf("internal error: task %s (%s) is missing %s information", task.ID(), task.Kind(), what)
+	}
+	return fmt.Errorf("internal error: retrieving %s information from task %s (%s): %v", what, task.ID(), task.Kind(), err)
+}
+
+// checkSnapshotTaskConflict checks whether there's an in-progress task
+// of one of the given kinds for snapshots with the given set ID.
+func checkSnapshotTaskConflict(st *state.State, setID uint64, conflictingKinds ...string) error {
+	for _, task := range st.Tasks() {
+		if chg := task.Change(); chg == nil || chg.Status().Ready() {
+			continue
+		}
+		if !strutil.ListContains(conflictingKinds, task.Kind()) {
+			continue
+		}
+
+		var snapshot snapshotSetup
+		if err := task.Get("snapshot-setup", &snapshot); err != nil {
+			return taskGetErrMsg(task, err, "snapshot")
+		}
+
+		if snapshot.SetID == setID {
+			return fmt.Errorf("cannot operate on snapshot set #%d while change %q is in progress", setID, task.Change().ID())
+		}
+	}
+
+	return nil
+}
+
+// automaticSnapshotExpiration returns how long automatic snapshots are
+// kept for, and whether taking them is disabled altogether, as set via
+// the core snapshots.automatic.retention option.
+func automaticSnapshotExpiration(st *state.State) (expiration time.Duration, disabled bool, err error) {
+	var retention string
+	tr := config.NewTransaction(st)
+	if err := tr.GetMaybe("core", "snapshots.automatic.retention", &retention); err != nil {
+		return 0, false, err
+	}
+	switch retention {
+	case "":
+		return defaultAutomaticSnapshotExpiration, false, nil
+	case "no":
+		// snapshots that were already taken still expire
+		return defaultAutomaticSnapshotExpiration, true, nil
+	}
+	expiration, err = time.ParseDuration(retention)
+	if err != nil {
+		return 0, false, fmt.Errorf("cannot parse snapshots.automatic.retention: %v", err)
+	}
+	return expiration, false, nil
+}
+
+// List valid snapshots.
+// Note that the state must be locked by the caller.
+func List(ctx context.Context, setID uint64, snapNames []string) ([]client.SnapshotSet, error) {
+	return backendList(ctx, setID, snapNames)
+}
+
+// Export writes the given snapshot set to w, in a format suitable for Import.
+// Note that the state must not be locked by the caller, as exporting can take
+// a while.
+func Export(ctx context.Context, setID uint64, w io.Writer) error {
+	return backendExport(ctx, setID, w)
+}
+
+// Import reads a snapshot set, as written by Export, from r, and adds it
+// under a new set ID.
+// Note that the state must not be locked by the caller.
+func Import(ctx context.Context, st *state.State, r io.Reader) (setID uint64, snapNames []string, err error) {
+	st.Lock()
+	setID, err = newSnapshotSetID(st)
+	st.Unlock()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	snapNames, err = backendImport(ctx, setID, r)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return setID, snapNames, nil
+}
+
+// Save creates a taskset for taking snapshots of snaps' data.
+// Note that the state must be locked by the caller.
+func Save(st *state.State, snapNames []string, users []string) (setID uint64, snapsSaved []string, ts *state.TaskSet, err error) {
+	if len(snapNames) == 0 {
+		snapNames, err = allActiveSnapNames(st)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+	}
+
+	for _, name := range snapNames {
+		var snapst snapstate.SnapState
+		if err := snapstate.Get(st, name, &snapst); err != nil {
+			if err == state.ErrNoState {
+				return 0, nil, nil, &snap.NotInstalledError{Snap: name}
+			}
+			return 0, nil, nil, err
+		}
+	}
+
+	if err := snapstateCheckChangeConflictMany(st, snapNames, nil); err != nil {
+		return 0, nil, nil, err
+	}
+
+	setID, err = newSnapshotSetID(st)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	ts = state.NewTaskSet()
+
+	for _, name := range snapNames {
+		desc := fmt.Sprintf(i18n.G("Save data of snap %q in snapshot set #%d"), name, setID)
+		task := st.NewTask("save-snapshot", desc)
+		snapshot := snapshotSetup{
+			SetID: setID,
+			Snap:  name,
+			Users: users,
+		}
+		task.Set("snapshot-setup", &snapshot)
+		// Here, note that a snapshot set behaves as a unit: it either
+		// succeeds, or fails, as a whole; we don't use lanes, to have
+		// some snaps' snapshot succeed and not others in a single set.
+		ts.AddTask(task)
+	}
+
+	return setID, snapNames, ts, nil
+}
+
+// AutomaticSnapshot creates a taskset for taking an automatic snapshot
+// of the data of the given snap, as done before removing it. It returns
+// snapstate.ErrNothingToDo if automatic snapshots are disabled.
+// Note that the state must be locked by the caller.
+func AutomaticSnapshot(st *state.State, snapName string) (ts *state.TaskSet, err error) {
+	_, disabled, err := automaticSnapshotExpiration(st)
+	if err != nil {
+		return nil, err
+	}
+	if disabled {
+		return nil, snapstate.ErrNothingToDo
+	}
+
+	setID, err := newSnapshotSetID(st)
+	if err != nil {
+		return nil, err
+	}
+
+	desc := fmt.Sprintf(i18n.G("Save data of snap %q in automatic snapshot set #%d"), snapName, setID)
+	task := st.NewTask("save-snapshot", desc)
+	snapshot := snapshotSetup{
+		SetID: setID,
+		Snap:  snapName,
+		Auto:  true,
+	}
+	task.Set("snapshot-setup", &snapshot)
+
+	return state.NewTaskSet(task), nil
+}
+
+// Restore creates a taskset for restoring a snapshot's data.
+// Note that the state must be locked by the caller.
+func Restore(st *state.State, setID uint64, snapNames []string, users []string) (snapsFound []string, ts *state.TaskSet, err error) {
+	summaries, err := snapSummariesInSnapshotSet(setID, snapNames)
+	if err != nil {
+		return nil, nil, err
+	}
+	snapsFound = summaries.snapNames()
+
+	if err := snapstateCheckChangeConflictMany(st, snapsFound, nil); err != nil {
+		return nil, nil, err
+	}
+
+	// restore needs to conflict with forget of itself
+	if err := checkSnapshotTaskConflict(st, setID, "forget-snapshot"); err != nil {
+		return nil, nil, err
+	}
+
+	ts = state.NewTaskSet()
+
+	for _, summary := range summaries {
+		desc := fmt.Sprintf(i18n.G("Restore data of snap %q from snapshot set #%d"), summary.snap, setID)
+		task := st.NewTask("restore-snapshot", desc)
+		snapshot := snapshotSetup{
+			SetID:    setID,
+			Snap:     summary.snap,
+			Users:    users,
+			Filename: summary.filename,
+		}
+		task.Set("snapshot-setup", &snapshot)
+		// see the note about not using lanes in Save
+		ts.AddTask(task)
+	}
+
+	return snapsFound, ts, nil
+}
+
+// Check creates a taskset for checking a snapshot's data.
+// Note that the state must be locked by the caller.
+func Check(st *state.State, setID uint64, snapNames []string, users []string) (snapsFound []string, ts *state.TaskSet, err error) {
+	// check needs to conflict with forget of itself
+	if err := checkSnapshotTaskConflict(st, setID, "forget-snapshot"); err != nil {
+		return nil, nil, err
+	}
+
+	summaries, err := snapSummariesInSnapshotSet(setID, snapNames)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ts = state.NewTaskSet()
+
+	for _, summary := range summaries {
+		desc := fmt.Sprintf(i18n.G("Check data of snap %q in snapshot set #%d"), summary.snap, setID)
+		task := st.NewTask("check-snapshot", desc)
+		snapshot := snapshotSetup{
+			SetID:    setID,
+			Snap:     summary.snap,
+			Users:    users,
+			Filename: summary.filename,
+		}
+		task.Set("snapshot-setup", &snapshot)
+		ts.AddTask(task)
+	}
+
+	return summaries.snapNames(), ts, nil
+}
+
+// Forget creates a taskset for deleting a snapshot.
+// Note that the state must be locked by the caller.
+func Forget(st *state.State, setID uint64, snapNames []string) (snapsFound []string, ts *state.TaskSet, err error) {
+	// forget needs to conflict with check and restore
+	if err := checkSnapshotTaskConflict(st, setID, "check-snapshot", "restore-snapshot"); err != nil {
+		return nil, nil, err
+	}
+
+	summaries, err := snapSummariesInSnapshotSet(setID, snapNames)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ts = state.NewTaskSet()
+	for _, summary := range summaries {
+		desc := fmt.Sprintf(i18n.G("Drop data of snap %q from snapshot set #%d"), summary.snap, setID)
+		task := st.NewTask("forget-snapshot", desc)
+		snapshot := snapshotSetup{
+			SetID:    setID,
+			Snap:     summary.snap,
+			Filename: summary.filename,
+		}
+		task.Set("snapshot-setup", &snapshot)
+		ts.AddTask(task)
+	}
+
+	return summaries.snapNames(), ts, nil
+}
@@ -0,0 +1,572 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapshotstate_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"golang.org/x/net/context"
+	"gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/snapshotstate"
+	"github.com/snapcore/snapd/overlord/snapshotstate/backend"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+func Test(t *testing.T) { check.TestingT(t) }
+
+type snapshotSuite struct{}
+
+var _ = check.Suite(&snapshotSuite{})
+
+func (snapshotSuite) TestNewSnapshotSetID(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	var diskSetIDs []uint64
+	defer snapshotstate.MockBackendIter(func(_ context.Context, f func(*backend.Reader) error) error {
+		for _, setID := range diskSetIDs {
+			r := &backend.Reader{Snapshot: client.Snapshot{SetID: setID}}
+			if err := f(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	})()
+
+	// nothing in state, nothing on disk: first ID is 1
+	setID, err := snapshotstate.NewSnapshotSetID(st)
+	c.Assert(err, check.IsNil)
+	c.Check(setID, check.Equals, uint64(1))
+
+	// the last ID is remembered in the state
+	setID, err = snapshotstate.NewSnapshotSetID(st)
+	c.Assert(err, check.IsNil)
+	c.Check(setID, check.Equals, uint64(2))
+
+	// higher IDs on disk win
+	diskSetIDs = []uint64{1, 7, 3}
+	setID, err = snapshotstate.NewSnapshotSetID(st)
+	c.Assert(err, check.IsNil)
+	c.Check(setID, check.Equals, uint64(8))
+
+	// as do higher IDs in state
+	st.Set("last-snapshot-set-id", 42)
+	setID, err = snapshotstate.NewSnapshotSetID(st)
+	c.Assert(err, check.IsNil)
+	c.Check(setID, check.Equals, uint64(43))
+}
+
+func (snapshotSuite) TestNewSnapshotSetIDIterError(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	defer snapshotstate.MockBackendIter(func(context.Context, func(*backend.Reader) error) error {
+		return errors.New("bzzt")
+	})()
+
+	_, err := snapshotstate.NewSnapshotSetID(st)
+	c.Check(err, check.ErrorMatches, "cannot list snapshots: bzzt")
+}
+
+func (snapshotSuite) TestAllActiveSnapNames(c *check.C) {
+	fakeSnapstateAll := func(*state.State) (map[string]*snapstate.SnapState, error) {
+		return map[string]*snapstate.SnapState{
+			"a-snap": {Active: true},
+			"b-snap": {},
+			"c-snap": {Active: true},
+		}, nil
+	}
+
+	defer snapshotstate.MockSnapstateAll(fakeSnapstateAll)()
+
+	// loop a few times to check the sorting isn't accidental
+	for i := 0; i < 10; i++ {
+		names, err := snapshotstate.AllActiveSnapNames(nil)
+		c.Assert(err, check.IsNil)
+		c.Check(names, check.DeepEquals, []string{"a-snap", "c-snap"})
+	}
+}
+
+func (snapshotSuite) TestAllActiveSnapNamesError(c *check.C) {
+	errSnapstateAll := func(*state.State) (map[string]*snapstate.SnapState, error) {
+		return nil, errors.New("bzzt")
+	}
+
+	defer snapshotstate.MockSnapstateAll(errSnapstateAll)()
+
+	names, err := snapshotstate.AllActiveSnapNames(nil)
+	c.Check(err, check.ErrorMatches, "bzzt")
+	c.Check(names, check.IsNil)
+}
+
+// mockIterOver makes backend.Iter go over the given snapshots, each
+// backed by an empty file in dir named after the snapshot's snap and set.
+func mockIterOver(c *check.C, dir string, snapshots ...client.Snapshot) (restore func()) {
+	return snapshotstate.MockBackendIter(func(_ context.Context, f func(*backend.Reader) error) error {
+		for _, shot := range snapshots {
+			fn := filepath.Join(dir, fmt.Sprintf("%d_%s.zip", shot.SetID, shot.Snap))
+			fh, err := os.Create(fn)
+			c.Assert(err, check.IsNil)
+			r := &backend.Reader{File: fh, Snapshot: shot}
+			err = f(r)
+			r.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (snapshotSuite) TestSnapSummariesInSnapshotSet(c *check.C) {
+	dir := c.MkDir()
+	defer mockIterOver(c, dir,
+		client.Snapshot{SetID: 1, Snap: "a-snap"},
+		client.Snapshot{SetID: 1, Snap: "b-snap"},
+		client.Snapshot{SetID: 2, Snap: "a-snap"},
+	)()
+
+	summaries, err := snapshotstate.SnapSummariesInSnapshotSet(1, nil)
+	c.Assert(err, check.IsNil)
+	c.Check(summaries.AsMaps(), check.DeepEquals, []map[string]string{
+		{"snap": "a-snap", "filename": filepath.Join(dir, "1_a-snap.zip")},
+		{"snap": "b-snap", "filename": filepath.Join(dir, "1_b-snap.zip")},
+	})
+
+	summaries, err = snapshotstate.SnapSummariesInSnapshotSet(1, []string{"b-snap"})
+	c.Assert(err, check.IsNil)
+	c.Check(summaries.AsMaps(), check.DeepEquals, []map[string]string{
+		{"snap": "b-snap", "filename": filepath.Join(dir, "1_b-snap.zip")},
+	})
+
+	_, err = snapshotstate.SnapSummariesInSnapshotSet(3, nil)
+	c.Check(err, check.Equals, client.ErrSnapshotSetNotFound)
+
+	_, err = snapshotstate.SnapSummariesInSnapshotSet(2, []string{"b-snap"})
+	c.Check(err, check.Equals, client.ErrSnapshotSnapsNotFound)
+}
+
+func (snapshotSuite) TestSnapSummariesInSnapshotSetIterError(c *check.C) {
+	defer snapshotstate.MockBackendIter(func(context.Context, func(*backend.Reader) error) error {
+		return errors.New("bzzt")
+	})()
+
+	summaries, err := snapshotstate.SnapSummariesInSnapshotSet(1, nil)
+	c.Check(err, check.ErrorMatches, "bzzt")
+	c.Check(summaries, check.IsNil)
+}
+
+func (snapshotSuite) TestCheckSnapshotTaskConflict(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	chg := st.NewChange("some-change", "...")
+	tsk := st.NewTask("forget-snapshot", "...")
+	tsk.Set("snapshot-setup", map[string]int{"set-id": 42})
+	chg.AddTask(tsk)
+
+	c.Check(snapshotstate.CheckSnapshotTaskConflict(st, 43, "forget-snapshot"), check.IsNil)
+	c.Check(snapshotstate.CheckSnapshotTaskConflict(st, 42, "check-snapshot"), check.IsNil)
+	c.Check(snapshotstate.CheckSnapshotTaskConflict(st, 42, "forget-snapshot"), check.ErrorMatches,
+		`cannot operate on snapshot set #42 while change "1" is in progress`)
+
+	tsk.SetStatus(state.DoneStatus)
+	c.Check(snapshotstate.CheckSnapshotTaskConflict(st, 42, "forget-snapshot"), check.IsNil)
+}
+
+func (snapshotSuite) TestCheckSnapshotTaskConflictBadTask(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	chg := st.NewChange("some-change", "...")
+	tsk := st.NewTask("forget-snapshot", "...")
+	chg.AddTask(tsk)
+
+	c.Check(snapshotstate.CheckSnapshotTaskConflict(st, 42, "forget-snapshot"), check.ErrorMatches,
+		`internal error: task 1 \(forget-snapshot\) is missing snapshot information`)
+}
+
+func (snapshotSuite) TestSaveChecksSnapnamesError(c *check.C) {
+	defer snapshotstate.MockSnapstateAll(func(*state.State) (map[string]*snapstate.SnapState, error) {
+		return nil, errors.New("bzzt")
+	})()
+
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	_, _, _, err := snapshotstate.Save(st, nil, nil)
+	c.Check(err, check.ErrorMatches, "bzzt")
+}
+
+func (snapshotSuite) TestSaveNotInstalled(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	_, _, _, err := snapshotstate.Save(st, []string{"a-snap"}, nil)
+	c.Check(err, check.ErrorMatches, `snap "a-snap" is not installed`)
+	c.Check(err, check.FitsTypeOf, &snap.NotInstalledError{})
+}
+
+func (snapshotSuite) TestSaveConflictsWithSnapstate(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	snapstate.Set(st, "foo", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "foo", Revision: snap.R(1)},
+		},
+		Current:  snap.R(1),
+		SnapType: "app",
+	})
+
+	chg := st.NewChange("install-snap", "...")
+	tsk := st.NewTask("link-snap", "...")
+	tsk.Set("snap-setup", &snapstate.SnapSetup{SideInfo: &snap.SideInfo{RealName: "foo"}})
+	chg.AddTask(tsk)
+
+	_, _, _, err := snapshotstate.Save(st, nil, nil)
+	c.Assert(err, check.ErrorMatches, `snap "foo" has "install-snap" change in progress`)
+}
+
+func (snapshotSuite) TestSave(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	defer mockIterOver(c, c.MkDir(), client.Snapshot{SetID: 4})()
+
+	for _, name := range []string{"foo", "bar", "baz"} {
+		snapstate.Set(st, name, &snapstate.SnapState{
+			Active: name != "baz",
+			Sequence: []*snap.SideInfo{
+				{RealName: name, Revision: snap.R(1)},
+			},
+			Current:  snap.R(1),
+			SnapType: "app",
+		})
+	}
+
+	setID, saved, taskset, err := snapshotstate.Save(st, nil, []string{"a-user"})
+	c.Assert(err, check.IsNil)
+	c.Check(setID, check.Equals, uint64(5))
+	c.Check(saved, check.DeepEquals, []string{"bar", "foo"})
+	tasks := taskset.Tasks()
+	c.Assert(tasks, check.HasLen, 2)
+	c.Check(tasks[0].Kind(), check.Equals, "save-snapshot")
+	c.Check(tasks[0].Summary(), check.Equals, `Save data of snap "bar" in snapshot set #5`)
+	var snapshot map[string]interface{}
+	c.Check(tasks[1].Get("snapshot-setup", &snapshot), check.IsNil)
+	c.Check(snapshot, check.DeepEquals, map[string]interface{}{
+		"set-id": 5.,
+		"snap":   "foo",
+		"users":  []interface{}{"a-user"},
+	})
+}
+
+func (snapshotSuite) TestSaveSomeSnaps(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	defer mockIterOver(c, c.MkDir())()
+
+	for _, name := range []string{"foo", "bar"} {
+		snapstate.Set(st, name, &snapstate.SnapState{
+			Active: true,
+			Sequence: []*snap.SideInfo{
+				{RealName: name, Revision: snap.R(1)},
+			},
+			Current:  snap.R(1),
+			SnapType: "app",
+		})
+	}
+
+	setID, saved, taskset, err := snapshotstate.Save(st, []string{"foo"}, nil)
+	c.Assert(err, check.IsNil)
+	c.Check(setID, check.Equals, uint64(1))
+	c.Check(saved, check.DeepEquals, []string{"foo"})
+	c.Check(taskset.Tasks(), check.HasLen, 1)
+}
+
+func (snapshotSuite) TestRestore(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	dir := c.MkDir()
+	defer mockIterOver(c, dir,
+		client.Snapshot{SetID: 42, Snap: "foo"},
+		client.Snapshot{SetID: 42, Snap: "bar"},
+	)()
+	var conflictChecked []string
+	defer snapshotstate.MockSnapstateCheckChangeConflictMany(func(_ *state.State, names []string, _ func(*state.Task) bool) error {
+		conflictChecked = names
+		return nil
+	})()
+
+	found, taskset, err := snapshotstate.Restore(st, 42, nil, []string{"a-user"})
+	c.Assert(err, check.IsNil)
+	sort.Strings(found)
+	c.Check(found, check.DeepEquals, []string{"bar", "foo"})
+	c.Check(conflictChecked, check.HasLen, 2)
+	tasks := taskset.Tasks()
+	c.Assert(tasks, check.HasLen, 2)
+	c.Check(tasks[0].Kind(), check.Equals, "restore-snapshot")
+	c.Check(tasks[0].Summary(), check.Equals, `Restore data of snap "foo" from snapshot set #42`)
+	var snapshot map[string]interface{}
+	c.Check(tasks[0].Get("snapshot-setup", &snapshot), check.IsNil)
+	c.Check(snapshot, check.DeepEquals, map[string]interface{}{
+		"set-id":   42.,
+		"snap":     "foo",
+		"users":    []interface{}{"a-user"},
+		"filename": filepath.Join(dir, "42_foo.zip"),
+	})
+}
+
+func (snapshotSuite) TestRestoreConflictsWithForget(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	defer mockIterOver(c, c.MkDir(), client.Snapshot{SetID: 42, Snap: "foo"})()
+
+	chg := st.NewChange("forget-snapshot", "...")
+	tsk := st.NewTask("forget-snapshot", "...")
+	tsk.Set("snapshot-setup", map[string]int{"set-id": 42})
+	chg.AddTask(tsk)
+
+	_, _, err := snapshotstate.Restore(st, 42, nil, nil)
+	c.Check(err, check.ErrorMatches, `cannot operate on snapshot set #42 while change "1" is in progress`)
+}
+
+func (snapshotSuite) TestRestoreNotFound(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	defer mockIterOver(c, c.MkDir(), client.Snapshot{SetID: 42, Snap: "foo"})()
+
+	_, _, err := snapshotstate.Restore(st, 43, nil, nil)
+	c.Check(err, check.Equals, client.ErrSnapshotSetNotFound)
+
+	_, _, err = snapshotstate.Restore(st, 42, []string{"bar"}, nil)
+	c.Check(err, check.Equals, client.ErrSnapshotSnapsNotFound)
+}
+
+func (snapshotSuite) TestCheck(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	defer mockIterOver(c, c.MkDir(),
+		client.Snapshot{SetID: 42, Snap: "foo"},
+		client.Snapshot{SetID: 42, Snap: "bar"},
+	)()
+
+	found, taskset, err := snapshotstate.Check(st, 42, []string{"bar"}, nil)
+	c.Assert(err, check.IsNil)
+	c.Check(found, check.DeepEquals, []string{"bar"})
+	tasks := taskset.Tasks()
+	c.Assert(tasks, check.HasLen, 1)
+	c.Check(tasks[0].Kind(), check.Equals, "check-snapshot")
+	c.Check(tasks[0].Summary(), check.Equals, `Check data of snap "bar" in snapshot set #42`)
+}
+
+func (snapshotSuite) TestCheckConflictsWithForget(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	defer mockIterOver(c, c.MkDir(), client.Snapshot{SetID: 42, Snap: "foo"})()
+
+	chg := st.NewChange("forget-snapshot", "...")
+	tsk := st.NewTask("forget-snapshot", "...")
+	tsk.Set("snapshot-setup", map[string]int{"set-id": 42})
+	chg.AddTask(tsk)
+
+	_, _, err := snapshotstate.Check(st, 42, nil, nil)
+	c.Check(err, check.ErrorMatches, `cannot operate on snapshot set #42 while change "1" is in progress`)
+}
+
+func (snapshotSuite) TestForget(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	defer mockIterOver(c, c.MkDir(),
+		client.Snapshot{SetID: 42, Snap: "foo"},
+		client.Snapshot{SetID: 42, Snap: "bar"},
+	)()
+
+	found, taskset, err := snapshotstate.Forget(st, 42, nil)
+	c.Assert(err, check.IsNil)
+	c.Check(found, check.DeepEquals, []string{"foo", "bar"})
+	tasks := taskset.Tasks()
+	c.Assert(tasks, check.HasLen, 2)
+	c.Check(tasks[1].Kind(), check.Equals, "forget-snapshot")
+	c.Check(tasks[1].Summary(), check.Equals, `Drop data of snap "bar" from snapshot set #42`)
+}
+
+func (snapshotSuite) TestForgetConflictsWithCheckAndRestore(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	defer mockIterOver(c, c.MkDir(), client.Snapshot{SetID: 42, Snap: "foo"})()
+
+	for _, kind := range []string{"check-snapshot", "restore-snapshot"} {
+		chg := st.NewChange(kind, "...")
+		tsk := st.NewTask(kind, "...")
+		tsk.Set("snapshot-setup", map[string]int{"set-id": 42})
+		chg.AddTask(tsk)
+
+		_, _, err := snapshotstate.Forget(st, 42, nil)
+		c.Check(err, check.ErrorMatches, `cannot operate on snapshot set #42 while change "\d+" is in progress`, check.Commentf(kind))
+
+		chg.SetStatus(state.DoneStatus)
+	}
+}
+
+func (snapshotSuite) TestList(c *check.C) {
+	expected := []client.SnapshotSet{{ID: 42}}
+	defer snapshotstate.MockBackendList(func(_ context.Context, setID uint64, snapNames []string) ([]client.SnapshotSet, error) {
+		c.Check(setID, check.Equals, uint64(42))
+		c.Check(snapNames, check.DeepEquals, []string{"foo"})
+		return expected, nil
+	})()
+
+	sets, err := snapshotstate.List(context.TODO(), 42, []string{"foo"})
+	c.Assert(err, check.IsNil)
+	c.Check(sets, check.DeepEquals, expected)
+}
+
+func (snapshotSuite) TestAutomaticSnapshot(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	defer mockIterOver(c, c.MkDir(), client.Snapshot{SetID: 4})()
+
+	ts, err := snapshotstate.AutomaticSnapshot(st, "foo")
+	c.Assert(err, check.IsNil)
+	tasks := ts.Tasks()
+	c.Assert(tasks, check.HasLen, 1)
+	c.Check(tasks[0].Kind(), check.Equals, "save-snapshot")
+	c.Check(tasks[0].Summary(), check.Equals, `Save data of snap "foo" in automatic snapshot set #5`)
+	var snapshot map[string]interface{}
+	c.Check(tasks[0].Get("snapshot-setup", &snapshot), check.IsNil)
+	c.Check(snapshot, check.DeepEquals, map[string]interface{}{
+		"set-id": 5.,
+		"snap":   "foo",
+		"auto":   true,
+	})
+}
+
+func (snapshotSuite) TestAutomaticSnapshotDisabled(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	tr := config.NewTransaction(st)
+	tr.Set("core", "snapshots.automatic.retention", "no")
+	tr.Commit()
+
+	_, err := snapshotstate.AutomaticSnapshot(st, "foo")
+	c.Check(err, check.Equals, snapstate.ErrNothingToDo)
+}
+
+func (snapshotSuite) TestAutomaticSnapshotBadRetention(c *check.C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	tr := config.NewTransaction(st)
+	tr.Set("core", "snapshots.automatic.retention", "bzzt")
+	tr.Commit()
+
+	_, err := snapshotstate.AutomaticSnapshot(st, "foo")
+	c.Check(err, check.ErrorMatches, `cannot parse snapshots.automatic.retention: .*`)
+}
+
+func (snapshotSuite) TestExport(c *check.C) {
+	var buf bytes.Buffer
+	defer snapshotstate.MockBackendExport(func(_ context.Context, setID uint64, w io.Writer) error {
+		c.Check(setID, check.Equals, uint64(42))
+		_, err := io.WriteString(w, "hello")
+		return err
+	})()
+
+	c.Assert(snapshotstate.Export(context.TODO(), 42, &buf), check.IsNil)
+	c.Check(buf.String(), check.Equals, "hello")
+}
+
+func (snapshotSuite) TestImport(c *check.C) {
+	st := state.New(nil)
+	defer mockIterOver(c, c.MkDir(), client.Snapshot{SetID: 7, Snap: "foo"})()
+	defer snapshotstate.MockBackendImport(func(_ context.Context, setID uint64, r io.Reader) ([]string, error) {
+		c.Check(setID, check.Equals, uint64(8))
+		buf, err := ioutil.ReadAll(r)
+		c.Check(err, check.IsNil)
+		c.Check(string(buf), check.Equals, "hello")
+		return []string{"foo"}, nil
+	})()
+
+	setID, snapNames, err := snapshotstate.Import(context.TODO(), st, bytes.NewBufferString("hello"))
+	c.Assert(err, check.IsNil)
+	c.Check(setID, check.Equals, uint64(8))
+	c.Check(snapNames, check.DeepEquals, []string{"foo"})
+
+	st.Lock()
+	defer st.Unlock()
+	var lastSetID uint64
+	c.Check(st.Get("last-snapshot-set-id", &lastSetID), check.IsNil)
+	c.Check(lastSetID, check.Equals, uint64(8))
+}
+
+func (snapshotSuite) TestImportError(c *check.C) {
+	st := state.New(nil)
+	defer snapshotstate.MockBackendImport(func(context.Context, uint64, io.Reader) ([]string, error) {
+		return nil, errors.New("bzzt")
+	})()
+
+	_, _, err := snapshotstate.Import(context.TODO(), st, bytes.NewBufferString("hello"))
+	c.Check(err, check.ErrorMatches, "bzzt")
+}
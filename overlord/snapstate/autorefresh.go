@@ -179,7 +179,7 @@ func (m *autoRefresh) canRefreshRespectingMetered(now, lastRefresh time.Time) (c
 
 	if now.Sub(lastRefresh) >= maxPostponement {
 		// TODO use warnings when the infra becomes available
-		logger.Noticef("Auto refresh disabled while on metered connections, but pending for too long (%s days). Trying to refresh now.", int(maxPostponement.Hours()/24))
+		logger.Noticef("Auto refresh disabled while on metered connections, but pending for too long (%d days). Trying to refresh now.", int(maxPostponement.Hours()/24))
 		return true, nil
 	}
 
@@ -374,7 +374,8 @@ func (m *autoRefresh) launchAutoRefresh() error {
 	m.lastRefreshAttempt = time.Now()
 	updated, tasksets, err := AutoRefresh(auth.EnsureContextTODO(), m.state)
 	if err != nil {
-		logger.Noticef("Cannot prepare auto-refresh change: %s", err)
+		// this also logs the problem
+		m.state.Warnf("cannot prepare auto-refresh change: %s", err)
 		return err
 	}
 
@@ -398,6 +399,8 @@ func (m *autoRefresh) launchAutoRefresh() error {
 	}
 
 	chg := m.state.NewChange("auto-refresh", msg)
+	// let changes requested by users overtake auto-refreshes
+	chg.SetPriority(state.LowPriority)
 	for _, ts := range tasksets {
 		chg.AddAll(ts)
 	}
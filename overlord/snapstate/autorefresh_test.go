@@ -179,6 +179,13 @@ func (s *autoRefreshTestSuite) TestRefreshBackoff(c *C) {
 	c.Check(s.store.ops, HasLen, 1)
 	c.Check(s.store.ops, DeepEquals, []string{"list-refresh"})
 
+	// the failure is surfaced as a warning
+	s.state.Lock()
+	warnings := s.state.AllWarnings()
+	s.state.Unlock()
+	c.Assert(warnings, HasLen, 1)
+	c.Check(warnings[0].String(), Equals, "cannot prepare auto-refresh change: random store error")
+
 	// call ensure again, our back-off will prevent the store from
 	// being hit again
 	err = af.Ensure()
@@ -56,11 +56,12 @@ type StoreService interface {
 
 type managerBackend interface {
 	// install releated
-	SetupSnap(snapFilePath string, si *snap.SideInfo, meter progress.Meter) (snap.Type, error)
+	SetupSnap(snapFilePath, instanceName string, si *snap.SideInfo, meter progress.Meter) (snap.Type, error)
 	CopySnapData(newSnap, oldSnap *snap.Info, meter progress.Meter) error
-	LinkSnap(info *snap.Info, model *asserts.Model) error
-	StartServices(svcs []*snap.AppInfo, meter progress.Meter) error
+	LinkSnap(info *snap.Info, model *asserts.Model, disabledSvcs []string) error
+	StartServices(svcs []*snap.AppInfo, disabledSvcs []string, meter progress.Meter) error
 	StopServices(svcs []*snap.AppInfo, reason snap.ServiceStopReason, meter progress.Meter) error
+	QueryDisabledServices(info *snap.Info, meter progress.Meter) ([]string, error)
 
 	// the undoers for install
 	UndoSetupSnap(s snap.PlaceInfo, typ snap.Type, meter progress.Meter) error
@@ -59,12 +59,13 @@ func updateCurrentSymlinks(info *snap.Info) error {
 }
 
 // LinkSnap makes the snap available by generating wrappers and setting the current symlinks.
-func (b Backend) LinkSnap(info *snap.Info, model *asserts.Model) error {
+// The services named in disabledSvcs are not enabled.
+func (b Backend) LinkSnap(info *snap.Info, model *asserts.Model, disabledSvcs []string) error {
 	if info.Revision.Unset() {
 		return fmt.Errorf("cannot link snap %q with unset revision", info.Name())
 	}
 
-	if err := generateWrappers(info); err != nil {
+	if err := generateWrappers(info, disabledSvcs); err != nil {
 		return err
 	}
 
@@ -85,21 +86,26 @@ func (b Backend) LinkSnap(info *snap.Info, model *asserts.Model) error {
 	return updateCurrentSymlinks(info)
 }
 
-func (b Backend) StartServices(apps []*snap.AppInfo, meter progress.Meter) error {
-	return wrappers.StartServices(apps, meter)
+func (b Backend) StartServices(apps []*snap.AppInfo, disabledSvcs []string, meter progress.Meter) error {
+	return wrappers.StartServices(apps, disabledSvcs, meter)
 }
 
 func (b Backend) StopServices(apps []*snap.AppInfo, reason snap.ServiceStopReason, meter progress.Meter) error {
 	return wrappers.StopServices(apps, reason, meter)
 }
 
-func generateWrappers(s *snap.Info) error {
+// QueryDisabledServices returns the names of the disabled services of the snap.
+func (b Backend) QueryDisabledServices(info *snap.Info, meter progress.Meter) ([]string, error) {
+	return wrappers.QueryDisabledServices(info, meter)
+}
+
+func generateWrappers(s *snap.Info, disabledSvcs []string) error {
 	// add the CLI apps from the snap.yaml
 	if err := wrappers.AddSnapBinaries(s); err != nil {
 		return err
 	}
 	// add the daemons from the snap.yaml
-	if err := wrappers.AddSnapServices(s, progress.Null); err != nil {
+	if err := wrappers.AddSnapServices(s, disabledSvcs, progress.Null); err != nil {
 		wrappers.RemoveSnapBinaries(s)
 		return err
 	}
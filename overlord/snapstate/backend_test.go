@@ -23,6 +23,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -58,6 +60,8 @@ type fakeOp struct {
 	rmAliases []*backend.Alias
 
 	userID int
+
+	disabledServices []string
 }
 
 type fakeOps []fakeOp
@@ -101,7 +105,7 @@ type byName []store.CurrentSnap
 func (bna byName) Len() int      { return len(bna) }
 func (bna byName) Swap(i, j int) { bna[i], bna[j] = bna[j], bna[i] }
 func (bna byName) Less(i, j int) bool {
-	return bna[i].Name < bna[j].Name
+	return bna[i].InstanceName < bna[j].InstanceName
 }
 
 type byAction []*store.SnapAction
@@ -111,6 +115,9 @@ func (ba byAction) Swap(i, j int) { ba[i], ba[j] = ba[j], ba[i] }
 func (ba byAction) Less(i, j int) bool {
 	if ba[i].Action == ba[j].Action {
 		if ba[i].Action == "refresh" {
+			if ba[i].SnapID == ba[j].SnapID {
+				return ba[i].InstanceName < ba[j].InstanceName
+			}
 			return ba[i].SnapID < ba[j].SnapID
 		} else {
 			return ba[i].Name < ba[j].Name
@@ -123,6 +130,7 @@ type fakeStore struct {
 	storetest.Store
 
 	downloads           []fakeDownload
+	downloadError       map[string]error
 	refreshRevnos       map[string]snap.Revision
 	fakeBackend         *fakeSnappyBackend
 	fakeCurrentProgress int
@@ -327,13 +335,13 @@ func (f *fakeStore) SnapAction(ctx context.Context, currentSnaps []*store.Curren
 		panic("fake SnapAction unexpectedly called with more than 3 actions")
 	}
 
-	curByID := make(map[string]*store.CurrentSnap, len(currentSnaps))
+	curByInstanceName := make(map[string]*store.CurrentSnap, len(currentSnaps))
 	curSnaps := make(byName, len(currentSnaps))
 	for i, cur := range currentSnaps {
-		if cur.Name == "" || cur.SnapID == "" || cur.Revision.Unset() {
+		if cur.Name == "" || cur.InstanceName == "" || cur.SnapID == "" || cur.Revision.Unset() {
 			return nil, fmt.Errorf("internal error: incomplete current snap info")
 		}
-		curByID[cur.SnapID] = cur
+		curByInstanceName[cur.InstanceName] = cur
 		curSnaps[i] = *cur
 	}
 	sort.Sort(curSnaps)
@@ -385,7 +393,7 @@ func (f *fakeStore) SnapAction(ctx context.Context, currentSnaps []*store.Curren
 
 		// refresh
 
-		cur := curByID[a.SnapID]
+		cur := curByInstanceName[a.InstanceName]
 		channel := a.Channel
 		if channel == "" {
 			channel = cur.TrackingChannel
@@ -418,7 +426,7 @@ func (f *fakeStore) SnapAction(ctx context.Context, currentSnaps []*store.Curren
 			userID: userID,
 		})
 		if err == store.ErrNoUpdateAvailable {
-			refreshErrors[cur.Name] = err
+			refreshErrors[cur.InstanceName] = err
 			continue
 		}
 		if err != nil {
@@ -427,6 +435,7 @@ func (f *fakeStore) SnapAction(ctx context.Context, currentSnaps []*store.Curren
 		if !a.Revision.Unset() {
 			info.Channel = ""
 		}
+		_, info.InstanceKey = snap.SplitInstanceName(a.InstanceName)
 		res = append(res, info)
 	}
 
@@ -466,6 +475,17 @@ func (f *fakeStore) Download(ctx context.Context, name, targetFn string, snapInf
 	})
 	f.fakeBackend.ops = append(f.fakeBackend.ops, fakeOp{op: "storesvc-download", name: name})
 
+	if err := f.downloadError[name]; err != nil {
+		// leave behind a partial download, like the real store
+		if err := os.MkdirAll(filepath.Dir(targetFn), 0755); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(store.PartialDownloadPath(targetFn), []byte("partial"), 0644); err != nil {
+			return err
+		}
+		return err
+	}
+
 	pb.SetTotal(float64(f.fakeTotalProgress))
 	pb.Set(float64(f.fakeCurrentProgress))
 
@@ -502,6 +522,10 @@ type fakeSnappyBackend struct {
 	linkSnapFailTrigger     string
 	copySnapDataFailTrigger string
 	emptyContainer          snap.Container
+
+	linkSnapHook func(info *snap.Info)
+
+	servicesDisabled []string
 }
 
 func (f *fakeSnappyBackend) OpenSnapFile(snapFilePath string, si *snap.SideInfo) (*snap.Info, snap.Container, error) {
@@ -523,7 +547,7 @@ func (f *fakeSnappyBackend) OpenSnapFile(snapFilePath string, si *snap.SideInfo)
 	return &snap.Info{SuggestedName: name, Architectures: []string{"all"}}, f.emptyContainer, nil
 }
 
-func (f *fakeSnappyBackend) SetupSnap(snapFilePath string, si *snap.SideInfo, p progress.Meter) (snap.Type, error) {
+func (f *fakeSnappyBackend) SetupSnap(snapFilePath, instanceName string, si *snap.SideInfo, p progress.Meter) (snap.Type, error) {
 	p.Notify("setup-snap")
 	revno := snap.R(0)
 	if si != nil {
@@ -552,8 +576,10 @@ func (f *fakeSnappyBackend) ReadInfo(name string, si *snap.SideInfo) (*snap.Info
 		return nil, &snap.NotFoundError{Snap: name, Revision: si.Revision}
 	}
 	// naive emulation for now, always works
+	snapName, instanceKey := snap.SplitInstanceName(name)
 	info := &snap.Info{
-		SuggestedName: name,
+		SuggestedName: snapName,
+		InstanceKey:   instanceKey,
 		SideInfo:      *si,
 		Architectures: []string{"all"},
 		Type:          snap.TypeApp,
@@ -574,6 +600,9 @@ apps:
     daemon: simple
   svc2:
     daemon: simple
+  svc3:
+    daemon: simple
+    install-mode: disable
 `))
 		if err != nil {
 			panic(err)
@@ -638,7 +667,11 @@ func (f *fakeSnappyBackend) CopySnapData(newInfo, oldInfo *snap.Info, p progress
 	return nil
 }
 
-func (f *fakeSnappyBackend) LinkSnap(info *snap.Info, model *asserts.Model) error {
+func (f *fakeSnappyBackend) LinkSnap(info *snap.Info, model *asserts.Model, disabledSvcs []string) error {
+	if f.linkSnapHook != nil {
+		f.linkSnapHook(info)
+	}
+
 	if info.MountDir() == f.linkSnapFailTrigger {
 		f.ops = append(f.ops, fakeOp{
 			op:   "link-snap.failed",
@@ -650,6 +683,8 @@ func (f *fakeSnappyBackend) LinkSnap(info *snap.Info, model *asserts.Model) erro
 	f.ops = append(f.ops, fakeOp{
 		op:   "link-snap",
 		name: info.MountDir(),
+
+		disabledServices: disabledSvcs,
 	})
 	return nil
 }
@@ -664,10 +699,12 @@ func svcSnapMountDir(svcs []*snap.AppInfo) string {
 	return svcs[0].Snap.MountDir()
 }
 
-func (f *fakeSnappyBackend) StartServices(svcs []*snap.AppInfo, meter progress.Meter) error {
+func (f *fakeSnappyBackend) StartServices(svcs []*snap.AppInfo, disabledSvcs []string, meter progress.Meter) error {
 	f.ops = append(f.ops, fakeOp{
 		op:   "start-snap-services",
 		name: svcSnapMountDir(svcs),
+
+		disabledServices: disabledSvcs,
 	})
 	return nil
 }
@@ -680,6 +717,10 @@ func (f *fakeSnappyBackend) StopServices(svcs []*snap.AppInfo, reason snap.Servi
 	return nil
 }
 
+func (f *fakeSnappyBackend) QueryDisabledServices(info *snap.Info, meter progress.Meter) ([]string, error) {
+	return f.servicesDisabled, nil
+}
+
 func (f *fakeSnappyBackend) UndoSetupSnap(s snap.PlaceInfo, typ snap.Type, p progress.Meter) error {
 	p.Notify("setup-snap")
 	f.ops = append(f.ops, fakeOp{
@@ -300,6 +300,12 @@ func checkGadgetOrKernel(st *state.State, snapInfo, curInfo *snap.Info, flags Fl
 		return fmt.Errorf("cannot find original %s snap: %v", kind, err)
 	}
 
+	// a remodel can switch to the kernel of the new model,
+	// devicestate checks it against the model
+	if flags.Remodel && snapInfo.Type == snap.TypeKernel && currentSnap.Name() != snapInfo.Name() {
+		return nil
+	}
+
 	if currentSnap.SnapID != "" && snapInfo.SnapID != "" {
 		if currentSnap.SnapID == snapInfo.SnapID {
 			// same snap
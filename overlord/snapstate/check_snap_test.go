@@ -620,6 +620,12 @@ version: 2
 	err = snapstate.CheckSnap(st, "snap-path", nil, nil, snapstate.Flags{})
 	st.Lock()
 	c.Check(err, ErrorMatches, "cannot replace kernel snap with a different one")
+
+	// unless switching to the kernel of a new model
+	st.Unlock()
+	err = snapstate.CheckSnap(st, "snap-path", nil, nil, snapstate.Flags{Remodel: true})
+	st.Lock()
+	c.Check(err, IsNil)
 }
 
 func (s *checkSnapSuite) TestCheckSnapBasesErrorsIfMissing(c *C) {
@@ -59,6 +59,7 @@ func (m *SnapManager) AddForeignTaskHandlers(tracker ForeignTaskTracker) {
 	}
 	m.runner.AddHandler("setup-profiles", fakeHandler, fakeHandler)
 	m.runner.AddHandler("auto-connect", fakeHandler, nil)
+	m.runner.AddHandler("auto-disconnect", fakeHandler, nil)
 	m.runner.AddHandler("remove-profiles", fakeHandler, fakeHandler)
 	m.runner.AddHandler("discard-conns", fakeHandler, fakeHandler)
 	m.runner.AddHandler("validate-snap", fakeHandler, nil)
@@ -193,3 +194,26 @@ func ByKindOrder(snaps ...*snap.Info) []*snap.Info {
 	sort.Sort(byKind(snaps))
 	return snaps
 }
+
+// refresh app awareness
+var (
+	PidsOfSnap                 = pidsOfSnap
+	NothingRunningRefreshCheck = nothingRunningRefreshCheck
+	InhibitRefresh             = inhibitRefresh
+)
+
+func MockPidsOfSnap(f func(info *snap.Info) (map[string][]int, error)) func() {
+	old := pidsOfSnap
+	pidsOfSnap = f
+	return func() {
+		pidsOfSnap = old
+	}
+}
+
+func MockMaxInhibition(d time.Duration) func() {
+	old := maxInhibition
+	maxInhibition = d
+	return func() {
+		maxInhibition = old
+	}
+}
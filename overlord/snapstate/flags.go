@@ -57,6 +57,14 @@ type Flags struct {
 	// Amend allows refreshing out of a snap unknown to the store
 	// and into one that is known.
 	Amend bool `json:"amend,omitempty"`
+
+	// Remodel is set when the snap is installed as part of switching
+	// the device to a new model, which can replace the kernel.
+	Remodel bool `json:"remodel,omitempty"`
+
+	// IsAutoRefresh is set when the refresh was triggered by the
+	// automatic refresh logic rather than by a user request.
+	IsAutoRefresh bool `json:"is-auto-refresh,omitempty"`
 }
 
 // DevModeAllowed returns whether a snap can be installed with devmode confinement (either set or overridden)
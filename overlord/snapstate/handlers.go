@@ -22,6 +22,7 @@ package snapstate
 import (
 	"fmt"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -39,6 +40,8 @@ import (
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/runinhibit"
+	"github.com/snapcore/snapd/store"
 )
 
 // hook setup by devicestate
@@ -414,7 +417,8 @@ func (m *SnapManager) doDownloadSnap(t *state.Task, tomb *tomb.Tomb) error {
 
 	meter := NewTaskProgressAdapterUnlocked(t)
 	targetFn := snapsup.MountFile()
-	if snapsup.DownloadInfo == nil {
+	downloadInfo := snapsup.DownloadInfo
+	if downloadInfo == nil {
 		var storeInfo *snap.Info
 		// COMPATIBILITY - this task was created from an older version
 		// of snapd that did not store the DownloadInfo in the state
@@ -423,11 +427,18 @@ func (m *SnapManager) doDownloadSnap(t *state.Task, tomb *tomb.Tomb) error {
 		if err != nil {
 			return err
 		}
-		err = theStore.Download(tomb.Context(nil), snapsup.Name(), targetFn, &storeInfo.DownloadInfo, meter, user)
+		downloadInfo = &storeInfo.DownloadInfo
 		snapsup.SideInfo = &storeInfo.SideInfo
-	} else {
-		err = theStore.Download(tomb.Context(nil), snapsup.Name(), targetFn, snapsup.DownloadInfo, meter, user)
 	}
+
+	st.Lock()
+	err = trackPartialDownload(t, targetFn, downloadInfo)
+	st.Unlock()
+	if err != nil {
+		return err
+	}
+
+	err = theStore.Download(tomb.Context(nil), snapsup.Name(), targetFn, downloadInfo, meter, user)
 	if err != nil {
 		return err
 	}
@@ -437,11 +448,65 @@ func (m *SnapManager) doDownloadSnap(t *state.Task, tomb *tomb.Tomb) error {
 	// update the snap setup for the follow up tasks
 	st.Lock()
 	t.Set("snap-setup", snapsup)
+	t.Clear("partial-download")
 	st.Unlock()
 
 	return nil
 }
 
+// partialDownload records the download of a task that is in progress,
+// which is left behind by the store if interrupted, so that it can be
+// resumed when the task is run again, e.g. after a restart, and cleaned
+// up if the change fails.
+type partialDownload struct {
+	Path     string `json:"path"`
+	Sha3_384 string `json:"sha3-384"`
+}
+
+// trackPartialDownload records the partial download of targetFn in the
+// task, discarding any partial download it recorded before for different
+// content.
+func trackPartialDownload(t *state.Task, targetFn string, downloadInfo *snap.DownloadInfo) error {
+	partial := partialDownload{
+		Path:     store.PartialDownloadPath(targetFn),
+		Sha3_384: downloadInfo.Sha3_384,
+	}
+
+	var prev partialDownload
+	err := t.Get("partial-download", &prev)
+	if err != nil && err != state.ErrNoState {
+		return err
+	}
+	if err == nil && prev != partial {
+		if err := os.Remove(prev.Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	t.Set("partial-download", partial)
+	return nil
+}
+
+func (m *SnapManager) cleanupDownloadSnap(t *state.Task, _ *tomb.Tomb) error {
+	st := t.State()
+	st.Lock()
+	defer st.Unlock()
+
+	var partial partialDownload
+	err := t.Get("partial-download", &partial)
+	if err == state.ErrNoState {
+		// nothing left behind
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(partial.Path); err != nil && !os.IsNotExist(err) {
+		logger.Noticef("Cannot remove partial download %q: %v", partial.Path, err)
+	}
+	return nil
+}
+
 var (
 	mountPollInterval = 1 * time.Second
 )
@@ -467,7 +532,7 @@ func (m *SnapManager) doMountSnap(t *state.Task, _ *tomb.Tomb) error {
 	pb := NewTaskProgressAdapterUnlocked(t)
 	// TODO Use snapsup.Revision() to obtain the right info to mount
 	//      instead of assuming the candidate is the right one.
-	snapType, err := m.backend.SetupSnap(snapsup.SnapPath, snapsup.SideInfo, pb)
+	snapType, err := m.backend.SetupSnap(snapsup.SnapPath, snapsup.Name(), snapsup.SideInfo, pb)
 	if err != nil {
 		return err
 	}
@@ -551,6 +616,25 @@ func (m *SnapManager) doUnlinkCurrentSnap(t *state.Task, _ *tomb.Tomb) error {
 		return err
 	}
 
+	appAwareness, err := refreshAppAwarenessEnabled(st)
+	if err != nil {
+		return err
+	}
+	if appAwareness {
+		// apps may have been started since the refresh was
+		// scheduled, check again before pulling the rug
+		if snapsup.IsAutoRefresh {
+			if err := inhibitRefresh(st, snapst, oldInfo, time.Now()); err != nil {
+				return err
+			}
+		}
+		// keep new apps from starting until the new revision
+		// is linked
+		if err := runinhibit.LockWithHint(snapsup.Name(), runinhibit.HintInhibitedForRefresh); err != nil {
+			return err
+		}
+	}
+
 	// Make a copy of configuration of given snap revision
 	if err = config.SaveRevisionConfig(st, snapsup.Name(), snapst.Current); err != nil {
 		return err
@@ -561,6 +645,9 @@ func (m *SnapManager) doUnlinkCurrentSnap(t *state.Task, _ *tomb.Tomb) error {
 	pb := NewTaskProgressAdapterLocked(t)
 	err = m.backend.UnlinkSnap(oldInfo, pb)
 	if err != nil {
+		if err := runinhibit.Unlock(snapsup.Name()); err != nil {
+			t.Errorf("cannot allow running apps of snap %q again: %v", snapsup.Name(), err)
+		}
 		return err
 	}
 
@@ -590,7 +677,7 @@ func (m *SnapManager) undoUnlinkCurrentSnap(t *state.Task, _ *tomb.Tomb) error {
 	}
 
 	snapst.Active = true
-	err = m.backend.LinkSnap(oldInfo, model)
+	err = m.backend.LinkSnap(oldInfo, model, snapst.LastActiveDisabledServices)
 	if err != nil {
 		return err
 	}
@@ -598,6 +685,10 @@ func (m *SnapManager) undoUnlinkCurrentSnap(t *state.Task, _ *tomb.Tomb) error {
 	// mark as active again
 	Set(st, snapsup.Name(), snapst)
 
+	if err := runinhibit.Unlock(snapsup.Name()); err != nil {
+		return err
+	}
+
 	// if we just put back a previous a core snap, request a restart
 	// so that we switch executing its snapd
 	maybeRestart(t, oldInfo)
@@ -700,6 +791,7 @@ func (m *SnapManager) doLinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	oldCurrent := snapst.Current
 	snapst.Current = cand.Revision
 	snapst.Active = true
+	snapst.InstanceKey = snapsup.InstanceKey
 	oldChannel := snapst.Channel
 	if snapsup.Channel != "" {
 		snapst.Channel = snapsup.Channel
@@ -714,6 +806,8 @@ func (m *SnapManager) doLinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	snapst.JailMode = snapsup.JailMode
 	oldClassic := snapst.Classic
 	snapst.Classic = snapsup.Classic
+	oldRefreshInhibitedTime := snapst.RefreshInhibitedTime
+	snapst.RefreshInhibitedTime = nil
 	if snapsup.Required { // set only on install and left alone on refresh
 		snapst.Required = true
 	}
@@ -743,9 +837,16 @@ func (m *SnapManager) doLinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	// record type
 	snapst.SetType(newInfo.Type)
 
+	// on install, services with install-mode: disable start out
+	// disabled; afterwards the services disabled by the user stay so
+	oldLastActiveDisabledServices := snapst.LastActiveDisabledServices
+	if oldCurrent.Unset() {
+		snapst.LastActiveDisabledServices = installModeDisabledServices(newInfo)
+	}
+
 	// XXX: this block is slightly ugly, find a pattern when we have more examples
 	model, _ := Model(st)
-	err = m.backend.LinkSnap(newInfo, model)
+	err = m.backend.LinkSnap(newInfo, model, snapst.LastActiveDisabledServices)
 	if err != nil {
 		pb := NewTaskProgressAdapterLocked(t)
 		err := m.backend.UnlinkSnap(newInfo, pb)
@@ -778,9 +879,16 @@ func (m *SnapManager) doLinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	t.Set("old-channel", oldChannel)
 	t.Set("old-current", oldCurrent)
 	t.Set("old-candidate-index", oldCandidateIndex)
+	t.Set("old-refresh-inhibited-time", oldRefreshInhibitedTime)
+	t.Set("old-last-active-disabled-services", oldLastActiveDisabledServices)
 	// Do at the end so we only preserve the new state if it worked.
 	Set(st, snapsup.Name(), snapst)
 
+	// the new revision is in place, apps can run again
+	if err := runinhibit.Unlock(snapsup.Name()); err != nil {
+		return err
+	}
+
 	// Compatibility with old snapd: check if we have auto-connect task and
 	// if not, inject it after self (link-snap) for snaps that are not core
 	if newInfo.Type != snap.TypeOS {
@@ -816,6 +924,19 @@ func (m *SnapManager) doLinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	return nil
 }
 
+// installModeDisabledServices returns the names of the services of the
+// snap that are not to be enabled on install.
+func installModeDisabledServices(info *snap.Info) []string {
+	var disabled []string
+	for _, app := range info.Services() {
+		if app.InstallMode == "disable" {
+			disabled = append(disabled, app.Name)
+		}
+	}
+	sort.Strings(disabled)
+	return disabled
+}
+
 // maybeRestart will schedule a reboot or restart as needed for the just linked
 // snap with info if it's a core or kernel snap.
 func maybeRestart(t *state.Task, info *snap.Info) {
@@ -879,6 +1000,14 @@ func (m *SnapManager) undoLinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	if err := t.Get("old-candidate-index", &oldCandidateIndex); err != nil {
 		return err
 	}
+	var oldRefreshInhibitedTime *time.Time
+	if err := t.Get("old-refresh-inhibited-time", &oldRefreshInhibitedTime); err != nil && err != state.ErrNoState {
+		return err
+	}
+	var oldLastActiveDisabledServices []string
+	if err := t.Get("old-last-active-disabled-services", &oldLastActiveDisabledServices); err != nil && err != state.ErrNoState {
+		return err
+	}
 
 	if len(snapst.Sequence) == 1 {
 		if err := m.removeSnapCookie(st, snapsup.Name()); err != nil {
@@ -909,6 +1038,8 @@ func (m *SnapManager) undoLinkSnap(t *state.Task, _ *tomb.Tomb) error {
 	snapst.DevMode = oldDevMode
 	snapst.JailMode = oldJailMode
 	snapst.Classic = oldClassic
+	snapst.RefreshInhibitedTime = oldRefreshInhibitedTime
+	snapst.LastActiveDisabledServices = oldLastActiveDisabledServices
 
 	newInfo, err := readInfo(snapsup.Name(), snapsup.SideInfo, 0)
 	if err != nil {
@@ -1018,8 +1149,9 @@ func (m *SnapManager) startSnapServices(t *state.Task, _ *tomb.Tomb) error {
 	}
 
 	pb := NewTaskProgressAdapterUnlocked(t)
+	disabledSvcs := snapst.LastActiveDisabledServices
 	st.Unlock()
-	err = m.backend.StartServices(svcs, pb)
+	err = m.backend.StartServices(svcs, disabledSvcs, pb)
 	st.Lock()
 	return err
 }
@@ -1050,9 +1182,29 @@ func (m *SnapManager) stopSnapServices(t *state.Task, _ *tomb.Tomb) error {
 
 	pb := NewTaskProgressAdapterUnlocked(t)
 	st.Unlock()
-	err = m.backend.StopServices(svcs, stopReason, pb)
+	var disabledSvcs []string
+	if stopReason != snap.StopReasonRemove {
+		// remember which services were disabled, for when the
+		// snap is linked again
+		disabledSvcs, err = m.backend.QueryDisabledServices(currentInfo, pb)
+	}
+	if err == nil {
+		err = m.backend.StopServices(svcs, stopReason, pb)
+	}
 	st.Lock()
-	return err
+	if err != nil {
+		return err
+	}
+
+	if stopReason != snap.StopReasonRemove {
+		snapsup, snapst, err := snapSetupAndState(t)
+		if err != nil {
+			return err
+		}
+		snapst.LastActiveDisabledServices = disabledSvcs
+		Set(st, snapsup.Name(), snapst)
+	}
+	return nil
 }
 
 func (m *SnapManager) doUnlinkSnap(t *state.Task, _ *tomb.Tomb) error {
@@ -20,8 +20,15 @@
 package snapstate_test
 
 import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
 	. "gopkg.in/check.v1"
 
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/overlord/snapstate"
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/snap"
@@ -204,3 +211,91 @@ func (s *downloadSnapSuite) TestDoUndoDownloadSnap(c *C) {
 	c.Assert(err, Equals, state.ErrNoState)
 
 }
+
+func (s *downloadSnapSuite) TestDoDownloadSnapTracksPartialDownload(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	defer dirs.SetRootDir("/")
+
+	s.state.Lock()
+	si := &snap.SideInfo{
+		RealName: "foo",
+		Revision: snap.R(11),
+	}
+	t := s.state.NewTask("download-snap", "test")
+	snapsup := &snapstate.SnapSetup{
+		SideInfo: si,
+		DownloadInfo: &snap.DownloadInfo{
+			DownloadURL: "http://some-url.com/snap",
+			Sha3_384:    "new-sha3",
+		},
+	}
+	t.Set("snap-setup", snapsup)
+	// a partial download of different content recorded by an earlier run
+	stalePartial := store.PartialDownloadPath(snapsup.MountFile())
+	c.Assert(os.MkdirAll(filepath.Dir(stalePartial), 0755), IsNil)
+	c.Assert(ioutil.WriteFile(stalePartial, []byte("stale"), 0644), IsNil)
+	t.Set("partial-download", map[string]string{
+		"path":     stalePartial,
+		"sha3-384": "old-sha3",
+	})
+	s.state.NewChange("dummy", "...").AddTask(t)
+	s.state.Unlock()
+
+	s.snapmgr.Ensure()
+	s.snapmgr.Wait()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	c.Check(t.Status(), Equals, state.DoneStatus)
+	c.Check(osutil.FileExists(stalePartial), Equals, false)
+	// nothing is left to track once downloaded
+	var partial map[string]string
+	c.Check(t.Get("partial-download", &partial), Equals, state.ErrNoState)
+}
+
+func (s *downloadSnapSuite) TestDoDownloadSnapErrorCleansUpPartialDownload(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	defer dirs.SetRootDir("/")
+
+	s.fakeStore.downloadError = map[string]error{"foo": errors.New("connection lost")}
+
+	s.state.Lock()
+	si := &snap.SideInfo{
+		RealName: "foo",
+		Revision: snap.R(11),
+	}
+	t := s.state.NewTask("download-snap", "test")
+	snapsup := &snapstate.SnapSetup{
+		SideInfo: si,
+		DownloadInfo: &snap.DownloadInfo{
+			DownloadURL: "http://some-url.com/snap",
+			Sha3_384:    "some-sha3",
+		},
+	}
+	t.Set("snap-setup", snapsup)
+	chg := s.state.NewChange("dummy", "...")
+	chg.AddTask(t)
+	s.state.Unlock()
+
+	s.snapmgr.Ensure()
+	s.snapmgr.Wait()
+
+	s.state.Lock()
+	c.Check(t.Status(), Equals, state.ErrorStatus)
+	var partial map[string]string
+	c.Assert(t.Get("partial-download", &partial), IsNil)
+	partialPath := store.PartialDownloadPath(snapsup.MountFile())
+	c.Check(partial, DeepEquals, map[string]string{
+		"path":     partialPath,
+		"sha3-384": "some-sha3",
+	})
+	c.Check(chg.IsReady(), Equals, true)
+	s.state.Unlock()
+
+	// the partial download is removed once the change is done
+	s.snapmgr.Ensure()
+	s.snapmgr.Wait()
+
+	c.Check(osutil.FileExists(partialPath), Equals, false)
+}
@@ -0,0 +1,220 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/configstate/config"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+// maxInhibition is the longest time an automatic refresh of a snap
+// can be postponed because its apps are running.
+var maxInhibition = 14 * 24 * time.Hour
+
+// refreshAppAwarenessEnabled returns whether snapd should take
+// running apps into account when refreshing snaps.
+func refreshAppAwarenessEnabled(st *state.State) (bool, error) {
+	tr := config.NewTransaction(st)
+	var enabled bool
+	if err := tr.GetMaybe("core", "experimental.refresh-app-awareness", &enabled); err != nil {
+		return false, err
+	}
+	return enabled, nil
+}
+
+// BusySnapError indicates that a snap cannot be refreshed because
+// some of its apps or hooks are running.
+type BusySnapError struct {
+	SnapName string
+	// Apps lists the running apps and hooks, if they are known.
+	Apps []string
+	Pids []int
+}
+
+func (err *BusySnapError) Error() string {
+	if len(err.Apps) == 0 {
+		return fmt.Sprintf("snap %q has running processes", err.SnapName)
+	}
+	return fmt.Sprintf("snap %q has running apps (%s)", err.SnapName, strings.Join(err.Apps, ", "))
+}
+
+// pidsOfSnap returns the pids of the processes of the given snap,
+// grouped by their security tag. Processes whose security tag cannot
+// be determined are grouped under the "snap.<name>" tag.
+var pidsOfSnap = func(info *snap.Info) (map[string][]int, error) {
+	procs := filepath.Join(dirs.FreezerCgroupDir, "snap."+info.Name(), "cgroup.procs")
+	f, err := os.Open(procs)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	pids := make(map[string][]int)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		pid, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse pid %q in %s", line, procs)
+		}
+		tag := securityTagOfProcess(info, pid)
+		pids[tag] = append(pids[tag], pid)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return pids, nil
+}
+
+// securityTagOfProcess determines the security tag of a process of
+// the given snap, first from the systemd unit of services and then
+// from the apparmor label of the process.
+func securityTagOfProcess(info *snap.Info, pid int) string {
+	prefix := "snap." + info.Name() + "."
+	procDir := filepath.Join(dirs.GlobalRootDir, "/proc", strconv.Itoa(pid))
+
+	if cgroups, err := ioutil.ReadFile(filepath.Join(procDir, "cgroup")); err == nil {
+		for _, line := range strings.Split(string(cgroups), "\n") {
+			fields := strings.SplitN(line, ":", 3)
+			if len(fields) != 3 || (fields[1] != "name=systemd" && fields[0] != "0") {
+				continue
+			}
+			unit := filepath.Base(fields[2])
+			if strings.HasPrefix(unit, prefix) && strings.HasSuffix(unit, ".service") {
+				return strings.TrimSuffix(unit, ".service")
+			}
+		}
+	}
+
+	if label, err := ioutil.ReadFile(filepath.Join(procDir, "attr", "current")); err == nil {
+		fields := strings.Fields(string(label))
+		if len(fields) > 0 && strings.HasPrefix(fields[0], prefix) {
+			return fields[0]
+		}
+	}
+
+	return "snap." + info.Name()
+}
+
+// nothingRunningRefreshCheck returns a *BusySnapError if any apps or
+// hooks of the snap are running. Services are not considered since
+// they are stopped and restarted as part of the refresh.
+func nothingRunningRefreshCheck(info *snap.Info) error {
+	pidsByTag, err := pidsOfSnap(info)
+	if err != nil {
+		return err
+	}
+
+	services := make(map[string]bool)
+	for _, app := range info.Apps {
+		if app.IsService() {
+			services[app.SecurityTag()] = true
+		}
+	}
+
+	prefix := "snap." + info.Name() + "."
+	var busy BusySnapError
+	for tag, pids := range pidsByTag {
+		if services[tag] {
+			continue
+		}
+		if strings.HasPrefix(tag, prefix) {
+			busy.Apps = append(busy.Apps, strings.TrimPrefix(tag, prefix))
+		}
+		busy.Pids = append(busy.Pids, pids...)
+	}
+	if len(busy.Pids) == 0 {
+		return nil
+	}
+
+	busy.SnapName = info.Name()
+	sort.Strings(busy.Apps)
+	sort.Ints(busy.Pids)
+	return &busy
+}
+
+// inhibitRefresh returns a *BusySnapError if the snap is busy and its
+// refresh can still be postponed. The first time a refresh is
+// postponed is recorded so that it cannot be postponed for longer
+// than maxInhibition.
+func inhibitRefresh(st *state.State, snapst *SnapState, info *snap.Info, now time.Time) error {
+	checkErr := nothingRunningRefreshCheck(info)
+	if checkErr == nil {
+		return nil
+	}
+	if _, ok := checkErr.(*BusySnapError); !ok {
+		logger.Noticef("cannot check for running apps of snap %q: %v", info.Name(), checkErr)
+		return nil
+	}
+
+	if snapst.RefreshInhibitedTime == nil {
+		inhibitedTime := now
+		snapst.RefreshInhibitedTime = &inhibitedTime
+		Set(st, info.Name(), snapst)
+		return checkErr
+	}
+	if now.Sub(*snapst.RefreshInhibitedTime) < maxInhibition {
+		return checkErr
+	}
+	logger.Noticef("refreshing snap %q despite running apps, it was postponed since %s", info.Name(), snapst.RefreshInhibitedTime.Format(time.RFC3339))
+	return nil
+}
+
+// autoRefreshFilter returns an updateFilter that postpones automatic
+// refreshes of busy snaps, or nil if running apps should not be taken
+// into account.
+func autoRefreshFilter(st *state.State) (updateFilter, error) {
+	enabled, err := refreshAppAwarenessEnabled(st)
+	if err != nil || !enabled {
+		return nil, err
+	}
+
+	now := time.Now()
+	return func(update *snap.Info, snapst *SnapState) bool {
+		info, err := snapst.CurrentInfo()
+		if err != nil {
+			return true
+		}
+		if err := inhibitRefresh(st, snapst, info, now); err != nil {
+			logger.Noticef("postponing refresh of snap %q: %v", info.Name(), err)
+			return false
+		}
+		return true
+	}, nil
+}
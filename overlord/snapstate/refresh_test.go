@@ -0,0 +1,180 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+)
+
+type refreshSuite struct {
+	state *state.State
+	info  *snap.Info
+	pids  map[string][]int
+}
+
+var _ = Suite(&refreshSuite{})
+
+const refreshSnapYaml = `
+name: pkg
+version: 1
+apps:
+  app:
+    command: bin/app
+  daemon:
+    command: bin/daemon
+    daemon: simple
+hooks:
+  configure:
+`
+
+func (s *refreshSuite) SetUpTest(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	s.state = state.New(nil)
+	s.info = snaptest.MockInfo(c, refreshSnapYaml, &snap.SideInfo{Revision: snap.R(1)})
+	s.pids = nil
+}
+
+func (s *refreshSuite) TearDownTest(c *C) {
+	dirs.SetRootDir("")
+}
+
+func (s *refreshSuite) mockPids(c *C) func() {
+	return snapstate.MockPidsOfSnap(func(info *snap.Info) (map[string][]int, error) {
+		c.Check(info.Name(), Equals, "pkg")
+		return s.pids, nil
+	})
+}
+
+func (s *refreshSuite) TestPidsOfSnap(c *C) {
+	mockFile := func(path, content string) {
+		path = filepath.Join(dirs.GlobalRootDir, path)
+		c.Assert(os.MkdirAll(filepath.Dir(path), 0755), IsNil)
+		c.Assert(ioutil.WriteFile(path, []byte(content), 0644), IsNil)
+	}
+
+	// no freezer cgroup, nothing is running
+	pids, err := snapstate.PidsOfSnap(s.info)
+	c.Assert(err, IsNil)
+	c.Check(pids, HasLen, 0)
+
+	mockFile("/sys/fs/cgroup/freezer/snap.pkg/cgroup.procs", "101\n102\n103\n")
+	// a service, found via its systemd unit
+	mockFile("/proc/101/cgroup", "5:freezer:/snap.pkg\n1:name=systemd:/system.slice/snap.pkg.daemon.service\n")
+	// an app, found via its apparmor label
+	mockFile("/proc/102/cgroup", "5:freezer:/snap.pkg\n1:name=systemd:/user.slice/user-1000.slice/session-2.scope\n")
+	mockFile("/proc/102/attr/current", "snap.pkg.app (enforce)\n")
+	// something unknown, e.g. unconfined
+
+	pids, err = snapstate.PidsOfSnap(s.info)
+	c.Assert(err, IsNil)
+	c.Check(pids, DeepEquals, map[string][]int{
+		"snap.pkg.daemon": {101},
+		"snap.pkg.app":    {102},
+		"snap.pkg":        {103},
+	})
+
+	mockFile("/sys/fs/cgroup/freezer/snap.pkg/cgroup.procs", "garbage\n")
+	_, err = snapstate.PidsOfSnap(s.info)
+	c.Check(err, ErrorMatches, `cannot parse pid "garbage" in .*/cgroup.procs`)
+}
+
+func (s *refreshSuite) TestNothingRunningRefreshCheck(c *C) {
+	defer s.mockPids(c)()
+
+	// nothing is running
+	c.Check(snapstate.NothingRunningRefreshCheck(s.info), IsNil)
+
+	// services are fine, they are restarted by the refresh
+	s.pids = map[string][]int{
+		"snap.pkg.daemon": {100},
+	}
+	c.Check(snapstate.NothingRunningRefreshCheck(s.info), IsNil)
+
+	// apps and hooks are not
+	s.pids = map[string][]int{
+		"snap.pkg.daemon":         {100},
+		"snap.pkg.app":            {102, 101},
+		"snap.pkg.hook.configure": {103},
+	}
+	err := snapstate.NothingRunningRefreshCheck(s.info)
+	c.Assert(err, ErrorMatches, `snap "pkg" has running apps \(app, hook.configure\)`)
+	c.Check(err.(*snapstate.BusySnapError).Pids, DeepEquals, []int{101, 102, 103})
+
+	// as are processes of unknown origin
+	s.pids = map[string][]int{
+		"snap.pkg": {105},
+	}
+	err = snapstate.NothingRunningRefreshCheck(s.info)
+	c.Assert(err, ErrorMatches, `snap "pkg" has running processes`)
+	c.Check(err.(*snapstate.BusySnapError).Pids, DeepEquals, []int{105})
+}
+
+func (s *refreshSuite) TestInhibitRefresh(c *C) {
+	defer s.mockPids(c)()
+	defer snapstate.MockMaxInhibition(24 * time.Hour)()
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapst := &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{{RealName: "pkg", Revision: snap.R(1)}},
+		Current:  snap.R(1),
+	}
+	snapstate.Set(s.state, "pkg", snapst)
+
+	now := time.Now()
+
+	// not busy, nothing is recorded
+	c.Check(snapstate.InhibitRefresh(s.state, snapst, s.info, now), IsNil)
+	c.Check(snapst.RefreshInhibitedTime, IsNil)
+
+	// busy, the refresh is postponed and the time recorded
+	s.pids = map[string][]int{"snap.pkg.app": {101}}
+	err := snapstate.InhibitRefresh(s.state, snapst, s.info, now)
+	c.Assert(err, FitsTypeOf, &snapstate.BusySnapError{})
+	c.Assert(snapst.RefreshInhibitedTime, NotNil)
+	c.Check(snapst.RefreshInhibitedTime.Equal(now), Equals, true)
+
+	var stored snapstate.SnapState
+	c.Assert(snapstate.Get(s.state, "pkg", &stored), IsNil)
+	c.Assert(stored.RefreshInhibitedTime, NotNil)
+	c.Check(stored.RefreshInhibitedTime.Equal(now), Equals, true)
+
+	// still busy later on, the original time is kept
+	err = snapstate.InhibitRefresh(s.state, snapst, s.info, now.Add(time.Hour))
+	c.Assert(err, FitsTypeOf, &snapstate.BusySnapError{})
+	c.Check(snapst.RefreshInhibitedTime.Equal(now), Equals, true)
+
+	// postponed for too long, the refresh goes ahead
+	err = snapstate.InhibitRefresh(s.state, snapst, s.info, now.Add(25*time.Hour))
+	c.Check(err, IsNil)
+}
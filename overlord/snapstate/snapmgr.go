@@ -36,6 +36,7 @@ import (
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/snap"
 	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/store/offline"
 )
 
 // overridden in the tests
@@ -77,9 +78,20 @@ type SnapSetup struct {
 
 	DownloadInfo *snap.DownloadInfo `json:"download-info,omitempty"`
 	SideInfo     *snap.SideInfo     `json:"side-info,omitempty"`
+
+	// InstanceKey is set by the user during installation and differs for
+	// each instance of given snap
+	InstanceKey string `json:"instance-key,omitempty"`
 }
 
+// Name returns the instance name of the snap, that is the snap name
+// qualified with the instance key, if any.
 func (snapsup *SnapSetup) Name() string {
+	return snap.InstanceName(snapsup.SnapName(), snapsup.InstanceKey)
+}
+
+// SnapName returns the name of the snap as known by the store.
+func (snapsup *SnapSetup) SnapName() string {
 	if snapsup.SideInfo.RealName == "" {
 		panic("SnapSetup.SideInfo.RealName not set")
 	}
@@ -120,6 +132,32 @@ type SnapState struct {
 
 	// UserID of the user requesting the install
 	UserID int `json:"user-id,omitempty"`
+
+	// InstanceKey is set by the user during installation and differs for
+	// each instance of given snap
+	InstanceKey string `json:"instance-key,omitempty"`
+
+	// RefreshHold is set when automatic refreshes of the snap are
+	// held, until the given time or indefinitely if it is zero
+	RefreshHold *time.Time `json:"refresh-hold,omitempty"`
+
+	// RefreshInhibitedTime records when an automatic refresh of the
+	// snap was first postponed because its apps were running
+	RefreshInhibitedTime *time.Time `json:"refresh-inhibited-time,omitempty"`
+
+	// LastActiveDisabledServices holds the names of the services of
+	// the snap that were disabled when it was last active, so that
+	// they stay disabled across refreshes and reverts
+	LastActiveDisabledServices []string `json:"last-active-disabled-services,omitempty"`
+}
+
+// RefreshHeld returns whether automatic refreshes of the snap are
+// held at the given time.
+func (snapst *SnapState) RefreshHeld(now time.Time) bool {
+	if snapst.RefreshHold == nil {
+		return false
+	}
+	return snapst.RefreshHold.IsZero() || snapst.RefreshHold.After(now)
 }
 
 // Type returns the type of the snap or an error.
@@ -229,8 +267,10 @@ func readInfo(name string, si *snap.SideInfo, flags int) (*snap.Info, error) {
 		logger.Noticef("cannot read snap info of snap %q at revision %s: %s", name, si.Revision, err)
 	}
 	if bse, ok := err.(snap.BrokenSnapError); ok {
+		snapName, instanceKey := snap.SplitInstanceName(name)
 		info := &snap.Info{
-			SuggestedName: name,
+			SuggestedName: snapName,
+			InstanceKey:   instanceKey,
 			Broken:        bse.Broken(),
 		}
 		info.Apps = snap.GuessAppsForBroken(info)
@@ -259,7 +299,18 @@ func (snapst *SnapState) CurrentInfo() (*snap.Info, error) {
 	if cur == nil {
 		return nil, ErrNoCurrent
 	}
-	return readInfo(cur.RealName, cur, 0)
+	return readInfo(snapst.InstanceName(), cur, 0)
+}
+
+// InstanceName returns the instance name of the snap, that is the snap
+// name qualified with the instance key, if any. It panics if there is
+// no current revision.
+func (snapst *SnapState) InstanceName() string {
+	cur := snapst.CurrentSideInfo()
+	if cur == nil {
+		panic("no current revision")
+	}
+	return snap.InstanceName(cur.RealName, snapst.InstanceKey)
 }
 
 func revisionInSequence(snapst *SnapState, needle snap.Revision) bool {
@@ -286,17 +337,37 @@ func cachedStore(st *state.State) StoreService {
 	return ubuntuStore.(StoreService)
 }
 
-// the store implementation has the interface consumed here
-var _ StoreService = (*store.Store)(nil)
+// the store implementations have the interface consumed here
+var (
+	_ StoreService = (*store.Store)(nil)
+	_ StoreService = (*offline.Store)(nil)
+)
+
+// hook setup by devicestate
+var (
+	OfflineStore func(st *state.State) StoreService
+)
 
-// Store returns the store service used by the snapstate package.
+// Store returns the store service used by the snapstate package, that
+// is the offline store if one is configured.
 func Store(st *state.State) StoreService {
+	if OfflineStore != nil {
+		if offlineStore := OfflineStore(st); offlineStore != nil {
+			return offlineStore
+		}
+	}
 	if cachedStore := cachedStore(st); cachedStore != nil {
 		return cachedStore
 	}
 	panic("internal error: needing the store before managers have initialized it")
 }
 
+// how many snaps are downloaded and mounted at the same time
+var (
+	maxConcurrentDownloads = 2
+	maxConcurrentMounts    = 2
+)
+
 // Manager returns a new snap manager.
 func Manager(st *state.State) (*SnapManager, error) {
 	runner := state.NewTaskRunner(st)
@@ -326,6 +397,7 @@ func Manager(st *state.State) (*SnapManager, error) {
 	runner.AddHandler("prerequisites", m.doPrerequisites, nil)
 	runner.AddHandler("prepare-snap", m.doPrepareSnap, m.undoPrepareSnap)
 	runner.AddHandler("download-snap", m.doDownloadSnap, m.undoPrepareSnap)
+	runner.AddCleanup("download-snap", m.cleanupDownloadSnap)
 	runner.AddHandler("mount-snap", m.doMountSnap, m.undoMountSnap)
 	runner.AddHandler("unlink-current-snap", m.doUnlinkCurrentSnap, m.undoUnlinkCurrentSnap)
 	runner.AddHandler("copy-snap-data", m.doCopySnapData, m.undoCopySnapData)
@@ -364,6 +436,10 @@ func Manager(st *state.State) (*SnapManager, error) {
 	// control serialisation
 	runner.SetBlocked(m.blockedTask)
 
+	// don't download and mount all the snaps of a big refresh at once
+	runner.SetMaxRunningKind("download-snap", maxConcurrentDownloads)
+	runner.SetMaxRunningKind("mount-snap", maxConcurrentMounts)
+
 	writeSnapReadme()
 
 	return m, nil
@@ -22,6 +22,7 @@ package snapstate
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"sort"
@@ -352,6 +353,19 @@ var snapTopicalTasks = map[string]bool{
 	"disconnect":          true,
 }
 
+// AffectedSnapsFunc returns the names of the snaps affected by the
+// given task, for use in conflict checks.
+type AffectedSnapsFunc func(*state.Task) ([]string, error)
+
+var affectedSnapsByKind = make(map[string]AffectedSnapsFunc)
+
+// AddAffectedSnapsByKind adds an AffectedSnapsFunc for the given task
+// kind, so that in-progress tasks of that kind, owned by other
+// managers, conflict with operations on the snaps they affect.
+func AddAffectedSnapsByKind(kind string, f AffectedSnapsFunc) {
+	affectedSnapsByKind[kind] = f
+}
+
 func getPlugAndSlotRefs(task *state.Task) (*interfaces.PlugRef, *interfaces.SlotRef, error) {
 	var plugRef interfaces.PlugRef
 	var slotRef interfaces.SlotRef
@@ -432,6 +446,16 @@ func CheckChangeConflictMany(st *state.State, snapNames []string, checkConflictP
 					return changeConflictError{snapName, chg.Kind()}
 				}
 			}
+		} else if f := affectedSnapsByKind[k]; f != nil && (chg == nil || !chg.Status().Ready()) {
+			affectedSnaps, err := f(task)
+			if err != nil {
+				return err
+			}
+			for _, snapName := range affectedSnaps {
+				if snapMap[snapName] && (checkConflictPredicate == nil || checkConflictPredicate(task)) {
+					return changeConflictError{snapName, chg.Kind()}
+				}
+			}
 		}
 	}
 
@@ -560,20 +584,29 @@ func defaultContentPlugProviders(st *state.State, info *snap.Info) []string {
 // validateFeatureFlags validates the given snap only uses experimental
 // features that are enabled by the user.
 func validateFeatureFlags(st *state.State, info *snap.Info) error {
-	if len(info.Layout) == 0 {
-		return nil
-	}
-
 	tr := config.NewTransaction(st)
-	var featureFlagLayouts bool
-	if err := tr.GetMaybe("core", "experimental.layouts", &featureFlagLayouts); err != nil {
-		return err
+
+	if len(info.Layout) > 0 {
+		var featureFlagLayouts bool
+		if err := tr.GetMaybe("core", "experimental.layouts", &featureFlagLayouts); err != nil {
+			return err
+		}
+		if !featureFlagLayouts {
+			return fmt.Errorf("cannot use experimental 'layouts' feature, set option 'experimental.layouts' to true and try again")
+		}
 	}
-	if featureFlagLayouts {
-		return nil
+
+	if info.InstanceKey != "" {
+		var featureFlagParallelInstances bool
+		if err := tr.GetMaybe("core", "experimental.parallel-instances", &featureFlagParallelInstances); err != nil {
+			return err
+		}
+		if !featureFlagParallelInstances {
+			return fmt.Errorf("cannot use experimental 'parallel-instances' feature, set option 'experimental.parallel-instances' to true and try again")
+		}
 	}
 
-	return fmt.Errorf("cannot use experimental 'layouts' feature, set option 'experimental.layouts' to true and try again")
+	return nil
 }
 
 // InstallPath returns a set of tasks for installing snap from a file path.
@@ -619,6 +652,9 @@ func InstallPath(st *state.State, si *snap.SideInfo, path, channel string, flags
 	if err := validateFeatureFlags(st, info); err != nil {
 		return nil, err
 	}
+	if err := checkValidationSetsForInstall(st, info); err != nil {
+		return nil, err
+	}
 
 	snapsup := &SnapSetup{
 		Base:     info.Base,
@@ -648,6 +684,10 @@ func Install(st *state.State, name, channel string, revision snap.Revision, user
 		channel = "stable"
 	}
 
+	if err := snap.ValidateInstanceName(name); err != nil {
+		return nil, err
+	}
+
 	var snapst SnapState
 	err := Get(st, name, &snapst)
 	if err != nil && err != state.ErrNoState {
@@ -657,10 +697,22 @@ func Install(st *state.State, name, channel string, revision snap.Revision, user
 		return nil, &snap.AlreadyInstalledError{Snap: name}
 	}
 
-	info, err := installInfo(st, name, channel, revision, userID)
+	snapName, instanceKey := snap.SplitInstanceName(name)
+	if revision.Unset() {
+		// install the revision required by enforced validation sets
+		revision, err = requiredRevision(st, snapName)
+		if err != nil {
+			return nil, err
+		}
+	}
+	info, err := installInfo(st, snapName, channel, revision, userID)
 	if err != nil {
 		return nil, err
 	}
+	info.InstanceKey = instanceKey
+	if instanceKey != "" && info.Type != snap.TypeApp {
+		return nil, fmt.Errorf("cannot install snap of type %v as %q", info.Type, name)
+	}
 
 	if err := validateInfoAndFlags(info, &snapst, flags); err != nil {
 		return nil, err
@@ -668,6 +720,9 @@ func Install(st *state.State, name, channel string, revision snap.Revision, user
 	if err := validateFeatureFlags(st, info); err != nil {
 		return nil, err
 	}
+	if err := checkValidationSetsForInstall(st, info); err != nil {
+		return nil, err
+	}
 
 	snapsup := &SnapSetup{
 		Channel:      channel,
@@ -678,6 +733,7 @@ func Install(st *state.State, name, channel string, revision snap.Revision, user
 		DownloadInfo: &info.DownloadInfo,
 		SideInfo:     &info.SideInfo,
 		Type:         info.Type,
+		InstanceKey:  info.InstanceKey,
 	}
 
 	return doInstall(st, &snapst, snapsup, needsMaybeCore(info.Type))
@@ -720,16 +776,38 @@ var ValidateRefreshes func(st *state.State, refreshes []*snap.Info, ignoreValida
 // store says is updateable. If the list is empty, update everything.
 // Note that the state must be locked by the caller.
 func UpdateMany(ctx context.Context, st *state.State, names []string, userID int) ([]string, []*state.TaskSet, error) {
+	return updateManyFiltered(ctx, st, names, userID, nil, nil)
+}
+
+// updateFilter is the type of functions that decide whether a
+// candidate update of a snap should go ahead.
+type updateFilter func(update *snap.Info, snapst *SnapState) bool
+
+func updateManyFiltered(ctx context.Context, st *state.State, names []string, userID int, filter updateFilter, flags *Flags) ([]string, []*state.TaskSet, error) {
+	if flags == nil {
+		flags = &Flags{}
+	}
+
 	user, err := userFromUserID(st, userID)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	updates, stateByID, ignoreValidation, err := refreshCandidates(ctx, st, names, user, nil)
+	updates, stateByInstanceName, ignoreValidation, err := refreshCandidates(ctx, st, names, user, nil)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	if filter != nil {
+		actual := updates[:0]
+		for _, update := range updates {
+			if filter(update, stateByInstanceName[update.Name()]) {
+				actual = append(actual, update)
+			}
+		}
+		updates = actual
+	}
+
 	if ValidateRefreshes != nil && len(updates) != 0 {
 		updates, err = ValidateRefreshes(st, updates, ignoreValidation, userID)
 		if err != nil {
@@ -743,8 +821,10 @@ func UpdateMany(ctx context.Context, st *state.State, names []string, userID int
 	}
 
 	params := func(update *snap.Info) (string, Flags, *SnapState) {
-		snapst := stateByID[update.SnapID]
-		return snapst.Channel, snapst.Flags, snapst
+		snapst := stateByInstanceName[update.Name()]
+		updateFlags := snapst.Flags
+		updateFlags.IsAutoRefresh = flags.IsAutoRefresh
+		return snapst.Channel, updateFlags, snapst
 
 	}
 
@@ -821,6 +901,13 @@ func doUpdate(st *state.State, names []string, updates []*snap.Info, params func
 			}
 			return nil, nil, err
 		}
+		if err := checkValidationSetsForInstall(st, update); err != nil {
+			if refreshAll {
+				logger.Noticef("cannot update %q: %v", update.Name(), err)
+				continue
+			}
+			return nil, nil, err
+		}
 
 		snapUserID, err := userIDForSnap(st, snapst, userID)
 		if err != nil {
@@ -836,6 +923,7 @@ func doUpdate(st *state.State, names []string, updates []*snap.Info, params func
 			DownloadInfo: &update.DownloadInfo,
 			SideInfo:     &update.SideInfo,
 			Type:         update.Type,
+			InstanceKey:  update.InstanceKey,
 		}
 
 		ts, err := doInstall(st, snapst, snapsup, needsMaybeCore(update.Type))
@@ -1035,8 +1123,9 @@ func Switch(st *state.State, name, channel string) (*state.TaskSet, error) {
 	}
 
 	snapsup := &SnapSetup{
-		SideInfo: snapst.CurrentSideInfo(),
-		Channel:  channel,
+		SideInfo:    snapst.CurrentSideInfo(),
+		InstanceKey: snapst.InstanceKey,
+		Channel:     channel,
 	}
 
 	switchSnap := st.NewTask("switch-snap", fmt.Sprintf(i18n.G("Switch snap %q to %s"), snapsup.Name(), snapsup.Channel))
@@ -1100,8 +1189,9 @@ func Update(st *state.State, name, channel string, revision snap.Revision, userI
 		}
 
 		snapsup := &SnapSetup{
-			SideInfo: snapst.CurrentSideInfo(),
-			Flags:    snapst.Flags.ForSnapSetup(),
+			SideInfo:    snapst.CurrentSideInfo(),
+			Flags:       snapst.Flags.ForSnapSetup(),
+			InstanceKey: snapst.InstanceKey,
 		}
 
 		if snapst.Channel != channel {
@@ -1201,7 +1291,12 @@ func AutoRefresh(ctx context.Context, st *state.State) ([]string, []*state.TaskS
 		}
 	}
 
-	return UpdateMany(ctx, st, nil, userID)
+	filter, err := autoRefreshFilter(st)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return updateManyFiltered(ctx, st, nil, userID, filter, &Flags{IsAutoRefresh: true})
 }
 
 // Enable sets a snap to the active state
@@ -1224,8 +1319,9 @@ func Enable(st *state.State, name string) (*state.TaskSet, error) {
 	}
 
 	snapsup := &SnapSetup{
-		SideInfo: snapst.CurrentSideInfo(),
-		Flags:    snapst.Flags.ForSnapSetup(),
+		SideInfo:    snapst.CurrentSideInfo(),
+		Flags:       snapst.Flags.ForSnapSetup(),
+		InstanceKey: snapst.InstanceKey,
 	}
 
 	prepareSnap := st.NewTask("prepare-snap", fmt.Sprintf(i18n.G("Prepare snap %q (%s)"), snapsup.Name(), snapst.Current))
@@ -1251,6 +1347,43 @@ func Enable(st *state.State, name string) (*state.TaskSet, error) {
 	return state.NewTaskSet(prepareSnap, setupProfiles, linkSnap, setupAliases, startSnapServices), nil
 }
 
+// HoldRefresh holds automatic refreshes of the snap until the given
+// time, or indefinitely if until is zero. Refreshes explicitly asking
+// for the snap are not affected.
+func HoldRefresh(st *state.State, name string, until time.Time) error {
+	var snapst SnapState
+	err := Get(st, name, &snapst)
+	if err != nil && err != state.ErrNoState {
+		return err
+	}
+	if !snapst.IsInstalled() {
+		return &snap.NotInstalledError{Snap: name}
+	}
+	if !until.IsZero() && !until.After(time.Now()) {
+		return fmt.Errorf("cannot hold refreshes of snap %q until a time in the past", name)
+	}
+
+	snapst.RefreshHold = &until
+	Set(st, name, &snapst)
+	return nil
+}
+
+// UnholdRefresh lets the snap be automatically refreshed again.
+func UnholdRefresh(st *state.State, name string) error {
+	var snapst SnapState
+	err := Get(st, name, &snapst)
+	if err != nil && err != state.ErrNoState {
+		return err
+	}
+	if !snapst.IsInstalled() {
+		return &snap.NotInstalledError{Snap: name}
+	}
+
+	snapst.RefreshHold = nil
+	Set(st, name, &snapst)
+	return nil
+}
+
 // Disable sets a snap to the inactive state
 func Disable(st *state.State, name string) (*state.TaskSet, error) {
 	var snapst SnapState
@@ -1277,11 +1410,13 @@ func Disable(st *state.State, name string) (*state.TaskSet, error) {
 		return nil, err
 	}
 
+	snapName, instanceKey := snap.SplitInstanceName(name)
 	snapsup := &SnapSetup{
 		SideInfo: &snap.SideInfo{
-			RealName: name,
+			RealName: snapName,
 			Revision: snapst.Current,
 		},
+		InstanceKey: instanceKey,
 	}
 
 	stopSnapServices := st.NewTask("stop-snap-services", fmt.Sprintf(i18n.G("Stop snap %q (%s) services"), snapsup.Name(), snapst.Current))
@@ -1365,9 +1500,24 @@ func canRemove(si *snap.Info, snapst *SnapState, removeAll bool) bool {
 	return true
 }
 
+// hook setup by snapshotstate
+var (
+	AutomaticSnapshot func(st *state.State, snapName string) (ts *state.TaskSet, err error)
+)
+
+// ErrNothingToDo is returned by hooks such as AutomaticSnapshot when
+// there is nothing for them to do.
+var ErrNothingToDo = errors.New("nothing to do")
+
+// RemoveFlags are used to pass additional flags to the Remove operation.
+type RemoveFlags struct {
+	// Remove the snap without creating an automatic snapshot of its data
+	Purge bool
+}
+
 // Remove returns a set of tasks for removing snap.
 // Note that the state must be locked by the caller.
-func Remove(st *state.State, name string, revision snap.Revision) (*state.TaskSet, error) {
+func Remove(st *state.State, name string, revision snap.Revision, flags *RemoveFlags) (*state.TaskSet, error) {
 	var snapst SnapState
 	err := Get(st, name, &snapst)
 	if err != nil && err != state.ErrNoState {
@@ -1415,13 +1565,20 @@ func Remove(st *state.State, name string, revision snap.Revision) (*state.TaskSe
 	if !canRemove(info, &snapst, removeAll) {
 		return nil, fmt.Errorf("snap %q is not removable", name)
 	}
+	if removeAll {
+		if err := checkValidationSetsForRemove(st, info.SnapName(), info.SnapID); err != nil {
+			return nil, err
+		}
+	}
 
 	// main/current SnapSetup
+	snapName, instanceKey := snap.SplitInstanceName(name)
 	snapsup := SnapSetup{
 		SideInfo: &snap.SideInfo{
-			RealName: name,
+			RealName: snapName,
 			Revision: revision,
 		},
+		InstanceKey: instanceKey,
 	}
 
 	// trigger remove
@@ -1458,6 +1615,15 @@ func Remove(st *state.State, name string, revision snap.Revision) (*state.TaskSe
 			prev = removeHook
 		}
 
+		if removeAll {
+			// run the disconnect hooks of all the connections of the snap
+			autoDisconnect := st.NewTask("auto-disconnect", fmt.Sprintf(i18n.G("Disconnect interfaces of snap %q"), name))
+			autoDisconnect.Set("snap-setup-task", stopSnapServices.ID())
+			autoDisconnect.WaitFor(prev)
+			tasks = append(tasks, autoDisconnect)
+			prev = autoDisconnect
+		}
+
 		removeAliases := st.NewTask("remove-aliases", fmt.Sprintf(i18n.G("Remove aliases for snap %q"), name))
 		removeAliases.WaitFor(prev)
 		removeAliases.Set("snap-setup-task", stopSnapServices.ID())
@@ -1476,6 +1642,21 @@ func Remove(st *state.State, name string, revision snap.Revision) (*state.TaskSe
 		addNext(state.NewTaskSet(removeHook))
 	}
 
+	// take an automatic snapshot of the data of app snaps before it
+	// is cleared, unless asked not to
+	purge := flags != nil && flags.Purge
+	if removeAll && !purge && info.Type == snap.TypeApp && AutomaticSnapshot != nil {
+		ts, err := AutomaticSnapshot(st, name)
+		switch err {
+		case nil:
+			addNext(ts)
+		case ErrNothingToDo:
+			// automatic snapshots are disabled
+		default:
+			return nil, err
+		}
+	}
+
 	if removeAll {
 		seq := snapst.Sequence
 		for i := len(seq) - 1; i >= 0; i-- {
@@ -1486,8 +1667,9 @@ func Remove(st *state.State, name string, revision snap.Revision) (*state.TaskSe
 		discardConns := st.NewTask("discard-conns", fmt.Sprintf(i18n.G("Discard interface connections for snap %q (%s)"), name, revision))
 		discardConns.Set("snap-setup", &SnapSetup{
 			SideInfo: &snap.SideInfo{
-				RealName: name,
+				RealName: snapName,
 			},
+			InstanceKey: instanceKey,
 		})
 		addNext(state.NewTaskSet(discardConns))
 	} else {
@@ -1498,11 +1680,13 @@ func Remove(st *state.State, name string, revision snap.Revision) (*state.TaskSe
 }
 
 func removeInactiveRevision(st *state.State, name string, revision snap.Revision) *state.TaskSet {
+	snapName, instanceKey := snap.SplitInstanceName(name)
 	snapsup := SnapSetup{
 		SideInfo: &snap.SideInfo{
-			RealName: name,
+			RealName: snapName,
 			Revision: revision,
 		},
+		InstanceKey: instanceKey,
 	}
 
 	clearData := st.NewTask("clear-snap", fmt.Sprintf(i18n.G("Remove data for snap %q (%s)"), name, revision))
@@ -1521,7 +1705,7 @@ func RemoveMany(st *state.State, names []string) ([]string, []*state.TaskSet, er
 	removed := make([]string, 0, len(names))
 	tasksets := make([]*state.TaskSet, 0, len(names))
 	for _, name := range names {
-		ts, err := Remove(st, name, snap.R(0))
+		ts, err := Remove(st, name, snap.R(0), nil)
 		// FIXME: is this expected behavior?
 		if _, ok := err.(*snap.NotInstalledError); ok {
 			continue
@@ -1590,8 +1774,9 @@ func RevertToRevision(st *state.State, name string, rev snap.Revision, flags Fla
 		}
 	}
 	snapsup := &SnapSetup{
-		SideInfo: snapst.Sequence[i],
-		Flags:    flags.ForSnapSetup(),
+		SideInfo:    snapst.Sequence[i],
+		Flags:       flags.ForSnapSetup(),
+		InstanceKey: snapst.InstanceKey,
 	}
 	return doInstall(st, &snapst, snapsup, needsMaybeCore(typ))
 }
@@ -1658,7 +1843,7 @@ func TransitionCore(st *state.State, oldName, newName string) ([]*state.TaskSet,
 	})
 
 	// then remove the old snap
-	tsRm, err := Remove(st, oldName, snap.R(0))
+	tsRm, err := Remove(st, oldName, snap.R(0), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -1848,9 +2033,24 @@ func GadgetInfo(st *state.State) (*snap.Info, error) {
 	return infoForType(st, snap.TypeGadget)
 }
 
-// KernelInfo finds the current kernel snap's info.
+// KernelInfo finds the current kernel snap's info. If more than one
+// kernel is installed, as happens after a remodel, the one the model
+// uses is returned.
 func KernelInfo(st *state.State) (*snap.Info, error) {
-	return infoForType(st, snap.TypeKernel)
+	res, err := infosForTypes(st, snap.TypeKernel)
+	if err != nil {
+		return nil, err
+	}
+	if len(res) > 1 && Model != nil {
+		if model, err := Model(st); err == nil && model != nil {
+			for _, info := range res {
+				if info.Name() == model.Kernel() {
+					return info, nil
+				}
+			}
+		}
+	}
+	return res[0], nil
 }
 
 // CoreInfo finds the current OS snap's info. If both
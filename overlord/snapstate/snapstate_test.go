@@ -36,6 +36,7 @@ import (
 	. "gopkg.in/check.v1"
 
 	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
 	"github.com/snapcore/snapd/dirs"
 	"github.com/snapcore/snapd/interfaces"
 	"github.com/snapcore/snapd/logger"
@@ -49,6 +50,7 @@ import (
 	"github.com/snapcore/snapd/overlord/state"
 	"github.com/snapcore/snapd/release"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/runinhibit"
 	"github.com/snapcore/snapd/snap/snaptest"
 	"github.com/snapcore/snapd/store"
 	"github.com/snapcore/snapd/testutil"
@@ -171,6 +173,7 @@ func (s *snapmgrTestSuite) SetUpTest(c *C) {
 func (s *snapmgrTestSuite) TearDownTest(c *C) {
 	s.BaseTest.TearDownTest(c)
 	snapstate.ValidateRefreshes = nil
+	snapstate.EnforcedValidationSets = nil
 	snapstate.AutoAliases = nil
 	snapstate.CanAutoRefresh = nil
 }
@@ -181,6 +184,7 @@ func (s *snapmgrTestSuite) TestKnownTaskKinds(c *C) {
 	c.Assert(kinds, DeepEquals, []string{
 		"alias",
 		"auto-connect",
+		"auto-disconnect",
 		"cleanup",
 		"clear-aliases",
 		"clear-snap",
@@ -392,6 +396,7 @@ func verifyRemoveTasks(c *C, ts *state.TaskSet) {
 	c.Assert(taskKinds(ts.Tasks()), DeepEquals, []string{
 		"stop-snap-services",
 		"run-hook[remove]",
+		"auto-disconnect",
 		"remove-aliases",
 		"unlink-snap",
 		"remove-profiles",
@@ -838,6 +843,356 @@ func (s *snapmgrTestSuite) TestUpdateAllDevMode(c *C) {
 	c.Check(updates, HasLen, 0)
 }
 
+func (s *snapmgrTestSuite) TestUpdateAllRefreshHeld(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	forever := time.Time{}
+	tomorrow := time.Now().Add(24 * time.Hour)
+	yesterday := time.Now().Add(-24 * time.Hour)
+	for _, t := range []struct {
+		hold    *time.Time
+		updates int
+	}{
+		{nil, 1},
+		{&forever, 0},
+		{&tomorrow, 0},
+		// expired holds don't matter
+		{&yesterday, 1},
+	} {
+		snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+			Active: true,
+			Sequence: []*snap.SideInfo{
+				{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(1)},
+			},
+			Current:     snap.R(1),
+			SnapType:    "app",
+			RefreshHold: t.hold,
+		})
+
+		updates, _, err := snapstate.UpdateMany(context.TODO(), s.state, nil, 0)
+		c.Assert(err, IsNil)
+		c.Check(updates, HasLen, t.updates, Commentf("%v", t.hold))
+	}
+}
+
+func (s *snapmgrTestSuite) TestUpdateManyRefreshHeldExplicitly(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(1)},
+		},
+		Current:     snap.R(1),
+		SnapType:    "app",
+		RefreshHold: &time.Time{},
+	})
+
+	// naming the snap refreshes it regardless of the hold
+	updates, _, err := snapstate.UpdateMany(context.TODO(), s.state, []string{"some-snap"}, 0)
+	c.Assert(err, IsNil)
+	c.Check(updates, HasLen, 1)
+}
+
+func (s *snapmgrTestSuite) enableRefreshAppAwareness() {
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "experimental.refresh-app-awareness", true)
+	tr.Commit()
+}
+
+func (s *snapmgrTestSuite) TestAutoRefreshPostponesBusySnap(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.enableRefreshAppAwareness()
+	defer snapstate.MockMaxInhibition(24 * time.Hour)()
+
+	var pids map[string][]int
+	defer snapstate.MockPidsOfSnap(func(info *snap.Info) (map[string][]int, error) {
+		c.Check(info.Name(), Equals, "some-snap")
+		return pids, nil
+	})()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(1)},
+		},
+		Current:  snap.R(1),
+		SnapType: "app",
+	})
+
+	// busy, the refresh is postponed
+	pids = map[string][]int{"snap.some-snap.app": {42}}
+	updates, tss, err := snapstate.AutoRefresh(context.TODO(), s.state)
+	c.Assert(err, IsNil)
+	c.Check(updates, HasLen, 0)
+	c.Check(tss, HasLen, 0)
+
+	var snapst snapstate.SnapState
+	c.Assert(snapstate.Get(s.state, "some-snap", &snapst), IsNil)
+	c.Assert(snapst.RefreshInhibitedTime, NotNil)
+	inhibitedTime := *snapst.RefreshInhibitedTime
+
+	// explicit refreshes are not postponed
+	updates, _, err = snapstate.UpdateMany(context.TODO(), s.state, nil, 0)
+	c.Assert(err, IsNil)
+	c.Check(updates, DeepEquals, []string{"some-snap"})
+
+	// postponed for too long
+	snapst.RefreshInhibitedTime = &time.Time{}
+	snapstate.Set(s.state, "some-snap", &snapst)
+	updates, _, err = snapstate.AutoRefresh(context.TODO(), s.state)
+	c.Assert(err, IsNil)
+	c.Check(updates, DeepEquals, []string{"some-snap"})
+
+	// not busy anymore
+	snapst.RefreshInhibitedTime = &inhibitedTime
+	snapstate.Set(s.state, "some-snap", &snapst)
+	pids = nil
+	updates, tss, err = snapstate.AutoRefresh(context.TODO(), s.state)
+	c.Assert(err, IsNil)
+	c.Check(updates, DeepEquals, []string{"some-snap"})
+	c.Assert(tss, HasLen, 1)
+	snapsup, err := snapstate.TaskSnapSetup(tss[0].Tasks()[0])
+	c.Assert(err, IsNil)
+	c.Check(snapsup.IsAutoRefresh, Equals, true)
+}
+
+func (s *snapmgrTestSuite) TestAutoRefreshIgnoresBusySnapWithoutFeature(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	defer snapstate.MockPidsOfSnap(func(info *snap.Info) (map[string][]int, error) {
+		return map[string][]int{"snap.some-snap.app": {42}}, nil
+	})()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(1)},
+		},
+		Current:  snap.R(1),
+		SnapType: "app",
+	})
+
+	updates, _, err := snapstate.AutoRefresh(context.TODO(), s.state)
+	c.Assert(err, IsNil)
+	c.Check(updates, DeepEquals, []string{"some-snap"})
+}
+
+func (s *snapmgrTestSuite) TestUpdateInhibitsRunningAppsUntilLinked(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.enableRefreshAppAwareness()
+
+	var hints []runinhibit.Hint
+	s.fakeBackend.linkSnapHook = func(info *snap.Info) {
+		hint, err := runinhibit.IsLocked(info.Name())
+		c.Check(err, IsNil)
+		hints = append(hints, hint)
+	}
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7)},
+		},
+		Current:              snap.R(7),
+		SnapType:             "app",
+		RefreshInhibitedTime: &time.Time{},
+	})
+
+	chg := s.state.NewChange("refresh", "refresh a snap")
+	ts, err := snapstate.Update(s.state, "some-snap", "some-channel", snap.R(0), s.user.ID, snapstate.Flags{})
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	c.Check(hints, DeepEquals, []runinhibit.Hint{runinhibit.HintInhibitedForRefresh})
+	hint, err := runinhibit.IsLocked("some-snap")
+	c.Assert(err, IsNil)
+	c.Check(hint, Equals, runinhibit.HintNotInhibited)
+
+	var snapst snapstate.SnapState
+	c.Assert(snapstate.Get(s.state, "some-snap", &snapst), IsNil)
+	c.Check(snapst.RefreshInhibitedTime, IsNil)
+}
+
+func (s *snapmgrTestSuite) TestUpdateUndoLiftsRunInhibition(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.enableRefreshAppAwareness()
+
+	inhibitedTime := time.Now().Add(-time.Hour)
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7)},
+		},
+		Current:              snap.R(7),
+		SnapType:             "app",
+		RefreshInhibitedTime: &inhibitedTime,
+	})
+
+	chg := s.state.NewChange("refresh", "refresh a snap")
+	ts, err := snapstate.Update(s.state, "some-snap", "some-channel", snap.R(0), s.user.ID, snapstate.Flags{})
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.fakeBackend.linkSnapFailTrigger = filepath.Join(dirs.SnapMountDir, "/some-snap/11")
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), NotNil)
+	hint, err := runinhibit.IsLocked("some-snap")
+	c.Assert(err, IsNil)
+	c.Check(hint, Equals, runinhibit.HintNotInhibited)
+
+	var snapst snapstate.SnapState
+	c.Assert(snapstate.Get(s.state, "some-snap", &snapst), IsNil)
+	c.Check(snapst.Current, Equals, snap.R(7))
+	c.Assert(snapst.RefreshInhibitedTime, NotNil)
+	c.Check(snapst.RefreshInhibitedTime.Equal(inhibitedTime), Equals, true)
+}
+
+func (s *snapmgrTestSuite) TestAutoRefreshBusyAtUnlink(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.enableRefreshAppAwareness()
+	defer snapstate.MockPidsOfSnap(func(info *snap.Info) (map[string][]int, error) {
+		return map[string][]int{"snap.some-snap.app": {42}}, nil
+	})()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7)},
+		},
+		Current:  snap.R(7),
+		SnapType: "app",
+	})
+
+	// the app was started after the refresh was scheduled
+	chg := s.state.NewChange("auto-refresh", "refresh a snap")
+	ts, err := snapstate.Update(s.state, "some-snap", "some-channel", snap.R(0), s.user.ID, snapstate.Flags{IsAutoRefresh: true})
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Check(chg.Err(), ErrorMatches, `(?s).*snap "some-snap" has running apps \(app\).*`)
+	hint, err := runinhibit.IsLocked("some-snap")
+	c.Assert(err, IsNil)
+	c.Check(hint, Equals, runinhibit.HintNotInhibited)
+
+	var snapst snapstate.SnapState
+	c.Assert(snapstate.Get(s.state, "some-snap", &snapst), IsNil)
+	c.Check(snapst.Current, Equals, snap.R(7))
+	c.Check(snapst.Active, Equals, true)
+	c.Check(snapst.RefreshInhibitedTime, NotNil)
+}
+
+func (s *snapmgrTestSuite) TestUpdateBusyNotAutoRefresh(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.enableRefreshAppAwareness()
+	defer snapstate.MockPidsOfSnap(func(info *snap.Info) (map[string][]int, error) {
+		return map[string][]int{"snap.some-snap.app": {42}}, nil
+	})()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7)},
+		},
+		Current:  snap.R(7),
+		SnapType: "app",
+	})
+
+	// refreshes requested by the user go ahead
+	chg := s.state.NewChange("refresh", "refresh a snap")
+	ts, err := snapstate.Update(s.state, "some-snap", "some-channel", snap.R(0), s.user.ID, snapstate.Flags{})
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	var snapst snapstate.SnapState
+	c.Assert(snapstate.Get(s.state, "some-snap", &snapst), IsNil)
+	c.Check(snapst.Current, Equals, snap.R(11))
+}
+
+func (s *snapmgrTestSuite) TestHoldAndUnholdRefresh(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	err := snapstate.HoldRefresh(s.state, "some-snap", time.Time{})
+	c.Check(err, DeepEquals, &snap.NotInstalledError{Snap: "some-snap"})
+	err = snapstate.UnholdRefresh(s.state, "some-snap")
+	c.Check(err, DeepEquals, &snap.NotInstalledError{Snap: "some-snap"})
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(1)},
+		},
+		Current:  snap.R(1),
+		SnapType: "app",
+	})
+
+	now := time.Now()
+	err = snapstate.HoldRefresh(s.state, "some-snap", now.Add(-time.Hour))
+	c.Check(err, ErrorMatches, `cannot hold refreshes of snap "some-snap" until a time in the past`)
+
+	var snapst snapstate.SnapState
+	// held until a given time
+	until := now.Add(time.Hour)
+	err = snapstate.HoldRefresh(s.state, "some-snap", until)
+	c.Assert(err, IsNil)
+	c.Assert(snapstate.Get(s.state, "some-snap", &snapst), IsNil)
+	c.Assert(snapst.RefreshHold, NotNil)
+	c.Check(snapst.RefreshHold.Equal(until), Equals, true)
+	c.Check(snapst.RefreshHeld(now), Equals, true)
+	c.Check(snapst.RefreshHeld(now.Add(2*time.Hour)), Equals, false)
+
+	// held forever
+	err = snapstate.HoldRefresh(s.state, "some-snap", time.Time{})
+	c.Assert(err, IsNil)
+	c.Assert(snapstate.Get(s.state, "some-snap", &snapst), IsNil)
+	c.Assert(snapst.RefreshHold, NotNil)
+	c.Check(snapst.RefreshHold.IsZero(), Equals, true)
+	c.Check(snapst.RefreshHeld(now.Add(24*365*time.Hour)), Equals, true)
+
+	err = snapstate.UnholdRefresh(s.state, "some-snap")
+	c.Assert(err, IsNil)
+	snapst = snapstate.SnapState{}
+	c.Assert(snapstate.Get(s.state, "some-snap", &snapst), IsNil)
+	c.Check(snapst.RefreshHold, IsNil)
+	c.Check(snapst.RefreshHeld(now), Equals, false)
+}
+
 func (s *snapmgrTestSuite) TestByKindOrder(c *C) {
 	core := &snap.Info{Type: snap.TypeOS}
 	base := &snap.Info{Type: snap.TypeBase}
@@ -1620,13 +1975,93 @@ func (s *snapmgrTestSuite) TestRemoveTasks(c *C) {
 		Current: snap.R(11),
 	})
 
-	ts, err := snapstate.Remove(s.state, "foo", snap.R(0))
+	ts, err := snapstate.Remove(s.state, "foo", snap.R(0), nil)
 	c.Assert(err, IsNil)
 
 	c.Assert(s.state.TaskCount(), Equals, len(ts.Tasks()))
 	verifyRemoveTasks(c, ts)
 }
 
+func (s *snapmgrTestSuite) TestRemoveTasksAutomaticSnapshot(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	var snapshotted []string
+	snapstate.AutomaticSnapshot = func(st *state.State, snapName string) (*state.TaskSet, error) {
+		snapshotted = append(snapshotted, snapName)
+		return state.NewTaskSet(st.NewTask("save-snapshot", "...")), nil
+	}
+	defer func() { snapstate.AutomaticSnapshot = nil }()
+
+	snapstate.Set(s.state, "foo", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "foo", Revision: snap.R(11)},
+		},
+		Current:  snap.R(11),
+		SnapType: "app",
+	})
+
+	ts, err := snapstate.Remove(s.state, "foo", snap.R(0), nil)
+	c.Assert(err, IsNil)
+	c.Check(snapshotted, DeepEquals, []string{"foo"})
+
+	c.Assert(taskKinds(ts.Tasks()), DeepEquals, []string{
+		"stop-snap-services",
+		"run-hook[remove]",
+		"auto-disconnect",
+		"remove-aliases",
+		"unlink-snap",
+		"remove-profiles",
+		"save-snapshot",
+		"clear-snap",
+		"discard-snap",
+		"discard-conns",
+	})
+	// the snapshot is taken after the snap is unlinked, and before
+	// its data is cleared
+	saveSnapshot := tasksWithKind(ts, "save-snapshot")[0]
+	clearSnap := tasksWithKind(ts, "clear-snap")[0]
+	c.Check(saveSnapshot.WaitTasks(), HasLen, 6)
+	c.Check(clearSnap.WaitTasks(), DeepEquals, []*state.Task{saveSnapshot})
+}
+
+func (s *snapmgrTestSuite) TestRemoveTasksAutomaticSnapshotPurgeOrDisabled(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapshotErr := snapstate.ErrNothingToDo
+	snapstate.AutomaticSnapshot = func(st *state.State, snapName string) (*state.TaskSet, error) {
+		return nil, snapshotErr
+	}
+	defer func() { snapstate.AutomaticSnapshot = nil }()
+
+	snapstate.Set(s.state, "foo", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "foo", Revision: snap.R(11)},
+		},
+		Current:  snap.R(11),
+		SnapType: "app",
+	})
+
+	// automatic snapshots are disabled
+	ts, err := snapstate.Remove(s.state, "foo", snap.R(0), nil)
+	c.Assert(err, IsNil)
+	verifyRemoveTasks(c, ts)
+
+	// no snapshot is attempted when purging
+	snapshotErr = errors.New("automatic snapshot should not be attempted")
+	ts, err = snapstate.Remove(s.state, "foo", snap.R(0), &snapstate.RemoveFlags{Purge: true})
+	c.Assert(err, IsNil)
+	verifyRemoveTasks(c, ts)
+
+	// other errors are returned
+	snapshotErr = errors.New("bzzt")
+	_, err = snapstate.Remove(s.state, "foo", snap.R(0), nil)
+	c.Assert(err, ErrorMatches, "bzzt")
+}
+
 func (s *snapmgrTestSuite) TestRemoveHookNotExecutedIfNotLastRevison(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -1640,7 +2075,7 @@ func (s *snapmgrTestSuite) TestRemoveHookNotExecutedIfNotLastRevison(c *C) {
 		Current: snap.R(12),
 	})
 
-	ts, err := snapstate.Remove(s.state, "foo", snap.R(11))
+	ts, err := snapstate.Remove(s.state, "foo", snap.R(11), nil)
 	c.Assert(err, IsNil)
 
 	runHooks := tasksWithKind(ts, "run-hook")
@@ -1658,12 +2093,12 @@ func (s *snapmgrTestSuite) TestRemoveConflict(c *C) {
 		Current:  snap.R(11),
 	})
 
-	ts, err := snapstate.Remove(s.state, "some-snap", snap.R(0))
+	ts, err := snapstate.Remove(s.state, "some-snap", snap.R(0), nil)
 	c.Assert(err, IsNil)
 	// need a change to make the tasks visible
 	s.state.NewChange("remove", "...").AddAll(ts)
 
-	_, err = snapstate.Remove(s.state, "some-snap", snap.R(0))
+	_, err = snapstate.Remove(s.state, "some-snap", snap.R(0), nil)
 	c.Assert(err, ErrorMatches, `snap "some-snap" has "remove" change in progress`)
 }
 
@@ -1993,6 +2428,85 @@ func (s *snapmgrTestSuite) TestInstalling(c *C) {
 	c.Check(snapstate.Installing(s.state), Equals, true)
 }
 
+func (s *snapmgrTestSuite) TestInstallInstallModeDisabledServices(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	chg := s.state.NewChange("install", "install a snap")
+	ts, err := snapstate.Install(s.state, "services-snap", "some-channel", snap.R(0), s.user.ID, snapstate.Flags{})
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	c.Assert(chg.IsReady(), Equals, true)
+
+	op := s.fakeBackend.ops.First("link-snap")
+	c.Assert(op, NotNil)
+	c.Check(op.disabledServices, DeepEquals, []string{"svc3"})
+	op = s.fakeBackend.ops.First("start-snap-services")
+	c.Assert(op, NotNil)
+	c.Check(op.disabledServices, DeepEquals, []string{"svc3"})
+
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "services-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.LastActiveDisabledServices, DeepEquals, []string{"svc3"})
+}
+
+func (s *snapmgrTestSuite) TestUpdateKeepsDisabledServices(c *C) {
+	si := snap.SideInfo{
+		RealName: "services-snap",
+		Revision: snap.R(7),
+		SnapID:   "services-snap-id",
+	}
+	snaptest.MockSnap(c, `name: services-snap`, &si)
+	// svc1 was disabled by the user, svc3 got enabled
+	s.fakeBackend.servicesDisabled = []string{"svc1"}
+
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "services-snap", &snapstate.SnapState{
+		Active:   true,
+		Sequence: []*snap.SideInfo{&si},
+		Current:  si.Revision,
+		SnapType: "app",
+		Channel:  "stable",
+
+		LastActiveDisabledServices: []string{"svc3"},
+	})
+
+	chg := s.state.NewChange("refresh", "refresh a snap")
+	ts, err := snapstate.Update(s.state, "services-snap", "some-channel", snap.R(0), s.user.ID, snapstate.Flags{})
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	c.Assert(chg.IsReady(), Equals, true)
+
+	op := s.fakeBackend.ops.First("link-snap")
+	c.Assert(op, NotNil)
+	c.Check(op.disabledServices, DeepEquals, []string{"svc1"})
+	op = s.fakeBackend.ops.First("start-snap-services")
+	c.Assert(op, NotNil)
+	c.Check(op.disabledServices, DeepEquals, []string{"svc1"})
+
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "services-snap", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.LastActiveDisabledServices, DeepEquals, []string{"svc1"})
+}
+
 func (s *snapmgrTestSuite) TestUpdateRunThrough(c *C) {
 	// use services-snap here to make sure services would be stopped/started appropriately
 	si := snap.SideInfo{
@@ -2034,6 +2548,7 @@ func (s *snapmgrTestSuite) TestUpdateRunThrough(c *C) {
 			op: "storesvc-snap-action",
 			curSnaps: []store.CurrentSnap{{
 				Name:            "services-snap",
+				InstanceName:    "services-snap",
 				SnapID:          "services-snap-id",
 				Revision:        snap.R(7),
 				TrackingChannel: "stable",
@@ -2044,10 +2559,11 @@ func (s *snapmgrTestSuite) TestUpdateRunThrough(c *C) {
 		{
 			op: "storesvc-snap-action:action",
 			action: store.SnapAction{
-				Action:  "refresh",
-				SnapID:  "services-snap-id",
-				Channel: "some-channel",
-				Flags:   store.SnapActionEnforceValidation,
+				Action:       "refresh",
+				SnapID:       "services-snap-id",
+				InstanceName: "services-snap",
+				Channel:      "some-channel",
+				Flags:        store.SnapActionEnforceValidation,
 			},
 			revno:  snap.R(11),
 			userID: 1,
@@ -2510,9 +3026,9 @@ func (s *snapmgrTestSuite) TestUpdateManyMultipleCredsNoUserRunThrough(c *C) {
 		case "storesvc-snap-action":
 			ir++
 			c.Check(op.curSnaps, DeepEquals, []store.CurrentSnap{
-				{Name: "core", SnapID: "core-snap-id", Revision: snap.R(1), RefreshedDate: fakeRevDateEpoch.AddDate(0, 0, 1)},
-				{Name: "services-snap", SnapID: "services-snap-id", Revision: snap.R(2), RefreshedDate: fakeRevDateEpoch.AddDate(0, 0, 2)},
-				{Name: "some-snap", SnapID: "some-snap-id", Revision: snap.R(5), RefreshedDate: fakeRevDateEpoch.AddDate(0, 0, 5)},
+				{Name: "core", InstanceName: "core", SnapID: "core-snap-id", Revision: snap.R(1), RefreshedDate: fakeRevDateEpoch.AddDate(0, 0, 1)},
+				{Name: "services-snap", InstanceName: "services-snap", SnapID: "services-snap-id", Revision: snap.R(2), RefreshedDate: fakeRevDateEpoch.AddDate(0, 0, 2)},
+				{Name: "some-snap", InstanceName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(5), RefreshedDate: fakeRevDateEpoch.AddDate(0, 0, 5)},
 			})
 		case "storesvc-snap-action:action":
 			snapID := op.action.SnapID
@@ -2600,9 +3116,9 @@ func (s *snapmgrTestSuite) TestUpdateManyMultipleCredsUserRunThrough(c *C) {
 		case "storesvc-snap-action":
 			ir++
 			c.Check(op.curSnaps, DeepEquals, []store.CurrentSnap{
-				{Name: "core", SnapID: "core-snap-id", Revision: snap.R(1), RefreshedDate: fakeRevDateEpoch.AddDate(0, 0, 1)},
-				{Name: "services-snap", SnapID: "services-snap-id", Revision: snap.R(2), RefreshedDate: fakeRevDateEpoch.AddDate(0, 0, 2)},
-				{Name: "some-snap", SnapID: "some-snap-id", Revision: snap.R(5), RefreshedDate: fakeRevDateEpoch.AddDate(0, 0, 5)},
+				{Name: "core", InstanceName: "core", SnapID: "core-snap-id", Revision: snap.R(1), RefreshedDate: fakeRevDateEpoch.AddDate(0, 0, 1)},
+				{Name: "services-snap", InstanceName: "services-snap", SnapID: "services-snap-id", Revision: snap.R(2), RefreshedDate: fakeRevDateEpoch.AddDate(0, 0, 2)},
+				{Name: "some-snap", InstanceName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(5), RefreshedDate: fakeRevDateEpoch.AddDate(0, 0, 5)},
 			})
 		case "storesvc-snap-action:action":
 			snapID := op.action.SnapID
@@ -2701,9 +3217,9 @@ func (s *snapmgrTestSuite) TestUpdateManyMultipleCredsUserWithNoStoreAuthRunThro
 		case "storesvc-snap-action":
 			ir++
 			c.Check(op.curSnaps, DeepEquals, []store.CurrentSnap{
-				{Name: "core", SnapID: "core-snap-id", Revision: snap.R(1), RefreshedDate: fakeRevDateEpoch.AddDate(0, 0, 1)},
-				{Name: "services-snap", SnapID: "services-snap-id", Revision: snap.R(2), RefreshedDate: fakeRevDateEpoch.AddDate(0, 0, 2)},
-				{Name: "some-snap", SnapID: "some-snap-id", Revision: snap.R(5), RefreshedDate: fakeRevDateEpoch.AddDate(0, 0, 5)},
+				{Name: "core", InstanceName: "core", SnapID: "core-snap-id", Revision: snap.R(1), RefreshedDate: fakeRevDateEpoch.AddDate(0, 0, 1)},
+				{Name: "services-snap", InstanceName: "services-snap", SnapID: "services-snap-id", Revision: snap.R(2), RefreshedDate: fakeRevDateEpoch.AddDate(0, 0, 2)},
+				{Name: "some-snap", InstanceName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(5), RefreshedDate: fakeRevDateEpoch.AddDate(0, 0, 5)},
 			})
 		case "storesvc-snap-action:action":
 			snapID := op.action.SnapID
@@ -2759,6 +3275,7 @@ func (s *snapmgrTestSuite) TestUpdateUndoRunThrough(c *C) {
 			op: "storesvc-snap-action",
 			curSnaps: []store.CurrentSnap{{
 				Name:          "some-snap",
+				InstanceName:  "some-snap",
 				SnapID:        "some-snap-id",
 				Revision:      snap.R(7),
 				RefreshedDate: fakeRevDateEpoch.AddDate(0, 0, 7),
@@ -2768,10 +3285,11 @@ func (s *snapmgrTestSuite) TestUpdateUndoRunThrough(c *C) {
 		{
 			op: "storesvc-snap-action:action",
 			action: store.SnapAction{
-				Action:  "refresh",
-				SnapID:  "some-snap-id",
-				Channel: "some-channel",
-				Flags:   store.SnapActionEnforceValidation,
+				Action:       "refresh",
+				SnapID:       "some-snap-id",
+				InstanceName: "some-snap",
+				Channel:      "some-channel",
+				Flags:        store.SnapActionEnforceValidation,
 			},
 			revno:  snap.R(11),
 			userID: 1,
@@ -2930,6 +3448,7 @@ func (s *snapmgrTestSuite) TestUpdateTotalUndoRunThrough(c *C) {
 			op: "storesvc-snap-action",
 			curSnaps: []store.CurrentSnap{{
 				Name:            "some-snap",
+				InstanceName:    "some-snap",
 				SnapID:          "some-snap-id",
 				Revision:        snap.R(7),
 				TrackingChannel: "stable",
@@ -2940,10 +3459,11 @@ func (s *snapmgrTestSuite) TestUpdateTotalUndoRunThrough(c *C) {
 		{
 			op: "storesvc-snap-action:action",
 			action: store.SnapAction{
-				Action:  "refresh",
-				SnapID:  "some-snap-id",
-				Channel: "some-channel",
-				Flags:   store.SnapActionEnforceValidation,
+				Action:       "refresh",
+				SnapID:       "some-snap-id",
+				InstanceName: "some-snap",
+				Channel:      "some-channel",
+				Flags:        store.SnapActionEnforceValidation,
 			},
 			revno:  snap.R(11),
 			userID: 1,
@@ -3214,6 +3734,7 @@ func (s *snapmgrTestSuite) TestUpdateSameRevisionSwitchChannelRunThrough(c *C) {
 			op: "storesvc-snap-action",
 			curSnaps: []store.CurrentSnap{{
 				Name:            "some-snap",
+				InstanceName:    "some-snap",
 				SnapID:          "some-snap-id",
 				Revision:        snap.R(7),
 				TrackingChannel: "other-channel",
@@ -3225,10 +3746,11 @@ func (s *snapmgrTestSuite) TestUpdateSameRevisionSwitchChannelRunThrough(c *C) {
 		{
 			op: "storesvc-snap-action:action",
 			action: store.SnapAction{
-				Action:  "refresh",
-				SnapID:  "some-snap-id",
-				Channel: "channel-for-7",
-				Flags:   store.SnapActionEnforceValidation,
+				Action:       "refresh",
+				SnapID:       "some-snap-id",
+				InstanceName: "some-snap",
+				Channel:      "channel-for-7",
+				Flags:        store.SnapActionEnforceValidation,
 			},
 			userID: 1,
 		},
@@ -3486,6 +4008,7 @@ func (s *snapmgrTestSuite) TestUpdateIgnoreValidationSticky(c *C) {
 		op: "storesvc-snap-action",
 		curSnaps: []store.CurrentSnap{{
 			Name:             "some-snap",
+			InstanceName:     "some-snap",
 			SnapID:           "some-snap-id",
 			Revision:         snap.R(7),
 			IgnoreValidation: false,
@@ -3497,10 +4020,11 @@ func (s *snapmgrTestSuite) TestUpdateIgnoreValidationSticky(c *C) {
 		op:    "storesvc-snap-action:action",
 		revno: snap.R(11),
 		action: store.SnapAction{
-			Action:  "refresh",
-			SnapID:  "some-snap-id",
-			Channel: "stable",
-			Flags:   store.SnapActionIgnoreValidation,
+			Action:       "refresh",
+			SnapID:       "some-snap-id",
+			InstanceName: "some-snap",
+			Channel:      "stable",
+			Flags:        store.SnapActionIgnoreValidation,
 		},
 		userID: 1,
 	})
@@ -3532,6 +4056,7 @@ func (s *snapmgrTestSuite) TestUpdateIgnoreValidationSticky(c *C) {
 		op: "storesvc-snap-action",
 		curSnaps: []store.CurrentSnap{{
 			Name:             "some-snap",
+			InstanceName:     "some-snap",
 			SnapID:           "some-snap-id",
 			Revision:         snap.R(11),
 			TrackingChannel:  "stable",
@@ -3544,9 +4069,10 @@ func (s *snapmgrTestSuite) TestUpdateIgnoreValidationSticky(c *C) {
 		op:    "storesvc-snap-action:action",
 		revno: snap.R(12),
 		action: store.SnapAction{
-			Action: "refresh",
-			SnapID: "some-snap-id",
-			Flags:  0,
+			Action:       "refresh",
+			SnapID:       "some-snap-id",
+			InstanceName: "some-snap",
+			Flags:        0,
 		},
 		userID: 1,
 	})
@@ -3583,6 +4109,7 @@ func (s *snapmgrTestSuite) TestUpdateIgnoreValidationSticky(c *C) {
 		op: "storesvc-snap-action",
 		curSnaps: []store.CurrentSnap{{
 			Name:             "some-snap",
+			InstanceName:     "some-snap",
 			SnapID:           "some-snap-id",
 			Revision:         snap.R(12),
 			TrackingChannel:  "stable",
@@ -3595,10 +4122,11 @@ func (s *snapmgrTestSuite) TestUpdateIgnoreValidationSticky(c *C) {
 		op:    "storesvc-snap-action:action",
 		revno: snap.R(11),
 		action: store.SnapAction{
-			Action:  "refresh",
-			SnapID:  "some-snap-id",
-			Channel: "stable",
-			Flags:   store.SnapActionEnforceValidation,
+			Action:       "refresh",
+			SnapID:       "some-snap-id",
+			InstanceName: "some-snap",
+			Channel:      "stable",
+			Flags:        store.SnapActionEnforceValidation,
 		},
 		userID: 1,
 	})
@@ -3718,6 +4246,7 @@ func (s *snapmgrTestSuite) TestSingleUpdateBlockedRevision(c *C) {
 		op: "storesvc-snap-action",
 		curSnaps: []store.CurrentSnap{{
 			Name:          "some-snap",
+			InstanceName:  "some-snap",
 			SnapID:        "some-snap-id",
 			Revision:      snap.R(7),
 			RefreshedDate: fakeRevDateEpoch.AddDate(0, 0, 7),
@@ -3758,6 +4287,7 @@ func (s *snapmgrTestSuite) TestMultiUpdateBlockedRevision(c *C) {
 		op: "storesvc-snap-action",
 		curSnaps: []store.CurrentSnap{{
 			Name:          "some-snap",
+			InstanceName:  "some-snap",
 			SnapID:        "some-snap-id",
 			Revision:      snap.R(7),
 			RefreshedDate: fakeRevDateEpoch.AddDate(0, 0, 7),
@@ -3797,6 +4327,7 @@ func (s *snapmgrTestSuite) TestAllUpdateBlockedRevision(c *C) {
 		op: "storesvc-snap-action",
 		curSnaps: []store.CurrentSnap{{
 			Name:          "some-snap",
+			InstanceName:  "some-snap",
 			SnapID:        "some-snap-id",
 			Revision:      snap.R(7),
 			RefreshedDate: fakeRevDateEpoch.AddDate(0, 0, 7),
@@ -4541,7 +5072,7 @@ func (s *snapmgrTestSuite) TestRemoveRunThrough(c *C) {
 	})
 
 	chg := s.state.NewChange("remove", "remove a snap")
-	ts, err := snapstate.Remove(s.state, "some-snap", snap.R(0))
+	ts, err := snapstate.Remove(s.state, "some-snap", snap.R(0), nil)
 	c.Assert(err, IsNil)
 	chg.AddAll(ts)
 
@@ -4551,6 +5082,11 @@ func (s *snapmgrTestSuite) TestRemoveRunThrough(c *C) {
 	s.state.Lock()
 
 	expected := fakeOps{
+		{
+			op:    "auto-disconnect:Doing",
+			name:  "some-snap",
+			revno: snap.R(7),
+		},
 		{
 			op:   "remove-snap-aliases",
 			name: "some-snap",
@@ -4651,7 +5187,7 @@ func (s *snapmgrTestSuite) TestRemoveWithManyRevisionsRunThrough(c *C) {
 	})
 
 	chg := s.state.NewChange("remove", "remove a snap")
-	ts, err := snapstate.Remove(s.state, "some-snap", snap.R(0))
+	ts, err := snapstate.Remove(s.state, "some-snap", snap.R(0), nil)
 	c.Assert(err, IsNil)
 	chg.AddAll(ts)
 
@@ -4661,6 +5197,11 @@ func (s *snapmgrTestSuite) TestRemoveWithManyRevisionsRunThrough(c *C) {
 	s.state.Lock()
 
 	expected := fakeOps{
+		{
+			op:    "auto-disconnect:Doing",
+			name:  "some-snap",
+			revno: snap.R(7),
+		},
 		{
 			op:   "remove-snap-aliases",
 			name: "some-snap",
@@ -4785,7 +5326,7 @@ func (s *snapmgrTestSuite) TestRemoveOneRevisionRunThrough(c *C) {
 	})
 
 	chg := s.state.NewChange("remove", "remove a snap")
-	ts, err := snapstate.Remove(s.state, "some-snap", snap.R(3))
+	ts, err := snapstate.Remove(s.state, "some-snap", snap.R(3), nil)
 	c.Assert(err, IsNil)
 	chg.AddAll(ts)
 
@@ -4850,7 +5391,7 @@ func (s *snapmgrTestSuite) TestRemoveLastRevisionRunThrough(c *C) {
 	})
 
 	chg := s.state.NewChange("remove", "remove a snap")
-	ts, err := snapstate.Remove(s.state, "some-snap", snap.R(2))
+	ts, err := snapstate.Remove(s.state, "some-snap", snap.R(2), nil)
 	c.Assert(err, IsNil)
 	chg.AddAll(ts)
 
@@ -4930,7 +5471,7 @@ func (s *snapmgrTestSuite) TestRemoveCurrentActiveRevisionRefused(c *C) {
 		SnapType: "app",
 	})
 
-	_, err := snapstate.Remove(s.state, "some-snap", snap.R(2))
+	_, err := snapstate.Remove(s.state, "some-snap", snap.R(2), nil)
 
 	c.Check(err, ErrorMatches, `cannot remove active revision 2 of snap "some-snap"`)
 }
@@ -4951,7 +5492,7 @@ func (s *snapmgrTestSuite) TestRemoveCurrentRevisionOfSeveralRefused(c *C) {
 		SnapType: "app",
 	})
 
-	_, err := snapstate.Remove(s.state, "some-snap", snap.R(2))
+	_, err := snapstate.Remove(s.state, "some-snap", snap.R(2), nil)
 	c.Assert(err, NotNil)
 	c.Check(err.Error(), Equals, `cannot remove active revision 2 of snap "some-snap" (revert first?)`)
 }
@@ -4972,7 +5513,7 @@ func (s *snapmgrTestSuite) TestRemoveMissingRevisionRefused(c *C) {
 		SnapType: "app",
 	})
 
-	_, err := snapstate.Remove(s.state, "some-snap", snap.R(1))
+	_, err := snapstate.Remove(s.state, "some-snap", snap.R(1), nil)
 
 	c.Check(err, ErrorMatches, `revision 1 of snap "some-snap" is not installed`)
 }
@@ -4993,7 +5534,7 @@ func (s *snapmgrTestSuite) TestRemoveRefused(c *C) {
 		SnapType: "app",
 	})
 
-	_, err := snapstate.Remove(s.state, "gadget", snap.R(0))
+	_, err := snapstate.Remove(s.state, "gadget", snap.R(0), nil)
 
 	c.Check(err, ErrorMatches, `snap "gadget" is not removable`)
 }
@@ -5014,7 +5555,7 @@ func (s *snapmgrTestSuite) TestRemoveRefusedLastRevision(c *C) {
 		SnapType: "app",
 	})
 
-	_, err := snapstate.Remove(s.state, "gadget", snap.R(7))
+	_, err := snapstate.Remove(s.state, "gadget", snap.R(7), nil)
 
 	c.Check(err, ErrorMatches, `snap "gadget" is not removable`)
 }
@@ -5057,7 +5598,7 @@ func (s *snapmgrTestSuite) TestRemoveDeletesConfigOnLastRevision(c *C) {
 	c.Assert(tr.Get("another-snap", "bar", &res), IsNil)
 
 	chg := s.state.NewChange("remove", "remove a snap")
-	ts, err := snapstate.Remove(s.state, "some-snap", snap.R(0))
+	ts, err := snapstate.Remove(s.state, "some-snap", snap.R(0), nil)
 	c.Assert(err, IsNil)
 	chg.AddAll(ts)
 
@@ -5110,7 +5651,7 @@ func (s *snapmgrTestSuite) TestRemoveDoesntDeleteConfigIfNotLastRevision(c *C) {
 	c.Assert(tr.Get("some-snap", "foo", &res), IsNil)
 
 	chg := s.state.NewChange("remove", "remove a snap")
-	ts, err := snapstate.Remove(s.state, "some-snap", si1.Revision)
+	ts, err := snapstate.Remove(s.state, "some-snap", si1.Revision, nil)
 	c.Assert(err, IsNil)
 	chg.AddAll(ts)
 
@@ -6485,6 +7026,7 @@ func (s *snapmgrTestSuite) TestEnsureRefreshesWithUpdate(c *C) {
 	c.Assert(s.state.Changes(), HasLen, 1)
 	chg := s.state.Changes()[0]
 	c.Check(chg.Kind(), Equals, "auto-refresh")
+	c.Check(chg.Priority(), Equals, state.LowPriority)
 	c.Check(chg.IsReady(), Equals, false)
 	s.verifyRefreshLast(c)
 }
@@ -6902,6 +7444,53 @@ func (s *snapmgrQuerySuite) TestTypeInfo(c *C) {
 	}
 }
 
+func (s *snapmgrQuerySuite) TestKernelInfoPrefersModelKernel(c *C) {
+	st := s.st
+	st.Lock()
+	defer st.Unlock()
+
+	// after a remodel both the old and the new kernel are installed
+	for _, name := range []string{"old-kernel", "new-kernel"} {
+		sideInfo := &snap.SideInfo{
+			RealName: name,
+			Revision: snap.R(2),
+		}
+		snaptest.MockSnap(c, fmt.Sprintf("name: %s\ntype: kernel\nversion: 1\n", name), sideInfo)
+		snapstate.Set(st, name, &snapstate.SnapState{
+			SnapType: "kernel",
+			Active:   true,
+			Sequence: []*snap.SideInfo{sideInfo},
+			Current:  sideInfo.Revision,
+		})
+	}
+
+	privKey, _ := assertstest.GenerateKey(752)
+	brandSigning := assertstest.NewSigningDB("my-brand", privKey)
+	model, err := brandSigning.Sign(asserts.ModelType, map[string]interface{}{
+		"series":       "16",
+		"brand-id":     "my-brand",
+		"model":        "my-model",
+		"architecture": "amd64",
+		"gadget":       "pc",
+		"kernel":       "new-kernel",
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+
+	snapstate.Model = func(*state.State) (*asserts.Model, error) {
+		return model.(*asserts.Model), nil
+	}
+	defer func() { snapstate.Model = nil }()
+
+	// unrolled to ensure we don't pass because the order is
+	// randomly right
+	for i := 0; i < 5; i++ {
+		info, err := snapstate.KernelInfo(st)
+		c.Assert(err, IsNil)
+		c.Check(info.Name(), Equals, "new-kernel")
+	}
+}
+
 func (s *snapmgrQuerySuite) TestTypeInfoCore(c *C) {
 	st := s.st
 	st.Lock()
@@ -7833,11 +8422,12 @@ func (s *snapmgrTestSuite) TestRemoveMany(c *C) {
 	c.Assert(tts, HasLen, 2)
 	c.Check(removed, DeepEquals, []string{"one", "two"})
 
-	c.Assert(s.state.TaskCount(), Equals, 8*2)
+	c.Assert(s.state.TaskCount(), Equals, 9*2)
 	for _, ts := range tts {
 		c.Assert(taskKinds(ts.Tasks()), DeepEquals, []string{
 			"stop-snap-services",
 			"run-hook[remove]",
+			"auto-disconnect",
 			"remove-aliases",
 			"unlink-snap",
 			"remove-profiles",
@@ -8245,7 +8835,7 @@ func (s *snapmgrTestSuite) TestTransitionCoreRunThrough(c *C) {
 		{
 			op: "storesvc-snap-action",
 			curSnaps: []store.CurrentSnap{
-				{Name: "ubuntu-core", SnapID: "ubuntu-core-snap-id", Revision: snap.R(1), TrackingChannel: "beta", RefreshedDate: fakeRevDateEpoch.AddDate(0, 0, 1)},
+				{Name: "ubuntu-core", InstanceName: "ubuntu-core", SnapID: "ubuntu-core-snap-id", Revision: snap.R(1), TrackingChannel: "beta", RefreshedDate: fakeRevDateEpoch.AddDate(0, 0, 1)},
 			},
 		},
 		{
@@ -8325,6 +8915,11 @@ func (s *snapmgrTestSuite) TestTransitionCoreRunThrough(c *C) {
 			op:   "transition-ubuntu-core:Doing",
 			name: "ubuntu-core",
 		},
+		{
+			op:    "auto-disconnect:Doing",
+			name:  "ubuntu-core",
+			revno: snap.R(1),
+		},
 		{
 			op:   "remove-snap-aliases",
 			name: "ubuntu-core",
@@ -8410,6 +9005,11 @@ func (s *snapmgrTestSuite) TestTransitionCoreRunThroughWithCore(c *C) {
 			op:   "transition-ubuntu-core:Doing",
 			name: "ubuntu-core",
 		},
+		{
+			op:    "auto-disconnect:Doing",
+			name:  "ubuntu-core",
+			revno: snap.R(1),
+		},
 		{
 			op:   "remove-snap-aliases",
 			name: "ubuntu-core",
@@ -8847,6 +9447,32 @@ func (s *snapmgrTestSuite) TestConflictMany(c *C) {
 	}
 }
 
+func (s *snapmgrTestSuite) TestConflictManyAffectedSnapsByKind(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.AddAffectedSnapsByKind("frobnicate-snap", func(t *state.Task) ([]string, error) {
+		var snapName string
+		if err := t.Get("frob-snap", &snapName); err != nil {
+			return nil, err
+		}
+		return []string{snapName}, nil
+	})
+	defer snapstate.AddAffectedSnapsByKind("frobnicate-snap", nil)
+
+	t := s.state.NewTask("frobnicate-snap", "...")
+	t.Set("frob-snap", "a-snap")
+	chg := s.state.NewChange("frobnicate", "...")
+	chg.AddTask(t)
+
+	c.Check(snapstate.CheckChangeConflictMany(s.state, []string{"b-snap"}, nil), IsNil)
+	c.Check(snapstate.CheckChangeConflictMany(s.state, []string{"a-snap", "b-snap"}, nil), ErrorMatches, `snap "a-snap" has "frobnicate" change in progress`)
+
+	// ready changes don't conflict
+	t.SetStatus(state.DoneStatus)
+	c.Check(snapstate.CheckChangeConflictMany(s.state, []string{"a-snap"}, nil), IsNil)
+}
+
 func (s *snapmgrTestSuite) TestInstallWithoutCoreRunThrough1(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -9776,6 +10402,211 @@ func (s *snapmgrTestSuite) TestInstallLayoutsChecksFeatureFlag(c *C) {
 	c.Assert(err, IsNil)
 }
 
+func (s *snapmgrTestSuite) TestInstallParallelInstanceChecksFeatureFlag(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	_, err := snapstate.Install(s.state, "some-snap_instance", "some-channel", snap.R(0), s.user.ID, snapstate.Flags{})
+	c.Assert(err, ErrorMatches, "cannot use experimental 'parallel-instances' feature.*")
+
+	// enable parallel instances
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "experimental.parallel-instances", true)
+	tr.Commit()
+
+	ts, err := snapstate.Install(s.state, "some-snap_instance", "some-channel", snap.R(0), s.user.ID, snapstate.Flags{})
+	c.Assert(err, IsNil)
+
+	snapsup, err := snapstate.TaskSnapSetup(ts.Tasks()[0])
+	c.Assert(err, IsNil)
+	c.Check(snapsup.InstanceKey, Equals, "instance")
+	c.Check(snapsup.SnapName(), Equals, "some-snap")
+	c.Check(snapsup.Name(), Equals, "some-snap_instance")
+}
+
+func (s *snapmgrTestSuite) TestInstallParallelInstanceInvalid(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "experimental.parallel-instances", true)
+	tr.Commit()
+
+	_, err := snapstate.Install(s.state, "some-snap_INSTANCE", "some-channel", snap.R(0), s.user.ID, snapstate.Flags{})
+	c.Assert(err, ErrorMatches, `invalid instance key: "INSTANCE"`)
+
+	_, err = snapstate.Install(s.state, "core_instance", "some-channel", snap.R(0), s.user.ID, snapstate.Flags{})
+	c.Assert(err, ErrorMatches, `cannot install snap of type os as "core_instance"`)
+}
+
+func (s *snapmgrTestSuite) TestInstallParallelInstanceRunThrough(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "experimental.parallel-instances", true)
+	tr.Commit()
+
+	// the snap is already installed without an instance key
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7)},
+		},
+		Current:  snap.R(7),
+		SnapType: "app",
+	})
+
+	chg := s.state.NewChange("install", "install a snap")
+	ts, err := snapstate.Install(s.state, "some-snap_instance", "some-channel", snap.R(0), s.user.ID, snapstate.Flags{})
+	c.Assert(err, IsNil)
+	chg.AddAll(ts)
+
+	s.state.Unlock()
+	defer s.snapmgr.Stop()
+	s.settle(c)
+	s.state.Lock()
+
+	c.Assert(chg.Err(), IsNil)
+	c.Assert(chg.IsReady(), Equals, true)
+
+	// the instance is installed next to the snap
+	c.Check(s.fakeBackend.ops.First("link-snap"), DeepEquals, &fakeOp{
+		op:   "link-snap",
+		name: filepath.Join(dirs.SnapMountDir, "some-snap_instance/11"),
+	})
+
+	var snapst snapstate.SnapState
+	err = snapstate.Get(s.state, "some-snap_instance", &snapst)
+	c.Assert(err, IsNil)
+	c.Check(snapst.InstanceKey, Equals, "instance")
+	c.Check(snapst.InstanceName(), Equals, "some-snap_instance")
+	info, err := snapst.CurrentInfo()
+	c.Assert(err, IsNil)
+	c.Check(info.Name(), Equals, "some-snap_instance")
+	c.Check(info.SnapName(), Equals, "some-snap")
+
+	// the original snap is untouched
+	var origSnapst snapstate.SnapState
+	err = snapstate.Get(s.state, "some-snap", &origSnapst)
+	c.Assert(err, IsNil)
+	c.Check(origSnapst.InstanceKey, Equals, "")
+	c.Check(origSnapst.Current, Equals, snap.R(7))
+}
+
+func (s *snapmgrTestSuite) TestUpdateParallelInstance(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "experimental.parallel-instances", true)
+	tr.Commit()
+
+	// only the instance is installed, not the snap itself
+	snapstate.Set(s.state, "some-snap_instance", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7)},
+		},
+		Current:     snap.R(7),
+		SnapType:    "app",
+		InstanceKey: "instance",
+	})
+
+	ts, err := snapstate.Update(s.state, "some-snap_instance", "some-channel", snap.R(0), s.user.ID, snapstate.Flags{})
+	c.Assert(err, IsNil)
+
+	c.Check(s.fakeBackend.ops[0], DeepEquals, fakeOp{
+		op: "storesvc-snap-action",
+		curSnaps: []store.CurrentSnap{{
+			Name:          "some-snap",
+			InstanceName:  "some-snap_instance",
+			SnapID:        "some-snap-id",
+			Revision:      snap.R(7),
+			RefreshedDate: fakeRevDateEpoch.AddDate(0, 0, 7),
+		}},
+		userID: 1,
+	})
+	c.Check(s.fakeBackend.ops[1].action, DeepEquals, store.SnapAction{
+		Action:       "refresh",
+		InstanceName: "some-snap_instance",
+		SnapID:       "some-snap-id",
+		Channel:      "some-channel",
+		Flags:        store.SnapActionEnforceValidation,
+	})
+
+	snapsup, err := snapstate.TaskSnapSetup(ts.Tasks()[0])
+	c.Assert(err, IsNil)
+	c.Check(snapsup.Name(), Equals, "some-snap_instance")
+	c.Check(snapsup.Revision(), Equals, snap.R(11))
+}
+
+func (s *snapmgrTestSuite) TestUpdateManyParallelInstance(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	tr := config.NewTransaction(s.state)
+	tr.Set("core", "experimental.parallel-instances", true)
+	tr.Commit()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7)},
+		},
+		Current:  snap.R(7),
+		SnapType: "app",
+	})
+	snapstate.Set(s.state, "some-snap_instance", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(5)},
+		},
+		Current:     snap.R(5),
+		SnapType:    "app",
+		InstanceKey: "instance",
+	})
+
+	// the instance alone
+	updates, tts, err := snapstate.UpdateMany(context.TODO(), s.state, []string{"some-snap_instance"}, s.user.ID)
+	c.Assert(err, IsNil)
+	c.Check(updates, DeepEquals, []string{"some-snap_instance"})
+	c.Assert(tts, HasLen, 1)
+	snapsup, err := snapstate.TaskSnapSetup(tts[0].Tasks()[0])
+	c.Assert(err, IsNil)
+	c.Check(snapsup.Name(), Equals, "some-snap_instance")
+
+	// both are sent to the store as current snaps
+	c.Check(s.fakeBackend.ops[0].curSnaps, DeepEquals, []store.CurrentSnap{
+		{Name: "some-snap", InstanceName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7), RefreshedDate: fakeRevDateEpoch.AddDate(0, 0, 7)},
+		{Name: "some-snap", InstanceName: "some-snap_instance", SnapID: "some-snap-id", Revision: snap.R(5), RefreshedDate: fakeRevDateEpoch.AddDate(0, 0, 5)},
+	})
+
+	// and together with the snap itself
+	s.fakeBackend.ops = nil
+	updates, tts, err = snapstate.UpdateMany(context.TODO(), s.state, nil, s.user.ID)
+	c.Assert(err, IsNil)
+	sort.Strings(updates)
+	c.Check(updates, DeepEquals, []string{"some-snap", "some-snap_instance"})
+	c.Assert(tts, HasLen, 2)
+	var names []string
+	for _, ts := range tts {
+		snapsup, err := snapstate.TaskSnapSetup(ts.Tasks()[0])
+		c.Assert(err, IsNil)
+		names = append(names, snapsup.Name())
+	}
+	sort.Strings(names)
+	c.Check(names, DeepEquals, []string{"some-snap", "some-snap_instance"})
+
+	var actions []string
+	for _, op := range s.fakeBackend.ops {
+		if op.op == "storesvc-snap-action:action" {
+			actions = append(actions, op.action.InstanceName)
+		}
+	}
+	c.Check(actions, DeepEquals, []string{"some-snap", "some-snap_instance"})
+}
+
 func (s *snapmgrTestSuite) TestUpdateLayoutsChecksFeatureFlag(c *C) {
 	s.state.Lock()
 	defer s.state.Unlock()
@@ -22,6 +22,7 @@ package snapstate
 import (
 	"fmt"
 	"sort"
+	"time"
 
 	"golang.org/x/net/context"
 
@@ -144,8 +145,9 @@ func updateInfo(st *state.State, snapst *SnapState, opts *updateInfoOpts, userID
 	}
 
 	action := &store.SnapAction{
-		Action: "refresh",
-		SnapID: curInfo.SnapID,
+		Action:       "refresh",
+		SnapID:       curInfo.SnapID,
+		InstanceName: snapst.InstanceName(),
 		// the desired channel
 		Channel: opts.channel,
 		Flags:   flags,
@@ -153,7 +155,7 @@ func updateInfo(st *state.State, snapst *SnapState, opts *updateInfoOpts, userID
 
 	if curInfo.SnapID == "" { // amend
 		action.Action = "install"
-		action.Name = curInfo.Name()
+		action.Name = curInfo.SnapName()
 	}
 
 	theStore := Store(st)
@@ -161,7 +163,24 @@ func updateInfo(st *state.State, snapst *SnapState, opts *updateInfoOpts, userID
 	res, err := theStore.SnapAction(context.TODO(), curSnaps, []*store.SnapAction{action}, user, nil)
 	st.Lock()
 
-	return singleActionResult(curInfo.Name(), action.Action, res, err)
+	return instanceActionResult(snapst, action.Action, res, err)
+}
+
+// instanceActionResult is like singleActionResult but the returned
+// info is set up for the instance described by snapst.
+func instanceActionResult(snapst *SnapState, action string, results []*snap.Info, e error) (*snap.Info, error) {
+	// the store reports refresh errors by instance name but install
+	// ones by snap name
+	name := snapst.InstanceName()
+	if action == "install" {
+		name = snap.InstanceSnap(name)
+	}
+	info, err := singleActionResult(name, action, results, e)
+	if err != nil {
+		return nil, err
+	}
+	info.InstanceKey = snapst.InstanceKey
+	return info, nil
 }
 
 func preUpdateInfo(st *state.State, snapst *SnapState, amend bool, userID int) (*snap.Info, *auth.UserState, error) {
@@ -237,8 +256,9 @@ func updateToRevisionInfo(st *state.State, snapst *SnapState, revision snap.Revi
 	}
 
 	action := &store.SnapAction{
-		Action: "refresh",
-		SnapID: curInfo.SnapID,
+		Action:       "refresh",
+		SnapID:       curInfo.SnapID,
+		InstanceName: snapst.InstanceName(),
 		// the desired revision
 		Revision: revision,
 	}
@@ -248,7 +268,7 @@ func updateToRevisionInfo(st *state.State, snapst *SnapState, revision snap.Revi
 	res, err := theStore.SnapAction(context.TODO(), curSnaps, []*store.SnapAction{action}, user, nil)
 	st.Lock()
 
-	return singleActionResult(curInfo.Name(), action.Action, res, err)
+	return instanceActionResult(snapst, action.Action, res, err)
 }
 
 func currentSnaps(st *state.State) ([]*store.CurrentSnap, error) {
@@ -264,7 +284,7 @@ func currentSnaps(st *state.State) ([]*store.CurrentSnap, error) {
 func collectCurrentSnaps(snapStates map[string]*SnapState, consider func(*store.CurrentSnap, *SnapState)) (curSnaps []*store.CurrentSnap) {
 	curSnaps = make([]*store.CurrentSnap, 0, len(snapStates))
 
-	for snapName, snapst := range snapStates {
+	for _, snapst := range snapStates {
 		if snapst.TryMode {
 			// try mode snaps are completely local and
 			// irrelevant for the operation
@@ -284,8 +304,9 @@ func collectCurrentSnaps(snapStates map[string]*SnapState, consider func(*store.
 		}
 
 		installed := &store.CurrentSnap{
-			Name:   snapName,
-			SnapID: snapInfo.SnapID,
+			Name:         snapInfo.SnapName(),
+			InstanceName: snap.InstanceName(snapInfo.SnapName(), snapst.InstanceKey),
+			SnapID:       snapInfo.SnapID,
 			// the desired channel (not snapInfo.Channel!)
 			TrackingChannel:  snapst.Channel,
 			Revision:         snapInfo.Revision,
@@ -302,6 +323,10 @@ func collectCurrentSnaps(snapStates map[string]*SnapState, consider func(*store.
 	return curSnaps
 }
 
+// refreshCandidates returns the candidate updates for the given
+// instance names, or for all snaps if names is empty, along with the
+// state of the snaps by instance name and the snap ids of those that
+// ignore validation.
 func refreshCandidates(ctx context.Context, st *state.State, names []string, user *auth.UserState, opts *store.RefreshOptions) ([]*snap.Info, map[string]*SnapState, map[string]bool, error) {
 	snapStates, err := All(st)
 	if err != nil {
@@ -325,8 +350,9 @@ func refreshCandidates(ctx context.Context, st *state.State, names []string, use
 		fallbackID = user.ID
 	}
 
+	now := time.Now()
 	actionsByUserID := make(map[int][]*store.SnapAction)
-	stateByID := make(map[string]*SnapState, len(snapStates))
+	stateByInstanceName := make(map[string]*SnapState, len(snapStates))
 	ignoreValidation := make(map[string]bool)
 	nCands := 0
 
@@ -342,11 +368,16 @@ func refreshCandidates(ctx context.Context, st *state.State, names []string, use
 			return
 		}
 
-		if len(names) > 0 && !strutil.SortedListContains(names, installed.Name) {
+		if len(names) == 0 && snapst.RefreshHeld(now) {
+			// refreshes of the snap are held
+			return
+		}
+
+		if len(names) > 0 && !strutil.SortedListContains(names, installed.InstanceName) {
 			return
 		}
 
-		stateByID[installed.SnapID] = snapst
+		stateByInstanceName[installed.InstanceName] = snapst
 
 		if len(names) == 0 {
 			installed.Block = snapst.Block()
@@ -357,8 +388,9 @@ func refreshCandidates(ctx context.Context, st *state.State, names []string, use
 			userID = fallbackID
 		}
 		actionsByUserID[userID] = append(actionsByUserID[userID], &store.SnapAction{
-			Action: "refresh",
-			SnapID: installed.SnapID,
+			Action:       "refresh",
+			SnapID:       installed.SnapID,
+			InstanceName: installed.InstanceName,
 		})
 		if snapst.IgnoreValidation {
 			ignoreValidation[installed.SnapID] = true
@@ -416,5 +448,5 @@ func refreshCandidates(ctx context.Context, st *state.State, names []string, use
 		updates = append(updates, updatesForUser...)
 	}
 
-	return updates, stateByID, ignoreValidation, nil
+	return updates, stateByInstanceName, ignoreValidation, nil
 }
@@ -0,0 +1,126 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+// EnforcedValidationSets allows to hook retrieving the validation
+// sets the system enforces.
+var EnforcedValidationSets func(st *state.State) ([]*asserts.ValidationSet, error)
+
+// validationConstraints are the constraints placed on a snap by the
+// enforced validation sets.
+type validationConstraints struct {
+	required bool
+	invalid  bool
+	revision snap.Revision
+	// sets lists the keys of the validation sets mentioning the snap
+	sets []string
+}
+
+func (vc *validationConstraints) in() string {
+	if len(vc.sets) == 1 {
+		return fmt.Sprintf("validation set %s", vc.sets[0])
+	}
+	return fmt.Sprintf("validation sets %s", strings.Join(vc.sets, ", "))
+}
+
+// validationConstraintsFor collects the constraints the enforced
+// validation sets place on the snap with the given name or snap id.
+func validationConstraintsFor(st *state.State, snapName, snapID string) (*validationConstraints, error) {
+	vc := &validationConstraints{}
+	if EnforcedValidationSets == nil {
+		return vc, nil
+	}
+	sets, err := EnforcedValidationSets(st)
+	if err != nil {
+		return nil, err
+	}
+	for _, vs := range sets {
+		for _, sn := range vs.Snaps() {
+			if sn.Name != snapName && (snapID == "" || sn.SnapID != snapID) {
+				continue
+			}
+			key := vs.AccountID() + "/" + vs.Name()
+			vc.sets = append(vc.sets, key)
+			switch sn.Presence {
+			case asserts.PresenceRequired:
+				vc.required = true
+			case asserts.PresenceInvalid:
+				vc.invalid = true
+			}
+			if sn.Revision != 0 {
+				rev := snap.R(sn.Revision)
+				if !vc.revision.Unset() && vc.revision != rev {
+					return nil, fmt.Errorf("cannot use snap %q: enforced validation sets require conflicting revisions %s and %s", snapName, vc.revision, rev)
+				}
+				vc.revision = rev
+			}
+		}
+	}
+	return vc, nil
+}
+
+// checkValidationSetsForInstall checks that installing or refreshing
+// to the given snap revision would not break the enforced validation
+// sets.
+func checkValidationSetsForInstall(st *state.State, info *snap.Info) error {
+	vc, err := validationConstraintsFor(st, info.SnapName(), info.SnapID)
+	if err != nil {
+		return err
+	}
+	if vc.invalid {
+		return fmt.Errorf("cannot install snap %q: snap is invalid in %s", info.Name(), vc.in())
+	}
+	if !vc.revision.Unset() && info.Revision != vc.revision {
+		return fmt.Errorf("cannot install snap %q at revision %s: %s requires revision %s", info.Name(), info.Revision, vc.in(), vc.revision)
+	}
+	return nil
+}
+
+// checkValidationSetsForRemove checks that removing the given snap
+// would not break the enforced validation sets.
+func checkValidationSetsForRemove(st *state.State, snapName, snapID string) error {
+	vc, err := validationConstraintsFor(st, snapName, snapID)
+	if err != nil {
+		return err
+	}
+	if vc.required {
+		return fmt.Errorf("cannot remove snap %q: snap is required by %s", snapName, vc.in())
+	}
+	return nil
+}
+
+// requiredRevision returns the revision of the snap the enforced
+// validation sets require, if any.
+func requiredRevision(st *state.State, snapName string) (snap.Revision, error) {
+	vc, err := validationConstraintsFor(st, snapName, "")
+	if err != nil {
+		return snap.Revision{}, err
+	}
+	return vc.revision, nil
+}
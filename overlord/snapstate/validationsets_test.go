@@ -0,0 +1,145 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package snapstate_test
+
+import (
+	"context"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/overlord/snapstate"
+	"github.com/snapcore/snapd/overlord/state"
+	"github.com/snapcore/snapd/snap"
+)
+
+func (s *snapmgrTestSuite) mockEnforcedValidationSet(c *C, snaps ...interface{}) {
+	privKey, _ := assertstest.GenerateKey(752)
+	signing := assertstest.NewSigningDB("my-brand", privKey)
+	vs, err := signing.Sign(asserts.ValidationSetType, map[string]interface{}{
+		"series":       "16",
+		"authority-id": "my-brand",
+		"account-id":   "my-brand",
+		"name":         "my-set",
+		"sequence":     "1",
+		"snaps":        snaps,
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+
+	snapstate.EnforcedValidationSets = func(*state.State) ([]*asserts.ValidationSet, error) {
+		return []*asserts.ValidationSet{vs.(*asserts.ValidationSet)}, nil
+	}
+}
+
+func (s *snapmgrTestSuite) TestInstallValidationSetRequiredRevision(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.mockEnforcedValidationSet(c, map[string]interface{}{
+		"name":     "some-snap",
+		"id":       "someidididididididididididididid",
+		"revision": "42",
+	})
+
+	ts, err := snapstate.Install(s.state, "some-snap", "", snap.R(0), 0, snapstate.Flags{})
+	c.Assert(err, IsNil)
+
+	var snapsup snapstate.SnapSetup
+	err = ts.Tasks()[0].Get("snap-setup", &snapsup)
+	c.Assert(err, IsNil)
+	c.Check(snapsup.Revision(), Equals, snap.R(42))
+
+	_, err = snapstate.Install(s.state, "some-snap", "", snap.R(7), 0, snapstate.Flags{})
+	c.Check(err, ErrorMatches, `cannot install snap "some-snap" at revision 7: validation set my-brand/my-set requires revision 42`)
+}
+
+func (s *snapmgrTestSuite) TestInstallValidationSetInvalid(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	s.mockEnforcedValidationSet(c, map[string]interface{}{
+		"name":     "some-snap",
+		"id":       "someidididididididididididididid",
+		"presence": "invalid",
+	})
+
+	_, err := snapstate.Install(s.state, "some-snap", "", snap.R(0), 0, snapstate.Flags{})
+	c.Check(err, ErrorMatches, `cannot install snap "some-snap": snap is invalid in validation set my-brand/my-set`)
+}
+
+func (s *snapmgrTestSuite) TestUpdateValidationSetRequiredRevision(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7)},
+		},
+		Current:  snap.R(7),
+		SnapType: "app",
+	})
+	s.mockEnforcedValidationSet(c, map[string]interface{}{
+		"name":     "some-snap",
+		"id":       "someidididididididididididididid",
+		"revision": "7",
+	})
+
+	_, err := snapstate.Update(s.state, "some-snap", "", snap.R(0), 0, snapstate.Flags{})
+	c.Check(err, ErrorMatches, `cannot install snap "some-snap" at revision 11: validation set my-brand/my-set requires revision 7`)
+
+	// refresh all skips the snap
+	updates, tts, err := snapstate.UpdateMany(context.TODO(), s.state, nil, 0)
+	c.Assert(err, IsNil)
+	c.Check(updates, HasLen, 0)
+	c.Check(tts, HasLen, 0)
+}
+
+func (s *snapmgrTestSuite) TestRemoveValidationSetRequired(c *C) {
+	s.state.Lock()
+	defer s.state.Unlock()
+
+	snapstate.Set(s.state, "some-snap", &snapstate.SnapState{
+		Active: true,
+		Sequence: []*snap.SideInfo{
+			{RealName: "some-snap", SnapID: "some-snap-id", Revision: snap.R(7)},
+		},
+		Current:  snap.R(7),
+		SnapType: "app",
+	})
+	s.mockEnforcedValidationSet(c, map[string]interface{}{
+		"name": "some-snap",
+		"id":   "someidididididididididididididid",
+	})
+
+	_, err := snapstate.Remove(s.state, "some-snap", snap.R(0), nil)
+	c.Check(err, ErrorMatches, `cannot remove snap "some-snap": snap is required by validation set my-brand/my-set`)
+
+	s.mockEnforcedValidationSet(c, map[string]interface{}{
+		"name":     "some-snap",
+		"id":       "someidididididididididididididid",
+		"presence": "optional",
+	})
+	_, err = snapstate.Remove(s.state, "some-snap", snap.R(0), nil)
+	c.Check(err, IsNil)
+}
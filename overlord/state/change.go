@@ -104,6 +104,18 @@ func (s Status) String() string {
 	panic(fmt.Sprintf("internal error: unknown task status code: %d", s))
 }
 
+// Priority of a change. When task runners limit how many tasks they run
+// concurrently, the tasks of changes with higher priority run first.
+type Priority int
+
+const (
+	// DefaultPriority is the priority of changes unless set otherwise.
+	DefaultPriority Priority = 0
+	// LowPriority is for background changes, like automatic refreshes,
+	// that should give way to changes requested by users.
+	LowPriority Priority = -1
+)
+
 // Change represents a tracked modification to the system state.
 //
 // The Change provides both the justification for individual tasks
@@ -126,6 +138,8 @@ type Change struct {
 	lanes   int
 	ready   chan struct{}
 
+	priority Priority
+
 	spawnTime time.Time
 	readyTime time.Time
 }
@@ -159,6 +173,8 @@ type marshalledChange struct {
 	TaskIDs []string                    `json:"task-ids,omitempty"`
 	Lanes   int                         `json:"lanes,omitempty"`
 
+	Priority Priority `json:"priority,omitempty"`
+
 	SpawnTime time.Time  `json:"spawn-time"`
 	ReadyTime *time.Time `json:"ready-time,omitempty"`
 }
@@ -180,6 +196,8 @@ func (c *Change) MarshalJSON() ([]byte, error) {
 		TaskIDs: c.taskIDs,
 		Lanes:   c.lanes,
 
+		Priority: c.priority,
+
 		SpawnTime: c.spawnTime,
 		ReadyTime: readyTime,
 	})
@@ -208,6 +226,7 @@ func (c *Change) UnmarshalJSON(data []byte) error {
 	c.taskIDs = unmarshalled.TaskIDs
 	c.lanes = unmarshalled.Lanes
 	c.ready = make(chan struct{})
+	c.priority = unmarshalled.Priority
 	c.spawnTime = unmarshalled.SpawnTime
 	if unmarshalled.ReadyTime != nil {
 		c.readyTime = *unmarshalled.ReadyTime
@@ -237,10 +256,24 @@ func (c *Change) Summary() string {
 	return c.summary
 }
 
+// Priority returns the priority of the change.
+func (c *Change) Priority() Priority {
+	c.state.reading()
+	return c.priority
+}
+
+// SetPriority sets the priority of the change. See Priority.
+func (c *Change) SetPriority(p Priority) {
+	c.state.writing()
+	c.state.touchChange(c.id)
+	c.priority = p
+}
+
 // Set associates value with key for future consulting by managers.
 // The provided value must properly marshal and unmarshal with encoding/json.
 func (c *Change) Set(key string, value interface{}) {
 	c.state.writing()
+	c.state.touchChange(c.id)
 	c.data.set(key, value)
 }
 
@@ -301,6 +334,7 @@ func (c *Change) Status() Status {
 // SetStatus sets the change status, overriding the default behavior (see Status method).
 func (c *Change) SetStatus(s Status) {
 	c.state.writing()
+	c.state.touchChange(c.id)
 	c.status = s
 	if s.Ready() {
 		c.markReady()
@@ -308,6 +342,7 @@ func (c *Change) SetStatus(s Status) {
 }
 
 func (c *Change) markReady() {
+	c.state.touchChange(c.id)
 	select {
 	case <-c.ready:
 	default:
@@ -323,6 +358,15 @@ func (c *Change) Ready() <-chan struct{} {
 	return c.ready
 }
 
+// Watch returns a channel that receives a value whenever the change or
+// any of its tasks is modified, including progress updates. Modifications
+// done before the previous value was received are coalesced. The
+// returned function stops the watching, and needs no state lock.
+func (c *Change) Watch() (modified <-chan struct{}, stop func()) {
+	c.state.reading()
+	return c.state.watchChange(c.id)
+}
+
 // taskStatusChanged is called by tasks when their status is changed,
 // to give the opportunity for the change to close its ready channel.
 func (c *Change) taskStatusChanged(t *Task, old, new Status) {
@@ -380,6 +424,7 @@ func (c *Change) taskCleanChanged() {
 		}
 	}
 	c.clean = true
+	c.state.touchChange(c.id)
 }
 
 // SpawnTime returns the time when the change was created.
@@ -460,6 +505,8 @@ func (c *Change) AddTask(t *Task) {
 	}
 	t.change = c.id
 	c.taskIDs = addOnce(c.taskIDs, t.ID())
+	c.state.touchChange(c.id)
+	c.state.touchTask(t.id)
 }
 
 // AddAll registers all tasks in the set as required for the state
@@ -608,3 +655,46 @@ func (c *Change) abortTasks(tasks []*Task, abortedLanes map[int]bool, seenTasks
 		c.abortLanes(lanes, abortedLanes, seenTasks)
 	}
 }
+
+func (s *State) watchChange(id string) (<-chan struct{}, func()) {
+	s.watchersLck.Lock()
+	defer s.watchersLck.Unlock()
+
+	if s.watchers == nil {
+		s.watchers = make(map[string][]chan struct{})
+	}
+	ch := make(chan struct{}, 1)
+	s.watchers[id] = append(s.watchers[id], ch)
+	stop := func() {
+		s.watchersLck.Lock()
+		defer s.watchersLck.Unlock()
+
+		watchers := s.watchers[id]
+		for i, w := range watchers {
+			if w == ch {
+				watchers = append(watchers[:i], watchers[i+1:]...)
+				break
+			}
+		}
+		if len(watchers) == 0 {
+			delete(s.watchers, id)
+		} else {
+			s.watchers[id] = watchers
+		}
+	}
+	return ch, stop
+}
+
+// notifyChange lets the watchers of the change know it was modified.
+func (s *State) notifyChange(id string) {
+	s.watchersLck.Lock()
+	defer s.watchersLck.Unlock()
+
+	for _, ch := range s.watchers[id] {
+		select {
+		case ch <- struct{}{}:
+		default:
+			// a notification is already pending
+		}
+	}
+}
@@ -20,6 +20,8 @@
 package state_test
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strconv"
@@ -67,6 +69,27 @@ func (cs *changeSuite) TestReadyTime(c *C) {
 	c.Check(t.Before(now.Add(5*time.Second)), Equals, true)
 }
 
+func (cs *changeSuite) TestPriority(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	chg := st.NewChange("auto-refresh", "summary...")
+	c.Check(chg.Priority(), Equals, state.DefaultPriority)
+
+	chg.SetPriority(state.LowPriority)
+	c.Check(chg.Priority(), Equals, state.LowPriority)
+
+	// the priority is persisted
+	data, err := json.Marshal(st)
+	c.Assert(err, IsNil)
+	st2, err := state.ReadState(nil, bytes.NewReader(data))
+	c.Assert(err, IsNil)
+	st2.Lock()
+	defer st2.Unlock()
+	c.Check(st2.Change(chg.ID()).Priority(), Equals, state.LowPriority)
+}
+
 func (cs *changeSuite) TestStatusString(c *C) {
 	for s := state.Status(0); s < state.ErrorStatus+1; s++ {
 		c.Assert(s.String(), Matches, ".+")
@@ -723,3 +746,60 @@ func (ts *taskRunnerSuite) TestAbortLanes(c *C) {
 		c.Assert(strings.Join(obtained, " "), Equals, strings.Join(expected, " "), Commentf("setup: %s", test.setup))
 	}
 }
+
+func (cs *changeSuite) TestWatch(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	chg := st.NewChange("install", "...")
+	t := st.NewTask("download", "...")
+	chg.AddTask(t)
+	other := st.NewChange("remove", "...")
+
+	modified, stop := chg.Watch()
+
+	select {
+	case <-modified:
+		c.Fatal("change reported as modified before any modification")
+	default:
+	}
+
+	// modifications are coalesced
+	t.SetStatus(state.DoingStatus)
+	t.SetProgress("label", 1, 10)
+	t.Logf("some log")
+	select {
+	case <-modified:
+	default:
+		c.Fatal("change not reported as modified")
+	}
+	select {
+	case <-modified:
+		c.Fatal("change reported as modified twice")
+	default:
+	}
+
+	chg.Set("key", "value")
+	select {
+	case <-modified:
+	default:
+		c.Fatal("change not reported as modified")
+	}
+
+	// other changes are not watched
+	other.Set("key", "value")
+	select {
+	case <-modified:
+		c.Fatal("change reported as modified by another change")
+	default:
+	}
+
+	stop()
+	t.SetStatus(state.DoneStatus)
+	select {
+	case <-modified:
+		c.Fatal("change reported as modified after stopping")
+	default:
+	}
+}
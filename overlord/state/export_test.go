@@ -44,3 +44,26 @@ func MockTaskTimes(t *Task, spawnTime, readyTime time.Time) {
 	t.spawnTime = spawnTime
 	t.readyTime = readyTime
 }
+
+func (s *State) AddWarning(message string, t time.Time) {
+	s.addWarning(message, t)
+}
+
+func MockWarningTimes(w *Warning, firstAdded, lastAdded, lastShown time.Time) {
+	w.firstAdded = firstAdded
+	w.lastAdded = lastAdded
+	w.lastShown = lastShown
+}
+
+func (w *Warning) LastShown() time.Time {
+	return w.lastShown
+}
+
+// MockJournalCompactSize changes minJournalCompactSize.
+func MockJournalCompactSize(size int) (restore func()) {
+	old := minJournalCompactSize
+	minJournalCompactSize = size
+	return func() {
+		minJournalCompactSize = old
+	}
+}
@@ -0,0 +1,246 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package state
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/snapcore/snapd/logger"
+)
+
+// A JournalBackend is a Backend that can also persist the state
+// incrementally.
+//
+// On unlock only the entries modified since the last checkpoint are
+// handed to Journal, which must durably append them to the state
+// journal. A full Checkpoint is still requested from time to time to
+// compact the journal, and it must discard the journal once the full
+// state is safely written.
+type JournalBackend interface {
+	Backend
+	Journal(entry []byte) error
+}
+
+// the journal is compacted with a full checkpoint once it grows bigger
+// than the last full checkpoint, but never before it reaches
+// minJournalCompactSize
+var minJournalCompactSize = 64 * 1024
+
+// dirtyEntries tracks the entries modified since the last checkpoint.
+type dirtyEntries struct {
+	data     map[string]bool
+	changes  map[string]bool
+	tasks    map[string]bool
+	warnings map[string]bool
+}
+
+func markDirty(set *map[string]bool, key string) {
+	if *set == nil {
+		*set = make(map[string]bool)
+	}
+	(*set)[key] = true
+}
+
+// the touch methods record the entries modified since the last
+// checkpoint, letting also the watchers of changes know about them
+// (see Change.Watch)
+
+func (s *State) touchData(key string) {
+	markDirty(&s.dirty.data, key)
+}
+
+func (s *State) touchChange(id string) {
+	markDirty(&s.dirty.changes, id)
+	s.notifyChange(id)
+}
+
+func (s *State) touchTask(id string) {
+	markDirty(&s.dirty.tasks, id)
+	if t := s.tasks[id]; t != nil && t.change != "" {
+		s.notifyChange(t.change)
+	}
+}
+
+func (s *State) touchWarning(message string) {
+	markDirty(&s.dirty.warnings, message)
+}
+
+// journalEntry holds the entries modified since the previous entry, or
+// the last full checkpoint. Removed entries are recorded as null.
+type journalEntry struct {
+	Seq uint64 `json:"seq"`
+
+	Data     map[string]*json.RawMessage `json:"data,omitempty"`
+	Changes  map[string]*Change          `json:"changes,omitempty"`
+	Tasks    map[string]*Task            `json:"tasks,omitempty"`
+	Warnings map[string]*Warning         `json:"warnings,omitempty"`
+
+	LastChangeId int `json:"last-change-id"`
+	LastTaskId   int `json:"last-task-id"`
+	LastLaneId   int `json:"last-lane-id"`
+}
+
+// needsCompaction returns whether the next checkpoint must be a full one.
+func (s *State) needsCompaction() bool {
+	if s.compact {
+		return true
+	}
+	return s.journalSize >= minJournalCompactSize && s.journalSize >= s.checkpointSize
+}
+
+// journalEntryData returns the next journal entry, terminated by a newline.
+func (s *State) journalEntryData() []byte {
+	entry := journalEntry{
+		Seq: s.journalSeq + 1,
+
+		LastChangeId: s.lastChangeId,
+		LastTaskId:   s.lastTaskId,
+		LastLaneId:   s.lastLaneId,
+	}
+	if len(s.dirty.data) > 0 {
+		entry.Data = make(map[string]*json.RawMessage, len(s.dirty.data))
+		for key := range s.dirty.data {
+			entry.Data[key] = s.data[key]
+		}
+	}
+	if len(s.dirty.changes) > 0 {
+		entry.Changes = make(map[string]*Change, len(s.dirty.changes))
+		for id := range s.dirty.changes {
+			entry.Changes[id] = s.changes[id]
+		}
+	}
+	if len(s.dirty.tasks) > 0 {
+		entry.Tasks = make(map[string]*Task, len(s.dirty.tasks))
+		for id := range s.dirty.tasks {
+			entry.Tasks[id] = s.tasks[id]
+		}
+	}
+	if len(s.dirty.warnings) > 0 {
+		entry.Warnings = make(map[string]*Warning, len(s.dirty.warnings))
+		for message := range s.dirty.warnings {
+			entry.Warnings[message] = s.warnings[message]
+		}
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		// this shouldn't happen, because the actual delicate serializing happens at various Set()s
+		logger.Panicf("internal error: could not marshal state journal entry: %v", err)
+	}
+	return append(data, '\n')
+}
+
+// journaled records that the journal entry in data was persisted.
+func (s *State) journaled(data []byte) {
+	s.journalSeq++
+	s.journalSize += len(data)
+	s.modified = false
+	s.dirty = dirtyEntries{}
+}
+
+// checkpointed records that the full state in data was persisted.
+func (s *State) checkpointed(data []byte) {
+	s.checkpointSize = len(data)
+	s.journalSize = 0
+	s.compact = false
+	s.modified = false
+	s.dirty = dirtyEntries{}
+}
+
+// apply applies the journal entry to the state.
+func (e *journalEntry) apply(s *State, replaced map[string]*Change) {
+	for key, value := range e.Data {
+		if value == nil {
+			delete(s.data, key)
+		} else {
+			s.data[key] = value
+		}
+	}
+	for id, chg := range e.Changes {
+		if chg == nil {
+			delete(s.changes, id)
+			delete(replaced, id)
+			continue
+		}
+		chg.state = s
+		s.changes[id] = chg
+		replaced[id] = chg
+	}
+	for id, t := range e.Tasks {
+		if t == nil {
+			delete(s.tasks, id)
+			continue
+		}
+		t.state = s
+		s.tasks[id] = t
+	}
+	for message, w := range e.Warnings {
+		if w == nil {
+			delete(s.warnings, message)
+		} else {
+			s.warnings[message] = w
+		}
+	}
+	s.lastChangeId = e.LastChangeId
+	s.lastTaskId = e.LastTaskId
+	s.lastLaneId = e.LastLaneId
+	s.journalSeq = e.Seq
+}
+
+// replayJournal applies to the state the entries read from r that are
+// not yet part of it. An incomplete last entry, left behind by an
+// interrupted write, is ignored.
+func (s *State) replayJournal(r io.Reader) (size int, err error) {
+	replaced := make(map[string]*Change)
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadBytes('\n')
+		size += len(line)
+		if err == io.EOF {
+			if len(line) > 0 {
+				logger.Noticef("ignoring incomplete entry at the end of the state journal")
+			}
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		var entry journalEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return 0, fmt.Errorf("cannot read state journal entry: %v", err)
+		}
+		if entry.Seq <= s.journalSeq {
+			// already part of the checkpoint
+			continue
+		}
+		if entry.Seq != s.journalSeq+1 {
+			return 0, fmt.Errorf("cannot replay state journal: expected entry %d, got %d", s.journalSeq+1, entry.Seq)
+		}
+		entry.apply(s, replaced)
+	}
+	for _, chg := range replaced {
+		chg.finishUnmarshal()
+	}
+	return size, nil
+}
@@ -0,0 +1,390 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package state_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+type journalSuite struct{}
+
+var _ = Suite(&journalSuite{})
+
+type fakeJournalBackend struct {
+	fakeStateBackend
+	journal      bytes.Buffer
+	entries      [][]byte
+	journalError func() error
+}
+
+func (b *fakeJournalBackend) Checkpoint(data []byte) error {
+	if err := b.fakeStateBackend.Checkpoint(data); err != nil {
+		return err
+	}
+	b.journal.Reset()
+	return nil
+}
+
+func (b *fakeJournalBackend) Journal(entry []byte) error {
+	if b.journalError != nil {
+		if err := b.journalError(); err != nil {
+			// leave a partial entry behind
+			b.journal.Write(entry[:len(entry)/2])
+			return err
+		}
+	}
+	b.entries = append(b.entries, entry)
+	b.journal.Write(entry)
+	return nil
+}
+
+func (b *fakeJournalBackend) lastCheckpoint() []byte {
+	return b.checkpoints[len(b.checkpoints)-1]
+}
+
+func marshalState(c *C, st *state.State) []byte {
+	st.Lock()
+	defer st.Unlock()
+	data, err := json.Marshal(st)
+	c.Assert(err, IsNil)
+	return data
+}
+
+func (js *journalSuite) TestFirstCheckpointIsFull(c *C) {
+	b := &fakeJournalBackend{}
+	st := state.New(b)
+	st.Lock()
+	st.Set("foo", "bar")
+	st.Unlock()
+
+	c.Check(b.checkpoints, HasLen, 1)
+	c.Check(b.entries, HasLen, 0)
+}
+
+func (js *journalSuite) TestJournalOnlyModifiedEntries(c *C) {
+	b := &fakeJournalBackend{}
+	st := state.New(b)
+	st.Lock()
+	st.Set("foo", "bar")
+	st.Set("other", 1)
+	chg := st.NewChange("chg", "...")
+	t1 := st.NewTask("t1", "...")
+	t2 := st.NewTask("t2", "...")
+	chg.AddTask(t1)
+	chg.AddTask(t2)
+	st.Unlock()
+	c.Assert(b.checkpoints, HasLen, 1)
+
+	st.Lock()
+	st.Set("foo", "baz")
+	t2.Logf("hello")
+	st.Unlock()
+
+	c.Assert(b.checkpoints, HasLen, 1)
+	c.Assert(b.entries, HasLen, 1)
+
+	var entry map[string]*json.RawMessage
+	err := json.Unmarshal(b.entries[0], &entry)
+	c.Assert(err, IsNil)
+	c.Check(string(*entry["seq"]), Equals, "1")
+	c.Check(string(*entry["data"]), Equals, `{"foo":"baz"}`)
+	c.Check(entry["changes"], IsNil)
+	var tasks map[string]*json.RawMessage
+	err = json.Unmarshal(*entry["tasks"], &tasks)
+	c.Assert(err, IsNil)
+	c.Check(tasks, HasLen, 1)
+	c.Check(tasks[t2.ID()], NotNil)
+}
+
+func (js *journalSuite) TestJournalReplay(c *C) {
+	b := &fakeJournalBackend{}
+	st := state.New(b)
+	st.Lock()
+	st.Set("foo", "bar")
+	st.Set("gone", "soon")
+	st.Unlock()
+	c.Assert(b.checkpoints, HasLen, 1)
+
+	st.Lock()
+	chg := st.NewChange("chg", "...")
+	t1 := st.NewTask("t1", "...")
+	t2 := st.NewTask("t2", "...")
+	t2.WaitFor(t1)
+	chg.AddTask(t1)
+	chg.AddTask(t2)
+	st.Set("gone", nil)
+	st.Warnf("something happened")
+	st.Unlock()
+
+	st.Lock()
+	t1.SetStatus(state.DoneStatus)
+	t1.Set("key", "value")
+	t2.SetStatus(state.DoneStatus)
+	chg.Set("key", 42)
+	st.NewLane()
+	st.Unlock()
+
+	c.Assert(b.checkpoints, HasLen, 1)
+	c.Assert(b.entries, HasLen, 2)
+
+	st2, err := state.ReadStateWithJournal(nil, bytes.NewReader(b.lastCheckpoint()), bytes.NewReader(b.journal.Bytes()))
+	c.Assert(err, IsNil)
+	c.Check(st2.Modified(), Equals, false)
+	c.Check(string(marshalState(c, st2)), Equals, string(marshalState(c, st)))
+
+	st2.Lock()
+	defer st2.Unlock()
+	chg2 := st2.Change(chg.ID())
+	c.Assert(chg2, NotNil)
+	c.Check(chg2.Status(), Equals, state.DoneStatus)
+	select {
+	case <-chg2.Ready():
+	default:
+		c.Errorf("change replayed from the journal not ready")
+	}
+	c.Check(chg2.Tasks(), HasLen, 2)
+	var gone string
+	c.Check(st2.Get("gone", &gone), Equals, state.ErrNoState)
+}
+
+func (js *journalSuite) TestJournalReplayPrune(c *C) {
+	b := &fakeJournalBackend{}
+	st := state.New(b)
+	st.Lock()
+	chg := st.NewChange("chg", "...")
+	t1 := st.NewTask("t1", "...")
+	chg.AddTask(t1)
+	t1.SetStatus(state.DoneStatus)
+	st.Unlock()
+	c.Assert(b.checkpoints, HasLen, 1)
+
+	st.Lock()
+	st.Prune(time.Hour, time.Hour, 0)
+	st.Unlock()
+	c.Assert(b.entries, HasLen, 1)
+
+	st2, err := state.ReadStateWithJournal(nil, bytes.NewReader(b.lastCheckpoint()), bytes.NewReader(b.journal.Bytes()))
+	c.Assert(err, IsNil)
+	st2.Lock()
+	defer st2.Unlock()
+	c.Check(st2.Changes(), HasLen, 0)
+	c.Check(st2.Task(t1.ID()), IsNil)
+}
+
+func (js *journalSuite) TestJournalCompaction(c *C) {
+	restore := state.MockJournalCompactSize(0)
+	defer restore()
+
+	b := &fakeJournalBackend{}
+	st := state.New(b)
+	st.Lock()
+	st.Set("foo", "bar")
+	st.Unlock()
+	c.Assert(b.checkpoints, HasLen, 1)
+
+	// the journal grows until it's as big as the last checkpoint
+	for i := 0; i < 20 && len(b.checkpoints) == 1; i++ {
+		st.Lock()
+		st.Set("foo", fmt.Sprintf("bar%d", i))
+		st.Unlock()
+	}
+	c.Assert(b.checkpoints, HasLen, 2)
+	c.Check(len(b.entries) > 1, Equals, true)
+	c.Check(b.journal.Len(), Equals, 0)
+
+	// the checkpoint accounts for all the journaled entries
+	st2, err := state.ReadState(nil, bytes.NewReader(b.lastCheckpoint()))
+	c.Assert(err, IsNil)
+	c.Check(string(marshalState(c, st2)), Equals, string(marshalState(c, st)))
+}
+
+func (js *journalSuite) TestJournalErrorFallsBackToCheckpoint(c *C) {
+	restore := state.MockCheckpointRetryDelay(2*time.Millisecond, 1*time.Second)
+	defer restore()
+
+	b := &fakeJournalBackend{}
+	st := state.New(b)
+	st.Lock()
+	st.Set("foo", "bar")
+	st.Unlock()
+	c.Assert(b.checkpoints, HasLen, 1)
+
+	b.journalError = func() error { return errors.New("boom") }
+	st.Lock()
+	st.Set("foo", "baz")
+	st.Unlock()
+
+	c.Check(b.entries, HasLen, 0)
+	c.Assert(b.checkpoints, HasLen, 2)
+	// the partial entry was discarded
+	c.Check(b.journal.Len(), Equals, 0)
+
+	st2, err := state.ReadState(nil, bytes.NewReader(b.lastCheckpoint()))
+	c.Assert(err, IsNil)
+	st2.Lock()
+	defer st2.Unlock()
+	var foo string
+	c.Assert(st2.Get("foo", &foo), IsNil)
+	c.Check(foo, Equals, "baz")
+}
+
+func (js *journalSuite) TestReplayIgnoresIncompleteLastEntry(c *C) {
+	b := &fakeJournalBackend{}
+	st := state.New(b)
+	st.Lock()
+	st.Set("foo", "bar")
+	st.Unlock()
+
+	st.Lock()
+	st.Set("foo", "baz")
+	st.Unlock()
+	c.Assert(b.entries, HasLen, 1)
+
+	journal := append(b.journal.Bytes(), `{"seq":2,"data":{"fo`...)
+	st2, err := state.ReadStateWithJournal(b, bytes.NewReader(b.lastCheckpoint()), bytes.NewReader(journal))
+	c.Assert(err, IsNil)
+	st2.Lock()
+	var foo string
+	c.Assert(st2.Get("foo", &foo), IsNil)
+	c.Check(foo, Equals, "baz")
+	// the next write starts over from a full checkpoint
+	st2.Set("foo", "quux")
+	st2.Unlock()
+	c.Check(b.checkpoints, HasLen, 2)
+	c.Check(b.entries, HasLen, 1)
+}
+
+func (js *journalSuite) TestReplaySkipsCheckpointedEntries(c *C) {
+	b := &fakeJournalBackend{}
+	st := state.New(b)
+	st.Lock()
+	st.Set("foo", "bar")
+	st.Unlock()
+
+	st.Lock()
+	st.Set("foo", "baz")
+	st.Unlock()
+	c.Assert(b.entries, HasLen, 1)
+	staleJournal := append([]byte(nil), b.journal.Bytes()...)
+
+	// a checkpoint done without the journal being removed after it
+	st.Lock()
+	st.Compact()
+	st.Set("foo", "quux")
+	st.Unlock()
+	c.Assert(b.checkpoints, HasLen, 2)
+
+	st2, err := state.ReadStateWithJournal(nil, bytes.NewReader(b.lastCheckpoint()), bytes.NewReader(staleJournal))
+	c.Assert(err, IsNil)
+	st2.Lock()
+	defer st2.Unlock()
+	var foo string
+	c.Assert(st2.Get("foo", &foo), IsNil)
+	c.Check(foo, Equals, "quux")
+}
+
+func (js *journalSuite) TestReplaySequenceGap(c *C) {
+	checkpoint := []byte(`{"data":{},"changes":{},"tasks":{},"last-change-id":0,"last-task-id":0,"last-lane-id":0,"journal-seq":1}`)
+	journal := []byte(`{"seq":3,"data":{"foo":"bar"},"last-change-id":0,"last-task-id":0,"last-lane-id":0}` + "\n")
+
+	_, err := state.ReadStateWithJournal(nil, bytes.NewReader(checkpoint), bytes.NewReader(journal))
+	c.Check(err, ErrorMatches, "cannot replay state journal: expected entry 2, got 3")
+}
+
+func (js *journalSuite) TestReplayCorruptEntry(c *C) {
+	checkpoint := []byte(`{"data":{},"changes":{},"tasks":{},"last-change-id":0,"last-task-id":0,"last-lane-id":0}`)
+	journal := []byte("garbage\n")
+
+	_, err := state.ReadStateWithJournal(nil, bytes.NewReader(checkpoint), bytes.NewReader(journal))
+	c.Check(err, ErrorMatches, "cannot read state journal entry: .*")
+}
+
+// populatedState returns a state with many changes, tasks and data
+// entries, as found on busy devices.
+func populatedState(backend state.Backend) (*state.State, []*state.Task) {
+	st := state.New(backend)
+	st.Lock()
+	defer st.Unlock()
+
+	var tasks []*state.Task
+	for i := 0; i < 50; i++ {
+		chg := st.NewChange("chg", "...")
+		for j := 0; j < 10; j++ {
+			t := st.NewTask("task", "...")
+			t.Set("some-data", map[string]string{"foo": "bar", "baz": "quux"})
+			t.Logf("something happened")
+			chg.AddTask(t)
+			tasks = append(tasks, t)
+		}
+	}
+	for i := 0; i < 200; i++ {
+		st.Set(fmt.Sprintf("entry-%d", i), map[string]interface{}{"value": i, "list": []int{1, 2, 3}})
+	}
+	return st, tasks
+}
+
+func benchmarkUnlock(c *C, backend state.Backend) {
+	st, tasks := populatedState(backend)
+	c.ResetTimer()
+	for i := 0; i < c.N; i++ {
+		st.Lock()
+		tasks[i%len(tasks)].Set("counter", i)
+		st.Unlock()
+	}
+}
+
+type discardBackend struct {
+	fakeStateBackend
+	written int
+}
+
+func (b *discardBackend) Checkpoint(data []byte) error {
+	b.written += len(data)
+	return nil
+}
+
+type discardJournalBackend struct {
+	discardBackend
+}
+
+func (b *discardJournalBackend) Journal(entry []byte) error {
+	b.written += len(entry)
+	return nil
+}
+
+func (js *journalSuite) BenchmarkUnlockFullCheckpoint(c *C) {
+	b := &discardBackend{}
+	benchmarkUnlock(c, b)
+	c.SetBytes(int64(b.written / c.N))
+}
+
+func (js *journalSuite) BenchmarkUnlockJournal(c *C) {
+	b := &discardJournalBackend{}
+	benchmarkUnlock(c, b)
+	c.SetBytes(int64(b.written / c.N))
+}
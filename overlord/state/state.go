@@ -36,6 +36,7 @@ import (
 
 // A Backend is used by State to checkpoint on every unlock operation
 // and to mediate requests to ensure the state sooner or request restarts.
+// See JournalBackend for persisting the state incrementally instead.
 type Backend interface {
 	Checkpoint(data []byte) error
 	EnsureBefore(d time.Duration)
@@ -85,7 +86,8 @@ const (
 // operations without it.
 //
 // The state is persisted on every unlock operation via the StateBackend
-// it was initialized with.
+// it was initialized with. If that is a JournalBackend only the entries
+// modified since the last checkpoint are persisted.
 type State struct {
 	mu  sync.Mutex
 	muC int32
@@ -99,10 +101,23 @@ type State struct {
 	changes map[string]*Change
 	tasks   map[string]*Task
 
+	warnings map[string]*Warning
+
 	modified bool
 
+	// incremental persistence, see JournalBackend
+	dirty          dirtyEntries
+	compact        bool
+	journalSeq     uint64
+	journalSize    int
+	checkpointSize int
+
 	cache map[interface{}]interface{}
 
+	// see Change.Watch
+	watchersLck sync.Mutex
+	watchers    map[string][]chan struct{}
+
 	restarting RestartType
 	restartLck sync.Mutex
 }
@@ -114,7 +129,9 @@ func New(backend Backend) *State {
 		data:     make(customData),
 		changes:  make(map[string]*Change),
 		tasks:    make(map[string]*Task),
+		warnings: make(map[string]*Warning),
 		modified: true,
+		compact:  true,
 		cache:    make(map[interface{}]interface{}),
 	}
 }
@@ -153,9 +170,13 @@ type marshalledState struct {
 	Changes map[string]*Change          `json:"changes"`
 	Tasks   map[string]*Task            `json:"tasks"`
 
+	Warnings []*Warning `json:"warnings,omitempty"`
+
 	LastChangeId int `json:"last-change-id"`
 	LastTaskId   int `json:"last-task-id"`
 	LastLaneId   int `json:"last-lane-id"`
+
+	JournalSeq uint64 `json:"journal-seq,omitempty"`
 }
 
 // MarshalJSON makes State a json.Marshaller
@@ -166,9 +187,13 @@ func (s *State) MarshalJSON() ([]byte, error) {
 		Changes: s.changes,
 		Tasks:   s.tasks,
 
+		Warnings: s.flattenWarnings(),
+
 		LastTaskId:   s.lastTaskId,
 		LastChangeId: s.lastChangeId,
 		LastLaneId:   s.lastLaneId,
+
+		JournalSeq: s.journalSeq,
 	})
 }
 
@@ -183,9 +208,11 @@ func (s *State) UnmarshalJSON(data []byte) error {
 	s.data = unmarshalled.Data
 	s.changes = unmarshalled.Changes
 	s.tasks = unmarshalled.Tasks
+	s.unflattenWarnings(unmarshalled.Warnings)
 	s.lastChangeId = unmarshalled.LastChangeId
 	s.lastTaskId = unmarshalled.LastTaskId
 	s.lastLaneId = unmarshalled.LastLaneId
+	s.journalSeq = unmarshalled.JournalSeq
 	// backlink state again
 	for _, t := range s.tasks {
 		t.state = s
@@ -222,19 +249,43 @@ func (s *State) Unlock() {
 		return
 	}
 
-	data := s.checkpointData()
+	var entry, data []byte
+	journal, ok := s.backend.(JournalBackend)
+	if ok && !s.needsCompaction() {
+		entry = s.journalEntryData()
+	}
 	var err error
 	start := time.Now()
 	for time.Since(start) <= unlockCheckpointRetryMaxTime {
-		if err = s.backend.Checkpoint(data); err == nil {
-			s.modified = false
-			return
+		if entry != nil {
+			if err = journal.Journal(entry); err == nil {
+				s.journaled(entry)
+				return
+			}
+			// the failed write might have left a partial entry
+			// behind, compact it away with a full checkpoint
+			entry = nil
+		} else {
+			if data == nil {
+				data = s.checkpointData()
+			}
+			if err = s.backend.Checkpoint(data); err == nil {
+				s.checkpointed(data)
+				return
+			}
 		}
 		time.Sleep(unlockCheckpointRetryInterval)
 	}
 	logger.Panicf("cannot checkpoint even after %v of retries every %v: %v", unlockCheckpointRetryMaxTime, unlockCheckpointRetryInterval, err)
 }
 
+// Compact asks for the next unlock operation to checkpoint the full
+// state, even if the backend supports persisting it incrementally.
+func (s *State) Compact() {
+	s.writing()
+	s.compact = true
+}
+
 // EnsureBefore asks for an ensure pass to happen sooner within duration from now.
 func (s *State) EnsureBefore(d time.Duration) {
 	if s.backend != nil {
@@ -282,6 +333,7 @@ func (s *State) Get(key string, value interface{}) error {
 // The provided value must properly marshal and unmarshal with encoding/json.
 func (s *State) Set(key string, value interface{}) {
 	s.writing()
+	s.touchData(key)
 	s.data.set(key, value)
 }
 
@@ -310,6 +362,7 @@ func (s *State) NewChange(kind, summary string) *Change {
 	id := strconv.Itoa(s.lastChangeId)
 	chg := newChange(s, id, kind, summary)
 	s.changes[id] = chg
+	s.touchChange(id)
 	return chg
 }
 
@@ -345,6 +398,7 @@ func (s *State) NewTask(kind, summary string) *Task {
 	id := strconv.Itoa(s.lastTaskId)
 	t := newTask(s, id, kind, summary)
 	s.tasks[id] = t
+	s.touchTask(id)
 	return t
 }
 
@@ -388,7 +442,8 @@ func (s *State) tasksIn(tids []string) []*Task {
 
 // Prune removes changes that became ready for more than pruneWait
 // and aborts tasks spawned for more than abortWait.
-// It also removes tasks unlinked to changes after pruneWait. When
+// It also removes tasks unlinked to changes after pruneWait, as well
+// as expired warnings. When
 // there are more changes than the limit set via "maxReadyChanges"
 // those changes in ready state will also removed even if they are below
 // the pruneWait duration.
@@ -397,6 +452,8 @@ func (s *State) Prune(pruneWait, abortWait time.Duration, maxReadyChanges int) {
 	pruneLimit := now.Add(-pruneWait)
 	abortLimit := now.Add(-abortWait)
 
+	s.pruneWarnings(now)
+
 	// sort from oldest to newest
 	changes := s.Changes()
 	sort.Sort(byReadyTime(changes))
@@ -420,6 +477,7 @@ func (s *State) Prune(pruneWait, abortWait time.Duration, maxReadyChanges int) {
 			if spawnTime.Before(pruneLimit) && len(chg.Tasks()) == 0 {
 				chg.Abort()
 				delete(s.changes, chg.ID())
+				s.touchChange(chg.ID())
 			} else if spawnTime.Before(abortLimit) {
 				chg.Abort()
 			}
@@ -430,8 +488,10 @@ func (s *State) Prune(pruneWait, abortWait time.Duration, maxReadyChanges int) {
 			s.writing()
 			for _, t := range chg.Tasks() {
 				delete(s.tasks, t.ID())
+				s.touchTask(t.ID())
 			}
 			delete(s.changes, chg.ID())
+			s.touchChange(chg.ID())
 			readyChangesCount--
 		}
 	}
@@ -441,22 +501,51 @@ func (s *State) Prune(pruneWait, abortWait time.Duration, maxReadyChanges int) {
 		if t.Change() == nil && t.SpawnTime().Before(pruneLimit) {
 			s.writing()
 			delete(s.tasks, tid)
+			s.touchTask(tid)
 		}
 	}
 }
 
 // ReadState returns the state deserialized from r.
 func ReadState(backend Backend, r io.Reader) (*State, error) {
+	return ReadStateWithJournal(backend, r, nil)
+}
+
+// ReadStateWithJournal returns the state deserialized from r, with the
+// entries read from journal, if not nil, replayed on top of it.
+func ReadStateWithJournal(backend Backend, r, journal io.Reader) (*State, error) {
 	s := new(State)
 	s.Lock()
 	defer s.unlock()
-	d := json.NewDecoder(r)
+	cr := &countingReader{r: r}
+	d := json.NewDecoder(cr)
 	err := d.Decode(&s)
 	if err != nil {
 		return nil, err
 	}
+	s.checkpointSize = cr.n
+	if journal != nil {
+		s.journalSize, err = s.replayJournal(journal)
+		if err != nil {
+			return nil, err
+		}
+		// start over from a full checkpoint, the journal might
+		// end with an incomplete entry
+		s.compact = s.journalSize > 0
+	}
 	s.backend = backend
 	s.modified = false
 	s.cache = make(map[interface{}]interface{})
 	return s, err
 }
+
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += n
+	return n, err
+}
@@ -56,6 +56,9 @@ type Task struct {
 	readyTime time.Time
 
 	atTime time.Time
+
+	// see IsQueued
+	queued bool
 }
 
 func newTask(state *State, id, kind, summary string) *Task {
@@ -88,6 +91,8 @@ type marshalledTask struct {
 	ReadyTime *time.Time `json:"ready-time,omitempty"`
 
 	AtTime *time.Time `json:"at-time,omitempty"`
+
+	Queued bool `json:"queued,omitempty"`
 }
 
 // MarshalJSON makes Task a json.Marshaller
@@ -119,6 +124,8 @@ func (t *Task) MarshalJSON() ([]byte, error) {
 		ReadyTime: readyTime,
 
 		AtTime: atTime,
+
+		Queued: t.queued,
 	})
 }
 
@@ -155,6 +162,7 @@ func (t *Task) UnmarshalJSON(data []byte) error {
 	if unmarshalled.AtTime != nil {
 		t.atTime = *unmarshalled.AtTime
 	}
+	t.queued = unmarshalled.Queued
 	return nil
 }
 
@@ -185,6 +193,7 @@ func (t *Task) Status() Status {
 // SetStatus sets the task status, overriding the default behavior (see Status method).
 func (t *Task) SetStatus(new Status) {
 	t.state.writing()
+	t.state.touchTask(t.id)
 	old := t.status
 	t.status = new
 	if !old.Ready() && new.Ready() {
@@ -211,6 +220,7 @@ func (t *Task) SetClean() {
 		return
 	}
 	t.clean = true
+	t.state.touchTask(t.id)
 	chg := t.Change()
 	if chg != nil {
 		chg.taskCleanChanged()
@@ -250,6 +260,7 @@ func (t *Task) SetProgress(label string, done, total int) {
 	} else {
 		t.state.reading()
 	}
+	t.state.touchTask(t.id)
 	if total <= 0 || done > total {
 		// Doing math wrong is easy. Be conservative.
 		t.progress = nil
@@ -270,6 +281,24 @@ func (t *Task) ReadyTime() time.Time {
 	return t.readyTime
 }
 
+// IsQueued returns whether the task could run but is being held back by
+// its task runner, either because of its concurrency limits or because
+// it is blocked by other running tasks.
+func (t *Task) IsQueued() bool {
+	t.state.reading()
+	return t.queued
+}
+
+func (t *Task) setQueued(queued bool) {
+	t.state.reading()
+	if t.queued == queued {
+		return
+	}
+	t.state.writing()
+	t.state.touchTask(t.id)
+	t.queued = queued
+}
+
 // AtTime returns the time at which the task is scheduled to run. A zero time means no special schedule, i.e. run as soon as prerequisites are met.
 func (t *Task) AtTime() time.Time {
 	t.state.reading()
@@ -322,12 +351,14 @@ func (t *Task) Log() []string {
 // Logf logs information about the progress of the task.
 func (t *Task) Logf(format string, args ...interface{}) {
 	t.state.writing()
+	t.state.touchTask(t.id)
 	t.addLog(LogInfo, format, args)
 }
 
 // Errorf logs error information about the progress of the task.
 func (t *Task) Errorf(format string, args ...interface{}) {
 	t.state.writing()
+	t.state.touchTask(t.id)
 	t.addLog(LogError, format, args)
 }
 
@@ -335,6 +366,7 @@ func (t *Task) Errorf(format string, args ...interface{}) {
 // The provided value must properly marshal and unmarshal with encoding/json.
 func (t *Task) Set(key string, value interface{}) {
 	t.state.writing()
+	t.state.touchTask(t.id)
 	t.data.set(key, value)
 }
 
@@ -348,6 +380,7 @@ func (t *Task) Get(key string, value interface{}) error {
 // Clear disassociates the value from key.
 func (t *Task) Clear(key string) {
 	t.state.writing()
+	t.state.touchTask(t.id)
 	delete(t.data, key)
 }
 
@@ -365,6 +398,8 @@ func (t *Task) WaitFor(another *Task) {
 	t.state.writing()
 	t.waitTasks = addOnce(t.waitTasks, another.id)
 	another.haltTasks = addOnce(another.haltTasks, t.id)
+	t.state.touchTask(t.id)
+	t.state.touchTask(another.id)
 }
 
 // WaitAll registers all the tasks in the set as a requirement for t
@@ -400,12 +435,14 @@ func (t *Task) Lanes() []int {
 // abort independently on errors. See Change.AbortLane for details.
 func (t *Task) JoinLane(lane int) {
 	t.state.writing()
+	t.state.touchTask(t.id)
 	t.lanes = append(t.lanes, lane)
 }
 
 // At schedules the task, if it's not ready, to happen no earlier than when, if when is the zero time any previous special scheduling is suppressed.
 func (t *Task) At(when time.Time) {
 	t.state.writing()
+	t.state.touchTask(t.id)
 	iszero := when.IsZero()
 	if t.Status().Ready() && !iszero {
 		return
@@ -20,6 +20,8 @@
 package state
 
 import (
+	"sort"
+	"strconv"
 	"sync"
 	"time"
 
@@ -58,6 +60,10 @@ type TaskRunner struct {
 	blocked     func(t *Task, running []*Task) bool
 	someBlocked bool
 
+	// concurrency limits, 0 means no limit
+	maxRunning     int
+	maxRunningKind map[string]int
+
 	// go-routines lifecycle
 	tombs map[string]*tomb.Tomb
 }
@@ -74,10 +80,11 @@ type optionalHandler struct {
 // NewTaskRunner creates a new TaskRunner
 func NewTaskRunner(s *State) *TaskRunner {
 	return &TaskRunner{
-		state:    s,
-		handlers: make(map[string]handlerPair),
-		cleanups: make(map[string]HandlerFunc),
-		tombs:    make(map[string]*tomb.Tomb),
+		state:          s,
+		handlers:       make(map[string]handlerPair),
+		cleanups:       make(map[string]HandlerFunc),
+		tombs:          make(map[string]*tomb.Tomb),
+		maxRunningKind: make(map[string]int),
 	}
 }
 
@@ -146,6 +153,40 @@ func (r *TaskRunner) SetBlocked(pred func(t *Task, running []*Task) bool) {
 	r.blocked = pred
 }
 
+// SetMaxRunning sets the maximum number of tasks the runner runs
+// concurrently, 0 meaning no limit. Tasks over the limit are queued,
+// and run as the running ones finish, in order of priority of their
+// changes (see Change.SetPriority).
+func (r *TaskRunner) SetMaxRunning(max int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.maxRunning = max
+}
+
+// SetMaxRunningKind sets the maximum number of tasks of the given kind
+// the runner runs concurrently, 0 meaning no limit. See SetMaxRunning.
+func (r *TaskRunner) SetMaxRunningKind(kind string, max int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if max <= 0 {
+		delete(r.maxRunningKind, kind)
+		return
+	}
+	r.maxRunningKind[kind] = max
+}
+
+// overLimits returns whether running t would go over the concurrency
+// limits of the runner, given the counts of running tasks.
+func (r *TaskRunner) overLimits(t *Task, running int, runningKind map[string]int) bool {
+	if r.maxRunning > 0 && running >= r.maxRunning {
+		return true
+	}
+	max, ok := r.maxRunningKind[t.Kind()]
+	return ok && runningKind[t.Kind()] >= max
+}
+
 // run must be called with the state lock in place
 func (r *TaskRunner) run(t *Task) {
 	var handler HandlerFunc
@@ -331,21 +372,34 @@ func (r *TaskRunner) Ensure() {
 
 	r.someBlocked = false
 	running := make([]*Task, 0, len(r.tombs))
+	// counts of the running tasks, not including cleanups
+	nRunning := 0
+	runningKind := make(map[string]int)
 	for tid := range r.tombs {
 		t := r.state.Task(tid)
 		if t != nil {
 			running = append(running, t)
+			if !t.Status().Ready() {
+				nRunning++
+				runningKind[t.Kind()]++
+			}
 		}
 	}
 
 	ensureTime := timeNow()
 	nextTaskTime := time.Time{}
-	for _, t := range r.state.Tasks() {
+	// the tasks considered by this pass and the ones held back, the
+	// queued flags are updated once at the end not to flip them back
+	// and forth
+	var considered []*Task
+	queued := make(map[string]bool)
+	for _, t := range tasksByPriority(r.state.Tasks()) {
 		handlers := r.handlerPair(t)
 		if handlers.do == nil {
 			// Handled by a different runner instance.
 			continue
 		}
+		considered = append(considered, t)
 
 		tb := r.tombs[t.ID()]
 
@@ -395,8 +449,9 @@ func (r *TaskRunner) Ensure() {
 			continue
 		}
 
-		if r.blocked != nil && r.blocked(t, running) {
+		if r.blocked != nil && r.blocked(t, running) || r.overLimits(t, nRunning, runningKind) {
 			r.someBlocked = true
+			queued[t.ID()] = true
 			continue
 		}
 
@@ -404,6 +459,12 @@ func (r *TaskRunner) Ensure() {
 		r.run(t)
 
 		running = append(running, t)
+		nRunning++
+		runningKind[t.Kind()]++
+	}
+
+	for _, t := range considered {
+		t.setQueued(queued[t.ID()])
 	}
 
 	// schedule next Ensure no later than the next task time
@@ -412,6 +473,42 @@ func (r *TaskRunner) Ensure() {
 	}
 }
 
+type prioritizedTask struct {
+	task     *Task
+	priority Priority
+	id       int
+}
+
+type byPriority []prioritizedTask
+
+func (p byPriority) Len() int      { return len(p) }
+func (p byPriority) Swap(i, j int) { p[i], p[j] = p[j], p[i] }
+func (p byPriority) Less(i, j int) bool {
+	if p[i].priority != p[j].priority {
+		return p[i].priority > p[j].priority
+	}
+	return p[i].id < p[j].id
+}
+
+// tasksByPriority returns the tasks sorted by the priority of their
+// changes, and then in creation order.
+func tasksByPriority(tasks []*Task) []*Task {
+	prioritized := make([]prioritizedTask, len(tasks))
+	for i, t := range tasks {
+		prioritized[i].task = t
+		if chg := t.Change(); chg != nil {
+			prioritized[i].priority = chg.priority
+		}
+		// task ids are sequential numbers
+		prioritized[i].id, _ = strconv.Atoi(t.ID())
+	}
+	sort.Sort(byPriority(prioritized))
+	for i := range prioritized {
+		tasks[i] = prioritized[i].task
+	}
+	return tasks
+}
+
 // mustWait returns whether task t must wait for other tasks to be done.
 func mustWait(t *Task) bool {
 	switch t.Status() {
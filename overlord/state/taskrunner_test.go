@@ -20,6 +20,7 @@
 package state_test
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"sort"
@@ -622,6 +623,183 @@ func (ts *taskRunnerSuite) TestTaskSerialization(c *C) {
 	c.Check(ensureBeforeTick, HasLen, 0)
 }
 
+// blockingHandler returns a handler reporting the summaries of the
+// started tasks on started, and finishing them one at a time as told
+// by release.
+func blockingHandler(started chan<- string, release <-chan bool) state.HandlerFunc {
+	return func(t *state.Task, tb *tomb.Tomb) error {
+		st := t.State()
+		st.Lock()
+		summary := t.Summary()
+		st.Unlock()
+		started <- summary
+		select {
+		case <-release:
+		case <-tb.Dying():
+		}
+		return nil
+	}
+}
+
+// ensureStarted runs ensure passes until a task is started, as
+// finishing tasks make room for queued ones only once they're done.
+func ensureStarted(c *C, r *state.TaskRunner, started <-chan string) string {
+	for i := 0; i < 200; i++ {
+		r.Ensure()
+		select {
+		case summary := <-started:
+			return summary
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	c.Fatal("task wasn't started")
+	return ""
+}
+
+func (ts *taskRunnerSuite) TestMaxRunningKind(c *C) {
+	sb := &stateBackend{}
+	st := state.New(sb)
+	r := state.NewTaskRunner(st)
+	defer r.Stop()
+
+	started := make(chan string, 10)
+	releaseLimited := make(chan bool)
+	releaseOther := make(chan bool)
+	r.AddHandler("limited", blockingHandler(started, releaseLimited), nil)
+	r.AddHandler("other", blockingHandler(started, releaseOther), nil)
+	r.SetMaxRunningKind("limited", 2)
+
+	st.Lock()
+	chg := st.NewChange("install", "...")
+	var limited []*state.Task
+	for i := 0; i < 3; i++ {
+		t := st.NewTask("limited", fmt.Sprintf("limited%d", i))
+		chg.AddTask(t)
+		limited = append(limited, t)
+	}
+	chg.AddTask(st.NewTask("other", "other"))
+	st.Unlock()
+
+	var seen []string
+	for i := 0; i < 3; i++ {
+		seen = append(seen, ensureStarted(c, r, started))
+	}
+	sort.Strings(seen)
+	c.Check(seen, DeepEquals, []string{"limited0", "limited1", "other"})
+	c.Check(started, HasLen, 0)
+
+	st.Lock()
+	c.Check(limited[0].IsQueued(), Equals, false)
+	c.Check(limited[2].IsQueued(), Equals, true)
+	c.Check(limited[2].Status(), Equals, state.DoStatus)
+	st.Unlock()
+
+	// nothing new runs while the limit is reached
+	r.Ensure()
+	c.Check(started, HasLen, 0)
+
+	// finishing an unrelated task doesn't
+	releaseOther <- true
+	r.Ensure()
+	c.Check(started, HasLen, 0)
+
+	// finishing a limited task makes room for the queued one
+	releaseLimited <- true
+	c.Check(ensureStarted(c, r, started), Equals, "limited2")
+
+	st.Lock()
+	c.Check(limited[2].IsQueued(), Equals, false)
+	st.Unlock()
+}
+
+func (ts *taskRunnerSuite) TestQueuedIsPersistedAndWatched(c *C) {
+	sb := &stateBackend{}
+	st := state.New(sb)
+	r := state.NewTaskRunner(st)
+	defer r.Stop()
+
+	started := make(chan string, 10)
+	release := make(chan bool)
+	r.AddHandler("do", blockingHandler(started, release), nil)
+	r.SetMaxRunning(1)
+
+	st.Lock()
+	chg := st.NewChange("install", "...")
+	t1 := st.NewTask("do", "t1")
+	chg.AddTask(t1)
+	t2 := st.NewTask("do", "t2")
+	chg.AddTask(t2)
+	modified, stop := chg.Watch()
+	st.Unlock()
+	defer stop()
+
+	c.Check(ensureStarted(c, r, started), Equals, "t1")
+	select {
+	case <-modified:
+	case <-time.After(2 * time.Second):
+		c.Fatal("change not reported as modified")
+	}
+
+	st.Lock()
+	c.Check(t2.IsQueued(), Equals, true)
+	data, err := json.Marshal(t2)
+	st.Unlock()
+	c.Assert(err, IsNil)
+	c.Check(strings.Contains(string(data), `"queued":true`), Equals, true)
+
+	// still queued, nothing to report
+	r.Ensure()
+	select {
+	case <-modified:
+		c.Fatal("change reported as modified")
+	default:
+	}
+
+	release <- true
+	c.Check(ensureStarted(c, r, started), Equals, "t2")
+	select {
+	case <-modified:
+	case <-time.After(2 * time.Second):
+		c.Fatal("change not reported as modified")
+	}
+
+	st.Lock()
+	c.Check(t2.IsQueued(), Equals, false)
+	st.Unlock()
+}
+
+func (ts *taskRunnerSuite) TestMaxRunningAndPriority(c *C) {
+	sb := &stateBackend{}
+	st := state.New(sb)
+	r := state.NewTaskRunner(st)
+	defer r.Stop()
+
+	started := make(chan string, 10)
+	release := make(chan bool)
+	r.AddHandler("do", blockingHandler(started, release), nil)
+	r.SetMaxRunning(1)
+
+	st.Lock()
+	lowChg := st.NewChange("auto-refresh", "...")
+	lowChg.SetPriority(state.LowPriority)
+	lowChg.AddTask(st.NewTask("do", "low"))
+	chg := st.NewChange("install", "...")
+	chg.AddTask(st.NewTask("do", "default1"))
+	chg.AddTask(st.NewTask("do", "default2"))
+	st.Unlock()
+
+	// the tasks of the default priority change run first, in order
+	c.Check(ensureStarted(c, r, started), Equals, "default1")
+	r.Ensure()
+	c.Check(started, HasLen, 0)
+
+	release <- true
+	c.Check(ensureStarted(c, r, started), Equals, "default2")
+
+	release <- true
+	c.Check(ensureStarted(c, r, started), Equals, "low")
+}
+
 func (ts *taskRunnerSuite) TestPrematureChangeReady(c *C) {
 	sb := &stateBackend{}
 	st := state.New(sb)
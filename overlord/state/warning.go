@@ -0,0 +1,290 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/snapcore/snapd/logger"
+)
+
+var (
+	// DefaultExpireAfter is how long a warning is kept around after it
+	// was last added.
+	DefaultExpireAfter = time.Hour * 24 * 28
+	// DefaultRepeatAfter is how long after being shown a warning is
+	// not shown again, even if it is added again.
+	DefaultRepeatAfter = time.Hour * 24
+
+	errNoWarningMessage     = errors.New("warning has no message")
+	errNoWarningFirstAdded  = errors.New("warning has no first-added timestamp")
+	errNoWarningExpireAfter = errors.New("warning has no expire-after duration")
+	errNoWarningRepeatAfter = errors.New("warning has no repeat-after duration")
+)
+
+type jsonWarning struct {
+	Message     string        `json:"message"`
+	FirstAdded  time.Time     `json:"first-added"`
+	LastAdded   time.Time     `json:"last-added"`
+	LastShown   *time.Time    `json:"last-shown,omitempty"`
+	ExpireAfter time.Duration `json:"expire-after,omitempty"`
+	RepeatAfter time.Duration `json:"repeat-after,omitempty"`
+}
+
+// A Warning is a message that snapd wants the user to see. Warnings
+// with the same message are coalesced: adding a warning that already
+// exists only bumps its last-added timestamp.
+//
+// A warning is shown until it is acknowledged, after which it is not
+// shown again unless it is added again, and only once its repeat-after
+// duration has passed since it was last shown. A warning that was not
+// added for longer than its expire-after duration is dropped.
+type Warning struct {
+	// the warning text itself. Only one of these in the system at a time.
+	message string
+	// the first time one of these messages was created
+	firstAdded time.Time
+	// the last time one of these was created
+	lastAdded time.Time
+	// the last time one of these was shown to the user
+	lastShown time.Time
+	// how much time since one of these was last added should we drop the message
+	expireAfter time.Duration
+	// how much time since one of these was last shown should we repeat it
+	repeatAfter time.Duration
+}
+
+func (w *Warning) String() string {
+	return w.message
+}
+
+func (w *Warning) MarshalJSON() ([]byte, error) {
+	jw := jsonWarning{
+		Message:     w.message,
+		FirstAdded:  w.firstAdded,
+		LastAdded:   w.lastAdded,
+		ExpireAfter: w.expireAfter,
+		RepeatAfter: w.repeatAfter,
+	}
+	if !w.lastShown.IsZero() {
+		jw.LastShown = &w.lastShown
+	}
+
+	return json.Marshal(jw)
+}
+
+func (w *Warning) UnmarshalJSON(data []byte) error {
+	var jw jsonWarning
+	err := json.Unmarshal(data, &jw)
+	if err != nil {
+		return err
+	}
+	w.message = jw.Message
+	w.firstAdded = jw.FirstAdded
+	w.lastAdded = jw.LastAdded
+	if jw.LastShown != nil {
+		w.lastShown = *jw.LastShown
+	}
+	w.expireAfter = jw.ExpireAfter
+	w.repeatAfter = jw.RepeatAfter
+
+	return w.validate()
+}
+
+func (w *Warning) validate() error {
+	if w.message == "" {
+		return errNoWarningMessage
+	}
+	if w.firstAdded.IsZero() {
+		return errNoWarningFirstAdded
+	}
+	if w.expireAfter == 0 {
+		return errNoWarningExpireAfter
+	}
+	if w.repeatAfter == 0 {
+		return errNoWarningRepeatAfter
+	}
+	return nil
+}
+
+// ExpiredBefore returns whether the warning was last added longer than
+// its expire-after duration before the given time.
+func (w *Warning) ExpiredBefore(now time.Time) bool {
+	return w.lastAdded.Add(w.expireAfter).Before(now)
+}
+
+// pendingAt returns whether the warning should be shown to the user at
+// the given time.
+func (w *Warning) pendingAt(now time.Time) bool {
+	if w.lastShown.IsZero() {
+		return true
+	}
+	if !w.lastAdded.After(w.lastShown) {
+		// not added again since it was last shown
+		return false
+	}
+	return !now.Before(w.lastShown.Add(w.repeatAfter))
+}
+
+// Warnf records a warning: if it's the first time the warning is
+// added, it is created; otherwise, its last-added timestamp is updated.
+func (s *State) Warnf(template string, args ...interface{}) {
+	var message string
+	if len(args) > 0 {
+		message = fmt.Sprintf(template, args...)
+	} else {
+		message = template
+	}
+	s.addWarning(message, time.Now().UTC())
+}
+
+func (s *State) addWarning(message string, now time.Time) {
+	s.writing()
+
+	if s.warnings[message] == nil || s.warnings[message].ExpiredBefore(now) {
+		s.warnings[message] = &Warning{
+			message:     message,
+			firstAdded:  now,
+			expireAfter: DefaultExpireAfter,
+			repeatAfter: DefaultRepeatAfter,
+		}
+		logger.Noticef("WARNING: %s", message)
+	}
+	s.warnings[message].lastAdded = now
+	s.touchWarning(message)
+}
+
+type byLastAdded []*Warning
+
+func (a byLastAdded) Len() int           { return len(a) }
+func (a byLastAdded) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byLastAdded) Less(i, j int) bool { return a[i].lastAdded.Before(a[j].lastAdded) }
+
+// AllWarnings returns all the warnings in the system that have not
+// expired, whether they're shown or not, sorted by last-added.
+func (s *State) AllWarnings() []*Warning {
+	s.reading()
+
+	now := time.Now().UTC()
+	all := make([]*Warning, 0, len(s.warnings))
+	for _, w := range s.warnings {
+		if w.ExpiredBefore(now) {
+			continue
+		}
+		all = append(all, w)
+	}
+	sort.Sort(byLastAdded(all))
+
+	return all
+}
+
+// PendingWarnings returns the list of warnings to show the user, sorted
+// by last-added, along with the timestamp of the latest of them. The
+// timestamp is meant to be handed back to OkayWarnings once the user
+// has seen the warnings.
+func (s *State) PendingWarnings() ([]*Warning, time.Time) {
+	s.reading()
+
+	var toShow []*Warning
+	var last time.Time
+	now := time.Now().UTC()
+	for _, w := range s.warnings {
+		if w.ExpiredBefore(now) || !w.pendingAt(now) {
+			continue
+		}
+		toShow = append(toShow, w)
+		if w.lastAdded.After(last) {
+			last = w.lastAdded
+		}
+	}
+	sort.Sort(byLastAdded(toShow))
+
+	return toShow, last
+}
+
+// WarningsSummary returns the number of warnings that are pending,
+// and the timestamp of the latest of them.
+func (s *State) WarningsSummary() (int, time.Time) {
+	s.reading()
+
+	var n int
+	var last time.Time
+	now := time.Now().UTC()
+	for _, w := range s.warnings {
+		if w.ExpiredBefore(now) || !w.pendingAt(now) {
+			continue
+		}
+		n++
+		if w.lastAdded.After(last) {
+			last = w.lastAdded
+		}
+	}
+
+	return n, last
+}
+
+// OkayWarnings marks as shown the pending warnings that were added no
+// later than the given time, and returns how many there were.
+func (s *State) OkayWarnings(t time.Time) int {
+	s.writing()
+
+	var n int
+	now := time.Now().UTC()
+	for _, w := range s.warnings {
+		if w.lastAdded.After(t) || !w.pendingAt(now) {
+			continue
+		}
+		w.lastShown = now
+		s.touchWarning(w.message)
+		n++
+	}
+
+	return n
+}
+
+func (s *State) flattenWarnings() []*Warning {
+	flat := make([]*Warning, 0, len(s.warnings))
+	for _, w := range s.warnings {
+		flat = append(flat, w)
+	}
+	return flat
+}
+
+func (s *State) unflattenWarnings(flat []*Warning) {
+	s.warnings = make(map[string]*Warning, len(flat))
+	for _, w := range flat {
+		s.warnings[w.message] = w
+	}
+}
+
+// pruneWarnings removes the warnings that have expired.
+func (s *State) pruneWarnings(now time.Time) {
+	for k, w := range s.warnings {
+		if w.ExpiredBefore(now) {
+			s.writing()
+			delete(s.warnings, k)
+			s.touchWarning(k)
+		}
+	}
+}
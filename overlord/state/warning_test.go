@@ -0,0 +1,171 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package state_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/overlord/state"
+)
+
+type warningSuite struct{}
+
+var _ = Suite(&warningSuite{})
+
+func (warningSuite) TestWarnfCoalesces(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	st.Warnf("hello %s", "world")
+	st.Warnf("hello %s", "world")
+	st.Warnf("hello %s", "there")
+
+	all := st.AllWarnings()
+	c.Assert(all, HasLen, 2)
+	c.Check(all[0].String(), Equals, "hello world")
+	c.Check(all[1].String(), Equals, "hello there")
+}
+
+func (warningSuite) TestWarnfWithoutArgs(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	st.Warnf("100%% sure")
+	// a message without arguments is used verbatim
+	msg := "50%"
+	st.Warnf(msg)
+
+	all := st.AllWarnings()
+	c.Assert(all, HasLen, 2)
+	c.Check(all[0].String(), Equals, "100%% sure")
+	c.Check(all[1].String(), Equals, "50%")
+}
+
+func (warningSuite) TestMarshalUnmarshal(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	st.Warnf("hello")
+	data, err := json.Marshal(st)
+	st.Unlock()
+	c.Assert(err, IsNil)
+
+	st2, err := state.ReadState(nil, bytes.NewReader(data))
+	c.Assert(err, IsNil)
+	st2.Lock()
+	defer st2.Unlock()
+	all := st2.AllWarnings()
+	c.Assert(all, HasLen, 1)
+	c.Check(all[0].String(), Equals, "hello")
+
+	var w state.Warning
+	c.Check(json.Unmarshal([]byte(`{"message": "x"}`), &w), ErrorMatches, "warning has no first-added timestamp")
+	c.Check(json.Unmarshal([]byte(`{"first-added": "2018-01-01T00:00:00Z"}`), &w), ErrorMatches, "warning has no message")
+	c.Check(json.Unmarshal([]byte(`{"message": "x", "first-added": "2018-01-01T00:00:00Z"}`), &w), ErrorMatches, "warning has no expire-after duration")
+	c.Check(json.Unmarshal([]byte(`{"message": "x", "first-added": "2018-01-01T00:00:00Z", "expire-after": 1}`), &w), ErrorMatches, "warning has no repeat-after duration")
+}
+
+func (warningSuite) TestPendingAndOkay(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	st.Warnf("one")
+	st.Warnf("two")
+
+	pending, last := st.PendingWarnings()
+	c.Assert(pending, HasLen, 2)
+	n, stamp := st.WarningsSummary()
+	c.Check(n, Equals, 2)
+	c.Check(stamp, Equals, last)
+
+	// a warning added after the user looked is not okayed
+	time.Sleep(time.Millisecond)
+	st.Warnf("three")
+
+	c.Check(st.OkayWarnings(last), Equals, 2)
+	pending, _ = st.PendingWarnings()
+	c.Assert(pending, HasLen, 1)
+	c.Check(pending[0].String(), Equals, "three")
+
+	// okayed warnings are still listed among all of them
+	c.Check(st.AllWarnings(), HasLen, 3)
+}
+
+func (warningSuite) TestRepeatAfter(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	now := time.Now().UTC()
+	st.AddWarning("hello", now)
+	w := st.AllWarnings()[0]
+
+	// shown an hour ago, not added since
+	state.MockWarningTimes(w, now.Add(-2*time.Hour), now.Add(-2*time.Hour), now.Add(-time.Hour))
+	pending, _ := st.PendingWarnings()
+	c.Check(pending, HasLen, 0)
+
+	// added again after being shown, but within repeat-after
+	st.AddWarning("hello", now)
+	pending, _ = st.PendingWarnings()
+	c.Check(pending, HasLen, 0)
+
+	// added again after being shown, and repeat-after passed
+	state.MockWarningTimes(w, now.Add(-48*time.Hour), now, now.Add(-state.DefaultRepeatAfter-time.Minute))
+	pending, _ = st.PendingWarnings()
+	c.Check(pending, HasLen, 1)
+	n, _ := st.WarningsSummary()
+	c.Check(n, Equals, 1)
+}
+
+func (warningSuite) TestExpireAfter(c *C) {
+	st := state.New(nil)
+	st.Lock()
+	defer st.Unlock()
+
+	old := time.Now().UTC().Add(-state.DefaultExpireAfter - time.Hour)
+	st.AddWarning("old", old)
+	st.Warnf("new")
+
+	all := st.AllWarnings()
+	c.Assert(all, HasLen, 1)
+	c.Check(all[0].String(), Equals, "new")
+	pending, _ := st.PendingWarnings()
+	c.Check(pending, HasLen, 1)
+
+	// adding an expired warning again starts it afresh
+	st.AddWarning("old", old)
+	st.Warnf("old")
+	all = st.AllWarnings()
+	c.Assert(all, HasLen, 2)
+
+	// expired warnings are pruned
+	st.AddWarning("older", old)
+	st.Prune(time.Hour, time.Hour, 100)
+	data, err := json.Marshal(st)
+	c.Assert(err, IsNil)
+	c.Check(string(data), Not(Matches), ".*older.*")
+}
@@ -207,6 +207,7 @@ mv %{buildroot}%{_bindir}/snap-seccomp %{buildroot}%{_libexecdir}/snapd/snap-sec
 # Install all systemd and dbus units, and env files
 %make_install -C data BINDIR=%{_bindir} LIBEXECDIR=%{_libexecdir} \
                       SYSTEMDSYSTEMUNITDIR=%{_unitdir} \
+                      SYSTEMDUSERUNITDIR=%{_userunitdir} \
                       SNAP_MOUNT_DIR=%{snap_mount_dir}
 
 # Generate and install man page for snap command
@@ -351,6 +352,8 @@ fi
 %{_mandir}/man1/snap.1.*
 %{_datadir}/dbus-1/services/io.snapcraft.Launcher.service
 %{_datadir}/dbus-1/services/io.snapcraft.Settings.service
+%{_userunitdir}/snapd.session-agent.service
+%{_userunitdir}/snapd.session-agent.socket
 %{_sysconfdir}/xdg/autostart/snap-userd-autostart.desktop
 %{_libexecdir}/snapd/snapd.run-from-snap
 
@@ -77,7 +77,8 @@ type PlaceInfo interface {
 
 // MinimalPlaceInfo returns a PlaceInfo with just the location information for a snap of the given name and revision.
 func MinimalPlaceInfo(name string, revision Revision) PlaceInfo {
-	return &Info{SideInfo: SideInfo{RealName: name, Revision: revision}}
+	snapName, instanceKey := SplitInstanceName(name)
+	return &Info{SideInfo: SideInfo{RealName: snapName, Revision: revision}, InstanceKey: instanceKey}
 }
 
 // MountDir returns the base directory where it gets mounted of the snap with the given name and revision.
@@ -173,6 +174,10 @@ type Info struct {
 	// The information in all the remaining fields is not sourced from the snap blob itself.
 	SideInfo
 
+	// InstanceKey is set by the user during installation and differs for
+	// each instance of given snap
+	InstanceKey string
+
 	// Broken marks whether the snap is broken and the reason.
 	Broken string
 
@@ -253,8 +258,15 @@ type ChannelSnapInfo struct {
 	Size        int64           `json:"size"`
 }
 
-// Name returns the blessed name for the snap.
+// Name returns the blessed name for the snap, i.e. the name of this
+// instance of it, including the instance key if it has one.
 func (s *Info) Name() string {
+	return InstanceName(s.SnapName(), s.InstanceKey)
+}
+
+// SnapName returns the global blessed name of the snap, as known by the
+// store, without any instance key.
+func (s *Info) SnapName() string {
 	if s.RealName != "" {
 		return s.RealName
 	}
@@ -295,6 +307,17 @@ func (s *Info) MountFile() string {
 	return MountFile(s.Name(), s.Revision)
 }
 
+// DesktopPrefix returns the prefix used for the desktop files installed
+// for the snap. Parallel instances use a '+' to separate the snap name
+// from the instance key, so that the desktop files of the instances do
+// not clash with those of the snap.
+func (s *Info) DesktopPrefix() string {
+	if s.InstanceKey == "" {
+		return s.SnapName()
+	}
+	return s.SnapName() + "+" + s.InstanceKey
+}
+
 // HooksDir returns the directory containing the snap's hooks.
 func (s *Info) HooksDir() string {
 	return filepath.Join(s.MountDir(), "meta", "hooks")
@@ -572,6 +595,12 @@ type SlotInfo struct {
 	Label     string
 	Apps      map[string]*AppInfo
 	Hooks     map[string]*HookInfo
+
+	// HotplugKey is a unique key built by the slot's interface
+	// using properties of a hotplugged device so that the same
+	// slot may be made available if the device is reinserted.
+	// It's empty for regular slots.
+	HotplugKey string
 }
 
 // SocketInfo provides information on application sockets.
@@ -590,6 +619,26 @@ type TimerInfo struct {
 	Timer string
 }
 
+// DaemonScope is the type for the "daemon-scope:" of a snap app
+type DaemonScope string
+
+const (
+	// SystemDaemon is a daemon managed by the system instance of systemd
+	SystemDaemon DaemonScope = "system"
+	// UserDaemon is a daemon managed by the systemd instance of each
+	// user session
+	UserDaemon DaemonScope = "user"
+)
+
+// Validate checks that the daemon scope is valid.
+func (ds DaemonScope) Validate() error {
+	switch ds {
+	case SystemDaemon, UserDaemon:
+		return nil
+	}
+	return fmt.Errorf(`"daemon-scope" field contains invalid value %q`, ds)
+}
+
 // StopModeType is the type for the "stop-mode:" of a snap app
 type StopModeType string
 
@@ -624,9 +673,11 @@ type AppInfo struct {
 	Name          string
 	LegacyAliases []string // FIXME: eventually drop this
 	Command       string
+	CommandChain  []string
 	CommonID      string
 
 	Daemon          string
+	DaemonScope     DaemonScope
 	StopTimeout     timeout.Timeout
 	WatchdogTimeout timeout.Timeout
 	StopCommand     string
@@ -636,6 +687,7 @@ type AppInfo struct {
 	Completer       string
 	RefreshMode     string
 	StopMode        StopModeType
+	InstallMode     string
 
 	// TODO: this should go away once we have more plumbing and can change
 	// things vs refactor
@@ -672,16 +724,18 @@ type HookInfo struct {
 	Name  string
 	Plugs map[string]*PlugInfo
 	Slots map[string]*SlotInfo
+
+	CommandChain []string
 }
 
 // File returns the path to the *.socket file
 func (socket *SocketInfo) File() string {
-	return filepath.Join(dirs.SnapServicesDir, socket.App.SecurityTag()+"."+socket.Name+".socket")
+	return filepath.Join(socket.App.serviceDir(), socket.App.SecurityTag()+"."+socket.Name+".socket")
 }
 
 // File returns the path to the *.timer file
 func (timer *TimerInfo) File() string {
-	return filepath.Join(dirs.SnapServicesDir, timer.App.SecurityTag()+".timer")
+	return filepath.Join(timer.App.serviceDir(), timer.App.SecurityTag()+".timer")
 }
 
 func (app *AppInfo) String() string {
@@ -715,10 +769,7 @@ func (app *AppInfo) launcherCommand(command string) string {
 	if command != "" {
 		command = " " + command
 	}
-	if app.Name == app.Snap.Name() {
-		return fmt.Sprintf("/usr/bin/snap run%s %s", command, app.Name)
-	}
-	return fmt.Sprintf("/usr/bin/snap run%s %s.%s", command, app.Snap.Name(), app.Name)
+	return fmt.Sprintf("/usr/bin/snap run%s %s", command, JoinSnapApp(app.Snap.Name(), app.Name))
 }
 
 // LauncherCommand returns the launcher command line to use when invoking the app binary.
@@ -749,9 +800,16 @@ func (app *AppInfo) ServiceName() string {
 	return app.SecurityTag() + ".service"
 }
 
+func (app *AppInfo) serviceDir() string {
+	if app.DaemonScope == UserDaemon {
+		return dirs.SnapUserServicesDir
+	}
+	return dirs.SnapServicesDir
+}
+
 // ServiceFile returns the systemd service file path for the daemon app.
 func (app *AppInfo) ServiceFile() string {
-	return filepath.Join(dirs.SnapServicesDir, app.ServiceName())
+	return filepath.Join(app.serviceDir(), app.ServiceName())
 }
 
 // Env returns the app specific environment overrides
@@ -769,6 +827,12 @@ func (app *AppInfo) IsService() bool {
 	return app.Daemon != ""
 }
 
+// IsUserService returns whether app represents a daemon/service that
+// runs in the user sessions.
+func (app *AppInfo) IsUserService() bool {
+	return app.IsService() && app.DaemonScope == UserDaemon
+}
+
 // SecurityTag returns the hook-specific security tag.
 //
 // Security tags are used by various security subsystems as "profile names" and
@@ -869,6 +933,8 @@ func ReadInfo(name string, si *SideInfo) (*Info, error) {
 		return nil, &invalidMetaError{Snap: name, Revision: si.Revision, Msg: err.Error()}
 	}
 
+	_, info.InstanceKey = SplitInstanceName(name)
+
 	mountFile := MountFile(name, si.Revision)
 	st, err := os.Stat(mountFile)
 	if os.IsNotExist(err) {
@@ -952,25 +1018,53 @@ func InstallDate(name string) time.Time {
 
 // SplitSnapApp will split a string of the form `snap.app` into
 // the `snap` and the `app` part. It also deals with the special
-// case of snapName == appName.
+// case of snapName == appName, including for snap instances.
 func SplitSnapApp(snapApp string) (snap, app string) {
 	l := strings.SplitN(snapApp, ".", 2)
 	if len(l) < 2 {
-		return l[0], l[0]
+		return l[0], InstanceSnap(l[0])
 	}
 	return l[0], l[1]
 }
 
 // JoinSnapApp produces a full application wrapper name from the
 // `snap` and the `app` part. It also deals with the special
-// case of snapName == appName.
+// case of snapName == appName, including for snap instances.
 func JoinSnapApp(snap, app string) string {
-	if snap == app {
-		return app
+	snapName, instanceKey := SplitInstanceName(snap)
+	if snapName == app {
+		return InstanceName(app, instanceKey)
 	}
 	return fmt.Sprintf("%s.%s", snap, app)
 }
 
+// InstanceName produces the name of an instance of a snap from the snap
+// name and the instance key. An empty instance key gives the snap name.
+func InstanceName(snapName, instanceKey string) string {
+	if instanceKey != "" {
+		return fmt.Sprintf("%s_%s", snapName, instanceKey)
+	}
+	return snapName
+}
+
+// SplitInstanceName splits the name of an instance of a snap into the
+// snap name and the instance key. The instance key is empty if the name
+// has none.
+func SplitInstanceName(instanceName string) (snapName, instanceKey string) {
+	split := strings.SplitN(instanceName, "_", 2)
+	snapName = split[0]
+	if len(split) > 1 {
+		instanceKey = split[1]
+	}
+	return snapName, instanceKey
+}
+
+// InstanceSnap returns the snap name of the given instance name.
+func InstanceSnap(instanceName string) string {
+	snapName, _ := SplitInstanceName(instanceName)
+	return snapName
+}
+
 // UseNick returns the nickname for given snap name. If there is none, returns
 // the original name.
 func UseNick(snapName string) string {
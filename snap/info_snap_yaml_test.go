@@ -901,6 +901,25 @@ hooks:
 	})
 }
 
+func (s *YamlSuite) TestUnmarshalCommandChain(c *C) {
+	// NOTE: yaml content cannot use tabs, indent the section with spaces.
+	info, err := snap.InfoFromSnapYaml([]byte(`
+name: snap
+apps:
+    app:
+        command: foo
+        command-chain: [chain1, chain2]
+hooks:
+    test-hook:
+        command-chain: [chain3]
+`))
+	c.Assert(err, IsNil)
+	c.Assert(info.Apps, HasLen, 1)
+	c.Check(info.Apps["app"].CommandChain, DeepEquals, []string{"chain1", "chain2"})
+	c.Assert(info.Hooks, HasLen, 1)
+	c.Check(info.Hooks["test-hook"].CommandChain, DeepEquals, []string{"chain3"})
+}
+
 func (s *YamlSuite) TestUnmarshalUnsupportedHook(c *C) {
 	s.restore()
 	hookType := snap.NewHookType(regexp.MustCompile("not-test-hook"))
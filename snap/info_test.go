@@ -507,6 +507,9 @@ func (s *infoSuite) TestSplitSnapApp(c *C) {
 		{"foo.bar.baz", []string{"foo", "bar.baz"}},
 		// special case, snapName == appName
 		{"foo", []string{"foo", "foo"}},
+		// snap instances
+		{"foo_bar.baz", []string{"foo_bar", "baz"}},
+		{"foo_bar", []string{"foo_bar", "foo"}},
 	} {
 		snap, app := snap.SplitSnapApp(t.in)
 		c.Check([]string{snap, app}, DeepEquals, t.out)
@@ -523,12 +526,76 @@ func (s *infoSuite) TestJoinSnapApp(c *C) {
 		{[]string{"foo", "bar-baz"}, "foo.bar-baz"},
 		// special case, snapName == appName
 		{[]string{"foo", "foo"}, "foo"},
+		// snap instances
+		{[]string{"foo_bar", "baz"}, "foo_bar.baz"},
+		{[]string{"foo_bar", "foo"}, "foo_bar"},
 	} {
 		snapApp := snap.JoinSnapApp(t.in[0], t.in[1])
 		c.Check(snapApp, Equals, t.out)
 	}
 }
 
+func (s *infoSuite) TestInstanceName(c *C) {
+	c.Check(snap.InstanceName("foo", ""), Equals, "foo")
+	c.Check(snap.InstanceName("foo", "bar"), Equals, "foo_bar")
+
+	for _, t := range []struct {
+		in          string
+		snapName    string
+		instanceKey string
+	}{
+		{"foo", "foo", ""},
+		{"foo_bar", "foo", "bar"},
+		{"foo_bar_baz", "foo", "bar_baz"},
+		{"foo_", "foo", ""},
+	} {
+		snapName, instanceKey := snap.SplitInstanceName(t.in)
+		c.Check(snapName, Equals, t.snapName, Commentf(t.in))
+		c.Check(instanceKey, Equals, t.instanceKey, Commentf(t.in))
+		c.Check(snap.InstanceSnap(t.in), Equals, t.snapName, Commentf(t.in))
+	}
+}
+
+func (s *infoSuite) TestInfoInstanceKey(c *C) {
+	info := &snap.Info{SideInfo: snap.SideInfo{RealName: "foo", Revision: snap.R(1)}, InstanceKey: "bar"}
+	info.Apps = map[string]*snap.AppInfo{
+		"foo": {Snap: info, Name: "foo"},
+		"app": {Snap: info, Name: "app"},
+	}
+
+	c.Check(info.SnapName(), Equals, "foo")
+	c.Check(info.Name(), Equals, "foo_bar")
+	c.Check(info.MountDir(), Equals, fmt.Sprintf("%s/foo_bar/1", dirs.SnapMountDir))
+	c.Check(info.DataDir(), Equals, filepath.Join(dirs.SnapDataDir, "foo_bar/1"))
+	c.Check(info.Apps["foo"].String(), Equals, "foo_bar")
+	c.Check(info.Apps["app"].String(), Equals, "foo_bar.app")
+	c.Check(info.Apps["app"].SecurityTag(), Equals, "snap.foo_bar.app")
+	c.Check(info.Apps["foo"].LauncherCommand(), Equals, "/usr/bin/snap run foo_bar")
+	c.Check(info.Apps["app"].LauncherCommand(), Equals, "/usr/bin/snap run foo_bar.app")
+	c.Check(info.DesktopPrefix(), Equals, "foo+bar")
+
+	info.InstanceKey = ""
+	c.Check(info.DesktopPrefix(), Equals, "foo")
+}
+
+func (s *infoSuite) TestMinimalPlaceInfoInstance(c *C) {
+	info := snap.MinimalPlaceInfo("foo_bar", snap.R(1))
+	c.Check(info.Name(), Equals, "foo_bar")
+	c.Check(info.MountDir(), Equals, fmt.Sprintf("%s/foo_bar/1", dirs.SnapMountDir))
+	c.Check(info.MountFile(), Equals, filepath.Join(dirs.SnapBlobDir, "foo_bar_1.snap"))
+}
+
+func (s *infoSuite) TestReadInfoInstance(c *C) {
+	si := &snap.SideInfo{Revision: snap.R(42), RealName: "sample"}
+	snaptest.MockSnapInstance(c, "sample_instance", sampleYaml, si)
+
+	info, err := snap.ReadInfo("sample_instance", si)
+	c.Assert(err, IsNil)
+	c.Check(info.SnapName(), Equals, "sample")
+	c.Check(info.InstanceKey, Equals, "instance")
+	c.Check(info.Name(), Equals, "sample_instance")
+}
+
 func ExampleSplitSnapApp() {
 	fmt.Println(snap.SplitSnapApp("hello-world.env"))
 	// Output: hello-world env
@@ -826,6 +893,36 @@ apps:
 	c.Check(info.Apps["app1"].IsService(), Equals, false)
 }
 
+func (s *infoSuite) TestAppInfoIsUserService(c *C) {
+	info, err := snap.InfoFromSnapYaml([]byte(`name: pans
+apps:
+  svc1:
+    daemon: simple
+    daemon-scope: user
+    timer: 10:00-12:00
+  svc2:
+    daemon: simple
+    daemon-scope: system
+  svc3:
+    daemon: simple
+  app1:
+`))
+	c.Assert(err, IsNil)
+
+	svc := info.Apps["svc1"]
+	c.Check(svc.DaemonScope, Equals, snap.UserDaemon)
+	c.Check(svc.IsService(), Equals, true)
+	c.Check(svc.IsUserService(), Equals, true)
+	c.Check(svc.ServiceName(), Equals, "snap.pans.svc1.service")
+	c.Check(svc.ServiceFile(), Equals, dirs.GlobalRootDir+"/etc/systemd/user/snap.pans.svc1.service")
+	c.Check(svc.Timer.File(), Equals, dirs.GlobalRootDir+"/etc/systemd/user/snap.pans.svc1.timer")
+
+	c.Check(info.Apps["svc2"].IsUserService(), Equals, false)
+	c.Check(info.Apps["svc2"].ServiceFile(), Equals, dirs.GlobalRootDir+"/etc/systemd/system/snap.pans.svc2.service")
+	c.Check(info.Apps["svc3"].IsUserService(), Equals, false)
+	c.Check(info.Apps["app1"].IsUserService(), Equals, false)
+}
+
 func (s *infoSuite) TestAppInfoStringer(c *C) {
 	info, err := snap.InfoFromSnapYaml([]byte(`name: asnap
 apps:
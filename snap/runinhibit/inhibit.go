@@ -0,0 +1,84 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package runinhibit contains helpers for preventing apps of a snap
+// from starting while snapd is changing the snap, for example in the
+// window between unlinking the old revision and linking the new one
+// during a refresh.
+package runinhibit
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+)
+
+// Hint describes why running apps of a snap is inhibited.
+type Hint string
+
+const (
+	// HintNotInhibited is the hint returned for snaps that can run.
+	HintNotInhibited Hint = ""
+	// HintInhibitedForRefresh is used while the snap is being refreshed.
+	HintInhibitedForRefresh Hint = "refresh"
+)
+
+func inhibitFile(snapName string) string {
+	return filepath.Join(dirs.SnapRunInhibitDir, snapName+".lock")
+}
+
+// LockWithHint inhibits running apps of the given snap, recording
+// the given hint as the reason.
+func LockWithHint(snapName string, hint Hint) error {
+	if hint == HintNotInhibited {
+		return fmt.Errorf("internal error: cannot inhibit running snap %q without a hint", snapName)
+	}
+	if err := os.MkdirAll(dirs.SnapRunInhibitDir, 0755); err != nil {
+		return err
+	}
+	return osutil.AtomicWriteFile(inhibitFile(snapName), []byte(hint), 0644, 0)
+}
+
+// Unlock lifts the inhibition of running apps of the given snap. It
+// is not an error if the snap was not inhibited.
+func Unlock(snapName string) error {
+	err := os.Remove(inhibitFile(snapName))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// IsLocked returns the hint recorded for the given snap, or
+// HintNotInhibited if running its apps is not inhibited.
+func IsLocked(snapName string) (Hint, error) {
+	content, err := ioutil.ReadFile(inhibitFile(snapName))
+	if os.IsNotExist(err) {
+		return HintNotInhibited, nil
+	}
+	if err != nil {
+		return HintNotInhibited, err
+	}
+	return Hint(strings.TrimSpace(string(content))), nil
+}
@@ -0,0 +1,88 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package runinhibit_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap/runinhibit"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type runInhibitSuite struct{}
+
+var _ = Suite(&runInhibitSuite{})
+
+func (s *runInhibitSuite) SetUpTest(c *C) {
+	dirs.SetRootDir(c.MkDir())
+}
+
+func (s *runInhibitSuite) TearDownTest(c *C) {
+	dirs.SetRootDir("")
+}
+
+func (s *runInhibitSuite) TestNotLocked(c *C) {
+	hint, err := runinhibit.IsLocked("pkg")
+	c.Assert(err, IsNil)
+	c.Check(hint, Equals, runinhibit.HintNotInhibited)
+}
+
+func (s *runInhibitSuite) TestLockUnlock(c *C) {
+	err := runinhibit.LockWithHint("pkg", runinhibit.HintInhibitedForRefresh)
+	c.Assert(err, IsNil)
+
+	content, err := ioutil.ReadFile(filepath.Join(dirs.SnapRunInhibitDir, "pkg.lock"))
+	c.Assert(err, IsNil)
+	c.Check(string(content), Equals, "refresh")
+
+	hint, err := runinhibit.IsLocked("pkg")
+	c.Assert(err, IsNil)
+	c.Check(hint, Equals, runinhibit.HintInhibitedForRefresh)
+
+	// other snaps are not affected
+	hint, err = runinhibit.IsLocked("other")
+	c.Assert(err, IsNil)
+	c.Check(hint, Equals, runinhibit.HintNotInhibited)
+
+	c.Assert(runinhibit.Unlock("pkg"), IsNil)
+	hint, err = runinhibit.IsLocked("pkg")
+	c.Assert(err, IsNil)
+	c.Check(hint, Equals, runinhibit.HintNotInhibited)
+	c.Check(osutil.FileExists(filepath.Join(dirs.SnapRunInhibitDir, "pkg.lock")), Equals, false)
+}
+
+func (s *runInhibitSuite) TestUnlockNotLocked(c *C) {
+	c.Check(runinhibit.Unlock("pkg"), IsNil)
+}
+
+func (s *runInhibitSuite) TestLockWithoutHint(c *C) {
+	err := runinhibit.LockWithHint("pkg", runinhibit.HintNotInhibited)
+	c.Assert(err, ErrorMatches, `internal error: cannot inhibit running snap "pkg" without a hint`)
+	_, err = os.Stat(dirs.SnapRunInhibitDir)
+	c.Check(os.IsNotExist(err), Equals, true)
+}
@@ -38,6 +38,16 @@ import (
 // The caller is responsible for mocking root directory with dirs.SetRootDir()
 // and for altering the overlord state if required.
 func MockSnap(c *check.C, yamlText string, sideInfo *snap.SideInfo) *snap.Info {
+	return MockSnapInstance(c, "", yamlText, sideInfo)
+}
+
+// MockSnapInstance does the same as MockSnap, but for the instance of the
+// snap with the given instance name. An empty instance name means the
+// snap name from the yaml is used.
+//
+// The caller is responsible for mocking root directory with dirs.SetRootDir()
+// and for altering the overlord state if required.
+func MockSnapInstance(c *check.C, instanceName, yamlText string, sideInfo *snap.SideInfo) *snap.Info {
 	c.Assert(sideInfo, check.Not(check.IsNil))
 
 	restoreSanitize := snap.MockSanitizePlugsSlots(func(snapInfo *snap.Info) {})
@@ -49,6 +59,9 @@ func MockSnap(c *check.C, yamlText string, sideInfo *snap.SideInfo) *snap.Info {
 
 	// Set SideInfo so that we can use MountDir below
 	snapInfo.SideInfo = *sideInfo
+	if instanceName != "" {
+		_, snapInfo.InstanceKey = snap.SplitInstanceName(instanceName)
+	}
 
 	// Put the YAML on disk, in the right spot.
 	metaDir := filepath.Join(snapInfo.MountDir(), "meta")
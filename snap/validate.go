@@ -72,6 +72,23 @@ func ValidateName(name string) error {
 	return nil
 }
 
+// validInstanceKey is the pattern instance keys need to match.
+var validInstanceKey = regexp.MustCompile("^[a-z0-9]{1,10}$")
+
+// ValidateInstanceName checks if a string can be used as the name of an
+// instance of a snap, i.e. a snap name optionally followed by an
+// underscore and an instance key.
+func ValidateInstanceName(instanceName string) error {
+	snapName, instanceKey := SplitInstanceName(instanceName)
+	if err := ValidateName(snapName); err != nil {
+		return err
+	}
+	if strings.Contains(instanceName, "_") && !validInstanceKey.MatchString(instanceKey) {
+		return fmt.Errorf("invalid instance key: %q", instanceKey)
+	}
+	return nil
+}
+
 // NB keep this in sync with snapcraft and the review tools :-)
 var isValidVersion = regexp.MustCompile("^[a-zA-Z0-9](?:[a-zA-Z0-9:.+~-]{0,30}[a-zA-Z0-9+~])?$").MatchString
 
@@ -148,7 +165,9 @@ func ValidateHook(hook *HookInfo) error {
 	if !valid {
 		return fmt.Errorf("invalid hook name: %q", hook.Name)
 	}
-	return nil
+
+	// Also validate the command chain
+	return validateCommandChain(hook.CommandChain)
 }
 
 var validAlias = regexp.MustCompile("^[a-zA-Z0-9][-_.a-zA-Z0-9]*$")
@@ -255,7 +274,7 @@ func validateSocketAddrNetPort(socket *SocketInfo, fieldName string, port string
 
 // Validate verifies the content in the info.
 func Validate(info *Info) error {
-	name := info.Name()
+	name := info.SnapName()
 	if name == "" {
 		return fmt.Errorf("snap name cannot be empty")
 	}
@@ -264,6 +283,10 @@ func Validate(info *Info) error {
 		return err
 	}
 
+	if info.InstanceKey != "" && !validInstanceKey.MatchString(info.InstanceKey) {
+		return fmt.Errorf("invalid instance key: %q", info.InstanceKey)
+	}
+
 	if err := ValidateVersion(info.Version); err != nil {
 		return err
 	}
@@ -477,10 +500,36 @@ func validateAppOrderNames(app *AppInfo, dependencies []string) error {
 			return fmt.Errorf("application %q refers to non-service application %q in before/after",
 				app.Name, dep)
 		}
+
+		if other.IsUserService() != app.IsUserService() {
+			return fmt.Errorf("application %q refers to application %q of a different daemon-scope in before/after",
+				app.Name, dep)
+		}
 	}
 	return nil
 }
 
+func validateAppDaemonScope(app *AppInfo) error {
+	if app.DaemonScope == "" {
+		// system daemon, or not a daemon at all
+		return nil
+	}
+
+	if !app.IsService() {
+		return fmt.Errorf(`"daemon-scope" cannot be used for %q, only for services`, app.Name)
+	}
+
+	if err := app.DaemonScope.Validate(); err != nil {
+		return err
+	}
+
+	if app.DaemonScope == UserDaemon && len(app.Sockets) > 0 {
+		return fmt.Errorf("cannot use sockets with application %q as it's a user daemon", app.Name)
+	}
+
+	return nil
+}
+
 func validateAppWatchdog(app *AppInfo) error {
 	if app.WatchdogTimeout == 0 {
 		// no watchdog
@@ -519,6 +568,28 @@ func validateAppTimer(app *AppInfo) error {
 // will get confused.
 var appContentWhitelist = regexp.MustCompile(`^[A-Za-z0-9/. _#:$-]*$`)
 
+// commandChainContentWhitelist is the whitelist of legal chars in the
+// "command-chain" of apps and hooks. Each element is a path to an
+// executable inside the snap, so no spaces are allowed.
+var commandChainContentWhitelist = regexp.MustCompile(`^[A-Za-z0-9/._#:$-]*$`)
+
+// validateCommandChain checks that each element of the command chain
+// only uses legal chars and names a path inside the snap.
+func validateCommandChain(commandChain []string) error {
+	for _, value := range commandChain {
+		if err := validateField("command-chain", value, commandChainContentWhitelist); err != nil {
+			return err
+		}
+		// elements are relative to the snap mount dir, even if
+		// they start with a slash
+		rel := strings.TrimPrefix(filepath.Clean(value), "/")
+		if rel == "" || rel == "." || rel == ".." || strings.HasPrefix(rel, "../") {
+			return fmt.Errorf("command-chain element %q must be a path inside the snap", value)
+		}
+	}
+	return nil
+}
+
 // ValidAppName tells whether a string is a valid application name.
 func ValidAppName(n string) bool {
 	var validAppName = regexp.MustCompile("^[a-zA-Z0-9](?:-?[a-zA-Z0-9])*$")
@@ -555,6 +626,15 @@ func ValidateApp(app *AppInfo) error {
 		}
 	}
 
+	// Also validate the command chain
+	if err := validateCommandChain(app.CommandChain); err != nil {
+		return err
+	}
+
+	if err := validateAppDaemonScope(app); err != nil {
+		return err
+	}
+
 	// Socket activation requires the "network-bind" plug
 	if len(app.Sockets) > 0 {
 		if _, ok := app.Plugs["network-bind"]; !ok {
@@ -597,6 +677,16 @@ func ValidateApp(app *AppInfo) error {
 	if app.RefreshMode != "" && app.Daemon == "" {
 		return fmt.Errorf(`"refresh-mode" cannot be used for %q, only for services`, app.Name)
 	}
+	// validate install-mode
+	switch app.InstallMode {
+	case "", "enable", "disable":
+		// valid
+	default:
+		return fmt.Errorf(`"install-mode" field contains invalid value %q`, app.InstallMode)
+	}
+	if app.InstallMode != "" && app.Daemon == "" {
+		return fmt.Errorf(`"install-mode" cannot be used for %q, only for services`, app.Name)
+	}
 
 	return validateAppTimer(app)
 }
@@ -111,6 +111,29 @@ func (s *ValidateSuite) TestValidateName(c *C) {
 	}
 }
 
+func (s *ValidateSuite) TestValidateInstanceName(c *C) {
+	for _, name := range []string{"foo", "foo_bar", "foo_1", "foo_1234567890", "foo-bar_baz"} {
+		c.Check(ValidateInstanceName(name), IsNil, Commentf(name))
+	}
+	for _, name := range []string{"foo_", "foo_bar-baz", "foo_BAR", "foo_12345678901", "foo_bar_baz"} {
+		c.Check(ValidateInstanceName(name), ErrorMatches, `invalid instance key: ".*"`, Commentf(name))
+	}
+	for _, name := range []string{"_bar", "foo--bar_baz", "0_bar"} {
+		c.Check(ValidateInstanceName(name), ErrorMatches, `invalid snap name: ".*"`, Commentf(name))
+	}
+}
+
+func (s *ValidateSuite) TestValidateInstanceKey(c *C) {
+	info, err := InfoFromSnapYaml([]byte("name: foo\nversion: 1.0\n"))
+	c.Assert(err, IsNil)
+
+	info.InstanceKey = "bar"
+	c.Check(Validate(info), IsNil)
+
+	info.InstanceKey = "bar-baz"
+	c.Check(Validate(info), ErrorMatches, `invalid instance key: "bar-baz"`)
+}
+
 func (s *ValidateSuite) TestValidateVersion(c *C) {
 	validVersions := []string{
 		"0", "v1.0", "0.12+16.04.20160126-0ubuntu1",
@@ -425,6 +448,63 @@ func (s *ValidateSuite) TestAppDaemonValue(c *C) {
 	}
 }
 
+func (s *ValidateSuite) TestAppDaemonScope(c *C) {
+	for _, t := range []struct {
+		scope DaemonScope
+		ok    bool
+	}{
+		// good
+		{"", true},
+		{SystemDaemon, true},
+		{UserDaemon, true},
+		// bad
+		{"invalid-thing", false},
+	} {
+		if t.ok {
+			c.Check(ValidateApp(&AppInfo{Name: "foo", Daemon: "simple", DaemonScope: t.scope}), IsNil)
+		} else {
+			c.Check(ValidateApp(&AppInfo{Name: "foo", Daemon: "simple", DaemonScope: t.scope}), ErrorMatches, fmt.Sprintf(`"daemon-scope" field contains invalid value %q`, t.scope))
+		}
+	}
+
+	// non-services cannot have a daemon-scope
+	err := ValidateApp(&AppInfo{Name: "foo", Daemon: "", DaemonScope: UserDaemon})
+	c.Check(err, ErrorMatches, `"daemon-scope" cannot be used for "foo", only for services`)
+}
+
+func (s *ValidateSuite) TestAppDaemonScopeUserSockets(c *C) {
+	info, err := InfoFromSnapYaml([]byte(`name: foo
+version: 1.0
+apps:
+  svc:
+    daemon: simple
+    daemon-scope: user
+    plugs: [network-bind]
+    sockets:
+      sock:
+        listen-stream: 8080
+`))
+	c.Assert(err, IsNil)
+	err = Validate(info)
+	c.Check(err, ErrorMatches, `cannot use sockets with application "svc" as it's a user daemon`)
+}
+
+func (s *ValidateSuite) TestAppDaemonScopeOrdering(c *C) {
+	info, err := InfoFromSnapYaml([]byte(`name: foo
+version: 1.0
+apps:
+  user-svc:
+    daemon: simple
+    daemon-scope: user
+    after: [sys-svc]
+  sys-svc:
+    daemon: simple
+`))
+	c.Assert(err, IsNil)
+	err = Validate(info)
+	c.Check(err, ErrorMatches, `application "user-svc" refers to application "sys-svc" of a different daemon-scope in before/after`)
+}
+
 func (s *ValidateSuite) TestAppStopMode(c *C) {
 	// check services
 	for _, t := range []struct {
@@ -481,12 +561,72 @@ func (s *ValidateSuite) TestAppRefreshMode(c *C) {
 	c.Check(err, ErrorMatches, `"refresh-mode" cannot be used for "foo", only for services`)
 }
 
+func (s *ValidateSuite) TestAppInstallMode(c *C) {
+	// check services
+	for _, t := range []struct {
+		installMode string
+		ok          bool
+	}{
+		// good
+		{"", true},
+		{"enable", true},
+		{"disable", true},
+		// bad
+		{"invalid-thing", false},
+	} {
+		if t.ok {
+			c.Check(ValidateApp(&AppInfo{Name: "foo", Daemon: "simple", InstallMode: t.installMode}), IsNil)
+		} else {
+			c.Check(ValidateApp(&AppInfo{Name: "foo", Daemon: "simple", InstallMode: t.installMode}), ErrorMatches, fmt.Sprintf(`"install-mode" field contains invalid value %q`, t.installMode))
+		}
+	}
+
+	// non-services cannot have a install-mode
+	err := ValidateApp(&AppInfo{Name: "foo", Daemon: "", InstallMode: "disable"})
+	c.Check(err, ErrorMatches, `"install-mode" cannot be used for "foo", only for services`)
+}
+
 func (s *ValidateSuite) TestAppWhitelistError(c *C) {
 	err := ValidateApp(&AppInfo{Name: "foo", Command: "x\n"})
 	c.Assert(err, NotNil)
 	c.Check(err.Error(), Equals, `app description field 'command' contains illegal "x\n" (legal: '^[A-Za-z0-9/. _#:$-]*$')`)
 }
 
+func (s *ValidateSuite) TestAppCommandChain(c *C) {
+	c.Check(ValidateApp(&AppInfo{Name: "foo", Command: "foo", CommandChain: []string{"bin/chain", "$SNAP/chain2"}}), IsNil)
+
+	err := ValidateApp(&AppInfo{Name: "foo", Command: "foo", CommandChain: []string{"bin/chain arg"}})
+	c.Check(err, ErrorMatches, `app description field 'command-chain' contains illegal "bin/chain arg" \(legal: '\^\[A-Za-z0-9/._#:\$-\]\*\$'\)`)
+}
+
+func (s *ValidateSuite) TestHookCommandChain(c *C) {
+	c.Check(ValidateHook(&HookInfo{Name: "configure", CommandChain: []string{"bin/chain"}}), IsNil)
+
+	err := ValidateHook(&HookInfo{Name: "configure", CommandChain: []string{"bin/chain\n"}})
+	c.Check(err, ErrorMatches, `app description field 'command-chain' contains illegal "bin/chain\\n" .*`)
+}
+
+func (s *ValidateSuite) TestCommandChainEmptyElement(c *C) {
+	for _, chain := range [][]string{{""}, {"bin/chain", ""}, {"/"}, {"."}, {"bin/.."}} {
+		err := ValidateApp(&AppInfo{Name: "foo", Command: "foo", CommandChain: chain})
+		c.Check(err, ErrorMatches, `command-chain element ".*" must be a path inside the snap`, Commentf("%q", chain))
+		err = ValidateHook(&HookInfo{Name: "configure", CommandChain: chain})
+		c.Check(err, ErrorMatches, `command-chain element ".*" must be a path inside the snap`, Commentf("%q", chain))
+	}
+}
+
+func (s *ValidateSuite) TestCommandChainOutsideSnap(c *C) {
+	for _, element := range []string{"..", "../chain", "bin/../../chain", "./../../usr/bin/chain"} {
+		err := ValidateApp(&AppInfo{Name: "foo", Command: "foo", CommandChain: []string{element}})
+		c.Check(err, ErrorMatches, `command-chain element ".*" must be a path inside the snap`, Commentf("%q", element))
+		err = ValidateHook(&HookInfo{Name: "configure", CommandChain: []string{element}})
+		c.Check(err, ErrorMatches, `command-chain element ".*" must be a path inside the snap`, Commentf("%q", element))
+	}
+
+	// paths that resolve inside the snap are fine
+	c.Check(ValidateApp(&AppInfo{Name: "foo", Command: "foo", CommandChain: []string{"bin/../chain", "/../chain"}}), IsNil)
+}
+
 // Validate
 
 func (s *ValidateSuite) TestDetectIllegalYamlBinaries(c *C) {
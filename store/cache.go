@@ -20,6 +20,7 @@
 package store
 
 import (
+	"crypto"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -57,27 +58,27 @@ func (s changesByReverseMtime) Less(i, j int) bool { return s[i].ModTime().After
 // cacheManager implements a downloadCache via content based hard linking
 type CacheManager struct {
 	cacheDir string
-	maxItems int
+	maxSize  int64
 }
 
 // NewCacheManager returns a new CacheManager with the given cacheDir
-// and the given maximum amount of items. The idea behind it is the
-// following algorithm:
+// and the given maximum total size in bytes of its items. The idea
+// behind it is the following algorithm:
 //
 // 1. When starting a download, check if it exists in $cacheDir
 // 2. If found, update its mtime, hardlink into target location, and
 //    return success
 // 3. If not found, download the snap
 // 4. On success, hardlink into $cacheDir/<digest>
-// 5. If cache dir has more than maxItems entries, remove oldest mtimes
-//    until it has maxItems
+// 5. If the entries in cache dir are bigger than maxSize in total,
+//    remove oldest mtimes until they are not
 //
 // The caching part is done here, the downloading happens in the store.go
 // code.
-func NewCacheManager(cacheDir string, maxItems int) *CacheManager {
+func NewCacheManager(cacheDir string, maxSize int64) *CacheManager {
 	return &CacheManager{
 		cacheDir: cacheDir,
-		maxItems: maxItems,
+		maxSize:  maxSize,
 	}
 }
 
@@ -128,26 +129,95 @@ func (cm *CacheManager) Count() int {
 	return 0
 }
 
+// Size returns the total size in bytes of the items in the cache
+func (cm *CacheManager) Size() int64 {
+	var size int64
+	if l, err := ioutil.ReadDir(cm.cacheDir); err == nil {
+		for _, fi := range l {
+			size += fi.Size()
+		}
+	}
+	return size
+}
+
 // path returns the full path of the given content in the cache
 func (cm *CacheManager) path(cacheKey string) string {
 	return filepath.Join(cm.cacheDir, cacheKey)
 }
 
-// cleanup ensures that only maxItems are stored in the cache
+// cleanup ensures that only up to maxSize bytes are stored in the cache
 func (cm *CacheManager) cleanup() error {
 	fil, err := ioutil.ReadDir(cm.cacheDir)
 	if err != nil {
 		return err
 	}
-	if len(fil) <= cm.maxItems {
-		return nil
-	}
 
 	sort.Sort(changesByReverseMtime(fil))
-	for _, fi := range fil[cm.maxItems:] {
-		if err := os.Remove(cm.path(fi.Name())); err != nil && os.IsNotExist(err) {
+	var size int64
+	for _, fi := range fil {
+		if size+fi.Size() <= cm.maxSize {
+			size += fi.Size()
+			continue
+		}
+		if err := os.Remove(cm.path(fi.Name())); err != nil && !os.IsNotExist(err) {
 			return err
 		}
 	}
 	return nil
 }
+
+// peerCache implements a downloadCache on top of a directory laid out
+// like the one of a CacheManager but shared with other machines,
+// e.g. over the LAN. Its content is copied rather than linked, and
+// checked against the cacheKey digest as it's not trusted.
+type peerCache struct {
+	cacheDir string
+}
+
+func (pc *peerCache) path(cacheKey string) string {
+	return filepath.Join(pc.cacheDir, cacheKey)
+}
+
+// Get gets the given cacheKey content and puts it into targetPath
+func (pc *peerCache) Get(cacheKey, targetPath string) error {
+	tmpPath := targetPath + ".peer"
+	if err := osutil.CopyFile(pc.path(cacheKey), tmpPath, osutil.CopyFlagOverwrite); err != nil {
+		return err
+	}
+	digest, _, err := osutil.FileDigest(tmpPath, crypto.SHA3_384)
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if sha3_384 := fmt.Sprintf("%x", digest); sha3_384 != cacheKey {
+		os.Remove(tmpPath)
+		return fmt.Errorf("sha3-384 mismatch for %q in peer cache: got %s", cacheKey, sha3_384)
+	}
+	if err := os.Rename(tmpPath, targetPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	logger.Debugf("using peer cache for %s", targetPath)
+	return nil
+}
+
+// Put shares a new file through the peer cache with the given cacheKey,
+// if the peer cache directory is writable
+func (pc *peerCache) Put(cacheKey, sourcePath string) error {
+	if !osutil.IsWritable(pc.cacheDir) || osutil.FileExists(pc.path(cacheKey)) {
+		return nil
+	}
+
+	// copy under a temporary name first, so that peers never get to see
+	// an incomplete file
+	tmpPath := filepath.Join(pc.cacheDir, "."+cacheKey+".tmp")
+	if err := osutil.CopyFile(sourcePath, tmpPath, osutil.CopyFlagOverwrite|osutil.CopyFlagSync); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, pc.path(cacheKey)); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
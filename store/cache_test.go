@@ -23,7 +23,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
-	"strconv"
+	"strings"
 	"time"
 
 	. "gopkg.in/check.v1"
@@ -45,11 +45,18 @@ func (s *cacheSuite) SetUpTest(c *C) {
 	s.tmp = c.MkDir()
 
 	s.maxItems = 5
-	s.cm = store.NewCacheManager(c.MkDir(), s.maxItems)
+	s.cm = store.NewCacheManager(c.MkDir(), int64(s.maxItems*itemSize))
 	// sanity
 	c.Check(s.cm.Count(), Equals, 0)
 }
 
+// the size of the test items, so that the cache holds maxItems of them
+const itemSize = 10
+
+func itemContent(i int) string {
+	return fmt.Sprintf("%0*d", itemSize, i)
+}
+
 func (s *cacheSuite) makeTestFile(c *C, name, content string) string {
 	p := filepath.Join(c.MkDir(), name)
 	err := ioutil.WriteFile(p, []byte(content), 0644)
@@ -59,7 +66,7 @@ func (s *cacheSuite) makeTestFile(c *C, name, content string) string {
 
 func (s *cacheSuite) TestPutMany(c *C) {
 	for i := 1; i < s.maxItems+10; i++ {
-		err := s.cm.Put(fmt.Sprintf("cacheKey-%d", i), s.makeTestFile(c, fmt.Sprintf("f%d", i), fmt.Sprintf("%d", i)))
+		err := s.cm.Put(fmt.Sprintf("cacheKey-%d", i), s.makeTestFile(c, fmt.Sprintf("f%d", i), itemContent(i)))
 		c.Check(err, IsNil)
 		if i < s.maxItems {
 			c.Check(s.cm.Count(), Equals, i)
@@ -91,7 +98,7 @@ func (s *cacheSuite) TestClenaup(c *C) {
 	// add files, add more than
 	cacheKeys := make([]string, s.maxItems+2)
 	for i := 0; i < s.maxItems+2; i++ {
-		p := s.makeTestFile(c, fmt.Sprintf("f%d", i), strconv.Itoa(i))
+		p := s.makeTestFile(c, fmt.Sprintf("f%d", i), itemContent(i))
 		cacheKey := fmt.Sprintf("cacheKey-%d", i)
 		cacheKeys[i] = cacheKey
 		s.cm.Put(cacheKey, p)
@@ -110,3 +117,23 @@ func (s *cacheSuite) TestClenaup(c *C) {
 	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), cacheKeys[len(cacheKeys)-1])), Equals, true)
 
 }
+
+func (s *cacheSuite) TestCleanupBySize(c *C) {
+	small := s.makeTestFile(c, "small", "x")
+	c.Assert(s.cm.Put("small", small), IsNil)
+	time.Sleep(10 * time.Millisecond)
+
+	// an item as big as the whole cache pushes out everything else
+	big := s.makeTestFile(c, "big", strings.Repeat("x", s.maxItems*itemSize))
+	c.Assert(s.cm.Put("big", big), IsNil)
+	c.Check(s.cm.Count(), Equals, 1)
+	c.Check(s.cm.Size(), Equals, int64(s.maxItems*itemSize))
+	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), "big")), Equals, true)
+
+	// and an item bigger than that is not kept at all
+	huge := s.makeTestFile(c, "huge", strings.Repeat("x", s.maxItems*itemSize+1))
+	c.Assert(s.cm.Put("huge", huge), IsNil)
+	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), "huge")), Equals, false)
+	c.Check(osutil.FileExists(huge), Equals, true)
+	c.Check(osutil.FileExists(filepath.Join(s.cm.CacheDir(), "big")), Equals, true)
+}
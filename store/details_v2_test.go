@@ -283,6 +283,7 @@ func (s *detailsV2Suite) TestInfoFromStoreSnap(c *C) {
 		"Channels", // TODO: support coming later
 		"Tracks",   // TODO: support coming later
 		"Layout",
+		"InstanceKey",
 		"SideInfo.Channel",
 		"DownloadInfo.AnonDownloadURL", // TODO: going away at some point
 	}
@@ -37,3 +37,11 @@ func MockDefaultRetryStrategy(t *testutil.BaseTest, strategy retry.Strategy) {
 func (cm *CacheManager) CacheDir() string {
 	return cm.cacheDir
 }
+
+func mockMinChunkedDownloadSize(size int64) (restore func()) {
+	old := minChunkedDownloadSize
+	minChunkedDownloadSize = size
+	return func() {
+		minChunkedDownloadSize = old
+	}
+}
@@ -0,0 +1,731 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package offline implements a store serving snaps and assertions from
+// a local directory, e.g. a mirror of a store on an air-gapped site.
+//
+// The directory holds the snap files, named *.snap, and under asserts/
+// assertion streams with the assertions for them, i.e. at least the
+// account, account-key, snap-declaration and snap-revision assertions,
+// and any other assertion that should be served. Only the snaps whose
+// assertions check out against the trusted ones are offered.
+package offline
+
+import (
+	"crypto"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/sysdb"
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/overlord/auth"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/release"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/store"
+)
+
+var errOffline = errors.New("cannot buy snaps from an offline store")
+
+// Store is a store serving snaps and assertions from a local directory.
+//
+// The directory is indexed when first needed and again whenever its
+// content changes. As the directory offers no channels, any channel
+// gets the latest revision of a snap.
+type Store struct {
+	dir string
+
+	mu  sync.Mutex
+	idx *index
+}
+
+// New returns a new Store serving snaps and assertions from dir.
+func New(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Dir returns the directory the store serves snaps and assertions from.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+func (s *Store) assertsDir() string {
+	return filepath.Join(s.dir, "asserts")
+}
+
+// stamp returns the latest modification time of the directories of the
+// store, which changes whenever files are added to or removed from them.
+func (s *Store) stamp() (time.Time, error) {
+	var stamp time.Time
+	for _, dir := range []string{s.dir, s.assertsDir()} {
+		fi, err := os.Stat(dir)
+		if os.IsNotExist(err) && dir != s.dir {
+			continue
+		}
+		if err != nil {
+			return time.Time{}, err
+		}
+		if fi.ModTime().After(stamp) {
+			stamp = fi.ModTime()
+		}
+	}
+	return stamp, nil
+}
+
+// index returns the index of the content of the store, (re)building it
+// if needed.
+func (s *Store) index() (*index, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stamp, err := s.stamp()
+	if err != nil {
+		return nil, fmt.Errorf("cannot use offline store: %v", err)
+	}
+	if s.idx != nil && s.idx.stamp.Equal(stamp) {
+		return s.idx, nil
+	}
+
+	idx, err := buildIndex(s.dir, s.assertsDir())
+	if err != nil {
+		return nil, fmt.Errorf("cannot use offline store: %v", err)
+	}
+	idx.stamp = stamp
+	s.idx = idx
+	return idx, nil
+}
+
+// entry is a snap offered by the store.
+type entry struct {
+	path     string
+	yaml     []byte
+	name     string
+	snapID   string
+	revision snap.Revision
+	size     int64
+	sha3_384 string
+
+	publisherID string
+	publisher   string
+}
+
+// info returns a new snap.Info for the entry, as if from the given
+// channel.
+func (e *entry) info(channel string) (*snap.Info, error) {
+	info, err := snap.InfoFromSnapYaml(e.yaml)
+	if err != nil {
+		return nil, err
+	}
+	if channel == "" {
+		channel = "stable"
+	}
+	info.SideInfo = snap.SideInfo{
+		RealName: e.name,
+		SnapID:   e.snapID,
+		Revision: e.revision,
+		Channel:  channel,
+	}
+	info.PublisherID = e.publisherID
+	info.Publisher = e.publisher
+	downloadURL := (&url.URL{Scheme: "file", Path: e.path}).String()
+	info.DownloadInfo = snap.DownloadInfo{
+		AnonDownloadURL: downloadURL,
+		DownloadURL:     downloadURL,
+		Size:            e.size,
+		Sha3_384:        e.sha3_384,
+	}
+	return info, nil
+}
+
+// byRevision sorts entries by their revision, the latest first.
+type byRevision []*entry
+
+func (es byRevision) Len() int           { return len(es) }
+func (es byRevision) Swap(i, j int)      { es[i], es[j] = es[j], es[i] }
+func (es byRevision) Less(i, j int) bool { return es[i].revision.N > es[j].revision.N }
+
+// index holds the verified assertions and snaps of the store.
+type index struct {
+	stamp time.Time
+
+	db *asserts.Database
+
+	// entries by snap id, the latest revision first
+	bySnapID map[string][]*entry
+	// snap ids by snap name
+	snapIDs  map[string]string
+	byDigest map[string]*entry
+}
+
+func buildIndex(dir, assertsDir string) (*index, error) {
+	db, err := asserts.OpenDatabase(&asserts.DatabaseConfig{
+		Backstore:       asserts.NewMemoryBackstore(),
+		Trusted:         sysdb.Trusted(),
+		OtherPredefined: sysdb.Generic(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := addAssertions(db, assertsDir); err != nil {
+		return nil, err
+	}
+
+	idx := &index{
+		db:       db,
+		bySnapID: make(map[string][]*entry),
+		snapIDs:  make(map[string]string),
+		byDigest: make(map[string]*entry),
+	}
+
+	snapFiles, err := filepath.Glob(filepath.Join(dir, "*.snap"))
+	if err != nil {
+		return nil, err
+	}
+	for _, fn := range snapFiles {
+		e, err := idx.newEntry(fn)
+		if err != nil {
+			logger.Noticef("Ignoring %s in offline store: %v", fn, err)
+			continue
+		}
+		if idx.byDigest[e.sha3_384] != nil {
+			// the same snap under another name
+			continue
+		}
+		idx.byDigest[e.sha3_384] = e
+		idx.bySnapID[e.snapID] = append(idx.bySnapID[e.snapID], e)
+		idx.snapIDs[e.name] = e.snapID
+	}
+	for _, entries := range idx.bySnapID {
+		sort.Sort(byRevision(entries))
+	}
+
+	return idx, nil
+}
+
+// addAssertions adds to db the assertions in the streams in assertsDir
+// that can be verified, whatever order they come in.
+func addAssertions(db *asserts.Database, assertsDir string) error {
+	fis, err := ioutil.ReadDir(assertsDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var pending []asserts.Assertion
+	for _, fi := range fis {
+		if fi.IsDir() {
+			continue
+		}
+		fn := filepath.Join(assertsDir, fi.Name())
+		as, err := readAssertions(fn)
+		if err != nil {
+			logger.Noticef("Ignoring %s in offline store: %v", fn, err)
+		}
+		pending = append(pending, as...)
+	}
+
+	// assertions can only be added once the ones they are signed with
+	// or depend on are, keep trying as long as that gets us further
+	for len(pending) > 0 {
+		var failed []asserts.Assertion
+		var errs []error
+		for _, a := range pending {
+			err := db.Add(a)
+			if _, ok := err.(*asserts.RevisionError); ok {
+				// already there, possibly as trusted
+				continue
+			}
+			if err != nil {
+				failed = append(failed, a)
+				errs = append(errs, err)
+			}
+		}
+		if len(failed) == len(pending) {
+			for i, a := range failed {
+				logger.Noticef("Ignoring %s assertion %v in offline store: %v", a.Type().Name, a.Ref().PrimaryKey, errs[i])
+			}
+			break
+		}
+		pending = failed
+	}
+	return nil
+}
+
+func readAssertions(fn string) ([]asserts.Assertion, error) {
+	f, err := os.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var as []asserts.Assertion
+	dec := asserts.NewDecoder(f)
+	for {
+		a, err := dec.Decode()
+		if err == io.EOF {
+			return as, nil
+		}
+		if err != nil {
+			return as, err
+		}
+		as = append(as, a)
+	}
+}
+
+// newEntry returns the entry for the snap file fn, if it checks out
+// against the assertions of the store.
+func (idx *index) newEntry(fn string) (*entry, error) {
+	digest, size, err := asserts.SnapFileSHA3_384(fn)
+	if err != nil {
+		return nil, err
+	}
+	// assertions carry the digest base64url encoded, the store and
+	// download info use it hex encoded
+	rawDigest, err := base64.RawURLEncoding.DecodeString(digest)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := idx.db.Find(asserts.SnapRevisionType, map[string]string{
+		"snap-sha3-384": digest,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot find snap-revision: %v", err)
+	}
+	snapRev := a.(*asserts.SnapRevision)
+	if snapRev.SnapSize() != size {
+		return nil, fmt.Errorf("snap-revision size %d does not match snap size %d", snapRev.SnapSize(), size)
+	}
+
+	a, err = idx.db.Find(asserts.SnapDeclarationType, map[string]string{
+		"series":  release.Series,
+		"snap-id": snapRev.SnapID(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot find snap-declaration: %v", err)
+	}
+	snapDecl := a.(*asserts.SnapDeclaration)
+
+	snapf, err := snap.Open(fn)
+	if err != nil {
+		return nil, err
+	}
+	info, err := snap.ReadInfoFromSnapFile(snapf, nil)
+	if err != nil {
+		return nil, err
+	}
+	if info.SnapName() != snapDecl.SnapName() {
+		return nil, fmt.Errorf("snap name %q does not match snap-declaration name %q", info.SnapName(), snapDecl.SnapName())
+	}
+	yaml, err := snapf.ReadFile("meta/snap.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	publisher := snapDecl.PublisherID()
+	a, err = idx.db.Find(asserts.AccountType, map[string]string{
+		"account-id": snapDecl.PublisherID(),
+	})
+	if err == nil {
+		publisher = a.(*asserts.Account).Username()
+	}
+
+	return &entry{
+		path:     fn,
+		yaml:     yaml,
+		name:     snapDecl.SnapName(),
+		snapID:   snapRev.SnapID(),
+		revision: snap.R(snapRev.SnapRevision()),
+		size:     int64(size),
+		sha3_384: fmt.Sprintf("%x", rawDigest),
+
+		publisherID: snapDecl.PublisherID(),
+		publisher:   publisher,
+	}, nil
+}
+
+// lookup returns the entry for the given revision of the snap with the
+// given name, or its latest revision if unset.
+func (idx *index) lookup(name string, revision snap.Revision) (*entry, error) {
+	entries := idx.bySnapID[idx.snapIDs[name]]
+	if len(entries) == 0 {
+		return nil, store.ErrSnapNotFound
+	}
+	if revision.Unset() {
+		return entries[0], nil
+	}
+	for _, e := range entries {
+		if e.revision == revision {
+			return e, nil
+		}
+	}
+	return nil, store.ErrRevisionNotAvailable
+}
+
+// refresh returns the entry for the latest revision of the snap with the
+// given snap id newer than current and not blocked.
+func (idx *index) refresh(snapID string, current snap.Revision, block []snap.Revision) (*entry, error) {
+	entries := idx.bySnapID[snapID]
+	if len(entries) == 0 {
+		return nil, store.ErrSnapNotFound
+	}
+	for _, e := range entries {
+		if e.revision.N <= current.N {
+			break
+		}
+		if !isBlocked(e.revision, block) {
+			return e, nil
+		}
+	}
+	return nil, store.ErrNoUpdateAvailable
+}
+
+func isBlocked(rev snap.Revision, block []snap.Revision) bool {
+	for _, r := range block {
+		if r == rev {
+			return true
+		}
+	}
+	return false
+}
+
+// SnapInfo returns the snap.Info for the snap matching the given spec.
+func (s *Store) SnapInfo(spec store.SnapSpec, user *auth.UserState) (*snap.Info, error) {
+	idx, err := s.index()
+	if err != nil {
+		return nil, err
+	}
+	e, err := idx.lookup(spec.Name, spec.Revision)
+	if err == store.ErrRevisionNotAvailable {
+		err = store.ErrSnapNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return e.info(spec.Channel)
+}
+
+// Find finds the snaps matching the given Search by name, summary or
+// description. The store has neither sections nor private snaps.
+func (s *Store) Find(search *store.Search, user *auth.UserState) ([]*snap.Info, error) {
+	if search.Private && user == nil {
+		return nil, store.ErrUnauthenticated
+	}
+	idx, err := s.index()
+	if err != nil {
+		return nil, err
+	}
+	if search.Private || search.Section != "" {
+		return nil, nil
+	}
+
+	query := strings.ToLower(strings.TrimSpace(search.Query))
+	var names []string
+	for name := range idx.snapIDs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var infos []*snap.Info
+	for _, name := range names {
+		info, err := idx.bySnapID[idx.snapIDs[name]][0].info("")
+		if err != nil {
+			return nil, err
+		}
+		if !matches(info, query, search.Prefix) {
+			continue
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func matches(info *snap.Info, query string, prefix bool) bool {
+	name := strings.ToLower(info.SnapName())
+	if prefix {
+		return strings.HasPrefix(name, query)
+	}
+	for _, field := range []string{name, info.Summary(), info.Description()} {
+		if strings.Contains(strings.ToLower(field), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// LookupRefresh returns the latest revision of the snap in the refresh
+// candidate, if newer than the one in it.
+func (s *Store) LookupRefresh(candidate *store.RefreshCandidate, user *auth.UserState) (*snap.Info, error) {
+	idx, err := s.index()
+	if err != nil {
+		return nil, err
+	}
+	e, err := idx.refresh(candidate.SnapID, candidate.Revision, candidate.Block)
+	if err != nil {
+		return nil, err
+	}
+	return e.info(candidate.Channel)
+}
+
+// ListRefresh returns the available updates for the refresh candidates.
+func (s *Store) ListRefresh(ctx context.Context, candidates []*store.RefreshCandidate, user *auth.UserState, opts *store.RefreshOptions) ([]*snap.Info, error) {
+	idx, err := s.index()
+	if err != nil {
+		return nil, err
+	}
+	var infos []*snap.Info
+	for _, candidate := range candidates {
+		e, err := idx.refresh(candidate.SnapID, candidate.Revision, candidate.Block)
+		if err == store.ErrSnapNotFound || err == store.ErrNoUpdateAvailable {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		info, err := e.info(candidate.Channel)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// SnapAction returns the snaps to install or refresh to for the given
+// install/refresh actions, given the current snaps. Like the store, it
+// returns both the snap infos and a SnapActionError if only some of the
+// actions fail.
+func (s *Store) SnapAction(ctx context.Context, currentSnaps []*store.CurrentSnap, actions []*store.SnapAction, user *auth.UserState, opts *store.RefreshOptions) ([]*snap.Info, error) {
+	if len(currentSnaps) == 0 && len(actions) == 0 {
+		// nothing to do
+		return nil, &store.SnapActionError{NoResults: true}
+	}
+
+	idx, err := s.index()
+	if err != nil {
+		return nil, err
+	}
+
+	curSnaps := make(map[string]*store.CurrentSnap, len(currentSnaps))
+	for _, curSnap := range currentSnaps {
+		if curSnap.SnapID == "" || curSnap.Name == "" || curSnap.Revision.Unset() {
+			return nil, fmt.Errorf("internal error: invalid current snap information")
+		}
+		curSnaps[curSnap.SnapID] = curSnap
+	}
+
+	installErrors := make(map[string]error)
+	refreshErrors := make(map[string]error)
+	var infos []*snap.Info
+	for _, a := range actions {
+		var e *entry
+		channel := a.Channel
+		switch a.Action {
+		case "install":
+			e, err = idx.lookup(a.Name, a.Revision)
+			if err != nil {
+				installErrors[a.Name] = err
+				continue
+			}
+		case "refresh":
+			cur := curSnaps[a.SnapID]
+			if cur == nil {
+				return nil, fmt.Errorf("internal error: cannot refresh snap %q with no current snap information", a.SnapID)
+			}
+			if channel == "" {
+				channel = cur.TrackingChannel
+			}
+			if a.Revision.Unset() {
+				e, err = idx.refresh(a.SnapID, cur.Revision, cur.Block)
+			} else {
+				e, err = idx.lookup(cur.Name, a.Revision)
+			}
+			if err != nil {
+				refreshErrors[cur.Name] = err
+				continue
+			}
+		default:
+			return nil, fmt.Errorf("internal error: unsupported snap action %q", a.Action)
+		}
+
+		info, err := e.info(channel)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+
+	if len(installErrors)+len(refreshErrors) != 0 {
+		// normalize empty maps
+		if len(refreshErrors) == 0 {
+			refreshErrors = nil
+		}
+		if len(installErrors) == 0 {
+			installErrors = nil
+		}
+		return infos, &store.SnapActionError{
+			NoResults: len(infos) == 0,
+			Refresh:   refreshErrors,
+			Install:   installErrors,
+		}
+	}
+	return infos, nil
+}
+
+// Sections returns no sections, the store has none.
+func (s *Store) Sections(ctx context.Context, user *auth.UserState) ([]string, error) {
+	return nil, nil
+}
+
+// WriteCatalogs writes the names of the snaps of the store to names, and
+// adds their commands to adder.
+func (s *Store) WriteCatalogs(ctx context.Context, names io.Writer, adder store.SnapAdder) error {
+	idx, err := s.index()
+	if err != nil {
+		return err
+	}
+
+	for _, entries := range idx.bySnapID {
+		info, err := entries[0].info("")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(names, info.SnapName())
+		if len(info.Apps) == 0 {
+			continue
+		}
+
+		commands := make([]string, 0, len(info.Apps))
+		for app := range info.Apps {
+			commands = append(commands, snap.JoinSnapApp(info.SnapName(), app))
+		}
+		sort.Strings(commands)
+		if err := adder.AddSnap(info.SnapName(), info.Version, info.Summary(), commands); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Download copies the snap addressed by download info to targetPath,
+// checking its digest.
+func (s *Store) Download(ctx context.Context, name string, targetPath string, downloadInfo *snap.DownloadInfo, pbar progress.Meter, user *auth.UserState) (err error) {
+	idx, err := s.index()
+	if err != nil {
+		return err
+	}
+	e := idx.byDigest[downloadInfo.Sha3_384]
+	if e == nil {
+		return store.ErrSnapNotFound
+	}
+
+	if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
+		return err
+	}
+
+	r, err := os.Open(e.path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	partialPath := store.PartialDownloadPath(targetPath)
+	w, err := os.Create(partialPath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := w.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(partialPath)
+		}
+	}()
+
+	if pbar == nil {
+		pbar = progress.Null
+	}
+	h := crypto.SHA3_384.New()
+	pbar.Start(name, float64(e.size))
+	_, err = io.Copy(io.MultiWriter(w, h, pbar), r)
+	pbar.Finished()
+	if err != nil {
+		return err
+	}
+
+	actualSha3 := fmt.Sprintf("%x", h.Sum(nil))
+	if actualSha3 != downloadInfo.Sha3_384 {
+		return fmt.Errorf("sha3-384 mismatch for %q: got %s but expected %s", name, actualSha3, downloadInfo.Sha3_384)
+	}
+
+	if err := w.Sync(); err != nil {
+		return err
+	}
+	return os.Rename(partialPath, targetPath)
+}
+
+// Assertion retrieves the assertion for the given type and primary key.
+func (s *Store) Assertion(assertType *asserts.AssertionType, primaryKey []string, user *auth.UserState) (asserts.Assertion, error) {
+	idx, err := s.index()
+	if err != nil {
+		return nil, err
+	}
+	headers, err := asserts.HeadersFromPrimaryKey(assertType, primaryKey)
+	if err != nil {
+		return nil, err
+	}
+	return idx.db.FindMaxFormat(assertType, headers, assertType.MaxSupportedFormat())
+}
+
+// SuggestedCurrency returns no currency, snaps cannot be bought offline.
+func (s *Store) SuggestedCurrency() string {
+	return ""
+}
+
+// Buy fails, snaps cannot be bought offline.
+func (s *Store) Buy(options *store.BuyOptions, user *auth.UserState) (*store.BuyResult, error) {
+	return nil, errOffline
+}
+
+// ReadyToBuy fails, snaps cannot be bought offline.
+func (s *Store) ReadyToBuy(*auth.UserState) error {
+	return errOffline
+}
+
+// ConnectivityCheck checks that the directory of the store is there.
+func (s *Store) ConnectivityCheck() (map[string]bool, error) {
+	_, err := os.Stat(s.dir)
+	return map[string]bool{s.dir: err == nil}, nil
+}
@@ -0,0 +1,357 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package offline_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/asserts"
+	"github.com/snapcore/snapd/asserts/assertstest"
+	"github.com/snapcore/snapd/asserts/sysdb"
+	"github.com/snapcore/snapd/osutil"
+	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/snap/snaptest"
+	"github.com/snapcore/snapd/store"
+	"github.com/snapcore/snapd/store/offline"
+	"github.com/snapcore/snapd/testutil"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type offlineSuite struct {
+	testutil.BaseTest
+
+	storeSigning *assertstest.StoreStack
+	dev1Acct     *asserts.Account
+
+	dir string
+	sto *offline.Store
+}
+
+var _ = Suite(&offlineSuite{})
+
+func (s *offlineSuite) SetUpTest(c *C) {
+	s.BaseTest.SetUpTest(c)
+	s.AddCleanup(snap.MockSanitizePlugsSlots(func(snapInfo *snap.Info) {}))
+
+	s.storeSigning = assertstest.NewStoreStack("can0nical", nil)
+	s.AddCleanup(sysdb.InjectTrusted(s.storeSigning.Trusted))
+
+	s.dev1Acct = assertstest.NewAccount(s.storeSigning, "developer1", nil, "")
+
+	s.dir = c.MkDir()
+	c.Assert(os.Mkdir(filepath.Join(s.dir, "asserts"), 0755), IsNil)
+	s.writeAssertions(c, "base.assert", s.storeSigning.StoreAccountKey(""), s.dev1Acct)
+
+	s.sto = offline.New(s.dir)
+}
+
+func (s *offlineSuite) TearDownTest(c *C) {
+	s.BaseTest.TearDownTest(c)
+}
+
+func (s *offlineSuite) writeAssertions(c *C, name string, as ...asserts.Assertion) {
+	buf := bytes.NewBuffer(nil)
+	enc := asserts.NewEncoder(buf)
+	for _, a := range as {
+		c.Assert(enc.Encode(a), IsNil)
+	}
+	c.Assert(ioutil.WriteFile(filepath.Join(s.dir, "asserts", name), buf.Bytes(), 0644), IsNil)
+}
+
+// addSnap adds to the store the given revision of a snap, with its
+// assertions unless unasserted, and returns its hex encoded sha3-384
+// digest.
+func (s *offlineSuite) addSnap(c *C, name string, rev int, summary string, unasserted bool) string {
+	snapYaml := fmt.Sprintf("name: %s\nversion: '%d.0'\nsummary: %s\napps:\n  %s:\n    command: bin/%s\n", name, rev, summary, name, name)
+	src := snaptest.MakeTestSnapWithFiles(c, snapYaml, [][]string{{"bin/" + name, "#!/bin/sh\n"}})
+	fn := filepath.Join(s.dir, fmt.Sprintf("%s_%d.snap", name, rev))
+	c.Assert(osutil.CopyFile(src, fn, 0), IsNil)
+
+	digest, size, err := asserts.SnapFileSHA3_384(fn)
+	c.Assert(err, IsNil)
+	rawDigest, err := base64.RawURLEncoding.DecodeString(digest)
+	c.Assert(err, IsNil)
+	hexDigest := fmt.Sprintf("%x", rawDigest)
+	if unasserted {
+		return hexDigest
+	}
+
+	snapDecl, err := s.storeSigning.Sign(asserts.SnapDeclarationType, map[string]interface{}{
+		"series":       "16",
+		"snap-id":      name + "-id",
+		"snap-name":    name,
+		"publisher-id": s.dev1Acct.AccountID(),
+		"timestamp":    time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	snapRev, err := s.storeSigning.Sign(asserts.SnapRevisionType, map[string]interface{}{
+		"snap-id":       name + "-id",
+		"snap-sha3-384": digest,
+		"snap-size":     fmt.Sprintf("%d", size),
+		"snap-revision": fmt.Sprintf("%d", rev),
+		"developer-id":  s.dev1Acct.AccountID(),
+		"timestamp":     time.Now().Format(time.RFC3339),
+	}, nil, "")
+	c.Assert(err, IsNil)
+	// out of order on purpose
+	s.writeAssertions(c, fmt.Sprintf("%s_%d.assert", name, rev), snapRev, snapDecl)
+
+	return hexDigest
+}
+
+func (s *offlineSuite) TestSnapInfo(c *C) {
+	s.addSnap(c, "foo", 1, "Foo", false)
+	digest := s.addSnap(c, "foo", 2, "Foo", false)
+
+	info, err := s.sto.SnapInfo(store.SnapSpec{Name: "foo"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(info.SnapName(), Equals, "foo")
+	c.Check(info.SnapID, Equals, "foo-id")
+	c.Check(info.Revision, Equals, snap.R(2))
+	c.Check(info.Version, Equals, "2.0")
+	c.Check(info.Channel, Equals, "stable")
+	c.Check(info.PublisherID, Equals, s.dev1Acct.AccountID())
+	c.Check(info.Publisher, Equals, "developer1")
+	c.Check(info.Sha3_384, Equals, digest)
+	c.Check(info.DownloadURL, Equals, "file://"+filepath.Join(s.dir, "foo_2.snap"))
+
+	info, err = s.sto.SnapInfo(store.SnapSpec{Name: "foo", Revision: snap.R(1)}, nil)
+	c.Assert(err, IsNil)
+	c.Check(info.Revision, Equals, snap.R(1))
+
+	_, err = s.sto.SnapInfo(store.SnapSpec{Name: "foo", Revision: snap.R(3)}, nil)
+	c.Check(err, Equals, store.ErrSnapNotFound)
+	_, err = s.sto.SnapInfo(store.SnapSpec{Name: "bar"}, nil)
+	c.Check(err, Equals, store.ErrSnapNotFound)
+}
+
+func (s *offlineSuite) TestUnassertedSnapsAreIgnored(c *C) {
+	s.addSnap(c, "foo", 1, "Foo", true)
+
+	_, err := s.sto.SnapInfo(store.SnapSpec{Name: "foo"}, nil)
+	c.Check(err, Equals, store.ErrSnapNotFound)
+}
+
+func (s *offlineSuite) TestUnverifiedAssertionsAreIgnored(c *C) {
+	// without the store account-key nothing checks out
+	s.writeAssertions(c, "base.assert", s.dev1Acct)
+	s.addSnap(c, "foo", 1, "Foo", false)
+
+	_, err := s.sto.SnapInfo(store.SnapSpec{Name: "foo"}, nil)
+	c.Check(err, Equals, store.ErrSnapNotFound)
+	_, err = s.sto.Assertion(asserts.SnapDeclarationType, []string{"16", "foo-id"}, nil)
+	c.Check(asserts.IsNotFound(err), Equals, true)
+}
+
+func (s *offlineSuite) TestFind(c *C) {
+	s.addSnap(c, "foo", 1, "Something", false)
+	s.addSnap(c, "bar", 1, "Like foo but better", false)
+	s.addSnap(c, "baz", 1, "Unrelated", false)
+
+	for _, t := range []struct {
+		search   store.Search
+		expected []string
+	}{
+		{store.Search{Query: "foo"}, []string{"bar", "foo"}},
+		{store.Search{Query: "FOO"}, []string{"bar", "foo"}},
+		{store.Search{Query: ""}, []string{"bar", "baz", "foo"}},
+		{store.Search{Query: "ba", Prefix: true}, []string{"bar", "baz"}},
+		{store.Search{Query: "foo", Section: "games"}, nil},
+		{store.Search{Query: "nothing"}, nil},
+	} {
+		infos, err := s.sto.Find(&t.search, nil)
+		c.Assert(err, IsNil)
+		var names []string
+		for _, info := range infos {
+			names = append(names, info.SnapName())
+		}
+		c.Check(names, DeepEquals, t.expected, Commentf("%+v", t.search))
+	}
+}
+
+func (s *offlineSuite) TestSnapActionInstall(c *C) {
+	s.addSnap(c, "foo", 1, "Foo", false)
+	s.addSnap(c, "foo", 2, "Foo", false)
+
+	infos, err := s.sto.SnapAction(context.TODO(), nil, []*store.SnapAction{
+		{Action: "install", Name: "foo", Channel: "beta"},
+		{Action: "install", Name: "bar"},
+	}, nil, nil)
+	c.Assert(infos, HasLen, 1)
+	c.Check(infos[0].Revision, Equals, snap.R(2))
+	c.Check(infos[0].Channel, Equals, "beta")
+	c.Check(err, DeepEquals, &store.SnapActionError{
+		Install: map[string]error{"bar": store.ErrSnapNotFound},
+	})
+
+	infos, err = s.sto.SnapAction(context.TODO(), nil, []*store.SnapAction{
+		{Action: "install", Name: "foo", Revision: snap.R(1)},
+	}, nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(infos, HasLen, 1)
+	c.Check(infos[0].Revision, Equals, snap.R(1))
+}
+
+func (s *offlineSuite) TestSnapActionRefresh(c *C) {
+	s.addSnap(c, "foo", 1, "Foo", false)
+	s.addSnap(c, "foo", 2, "Foo", false)
+	s.addSnap(c, "foo", 3, "Foo", false)
+
+	refresh := []*store.SnapAction{{Action: "refresh", SnapID: "foo-id"}}
+
+	infos, err := s.sto.SnapAction(context.TODO(), []*store.CurrentSnap{
+		{Name: "foo", SnapID: "foo-id", Revision: snap.R(1), TrackingChannel: "edge"},
+	}, refresh, nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(infos, HasLen, 1)
+	c.Check(infos[0].Revision, Equals, snap.R(3))
+	c.Check(infos[0].Channel, Equals, "edge")
+
+	// blocked revisions are skipped
+	infos, err = s.sto.SnapAction(context.TODO(), []*store.CurrentSnap{
+		{Name: "foo", SnapID: "foo-id", Revision: snap.R(1), Block: []snap.Revision{snap.R(3)}},
+	}, refresh, nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(infos, HasLen, 1)
+	c.Check(infos[0].Revision, Equals, snap.R(2))
+
+	infos, err = s.sto.SnapAction(context.TODO(), []*store.CurrentSnap{
+		{Name: "foo", SnapID: "foo-id", Revision: snap.R(3)},
+	}, refresh, nil, nil)
+	c.Check(infos, HasLen, 0)
+	c.Check(err, DeepEquals, &store.SnapActionError{
+		NoResults: true,
+		Refresh:   map[string]error{"foo": store.ErrNoUpdateAvailable},
+	})
+}
+
+func (s *offlineSuite) TestListRefresh(c *C) {
+	s.addSnap(c, "foo", 1, "Foo", false)
+	s.addSnap(c, "foo", 2, "Foo", false)
+	s.addSnap(c, "bar", 1, "Bar", false)
+
+	infos, err := s.sto.ListRefresh(context.TODO(), []*store.RefreshCandidate{
+		{SnapID: "foo-id", Revision: snap.R(1)},
+		{SnapID: "bar-id", Revision: snap.R(1)},
+		{SnapID: "baz-id", Revision: snap.R(1)},
+	}, nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(infos, HasLen, 1)
+	c.Check(infos[0].SnapName(), Equals, "foo")
+	c.Check(infos[0].Revision, Equals, snap.R(2))
+
+	_, err = s.sto.LookupRefresh(&store.RefreshCandidate{SnapID: "bar-id", Revision: snap.R(1)}, nil)
+	c.Check(err, Equals, store.ErrNoUpdateAvailable)
+}
+
+func (s *offlineSuite) TestDownload(c *C) {
+	digest := s.addSnap(c, "foo", 1, "Foo", false)
+
+	info, err := s.sto.SnapInfo(store.SnapSpec{Name: "foo"}, nil)
+	c.Assert(err, IsNil)
+
+	targetPath := filepath.Join(c.MkDir(), "dl", "foo_1.snap")
+	err = s.sto.Download(context.TODO(), "foo", targetPath, &info.DownloadInfo, nil, nil)
+	c.Assert(err, IsNil)
+	content, err := ioutil.ReadFile(filepath.Join(s.dir, "foo_1.snap"))
+	c.Assert(err, IsNil)
+	c.Check(targetPath, testutil.FileEquals, content)
+	c.Check(osutil.FileExists(store.PartialDownloadPath(targetPath)), Equals, false)
+
+	// the snap file changed behind our back
+	c.Assert(ioutil.WriteFile(filepath.Join(s.dir, "foo_1.snap"), []byte("something else"), 0644), IsNil)
+	otherTarget := filepath.Join(c.MkDir(), "foo_1.snap")
+	err = s.sto.Download(context.TODO(), "foo", otherTarget, &snap.DownloadInfo{Sha3_384: digest}, nil, nil)
+	c.Check(err, ErrorMatches, `sha3-384 mismatch for "foo": .*`)
+	c.Check(osutil.FileExists(otherTarget), Equals, false)
+
+	err = s.sto.Download(context.TODO(), "foo", otherTarget, &snap.DownloadInfo{Sha3_384: "unknown"}, nil, nil)
+	c.Check(err, Equals, store.ErrSnapNotFound)
+}
+
+func (s *offlineSuite) TestAssertion(c *C) {
+	s.addSnap(c, "foo", 1, "Foo", false)
+
+	a, err := s.sto.Assertion(asserts.SnapDeclarationType, []string{"16", "foo-id"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(a.(*asserts.SnapDeclaration).SnapName(), Equals, "foo")
+
+	a, err = s.sto.Assertion(asserts.AccountType, []string{s.dev1Acct.AccountID()}, nil)
+	c.Assert(err, IsNil)
+	c.Check(a.(*asserts.Account).Username(), Equals, "developer1")
+
+	_, err = s.sto.Assertion(asserts.SnapDeclarationType, []string{"16", "bar-id"}, nil)
+	c.Check(asserts.IsNotFound(err), Equals, true)
+}
+
+func (s *offlineSuite) TestReindexOnChanges(c *C) {
+	s.addSnap(c, "foo", 1, "Foo", false)
+
+	_, err := s.sto.SnapInfo(store.SnapSpec{Name: "foo"}, nil)
+	c.Assert(err, IsNil)
+	_, err = s.sto.SnapInfo(store.SnapSpec{Name: "bar"}, nil)
+	c.Assert(err, Equals, store.ErrSnapNotFound)
+
+	s.addSnap(c, "bar", 1, "Bar", false)
+	// make sure the change is noticed despite the mtime granularity
+	later := time.Now().Add(time.Minute)
+	c.Assert(os.Chtimes(s.dir, later, later), IsNil)
+
+	info, err := s.sto.SnapInfo(store.SnapSpec{Name: "bar"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(info.Revision, Equals, snap.R(1))
+}
+
+func (s *offlineSuite) TestWriteCatalogs(c *C) {
+	s.addSnap(c, "foo", 1, "Foo", false)
+
+	names := bytes.NewBuffer(nil)
+	adder := &testSnapAdder{}
+	err := s.sto.WriteCatalogs(context.TODO(), names, adder)
+	c.Assert(err, IsNil)
+	c.Check(names.String(), Equals, "foo\n")
+	c.Check(adder.added, DeepEquals, []string{"foo 1.0 Foo [foo]"})
+}
+
+type testSnapAdder struct {
+	added []string
+}
+
+func (a *testSnapAdder) AddSnap(snapName, version, summary string, commands []string) error {
+	a.added = append(a.added, fmt.Sprintf("%s %s %s %v", snapName, version, summary, commands))
+	return nil
+}
+
+func (s *offlineSuite) TestBuy(c *C) {
+	_, err := s.sto.Buy(&store.BuyOptions{}, nil)
+	c.Check(err, ErrorMatches, "cannot buy snaps from an offline store")
+	c.Check(s.sto.ReadyToBuy(nil), ErrorMatches, "cannot buy snaps from an offline store")
+}
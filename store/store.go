@@ -27,6 +27,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
@@ -107,8 +108,23 @@ type Config struct {
 	DetailFields []string
 	DeltaFormat  string
 
-	// CacheDownloads is the number of downloads that should be cached
-	CacheDownloads int
+	// CacheDownloadsSize is the maximum total size in bytes of the
+	// downloads that should be cached
+	CacheDownloadsSize int64
+
+	// DownloadChunks is the number of parallel range requests used to
+	// download big snaps, which are downloaded with a single request
+	// if it's less than 2
+	DownloadChunks int
+
+	// PeerCacheDir is a download cache directory shared with other
+	// machines, e.g. over the LAN, that is consulted before downloading
+	// and shares what gets downloaded if writable
+	PeerCacheDir string
+	// DownloadMirror is the base URL of an HTTP mirror serving snaps by
+	// their sha3-384 digest, as laid out in the download cache
+	// directory, which is tried before the store
+	DownloadMirror *url.URL
 }
 
 // setBaseURL updates the store API's base URL in the Config. Must not be used
@@ -151,7 +167,8 @@ type Store struct {
 	mu                sync.Mutex
 	suggestedCurrency string
 
-	cacher downloadCache
+	cacher     downloadCache
+	peerCacher downloadCache
 }
 
 func respToError(resp *http.Response, msg string) error {
@@ -241,6 +258,19 @@ func assertsURL() (*url.URL, error) {
 	return nil, nil
 }
 
+func downloadMirrorURL() (*url.URL, error) {
+	if s := os.Getenv("SNAPD_DOWNLOAD_MIRROR"); s != "" {
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SNAPD_DOWNLOAD_MIRROR: %s", err)
+		}
+		return u, nil
+	}
+
+	// nil means no mirror
+	return nil, nil
+}
+
 func authLocation() string {
 	if useStaging() {
 		return "login.staging.ubuntu.com"
@@ -264,7 +294,9 @@ func storeDeveloperURL() string {
 	return defaultStoreDeveloperURL
 }
 
-var defaultConfig = Config{}
+var defaultConfig = Config{
+	DownloadChunks: defaultDownloadChunks,
+}
 
 // DefaultConfig returns a copy of the default configuration ready to be adapted.
 func DefaultConfig() *Config {
@@ -284,6 +316,13 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
+
+	mirror, err := downloadMirrorURL()
+	if err != nil {
+		logger.Noticef("Ignoring download mirror: %v", err)
+	}
+	defaultConfig.DownloadMirror = mirror
+	defaultConfig.PeerCacheDir = os.Getenv("SNAPD_PEER_CACHE_DIR")
 }
 
 type searchResults struct {
@@ -345,7 +384,12 @@ func New(cfg *Config, authContext auth.AuthContext) *Store {
 			MayLogBody: true,
 		}),
 	}
-	store.SetCacheDownloads(cfg.CacheDownloads)
+	store.SetCacheDownloadsSize(cfg.CacheDownloadsSize)
+	if cfg.PeerCacheDir != "" {
+		store.peerCacher = &peerCache{cacheDir: cfg.PeerCacheDir}
+	} else {
+		store.peerCacher = &nullCache{}
+	}
 
 	return store
 }
@@ -1467,6 +1511,11 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 	if err := s.cacher.Get(downloadInfo.Sha3_384, targetPath); err == nil {
 		return nil
 	}
+	if downloadInfo.Sha3_384 != "" {
+		if err := s.peerCacher.Get(downloadInfo.Sha3_384, targetPath); err == nil {
+			return s.cacher.Put(downloadInfo.Sha3_384, targetPath)
+		}
+	}
 
 	if useDeltas() {
 		logger.Debugf("Available deltas returned by store: %v", downloadInfo.Deltas)
@@ -1481,8 +1530,7 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 		}
 	}
 
-	partialPath := targetPath + ".partial"
-	w, err := os.OpenFile(partialPath, os.O_RDWR|os.O_CREATE, 0644)
+	w, err := os.OpenFile(PartialDownloadPath(targetPath), os.O_RDWR|os.O_CREATE, 0644)
 	if err != nil {
 		return err
 	}
@@ -1494,8 +1542,9 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 		if cerr := w.Close(); cerr != nil && err == nil {
 			err = cerr
 		}
-		if err != nil {
+		if err != nil && !keepPartialDownload(w.Name(), err) {
 			os.Remove(w.Name())
+			os.Remove(chunksProgressPath(w.Name()))
 		}
 	}()
 
@@ -1509,8 +1558,11 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 		url = downloadInfo.DownloadURL
 	}
 
-	if downloadInfo.Size == 0 || resume < downloadInfo.Size {
-		err = download(ctx, name, downloadInfo.Sha3_384, url, user, s, w, resume, pbar)
+	// a partial download in chunks can have holes anywhere, so its size
+	// says nothing about how much of it was downloaded
+	chunked := downloadInfo.Size > 0 && osutil.FileExists(chunksProgressPath(w.Name()))
+	if downloadInfo.Size == 0 || resume < downloadInfo.Size || chunked {
+		err = s.downloadFromSources(ctx, name, url, downloadInfo, user, w, resume, pbar)
 	} else {
 		// we're done! check the hash though
 		h := crypto.SHA3_384.New()
@@ -1551,9 +1603,77 @@ func (s *Store) Download(ctx context.Context, name string, targetPath string, do
 		return err
 	}
 
+	if err := s.peerCacher.Put(downloadInfo.Sha3_384, targetPath); err != nil {
+		logger.Noticef("Cannot share %s through the peer cache: %v", name, err)
+	}
 	return s.cacher.Put(downloadInfo.Sha3_384, targetPath)
 }
 
+// PartialDownloadPath returns the path where the download of targetPath
+// is kept while in progress, and left behind if interrupted so that it
+// can be resumed.
+func PartialDownloadPath(targetPath string) string {
+	return targetPath + ".partial"
+}
+
+// keepPartialDownload returns whether the partial download at the given
+// path that failed with err is worth resuming.
+func keepPartialDownload(partialPath string, err error) bool {
+	if _, ok := err.(HashError); ok {
+		return false
+	}
+	fi, serr := os.Stat(partialPath)
+	return serr == nil && fi.Size() > 0
+}
+
+// the number of parallel range requests used by default to download big
+// snaps, and the minimum size of the snaps downloaded that way
+var (
+	defaultDownloadChunks        = 4
+	minChunkedDownloadSize int64 = 64 * 1024 * 1024
+)
+
+// downloadFromSources downloads the snap into w, resuming at the given
+// offset. It tries the download mirror first, if any, and then the
+// store at url, in parallel chunks if the snap is big enough or if w
+// holds an interrupted download in chunks.
+func (s *Store) downloadFromSources(ctx context.Context, name, url string, downloadInfo *snap.DownloadInfo, user *auth.UserState, w *os.File, resume int64, pbar progress.Meter) error {
+	resumeChunks := osutil.FileExists(chunksProgressPath(w.Name()))
+	if mirror := s.cfg.DownloadMirror; mirror != nil && downloadInfo.Sha3_384 != "" && !resumeChunks {
+		err := downloadFromMirror(ctx, name, downloadInfo.Sha3_384, mirror, w, resume, pbar)
+		if err == nil || cancelled(ctx) {
+			return err
+		}
+		logger.Noticef("Cannot download %s from mirror %s, using the store instead: %v", name, mirror, err)
+		if _, ok := err.(HashError); ok {
+			// the mirror has the wrong content, start over
+			if err := w.Truncate(0); err != nil {
+				return err
+			}
+		}
+		if resume, err = w.Seek(0, os.SEEK_END); err != nil {
+			return err
+		}
+	}
+
+	chunks := s.cfg.DownloadChunks
+	if resumeChunks || (chunks > 1 && resume == 0 && downloadInfo.Size >= minChunkedDownloadSize) {
+		err := downloadChunks(ctx, name, downloadInfo.Sha3_384, url, user, s, w, downloadInfo.Size, chunks, pbar)
+		if err == nil || cancelled(ctx) {
+			return err
+		}
+		if _, ok := err.(HashError); ok {
+			return err
+		}
+		logger.Noticef("Cannot download %s in parallel chunks, resuming sequentially: %v", name, err)
+		if resume, err = w.Seek(0, os.SEEK_END); err != nil {
+			return err
+		}
+	}
+
+	return download(ctx, name, downloadInfo.Sha3_384, url, user, s, w, resume, pbar)
+}
+
 func downloadOptions(storeURL *url.URL, cdnHeader string) *requestOptions {
 	reqOptions := requestOptions{
 		Method:       "GET",
@@ -1668,6 +1788,311 @@ var download = func(ctx context.Context, name, sha3_384, downloadURL string, use
 	return finalErr
 }
 
+// downloadFromMirror downloads the snap with the given sha3-384 digest
+// from the mirror into w, resuming at the given offset. Unlike the store,
+// the mirror gets no authorization.
+func downloadFromMirror(ctx context.Context, name, sha3_384 string, mirror *url.URL, w *os.File, resume int64, pbar progress.Meter) error {
+	base := *mirror
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+	mirrorURL := base.ResolveReference(&url.URL{Path: sha3_384})
+
+	req, err := http.NewRequest("GET", mirrorURL.String(), nil)
+	if err != nil {
+		return err
+	}
+	h := crypto.SHA3_384.New()
+	if resume > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", resume))
+		// seed the sha3 with the already local file
+		if _, err := w.Seek(0, os.SEEK_SET); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(h, w, resume); err != nil {
+			return err
+		}
+	}
+
+	resp, err := ctxhttp.Do(ctx, httputil.NewHTTPClient(nil), req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case 206: // Partial Content
+	case 200: // OK
+		if resume > 0 {
+			// the mirror ignored the range, start over
+			if err := w.Truncate(0); err != nil {
+				return err
+			}
+			if _, err := w.Seek(0, os.SEEK_SET); err != nil {
+				return err
+			}
+			h.Reset()
+		}
+	default:
+		return &DownloadError{Code: resp.StatusCode, URL: resp.Request.URL}
+	}
+
+	if pbar == nil {
+		pbar = progress.Null
+	}
+	pbar.Start(name, float64(resp.ContentLength))
+	_, err = io.Copy(io.MultiWriter(w, h, pbar), resp.Body)
+	pbar.Finished()
+	if err != nil {
+		return err
+	}
+
+	actualSha3 := fmt.Sprintf("%x", h.Sum(nil))
+	if sha3_384 != actualSha3 {
+		return HashError{name, actualSha3, sha3_384}
+	}
+	return nil
+}
+
+// chunkProgress is how far the download of the chunk of a snap from
+// Start up to End got, as recorded next to the partial download.
+type chunkProgress struct {
+	Start   int64 `json:"start"`
+	End     int64 `json:"end"`
+	Written int64 `json:"written"`
+}
+
+// chunksProgressPath returns the path where the progress of the chunks
+// of the given partial download is recorded while they are downloaded.
+func chunksProgressPath(partialPath string) string {
+	return partialPath + ".chunks"
+}
+
+// loadChunksProgress returns the progress recorded for the chunks of the
+// given partial download, or nil if there is none for a snap of the
+// given size.
+func loadChunksProgress(partialPath string, size int64) []chunkProgress {
+	data, err := ioutil.ReadFile(chunksProgressPath(partialPath))
+	if err != nil {
+		return nil
+	}
+	var progress []chunkProgress
+	if err := json.Unmarshal(data, &progress); err != nil {
+		return nil
+	}
+	// the chunks must cover the snap exactly
+	var next int64
+	for _, p := range progress {
+		if p.Start != next || p.End < p.Start || p.Written < 0 || p.Written > p.End-p.Start {
+			return nil
+		}
+		next = p.End
+	}
+	if len(progress) == 0 || next != size {
+		return nil
+	}
+	return progress
+}
+
+func saveChunksProgress(partialPath string, progress []chunkProgress) error {
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return err
+	}
+	return osutil.AtomicWriteFile(chunksProgressPath(partialPath), data, 0644, 0)
+}
+
+// how many bytes are downloaded in chunks between recordings of their
+// progress
+var chunksProgressSaveInterval int64 = 4 * 1024 * 1024
+
+// chunksState is the state shared by the chunks of a download.
+type chunksState struct {
+	f    *os.File
+	pbar progress.Meter
+
+	// protects the rest, and the progress meter
+	mu       sync.Mutex
+	progress []chunkProgress
+	// written since the progress was last saved
+	unsaved int64
+}
+
+// save syncs the partial download before recording the progress of the
+// chunks, so that a crash cannot leave recorded bytes unwritten.
+func (cs *chunksState) save() error {
+	cs.unsaved = 0
+	if err := cs.f.Sync(); err != nil {
+		return err
+	}
+	return saveChunksProgress(cs.f.Name(), cs.progress)
+}
+
+// chunkWriter writes a chunk of a download where its progress says,
+// keeping track of it.
+type chunkWriter struct {
+	state  *chunksState
+	chunk  int
+	offset int64
+}
+
+func (cw *chunkWriter) Write(p []byte) (int, error) {
+	cs := cw.state
+	n, err := cs.f.WriteAt(p, cw.offset)
+	cw.offset += int64(n)
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.progress[cw.chunk].Written += int64(n)
+	cs.unsaved += int64(n)
+	cs.pbar.Write(p[:n])
+	if err == nil && cs.unsaved >= chunksProgressSaveInterval {
+		err = cs.save()
+	}
+	return n, err
+}
+
+// downloadChunks downloads the size bytes of the snap at downloadURL
+// into w with the given number of parallel range requests, or resumes
+// the chunks of an earlier such download from their recorded progress.
+// If cancelled the progress is kept for resuming the chunks later, on
+// other failures w is truncated to what was downloaded contiguously
+// from its start, so that the download can be resumed from there.
+func downloadChunks(ctx context.Context, name, sha3_384, downloadURL string, user *auth.UserState, s *Store, w *os.File, size int64, chunks int, pbar progress.Meter) error {
+	storeURL, err := url.Parse(downloadURL)
+	if err != nil {
+		return err
+	}
+
+	cdnHeader, err := s.cdnHeader()
+	if err != nil {
+		return err
+	}
+
+	chunksProgress := loadChunksProgress(w.Name(), size)
+	if chunksProgress == nil {
+		// nothing that might have been left can be trusted
+		if err := w.Truncate(0); err != nil {
+			return err
+		}
+		if chunks < 1 {
+			chunks = 1
+		}
+		chunkSize := size / int64(chunks)
+		chunksProgress = make([]chunkProgress, chunks)
+		for i := range chunksProgress {
+			chunksProgress[i].Start = int64(i) * chunkSize
+			chunksProgress[i].End = chunksProgress[i].Start + chunkSize
+		}
+		chunksProgress[chunks-1].End = size
+	}
+	cs := &chunksState{f: w, pbar: pbar, progress: chunksProgress}
+	if err := cs.save(); err != nil {
+		return err
+	}
+
+	if cs.pbar == nil {
+		cs.pbar = progress.Null
+	}
+	cs.pbar.Start(name, float64(size))
+	defer cs.pbar.Finished()
+	var done int64
+	for _, p := range cs.progress {
+		done += p.Written
+	}
+	cs.pbar.Set(float64(done))
+
+	// stop all the chunks as soon as one fails
+	chunksCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, len(cs.progress))
+	started := 0
+	for i, p := range cs.progress {
+		if p.Written == p.End-p.Start {
+			continue
+		}
+		started++
+		cw := &chunkWriter{state: cs, chunk: i, offset: p.Start + p.Written}
+		go func(start, end int64) {
+			errs <- downloadChunk(chunksCtx, name, storeURL, cdnHeader, user, s, cw, start, end)
+		}(cw.offset, p.End)
+	}
+	for i := 0; i < started; i++ {
+		if cerr := <-errs; cerr != nil && err == nil {
+			err = cerr
+			cancel()
+		}
+	}
+
+	if err != nil {
+		if cancelled(ctx) {
+			if serr := cs.save(); serr != nil {
+				logger.Noticef("Cannot record the progress of downloading %s: %v", name, serr)
+			}
+			return err
+		}
+		var prefix int64
+		for _, p := range cs.progress {
+			prefix += p.Written
+			if p.Written < p.End-p.Start {
+				break
+			}
+		}
+		if terr := w.Truncate(prefix); terr != nil {
+			return terr
+		}
+		os.Remove(chunksProgressPath(w.Name()))
+		return err
+	}
+	os.Remove(chunksProgressPath(w.Name()))
+
+	h := crypto.SHA3_384.New()
+	if _, err := w.Seek(0, os.SEEK_SET); err != nil {
+		return err
+	}
+	if _, err := io.Copy(h, w); err != nil {
+		return err
+	}
+	actualSha3 := fmt.Sprintf("%x", h.Sum(nil))
+	if sha3_384 != "" && sha3_384 != actualSha3 {
+		return HashError{name, actualSha3, sha3_384}
+	}
+	return nil
+}
+
+// downloadChunk downloads the bytes from start up to end of the snap at
+// storeURL into w with a range request.
+func downloadChunk(ctx context.Context, name string, storeURL *url.URL, cdnHeader string, user *auth.UserState, s *Store, w io.Writer, start, end int64) error {
+	reqOptions := downloadOptions(storeURL, cdnHeader)
+	reqOptions.ExtraHeaders["Range"] = fmt.Sprintf("bytes=%d-%d", start, end-1)
+
+	resp, err := s.doRequest(ctx, httputil.NewHTTPClient(nil), reqOptions, user)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case 206: // Partial Content
+	case 200: // OK
+		return fmt.Errorf("cannot download %s in chunks: range requests not supported", name)
+	case 402: // Payment Required
+		return fmt.Errorf("please buy %s before installing it.", name)
+	default:
+		return &DownloadError{Code: resp.StatusCode, URL: resp.Request.URL}
+	}
+
+	n, err := io.Copy(w, io.LimitReader(resp.Body, end-start))
+	if err != nil {
+		return err
+	}
+	if n != end-start {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
 // downloadDelta downloads the delta for the preferred format, returning the path.
 func (s *Store) downloadDelta(deltaName string, downloadInfo *snap.DownloadInfo, w io.ReadWriteSeeker, pbar progress.Meter, user *auth.UserState) error {
 
@@ -2032,14 +2457,14 @@ func (s *Store) ReadyToBuy(user *auth.UserState) error {
 	}
 }
 
-func (s *Store) CacheDownloads() int {
-	return s.cfg.CacheDownloads
+func (s *Store) CacheDownloadsSize() int64 {
+	return s.cfg.CacheDownloadsSize
 }
 
-func (s *Store) SetCacheDownloads(fileCount int) {
-	s.cfg.CacheDownloads = fileCount
-	if fileCount > 0 {
-		s.cacher = NewCacheManager(dirs.SnapDownloadCacheDir, fileCount)
+func (s *Store) SetCacheDownloadsSize(size int64) {
+	s.cfg.CacheDownloadsSize = size
+	if size > 0 {
+		s.cacher = NewCacheManager(dirs.SnapDownloadCacheDir, size)
 	} else {
 		s.cacher = &nullCache{}
 	}
@@ -2048,7 +2473,10 @@ func (s *Store) SetCacheDownloads(fileCount int) {
 // snap action: install/refresh
 
 type CurrentSnap struct {
-	Name             string
+	Name string
+	// InstanceName is the name of the installed instance of the
+	// snap, it differs from Name for parallel instances
+	InstanceName     string
 	SnapID           string
 	Revision         snap.Revision
 	TrackingChannel  string
@@ -2057,6 +2485,22 @@ type CurrentSnap struct {
 	Block            []snap.Revision
 }
 
+func (cur *CurrentSnap) instanceName() string {
+	if cur.InstanceName != "" {
+		return cur.InstanceName
+	}
+	return cur.Name
+}
+
+// snapActionInstanceKey returns the instance-key to use for the snap
+// with the given snap-id and instance name in install/refresh requests.
+func snapActionInstanceKey(snapID, instanceName string) string {
+	if _, instanceKey := snap.SplitInstanceName(instanceName); instanceKey != "" {
+		return instanceName
+	}
+	return snapID
+}
+
 type currentSnapV2JSON struct {
 	SnapID           string     `json:"snap-id"`
 	InstanceKey      string     `json:"instance-key"`
@@ -2074,13 +2518,16 @@ const (
 )
 
 type SnapAction struct {
-	Action   string
-	Name     string
-	SnapID   string
-	Channel  string
-	Revision snap.Revision
-	Flags    SnapActionFlags
-	Epoch    *snap.Epoch
+	Action string
+	Name   string
+	// InstanceName is the name of the instance to refresh, for
+	// parallel instances of a snap
+	InstanceName string
+	SnapID       string
+	Channel      string
+	Revision     snap.Revision
+	Flags        SnapActionFlags
+	Epoch        *snap.Epoch
 }
 
 type snapActionJSON struct {
@@ -2174,9 +2621,9 @@ func (s *Store) SnapAction(ctx context.Context, currentSnaps []*CurrentSnap, act
 
 func (s *Store) snapAction(ctx context.Context, currentSnaps []*CurrentSnap, actions []*SnapAction, user *auth.UserState, opts *RefreshOptions) ([]*snap.Info, error) {
 
-	// TODO: the store already requires instance-key but doesn't
-	// yet support repeating in context or sending actions for the
-	// same snap-id, for now we keep instance-key handling internal
+	// snaps are told apart by their snap-id as instance-key, parallel
+	// instances of a snap share its snap-id so their instance name is
+	// used instead, which cannot be mistaken for a snap-id
 
 	curSnaps := make(map[string]*CurrentSnap, len(currentSnaps))
 	curSnapJSONs := make([]*currentSnapV2JSON, len(currentSnaps))
@@ -2184,7 +2631,8 @@ func (s *Store) snapAction(ctx context.Context, currentSnaps []*CurrentSnap, act
 		if curSnap.SnapID == "" || curSnap.Name == "" || curSnap.Revision.Unset() {
 			return nil, fmt.Errorf("internal error: invalid current snap information")
 		}
-		curSnaps[curSnap.SnapID] = curSnap
+		instanceKey := snapActionInstanceKey(curSnap.SnapID, curSnap.InstanceName)
+		curSnaps[instanceKey] = curSnap
 		channel := curSnap.TrackingChannel
 		if channel == "" {
 			channel = "stable"
@@ -2195,7 +2643,7 @@ func (s *Store) snapAction(ctx context.Context, currentSnaps []*CurrentSnap, act
 		}
 		curSnapJSONs[i] = &currentSnapV2JSON{
 			SnapID:           curSnap.SnapID,
-			InstanceKey:      curSnap.SnapID,
+			InstanceKey:      instanceKey,
 			Revision:         curSnap.Revision.N,
 			TrackingChannel:  channel,
 			IgnoreValidation: curSnap.IgnoreValidation,
@@ -2216,7 +2664,7 @@ func (s *Store) snapAction(ctx context.Context, currentSnaps []*CurrentSnap, act
 			ignoreValidation = &f
 		}
 
-		instanceKey := a.SnapID
+		instanceKey := snapActionInstanceKey(a.SnapID, a.InstanceName)
 		if a.Action == "install" {
 			installNum++
 			instanceKey = fmt.Sprintf("install-%d", installNum)
@@ -2289,7 +2737,7 @@ func (s *Store) snapAction(ctx context.Context, currentSnaps []*CurrentSnap, act
 				}
 			} else {
 				if cur := curSnaps[res.InstanceKey]; cur != nil {
-					refreshErrors[cur.Name] = translateSnapActionError("refresh", res.Error.Code, res.Error.Message)
+					refreshErrors[cur.instanceName()] = translateSnapActionError("refresh", res.Error.Code, res.Error.Message)
 					continue
 				}
 			}
@@ -2302,15 +2750,16 @@ func (s *Store) snapAction(ctx context.Context, currentSnaps []*CurrentSnap, act
 		}
 		snapInfo.Channel = res.EffectiveChannel
 		if res.Result == "refresh" {
-			cur := curSnaps[res.SnapID]
+			cur := curSnaps[res.InstanceKey]
 			if cur == nil {
 				return nil, fmt.Errorf("unexpected invalid install/refresh API result: unexpected refresh")
 			}
 			rrev := snap.R(res.Snap.Revision)
 			if rrev == cur.Revision || findRev(rrev, cur.Block) {
-				refreshErrors[cur.Name] = ErrNoUpdateAvailable
+				refreshErrors[cur.instanceName()] = ErrNoUpdateAvailable
 				continue
 			}
+			_, snapInfo.InstanceKey = snap.SplitInstanceName(cur.InstanceName)
 		}
 		snaps = append(snaps, snapInfo)
 	}
@@ -33,7 +33,9 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -121,6 +123,27 @@ func (suite *configTestSuite) TestSetBaseURLAssertsURLBadEnviron(c *C) {
 	c.Check(err, ErrorMatches, "invalid SNAPPY_FORCE_SAS_URL: parse ://example.com: missing protocol scheme")
 }
 
+func (suite *configTestSuite) TestDownloadMirrorURL(c *C) {
+	u, err := downloadMirrorURL()
+	c.Assert(err, IsNil)
+	c.Check(u, IsNil)
+
+	c.Assert(os.Setenv("SNAPD_DOWNLOAD_MIRROR", "https://mirror.local/"), IsNil)
+	defer os.Setenv("SNAPD_DOWNLOAD_MIRROR", "")
+	u, err = downloadMirrorURL()
+	c.Assert(err, IsNil)
+	c.Check(u.String(), Equals, "https://mirror.local/")
+}
+
+func (suite *configTestSuite) TestDownloadMirrorURLBadEnviron(c *C) {
+	c.Assert(os.Setenv("SNAPD_DOWNLOAD_MIRROR", "://example.com"), IsNil)
+	defer os.Setenv("SNAPD_DOWNLOAD_MIRROR", "")
+
+	u, err := downloadMirrorURL()
+	c.Check(err, ErrorMatches, "invalid SNAPD_DOWNLOAD_MIRROR: parse ://example.com: missing protocol scheme")
+	c.Check(u, IsNil)
+}
+
 const (
 	// Store API paths/patterns.
 	authNoncesPath     = "/api/v1/snaps/auth/nonces"
@@ -5377,6 +5400,339 @@ func (s *storeTestSuite) TestDownloadCacheMiss(c *C) {
 	c.Check(obs.puts, DeepEquals, []string{fmt.Sprintf("the-snaps-sha3_384:%s", path)})
 }
 
+func (s *storeTestSuite) TestDownloadPeerCacheHit(c *C) {
+	content := "snap content"
+	sha3_384 := fmt.Sprintf("%x", sha3.Sum384([]byte(content)))
+	peerDir := c.MkDir()
+	c.Assert(ioutil.WriteFile(filepath.Join(peerDir, sha3_384), []byte(content), 0644), IsNil)
+
+	obs := &cacheObserver{inCache: map[string]bool{}}
+	sto := New(&Config{PeerCacheDir: peerDir}, nil)
+	sto.cacher = obs
+
+	download = func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter) error {
+		c.Fatalf("download should not be called when results come from the peer cache")
+		return nil
+	}
+
+	snap := &snap.Info{}
+	snap.Sha3_384 = sha3_384
+
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	err := sto.Download(context.TODO(), "foo", path, &snap.DownloadInfo, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(path, testutil.FileEquals, content)
+	// what comes from the peer cache is cached locally too
+	c.Check(obs.puts, DeepEquals, []string{fmt.Sprintf("%s:%s", sha3_384, path)})
+}
+
+func (s *storeTestSuite) TestDownloadPeerCacheBadContent(c *C) {
+	content := "snap content"
+	sha3_384 := fmt.Sprintf("%x", sha3.Sum384([]byte(content)))
+	peerDir := c.MkDir()
+	c.Assert(ioutil.WriteFile(filepath.Join(peerDir, sha3_384), []byte("something else"), 0644), IsNil)
+
+	sto := New(&Config{PeerCacheDir: peerDir}, nil)
+
+	downloadWasCalled := false
+	download = func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter) error {
+		downloadWasCalled = true
+		_, err := w.Write([]byte(content))
+		return err
+	}
+
+	snap := &snap.Info{}
+	snap.Sha3_384 = sha3_384
+	snap.Size = int64(len(content))
+
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	err := sto.Download(context.TODO(), "foo", path, &snap.DownloadInfo, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(downloadWasCalled, Equals, true)
+	c.Check(path, testutil.FileEquals, content)
+	c.Check(osutil.FileExists(path+".peer"), Equals, false)
+}
+
+func (s *storeTestSuite) TestDownloadSharesThroughPeerCache(c *C) {
+	content := "snap content"
+	sha3_384 := fmt.Sprintf("%x", sha3.Sum384([]byte(content)))
+	peerDir := c.MkDir()
+
+	sto := New(&Config{PeerCacheDir: peerDir}, nil)
+
+	download = func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter) error {
+		_, err := w.Write([]byte(content))
+		return err
+	}
+
+	snap := &snap.Info{}
+	snap.Sha3_384 = sha3_384
+	snap.Size = int64(len(content))
+
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	err := sto.Download(context.TODO(), "foo", path, &snap.DownloadInfo, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(filepath.Join(peerDir, sha3_384), testutil.FileEquals, content)
+}
+
+func (s *storeTestSuite) TestDownloadKeepsPartialForResume(c *C) {
+	download = func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter) error {
+		w.Write([]byte("partial"))
+		return fmt.Errorf("connection lost")
+	}
+
+	snap := &snap.Info{}
+	snap.AnonDownloadURL = "anon-url"
+	snap.Size = 100
+
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	err := s.store.Download(context.TODO(), "foo", path, &snap.DownloadInfo, nil, nil)
+	c.Assert(err, ErrorMatches, "connection lost")
+	c.Check(PartialDownloadPath(path), testutil.FileEquals, "partial")
+}
+
+func (s *storeTestSuite) TestDownloadFromMirror(c *C) {
+	content := "snap content from the mirror"
+	sha3_384 := fmt.Sprintf("%x", sha3.Sum384([]byte(content)))
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, Equals, "/mirror/"+sha3_384)
+		// the mirror gets no authorization
+		c.Check(r.Header.Get("Authorization"), Equals, "")
+		c.Check(r.Header.Get("Range"), Equals, "bytes=5-")
+		w.WriteHeader(206)
+		io.WriteString(w, content[5:])
+	}))
+	defer mockServer.Close()
+
+	mirror, err := url.Parse(mockServer.URL + "/mirror")
+	c.Assert(err, IsNil)
+	sto := New(&Config{DownloadMirror: mirror}, nil)
+
+	download = func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter) error {
+		c.Fatalf("the store should not be used when the mirror has the snap")
+		return nil
+	}
+
+	snap := &snap.Info{}
+	snap.AnonDownloadURL = "anon-url"
+	snap.Sha3_384 = sha3_384
+	snap.Size = int64(len(content))
+
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	c.Assert(ioutil.WriteFile(PartialDownloadPath(path), []byte(content[:5]), 0644), IsNil)
+	err = sto.Download(context.TODO(), "foo", path, &snap.DownloadInfo, nil, s.user)
+	c.Assert(err, IsNil)
+	c.Check(path, testutil.FileEquals, content)
+}
+
+func (s *storeTestSuite) TestDownloadFromMirrorFallsBackToStore(c *C) {
+	content := "snap content"
+	sha3_384 := fmt.Sprintf("%x", sha3.Sum384([]byte(content)))
+
+	for _, mirrorContent := range []string{"", "wrong content"} {
+		mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if mirrorContent == "" {
+				w.WriteHeader(404)
+				return
+			}
+			io.WriteString(w, mirrorContent)
+		}))
+
+		mirror, err := url.Parse(mockServer.URL)
+		c.Assert(err, IsNil)
+		sto := New(&Config{DownloadMirror: mirror}, nil)
+
+		download = func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter) error {
+			c.Check(resume, Equals, int64(0))
+			_, err := w.Write([]byte(content))
+			return err
+		}
+
+		snap := &snap.Info{}
+		snap.AnonDownloadURL = "anon-url"
+		snap.Sha3_384 = sha3_384
+		snap.Size = int64(len(content))
+
+		path := filepath.Join(c.MkDir(), "downloaded-file")
+		err = sto.Download(context.TODO(), "foo", path, &snap.DownloadInfo, nil, nil)
+		c.Assert(err, IsNil)
+		c.Check(path, testutil.FileEquals, content)
+
+		mockServer.Close()
+	}
+}
+
+func (s *storeTestSuite) TestDownloadChunks(c *C) {
+	restore := mockMinChunkedDownloadSize(10)
+	defer restore()
+
+	content := strings.Repeat("0123456789", 10) + "xyz"
+	sha3_384 := fmt.Sprintf("%x", sha3.Sum384([]byte(content)))
+
+	var mu sync.Mutex
+	var ranges []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+	}))
+	defer mockServer.Close()
+
+	sto := New(&Config{DownloadChunks: 4}, nil)
+
+	download = func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter) error {
+		c.Fatalf("the snap should be downloaded in chunks")
+		return nil
+	}
+
+	snap := &snap.Info{}
+	snap.AnonDownloadURL = mockServer.URL
+	snap.Sha3_384 = sha3_384
+	snap.Size = int64(len(content))
+
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	err := sto.Download(context.TODO(), "foo", path, &snap.DownloadInfo, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(path, testutil.FileEquals, content)
+
+	sort.Strings(ranges)
+	c.Check(ranges, DeepEquals, []string{"bytes=0-24", "bytes=25-49", "bytes=50-74", "bytes=75-102"})
+}
+
+func (s *storeTestSuite) TestDownloadChunksFailureResumesSequentially(c *C) {
+	restore := mockMinChunkedDownloadSize(10)
+	defer restore()
+
+	content := strings.Repeat("0123456789", 10)
+	sha3_384 := fmt.Sprintf("%x", sha3.Sum384([]byte(content)))
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") == "bytes=50-74" {
+			w.WriteHeader(500)
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+	}))
+	defer mockServer.Close()
+
+	sto := New(&Config{DownloadChunks: 4}, nil)
+
+	download = func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter) error {
+		// the first two chunks are kept, unless cancelled
+		// before finishing
+		c.Check(resume == 0 || resume == 25 || resume == 50, Equals, true, Commentf("resume: %d", resume))
+		_, err := io.WriteString(w, content[resume:])
+		return err
+	}
+
+	snap := &snap.Info{}
+	snap.AnonDownloadURL = mockServer.URL
+	snap.Sha3_384 = sha3_384
+	snap.Size = int64(len(content))
+
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	err := sto.Download(context.TODO(), "foo", path, &snap.DownloadInfo, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(path, testutil.FileEquals, content)
+}
+
+func (s *storeTestSuite) TestDownloadChunksResumesChunksWithHoles(c *C) {
+	content := strings.Repeat("0123456789", 10)
+	sha3_384 := fmt.Sprintf("%x", sha3.Sum384([]byte(content)))
+
+	var mu sync.Mutex
+	var ranges []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+	}))
+	defer mockServer.Close()
+
+	// chunked downloads are not even enabled anymore
+	sto := New(&Config{}, nil)
+
+	download = func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter) error {
+		c.Fatalf("the chunks should be resumed")
+		return nil
+	}
+
+	snap := &snap.Info{}
+	snap.AnonDownloadURL = mockServer.URL
+	snap.Sha3_384 = sha3_384
+	snap.Size = int64(len(content))
+
+	// an interrupted download in chunks, with a hole in the second
+	// one and the last one not started
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	partial := content[:35] + strings.Repeat("\x00", 15) + content[50:75] + strings.Repeat("\x00", 25)
+	c.Assert(ioutil.WriteFile(PartialDownloadPath(path), []byte(partial), 0644), IsNil)
+	err := saveChunksProgress(PartialDownloadPath(path), []chunkProgress{
+		{Start: 0, End: 25, Written: 25},
+		{Start: 25, End: 50, Written: 10},
+		{Start: 50, End: 75, Written: 25},
+		{Start: 75, End: 100, Written: 0},
+	})
+	c.Assert(err, IsNil)
+
+	err = sto.Download(context.TODO(), "foo", path, &snap.DownloadInfo, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(path, testutil.FileEquals, content)
+	c.Check(osutil.FileExists(chunksProgressPath(PartialDownloadPath(path))), Equals, false)
+
+	sort.Strings(ranges)
+	c.Check(ranges, DeepEquals, []string{"bytes=35-49", "bytes=75-99"})
+}
+
+func (s *storeTestSuite) TestDownloadChunksRestartsWithInvalidProgress(c *C) {
+	restore := mockMinChunkedDownloadSize(10)
+	defer restore()
+
+	content := strings.Repeat("0123456789", 10)
+	sha3_384 := fmt.Sprintf("%x", sha3.Sum384([]byte(content)))
+
+	var mu sync.Mutex
+	var ranges []string
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+		http.ServeContent(w, r, "", time.Time{}, strings.NewReader(content))
+	}))
+	defer mockServer.Close()
+
+	sto := New(&Config{DownloadChunks: 2}, nil)
+
+	download = func(ctx context.Context, name, sha3, url string, user *auth.UserState, s *Store, w io.ReadWriteSeeker, resume int64, pbar progress.Meter) error {
+		c.Fatalf("the snap should be downloaded in chunks")
+		return nil
+	}
+
+	snap := &snap.Info{}
+	snap.AnonDownloadURL = mockServer.URL
+	snap.Sha3_384 = sha3_384
+	snap.Size = int64(len(content))
+
+	// the partial download looks complete, but its chunks do not
+	// cover the snap
+	path := filepath.Join(c.MkDir(), "downloaded-file")
+	c.Assert(ioutil.WriteFile(PartialDownloadPath(path), make([]byte, 100), 0644), IsNil)
+	err := saveChunksProgress(PartialDownloadPath(path), []chunkProgress{
+		{Start: 0, End: 50, Written: 50},
+	})
+	c.Assert(err, IsNil)
+
+	err = sto.Download(context.TODO(), "foo", path, &snap.DownloadInfo, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(path, testutil.FileEquals, content)
+
+	sort.Strings(ranges)
+	c.Check(ranges, DeepEquals, []string{"bytes=0-49", "bytes=50-99"})
+}
+
 var (
 	helloRefreshedDateStr = "2018-02-27T11:00:00Z"
 	helloRefreshedDate    time.Time
@@ -5492,6 +5848,191 @@ func (s *storeTestSuite) TestSnapAction(c *C) {
 	c.Assert(results[0].Deltas, HasLen, 0)
 }
 
+func (s *storeTestSuite) TestSnapActionRefreshParallelInstances(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequest(c, r, "POST", snapActionPath)
+
+		jsonReq, err := ioutil.ReadAll(r.Body)
+		c.Assert(err, IsNil)
+		var req struct {
+			Context []map[string]interface{} `json:"context"`
+			Fields  []string                 `json:"fields"`
+			Actions []map[string]interface{} `json:"actions"`
+		}
+
+		err = json.Unmarshal(jsonReq, &req)
+		c.Assert(err, IsNil)
+
+		// the snap and its instance are told apart by their
+		// instance-key
+		c.Assert(req.Context, HasLen, 2)
+		c.Assert(req.Context[0], DeepEquals, map[string]interface{}{
+			"snap-id":          helloWorldSnapID,
+			"instance-key":     helloWorldSnapID,
+			"revision":         float64(1),
+			"tracking-channel": "stable",
+		})
+		c.Assert(req.Context[1], DeepEquals, map[string]interface{}{
+			"snap-id":          helloWorldSnapID,
+			"instance-key":     "hello-world_foo",
+			"revision":         float64(2),
+			"tracking-channel": "beta",
+		})
+		c.Assert(req.Actions, HasLen, 2)
+		c.Assert(req.Actions[0], DeepEquals, map[string]interface{}{
+			"action":       "refresh",
+			"instance-key": helloWorldSnapID,
+			"snap-id":      helloWorldSnapID,
+		})
+		c.Assert(req.Actions[1], DeepEquals, map[string]interface{}{
+			"action":       "refresh",
+			"instance-key": "hello-world_foo",
+			"snap-id":      helloWorldSnapID,
+		})
+
+		io.WriteString(w, `{
+  "results": [{
+     "result": "refresh",
+     "instance-key": "buPKUD3TKqCOgLEjjHx5kSiCpIs5cMuQ",
+     "snap-id": "buPKUD3TKqCOgLEjjHx5kSiCpIs5cMuQ",
+     "name": "hello-world",
+     "snap": {
+       "snap-id": "buPKUD3TKqCOgLEjjHx5kSiCpIs5cMuQ",
+       "name": "hello-world",
+       "revision": 26,
+       "version": "6.1",
+       "publisher": {
+          "id": "canonical",
+          "username": "canonical",
+          "display-name": "Canonical"
+       }
+     }
+  }, {
+     "result": "refresh",
+     "instance-key": "hello-world_foo",
+     "snap-id": "buPKUD3TKqCOgLEjjHx5kSiCpIs5cMuQ",
+     "name": "hello-world",
+     "snap": {
+       "snap-id": "buPKUD3TKqCOgLEjjHx5kSiCpIs5cMuQ",
+       "name": "hello-world",
+       "revision": 27,
+       "version": "6.2",
+       "publisher": {
+          "id": "canonical",
+          "username": "canonical",
+          "display-name": "Canonical"
+       }
+     }
+  }]
+}`)
+	}))
+
+	c.Assert(mockServer, NotNil)
+	defer mockServer.Close()
+
+	mockServerURL, _ := url.Parse(mockServer.URL)
+	cfg := Config{
+		StoreBaseURL: mockServerURL,
+	}
+	authContext := &testAuthContext{c: c, device: s.device}
+	sto := New(&cfg, authContext)
+
+	results, err := sto.SnapAction(context.TODO(), []*CurrentSnap{
+		{
+			Name:         "hello-world",
+			InstanceName: "hello-world",
+			SnapID:       helloWorldSnapID,
+			Revision:     snap.R(1),
+		}, {
+			Name:            "hello-world",
+			InstanceName:    "hello-world_foo",
+			SnapID:          helloWorldSnapID,
+			TrackingChannel: "beta",
+			Revision:        snap.R(2),
+		},
+	}, []*SnapAction{
+		{
+			Action:       "refresh",
+			SnapID:       helloWorldSnapID,
+			InstanceName: "hello-world",
+		}, {
+			Action:       "refresh",
+			SnapID:       helloWorldSnapID,
+			InstanceName: "hello-world_foo",
+		},
+	}, nil, nil)
+	c.Assert(err, IsNil)
+	c.Assert(results, HasLen, 2)
+	c.Check(results[0].Name(), Equals, "hello-world")
+	c.Check(results[0].InstanceKey, Equals, "")
+	c.Check(results[0].Revision, Equals, snap.R(26))
+	c.Check(results[1].Name(), Equals, "hello-world_foo")
+	c.Check(results[1].InstanceKey, Equals, "foo")
+	c.Check(results[1].Revision, Equals, snap.R(27))
+}
+
+func (s *storeTestSuite) TestSnapActionRefreshParallelInstanceNoUpdate(c *C) {
+	restore := release.MockOnClassic(false)
+	defer restore()
+
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assertRequest(c, r, "POST", snapActionPath)
+		io.WriteString(w, `{
+  "results": [{
+     "result": "refresh",
+     "instance-key": "hello-world_foo",
+     "snap-id": "buPKUD3TKqCOgLEjjHx5kSiCpIs5cMuQ",
+     "name": "hello-world",
+     "snap": {
+       "snap-id": "buPKUD3TKqCOgLEjjHx5kSiCpIs5cMuQ",
+       "name": "hello-world",
+       "revision": 2,
+       "version": "6.1",
+       "publisher": {
+          "id": "canonical",
+          "username": "canonical",
+          "display-name": "Canonical"
+       }
+     }
+  }]
+}`)
+	}))
+
+	c.Assert(mockServer, NotNil)
+	defer mockServer.Close()
+
+	mockServerURL, _ := url.Parse(mockServer.URL)
+	cfg := Config{
+		StoreBaseURL: mockServerURL,
+	}
+	authContext := &testAuthContext{c: c, device: s.device}
+	sto := New(&cfg, authContext)
+
+	results, err := sto.SnapAction(context.TODO(), []*CurrentSnap{
+		{
+			Name:         "hello-world",
+			InstanceName: "hello-world_foo",
+			SnapID:       helloWorldSnapID,
+			Revision:     snap.R(2),
+		},
+	}, []*SnapAction{
+		{
+			Action:       "refresh",
+			SnapID:       helloWorldSnapID,
+			InstanceName: "hello-world_foo",
+		},
+	}, nil, nil)
+	c.Assert(results, HasLen, 0)
+	c.Check(err, DeepEquals, &SnapActionError{
+		Refresh: map[string]error{
+			"hello-world_foo": ErrNoUpdateAvailable,
+		},
+	})
+}
+
 func (s *storeTestSuite) TestSnapActionNoResults(c *C) {
 	restore := release.MockOnClassic(false)
 	defer restore()
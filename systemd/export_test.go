@@ -58,3 +58,7 @@ func MockJournalStdoutPath(path string) func() {
 		journalStdoutPath = oldPath
 	}
 }
+
+func NewError(cmd []string, exitCode int, msg []byte) error {
+	return &Error{cmd: cmd, exitCode: exitCode, msg: msg}
+}
@@ -76,24 +76,84 @@ func Available() error {
 
 var osutilStreamCommand = osutil.StreamCommand
 
-// jctl calls journalctl to get the JSON logs of the given services.
-var jctl = func(svcs []string, n string, follow bool) (io.ReadCloser, error) {
-	// args will need two entries per service, plus a fixed number (give or take
-	// one) for the initial options.
-	args := make([]string, 0, 2*len(svcs)+6)
-	args = append(args, "-o", "json", "-n", n, "--no-pager") // len(this)+1 == that ^ fixed number
-	if follow {
-		args = append(args, "-f") // this is the +1 :-)
+// LogOptions are the options that narrow down what LogReader returns.
+type LogOptions struct {
+	// N is the maximum number of lines to return initially, or "all".
+	N string
+	// Follow keeps returning new lines as they appear.
+	Follow bool
+	// Priority, if set, only returns entries of this priority or
+	// more important ones; see ParseLogPriority.
+	Priority string
+	// Since and Until, if not zero, limit the entries to the ones
+	// received by the journal in that time range.
+	Since time.Time
+	Until time.Time
+	// Cursor, if set, only returns entries after the one with the
+	// given journal cursor.
+	Cursor string
+	// UserServices are services of the user sessions whose logs are
+	// returned too.
+	UserServices []string
+}
+
+// args returns the journalctl options corresponding to the LogOptions.
+func (opts *LogOptions) args() []string {
+	args := []string{"-n", opts.N}
+	if opts.Follow {
+		args = append(args, "-f")
+	}
+	if opts.Priority != "" {
+		args = append(args, "-p", opts.Priority)
+	}
+	// "@<seconds since the epoch>" is understood by all the journalctl
+	// versions we care about, independently of the timezone
+	if !opts.Since.IsZero() {
+		args = append(args, "--since", fmt.Sprintf("@%d", opts.Since.Unix()))
 	}
+	if !opts.Until.IsZero() {
+		args = append(args, "--until", fmt.Sprintf("@%d", opts.Until.Unix()))
+	}
+	if opts.Cursor != "" {
+		args = append(args, "--after-cursor", opts.Cursor)
+	}
+	return args
+}
+
+var logPriorities = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// ParseLogPriority parses a syslog priority, given either by name
+// ("emerg", "alert", "crit", "err", "warning", "notice", "info" or
+// "debug") or by number (0 to 7), into its numeric value.
+func ParseLogPriority(s string) (int, error) {
+	for i, name := range logPriorities {
+		if s == name || s == strconv.Itoa(i) {
+			return i, nil
+		}
+	}
+	return -1, fmt.Errorf("invalid log priority %q", s)
+}
+
+// jctl calls journalctl to get the JSON logs of the given services.
+var jctl = func(svcs []string, opts LogOptions) (io.ReadCloser, error) {
+	optArgs := opts.args()
+	// args will need two entries per service, plus the fixed ones
+	// and the ones coming from the options.
+	args := make([]string, 0, 2*(len(svcs)+len(opts.UserServices))+len(optArgs)+3)
+	args = append(args, "-o", "json", "--no-pager")
+	args = append(args, optArgs...)
 
 	for i := range svcs {
 		args = append(args, "-u", svcs[i]) // this is why 2×
 	}
+	for i := range opts.UserServices {
+		args = append(args, "--user-unit", opts.UserServices[i])
+	}
 
 	return osutilStreamCommand("journalctl", args...)
 }
 
-func MockJournalctl(f func(svcs []string, n string, follow bool) (io.ReadCloser, error)) func() {
+func MockJournalctl(f func(svcs []string, opts LogOptions) (io.ReadCloser, error)) func() {
 	oldJctl := jctl
 	jctl = f
 	return func() {
@@ -111,7 +171,8 @@ type Systemd interface {
 	Kill(service, signal, who string) error
 	Restart(service string, timeout time.Duration) error
 	Status(services ...string) ([]*ServiceStatus, error)
-	LogReader(services []string, n string, follow bool) (io.ReadCloser, error)
+	IsEnabled(service string) (bool, error)
+	LogReader(services []string, opts LogOptions) (io.ReadCloser, error)
 	WriteMountUnitFile(name, revision, what, where, fstype string) (string, error)
 	Mask(service string) error
 	Unmask(service string) error
@@ -124,6 +185,9 @@ const (
 	// the default target for systemd units that we generate
 	ServicesTarget = "multi-user.target"
 
+	// the target for the user session systemd units that we generate
+	UserServicesTarget = "default.target"
+
 	// the target prerequisite for systemd units we generate
 	PrerequisiteTarget = "network-online.target"
 
@@ -138,55 +202,98 @@ type reporter interface {
 	Notify(string)
 }
 
+// InstanceMode determines which instance of systemd a Systemd talks to.
+type InstanceMode int
+
+const (
+	// SystemMode talks to the system instance of systemd.
+	SystemMode InstanceMode = iota
+	// UserMode talks to the systemd instance of the calling user's
+	// session (systemctl --user).
+	UserMode
+	// GlobalUserMode operates on the configuration shared by the
+	// systemd instances of all the user sessions (systemctl --user
+	// --global); only enabling, disabling, masking and unmasking are
+	// possible in this mode.
+	GlobalUserMode
+)
+
 // New returns a Systemd that uses the given rootDir
 func New(rootDir string, rep reporter) Systemd {
 	return &systemd{rootDir: rootDir, reporter: rep}
 }
 
+// NewWithMode returns a Systemd that uses the given rootDir and talks
+// to the instance of systemd given by mode.
+func NewWithMode(rootDir string, mode InstanceMode, rep reporter) Systemd {
+	return &systemd{rootDir: rootDir, mode: mode, reporter: rep}
+}
+
 type systemd struct {
 	rootDir  string
+	mode     InstanceMode
 	reporter reporter
 }
 
+var errGlobalUserMode = errors.New("operation not supported in global user mode")
+
+// systemctl runs systemctl with the given args, for the instance of
+// systemd selected by the mode.
+func (s *systemd) systemctl(args ...string) ([]byte, error) {
+	switch s.mode {
+	case UserMode:
+		args = append([]string{"--user"}, args...)
+	case GlobalUserMode:
+		args = append([]string{"--user", "--global"}, args...)
+	}
+	return systemctlCmd(args...)
+}
+
 // DaemonReload reloads systemd's configuration.
-func (*systemd) DaemonReload() error {
-	_, err := systemctlCmd("daemon-reload")
+func (s *systemd) DaemonReload() error {
+	if s.mode == GlobalUserMode {
+		return errGlobalUserMode
+	}
+	_, err := s.systemctl("daemon-reload")
 	return err
 }
 
 // Enable the given service
 func (s *systemd) Enable(serviceName string) error {
-	_, err := systemctlCmd("--root", s.rootDir, "enable", serviceName)
+	_, err := s.systemctl("--root", s.rootDir, "enable", serviceName)
 	return err
 }
 
 // Unmask the given service
 func (s *systemd) Unmask(serviceName string) error {
-	_, err := systemctlCmd("--root", s.rootDir, "unmask", serviceName)
+	_, err := s.systemctl("--root", s.rootDir, "unmask", serviceName)
 	return err
 }
 
 // Disable the given service
 func (s *systemd) Disable(serviceName string) error {
-	_, err := systemctlCmd("--root", s.rootDir, "disable", serviceName)
+	_, err := s.systemctl("--root", s.rootDir, "disable", serviceName)
 	return err
 }
 
 // Mask the given service
 func (s *systemd) Mask(serviceName string) error {
-	_, err := systemctlCmd("--root", s.rootDir, "mask", serviceName)
+	_, err := s.systemctl("--root", s.rootDir, "mask", serviceName)
 	return err
 }
 
 // Start the given service or services
-func (*systemd) Start(serviceNames ...string) error {
-	_, err := systemctlCmd(append([]string{"start"}, serviceNames...)...)
+func (s *systemd) Start(serviceNames ...string) error {
+	if s.mode == GlobalUserMode {
+		return errGlobalUserMode
+	}
+	_, err := s.systemctl(append([]string{"start"}, serviceNames...)...)
 	return err
 }
 
 // LogReader for the given services
-func (*systemd) LogReader(serviceNames []string, n string, follow bool) (io.ReadCloser, error) {
-	return jctl(serviceNames, n, follow)
+func (*systemd) LogReader(serviceNames []string, opts LogOptions) (io.ReadCloser, error) {
+	return jctl(serviceNames, opts)
 }
 
 var statusregex = regexp.MustCompile(`(?m)^(?:(.+?)=(.*)|(.*))?$`)
@@ -199,12 +306,15 @@ type ServiceStatus struct {
 }
 
 func (s *systemd) Status(serviceNames ...string) ([]*ServiceStatus, error) {
+	if s.mode == GlobalUserMode {
+		return nil, errGlobalUserMode
+	}
 	expected := []string{"Id", "Type", "ActiveState", "UnitFileState"}
 	cmd := make([]string, len(serviceNames)+2)
 	cmd[0] = "show"
 	cmd[1] = "--property=" + strings.Join(expected, ",")
 	copy(cmd[2:], serviceNames)
-	bs, err := systemctlCmd(cmd...)
+	bs, err := s.systemctl(cmd...)
 	if err != nil {
 		return nil, err
 	}
@@ -276,9 +386,26 @@ func (s *systemd) Status(serviceNames ...string) ([]*ServiceStatus, error) {
 	return sts, nil
 }
 
+// IsEnabled checks whether the given service is enabled.
+func (s *systemd) IsEnabled(serviceName string) (bool, error) {
+	_, err := s.systemctl("--root", s.rootDir, "is-enabled", serviceName)
+	if err == nil {
+		return true, nil
+	}
+	// "systemctl is-enabled" exits with 1 for services that are not
+	// enabled (or that do not exist)
+	if sysdErr, ok := err.(*Error); ok && sysdErr.exitCode == 1 {
+		return false, nil
+	}
+	return false, err
+}
+
 // Stop the given service, and wait until it has stopped.
 func (s *systemd) Stop(serviceName string, timeout time.Duration) error {
-	if _, err := systemctlCmd("stop", serviceName); err != nil {
+	if s.mode == GlobalUserMode {
+		return errGlobalUserMode
+	}
+	if _, err := s.systemctl("stop", serviceName); err != nil {
 		return err
 	}
 
@@ -296,7 +423,7 @@ loop:
 		case <-giveup.C:
 			break loop
 		case <-check.C:
-			bs, err := systemctlCmd("show", "--property=ActiveState", serviceName)
+			bs, err := s.systemctl("show", "--property=ActiveState", serviceName)
 			if err != nil {
 				return err
 			}
@@ -318,10 +445,13 @@ loop:
 
 // Kill all processes of the unit with the given signal
 func (s *systemd) Kill(serviceName, signal, who string) error {
+	if s.mode == GlobalUserMode {
+		return errGlobalUserMode
+	}
 	if who == "" {
 		who = "all"
 	}
-	_, err := systemctlCmd("kill", serviceName, "-s", signal, "--kill-who="+who)
+	_, err := s.systemctl("kill", serviceName, "-s", signal, "--kill-who="+who)
 	return err
 }
 
@@ -406,6 +536,31 @@ func (l Log) PID() string {
 	return "-"
 }
 
+// Cursor is the journal cursor of the Log, that can be used to resume
+// reading after it, if any; otherwise, "".
+func (l Log) Cursor() string {
+	return l["__CURSOR"]
+}
+
+// BootID is the id of the boot the Log was received in, if any; otherwise, "".
+func (l Log) BootID() string {
+	return l["_BOOT_ID"]
+}
+
+// Unit is the systemd unit that generated the Log, if any; otherwise, "".
+// For user session units this is the user unit.
+func (l Log) Unit() string {
+	if unit, ok := l["_SYSTEMD_USER_UNIT"]; ok {
+		return unit
+	}
+	return l["_SYSTEMD_UNIT"]
+}
+
+// Priority is the syslog priority of the Log (0 to 7), if any; otherwise, "".
+func (l Log) Priority() string {
+	return l["PRIORITY"]
+}
+
 // MountUnitPath returns the path of a {,auto}mount unit
 func MountUnitPath(baseDir string) string {
 	escapedPath := EscapeUnitNamePath(baseDir)
@@ -26,6 +26,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strconv"
 	"testing"
 	"time"
 
@@ -114,13 +115,13 @@ func (s *SystemdTestSuite) myRun(args ...string) (out []byte, err error) {
 	return out, err
 }
 
-func (s *SystemdTestSuite) myJctl(svcs []string, n string, follow bool) (io.ReadCloser, error) {
+func (s *SystemdTestSuite) myJctl(svcs []string, opts LogOptions) (io.ReadCloser, error) {
 	var err error
 	var out []byte
 
-	s.jns = append(s.jns, n)
+	s.jns = append(s.jns, opts.N)
 	s.jsvcs = append(s.jsvcs, svcs)
-	s.jfollows = append(s.jfollows, follow)
+	s.jfollows = append(s.jfollows, opts.Follow)
 
 	if s.j < len(s.jouts) {
 		out = s.jouts[s.j]
@@ -358,6 +359,27 @@ func (s *SystemdTestSuite) TestEnable(c *C) {
 	c.Check(s.argses, DeepEquals, [][]string{{"--root", "xyzzy", "enable", "foo"}})
 }
 
+func (s *SystemdTestSuite) TestIsEnabled(c *C) {
+	s.outs = [][]byte{[]byte("enabled\n"), []byte("disabled\n"), nil}
+	s.errors = []error{nil, NewError([]string{"is-enabled"}, 1, nil), NewError([]string{"is-enabled"}, 4, []byte("boom"))}
+	sysd := NewWithMode("xyzzy", GlobalUserMode, s.rep)
+
+	enabled, err := sysd.IsEnabled("foo")
+	c.Assert(err, IsNil)
+	c.Check(enabled, Equals, true)
+	enabled, err = sysd.IsEnabled("foo")
+	c.Assert(err, IsNil)
+	c.Check(enabled, Equals, false)
+	_, err = sysd.IsEnabled("foo")
+	c.Check(err, ErrorMatches, ".* failed with exit status 4: boom")
+
+	c.Check(s.argses, DeepEquals, [][]string{
+		{"--user", "--global", "--root", "xyzzy", "is-enabled", "foo"},
+		{"--user", "--global", "--root", "xyzzy", "is-enabled", "foo"},
+		{"--user", "--global", "--root", "xyzzy", "is-enabled", "foo"},
+	})
+}
+
 func (s *SystemdTestSuite) TestMask(c *C) {
 	err := New("xyzzy", s.rep).Mask("foo")
 	c.Assert(err, IsNil)
@@ -392,6 +414,49 @@ func (s *SystemdTestSuite) TestKill(c *C) {
 	c.Check(s.argses, DeepEquals, [][]string{{"kill", "foo", "-s", "HUP", "--kill-who=all"}})
 }
 
+func (s *SystemdTestSuite) TestUserMode(c *C) {
+	restore := MockStopDelays(time.Millisecond, 25*time.Second)
+	defer restore()
+	s.outs = [][]byte{
+		nil, // for the "start"
+		nil, // for the "stop" itself
+		[]byte("ActiveState=inactive\n"),
+	}
+	sysd := NewWithMode("", UserMode, s.rep)
+	c.Assert(sysd.Start("foo"), IsNil)
+	c.Assert(sysd.Stop("foo", time.Second), IsNil)
+	c.Assert(sysd.DaemonReload(), IsNil)
+	c.Check(s.argses, DeepEquals, [][]string{
+		{"--user", "start", "foo"},
+		{"--user", "stop", "foo"},
+		{"--user", "show", "--property=ActiveState", "foo"},
+		{"--user", "daemon-reload"},
+	})
+}
+
+func (s *SystemdTestSuite) TestGlobalUserMode(c *C) {
+	sysd := NewWithMode("xyzzy", GlobalUserMode, s.rep)
+	c.Assert(sysd.Enable("foo"), IsNil)
+	c.Assert(sysd.Disable("foo"), IsNil)
+	c.Assert(sysd.Mask("foo"), IsNil)
+	c.Assert(sysd.Unmask("foo"), IsNil)
+	c.Check(s.argses, DeepEquals, [][]string{
+		{"--user", "--global", "--root", "xyzzy", "enable", "foo"},
+		{"--user", "--global", "--root", "xyzzy", "disable", "foo"},
+		{"--user", "--global", "--root", "xyzzy", "mask", "foo"},
+		{"--user", "--global", "--root", "xyzzy", "unmask", "foo"},
+	})
+
+	// the rest need a running instance of systemd
+	c.Check(sysd.DaemonReload(), ErrorMatches, "operation not supported in global user mode")
+	c.Check(sysd.Start("foo"), ErrorMatches, "operation not supported in global user mode")
+	c.Check(sysd.Stop("foo", time.Second), ErrorMatches, "operation not supported in global user mode")
+	c.Check(sysd.Kill("foo", "HUP", ""), ErrorMatches, "operation not supported in global user mode")
+	_, err := sysd.Status("foo")
+	c.Check(err, ErrorMatches, "operation not supported in global user mode")
+	c.Check(s.argses, HasLen, 4)
+}
+
 func (s *SystemdTestSuite) TestIsTimeout(c *C) {
 	c.Check(IsTimeout(os.ErrInvalid), Equals, false)
 	c.Check(IsTimeout(&Timeout{}), Equals, true)
@@ -400,7 +465,7 @@ func (s *SystemdTestSuite) TestIsTimeout(c *C) {
 func (s *SystemdTestSuite) TestLogErrJctl(c *C) {
 	s.jerrs = []error{&Timeout{}}
 
-	reader, err := New("", s.rep).LogReader([]string{"foo"}, "24", false)
+	reader, err := New("", s.rep).LogReader([]string{"foo"}, LogOptions{N: "24"})
 	c.Check(err, NotNil)
 	c.Check(reader, IsNil)
 	c.Check(s.jns, DeepEquals, []string{"24"})
@@ -415,7 +480,7 @@ func (s *SystemdTestSuite) TestLogs(c *C) {
 `
 	s.jouts = [][]byte{[]byte(expected)}
 
-	reader, err := New("", s.rep).LogReader([]string{"foo"}, "24", false)
+	reader, err := New("", s.rep).LogReader([]string{"foo"}, LogOptions{N: "24"})
 	c.Check(err, IsNil)
 	logs, err := ioutil.ReadAll(reader)
 	c.Assert(err, IsNil)
@@ -426,6 +491,30 @@ func (s *SystemdTestSuite) TestLogs(c *C) {
 	c.Check(s.j, Equals, 1)
 }
 
+func (s *SystemdTestSuite) TestLogJournalFields(c *C) {
+	c.Check(Log{}.Cursor(), Equals, "")
+	c.Check(Log{}.BootID(), Equals, "")
+	c.Check(Log{}.Unit(), Equals, "")
+	c.Check(Log{}.Priority(), Equals, "")
+
+	l := Log{
+		"__CURSOR":      "s=abc;i=42",
+		"_BOOT_ID":      "0ab1c2",
+		"_SYSTEMD_UNIT": "snap.foo.bar.service",
+		"PRIORITY":      "6",
+	}
+	c.Check(l.Cursor(), Equals, "s=abc;i=42")
+	c.Check(l.BootID(), Equals, "0ab1c2")
+	c.Check(l.Unit(), Equals, "snap.foo.bar.service")
+	c.Check(l.Priority(), Equals, "6")
+
+	l = Log{
+		"_SYSTEMD_UNIT":      "user@1000.service",
+		"_SYSTEMD_USER_UNIT": "snap.foo.baz.service",
+	}
+	c.Check(l.Unit(), Equals, "snap.foo.baz.service")
+}
+
 func (s *SystemdTestSuite) TestLogPID(c *C) {
 	c.Check(Log{}.PID(), Equals, "-")
 	c.Check(Log{"_PID": "99"}.PID(), Equals, "99")
@@ -602,10 +691,38 @@ func (s *SystemdTestSuite) TestJctl(c *C) {
 		return nil, nil
 	})
 
-	_, err = Jctl([]string{"foo", "bar"}, "10", false)
+	_, err = Jctl([]string{"foo", "bar"}, LogOptions{N: "10"})
 	c.Assert(err, IsNil)
-	c.Check(args, DeepEquals, []string{"-o", "json", "-n", "10", "--no-pager", "-u", "foo", "-u", "bar"})
-	_, err = Jctl([]string{"foo", "bar", "baz"}, "99", true)
+	c.Check(args, DeepEquals, []string{"-o", "json", "--no-pager", "-n", "10", "-u", "foo", "-u", "bar"})
+	_, err = Jctl([]string{"foo", "bar", "baz"}, LogOptions{N: "99", Follow: true})
 	c.Assert(err, IsNil)
-	c.Check(args, DeepEquals, []string{"-o", "json", "-n", "99", "--no-pager", "-f", "-u", "foo", "-u", "bar", "-u", "baz"})
+	c.Check(args, DeepEquals, []string{"-o", "json", "--no-pager", "-n", "99", "-f", "-u", "foo", "-u", "bar", "-u", "baz"})
+	_, err = Jctl([]string{"foo"}, LogOptions{
+		N:        "all",
+		Priority: "3",
+		Since:    time.Unix(1500000000, 0),
+		Until:    time.Unix(1500003600, 500),
+		Cursor:   "s=abc;i=42",
+	})
+	c.Assert(err, IsNil)
+	c.Check(args, DeepEquals, []string{"-o", "json", "--no-pager", "-n", "all", "-p", "3", "--since", "@1500000000", "--until", "@1500003600", "--after-cursor", "s=abc;i=42", "-u", "foo"})
+	_, err = Jctl([]string{"foo"}, LogOptions{N: "10", UserServices: []string{"bar", "baz"}})
+	c.Assert(err, IsNil)
+	c.Check(args, DeepEquals, []string{"-o", "json", "--no-pager", "-n", "10", "-u", "foo", "--user-unit", "bar", "--user-unit", "baz"})
+}
+
+func (s *SystemdTestSuite) TestParseLogPriority(c *C) {
+	for i, name := range []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"} {
+		p, err := ParseLogPriority(name)
+		c.Check(err, IsNil)
+		c.Check(p, Equals, i)
+		p, err = ParseLogPriority(strconv.Itoa(i))
+		c.Check(err, IsNil)
+		c.Check(p, Equals, i)
+	}
+
+	for _, bad := range []string{"", "8", "-1", "error", "warn", "ERR"} {
+		_, err := ParseLogPriority(bad)
+		c.Check(err, ErrorMatches, `invalid log priority ".*"`, Commentf(bad))
+	}
 }
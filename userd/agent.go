@@ -0,0 +1,229 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package userd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	sys "syscall"
+	"time"
+
+	"github.com/coreos/go-systemd/activation"
+	"gopkg.in/tomb.v2"
+
+	"github.com/snapcore/snapd/logger"
+	"github.com/snapcore/snapd/progress"
+	"github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/timeout"
+	"github.com/snapcore/snapd/userd/sessionagent"
+)
+
+// SessionAgent serves the requests snapd makes to manage the user
+// session services of snaps, on a socket in the XDG_RUNTIME_DIR of the
+// session.
+type SessionAgent struct {
+	tomb     tomb.Tomb
+	listener net.Listener
+	server   *http.Server
+}
+
+var (
+	osGetuid = os.Getuid
+	getUcred = sys.GetsockoptUcred
+)
+
+// how long to wait for a service to stop
+var agentStopTimeout = time.Duration(timeout.DefaultTimeout)
+
+// Init sets up the listener of the session agent, either from socket
+// activation or by listening itself.
+func (sa *SessionAgent) Init() error {
+	socketPath := sessionagent.SocketPath(osGetuid())
+
+	listeners, err := activation.Listeners(false)
+	if err != nil {
+		return err
+	}
+	var listener net.Listener
+	for _, l := range listeners {
+		if l != nil && l.Addr().String() == socketPath {
+			listener = l
+			break
+		}
+	}
+	if listener == nil {
+		if err := os.MkdirAll(filepath.Dir(socketPath), 0700); err != nil {
+			return err
+		}
+		// remove the socket of a previous agent, if any
+		if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		listener, err = net.Listen("unix", socketPath)
+		if err != nil {
+			return err
+		}
+		if err := os.Chmod(socketPath, 0600); err != nil {
+			listener.Close()
+			return err
+		}
+	}
+	sa.listener = &peerCredListener{listener}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/service-control", serviceControl)
+	sa.server = &http.Server{Handler: mux}
+
+	return nil
+}
+
+// Start serves the requests in the background.
+func (sa *SessionAgent) Start() {
+	logger.Noticef("Starting snap session agent")
+
+	sa.tomb.Go(func() error {
+		err := sa.server.Serve(sa.listener)
+		select {
+		case <-sa.tomb.Dying():
+			// the listener was closed by Stop
+			return nil
+		default:
+			return err
+		}
+	})
+}
+
+// Stop stops serving requests.
+func (sa *SessionAgent) Stop() error {
+	sa.tomb.Kill(nil)
+	sa.listener.Close()
+	return sa.tomb.Wait()
+}
+
+// Dying is closed when the session agent is stopping.
+func (sa *SessionAgent) Dying() <-chan struct{} {
+	return sa.tomb.Dying()
+}
+
+// peerCredListener only accepts connections from root (snapd) and
+// from the user running the session agent.
+type peerCredListener struct{ net.Listener }
+
+func (pl *peerCredListener) Accept() (net.Conn, error) {
+	for {
+		con, err := pl.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		if ucon, ok := con.(*net.UnixConn); ok {
+			uid, err := peerUid(ucon)
+			if err == nil && (uid == 0 || uid == uint32(osGetuid())) {
+				return con, nil
+			}
+			logger.Noticef("rejected session agent connection: uid %d, %v", uid, err)
+		}
+		con.Close()
+	}
+}
+
+func peerUid(ucon *net.UnixConn) (uint32, error) {
+	f, err := ucon.File()
+	if err != nil {
+		return 0, err
+	}
+	// File() is a dup(); needs closing
+	defer f.Close()
+
+	ucred, err := getUcred(int(f.Fd()), sys.SOL_SOCKET, sys.SO_PEERCRED)
+	if err != nil {
+		return 0, err
+	}
+	return ucred.Uid, nil
+}
+
+func writeAgentResponse(w http.ResponseWriter, status int, rsp *sessionagent.Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(rsp); err != nil {
+		logger.Noticef("cannot write session agent response: %v", err)
+	}
+}
+
+func writeAgentError(w http.ResponseWriter, status int, format string, v ...interface{}) {
+	result, _ := json.Marshal(&sessionagent.ErrorResult{Message: fmt.Sprintf(format, v...)})
+	writeAgentResponse(w, status, &sessionagent.Response{Type: "error", Result: result})
+}
+
+func serviceControl(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		writeAgentError(w, 405, "method %q not allowed", r.Method)
+		return
+	}
+
+	var inst sessionagent.ServiceInstruction
+	if err := json.NewDecoder(r.Body).Decode(&inst); err != nil {
+		writeAgentError(w, 400, "cannot decode request body into service instruction: %v", err)
+		return
+	}
+	for _, svc := range inst.Services {
+		// only snap services and timers are managed through the agent
+		if !strings.HasPrefix(svc, "snap.") || !(strings.HasSuffix(svc, ".service") || strings.HasSuffix(svc, ".timer")) {
+			writeAgentError(w, 400, "cannot manage non-snap service %q", svc)
+			return
+		}
+	}
+
+	sysd := systemd.NewWithMode("", systemd.UserMode, progress.Null)
+	var err error
+	switch inst.Action {
+	case "daemon-reload":
+		err = sysd.DaemonReload()
+	case "start":
+		if len(inst.Services) > 0 {
+			err = sysd.Start(inst.Services...)
+		}
+	case "stop":
+		for _, svc := range inst.Services {
+			if e := sysd.Stop(svc, agentStopTimeout); e != nil && err == nil {
+				err = e
+			}
+		}
+	case "restart":
+		for _, svc := range inst.Services {
+			if e := sysd.Restart(svc, agentStopTimeout); e != nil && err == nil {
+				err = e
+			}
+		}
+	default:
+		writeAgentError(w, 400, "unknown action %q", inst.Action)
+		return
+	}
+	if err != nil {
+		writeAgentError(w, 500, "cannot %s services: %v", inst.Action, err)
+		return
+	}
+
+	writeAgentResponse(w, 200, &sessionagent.Response{Type: "sync"})
+}
@@ -0,0 +1,105 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package userd_test
+
+import (
+	"errors"
+	"os"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/systemd"
+	"github.com/snapcore/snapd/userd"
+	"github.com/snapcore/snapd/userd/sessionagent"
+)
+
+type agentSuite struct {
+	agent *userd.SessionAgent
+
+	sysctlArgs [][]string
+	sysctlErr  error
+
+	restore func()
+}
+
+var _ = Suite(&agentSuite{})
+
+func (s *agentSuite) SetUpTest(c *C) {
+	dirs.SetRootDir(c.MkDir())
+	s.sysctlArgs = nil
+	s.sysctlErr = nil
+	s.restore = systemd.MockSystemctl(func(args ...string) ([]byte, error) {
+		s.sysctlArgs = append(s.sysctlArgs, args)
+		if args[1] == "show" {
+			return []byte("ActiveState=inactive\n"), nil
+		}
+		return nil, s.sysctlErr
+	})
+
+	s.agent = &userd.SessionAgent{}
+	c.Assert(s.agent.Init(), IsNil)
+	s.agent.Start()
+}
+
+func (s *agentSuite) TearDownTest(c *C) {
+	c.Check(s.agent.Stop(), IsNil)
+	s.restore()
+	dirs.SetRootDir("/")
+}
+
+func (s *agentSuite) TestSocket(c *C) {
+	fi, err := os.Stat(sessionagent.SocketPath(os.Getuid()))
+	c.Assert(err, IsNil)
+	c.Check(fi.Mode()&os.ModeSocket, Equals, os.ModeSocket)
+	c.Check(fi.Mode().Perm(), Equals, os.FileMode(0600))
+}
+
+func (s *agentSuite) TestServiceControl(c *C) {
+	cli := sessionagent.New()
+	c.Assert(cli.ServicesDaemonReload(), IsNil)
+	c.Assert(cli.ServicesStart([]string{"snap.foo.bar.service", "snap.foo.baz.timer"}), IsNil)
+	c.Assert(cli.ServicesStop([]string{"snap.foo.bar.service"}), IsNil)
+	c.Assert(cli.ServicesRestart([]string{"snap.foo.baz.service"}), IsNil)
+
+	c.Check(s.sysctlArgs, DeepEquals, [][]string{
+		{"--user", "daemon-reload"},
+		{"--user", "start", "snap.foo.bar.service", "snap.foo.baz.timer"},
+		{"--user", "stop", "snap.foo.bar.service"},
+		{"--user", "show", "--property=ActiveState", "snap.foo.bar.service"},
+		{"--user", "stop", "snap.foo.baz.service"},
+		{"--user", "show", "--property=ActiveState", "snap.foo.baz.service"},
+		{"--user", "start", "snap.foo.baz.service"},
+	})
+}
+
+func (s *agentSuite) TestServiceControlError(c *C) {
+	s.sysctlErr = errors.New("boom")
+
+	err := sessionagent.New().ServicesStart([]string{"snap.foo.bar.service"})
+	c.Assert(err, FitsTypeOf, &sessionagent.Error{})
+	c.Check(err, ErrorMatches, `cannot start user services: user [0-9]+: cannot start services: boom`)
+}
+
+func (s *agentSuite) TestServiceControlNonSnapService(c *C) {
+	err := sessionagent.New().ServicesStop([]string{"ssh.service"})
+	c.Check(err, ErrorMatches, `cannot stop user services: user [0-9]+: cannot manage non-snap service "ssh.service"`)
+	c.Check(s.sysctlArgs, HasLen, 0)
+}
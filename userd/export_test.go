@@ -23,6 +23,8 @@ import (
 	"os/user"
 
 	"github.com/godbus/dbus"
+
+	"github.com/snapcore/snapd/client"
 )
 
 var (
@@ -47,3 +49,19 @@ func MockUserCurrent(f func() (*user.User, error)) func() {
 		userCurrent = origUserCurrent
 	}
 }
+
+func NewRefreshNotifier(cli *client.Client) *refreshNotifier {
+	return newRefreshNotifier(nil, cli)
+}
+
+func (n *refreshNotifier) Check() error {
+	return n.check()
+}
+
+func MockSendNotification(f func(conn *dbus.Conn, summary, body string) error) func() {
+	origSendNotification := sendNotification
+	sendNotification = f
+	return func() {
+		sendNotification = origSendNotification
+	}
+}
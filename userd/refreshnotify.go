@@ -0,0 +1,108 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package userd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/godbus/dbus"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/i18n"
+	"github.com/snapcore/snapd/logger"
+)
+
+// refreshNotifyInterval is how often snapd is asked about refreshes
+// postponed because apps are running.
+var refreshNotifyInterval = 10 * time.Minute
+
+// sendNotification shows a desktop notification in the user session.
+var sendNotification = func(conn *dbus.Conn, summary, body string) error {
+	obj := conn.Object("org.freedesktop.Notifications", "/org/freedesktop/Notifications")
+	call := obj.Call("org.freedesktop.Notifications.Notify", 0,
+		"snapd", uint32(0), "", summary, body, []string{}, map[string]dbus.Variant{}, int32(-1))
+	return call.Err
+}
+
+// refreshNotifier tells the user about snaps whose automatic refresh
+// is postponed because some of their apps are running, so that they
+// can close them.
+type refreshNotifier struct {
+	conn *dbus.Conn
+	cli  *client.Client
+
+	// notified maps snap names to the time since when their
+	// refresh was postponed when the user was last notified
+	notified map[string]time.Time
+}
+
+func newRefreshNotifier(conn *dbus.Conn, cli *client.Client) *refreshNotifier {
+	return &refreshNotifier{
+		conn:     conn,
+		cli:      cli,
+		notified: make(map[string]time.Time),
+	}
+}
+
+// check notifies the user once about every postponed refresh.
+func (n *refreshNotifier) check() error {
+	snaps, err := n.cli.List(nil, nil)
+	if err != nil {
+		return err
+	}
+
+	pending := make(map[string]bool, len(snaps))
+	for _, snap := range snaps {
+		if snap.RefreshInhibitedSince == nil {
+			continue
+		}
+		pending[snap.Name] = true
+		if last, ok := n.notified[snap.Name]; ok && last.Equal(*snap.RefreshInhibitedSince) {
+			continue
+		}
+		// TRANSLATORS: %q is a snap name
+		summary := fmt.Sprintf(i18n.G("Pending update of %q snap"), snap.Name)
+		body := i18n.G("Close the app to avoid disruptions")
+		if err := sendNotification(n.conn, summary, body); err != nil {
+			return err
+		}
+		n.notified[snap.Name] = *snap.RefreshInhibitedSince
+	}
+	for name := range n.notified {
+		if !pending[name] {
+			delete(n.notified, name)
+		}
+	}
+	return nil
+}
+
+func (n *refreshNotifier) run(dying <-chan struct{}) error {
+	for {
+		if err := n.check(); err != nil {
+			logger.Debugf("cannot check for pending refreshes: %v", err)
+		}
+		select {
+		case <-time.After(refreshNotifyInterval):
+		case <-dying:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package userd_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/godbus/dbus"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/client"
+	"github.com/snapcore/snapd/userd"
+)
+
+type refreshNotifySuite struct {
+	server        *httptest.Server
+	rsp           string
+	notifications []string
+
+	restoreSendNotification func()
+}
+
+var _ = Suite(&refreshNotifySuite{})
+
+func (s *refreshNotifySuite) SetUpTest(c *C) {
+	s.notifications = nil
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "GET")
+		c.Check(r.URL.Path, Equals, "/v2/snaps")
+		fmt.Fprintln(w, s.rsp)
+	}))
+	s.restoreSendNotification = userd.MockSendNotification(func(conn *dbus.Conn, summary, body string) error {
+		s.notifications = append(s.notifications, summary+": "+body)
+		return nil
+	})
+}
+
+func (s *refreshNotifySuite) TearDownTest(c *C) {
+	s.server.Close()
+	s.restoreSendNotification()
+}
+
+func (s *refreshNotifySuite) TestCheckNotifiesOnce(c *C) {
+	n := userd.NewRefreshNotifier(client.New(&client.Config{BaseURL: s.server.URL}))
+
+	s.rsp = `{"type": "sync", "result": [
+{"name": "foo", "refresh-inhibited-since": "2018-10-01T10:00:00Z"},
+{"name": "bar"}
+]}`
+	c.Assert(n.Check(), IsNil)
+	c.Check(s.notifications, DeepEquals, []string{
+		`Pending update of "foo" snap: Close the app to avoid disruptions`,
+	})
+
+	// nothing new
+	c.Assert(n.Check(), IsNil)
+	c.Check(s.notifications, HasLen, 1)
+
+	// the refresh happened and was postponed again later
+	s.rsp = `{"type": "sync", "result": [{"name": "foo"}]}`
+	c.Assert(n.Check(), IsNil)
+	c.Check(s.notifications, HasLen, 1)
+	s.rsp = `{"type": "sync", "result": [{"name": "foo", "refresh-inhibited-since": "2018-10-03T10:00:00Z"}]}`
+	c.Assert(n.Check(), IsNil)
+	c.Check(s.notifications, HasLen, 2)
+}
+
+func (s *refreshNotifySuite) TestCheckError(c *C) {
+	n := userd.NewRefreshNotifier(client.New(&client.Config{BaseURL: s.server.URL}))
+
+	s.rsp = `{"type": "error", "status-code": 500, "result": {"message": "boom"}}`
+	c.Check(n.Check(), ErrorMatches, "boom")
+	c.Check(s.notifications, HasLen, 0)
+}
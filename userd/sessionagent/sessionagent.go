@@ -0,0 +1,217 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+// Package sessionagent holds what snapd and the session agents (run by
+// "snap userd --agent" in each user session) share to talk to each
+// other, and the client snapd uses to reach all the running agents.
+package sessionagent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/snapcore/snapd/dirs"
+)
+
+// SocketName is the name of the socket the session agent listens on,
+// in the XDG_RUNTIME_DIR of the session.
+const SocketName = "snapd-session-agent.socket"
+
+// SocketPath returns the path of the socket of the session agent of
+// the given user.
+func SocketPath(uid int) string {
+	return filepath.Join(dirs.XdgRuntimeDirBase, strconv.Itoa(uid), SocketName)
+}
+
+// ServiceInstruction is what snapd asks a session agent to do with
+// the user session services.
+type ServiceInstruction struct {
+	// Action is one of "daemon-reload", "start", "stop" or "restart".
+	Action   string   `json:"action"`
+	Services []string `json:"services,omitempty"`
+}
+
+// Response is the body of the responses of the session agent.
+type Response struct {
+	Type   string          `json:"type"`
+	Result json.RawMessage `json:"result,omitempty"`
+}
+
+// ErrorResult is the result of an error Response.
+type ErrorResult struct {
+	Message string `json:"message"`
+}
+
+// Error is returned when some of the session agents failed to do
+// what was asked.
+type Error struct {
+	Action string
+	// Errors holds the error of each failed agent, by uid.
+	Errors map[int]error
+}
+
+func (e *Error) Error() string {
+	uids := make([]int, 0, len(e.Errors))
+	for uid := range e.Errors {
+		uids = append(uids, uid)
+	}
+	sort.Ints(uids)
+
+	msgs := make([]string, len(uids))
+	for i, uid := range uids {
+		msgs[i] = fmt.Sprintf("user %d: %v", uid, e.Errors[uid])
+	}
+	return fmt.Sprintf("cannot %s user services: %s", e.Action, strings.Join(msgs, "; "))
+}
+
+// Client talks to the session agents of all the current user sessions.
+type Client struct {
+	timeout time.Duration
+}
+
+// New returns a Client.
+func New() *Client {
+	return &Client{timeout: 61 * time.Second}
+}
+
+func (c *Client) do(uid int, inst *ServiceInstruction) error {
+	sockPath := SocketPath(uid)
+	cli := &http.Client{
+		Timeout: c.timeout,
+		Transport: &http.Transport{
+			Dial: func(_, _ string) (net.Conn, error) {
+				return net.Dial("unix", sockPath)
+			},
+		},
+	}
+
+	buf, err := json.Marshal(inst)
+	if err != nil {
+		return err
+	}
+	rsp, err := cli.Post("http://localhost/v1/service-control", "application/json", bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	defer rsp.Body.Close()
+
+	var r Response
+	if err := json.NewDecoder(rsp.Body).Decode(&r); err != nil {
+		return fmt.Errorf("cannot decode session agent response: %v", err)
+	}
+	if r.Type == "error" {
+		var e ErrorResult
+		if err := json.Unmarshal(r.Result, &e); err != nil {
+			return fmt.Errorf("cannot decode session agent error: %v", err)
+		}
+		return fmt.Errorf("%s", e.Message)
+	}
+	if rsp.StatusCode != 200 {
+		return fmt.Errorf("unexpected session agent response status %d", rsp.StatusCode)
+	}
+	return nil
+}
+
+// agentUids returns the uids of the users with a session agent socket.
+func agentUids() ([]int, error) {
+	matches, err := filepath.Glob(filepath.Join(dirs.XdgRuntimeDirBase, "*", SocketName))
+	if err != nil {
+		return nil, err
+	}
+	uids := make([]int, 0, len(matches))
+	for _, m := range matches {
+		uid, err := strconv.Atoi(filepath.Base(filepath.Dir(m)))
+		if err != nil {
+			continue
+		}
+		uids = append(uids, uid)
+	}
+	sort.Ints(uids)
+	return uids, nil
+}
+
+func (c *Client) doAll(inst *ServiceInstruction) error {
+	uids, err := agentUids()
+	if err != nil {
+		return err
+	}
+	errs := make(map[int]error)
+	for _, uid := range uids {
+		if err := c.do(uid, inst); err != nil {
+			if isNotListening(err) {
+				// stale socket, the session is gone
+				continue
+			}
+			errs[uid] = err
+		}
+	}
+	if len(errs) > 0 {
+		return &Error{Action: inst.Action, Errors: errs}
+	}
+	return nil
+}
+
+// isNotListening returns whether the error means nothing is
+// listening on the socket of the agent.
+func isNotListening(err error) bool {
+	if uerr, ok := err.(*url.Error); ok {
+		err = uerr.Err
+	}
+	if operr, ok := err.(*net.OpError); ok {
+		err = operr.Err
+	}
+	if syserr, ok := err.(*os.SyscallError); ok {
+		err = syserr.Err
+	}
+	return err == syscall.ECONNREFUSED || err == syscall.ENOENT
+}
+
+// ServicesDaemonReload asks all the session agents to reload the
+// configuration of their systemd instance.
+func (c *Client) ServicesDaemonReload() error {
+	return c.doAll(&ServiceInstruction{Action: "daemon-reload"})
+}
+
+// ServicesStart asks all the session agents to start the given
+// services.
+func (c *Client) ServicesStart(services []string) error {
+	return c.doAll(&ServiceInstruction{Action: "start", Services: services})
+}
+
+// ServicesStop asks all the session agents to stop the given services.
+func (c *Client) ServicesStop(services []string) error {
+	return c.doAll(&ServiceInstruction{Action: "stop", Services: services})
+}
+
+// ServicesRestart asks all the session agents to restart the given
+// services.
+func (c *Client) ServicesRestart(services []string) error {
+	return c.doAll(&ServiceInstruction{Action: "restart", Services: services})
+}
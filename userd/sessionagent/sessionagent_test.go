@@ -0,0 +1,122 @@
+// -*- Mode: Go; indent-tabs-mode: t -*-
+
+/*
+ * Copyright (C) 2018 Canonical Ltd
+ *
+ * This program is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU General Public License version 3 as
+ * published by the Free Software Foundation.
+ *
+ * This program is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU General Public License for more details.
+ *
+ * You should have received a copy of the GNU General Public License
+ * along with this program.  If not, see <http://www.gnu.org/licenses/>.
+ *
+ */
+
+package sessionagent_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "gopkg.in/check.v1"
+
+	"github.com/snapcore/snapd/dirs"
+	"github.com/snapcore/snapd/userd/sessionagent"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type sessionAgentSuite struct{}
+
+var _ = Suite(&sessionAgentSuite{})
+
+func (s *sessionAgentSuite) SetUpTest(c *C) {
+	dirs.SetRootDir(c.MkDir())
+}
+
+func (s *sessionAgentSuite) TearDownTest(c *C) {
+	dirs.SetRootDir("/")
+}
+
+func (s *sessionAgentSuite) TestSocketPath(c *C) {
+	c.Check(sessionagent.SocketPath(1000), Equals, filepath.Join(dirs.XdgRuntimeDirBase, "1000/snapd-session-agent.socket"))
+}
+
+// serve runs a fake agent for the given uid, returning what it was asked.
+func (s *sessionAgentSuite) serve(c *C, uid int, handler func(w http.ResponseWriter, inst *sessionagent.ServiceInstruction)) (insts *[]sessionagent.ServiceInstruction, stop func()) {
+	path := sessionagent.SocketPath(uid)
+	c.Assert(os.MkdirAll(filepath.Dir(path), 0700), IsNil)
+	l, err := net.Listen("unix", path)
+	c.Assert(err, IsNil)
+
+	insts = &[]sessionagent.ServiceInstruction{}
+	go http.Serve(l, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.Method, Equals, "POST")
+		c.Check(r.URL.Path, Equals, "/v1/service-control")
+		var inst sessionagent.ServiceInstruction
+		c.Check(json.NewDecoder(r.Body).Decode(&inst), IsNil)
+		*insts = append(*insts, inst)
+		handler(w, &inst)
+	}))
+	return insts, func() { l.Close() }
+}
+
+func (s *sessionAgentSuite) TestNoAgents(c *C) {
+	c.Check(sessionagent.New().ServicesStart([]string{"snap.foo.bar.service"}), IsNil)
+}
+
+func (s *sessionAgentSuite) TestAllAgents(c *C) {
+	ok := func(w http.ResponseWriter, inst *sessionagent.ServiceInstruction) {
+		fmt.Fprintln(w, `{"type": "sync"}`)
+	}
+	insts1, stop1 := s.serve(c, 1000, ok)
+	defer stop1()
+	insts2, stop2 := s.serve(c, 1001, ok)
+	defer stop2()
+
+	err := sessionagent.New().ServicesStop([]string{"snap.foo.bar.service"})
+	c.Assert(err, IsNil)
+	expected := []sessionagent.ServiceInstruction{{Action: "stop", Services: []string{"snap.foo.bar.service"}}}
+	c.Check(*insts1, DeepEquals, expected)
+	c.Check(*insts2, DeepEquals, expected)
+}
+
+func (s *sessionAgentSuite) TestStaleSocketIgnored(c *C) {
+	insts, stop := s.serve(c, 1000, nil)
+	// the session went away, leaving the socket behind
+	stop()
+
+	c.Check(sessionagent.New().ServicesDaemonReload(), IsNil)
+	c.Check(*insts, HasLen, 0)
+}
+
+func (s *sessionAgentSuite) TestErrors(c *C) {
+	_, stop1 := s.serve(c, 1000, func(w http.ResponseWriter, inst *sessionagent.ServiceInstruction) {
+		w.WriteHeader(500)
+		fmt.Fprintln(w, `{"type": "error", "result": {"message": "boom"}}`)
+	})
+	defer stop1()
+	_, stop2 := s.serve(c, 1001, func(w http.ResponseWriter, inst *sessionagent.ServiceInstruction) {
+		fmt.Fprintln(w, `{"type": "sync"}`)
+	})
+	defer stop2()
+	_, stop3 := s.serve(c, 1002, func(w http.ResponseWriter, inst *sessionagent.ServiceInstruction) {
+		fmt.Fprintln(w, `potato`)
+	})
+	defer stop3()
+
+	err := sessionagent.New().ServicesRestart([]string{"snap.foo.bar.service"})
+	c.Assert(err, FitsTypeOf, &sessionagent.Error{})
+	c.Check(err.(*sessionagent.Error).Errors, HasLen, 2)
+	c.Check(err, ErrorMatches, `cannot restart user services: user 1000: boom; user 1002: cannot decode session agent response: .*`)
+}
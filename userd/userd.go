@@ -26,6 +26,7 @@ import (
 	"github.com/godbus/dbus/introspect"
 	"gopkg.in/tomb.v2"
 
+	"github.com/snapcore/snapd/client"
 	"github.com/snapcore/snapd/logger"
 )
 
@@ -39,6 +40,7 @@ type Userd struct {
 	tomb       tomb.Tomb
 	conn       *dbus.Conn
 	dbusIfaces []dbusInterface
+	notifier   *refreshNotifier
 }
 
 func (ud *Userd) Init() error {
@@ -53,6 +55,7 @@ func (ud *Userd) Init() error {
 		&Launcher{ud.conn},
 		&Settings{ud.conn},
 	}
+	ud.notifier = newRefreshNotifier(ud.conn, client.New(nil))
 	for _, iface := range ud.dbusIfaces {
 		reply, err := ud.conn.RequestName(iface.Name(), dbus.NameFlagDoNotQueue)
 		if err != nil {
@@ -86,6 +89,9 @@ func (ud *Userd) Start() {
 		}
 		return nil
 	})
+	ud.tomb.Go(func() error {
+		return ud.notifier.run(ud.tomb.Dying())
+	})
 }
 
 func (ud *Userd) Stop() error {
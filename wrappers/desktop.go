@@ -109,14 +109,22 @@ func rewriteExecLine(s *snap.Info, desktopFile, line string) (string, error) {
 	cmd := strings.SplitN(line, "=", 2)[1]
 	for _, app := range s.Apps {
 		wrapper := app.WrapperPath()
-		validCmd := filepath.Base(wrapper)
-		// check the prefix to allow %flag style args
-		// this is ok because desktop files are not run through sh
-		// so we don't have to worry about the arguments too much
-		if cmd == validCmd {
-			return "Exec=" + env + wrapper, nil
-		} else if strings.HasPrefix(cmd, validCmd+" ") {
-			return fmt.Sprintf("Exec=%s%s%s", env, wrapper, line[len("Exec=")+len(validCmd):]), nil
+		validCmds := []string{filepath.Base(wrapper)}
+		if s.InstanceKey != "" {
+			// the desktop file refers to the command as
+			// shipped by the snap, not as exposed by the
+			// instance
+			validCmds = append(validCmds, snap.JoinSnapApp(s.SnapName(), app.Name))
+		}
+		for _, validCmd := range validCmds {
+			// check the prefix to allow %flag style args
+			// this is ok because desktop files are not run through sh
+			// so we don't have to worry about the arguments too much
+			if cmd == validCmd {
+				return "Exec=" + env + wrapper, nil
+			} else if strings.HasPrefix(cmd, validCmd+" ") {
+				return fmt.Sprintf("Exec=%s%s%s", env, wrapper, line[len("Exec=")+len(validCmd):]), nil
+			}
 		}
 	}
 
@@ -214,7 +222,7 @@ func AddSnapDesktopFiles(s *snap.Info) (err error) {
 			return err
 		}
 
-		installedDesktopFileName := filepath.Join(dirs.SnapDesktopFilesDir, fmt.Sprintf("%s_%s", s.Name(), filepath.Base(df)))
+		installedDesktopFileName := filepath.Join(dirs.SnapDesktopFilesDir, fmt.Sprintf("%s_%s", s.DesktopPrefix(), filepath.Base(df)))
 		content = sanitizeDesktopFile(s, installedDesktopFileName, content)
 		if err := osutil.AtomicWriteFile(installedDesktopFileName, content, 0755, 0); err != nil {
 			return err
@@ -232,7 +240,7 @@ func AddSnapDesktopFiles(s *snap.Info) (err error) {
 
 // RemoveSnapDesktopFiles removes the added desktop files for the applications in the snap.
 func RemoveSnapDesktopFiles(s *snap.Info) error {
-	glob := filepath.Join(dirs.SnapDesktopFilesDir, s.Name()+"_*.desktop")
+	glob := filepath.Join(dirs.SnapDesktopFilesDir, s.DesktopPrefix()+"_*.desktop")
 	activeDesktopFiles, err := filepath.Glob(glob)
 	if err != nil {
 		return fmt.Errorf("cannot get desktop files for %v: %s", glob, err)
@@ -110,6 +110,26 @@ func (s *desktopSuite) TestRemovePackageDesktopFiles(c *C) {
 	})
 }
 
+func (s *desktopSuite) TestRemovePackageDesktopFilesKeepsInstances(c *C) {
+	mockDesktopFilePath := filepath.Join(dirs.SnapDesktopFilesDir, "foo_foobar.desktop")
+	mockInstanceDesktopFilePath := filepath.Join(dirs.SnapDesktopFilesDir, "foo+bar_foobar.desktop")
+
+	err := os.MkdirAll(dirs.SnapDesktopFilesDir, 0755)
+	c.Assert(err, IsNil)
+	for _, fn := range []string{mockDesktopFilePath, mockInstanceDesktopFilePath} {
+		err = ioutil.WriteFile(fn, mockDesktopFile, 0644)
+		c.Assert(err, IsNil)
+	}
+	info, err := snap.InfoFromSnapYaml([]byte(desktopAppYaml))
+	c.Assert(err, IsNil)
+	info.InstanceKey = "bar"
+
+	err = wrappers.RemoveSnapDesktopFiles(info)
+	c.Assert(err, IsNil)
+	c.Assert(osutil.FileExists(mockDesktopFilePath), Equals, true)
+	c.Assert(osutil.FileExists(mockInstanceDesktopFilePath), Equals, false)
+}
+
 func (s *desktopSuite) TestAddPackageDesktopFilesCleanup(c *C) {
 	mockDesktopFilePath := filepath.Join(dirs.SnapDesktopFilesDir, "foo_foobar1.desktop")
 	c.Assert(osutil.FileExists(mockDesktopFilePath), Equals, false)
@@ -347,6 +367,24 @@ apps:
 	c.Assert(newl, Equals, fmt.Sprintf("Exec=env BAMF_DESKTOP_FILE_HINT=foo.desktop %s/bin/snap.app", dirs.SnapMountDir))
 }
 
+func (s *sanitizeDesktopFileSuite) TestRewriteExecLineInstance(c *C) {
+	snap, err := snap.InfoFromSnapYaml([]byte(`
+name: snap
+version: 1.0
+apps:
+ app:
+  command: cmd
+`))
+	c.Assert(err, IsNil)
+	snap.InstanceKey = "foo"
+
+	for _, line := range []string{"Exec=snap.app", "Exec=snap_foo.app"} {
+		newl, err := wrappers.RewriteExecLine(snap, "foo.desktop", line)
+		c.Assert(err, IsNil)
+		c.Check(newl, Equals, fmt.Sprintf("Exec=env BAMF_DESKTOP_FILE_HINT=foo.desktop %s/bin/snap_foo.app", dirs.SnapMountDir))
+	}
+}
+
 func (s *sanitizeDesktopFileSuite) TestLangLang(c *C) {
 	langs := []struct {
 		line    string
@@ -45,3 +45,13 @@ func MockKillWait(wait time.Duration) (restore func()) {
 		killWait = oldKillWait
 	}
 }
+
+func MockUserServiceClient(cli userServiceClient) (restore func()) {
+	oldNewUserServiceClient := newUserServiceClient
+	newUserServiceClient = func() userServiceClient {
+		return cli
+	}
+	return func() {
+		newUserServiceClient = oldNewUserServiceClient
+	}
+}
@@ -25,6 +25,7 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"text/template"
 	"time"
@@ -33,9 +34,11 @@ import (
 	"github.com/snapcore/snapd/logger"
 	"github.com/snapcore/snapd/osutil"
 	"github.com/snapcore/snapd/snap"
+	"github.com/snapcore/snapd/strutil"
 	"github.com/snapcore/snapd/systemd"
 	"github.com/snapcore/snapd/timeout"
 	"github.com/snapcore/snapd/timeutil"
+	"github.com/snapcore/snapd/userd/sessionagent"
 )
 
 type interacter interface {
@@ -45,6 +48,24 @@ type interacter interface {
 // wait this time between TERM and KILL
 var killWait = 5 * time.Second
 
+// userServiceClient manages the user session services of snaps in all
+// the current user sessions.
+type userServiceClient interface {
+	ServicesDaemonReload() error
+	ServicesStart(services []string) error
+	ServicesStop(services []string) error
+}
+
+var newUserServiceClient = func() userServiceClient {
+	return sessionagent.New()
+}
+
+// newUserSystemd returns a Systemd to enable and disable user session
+// services for all the users.
+func newUserSystemd(inter interacter) systemd.Systemd {
+	return systemd.NewWithMode(dirs.GlobalRootDir, systemd.GlobalUserMode, inter)
+}
+
 func serviceStopTimeout(app *snap.AppInfo) time.Duration {
 	tout := app.StopTimeout
 	if tout == 0 {
@@ -98,15 +119,31 @@ func stopService(sysd systemd.Systemd, app *snap.AppInfo, inter interacter) erro
 }
 
 // StartServices starts service units for the applications from the snap which are services.
-func StartServices(apps []*snap.AppInfo, inter interacter) (err error) {
+// Services whose names are in disabledSvcs are left alone.
+func StartServices(apps []*snap.AppInfo, disabledSvcs []string, inter interacter) (err error) {
 	sysd := systemd.New(dirs.GlobalRootDir, inter)
 
 	services := make([]string, 0, len(apps))
+	var userServices []string
 	for _, app := range apps {
 		// they're *supposed* to be all services, but checking doesn't hurt
 		if !app.IsService() {
 			continue
 		}
+		if strutil.ListContains(disabledSvcs, app.Name) {
+			continue
+		}
+
+		if app.IsUserService() {
+			// these are enabled for all users already, running
+			// them in the current sessions is best effort
+			if app.Timer != nil {
+				userServices = append(userServices, filepath.Base(app.Timer.File()))
+			} else {
+				userServices = append(userServices, app.ServiceName())
+			}
+			continue
+		}
 
 		defer func(app *snap.AppInfo) {
 			if err == nil {
@@ -166,14 +203,22 @@ func StartServices(apps []*snap.AppInfo, inter interacter) (err error) {
 		}
 	}
 
+	if len(userServices) > 0 {
+		if err := newUserServiceClient().ServicesStart(userServices); err != nil {
+			logger.Noticef("cannot start services in user sessions: %v", err)
+		}
+	}
+
 	return nil
 }
 
 // AddSnapServices adds service units for the applications from the snap which are services.
-func AddSnapServices(s *snap.Info, inter interacter) (err error) {
+// Services whose names are in disabledSvcs are not enabled.
+func AddSnapServices(s *snap.Info, disabledSvcs []string, inter interacter) (err error) {
 	sysd := systemd.New(dirs.GlobalRootDir, inter)
-	var written []string
-	var enabled []string
+	userSysd := newUserSystemd(inter)
+	var written, userWritten []string
+	var enabled, userEnabled []string
 	defer func() {
 		if err == nil {
 			return
@@ -183,7 +228,12 @@ func AddSnapServices(s *snap.Info, inter interacter) (err error) {
 				inter.Notify(fmt.Sprintf("while trying to disable %s due to previous failure: %v", s, e))
 			}
 		}
-		for _, s := range written {
+		for _, s := range userEnabled {
+			if e := userSysd.Disable(s); e != nil {
+				inter.Notify(fmt.Sprintf("while trying to disable %s due to previous failure: %v", s, e))
+			}
+		}
+		for _, s := range append(written, userWritten...) {
 			if e := os.Remove(s); e != nil {
 				inter.Notify(fmt.Sprintf("while trying to remove %s due to previous failure: %v", s, e))
 			}
@@ -199,6 +249,18 @@ func AddSnapServices(s *snap.Info, inter interacter) (err error) {
 		if !app.IsService() {
 			continue
 		}
+		disabled := strutil.ListContains(disabledSvcs, app.Name)
+		if app.IsUserService() {
+			paths, err := addUserService(userSysd, app, disabled)
+			userWritten = append(userWritten, paths...)
+			if err != nil {
+				return err
+			}
+			if !disabled {
+				userEnabled = append(userEnabled, filepath.Base(paths[len(paths)-1]))
+			}
+			continue
+		}
 		// Generate service file
 		content, err := generateSnapServiceFile(app)
 		if err != nil {
@@ -242,6 +304,9 @@ func AddSnapServices(s *snap.Info, inter interacter) (err error) {
 			// boot
 			continue
 		}
+		if disabled {
+			continue
+		}
 
 		svcName := app.ServiceName()
 		if err := sysd.Enable(svcName); err != nil {
@@ -256,14 +321,113 @@ func AddSnapServices(s *snap.Info, inter interacter) (err error) {
 		}
 	}
 
+	if len(userWritten) > 0 {
+		// the user sessions pick up the units on their next start
+		// anyway, so reloading the current ones is best effort
+		if err := newUserServiceClient().ServicesDaemonReload(); err != nil {
+			logger.Noticef("cannot reload services in user sessions: %v", err)
+		}
+	}
+
 	return nil
 }
 
+// addUserService writes the units of the given user session service
+// and, unless disabled, enables them for all users; it returns the
+// paths of the units written, with the one to enable last.
+func addUserService(userSysd systemd.Systemd, app *snap.AppInfo, disabled bool) (written []string, err error) {
+	content, err := generateSnapServiceFile(app)
+	if err != nil {
+		return nil, err
+	}
+	svcFilePath := app.ServiceFile()
+	os.MkdirAll(filepath.Dir(svcFilePath), 0755)
+	if err := osutil.AtomicWriteFile(svcFilePath, content, 0644, 0); err != nil {
+		return nil, err
+	}
+	written = append(written, svcFilePath)
+
+	if app.Timer != nil {
+		content, err := generateSnapTimerFile(app)
+		if err != nil {
+			return written, err
+		}
+		path := app.Timer.File()
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := osutil.AtomicWriteFile(path, content, 0644, 0); err != nil {
+			return written, err
+		}
+		written = append(written, path)
+	}
+
+	if disabled {
+		return written, nil
+	}
+
+	// enable the timer if there is one, the service otherwise
+	if err := userSysd.Enable(filepath.Base(written[len(written)-1])); err != nil {
+		return written, err
+	}
+
+	return written, nil
+}
+
+// QueryDisabledServices returns the names of the services of the snap
+// that are disabled, i.e. that will not be started on boot (or, for
+// user services, on login) nor activated by their timer or sockets.
+func QueryDisabledServices(s *snap.Info, inter interacter) ([]string, error) {
+	sysd := systemd.New(dirs.GlobalRootDir, inter)
+	userSysd := newUserSystemd(inter)
+
+	var disabled []string
+	for _, app := range s.Services() {
+		// skip services without a unit, as in StopServices
+		if !osutil.FileExists(app.ServiceFile()) {
+			continue
+		}
+		unitSysd := sysd
+		if app.IsUserService() {
+			unitSysd = userSysd
+		}
+		// the units that get enabled, see AddSnapServices and
+		// StartServices
+		var units []string
+		switch {
+		case app.Timer != nil:
+			units = []string{filepath.Base(app.Timer.File())}
+		case len(app.Sockets) > 0:
+			for _, socket := range app.Sockets {
+				units = append(units, filepath.Base(socket.File()))
+			}
+		default:
+			units = []string{app.ServiceName()}
+		}
+		enabled := false
+		for _, unit := range units {
+			isEnabled, err := unitSysd.IsEnabled(unit)
+			if err != nil {
+				return nil, err
+			}
+			if isEnabled {
+				enabled = true
+				break
+			}
+		}
+		if !enabled {
+			disabled = append(disabled, app.Name)
+		}
+	}
+	sort.Strings(disabled)
+
+	return disabled, nil
+}
+
 // StopServices stops service units for the applications from the snap which are services.
 func StopServices(apps []*snap.AppInfo, reason snap.ServiceStopReason, inter interacter) error {
 	sysd := systemd.New(dirs.GlobalRootDir, inter)
 
 	logger.Debugf("StopServices called for %q, reason: %v", apps, reason)
+	var userServices []string
 	for _, app := range apps {
 		// Handle the case where service file doesn't exist and don't try to stop it as it will fail.
 		// This can happen with snap try when snap.yaml is modified on the fly and a daemon line is added.
@@ -280,6 +444,13 @@ func StopServices(apps []*snap.AppInfo, reason snap.ServiceStopReason, inter int
 				continue
 			}
 		}
+		if app.IsUserService() {
+			if app.Timer != nil {
+				userServices = append(userServices, filepath.Base(app.Timer.File()))
+			}
+			userServices = append(userServices, app.ServiceName())
+			continue
+		}
 		if err := stopService(sysd, app, inter); err != nil {
 			return err
 		}
@@ -296,6 +467,12 @@ func StopServices(apps []*snap.AppInfo, reason snap.ServiceStopReason, inter int
 		}
 	}
 
+	if len(userServices) > 0 {
+		if err := newUserServiceClient().ServicesStop(userServices); err != nil {
+			logger.Noticef("cannot stop services in user sessions: %v", err)
+		}
+	}
+
 	return nil
 
 }
@@ -304,11 +481,19 @@ func StopServices(apps []*snap.AppInfo, reason snap.ServiceStopReason, inter int
 func RemoveSnapServices(s *snap.Info, inter interacter) error {
 	sysd := systemd.New(dirs.GlobalRootDir, inter)
 	nservices := 0
+	nuserServices := 0
 
 	for _, app := range s.Apps {
 		if !app.IsService() || !osutil.FileExists(app.ServiceFile()) {
 			continue
 		}
+		if app.IsUserService() {
+			nuserServices++
+			if err := removeUserService(newUserSystemd(inter), app); err != nil {
+				return err
+			}
+			continue
+		}
 		nservices++
 
 		serviceName := filepath.Base(app.ServiceFile())
@@ -355,6 +540,30 @@ func RemoveSnapServices(s *snap.Info, inter interacter) error {
 		}
 	}
 
+	if nuserServices > 0 {
+		if err := newUserServiceClient().ServicesDaemonReload(); err != nil {
+			logger.Noticef("cannot reload services in user sessions: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// removeUserService disables the units of the given user session
+// service for all users and removes them.
+func removeUserService(userSysd systemd.Systemd, app *snap.AppInfo) error {
+	paths := []string{app.ServiceFile()}
+	if app.Timer != nil {
+		paths = append(paths, app.Timer.File())
+	}
+	for _, path := range paths {
+		if err := userSysd.Disable(filepath.Base(path)); err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			logger.Noticef("Failed to remove %q for %q: %v", path, app.ServiceName(), err)
+		}
+	}
 	return nil
 }
 
@@ -373,9 +582,13 @@ func genServiceFile(appInfo *snap.AppInfo) []byte {
 	serviceTemplate := `[Unit]
 # Auto-generated, DO NOT EDIT
 Description=Service for snap application {{.App.Snap.Name}}.{{.App.Name}}
+{{- if .MountUnit}}
 Requires={{.MountUnit}}
 Wants={{.PrerequisiteTarget}}
 After={{.MountUnit}} {{.PrerequisiteTarget}}{{range .After}} {{.}}{{end}}
+{{- else if .After}}
+After={{range $i, $a := .After}}{{if $i}} {{end}}{{$a}}{{end}}
+{{- end}}
 {{- if .Before}}
 Before={{ range .Before -}}{{.}} {{- end}}
 {{- end}}
@@ -385,7 +598,7 @@ X-Snappy=yes
 ExecStart={{.App.LauncherCommand}}
 SyslogIdentifier={{.App.Snap.Name}}.{{.App.Name}}
 Restart={{.Restart}}
-WorkingDirectory={{.App.Snap.DataDir}}
+WorkingDirectory={{.WorkingDir}}
 {{- if .App.StopCommand}}
 ExecStop={{.App.LauncherStopCommand}}
 {{- end}}
@@ -447,6 +660,7 @@ WantedBy={{.ServicesTarget}}
 		App *snap.AppInfo
 
 		Restart            string
+		WorkingDir         string
 		StopTimeout        time.Duration
 		ServicesTarget     string
 		PrerequisiteTarget string
@@ -463,6 +677,7 @@ WantedBy={{.ServicesTarget}}
 		App: appInfo,
 
 		Restart:            restartCond,
+		WorkingDir:         appInfo.Snap.DataDir(),
 		StopTimeout:        serviceStopTimeout(appInfo),
 		ServicesTarget:     systemd.ServicesTarget,
 		PrerequisiteTarget: systemd.PrerequisiteTarget,
@@ -477,6 +692,14 @@ WantedBy={{.ServicesTarget}}
 		// systemd runs as PID 1 so %h will not work.
 		Home: "/root",
 	}
+	if appInfo.IsUserService() {
+		// the instance of systemd of the user session cannot see
+		// the mount units, and runs as the user
+		wrapperData.WorkingDir = appInfo.Snap.UserDataDir("%h")
+		wrapperData.ServicesTarget = systemd.UserServicesTarget
+		wrapperData.PrerequisiteTarget = ""
+		wrapperData.MountUnit = ""
+	}
 
 	if err := t.Execute(&templateOut, wrapperData); err != nil {
 		// this can never happen, except we forget a variable
@@ -559,8 +782,10 @@ func generateSnapTimerFile(app *snap.AppInfo) ([]byte, error) {
 	timerTemplate := `[Unit]
 # Auto-generated, DO NOT EDIT
 Description=Timer {{.TimerName}} for snap application {{.App.Snap.Name}}.{{.App.Name}}
+{{- if .MountUnit}}
 Requires={{.MountUnit}}
 After={{.MountUnit}}
+{{- end}}
 X-Snappy=yes
 
 [Timer]
@@ -595,6 +820,9 @@ WantedBy={{.TimersTarget}}
 		MountUnit:       filepath.Base(systemd.MountUnitPath(app.Snap.MountDir())),
 		Schedules:       schedules,
 	}
+	if app.IsUserService() {
+		wrapperData.MountUnit = ""
+	}
 
 	if err := t.Execute(&templateOut, wrapperData); err != nil {
 		// this can never happen, except we forget a variable
@@ -130,6 +130,59 @@ apps:
 	c.Check(string(generatedWrapper), Equals, expectedAppService)
 }
 
+func (s *servicesWrapperGenSuite) TestGenerateSnapUserServiceFile(c *C) {
+	yamlText := `
+name: snap
+version: 1.0
+apps:
+    app:
+        command: bin/start
+        stop-command: bin/stop
+        stop-timeout: 10s
+        daemon: simple
+        daemon-scope: user
+        after: [other]
+    other:
+        command: bin/other
+        daemon: simple
+        daemon-scope: user
+`
+	info, err := snap.InfoFromSnapYaml([]byte(yamlText))
+	c.Assert(err, IsNil)
+	info.Revision = snap.R(44)
+	app := info.Apps["app"]
+
+	generatedWrapper, err := wrappers.GenerateSnapServiceFile(app)
+	c.Assert(err, IsNil)
+	c.Check(string(generatedWrapper), Equals, `[Unit]
+# Auto-generated, DO NOT EDIT
+Description=Service for snap application snap.app
+After=snap.snap.other.service
+X-Snappy=yes
+
+[Service]
+ExecStart=/usr/bin/snap run snap.app
+SyslogIdentifier=snap.app
+Restart=on-failure
+WorkingDirectory=%h/snap/snap/44
+ExecStop=/usr/bin/snap run --command=stop snap.app
+TimeoutStopSec=10
+Type=simple
+
+[Install]
+WantedBy=default.target
+`)
+
+	generatedWrapper, err = wrappers.GenerateSnapServiceFile(info.Apps["other"])
+	c.Assert(err, IsNil)
+	c.Check(string(generatedWrapper), Matches, `(?s)\[Unit\]
+# Auto-generated, DO NOT EDIT
+Description=Service for snap application snap.other
+X-Snappy=yes
+
+\[Service\].*`)
+}
+
 func (s *servicesWrapperGenSuite) TestGenerateSnapServiceFileRestart(c *C) {
 	yamlTextTemplate := `
 name: snap
@@ -377,6 +430,41 @@ WantedBy=timers.target
 	c.Assert(string(generatedWrapper), Equals, expectedService)
 }
 
+func (s *servicesWrapperGenSuite) TestUserServiceTimerUnit(c *C) {
+	service := &snap.AppInfo{
+		Snap: &snap.Info{
+			SuggestedName: "snap",
+			Version:       "0.3.4",
+			SideInfo:      snap.SideInfo{Revision: snap.R(44)},
+		},
+		Name:        "app",
+		Command:     "bin/foo start",
+		Daemon:      "simple",
+		DaemonScope: snap.UserDaemon,
+		StopTimeout: timeout.DefaultTimeout,
+		Timer: &snap.TimerInfo{
+			Timer: "10:00-12:00/2",
+		},
+	}
+	service.Timer.App = service
+
+	generatedWrapper, err := wrappers.GenerateSnapTimerFile(service)
+	c.Assert(err, IsNil)
+	c.Check(string(generatedWrapper), Equals, `[Unit]
+# Auto-generated, DO NOT EDIT
+Description=Timer app for snap application snap.app
+X-Snappy=yes
+
+[Timer]
+Unit=snap.snap.app.service
+OnCalendar=*-*-* 10:00
+OnCalendar=*-*-* 11:00
+
+[Install]
+WantedBy=timers.target
+`)
+}
+
 func (s *servicesWrapperGenSuite) TestServiceTimerUnitBadTimer(c *C) {
 	service := &snap.AppInfo{
 		Snap: &snap.Info{
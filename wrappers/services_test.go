@@ -22,6 +22,7 @@ package wrappers_test
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
 	"regexp"
 	"sort"
@@ -74,7 +75,7 @@ func (s *servicesTestSuite) TestAddSnapServicesAndRemove(c *C) {
 	info := snaptest.MockSnap(c, packageHello, &snap.SideInfo{Revision: snap.R(12)})
 	svcFile := filepath.Join(s.tempdir, "/etc/systemd/system/snap.hello-snap.svc1.service")
 
-	err := wrappers.AddSnapServices(info, nil)
+	err := wrappers.AddSnapServices(info, nil, nil)
 	c.Assert(err, IsNil)
 	c.Check(sysdLog, DeepEquals, [][]string{
 		{"--root", dirs.GlobalRootDir, "enable", filepath.Base(svcFile)},
@@ -122,7 +123,7 @@ func (s *servicesTestSuite) TestRemoveSnapWithSocketsRemovesSocketsService(c *C)
       listen-stream: $SNAP_COMMON/sock2.socket
 `, &snap.SideInfo{Revision: snap.R(12)})
 
-	err := wrappers.AddSnapServices(info, nil)
+	err := wrappers.AddSnapServices(info, nil, nil)
 	c.Assert(err, IsNil)
 
 	err = wrappers.StopServices(info.Services(), "", &progress.Null)
@@ -162,7 +163,7 @@ apps:
    daemon: forking
 `, &snap.SideInfo{Revision: snap.R(11)})
 
-	err := wrappers.AddSnapServices(info, nil)
+	err := wrappers.AddSnapServices(info, nil, nil)
 	c.Assert(err, IsNil)
 
 	sysdLog = nil
@@ -202,7 +203,7 @@ func (s *servicesTestSuite) TestStopServicesWithSockets(c *C) {
       listen-stream: $SNAP_DATA/sock2.socket
 `, &snap.SideInfo{Revision: snap.R(12)})
 
-	err := wrappers.AddSnapServices(info, nil)
+	err := wrappers.AddSnapServices(info, nil, nil)
 	c.Assert(err, IsNil)
 
 	sysdLog = nil
@@ -226,12 +227,82 @@ func (s *servicesTestSuite) TestStartServices(c *C) {
 	info := snaptest.MockSnap(c, packageHello, &snap.SideInfo{Revision: snap.R(12)})
 	svcFile := filepath.Join(s.tempdir, "/etc/systemd/system/snap.hello-snap.svc1.service")
 
-	err := wrappers.StartServices(info.Services(), nil)
+	err := wrappers.StartServices(info.Services(), nil, nil)
 	c.Assert(err, IsNil)
 
 	c.Assert(sysdLog, DeepEquals, [][]string{{"start", filepath.Base(svcFile)}})
 }
 
+func (s *servicesTestSuite) TestAddAndStartSnapServicesDisabled(c *C) {
+	var sysdLog [][]string
+	r := systemd.MockSystemctl(func(cmd ...string) ([]byte, error) {
+		sysdLog = append(sysdLog, cmd)
+		return []byte("ActiveState=inactive\n"), nil
+	})
+	defer r()
+
+	info := snaptest.MockSnap(c, packageHello+`
+ svc2:
+  command: bin/hello
+  daemon: simple
+`, &snap.SideInfo{Revision: snap.R(12)})
+
+	err := wrappers.AddSnapServices(info, []string{"svc1"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(sysdLog, DeepEquals, [][]string{
+		{"--root", dirs.GlobalRootDir, "enable", "snap.hello-snap.svc2.service"},
+		{"daemon-reload"},
+	})
+
+	sysdLog = nil
+	err = wrappers.StartServices(info.Services(), []string{"svc1"}, nil)
+	c.Assert(err, IsNil)
+	c.Check(sysdLog, DeepEquals, [][]string{
+		{"start", "snap.hello-snap.svc2.service"},
+	})
+}
+
+func (s *servicesTestSuite) TestQueryDisabledServices(c *C) {
+	// use a mock systemctl command, so that it exits like the real one
+	s.restorer()
+	s.restorer = func() {}
+	cmd := testutil.MockCommand(c, "systemctl", `
+case "$*" in
+    *snap.hello-snap.svc1.service|*snap.hello-snap.svc3.timer)
+        echo disabled
+        exit 1
+        ;;
+esac
+echo enabled
+`)
+	defer cmd.Restore()
+
+	info := snaptest.MockSnap(c, packageHello+`
+ svc2:
+  command: bin/hello
+  daemon: simple
+ svc3:
+  command: bin/hello
+  daemon: simple
+  timer: 10:00-12:00
+ svc4:
+  command: bin/hello
+  daemon: simple
+`, &snap.SideInfo{Revision: snap.R(12)})
+	// svc4 has no unit, as with snap try
+	for _, name := range []string{"svc1", "svc2", "svc3"} {
+		app := info.Apps[name]
+		c.Assert(os.MkdirAll(filepath.Dir(app.ServiceFile()), 0755), IsNil)
+		c.Assert(ioutil.WriteFile(app.ServiceFile(), nil, 0644), IsNil)
+	}
+
+	disabled, err := wrappers.QueryDisabledServices(info, progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(disabled, DeepEquals, []string{"svc1", "svc3"})
+	c.Check(cmd.Calls(), HasLen, 3)
+	c.Check(cmd.Calls(), testutil.DeepContains, []string{"systemctl", "--root", dirs.GlobalRootDir, "is-enabled", "snap.hello-snap.svc3.timer"})
+}
+
 func (s *servicesTestSuite) TestAddSnapMultiServicesFailCreateCleanup(c *C) {
 	var sysdLog [][]string
 
@@ -250,7 +321,7 @@ func (s *servicesTestSuite) TestAddSnapMultiServicesFailCreateCleanup(c *C) {
   daemon: potato
 `, &snap.SideInfo{Revision: snap.R(12)})
 
-	err := wrappers.AddSnapServices(info, nil)
+	err := wrappers.AddSnapServices(info, nil, nil)
 	c.Assert(err, ErrorMatches, ".*potato.*")
 
 	// the services are cleaned up
@@ -315,7 +386,7 @@ func (s *servicesTestSuite) TestAddSnapMultiServicesFailEnableCleanup(c *C) {
   daemon: simple
 `, &snap.SideInfo{Revision: snap.R(12)})
 
-	err := wrappers.AddSnapServices(info, nil)
+	err := wrappers.AddSnapServices(info, nil, nil)
 	c.Assert(err, ErrorMatches, "failed")
 
 	// the services are cleaned up
@@ -363,7 +434,7 @@ func (s *servicesTestSuite) TestAddSnapMultiServicesStartFailOnSystemdReloadClea
   daemon: simple
 `, &snap.SideInfo{Revision: snap.R(12)})
 
-	err := wrappers.AddSnapServices(info, progress.Null)
+	err := wrappers.AddSnapServices(info, nil, progress.Null)
 	c.Assert(err, ErrorMatches, "failed")
 
 	// the services are cleaned up
@@ -403,7 +474,7 @@ func (s *servicesTestSuite) TestAddSnapSocketFiles(c *C) {
 	sock1File := filepath.Join(s.tempdir, "/etc/systemd/system/snap.hello-snap.svc1.sock1.socket")
 	sock2File := filepath.Join(s.tempdir, "/etc/systemd/system/snap.hello-snap.svc1.sock2.socket")
 
-	err := wrappers.AddSnapServices(info, nil)
+	err := wrappers.AddSnapServices(info, nil, nil)
 	c.Assert(err, IsNil)
 
 	expected := fmt.Sprintf(
@@ -451,7 +522,7 @@ func (s *servicesTestSuite) TestStartSnapMultiServicesFailStartCleanup(c *C) {
   daemon: simple
 `, &snap.SideInfo{Revision: snap.R(12)})
 
-	err := wrappers.StartServices(info.Services(), nil)
+	err := wrappers.StartServices(info.Services(), nil, nil)
 	c.Assert(err, ErrorMatches, "failed")
 	c.Assert(sysdLog, HasLen, 5, Commentf("len: %v calls: %v", len(sysdLog), sysdLog))
 	c.Check(sysdLog, DeepEquals, [][]string{
@@ -502,7 +573,7 @@ func (s *servicesTestSuite) TestStartSnapMultiServicesFailStartCleanupWithSocket
 	// ensure desired order
 	apps := []*snap.AppInfo{info.Apps["svc1"], info.Apps["svc2"], info.Apps["svc3"]}
 
-	err := wrappers.StartServices(apps, nil)
+	err := wrappers.StartServices(apps, nil, nil)
 	c.Assert(err, ErrorMatches, "failed")
 	c.Logf("sysdlog: %v", sysdLog)
 	c.Assert(sysdLog, HasLen, 16, Commentf("len: %v calls: %v", len(sysdLog), sysdLog))
@@ -578,7 +649,7 @@ func (s *servicesTestSuite) TestServiceAfterBefore(c *C) {
 		},
 	}}
 
-	err := wrappers.AddSnapServices(info, nil)
+	err := wrappers.AddSnapServices(info, nil, nil)
 	c.Assert(err, IsNil)
 
 	for _, check := range checks {
@@ -626,7 +697,7 @@ func (s *servicesTestSuite) TestServiceWatchdog(c *C) {
 `
 	info := snaptest.MockSnap(c, snapYaml, &snap.SideInfo{Revision: snap.R(12)})
 
-	err := wrappers.AddSnapServices(info, nil)
+	err := wrappers.AddSnapServices(info, nil, nil)
 	c.Assert(err, IsNil)
 
 	content, err := ioutil.ReadFile(filepath.Join(s.tempdir, "/etc/systemd/system/snap.hello-snap.svc2.service"))
@@ -663,7 +734,7 @@ apps:
 	info := snaptest.MockSnap(c, surviveYaml, &snap.SideInfo{Revision: snap.R(1)})
 	survivorFile := filepath.Join(s.tempdir, "/etc/systemd/system/snap.survive-snap.survivor.service")
 
-	err := wrappers.AddSnapServices(info, nil)
+	err := wrappers.AddSnapServices(info, nil, nil)
 	c.Assert(err, IsNil)
 	c.Check(sysdLog, DeepEquals, [][]string{
 		{"--root", dirs.GlobalRootDir, "enable", filepath.Base(survivorFile)},
@@ -722,7 +793,7 @@ apps:
 		info := snaptest.MockSnap(c, surviveYaml, &snap.SideInfo{Revision: snap.R(1)})
 
 		sysdLog = nil
-		err := wrappers.AddSnapServices(info, nil)
+		err := wrappers.AddSnapServices(info, nil, nil)
 		c.Assert(err, IsNil)
 		c.Check(sysdLog, DeepEquals, [][]string{
 			{"--root", dirs.GlobalRootDir, "enable", filepath.Base(survivorFile)},
@@ -779,7 +850,7 @@ func (s *servicesTestSuite) TestStartSnapTimerEnableStart(c *C) {
   timer: 10:00-12:00
 `, &snap.SideInfo{Revision: snap.R(12)})
 
-	err := wrappers.StartServices(info.Services(), nil)
+	err := wrappers.StartServices(info.Services(), nil, nil)
 	c.Assert(err, IsNil)
 	c.Assert(sysdLog, HasLen, 3, Commentf("len: %v calls: %v", len(sysdLog), sysdLog))
 	c.Check(sysdLog, DeepEquals, [][]string{
@@ -813,7 +884,7 @@ func (s *servicesTestSuite) TestStartSnapTimerCleanup(c *C) {
 
 	// fix the apps order to make the test stable
 	apps := []*snap.AppInfo{info.Apps["svc1"], info.Apps["svc2"]}
-	err := wrappers.StartServices(apps, nil)
+	err := wrappers.StartServices(apps, nil, nil)
 	c.Assert(err, ErrorMatches, "failed")
 	c.Assert(sysdLog, HasLen, 9, Commentf("len: %v calls: %v", len(sysdLog), sysdLog))
 	c.Check(sysdLog, DeepEquals, [][]string{
@@ -837,7 +908,7 @@ func (s *servicesTestSuite) TestAddRemoveSnapWithTimersAddsRemovesTimerFiles(c *
   timer: 10:00-12:00
 `, &snap.SideInfo{Revision: snap.R(12)})
 
-	err := wrappers.AddSnapServices(info, nil)
+	err := wrappers.AddSnapServices(info, nil, nil)
 	c.Assert(err, IsNil)
 
 	app := info.Apps["svc2"]
@@ -856,6 +927,96 @@ func (s *servicesTestSuite) TestAddRemoveSnapWithTimersAddsRemovesTimerFiles(c *
 	c.Check(osutil.FileExists(app.ServiceFile()), Equals, false)
 }
 
+type fakeUserServiceClient struct {
+	calls [][]string
+	err   error
+}
+
+func (f *fakeUserServiceClient) ServicesDaemonReload() error {
+	f.calls = append(f.calls, []string{"daemon-reload"})
+	return f.err
+}
+
+func (f *fakeUserServiceClient) ServicesStart(services []string) error {
+	f.calls = append(f.calls, append([]string{"start"}, services...))
+	return f.err
+}
+
+func (f *fakeUserServiceClient) ServicesStop(services []string) error {
+	f.calls = append(f.calls, append([]string{"stop"}, services...))
+	return f.err
+}
+
+func (f *fakeUserServiceClient) ServicesRestart(services []string) error {
+	f.calls = append(f.calls, append([]string{"restart"}, services...))
+	return f.err
+}
+
+func (s *servicesTestSuite) TestAddStartStopRemoveUserServices(c *C) {
+	var sysdLog [][]string
+	r := systemd.MockSystemctl(func(cmd ...string) ([]byte, error) {
+		sysdLog = append(sysdLog, cmd)
+		return []byte("ActiveState=inactive\n"), nil
+	})
+	defer r()
+	cli := &fakeUserServiceClient{}
+	defer wrappers.MockUserServiceClient(cli)()
+
+	info := snaptest.MockSnap(c, packageHello+`
+ svc2:
+  command: bin/hello
+  daemon: simple
+  daemon-scope: user
+ svc3:
+  command: bin/hello
+  daemon: oneshot
+  daemon-scope: user
+  timer: 10:00-12:00
+`, &snap.SideInfo{Revision: snap.R(12)})
+	svc2 := info.Apps["svc2"]
+	svc3 := info.Apps["svc3"]
+	userDir := filepath.Join(s.tempdir, "/etc/systemd/user")
+
+	err := wrappers.AddSnapServices(info, nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(svc2.ServiceFile(), Equals, filepath.Join(userDir, "snap.hello-snap.svc2.service"))
+	c.Check(osutil.FileExists(svc2.ServiceFile()), Equals, true)
+	c.Check(osutil.FileExists(svc3.ServiceFile()), Equals, true)
+	c.Check(svc3.Timer.File(), Equals, filepath.Join(userDir, "snap.hello-snap.svc3.timer"))
+	c.Check(osutil.FileExists(svc3.Timer.File()), Equals, true)
+	c.Check(sysdLog, testutil.DeepContains, []string{"--user", "--global", "--root", dirs.GlobalRootDir, "enable", "snap.hello-snap.svc2.service"})
+	c.Check(sysdLog, testutil.DeepContains, []string{"--user", "--global", "--root", dirs.GlobalRootDir, "enable", "snap.hello-snap.svc3.timer"})
+	c.Check(cli.calls, DeepEquals, [][]string{{"daemon-reload"}})
+
+	cli.calls = nil
+	err = wrappers.StartServices(info.Services(), nil, nil)
+	c.Assert(err, IsNil)
+	c.Check(cli.calls, HasLen, 1)
+	c.Check(cli.calls[0][0], Equals, "start")
+	started := cli.calls[0][1:]
+	sort.Strings(started)
+	c.Check(started, DeepEquals, []string{"snap.hello-snap.svc2.service", "snap.hello-snap.svc3.timer"})
+
+	cli.calls = nil
+	cli.err = fmt.Errorf("boom")
+	// failures to reach the user sessions are not fatal
+	err = wrappers.StopServices(info.Services(), "", &progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(cli.calls, HasLen, 1)
+	c.Check(cli.calls[0][0], Equals, "stop")
+
+	sysdLog = nil
+	cli.calls = nil
+	err = wrappers.RemoveSnapServices(info, &progress.Null)
+	c.Assert(err, IsNil)
+	c.Check(osutil.FileExists(svc2.ServiceFile()), Equals, false)
+	c.Check(osutil.FileExists(svc3.ServiceFile()), Equals, false)
+	c.Check(osutil.FileExists(svc3.Timer.File()), Equals, false)
+	c.Check(sysdLog, testutil.DeepContains, []string{"--user", "--global", "--root", dirs.GlobalRootDir, "disable", "snap.hello-snap.svc2.service"})
+	c.Check(sysdLog, testutil.DeepContains, []string{"--user", "--global", "--root", dirs.GlobalRootDir, "disable", "snap.hello-snap.svc3.timer"})
+	c.Check(cli.calls, DeepEquals, [][]string{{"daemon-reload"}})
+}
+
 func (s *servicesTestSuite) TestFailedAddSnapCleansUp(c *C) {
 	info := snaptest.MockSnap(c, packageHello+`
  svc2:
@@ -884,7 +1045,7 @@ func (s *servicesTestSuite) TestFailedAddSnapCleansUp(c *C) {
 	})
 	defer r()
 
-	err := wrappers.AddSnapServices(info, &progress.Null)
+	err := wrappers.AddSnapServices(info, nil, &progress.Null)
 	c.Assert(err, NotNil)
 
 	c.Logf("services dir: %v", dirs.SnapServicesDir)
@@ -933,7 +1094,7 @@ apps:
 
 	for i, info := range []*snap.Info{onlyServices, onlySockets, onlyTimers} {
 		sysdLog = [][]string{}
-		err := wrappers.AddSnapServices(info, &progress.Null)
+		err := wrappers.AddSnapServices(info, nil, &progress.Null)
 		c.Assert(err, IsNil)
 		reloads := 0
 		c.Logf("calls: %v", sysdLog)
@@ -981,7 +1142,7 @@ apps:
 	info := snaptest.MockSnap(c, snapYaml, &snap.SideInfo{Revision: snap.R(12)})
 
 	// fix the apps order to make the test stable
-	err := wrappers.AddSnapServices(info, nil)
+	err := wrappers.AddSnapServices(info, nil, nil)
 	c.Assert(err, IsNil)
 	c.Assert(sysdLog, HasLen, 2, Commentf("len: %v calls: %v", len(sysdLog), sysdLog))
 	c.Check(sysdLog, DeepEquals, [][]string{